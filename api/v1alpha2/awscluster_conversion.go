@@ -80,13 +80,13 @@ func Convert_v1alpha2_AWSClusterStatus_To_v1alpha3_AWSClusterStatus(in *AWSClust
 }
 
 // Convert_v1alpha3_AWSClusterSpec_To_v1alpha2_AWSClusterSpec converts from the Hub version (v1alpha3) of the AWSClusterSpec to this version.
-// Requires manual conversion as infrav1alpha3.AWSClusterSpec.ImageLookupOrg does not exist in AWSClusterSpec.
+// Requires manual conversion as infrav1alpha3.AWSClusterSpec.ImageLookupOrg and ImageLookupFormat do not exist in AWSClusterSpec.
 func Convert_v1alpha3_AWSClusterSpec_To_v1alpha2_AWSClusterSpec(in *infrav1alpha3.AWSClusterSpec, out *AWSClusterSpec, s apiconversion.Scope) error { // nolint
 	if err := autoConvert_v1alpha3_AWSClusterSpec_To_v1alpha2_AWSClusterSpec(in, out, s); err != nil {
 		return err
 	}
 
-	// Discards ImageLookupOrg
+	// Discards ImageLookupOrg and ImageLookupFormat
 
 	return nil
 }
@@ -100,3 +100,45 @@ func Convert_v1alpha3_AWSClusterStatus_To_v1alpha2_AWSClusterStatus(in *infrav1a
 func Convert_v1alpha3_ClassicELB_To_v1alpha2_ClassicELB(in *infrav1alpha3.ClassicELB, out *ClassicELB, s apiconversion.Scope) error { //nolint
 	return autoConvert_v1alpha3_ClassicELB_To_v1alpha2_ClassicELB(in, out, s)
 }
+
+// Convert_v1alpha3_Network_To_v1alpha2_Network converts from the Hub version (v1alpha3) of the Network to this version.
+// Requires manual conversion as infrav1alpha3.Network.APIServerDNSRecord does not exist in Network.
+func Convert_v1alpha3_Network_To_v1alpha2_Network(in *infrav1alpha3.Network, out *Network, s apiconversion.Scope) error { //nolint
+	return autoConvert_v1alpha3_Network_To_v1alpha2_Network(in, out, s)
+}
+
+// Convert_v1alpha3_SubnetSpec_To_v1alpha2_SubnetSpec converts from the Hub version (v1alpha3) of the SubnetSpec to this version.
+// Requires manual conversion as infrav1alpha3.SubnetSpec.IPv6CidrBlock, ForPods, MapPublicIPOnLaunch and NatGatewayAllocationID do not exist in SubnetSpec.
+func Convert_v1alpha3_SubnetSpec_To_v1alpha2_SubnetSpec(in *infrav1alpha3.SubnetSpec, out *SubnetSpec, s apiconversion.Scope) error { //nolint
+	return autoConvert_v1alpha3_SubnetSpec_To_v1alpha2_SubnetSpec(in, out, s)
+}
+
+// Convert_v1alpha3_VPCSpec_To_v1alpha2_VPCSpec converts from the Hub version (v1alpha3) of the VPCSpec to this version.
+// Requires manual conversion as the IPv6 fields, SecondaryCidrBlocks and ExternallyManaged of infrav1alpha3.VPCSpec do not exist in VPCSpec.
+func Convert_v1alpha3_VPCSpec_To_v1alpha2_VPCSpec(in *infrav1alpha3.VPCSpec, out *VPCSpec, s apiconversion.Scope) error { //nolint
+	return autoConvert_v1alpha3_VPCSpec_To_v1alpha2_VPCSpec(in, out, s)
+}
+
+// Convert_v1alpha3_AWSLoadBalancerSpec_To_v1alpha2_AWSLoadBalancerSpec converts from the Hub version (v1alpha3) of the AWSLoadBalancerSpec to this version.
+// Requires manual conversion as infrav1alpha3.AWSLoadBalancerSpec.LoadBalancerType, ARN, IngressSources, AdvertisedEndpoint, HealthCheck, ProxyProtocol, IdleTimeoutSeconds, CrossZoneLoadBalancing, DeletionProtection and DNSRecord do not exist in AWSLoadBalancerSpec.
+func Convert_v1alpha3_AWSLoadBalancerSpec_To_v1alpha2_AWSLoadBalancerSpec(in *infrav1alpha3.AWSLoadBalancerSpec, out *AWSLoadBalancerSpec, s apiconversion.Scope) error { // nolint
+	return autoConvert_v1alpha3_AWSLoadBalancerSpec_To_v1alpha2_AWSLoadBalancerSpec(in, out, s)
+}
+
+// Convert_v1alpha3_IngressRule_To_v1alpha2_IngressRule converts from the Hub version (v1alpha3) of the IngressRule to this version.
+// Requires manual conversion as infrav1alpha3.IngressRule.PrefixListIDs does not exist in IngressRule.
+func Convert_v1alpha3_IngressRule_To_v1alpha2_IngressRule(in *infrav1alpha3.IngressRule, out *IngressRule, s apiconversion.Scope) error { // nolint
+	return autoConvert_v1alpha3_IngressRule_To_v1alpha2_IngressRule(in, out, s)
+}
+
+// Convert_v1alpha3_NetworkSpec_To_v1alpha2_NetworkSpec converts from the Hub version (v1alpha3) of the NetworkSpec to this version.
+// Requires manual conversion as infrav1alpha3.NetworkSpec.NetworkACL, NodeIngress, VPCEndpoints, NatGatewayMode, SharedSubnets, DHCPOptions, TransitGateway, VPCPeering and PublicIPv4Pool do not exist in NetworkSpec.
+func Convert_v1alpha3_NetworkSpec_To_v1alpha2_NetworkSpec(in *infrav1alpha3.NetworkSpec, out *NetworkSpec, s apiconversion.Scope) error { // nolint
+	return autoConvert_v1alpha3_NetworkSpec_To_v1alpha2_NetworkSpec(in, out, s)
+}
+
+// Convert_v1alpha3_Instance_To_v1alpha2_Instance converts from the Hub version (v1alpha3) of the Instance to this version.
+// Requires manual conversion as infrav1alpha3.Instance.SpotMarketOptions, NetworkInterfaceSpecs, NetworkInterfaceType, the placement group fields, the tenancy fields, the capacity reservation fields, InstanceMetadataOptions, DisableAPITermination, RootVolume and NonRootVolumes do not exist in Instance.
+func Convert_v1alpha3_Instance_To_v1alpha2_Instance(in *infrav1alpha3.Instance, out *Instance, s apiconversion.Scope) error { // nolint
+	return autoConvert_v1alpha3_Instance_To_v1alpha2_Instance(in, out, s)
+}
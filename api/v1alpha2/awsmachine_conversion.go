@@ -58,7 +58,7 @@ func Convert_v1alpha3_AWSMachineSpec_To_v1alpha2_AWSMachineSpec(in *infrav1alpha
 		out.AvailabilityZone = in.FailureDomain
 	}
 
-	// Discards ImageLookupBaseOS
+	// Discards ImageLookupBaseOS, ImageLookupFormat, ImageLookupSSMParameter, AlternativeInstanceTypes, AutoRecovery, KubeletRegistration, UserDataSecret, CloudInit, ElasticIPPool, SpotMarketOptions, NetworkInterfaceSpecs, NetworkInterfaceType, the placement group, the tenancy, the capacity reservation, InstanceMetadataOptions, DisableAPITermination, RootVolume and NonRootVolumes
 
 	return nil
 }
@@ -82,11 +82,6 @@ func RegisterConversions(s *runtime.Scheme) error {
 	}); err != nil {
 		return err
 	}
-	if err := s.AddGeneratedConversionFunc((*v1alpha3.AWSLoadBalancerSpec)(nil), (*AWSLoadBalancerSpec)(nil), func(a, b interface{}, scope conversion.Scope) error {
-		return Convert_v1alpha3_AWSLoadBalancerSpec_To_v1alpha2_AWSLoadBalancerSpec(a.(*v1alpha3.AWSLoadBalancerSpec), b.(*AWSLoadBalancerSpec), scope)
-	}); err != nil {
-		return err
-	}
 	if err := s.AddGeneratedConversionFunc((*AWSMachine)(nil), (*v1alpha3.AWSMachine)(nil), func(a, b interface{}, scope conversion.Scope) error {
 		return Convert_v1alpha2_AWSMachine_To_v1alpha3_AWSMachine(a.(*AWSMachine), b.(*v1alpha3.AWSMachine), scope)
 	}); err != nil {
@@ -242,41 +237,21 @@ func RegisterConversions(s *runtime.Scheme) error {
 	}); err != nil {
 		return err
 	}
-	if err := s.AddGeneratedConversionFunc((*v1alpha3.IngressRule)(nil), (*IngressRule)(nil), func(a, b interface{}, scope conversion.Scope) error {
-		return Convert_v1alpha3_IngressRule_To_v1alpha2_IngressRule(a.(*v1alpha3.IngressRule), b.(*IngressRule), scope)
-	}); err != nil {
-		return err
-	}
 	if err := s.AddGeneratedConversionFunc((*Instance)(nil), (*v1alpha3.Instance)(nil), func(a, b interface{}, scope conversion.Scope) error {
 		return Convert_v1alpha2_Instance_To_v1alpha3_Instance(a.(*Instance), b.(*v1alpha3.Instance), scope)
 	}); err != nil {
 		return err
 	}
-	if err := s.AddGeneratedConversionFunc((*v1alpha3.Instance)(nil), (*Instance)(nil), func(a, b interface{}, scope conversion.Scope) error {
-		return Convert_v1alpha3_Instance_To_v1alpha2_Instance(a.(*v1alpha3.Instance), b.(*Instance), scope)
-	}); err != nil {
-		return err
-	}
 	if err := s.AddGeneratedConversionFunc((*Network)(nil), (*v1alpha3.Network)(nil), func(a, b interface{}, scope conversion.Scope) error {
 		return Convert_v1alpha2_Network_To_v1alpha3_Network(a.(*Network), b.(*v1alpha3.Network), scope)
 	}); err != nil {
 		return err
 	}
-	if err := s.AddGeneratedConversionFunc((*v1alpha3.Network)(nil), (*Network)(nil), func(a, b interface{}, scope conversion.Scope) error {
-		return Convert_v1alpha3_Network_To_v1alpha2_Network(a.(*v1alpha3.Network), b.(*Network), scope)
-	}); err != nil {
-		return err
-	}
 	if err := s.AddGeneratedConversionFunc((*NetworkSpec)(nil), (*v1alpha3.NetworkSpec)(nil), func(a, b interface{}, scope conversion.Scope) error {
 		return Convert_v1alpha2_NetworkSpec_To_v1alpha3_NetworkSpec(a.(*NetworkSpec), b.(*v1alpha3.NetworkSpec), scope)
 	}); err != nil {
 		return err
 	}
-	if err := s.AddGeneratedConversionFunc((*v1alpha3.NetworkSpec)(nil), (*NetworkSpec)(nil), func(a, b interface{}, scope conversion.Scope) error {
-		return Convert_v1alpha3_NetworkSpec_To_v1alpha2_NetworkSpec(a.(*v1alpha3.NetworkSpec), b.(*NetworkSpec), scope)
-	}); err != nil {
-		return err
-	}
 	if err := s.AddGeneratedConversionFunc((*RouteTable)(nil), (*v1alpha3.RouteTable)(nil), func(a, b interface{}, scope conversion.Scope) error {
 		return Convert_v1alpha2_RouteTable_To_v1alpha3_RouteTable(a.(*RouteTable), b.(*v1alpha3.RouteTable), scope)
 	}); err != nil {
@@ -302,21 +277,11 @@ func RegisterConversions(s *runtime.Scheme) error {
 	}); err != nil {
 		return err
 	}
-	if err := s.AddGeneratedConversionFunc((*v1alpha3.SubnetSpec)(nil), (*SubnetSpec)(nil), func(a, b interface{}, scope conversion.Scope) error {
-		return Convert_v1alpha3_SubnetSpec_To_v1alpha2_SubnetSpec(a.(*v1alpha3.SubnetSpec), b.(*SubnetSpec), scope)
-	}); err != nil {
-		return err
-	}
 	if err := s.AddGeneratedConversionFunc((*VPCSpec)(nil), (*v1alpha3.VPCSpec)(nil), func(a, b interface{}, scope conversion.Scope) error {
 		return Convert_v1alpha2_VPCSpec_To_v1alpha3_VPCSpec(a.(*VPCSpec), b.(*v1alpha3.VPCSpec), scope)
 	}); err != nil {
 		return err
 	}
-	if err := s.AddGeneratedConversionFunc((*v1alpha3.VPCSpec)(nil), (*VPCSpec)(nil), func(a, b interface{}, scope conversion.Scope) error {
-		return Convert_v1alpha3_VPCSpec_To_v1alpha2_VPCSpec(a.(*v1alpha3.VPCSpec), b.(*VPCSpec), scope)
-	}); err != nil {
-		return err
-	}
 	if err := s.AddConversionFunc((*AWSClusterStatus)(nil), (*v1alpha3.AWSClusterStatus)(nil), func(a, b interface{}, scope conversion.Scope) error {
 		return Convert_v1alpha2_AWSClusterStatus_To_v1alpha3_AWSClusterStatus(a.(*AWSClusterStatus), b.(*v1alpha3.AWSClusterStatus), scope)
 	}); err != nil {
@@ -337,6 +302,11 @@ func RegisterConversions(s *runtime.Scheme) error {
 	}); err != nil {
 		return err
 	}
+	if err := s.AddConversionFunc((*v1alpha3.AWSLoadBalancerSpec)(nil), (*AWSLoadBalancerSpec)(nil), func(a, b interface{}, scope conversion.Scope) error {
+		return Convert_v1alpha3_AWSLoadBalancerSpec_To_v1alpha2_AWSLoadBalancerSpec(a.(*v1alpha3.AWSLoadBalancerSpec), b.(*AWSLoadBalancerSpec), scope)
+	}); err != nil {
+		return err
+	}
 	if err := s.AddConversionFunc((*v1alpha3.AWSMachineSpec)(nil), (*AWSMachineSpec)(nil), func(a, b interface{}, scope conversion.Scope) error {
 		return Convert_v1alpha3_AWSMachineSpec_To_v1alpha2_AWSMachineSpec(a.(*v1alpha3.AWSMachineSpec), b.(*AWSMachineSpec), scope)
 	}); err != nil {
@@ -352,6 +322,36 @@ func RegisterConversions(s *runtime.Scheme) error {
 	}); err != nil {
 		return err
 	}
+	if err := s.AddConversionFunc((*v1alpha3.IngressRule)(nil), (*IngressRule)(nil), func(a, b interface{}, scope conversion.Scope) error {
+		return Convert_v1alpha3_IngressRule_To_v1alpha2_IngressRule(a.(*v1alpha3.IngressRule), b.(*IngressRule), scope)
+	}); err != nil {
+		return err
+	}
+	if err := s.AddConversionFunc((*v1alpha3.Instance)(nil), (*Instance)(nil), func(a, b interface{}, scope conversion.Scope) error {
+		return Convert_v1alpha3_Instance_To_v1alpha2_Instance(a.(*v1alpha3.Instance), b.(*Instance), scope)
+	}); err != nil {
+		return err
+	}
+	if err := s.AddConversionFunc((*v1alpha3.Network)(nil), (*Network)(nil), func(a, b interface{}, scope conversion.Scope) error {
+		return Convert_v1alpha3_Network_To_v1alpha2_Network(a.(*v1alpha3.Network), b.(*Network), scope)
+	}); err != nil {
+		return err
+	}
+	if err := s.AddConversionFunc((*v1alpha3.NetworkSpec)(nil), (*NetworkSpec)(nil), func(a, b interface{}, scope conversion.Scope) error {
+		return Convert_v1alpha3_NetworkSpec_To_v1alpha2_NetworkSpec(a.(*v1alpha3.NetworkSpec), b.(*NetworkSpec), scope)
+	}); err != nil {
+		return err
+	}
+	if err := s.AddConversionFunc((*v1alpha3.SubnetSpec)(nil), (*SubnetSpec)(nil), func(a, b interface{}, scope conversion.Scope) error {
+		return Convert_v1alpha3_SubnetSpec_To_v1alpha2_SubnetSpec(a.(*v1alpha3.SubnetSpec), b.(*SubnetSpec), scope)
+	}); err != nil {
+		return err
+	}
+	if err := s.AddConversionFunc((*v1alpha3.VPCSpec)(nil), (*VPCSpec)(nil), func(a, b interface{}, scope conversion.Scope) error {
+		return Convert_v1alpha3_VPCSpec_To_v1alpha2_VPCSpec(a.(*v1alpha3.VPCSpec), b.(*VPCSpec), scope)
+	}); err != nil {
+		return err
+	}
 	return nil
 }
 
@@ -436,7 +436,15 @@ func autoConvert_v1alpha2_AWSClusterSpec_To_v1alpha3_AWSClusterSpec(in *AWSClust
 	out.Region = in.Region
 	out.SSHKeyName = in.SSHKeyName
 	out.AdditionalTags = *(*v1alpha3.Tags)(unsafe.Pointer(&in.AdditionalTags))
-	out.ControlPlaneLoadBalancer = (*v1alpha3.AWSLoadBalancerSpec)(unsafe.Pointer(in.ControlPlaneLoadBalancer))
+	if in.ControlPlaneLoadBalancer != nil {
+		in, out := &in.ControlPlaneLoadBalancer, &out.ControlPlaneLoadBalancer
+		*out = new(v1alpha3.AWSLoadBalancerSpec)
+		if err := Convert_v1alpha2_AWSLoadBalancerSpec_To_v1alpha3_AWSLoadBalancerSpec(*in, *out, s); err != nil {
+			return err
+		}
+	} else {
+		out.ControlPlaneLoadBalancer = nil
+	}
 	return nil
 }
 
@@ -453,9 +461,25 @@ func autoConvert_v1alpha3_AWSClusterSpec_To_v1alpha2_AWSClusterSpec(in *v1alpha3
 	out.SSHKeyName = in.SSHKeyName
 	// WARNING: in.ControlPlaneEndpoint requires manual conversion: does not exist in peer-type
 	out.AdditionalTags = *(*Tags)(unsafe.Pointer(&in.AdditionalTags))
-	out.ControlPlaneLoadBalancer = (*AWSLoadBalancerSpec)(unsafe.Pointer(in.ControlPlaneLoadBalancer))
+	if in.ControlPlaneLoadBalancer != nil {
+		in, out := &in.ControlPlaneLoadBalancer, &out.ControlPlaneLoadBalancer
+		*out = new(AWSLoadBalancerSpec)
+		if err := Convert_v1alpha3_AWSLoadBalancerSpec_To_v1alpha2_AWSLoadBalancerSpec(*in, *out, s); err != nil {
+			return err
+		}
+	} else {
+		out.ControlPlaneLoadBalancer = nil
+	}
 	// WARNING: in.ImageLookupOrg requires manual conversion: does not exist in peer-type
 	// WARNING: in.ImageLookupBaseOS requires manual conversion: does not exist in peer-type
+	// WARNING: in.ImageLookupFormat requires manual conversion: does not exist in peer-type
+	// WARNING: in.ControlPlaneIAMInstanceProfile requires manual conversion: does not exist in peer-type
+	// WARNING: in.NodeIAMInstanceProfile requires manual conversion: does not exist in peer-type
+	// WARNING: in.Bastion requires manual conversion: does not exist in peer-type
+	// WARNING: in.SessionManager requires manual conversion: does not exist in peer-type
+	// WARNING: in.AssumeRole requires manual conversion: does not exist in peer-type
+	// WARNING: in.IdentityRef requires manual conversion: does not exist in peer-type
+	// WARNING: in.ExternalResourceGC requires manual conversion: does not exist in peer-type
 	return nil
 }
 
@@ -480,6 +504,10 @@ func autoConvert_v1alpha3_AWSClusterStatus_To_v1alpha2_AWSClusterStatus(in *v1al
 		return err
 	}
 	out.Ready = in.Ready
+	// WARNING: in.FailureReason requires manual conversion: does not exist in peer-type
+	// WARNING: in.FailureMessage requires manual conversion: does not exist in peer-type
+	// WARNING: in.CredentialsFailureReason requires manual conversion: does not exist in peer-type
+	// WARNING: in.CredentialsFailureMessage requires manual conversion: does not exist in peer-type
 	return nil
 }
 
@@ -495,14 +523,19 @@ func Convert_v1alpha2_AWSLoadBalancerSpec_To_v1alpha3_AWSLoadBalancerSpec(in *AW
 
 func autoConvert_v1alpha3_AWSLoadBalancerSpec_To_v1alpha2_AWSLoadBalancerSpec(in *v1alpha3.AWSLoadBalancerSpec, out *AWSLoadBalancerSpec, s conversion.Scope) error {
 	out.Scheme = (*ClassicELBScheme)(unsafe.Pointer(in.Scheme))
+	// WARNING: in.LoadBalancerType requires manual conversion: does not exist in peer-type
+	// WARNING: in.ARN requires manual conversion: does not exist in peer-type
+	// WARNING: in.IngressSources requires manual conversion: does not exist in peer-type
+	// WARNING: in.AdvertisedEndpoint requires manual conversion: does not exist in peer-type
+	// WARNING: in.HealthCheck requires manual conversion: does not exist in peer-type
+	// WARNING: in.ProxyProtocol requires manual conversion: does not exist in peer-type
+	// WARNING: in.IdleTimeoutSeconds requires manual conversion: does not exist in peer-type
+	// WARNING: in.CrossZoneLoadBalancing requires manual conversion: does not exist in peer-type
+	// WARNING: in.DeletionProtection requires manual conversion: does not exist in peer-type
+	// WARNING: in.DNSRecord requires manual conversion: does not exist in peer-type
 	return nil
 }
 
-// Convert_v1alpha3_AWSLoadBalancerSpec_To_v1alpha2_AWSLoadBalancerSpec is an autogenerated conversion function.
-func Convert_v1alpha3_AWSLoadBalancerSpec_To_v1alpha2_AWSLoadBalancerSpec(in *v1alpha3.AWSLoadBalancerSpec, out *AWSLoadBalancerSpec, s conversion.Scope) error {
-	return autoConvert_v1alpha3_AWSLoadBalancerSpec_To_v1alpha2_AWSLoadBalancerSpec(in, out, s)
-}
-
 func autoConvert_v1alpha2_AWSMachine_To_v1alpha3_AWSMachine(in *AWSMachine, out *v1alpha3.AWSMachine, s conversion.Scope) error {
 	out.ObjectMeta = in.ObjectMeta
 	if err := Convert_v1alpha2_AWSMachineSpec_To_v1alpha3_AWSMachineSpec(&in.Spec, &out.Spec, s); err != nil {
@@ -608,17 +641,39 @@ func autoConvert_v1alpha3_AWSMachineSpec_To_v1alpha2_AWSMachineSpec(in *v1alpha3
 	}
 	out.ImageLookupOrg = in.ImageLookupOrg
 	// WARNING: in.ImageLookupBaseOS requires manual conversion: does not exist in peer-type
+	// WARNING: in.ImageLookupFormat requires manual conversion: does not exist in peer-type
+	// WARNING: in.ImageLookupSSMParameter requires manual conversion: does not exist in peer-type
 	out.InstanceType = in.InstanceType
+	// WARNING: in.AlternativeInstanceTypes requires manual conversion: does not exist in peer-type
 	out.AdditionalTags = *(*Tags)(unsafe.Pointer(&in.AdditionalTags))
 	out.IAMInstanceProfile = in.IAMInstanceProfile
 	out.PublicIP = (*bool)(unsafe.Pointer(in.PublicIP))
+	// WARNING: in.ElasticIPPool requires manual conversion: does not exist in peer-type
 	out.AdditionalSecurityGroups = *(*[]AWSResourceReference)(unsafe.Pointer(&in.AdditionalSecurityGroups))
 	// WARNING: in.FailureDomain requires manual conversion: does not exist in peer-type
 	out.AvailabilityZone = (*string)(unsafe.Pointer(in.AvailabilityZone))
 	out.Subnet = (*AWSResourceReference)(unsafe.Pointer(in.Subnet))
 	out.SSHKeyName = in.SSHKeyName
 	out.RootDeviceSize = in.RootDeviceSize
+	// WARNING: in.RootVolume requires manual conversion: does not exist in peer-type
 	out.NetworkInterfaces = *(*[]string)(unsafe.Pointer(&in.NetworkInterfaces))
+	// WARNING: in.NetworkInterfaceSpecs requires manual conversion: does not exist in peer-type
+	// WARNING: in.NetworkInterfaceType requires manual conversion: does not exist in peer-type
+	// WARNING: in.AutoRecovery requires manual conversion: does not exist in peer-type
+	// WARNING: in.KubeletRegistration requires manual conversion: does not exist in peer-type
+	// WARNING: in.UserDataSecret requires manual conversion: does not exist in peer-type
+	// WARNING: in.CloudInit requires manual conversion: does not exist in peer-type
+	// WARNING: in.SpotMarketOptions requires manual conversion: does not exist in peer-type
+	// WARNING: in.PlacementGroupName requires manual conversion: does not exist in peer-type
+	// WARNING: in.PlacementGroupPartition requires manual conversion: does not exist in peer-type
+	// WARNING: in.PlacementGroupStrategy requires manual conversion: does not exist in peer-type
+	// WARNING: in.Tenancy requires manual conversion: does not exist in peer-type
+	// WARNING: in.HostID requires manual conversion: does not exist in peer-type
+	// WARNING: in.CapacityReservationPreference requires manual conversion: does not exist in peer-type
+	// WARNING: in.CapacityReservationID requires manual conversion: does not exist in peer-type
+	// WARNING: in.InstanceMetadataOptions requires manual conversion: does not exist in peer-type
+	// WARNING: in.DisableAPITermination requires manual conversion: does not exist in peer-type
+	// WARNING: in.NonRootVolumes requires manual conversion: does not exist in peer-type
 	return nil
 }
 
@@ -635,6 +690,8 @@ func autoConvert_v1alpha3_AWSMachineStatus_To_v1alpha2_AWSMachineStatus(in *v1al
 	out.Ready = in.Ready
 	out.Addresses = *(*[]v1.NodeAddress)(unsafe.Pointer(&in.Addresses))
 	out.InstanceState = (*InstanceState)(unsafe.Pointer(in.InstanceState))
+	// WARNING: in.InstanceType requires manual conversion: does not exist in peer-type
+	// WARNING: in.InstanceProfileAssociation requires manual conversion: does not exist in peer-type
 	// WARNING: in.FailureReason requires manual conversion: does not exist in peer-type
 	// WARNING: in.FailureMessage requires manual conversion: does not exist in peer-type
 	return nil
@@ -833,12 +890,15 @@ func Convert_v1alpha2_ClassicELB_To_v1alpha3_ClassicELB(in *ClassicELB, out *v1a
 func autoConvert_v1alpha3_ClassicELB_To_v1alpha2_ClassicELB(in *v1alpha3.ClassicELB, out *ClassicELB, s conversion.Scope) error {
 	out.Name = in.Name
 	out.DNSName = in.DNSName
+	// WARNING: in.CanonicalHostedZoneID requires manual conversion: does not exist in peer-type
 	out.Scheme = ClassicELBScheme(in.Scheme)
 	// WARNING: in.AvailabilityZones requires manual conversion: does not exist in peer-type
 	out.SubnetIDs = *(*[]string)(unsafe.Pointer(&in.SubnetIDs))
 	out.SecurityGroupIDs = *(*[]string)(unsafe.Pointer(&in.SecurityGroupIDs))
 	out.Listeners = *(*[]*ClassicELBListener)(unsafe.Pointer(&in.Listeners))
 	out.HealthCheck = (*ClassicELBHealthCheck)(unsafe.Pointer(in.HealthCheck))
+	// WARNING: in.ProxyProtocol requires manual conversion: does not exist in peer-type
+	// WARNING: in.CrossZoneLoadBalancing requires manual conversion: does not exist in peer-type
 	if err := Convert_v1alpha3_ClassicELBAttributes_To_v1alpha2_ClassicELBAttributes(&in.Attributes, &out.Attributes, s); err != nil {
 		return err
 	}
@@ -964,14 +1024,10 @@ func autoConvert_v1alpha3_IngressRule_To_v1alpha2_IngressRule(in *v1alpha3.Ingre
 	out.ToPort = in.ToPort
 	out.CidrBlocks = *(*[]string)(unsafe.Pointer(&in.CidrBlocks))
 	out.SourceSecurityGroupIDs = *(*[]string)(unsafe.Pointer(&in.SourceSecurityGroupIDs))
+	// WARNING: in.PrefixListIDs requires manual conversion: does not exist in peer-type
 	return nil
 }
 
-// Convert_v1alpha3_IngressRule_To_v1alpha2_IngressRule is an autogenerated conversion function.
-func Convert_v1alpha3_IngressRule_To_v1alpha2_IngressRule(in *v1alpha3.IngressRule, out *IngressRule, s conversion.Scope) error {
-	return autoConvert_v1alpha3_IngressRule_To_v1alpha2_IngressRule(in, out, s)
-}
-
 func autoConvert_v1alpha2_Instance_To_v1alpha3_Instance(in *Instance, out *v1alpha3.Instance, s conversion.Scope) error {
 	out.ID = in.ID
 	out.State = v1alpha3.InstanceState(in.State)
@@ -1014,18 +1070,38 @@ func autoConvert_v1alpha3_Instance_To_v1alpha2_Instance(in *v1alpha3.Instance, o
 	out.ENASupport = (*bool)(unsafe.Pointer(in.ENASupport))
 	out.EBSOptimized = (*bool)(unsafe.Pointer(in.EBSOptimized))
 	out.RootDeviceSize = in.RootDeviceSize
+	// WARNING: in.RootVolume requires manual conversion: does not exist in peer-type
 	out.NetworkInterfaces = *(*[]string)(unsafe.Pointer(&in.NetworkInterfaces))
+	// WARNING: in.NetworkInterfaceSpecs requires manual conversion: does not exist in peer-type
+	// WARNING: in.NetworkInterfaceType requires manual conversion: does not exist in peer-type
 	out.Tags = *(*map[string]string)(unsafe.Pointer(&in.Tags))
+	// WARNING: in.SpotMarketOptions requires manual conversion: does not exist in peer-type
+	// WARNING: in.PlacementGroupName requires manual conversion: does not exist in peer-type
+	// WARNING: in.PlacementGroupPartition requires manual conversion: does not exist in peer-type
+	// WARNING: in.Tenancy requires manual conversion: does not exist in peer-type
+	// WARNING: in.HostID requires manual conversion: does not exist in peer-type
+	// WARNING: in.CapacityReservationPreference requires manual conversion: does not exist in peer-type
+	// WARNING: in.CapacityReservationID requires manual conversion: does not exist in peer-type
+	// WARNING: in.InstanceMetadataOptions requires manual conversion: does not exist in peer-type
+	// WARNING: in.DisableAPITermination requires manual conversion: does not exist in peer-type
+	// WARNING: in.NonRootVolumes requires manual conversion: does not exist in peer-type
 	return nil
 }
 
-// Convert_v1alpha3_Instance_To_v1alpha2_Instance is an autogenerated conversion function.
-func Convert_v1alpha3_Instance_To_v1alpha2_Instance(in *v1alpha3.Instance, out *Instance, s conversion.Scope) error {
-	return autoConvert_v1alpha3_Instance_To_v1alpha2_Instance(in, out, s)
-}
-
 func autoConvert_v1alpha2_Network_To_v1alpha3_Network(in *Network, out *v1alpha3.Network, s conversion.Scope) error {
-	out.SecurityGroups = *(*map[v1alpha3.SecurityGroupRole]v1alpha3.SecurityGroup)(unsafe.Pointer(&in.SecurityGroups))
+	if in.SecurityGroups != nil {
+		in, out := &in.SecurityGroups, &out.SecurityGroups
+		*out = make(map[v1alpha3.SecurityGroupRole]v1alpha3.SecurityGroup, len(*in))
+		for key, val := range *in {
+			newVal := new(v1alpha3.SecurityGroup)
+			if err := Convert_v1alpha2_SecurityGroup_To_v1alpha3_SecurityGroup(&val, newVal, s); err != nil {
+				return err
+			}
+			(*out)[v1alpha3.SecurityGroupRole(key)] = *newVal
+		}
+	} else {
+		out.SecurityGroups = nil
+	}
 	if err := Convert_v1alpha2_ClassicELB_To_v1alpha3_ClassicELB(&in.APIServerELB, &out.APIServerELB, s); err != nil {
 		return err
 	}
@@ -1038,23 +1114,47 @@ func Convert_v1alpha2_Network_To_v1alpha3_Network(in *Network, out *v1alpha3.Net
 }
 
 func autoConvert_v1alpha3_Network_To_v1alpha2_Network(in *v1alpha3.Network, out *Network, s conversion.Scope) error {
-	out.SecurityGroups = *(*map[SecurityGroupRole]SecurityGroup)(unsafe.Pointer(&in.SecurityGroups))
+	if in.SecurityGroups != nil {
+		in, out := &in.SecurityGroups, &out.SecurityGroups
+		*out = make(map[SecurityGroupRole]SecurityGroup, len(*in))
+		for key, val := range *in {
+			newVal := new(SecurityGroup)
+			if err := Convert_v1alpha3_SecurityGroup_To_v1alpha2_SecurityGroup(&val, newVal, s); err != nil {
+				return err
+			}
+			(*out)[SecurityGroupRole(key)] = *newVal
+		}
+	} else {
+		out.SecurityGroups = nil
+	}
 	if err := Convert_v1alpha3_ClassicELB_To_v1alpha2_ClassicELB(&in.APIServerELB, &out.APIServerELB, s); err != nil {
 		return err
 	}
+	// WARNING: in.APIServerDNSRecord requires manual conversion: does not exist in peer-type
 	return nil
 }
 
-// Convert_v1alpha3_Network_To_v1alpha2_Network is an autogenerated conversion function.
-func Convert_v1alpha3_Network_To_v1alpha2_Network(in *v1alpha3.Network, out *Network, s conversion.Scope) error {
-	return autoConvert_v1alpha3_Network_To_v1alpha2_Network(in, out, s)
-}
-
 func autoConvert_v1alpha2_NetworkSpec_To_v1alpha3_NetworkSpec(in *NetworkSpec, out *v1alpha3.NetworkSpec, s conversion.Scope) error {
 	if err := Convert_v1alpha2_VPCSpec_To_v1alpha3_VPCSpec(&in.VPC, &out.VPC, s); err != nil {
 		return err
 	}
-	out.Subnets = *(*v1alpha3.Subnets)(unsafe.Pointer(&in.Subnets))
+	if in.Subnets != nil {
+		in, out := &in.Subnets, &out.Subnets
+		*out = make(v1alpha3.Subnets, len(*in))
+		for i := range *in {
+			if (*in)[i] != nil {
+				in, out := &(*in)[i], &(*out)[i]
+				*out = new(v1alpha3.SubnetSpec)
+				if err := Convert_v1alpha2_SubnetSpec_To_v1alpha3_SubnetSpec(*in, *out, s); err != nil {
+					return err
+				}
+			} else {
+				(*out)[i] = nil
+			}
+		}
+	} else {
+		out.Subnets = nil
+	}
 	return nil
 }
 
@@ -1067,15 +1167,35 @@ func autoConvert_v1alpha3_NetworkSpec_To_v1alpha2_NetworkSpec(in *v1alpha3.Netwo
 	if err := Convert_v1alpha3_VPCSpec_To_v1alpha2_VPCSpec(&in.VPC, &out.VPC, s); err != nil {
 		return err
 	}
-	out.Subnets = *(*Subnets)(unsafe.Pointer(&in.Subnets))
+	if in.Subnets != nil {
+		in, out := &in.Subnets, &out.Subnets
+		*out = make(Subnets, len(*in))
+		for i := range *in {
+			if (*in)[i] != nil {
+				in, out := &(*in)[i], &(*out)[i]
+				*out = new(SubnetSpec)
+				if err := Convert_v1alpha3_SubnetSpec_To_v1alpha2_SubnetSpec(*in, *out, s); err != nil {
+					return err
+				}
+			} else {
+				(*out)[i] = nil
+			}
+		}
+	} else {
+		out.Subnets = nil
+	}
+	// WARNING: in.NetworkACL requires manual conversion: does not exist in peer-type
+	// WARNING: in.NodeIngress requires manual conversion: does not exist in peer-type
+	// WARNING: in.VPCEndpoints requires manual conversion: does not exist in peer-type
+	// WARNING: in.NatGatewayMode requires manual conversion: does not exist in peer-type
+	// WARNING: in.SharedSubnets requires manual conversion: does not exist in peer-type
+	// WARNING: in.DHCPOptions requires manual conversion: does not exist in peer-type
+	// WARNING: in.TransitGateway requires manual conversion: does not exist in peer-type
+	// WARNING: in.VPCPeering requires manual conversion: does not exist in peer-type
+	// WARNING: in.PublicIPv4Pool requires manual conversion: does not exist in peer-type
 	return nil
 }
 
-// Convert_v1alpha3_NetworkSpec_To_v1alpha2_NetworkSpec is an autogenerated conversion function.
-func Convert_v1alpha3_NetworkSpec_To_v1alpha2_NetworkSpec(in *v1alpha3.NetworkSpec, out *NetworkSpec, s conversion.Scope) error {
-	return autoConvert_v1alpha3_NetworkSpec_To_v1alpha2_NetworkSpec(in, out, s)
-}
-
 func autoConvert_v1alpha2_RouteTable_To_v1alpha3_RouteTable(in *RouteTable, out *v1alpha3.RouteTable, s conversion.Scope) error {
 	out.ID = in.ID
 	return nil
@@ -1099,7 +1219,21 @@ func Convert_v1alpha3_RouteTable_To_v1alpha2_RouteTable(in *v1alpha3.RouteTable,
 func autoConvert_v1alpha2_SecurityGroup_To_v1alpha3_SecurityGroup(in *SecurityGroup, out *v1alpha3.SecurityGroup, s conversion.Scope) error {
 	out.ID = in.ID
 	out.Name = in.Name
-	out.IngressRules = *(*v1alpha3.IngressRules)(unsafe.Pointer(&in.IngressRules))
+	if in.IngressRules != nil {
+		in, out := &in.IngressRules, &out.IngressRules
+		*out = make(v1alpha3.IngressRules, len(*in))
+		for i := range *in {
+			if (*in)[i] == nil {
+				continue
+			}
+			(*out)[i] = new(v1alpha3.IngressRule)
+			if err := Convert_v1alpha2_IngressRule_To_v1alpha3_IngressRule((*in)[i], (*out)[i], s); err != nil {
+				return err
+			}
+		}
+	} else {
+		out.IngressRules = nil
+	}
 	out.Tags = *(*v1alpha3.Tags)(unsafe.Pointer(&in.Tags))
 	return nil
 }
@@ -1112,7 +1246,21 @@ func Convert_v1alpha2_SecurityGroup_To_v1alpha3_SecurityGroup(in *SecurityGroup,
 func autoConvert_v1alpha3_SecurityGroup_To_v1alpha2_SecurityGroup(in *v1alpha3.SecurityGroup, out *SecurityGroup, s conversion.Scope) error {
 	out.ID = in.ID
 	out.Name = in.Name
-	out.IngressRules = *(*IngressRules)(unsafe.Pointer(&in.IngressRules))
+	if in.IngressRules != nil {
+		in, out := &in.IngressRules, &out.IngressRules
+		*out = make(IngressRules, len(*in))
+		for i := range *in {
+			if (*in)[i] == nil {
+				continue
+			}
+			(*out)[i] = new(IngressRule)
+			if err := Convert_v1alpha3_IngressRule_To_v1alpha2_IngressRule((*in)[i], (*out)[i], s); err != nil {
+				return err
+			}
+		}
+	} else {
+		out.IngressRules = nil
+	}
 	out.Tags = *(*Tags)(unsafe.Pointer(&in.Tags))
 	return nil
 }
@@ -1141,19 +1289,18 @@ func Convert_v1alpha2_SubnetSpec_To_v1alpha3_SubnetSpec(in *SubnetSpec, out *v1a
 func autoConvert_v1alpha3_SubnetSpec_To_v1alpha2_SubnetSpec(in *v1alpha3.SubnetSpec, out *SubnetSpec, s conversion.Scope) error {
 	out.ID = in.ID
 	out.CidrBlock = in.CidrBlock
+	// WARNING: in.IPv6CidrBlock requires manual conversion: does not exist in peer-type
 	out.AvailabilityZone = in.AvailabilityZone
 	out.IsPublic = in.IsPublic
+	// WARNING: in.ForPods requires manual conversion: does not exist in peer-type
+	// WARNING: in.MapPublicIPOnLaunch requires manual conversion: does not exist in peer-type
 	out.RouteTableID = (*string)(unsafe.Pointer(in.RouteTableID))
 	out.NatGatewayID = (*string)(unsafe.Pointer(in.NatGatewayID))
+	// WARNING: in.NatGatewayAllocationID requires manual conversion: does not exist in peer-type
 	out.Tags = *(*Tags)(unsafe.Pointer(&in.Tags))
 	return nil
 }
 
-// Convert_v1alpha3_SubnetSpec_To_v1alpha2_SubnetSpec is an autogenerated conversion function.
-func Convert_v1alpha3_SubnetSpec_To_v1alpha2_SubnetSpec(in *v1alpha3.SubnetSpec, out *SubnetSpec, s conversion.Scope) error {
-	return autoConvert_v1alpha3_SubnetSpec_To_v1alpha2_SubnetSpec(in, out, s)
-}
-
 func autoConvert_v1alpha2_VPCSpec_To_v1alpha3_VPCSpec(in *VPCSpec, out *v1alpha3.VPCSpec, s conversion.Scope) error {
 	out.ID = in.ID
 	out.CidrBlock = in.CidrBlock
@@ -1169,13 +1316,13 @@ func Convert_v1alpha2_VPCSpec_To_v1alpha3_VPCSpec(in *VPCSpec, out *v1alpha3.VPC
 
 func autoConvert_v1alpha3_VPCSpec_To_v1alpha2_VPCSpec(in *v1alpha3.VPCSpec, out *VPCSpec, s conversion.Scope) error {
 	out.ID = in.ID
+	// WARNING: in.ExternallyManaged requires manual conversion: does not exist in peer-type
 	out.CidrBlock = in.CidrBlock
+	// WARNING: in.SecondaryCidrBlocks requires manual conversion: does not exist in peer-type
 	out.InternetGatewayID = (*string)(unsafe.Pointer(in.InternetGatewayID))
+	// WARNING: in.EnableIPv6 requires manual conversion: does not exist in peer-type
+	// WARNING: in.IPv6CidrBlock requires manual conversion: does not exist in peer-type
+	// WARNING: in.EgressOnlyInternetGatewayID requires manual conversion: does not exist in peer-type
 	out.Tags = *(*Tags)(unsafe.Pointer(&in.Tags))
 	return nil
 }
-
-// Convert_v1alpha3_VPCSpec_To_v1alpha2_VPCSpec is an autogenerated conversion function.
-func Convert_v1alpha3_VPCSpec_To_v1alpha2_VPCSpec(in *v1alpha3.VPCSpec, out *VPCSpec, s conversion.Scope) error {
-	return autoConvert_v1alpha3_VPCSpec_To_v1alpha2_VPCSpec(in, out, s)
-}
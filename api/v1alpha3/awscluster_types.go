@@ -19,12 +19,18 @@ package v1alpha3
 import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	clusterv1 "sigs.k8s.io/cluster-api/api/v1alpha3"
+	"sigs.k8s.io/cluster-api/errors"
 )
 
 const (
 	// ClusterFinalizer allows ReconcileAWSCluster to clean up AWS resources associated with AWSCluster before
 	// removing it from the apiserver.
 	ClusterFinalizer = "awscluster.infrastructure.cluster.x-k8s.io"
+
+	// MigrateLoadBalancerAnnotation allows the control plane load balancer of a
+	// cluster whose endpoint is set to be changed from a classic ELB to a
+	// network load balancer. It is removed once the classic ELB is deleted.
+	MigrateLoadBalancerAnnotation = "awscluster.infrastructure.cluster.x-k8s.io/migrate-load-balancer"
 )
 
 // AWSClusterSpec defines the desired state of AWSCluster
@@ -43,7 +49,8 @@ type AWSClusterSpec struct {
 	ControlPlaneEndpoint clusterv1.APIEndpoint `json:"controlPlaneEndpoint"`
 
 	// AdditionalTags is an optional set of tags to add to AWS resources managed by the AWS provider, in addition to the
-	// ones added by default.
+	// ones added by default. Changes are applied to existing resources, and tags removed from this set are removed
+	// from the resources, while tags set outside of the provider are left untouched.
 	// +optional
 	AdditionalTags Tags `json:"additionalTags,omitempty"`
 
@@ -62,6 +69,122 @@ type AWSClusterSpec struct {
 	// will be used for all cluster machines unless a machine specifies a
 	// different ImageLookupBaseOS.
 	ImageLookupBaseOS string `json:"imageLookupBaseOS,omitempty"`
+
+	// ImageLookupFormat is the name glob used to look up machine images when a
+	// machine does not specify an AMI. When set, this will be used for all
+	// cluster machines unless a machine specifies a different
+	// ImageLookupFormat. See AWSMachineSpec.ImageLookupFormat.
+	// +optional
+	ImageLookupFormat string `json:"imageLookupFormat,omitempty"`
+
+	// ControlPlaneIAMInstanceProfile is the name of the IAM instance profile
+	// assigned to control plane machines that do not specify an
+	// IAMInstanceProfile. Worker machines are never launched with this
+	// profile. Defaults to the control plane instance profile created by
+	// clusterawsadm for the purpose of that check.
+	// +optional
+	ControlPlaneIAMInstanceProfile string `json:"controlPlaneIAMInstanceProfile,omitempty"`
+
+	// NodeIAMInstanceProfile is the name of the IAM instance profile assigned
+	// to worker machines that do not specify an IAMInstanceProfile.
+	// +optional
+	NodeIAMInstanceProfile string `json:"nodeIAMInstanceProfile,omitempty"`
+
+	// Bastion contains options to configure the bastion host.
+	// +optional
+	Bastion Bastion `json:"bastion"`
+
+	// SessionManager makes operators access the cluster machines through AWS
+	// Systems Manager Session Manager instead of SSH through a bastion host.
+	// When enabled, no bastion host is created and an existing one is removed.
+	// In managed VPCs, the ssm, ssmmessages and ec2messages interface VPC
+	// endpoints are created so the SSM agent of machines in private subnets
+	// reaches Systems Manager. The instance profiles of the machines need the
+	// permissions of the SSM agent, which the instance profiles created by
+	// clusterawsadm have.
+	// +optional
+	SessionManager bool `json:"sessionManager,omitempty"`
+
+	// AssumeRole configures a role which is assumed for all AWS calls made for
+	// the cluster, e.g. to provision it in another AWS account. When unset, the
+	// controller's own credentials are used.
+	// +optional
+	AssumeRole *AssumeRoleSpec `json:"assumeRole,omitempty"`
+
+	// IdentityRef references the cluster-scoped identity the AWS calls made
+	// for the cluster use, which must allow the namespace of the AWSCluster.
+	// It cannot be set together with AssumeRole. When unset, the controller's
+	// own credentials are used.
+	// +optional
+	IdentityRef *AWSIdentityReference `json:"identityRef,omitempty"`
+
+	// ExternalResourceGC makes the controllers delete, when the cluster is
+	// deleted, the load balancers, target groups and security groups created
+	// in the VPC by the workload cluster, i.e. by the in-tree cloud provider or
+	// the AWS Load Balancer Controller for Services of type LoadBalancer and
+	// Ingresses. Left behind, they block the deletion of the network.
+	// +optional
+	ExternalResourceGC bool `json:"externalResourceGC,omitempty"`
+}
+
+// AssumeRoleSpec configures the role assumed for the AWS calls of a cluster.
+type AssumeRoleSpec struct {
+	// RoleARN is the ARN of the role to assume.
+	RoleARN string `json:"roleARN"`
+
+	// ExternalID is the external ID required by the trust policy of the role.
+	// +optional
+	ExternalID string `json:"externalID,omitempty"`
+
+	// SessionName is the name of the role session, recorded in CloudTrail.
+	// Defaults to "capa-<namespace>-<name>" of the AWSCluster.
+	// +optional
+	SessionName string `json:"sessionName,omitempty"`
+}
+
+// Bastion defines a bastion host.
+type Bastion struct {
+	// Enabled allows this provider to create a bastion host instance
+	// to access the VPC private network.
+	// Disabling it on a cluster that has a bastion host removes the
+	// bastion host and the SSH ingress rules that allow access from it.
+	// Defaults to true.
+	// +optional
+	Enabled *bool `json:"enabled,omitempty"`
+
+	// PublicIP defines whether the bastion host is reachable from the internet.
+	// When false, the bastion host is launched without a public IP address in a
+	// private subnet, and has to be reached through a VPN or other private
+	// connectivity into the VPC.
+	// Defaults to true.
+	// +optional
+	PublicIP *bool `json:"publicIP,omitempty"`
+
+	// AllowedCIDRBlocks is the list of IPv4 CIDR blocks allowed to reach the
+	// bastion host over SSH. Defaults to any IPv4 address when the bastion
+	// host has a public IP, and to the CIDR block of the VPC otherwise.
+	// +optional
+	AllowedCIDRBlocks []string `json:"allowedCIDRBlocks,omitempty"`
+
+	// ElasticIPAllocationID is the allocation ID of a pre-allocated Elastic IP
+	// to associate with the bastion host, so its public IP address survives
+	// the bastion host being recreated. The Elastic IP must not be associated
+	// with another resource, and is not released when the bastion host is
+	// deleted. When unset, the bastion host uses the public IP address
+	// assigned on launch.
+	// +optional
+	// +kubebuilder:validation:Pattern=`^eipalloc-`
+	ElasticIPAllocationID string `json:"elasticIPAllocationID,omitempty"`
+}
+
+// IsEnabled returns true if a bastion host should be created.
+func (b *Bastion) IsEnabled() bool {
+	return b.Enabled == nil || *b.Enabled
+}
+
+// IsPublic returns true if the bastion host should get a public IP address.
+func (b *Bastion) IsPublic() bool {
+	return b.PublicIP == nil || *b.PublicIP
 }
 
 // AWSLoadBalancerSpec defines the desired state of an AWS load balancer
@@ -69,6 +192,198 @@ type AWSLoadBalancerSpec struct {
 	// Scheme sets the scheme of the load balancer (defaults to Internet-facing)
 	// +optional
 	Scheme *ClassicELBScheme `json:"scheme,omitempty"`
+
+	// LoadBalancerType sets the type of the load balancer, either a Classic ELB
+	// or a Network Load Balancer (defaults to classic). A Network Load Balancer
+	// preserves the client source IP, so the API server ingress rules apply to
+	// clients directly. It cannot be changed once the control plane endpoint is
+	// set, since the endpoint would change.
+	// +kubebuilder:validation:Enum=classic;nlb
+	// +optional
+	LoadBalancerType LoadBalancerType `json:"loadBalancerType,omitempty"`
+
+	// ARN references an existing load balancer of the loadBalancerType to use
+	// instead of creating one, e.g. a centrally managed one. The controller
+	// only registers the control plane instances with it, and deregisters
+	// them when they are deleted. The load balancer itself is never modified
+	// nor deleted. A network load balancer must have a TCP listener on the
+	// API server port forwarding to a target group of instances. It cannot
+	// be changed once the control plane endpoint is set.
+	// +optional
+	ARN *string `json:"arn,omitempty"`
+
+	// IngressSources restricts which sources can reach the Kubernetes API
+	// server through the load balancer. When empty, any IPv4 address is allowed.
+	// The control plane and node security groups, and the public IPs of the
+	// cluster NAT gateways, are always allowed so the cluster keeps working.
+	// +optional
+	IngressSources []IngressSource `json:"ingressSources,omitempty"`
+
+	// AdvertisedEndpoint, when set, is advertised as the control plane endpoint
+	// instead of the load balancer DNS name and the API server port. Use it when
+	// the API server is reached through a proxy or NAT in front of the load
+	// balancer. The proxy must forward to the load balancer listener, and the
+	// API server certificate must be valid for the advertised host. It cannot
+	// be changed once the control plane endpoint is set.
+	// +optional
+	AdvertisedEndpoint *AdvertisedEndpoint `json:"advertisedEndpoint,omitempty"`
+
+	// HealthCheck customizes the health check of a classic load balancer.
+	// Changes made to the health check outside of the controller are reverted.
+	// +optional
+	HealthCheck *ClassicELBHealthCheckSpec `json:"healthCheck,omitempty"`
+
+	// ProxyProtocol makes a classic load balancer send the PROXY protocol
+	// header to the API server port of the control plane instances, so they
+	// get the address of the client. Classic load balancers send version 1
+	// of the PROXY protocol, the API server must be behind a proxy accepting
+	// it. Turning it off removes the PROXY protocol policy.
+	// +optional
+	ProxyProtocol bool `json:"proxyProtocol,omitempty"`
+
+	// IdleTimeoutSeconds is the time a connection through a classic load
+	// balancer may be idle before it is closed. Defaults to 600. Network load
+	// balancers have a fixed idle timeout of 350 seconds for TCP.
+	// +optional
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=4000
+	IdleTimeoutSeconds int64 `json:"idleTimeoutSeconds,omitempty"`
+
+	// CrossZoneLoadBalancing makes the load balancer spread the connections
+	// received in each availability zone over the control plane instances of
+	// all zones, rather than of the same zone only.
+	// +optional
+	CrossZoneLoadBalancing bool `json:"crossZoneLoadBalancing,omitempty"`
+
+	// DeletionProtection prevents a network load balancer from being deleted
+	// outside of the controller. The controller turns it off when it deletes
+	// the load balancer with the cluster.
+	// +optional
+	DeletionProtection bool `json:"deletionProtection,omitempty"`
+
+	// DNSRecord makes the controller manage a record in a Route53 hosted zone
+	// pointing at the load balancer, giving clients a stable name for the
+	// API server. Failing to manage the record does not block the cluster,
+	// it is reported in status.network.apiServerDnsRecord and by events. The
+	// record is removed with the cluster, or when this is unset. Set the
+	// advertisedEndpoint host to the record name to make it the control
+	// plane endpoint.
+	// +optional
+	DNSRecord *DNSRecordSpec `json:"dnsRecord,omitempty"`
+}
+
+// DNSRecordType defines how a DNS record points at the load balancer.
+type DNSRecordType string
+
+var (
+	// DNSRecordTypeAlias defines a Route53 alias A record.
+	DNSRecordTypeAlias = DNSRecordType("ALIAS")
+
+	// DNSRecordTypeCNAME defines a CNAME record.
+	DNSRecordTypeCNAME = DNSRecordType("CNAME")
+)
+
+// DNSRecordSpec defines a Route53 record pointing at the control plane load
+// balancer.
+type DNSRecordSpec struct {
+	// HostedZoneID is the ID of the Route53 hosted zone of the record, e.g. a
+	// private hosted zone associated with the cluster VPC.
+	HostedZoneID string `json:"hostedZoneId"`
+
+	// Name is the fully qualified name of the record, within the hosted zone.
+	// An existing record of that name is only updated if it already points
+	// at the load balancer.
+	Name string `json:"name"`
+
+	// Type is the type of the record, either an alias A record or a CNAME
+	// record (defaults to ALIAS).
+	// +kubebuilder:validation:Enum=ALIAS;CNAME
+	// +optional
+	Type DNSRecordType `json:"type,omitempty"`
+}
+
+// ClassicELBHealthCheckSpec customizes the health check of the control plane
+// classic load balancer. Unset fields keep their default.
+type ClassicELBHealthCheckSpec struct {
+	// Protocol is the protocol of the health check of the API server port.
+	// SSL only checks the TLS handshake, HTTPS requests /healthz and expects
+	// a 200 response. Defaults to SSL.
+	// +kubebuilder:validation:Enum=TCP;SSL;HTTPS
+	// +optional
+	Protocol ClassicELBProtocol `json:"protocol,omitempty"`
+
+	// IntervalSeconds is the time between health checks of an instance,
+	// between 5 and 300 seconds. Defaults to 10.
+	// +kubebuilder:validation:Minimum=5
+	// +kubebuilder:validation:Maximum=300
+	// +optional
+	IntervalSeconds int64 `json:"intervalSeconds,omitempty"`
+
+	// TimeoutSeconds is the time without response after which a health check
+	// fails, between 2 and 60 seconds. It must be less than the interval.
+	// Defaults to 5.
+	// +kubebuilder:validation:Minimum=2
+	// +kubebuilder:validation:Maximum=60
+	// +optional
+	TimeoutSeconds int64 `json:"timeoutSeconds,omitempty"`
+
+	// HealthyThreshold is the number of consecutive successful health checks
+	// after which an instance is healthy, between 2 and 10. Defaults to 5.
+	// +kubebuilder:validation:Minimum=2
+	// +kubebuilder:validation:Maximum=10
+	// +optional
+	HealthyThreshold int64 `json:"healthyThreshold,omitempty"`
+
+	// UnhealthyThreshold is the number of consecutive failed health checks
+	// after which an instance is unhealthy, between 2 and 10. Defaults to 3.
+	// +kubebuilder:validation:Minimum=2
+	// +kubebuilder:validation:Maximum=10
+	// +optional
+	UnhealthyThreshold int64 `json:"unhealthyThreshold,omitempty"`
+}
+
+// Defaults of the control plane classic load balancer health check.
+const (
+	DefaultClassicELBHealthCheckInterval           = 10
+	DefaultClassicELBHealthCheckTimeout            = 5
+	DefaultClassicELBHealthCheckHealthyThreshold   = 5
+	DefaultClassicELBHealthCheckUnhealthyThreshold = 3
+)
+
+// loadBalancerType returns the type of the load balancer, defaulting to classic.
+func (s *AWSLoadBalancerSpec) loadBalancerType() LoadBalancerType {
+	if s.LoadBalancerType == "" {
+		return LoadBalancerTypeClassic
+	}
+	return s.LoadBalancerType
+}
+
+// AdvertisedEndpoint is a control plane endpoint advertised in place of the
+// load balancer's own.
+type AdvertisedEndpoint struct {
+	// Host is the DNS name or IP address to advertise.
+	Host string `json:"host"`
+
+	// Port is the port to advertise. Defaults to the API server port.
+	// +optional
+	Port int32 `json:"port,omitempty"`
+}
+
+// IngressSource is a source of traffic allowed to reach a load balancer.
+// Exactly one of the fields must be set.
+type IngressSource struct {
+	// CidrBlock is an IPv4 CIDR block to allow access from, e.g. 10.0.0.0/8.
+	// +optional
+	CidrBlock string `json:"cidrBlock,omitempty"`
+
+	// PrefixListID is the ID of a prefix list to allow access from.
+	// +optional
+	PrefixListID string `json:"prefixListId,omitempty"`
+
+	// SecurityGroupID is the ID of a security group to allow access from,
+	// e.g. a security group of a peered VPC.
+	// +optional
+	SecurityGroupID string `json:"securityGroupId,omitempty"`
 }
 
 // AWSClusterStatus defines the observed state of AWSCluster
@@ -77,6 +392,30 @@ type AWSClusterStatus struct {
 	FailureDomains clusterv1.FailureDomains `json:"failureDomains,omitempty"`
 	Bastion        Instance                 `json:"bastion,omitempty"`
 	Ready          bool                     `json:"ready"`
+
+	// FailureReason will be set when the cluster configuration needs manual
+	// intervention, e.g. when a provided network fails validation, and
+	// contains a succinct value suitable for machine interpretation. It is
+	// cleared once the problem is resolved.
+	// +optional
+	FailureReason *errors.ClusterStatusError `json:"failureReason,omitempty"`
+
+	// FailureMessage will be set along with FailureReason and contains a more
+	// verbose string suitable for logging and human consumption.
+	// +optional
+	FailureMessage *string `json:"failureMessage,omitempty"`
+
+	// CredentialsFailureReason is set while the credentials of the cluster,
+	// from assumeRole or identityRef, can't be used, e.g. AssumeRoleFailed.
+	// Unlike FailureReason it doesn't fail the cluster: the credentials are
+	// tried again periodically and it is cleared once they can be used.
+	// +optional
+	CredentialsFailureReason string `json:"credentialsFailureReason,omitempty"`
+
+	// CredentialsFailureMessage is set along with CredentialsFailureReason and
+	// explains why the credentials can't be used.
+	// +optional
+	CredentialsFailureMessage string `json:"credentialsFailureMessage,omitempty"`
 }
 
 // +kubebuilder:object:root=true
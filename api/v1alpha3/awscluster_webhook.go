@@ -17,8 +17,21 @@ limitations under the License.
 package v1alpha3
 
 import (
+	"fmt"
+	"net"
+	"reflect"
+	"regexp"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws/arn"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/apimachinery/pkg/util/validation/field"
 	ctrl "sigs.k8s.io/controller-runtime"
 	logf "sigs.k8s.io/controller-runtime/pkg/runtime/log"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
 )
 
 // log is for logging in this package.
@@ -29,3 +42,781 @@ func (r *AWSCluster) SetupWebhookWithManager(mgr ctrl.Manager) error {
 		For(r).
 		Complete()
 }
+
+// +kubebuilder:webhook:verbs=create;update,path=/validate-infrastructure-cluster-x-k8s-io-v1alpha3-awscluster,mutating=false,failurePolicy=fail,groups=infrastructure.cluster.x-k8s.io,resources=awsclusters,versions=v1alpha3,name=validation.awscluster.infrastructure.cluster.x-k8s.io
+
+var _ webhook.Validator = &AWSCluster{}
+
+// ValidateCreate implements webhook.Validator so a webhook will be registered for the type
+func (r *AWSCluster) ValidateCreate() error {
+	return r.validateSpec()
+}
+
+// ValidateUpdate implements webhook.Validator so a webhook will be registered for the type
+func (r *AWSCluster) ValidateUpdate(old runtime.Object) error {
+	if oldCluster, ok := old.(*AWSCluster); ok && oldCluster.Spec.ControlPlaneEndpoint.Host != "" {
+		var allErrs field.ErrorList
+
+		oldLB, newLB := &AWSLoadBalancerSpec{}, &AWSLoadBalancerSpec{}
+		if oldCluster.Spec.ControlPlaneLoadBalancer != nil {
+			oldLB = oldCluster.Spec.ControlPlaneLoadBalancer
+		}
+		if r.Spec.ControlPlaneLoadBalancer != nil {
+			newLB = r.Spec.ControlPlaneLoadBalancer
+		}
+
+		if !reflect.DeepEqual(oldLB.AdvertisedEndpoint, newLB.AdvertisedEndpoint) {
+			allErrs = append(allErrs, field.Forbidden(field.NewPath("spec", "controlPlaneLoadBalancer", "advertisedEndpoint"), "cannot be modified once the control plane endpoint is set"))
+		}
+
+		// Migrating to another load balancer type changes the load balancer DNS
+		// name, only the advertised endpoint stays the same.
+		if oldLB.loadBalancerType() != newLB.loadBalancerType() {
+			fldPath := field.NewPath("spec", "controlPlaneLoadBalancer", "loadBalancerType")
+			_, migrate := r.GetAnnotations()[MigrateLoadBalancerAnnotation]
+			switch {
+			case !migrate:
+				allErrs = append(allErrs, field.Forbidden(fldPath, fmt.Sprintf("cannot be modified once the control plane endpoint is set, unless the %q annotation is set", MigrateLoadBalancerAnnotation)))
+			case oldLB.loadBalancerType() != LoadBalancerTypeClassic || newLB.loadBalancerType() != LoadBalancerTypeNLB:
+				allErrs = append(allErrs, field.Forbidden(fldPath, "can only be migrated from a classic to a network load balancer"))
+			case newLB.ARN != nil:
+				allErrs = append(allErrs, field.Forbidden(fldPath, "cannot be migrated for an existing load balancer"))
+			case newLB.AdvertisedEndpoint == nil:
+				allErrs = append(allErrs, field.Forbidden(fldPath, "can only be migrated with an advertised endpoint, which keeps the control plane endpoint"))
+			}
+		}
+
+		if !reflect.DeepEqual(oldLB.ARN, newLB.ARN) {
+			allErrs = append(allErrs, field.Forbidden(field.NewPath("spec", "controlPlaneLoadBalancer", "arn"), "cannot be modified once the control plane endpoint is set"))
+		}
+
+		if len(allErrs) > 0 {
+			return apierrors.NewInvalid(GroupVersion.WithKind("AWSCluster").GroupKind(), r.Name, allErrs)
+		}
+	}
+
+	// The attachment is only looked up while the transit gateway is
+	// configured, it would be left behind and block the VPC deletion.
+	if oldCluster, ok := old.(*AWSCluster); ok && oldCluster.Spec.NetworkSpec.TransitGateway != nil && r.Spec.NetworkSpec.TransitGateway == nil {
+		return apierrors.NewInvalid(GroupVersion.WithKind("AWSCluster").GroupKind(), r.Name, field.ErrorList{
+			field.Forbidden(field.NewPath("spec", "networkSpec", "transitGateway"), "cannot be removed once set"),
+		})
+	}
+
+	// Same for the peering connection and the routes added to the peer VPC.
+	if oldCluster, ok := old.(*AWSCluster); ok && oldCluster.Spec.NetworkSpec.VPCPeering != nil && r.Spec.NetworkSpec.VPCPeering == nil {
+		return apierrors.NewInvalid(GroupVersion.WithKind("AWSCluster").GroupKind(), r.Name, field.ErrorList{
+			field.Forbidden(field.NewPath("spec", "networkSpec", "vpcPeering"), "cannot be removed once set"),
+		})
+	}
+	return r.validateSpec()
+}
+
+// ValidateDelete implements webhook.Validator so a webhook will be registered for the type
+func (r *AWSCluster) ValidateDelete() error {
+	return nil
+}
+
+func (r *AWSCluster) validateSpec() error {
+	var allErrs field.ErrorList
+
+	if lb := r.Spec.ControlPlaneLoadBalancer; lb != nil {
+		allErrs = append(allErrs, validateIngressSources(lb.IngressSources, field.NewPath("spec", "controlPlaneLoadBalancer", "ingressSources"))...)
+		if lb.ARN != nil {
+			allErrs = append(allErrs, validateLoadBalancerARN(lb, field.NewPath("spec", "controlPlaneLoadBalancer"))...)
+		}
+		if lb.AdvertisedEndpoint != nil {
+			allErrs = append(allErrs, validateAdvertisedEndpoint(lb.AdvertisedEndpoint, field.NewPath("spec", "controlPlaneLoadBalancer", "advertisedEndpoint"))...)
+		}
+		if lb.HealthCheck != nil {
+			fldPath := field.NewPath("spec", "controlPlaneLoadBalancer", "healthCheck")
+			if lb.loadBalancerType() != LoadBalancerTypeClassic {
+				allErrs = append(allErrs, field.Forbidden(fldPath, "is only supported for classic load balancers"))
+			}
+			allErrs = append(allErrs, validateClassicELBHealthCheck(lb.HealthCheck, fldPath)...)
+		}
+		if lb.ProxyProtocol && lb.loadBalancerType() != LoadBalancerTypeClassic {
+			allErrs = append(allErrs, field.Forbidden(field.NewPath("spec", "controlPlaneLoadBalancer", "proxyProtocol"), "is only supported for classic load balancers"))
+		}
+		if lb.IdleTimeoutSeconds != 0 && lb.loadBalancerType() != LoadBalancerTypeClassic {
+			allErrs = append(allErrs, field.Forbidden(field.NewPath("spec", "controlPlaneLoadBalancer", "idleTimeoutSeconds"), "is only supported for classic load balancers"))
+		}
+		if lb.DeletionProtection && lb.loadBalancerType() != LoadBalancerTypeNLB {
+			allErrs = append(allErrs, field.Forbidden(field.NewPath("spec", "controlPlaneLoadBalancer", "deletionProtection"), "is only supported for network load balancers"))
+		}
+		if lb.DNSRecord != nil {
+			allErrs = append(allErrs, validateDNSRecord(lb.DNSRecord, field.NewPath("spec", "controlPlaneLoadBalancer", "dnsRecord"))...)
+		}
+	}
+
+	if acl := r.Spec.NetworkSpec.NetworkACL; acl != nil {
+		allErrs = append(allErrs, validateNetworkACLRules(acl.Rules, field.NewPath("spec", "networkSpec", "networkACL", "rules"))...)
+	}
+
+	if ingress := r.Spec.NetworkSpec.NodeIngress; ingress != nil {
+		fldPath := field.NewPath("spec", "networkSpec", "nodeIngress")
+		if ingress.Kubelet != nil {
+			allErrs = append(allErrs, validateNodeIngressRule(ingress.Kubelet, fldPath.Child("kubelet"))...)
+		}
+		if ingress.NodePort != nil {
+			allErrs = append(allErrs, validateNodeIngressRule(ingress.NodePort, fldPath.Child("nodePort"))...)
+		}
+	}
+
+	if r.Spec.SessionManager && r.Spec.Bastion.Enabled != nil && *r.Spec.Bastion.Enabled {
+		allErrs = append(allErrs, field.Forbidden(field.NewPath("spec", "bastion", "enabled"), "cannot be true when sessionManager is enabled"))
+	}
+
+	if r.Spec.Bastion.ElasticIPAllocationID != "" && !r.Spec.Bastion.IsPublic() {
+		allErrs = append(allErrs, field.Forbidden(field.NewPath("spec", "bastion", "elasticIPAllocationID"), "cannot be set when publicIP is false"))
+	}
+
+	for i, cidr := range r.Spec.Bastion.AllowedCIDRBlocks {
+		if ip, _, err := net.ParseCIDR(cidr); err != nil || ip.To4() == nil {
+			allErrs = append(allErrs, field.Invalid(field.NewPath("spec", "bastion", "allowedCIDRBlocks").Index(i), cidr, "must be an IPv4 CIDR block"))
+		}
+	}
+
+	if r.Spec.AssumeRole != nil {
+		allErrs = append(allErrs, validateAssumeRole(r.Spec.AssumeRole, field.NewPath("spec", "assumeRole"))...)
+	}
+
+	if r.Spec.IdentityRef != nil && r.Spec.AssumeRole != nil {
+		allErrs = append(allErrs, field.Forbidden(field.NewPath("spec", "identityRef"), "cannot be set together with assumeRole"))
+	}
+
+	allErrs = append(allErrs, validateVPCCidrBlocks(&r.Spec.NetworkSpec.VPC, field.NewPath("spec", "networkSpec", "vpc"))...)
+	allErrs = append(allErrs, validatePodSubnets(&r.Spec.NetworkSpec, field.NewPath("spec", "networkSpec", "subnets"))...)
+	allErrs = append(allErrs, validateVPCEndpoints(r.Spec.NetworkSpec.VPCEndpoints, field.NewPath("spec", "networkSpec", "vpcEndpoints"))...)
+	if r.Spec.NetworkSpec.DHCPOptions != nil {
+		allErrs = append(allErrs, validateDHCPOptions(r.Spec.NetworkSpec.DHCPOptions, field.NewPath("spec", "networkSpec", "dhcpOptions"))...)
+	}
+	if r.Spec.NetworkSpec.TransitGateway != nil {
+		allErrs = append(allErrs, validateTransitGateway(&r.Spec.NetworkSpec, field.NewPath("spec", "networkSpec", "transitGateway"))...)
+	}
+	if r.Spec.NetworkSpec.VPCPeering != nil {
+		allErrs = append(allErrs, validateVPCPeering(&r.Spec.NetworkSpec, r.Spec.Region, field.NewPath("spec", "networkSpec", "vpcPeering"))...)
+	}
+	allErrs = append(allErrs, validateImageLookupFormat(r.Spec.ImageLookupFormat, field.NewPath("spec", "imageLookupFormat"))...)
+
+	if r.Spec.NetworkSpec.IsShared() {
+		allErrs = append(allErrs, validateSharedNetwork(&r.Spec.NetworkSpec, field.NewPath("spec", "networkSpec"))...)
+	}
+
+	if r.Spec.NetworkSpec.VPC.ExternallyManaged {
+		allErrs = append(allErrs, validateExternallyManagedNetwork(&r.Spec.NetworkSpec, field.NewPath("spec", "networkSpec"))...)
+	}
+
+	if len(allErrs) == 0 {
+		return nil
+	}
+	return apierrors.NewInvalid(GroupVersion.WithKind("AWSCluster").GroupKind(), r.Name, allErrs)
+}
+
+var (
+	// reExternalID and reRoleSessionName match the characters STS accepts in
+	// the external ID and the role session name of an AssumeRole call. The
+	// length of the external ID is checked separately, as it exceeds the
+	// repeat limit of regexp.
+	reExternalID      = regexp.MustCompile(`^[\w+=,.@:/-]+$`)
+	reRoleSessionName = regexp.MustCompile(`^[\w+=,.@-]{2,64}$`)
+
+	// reAccountID matches an AWS account ID.
+	reAccountID = regexp.MustCompile(`^\d{12}$`)
+
+	// privateCidrBlocks are the RFC 1918 private address ranges.
+	privateCidrBlocks = []string{"10.0.0.0/8", "172.16.0.0/12", "192.168.0.0/16"}
+)
+
+// RequirePrivateVPCCidrBlocks makes the AWSCluster webhook reject VPC CIDR
+// blocks outside of the RFC 1918 private address ranges.
+var RequirePrivateVPCCidrBlocks bool
+
+func validateAssumeRole(spec *AssumeRoleSpec, fldPath *field.Path) field.ErrorList {
+	var allErrs field.ErrorList
+
+	if spec.RoleARN == "" {
+		allErrs = append(allErrs, field.Required(fldPath.Child("roleARN"), "must be set"))
+	} else if a, err := arn.Parse(spec.RoleARN); err != nil || a.Service != "iam" || !strings.HasPrefix(a.Resource, "role/") {
+		allErrs = append(allErrs, field.Invalid(fldPath.Child("roleARN"), spec.RoleARN, "must be the ARN of an IAM role"))
+	}
+
+	if spec.ExternalID != "" && (len(spec.ExternalID) < 2 || len(spec.ExternalID) > 1224 || !reExternalID.MatchString(spec.ExternalID)) {
+		allErrs = append(allErrs, field.Invalid(fldPath.Child("externalID"), spec.ExternalID, "must be 2 to 1224 characters of letters, digits and +=,.@:/-_"))
+	}
+
+	if spec.SessionName != "" && !reRoleSessionName.MatchString(spec.SessionName) {
+		allErrs = append(allErrs, field.Invalid(fldPath.Child("sessionName"), spec.SessionName, "must be 2 to 64 characters of letters, digits and +=,.@-_"))
+	}
+
+	return allErrs
+}
+
+// validateLoadBalancerARN checks the ARN references a load balancer of the
+// type of the spec, and that nothing configuring the load balancer itself is
+// set, since an existing load balancer is never modified.
+func validateLoadBalancerARN(lb *AWSLoadBalancerSpec, fldPath *field.Path) field.ErrorList {
+	var allErrs field.ErrorList
+
+	a, err := arn.Parse(*lb.ARN)
+	if err != nil || a.Service != "elasticloadbalancing" || !strings.HasPrefix(a.Resource, "loadbalancer/") {
+		allErrs = append(allErrs, field.Invalid(fldPath.Child("arn"), *lb.ARN, "must be the ARN of a load balancer"))
+	} else {
+		lbType := LoadBalancerTypeClassic
+		if strings.HasPrefix(a.Resource, "loadbalancer/net/") {
+			lbType = LoadBalancerTypeNLB
+		}
+		if strings.HasPrefix(a.Resource, "loadbalancer/app/") {
+			allErrs = append(allErrs, field.Invalid(fldPath.Child("arn"), *lb.ARN, "application load balancers are not supported"))
+		} else if lbType != lb.loadBalancerType() {
+			allErrs = append(allErrs, field.Invalid(fldPath.Child("arn"), *lb.ARN, fmt.Sprintf("must be the ARN of a load balancer of type %q", lb.loadBalancerType())))
+		}
+	}
+
+	if lb.Scheme != nil {
+		allErrs = append(allErrs, field.Forbidden(fldPath.Child("scheme"), "cannot be set with an existing load balancer"))
+	}
+	if lb.HealthCheck != nil {
+		allErrs = append(allErrs, field.Forbidden(fldPath.Child("healthCheck"), "cannot be set with an existing load balancer"))
+	}
+	if lb.ProxyProtocol {
+		allErrs = append(allErrs, field.Forbidden(fldPath.Child("proxyProtocol"), "cannot be set with an existing load balancer"))
+	}
+	if lb.IdleTimeoutSeconds != 0 {
+		allErrs = append(allErrs, field.Forbidden(fldPath.Child("idleTimeoutSeconds"), "cannot be set with an existing load balancer"))
+	}
+	if lb.CrossZoneLoadBalancing {
+		allErrs = append(allErrs, field.Forbidden(fldPath.Child("crossZoneLoadBalancing"), "cannot be set with an existing load balancer"))
+	}
+	if lb.DeletionProtection {
+		allErrs = append(allErrs, field.Forbidden(fldPath.Child("deletionProtection"), "cannot be set with an existing load balancer"))
+	}
+
+	return allErrs
+}
+
+func validateVPCEndpoints(endpoints []VPCEndpointSpec, fldPath *field.Path) field.ErrorList {
+	var allErrs field.ErrorList
+
+	// An endpoint is identified by its service, the VPC can't have two of them.
+	seen := sets.NewString()
+	for i, endpoint := range endpoints {
+		idxPath := fldPath.Index(i)
+
+		switch {
+		case endpoint.Service == "":
+			allErrs = append(allErrs, field.Required(idxPath.Child("service"), "must be set"))
+		case len(validation.IsDNS1123Subdomain(endpoint.Service)) > 0:
+			allErrs = append(allErrs, field.Invalid(idxPath.Child("service"), endpoint.Service, "must be the name of an AWS service, e.g. \"s3\" or \"ecr.api\""))
+		case seen.Has(endpoint.Service):
+			allErrs = append(allErrs, field.Duplicate(idxPath.Child("service"), endpoint.Service))
+		}
+		seen.Insert(endpoint.Service)
+
+		if endpoint.Type != VPCEndpointTypeGateway && endpoint.Type != VPCEndpointTypeInterface {
+			allErrs = append(allErrs, field.NotSupported(idxPath.Child("type"), endpoint.Type, []string{
+				string(VPCEndpointTypeGateway), string(VPCEndpointTypeInterface),
+			}))
+		}
+
+		if len(endpoint.SecurityGroups) > 0 && endpoint.Type != VPCEndpointTypeInterface {
+			allErrs = append(allErrs, field.Forbidden(idxPath.Child("securityGroups"), "can only be set for interface endpoints"))
+		}
+		for j, ref := range endpoint.SecurityGroups {
+			if ref.ID == nil && len(ref.Filters) == 0 {
+				allErrs = append(allErrs, field.Required(idxPath.Child("securityGroups").Index(j), "either id or filters must be set"))
+			}
+		}
+	}
+
+	return allErrs
+}
+
+func validateDHCPOptions(spec *DHCPOptionsSpec, fldPath *field.Path) field.ErrorList {
+	var allErrs field.ErrorList
+
+	if spec.DomainName == "" && len(spec.DomainNameServers) == 0 && len(spec.NTPServers) == 0 {
+		allErrs = append(allErrs, field.Required(fldPath, "at least one of domainName, domainNameServers or ntpServers must be set"))
+	}
+
+	if spec.DomainName != "" {
+		for _, msg := range validation.IsDNS1123Subdomain(spec.DomainName) {
+			allErrs = append(allErrs, field.Invalid(fldPath.Child("domainName"), spec.DomainName, msg))
+		}
+	}
+
+	for i, server := range spec.DomainNameServers {
+		if server != "AmazonProvidedDNS" && net.ParseIP(server) == nil {
+			allErrs = append(allErrs, field.Invalid(fldPath.Child("domainNameServers").Index(i), server, "must be an IP address or AmazonProvidedDNS"))
+		}
+	}
+
+	for i, server := range spec.NTPServers {
+		if net.ParseIP(server) == nil {
+			allErrs = append(allErrs, field.Invalid(fldPath.Child("ntpServers").Index(i), server, "must be an IP address"))
+		}
+	}
+
+	return allErrs
+}
+
+func validateTransitGateway(spec *NetworkSpec, fldPath *field.Path) field.ErrorList {
+	var allErrs field.ErrorList
+
+	tgw := spec.TransitGateway
+	if !strings.HasPrefix(tgw.ID, "tgw-") {
+		allErrs = append(allErrs, field.Invalid(fldPath.Child("id"), tgw.ID, "must be a transit gateway ID"))
+	}
+
+	seen := sets.NewString()
+	for i, cidr := range tgw.Routes {
+		idxPath := fldPath.Child("routes").Index(i)
+
+		ip, ipNet, err := net.ParseCIDR(cidr)
+		if err != nil || ip.To4() == nil {
+			allErrs = append(allErrs, field.Invalid(idxPath, cidr, "must be an IPv4 CIDR block"))
+			continue
+		}
+
+		if seen.Has(ipNet.String()) {
+			allErrs = append(allErrs, field.Duplicate(idxPath, cidr))
+		}
+		seen.Insert(ipNet.String())
+
+		// The private subnets already route 0.0.0.0/0 through the NAT gateways.
+		if ipNet.String() == "0.0.0.0/0" && spec.NatGatewayMode != NatGatewayModeNone {
+			allErrs = append(allErrs, field.Invalid(idxPath, cidr, "requires natGatewayMode None"))
+		}
+	}
+
+	return allErrs
+}
+
+func validateVPCPeering(spec *NetworkSpec, region string, fldPath *field.Path) field.ErrorList {
+	var allErrs field.ErrorList
+
+	peering := spec.VPCPeering
+	if !strings.HasPrefix(peering.PeerVPCID, "vpc-") {
+		allErrs = append(allErrs, field.Invalid(fldPath.Child("peerVpcId"), peering.PeerVPCID, "must be a VPC ID"))
+	}
+	if peering.PeerOwnerID != "" && !reAccountID.MatchString(peering.PeerOwnerID) {
+		allErrs = append(allErrs, field.Invalid(fldPath.Child("peerOwnerId"), peering.PeerOwnerID, "must be a 12 digit AWS account ID"))
+	}
+
+	var vpcNet *net.IPNet
+	if spec.VPC.CidrBlock != "" {
+		_, vpcNet, _ = net.ParseCIDR(spec.VPC.CidrBlock)
+	}
+	seen := sets.NewString()
+	for i, cidr := range peering.PeerCidrBlocks {
+		idxPath := fldPath.Child("peerCidrBlocks").Index(i)
+
+		ip, ipNet, err := net.ParseCIDR(cidr)
+		if err != nil || ip.To4() == nil {
+			allErrs = append(allErrs, field.Invalid(idxPath, cidr, "must be an IPv4 CIDR block"))
+			continue
+		}
+
+		if seen.Has(ipNet.String()) {
+			allErrs = append(allErrs, field.Duplicate(idxPath, cidr))
+		}
+		seen.Insert(ipNet.String())
+
+		// AWS refuses to peer VPCs with overlapping CIDR blocks.
+		if vpcNet != nil && (vpcNet.Contains(ipNet.IP) || ipNet.Contains(vpcNet.IP)) {
+			allErrs = append(allErrs, field.Invalid(idxPath, cidr, fmt.Sprintf("overlaps %s", spec.VPC.CidrBlock)))
+		}
+	}
+
+	// The route tables of a peer VPC in another account or region can't be
+	// modified with the credentials of the cluster.
+	crossAccount := peering.PeerOwnerID != "" || (peering.PeerRegion != "" && peering.PeerRegion != region)
+	for i, id := range peering.PeerRouteTableIDs {
+		idxPath := fldPath.Child("peerRouteTableIds").Index(i)
+		if crossAccount {
+			allErrs = append(allErrs, field.Forbidden(idxPath, "cannot be set when peering with another account or region"))
+			break
+		}
+		if !strings.HasPrefix(id, "rtb-") {
+			allErrs = append(allErrs, field.Invalid(idxPath, id, "must be a route table ID"))
+		}
+	}
+
+	return allErrs
+}
+
+func validateSharedNetwork(spec *NetworkSpec, fldPath *field.Path) field.ErrorList {
+	var allErrs field.ErrorList
+
+	seen := sets.NewString()
+	private := 0
+	for i, ref := range spec.SharedSubnets {
+		idxPath := fldPath.Child("sharedSubnets").Index(i)
+
+		if strings.HasPrefix(ref.ID, "arn:") {
+			if a, err := arn.Parse(ref.ID); err != nil || a.Service != "ec2" || !strings.HasPrefix(a.Resource, "subnet/") {
+				allErrs = append(allErrs, field.Invalid(idxPath.Child("id"), ref.ID, "must be the ARN of a subnet"))
+				continue
+			}
+		} else if !strings.HasPrefix(ref.ID, "subnet-") {
+			allErrs = append(allErrs, field.Invalid(idxPath.Child("id"), ref.ID, "must be a subnet ID or ARN"))
+			continue
+		}
+
+		// The same subnet may be referenced once by ID and once by ARN.
+		if seen.Has(ref.SubnetID()) {
+			allErrs = append(allErrs, field.Duplicate(idxPath.Child("id"), ref.ID))
+		}
+		seen.Insert(ref.SubnetID())
+
+		if !ref.IsPublic {
+			private++
+		}
+	}
+	if private == 0 {
+		allErrs = append(allErrs, field.Invalid(fldPath.Child("sharedSubnets"), len(spec.SharedSubnets), "at least one private subnet is required"))
+	}
+
+	// The VPC of a shared network belongs to another account, the cluster
+	// can't modify it.
+	if len(spec.VPC.SecondaryCidrBlocks) > 0 {
+		allErrs = append(allErrs, field.Forbidden(fldPath.Child("vpc", "secondaryCidrBlocks"), "cannot be set together with sharedSubnets"))
+	}
+	if spec.NetworkACL != nil {
+		allErrs = append(allErrs, field.Forbidden(fldPath.Child("networkACL"), "cannot be set together with sharedSubnets"))
+	}
+	if len(spec.VPCEndpoints) > 0 {
+		allErrs = append(allErrs, field.Forbidden(fldPath.Child("vpcEndpoints"), "cannot be set together with sharedSubnets"))
+	}
+	if spec.DHCPOptions != nil {
+		allErrs = append(allErrs, field.Forbidden(fldPath.Child("dhcpOptions"), "cannot be set together with sharedSubnets"))
+	}
+	if spec.TransitGateway != nil {
+		allErrs = append(allErrs, field.Forbidden(fldPath.Child("transitGateway"), "cannot be set together with sharedSubnets"))
+	}
+	if spec.VPCPeering != nil {
+		allErrs = append(allErrs, field.Forbidden(fldPath.Child("vpcPeering"), "cannot be set together with sharedSubnets"))
+	}
+	if spec.PublicIPv4Pool != "" {
+		allErrs = append(allErrs, field.Forbidden(fldPath.Child("publicIpv4Pool"), "cannot be set together with sharedSubnets"))
+	}
+
+	return allErrs
+}
+
+// validateExternallyManagedNetwork checks nothing that would modify the VPC is
+// configured, since an externally managed VPC is never modified.
+func validateExternallyManagedNetwork(spec *NetworkSpec, fldPath *field.Path) field.ErrorList {
+	var allErrs field.ErrorList
+
+	vpcPath := fldPath.Child("vpc")
+	if spec.VPC.ID == "" {
+		allErrs = append(allErrs, field.Required(vpcPath.Child("id"), "must be set when externallyManaged is true"))
+	}
+	if len(spec.VPC.SecondaryCidrBlocks) > 0 {
+		allErrs = append(allErrs, field.Forbidden(vpcPath.Child("secondaryCidrBlocks"), "cannot be set for an externally managed VPC"))
+	}
+	if spec.VPC.EnableIPv6 {
+		allErrs = append(allErrs, field.Forbidden(vpcPath.Child("enableIPv6"), "cannot be set for an externally managed VPC"))
+	}
+	if spec.NetworkACL != nil {
+		allErrs = append(allErrs, field.Forbidden(fldPath.Child("networkACL"), "cannot be set for an externally managed VPC"))
+	}
+	if len(spec.VPCEndpoints) > 0 {
+		allErrs = append(allErrs, field.Forbidden(fldPath.Child("vpcEndpoints"), "cannot be set for an externally managed VPC"))
+	}
+	if spec.DHCPOptions != nil {
+		allErrs = append(allErrs, field.Forbidden(fldPath.Child("dhcpOptions"), "cannot be set for an externally managed VPC"))
+	}
+	if spec.TransitGateway != nil {
+		allErrs = append(allErrs, field.Forbidden(fldPath.Child("transitGateway"), "cannot be set for an externally managed VPC"))
+	}
+	if spec.VPCPeering != nil {
+		allErrs = append(allErrs, field.Forbidden(fldPath.Child("vpcPeering"), "cannot be set for an externally managed VPC"))
+	}
+
+	return allErrs
+}
+
+func validateClassicELBHealthCheck(hc *ClassicELBHealthCheckSpec, fldPath *field.Path) field.ErrorList {
+	var allErrs field.ErrorList
+
+	switch hc.Protocol {
+	case "", ClassicELBProtocolTCP, ClassicELBProtocolSSL, ClassicELBProtocolHTTPS:
+	default:
+		allErrs = append(allErrs, field.NotSupported(fldPath.Child("protocol"), hc.Protocol, []string{
+			string(ClassicELBProtocolTCP), string(ClassicELBProtocolSSL), string(ClassicELBProtocolHTTPS),
+		}))
+	}
+
+	// Unset values keep their default, the others must be in the ranges AWS accepts.
+	ranges := []struct {
+		name     string
+		value    int64
+		min, max int64
+	}{
+		{"intervalSeconds", hc.IntervalSeconds, 5, 300},
+		{"timeoutSeconds", hc.TimeoutSeconds, 2, 60},
+		{"healthyThreshold", hc.HealthyThreshold, 2, 10},
+		{"unhealthyThreshold", hc.UnhealthyThreshold, 2, 10},
+	}
+	for _, r := range ranges {
+		if r.value != 0 && (r.value < r.min || r.value > r.max) {
+			allErrs = append(allErrs, field.Invalid(fldPath.Child(r.name), r.value, fmt.Sprintf("must be between %d and %d", r.min, r.max)))
+		}
+	}
+
+	interval, timeout := hc.IntervalSeconds, hc.TimeoutSeconds
+	if interval == 0 {
+		interval = DefaultClassicELBHealthCheckInterval
+	}
+	if timeout == 0 {
+		timeout = DefaultClassicELBHealthCheckTimeout
+	}
+	if timeout >= interval {
+		allErrs = append(allErrs, field.Invalid(fldPath.Child("timeoutSeconds"), timeout, fmt.Sprintf("must be less than the interval of %d seconds", interval)))
+	}
+
+	return allErrs
+}
+
+func validateAdvertisedEndpoint(endpoint *AdvertisedEndpoint, fldPath *field.Path) field.ErrorList {
+	var allErrs field.ErrorList
+
+	// The host is handed to clients as is, so reject URLs and host:port pairs.
+	if endpoint.Host == "" {
+		allErrs = append(allErrs, field.Required(fldPath.Child("host"), "must be set"))
+	} else if net.ParseIP(endpoint.Host) == nil && len(validation.IsDNS1123Subdomain(endpoint.Host)) > 0 {
+		allErrs = append(allErrs, field.Invalid(fldPath.Child("host"), endpoint.Host, "must be a DNS name or an IP address"))
+	}
+
+	if endpoint.Port < 0 || endpoint.Port > 65535 {
+		allErrs = append(allErrs, field.Invalid(fldPath.Child("port"), endpoint.Port, "must be between 1 and 65535"))
+	}
+
+	return allErrs
+}
+
+func validateDNSRecord(spec *DNSRecordSpec, fldPath *field.Path) field.ErrorList {
+	var allErrs field.ErrorList
+
+	if spec.HostedZoneID == "" {
+		allErrs = append(allErrs, field.Required(fldPath.Child("hostedZoneId"), "must be set"))
+	}
+
+	// Route53 accepts fully qualified names with a trailing dot.
+	if spec.Name == "" {
+		allErrs = append(allErrs, field.Required(fldPath.Child("name"), "must be set"))
+	} else {
+		for _, msg := range validation.IsDNS1123Subdomain(strings.TrimSuffix(spec.Name, ".")) {
+			allErrs = append(allErrs, field.Invalid(fldPath.Child("name"), spec.Name, msg))
+		}
+	}
+
+	return allErrs
+}
+
+func validateIngressSources(sources []IngressSource, fldPath *field.Path) field.ErrorList {
+	var allErrs field.ErrorList
+
+	seen := map[IngressSource]bool{}
+	for i, source := range sources {
+		idxPath := fldPath.Index(i)
+
+		set := 0
+		if source.CidrBlock != "" {
+			set++
+			if ip, _, err := net.ParseCIDR(source.CidrBlock); err != nil || ip.To4() == nil {
+				allErrs = append(allErrs, field.Invalid(idxPath.Child("cidrBlock"), source.CidrBlock, "must be an IPv4 CIDR block"))
+			}
+		}
+		if source.PrefixListID != "" {
+			set++
+			if !strings.HasPrefix(source.PrefixListID, "pl-") {
+				allErrs = append(allErrs, field.Invalid(idxPath.Child("prefixListId"), source.PrefixListID, "must be a prefix list ID"))
+			}
+		}
+		if source.SecurityGroupID != "" {
+			set++
+			if !strings.HasPrefix(source.SecurityGroupID, "sg-") {
+				allErrs = append(allErrs, field.Invalid(idxPath.Child("securityGroupId"), source.SecurityGroupID, "must be a security group ID"))
+			}
+		}
+		if set != 1 {
+			allErrs = append(allErrs, field.Invalid(idxPath, source, "exactly one of cidrBlock, prefixListId or securityGroupId must be set"))
+			continue
+		}
+
+		if seen[source] {
+			allErrs = append(allErrs, field.Duplicate(idxPath, source))
+		}
+		seen[source] = true
+	}
+
+	return allErrs
+}
+
+func validateNetworkACLRules(rules []NetworkACLRule, fldPath *field.Path) field.ErrorList {
+	var allErrs field.ErrorList
+
+	// Rule numbers must be unique and listed in ascending order for each direction,
+	// so the rules read in the order they are evaluated.
+	lastRuleNumber := map[bool]int64{}
+	for i, rule := range rules {
+		idxPath := fldPath.Index(i)
+
+		switch last := lastRuleNumber[rule.Egress]; {
+		case rule.RuleNumber < 1 || rule.RuleNumber > 32766:
+			allErrs = append(allErrs, field.Invalid(idxPath.Child("ruleNumber"), rule.RuleNumber, "must be between 1 and 32766"))
+		case rule.RuleNumber == last:
+			allErrs = append(allErrs, field.Duplicate(idxPath.Child("ruleNumber"), rule.RuleNumber))
+		case rule.RuleNumber < last:
+			allErrs = append(allErrs, field.Invalid(idxPath.Child("ruleNumber"), rule.RuleNumber, "rules must be listed in ascending rule number order"))
+		default:
+			lastRuleNumber[rule.Egress] = rule.RuleNumber
+		}
+
+		switch rule.Protocol {
+		case SecurityGroupProtocolTCP, SecurityGroupProtocolUDP:
+			if rule.FromPort < 0 || rule.FromPort > 65535 {
+				allErrs = append(allErrs, field.Invalid(idxPath.Child("fromPort"), rule.FromPort, "must be between 0 and 65535"))
+			}
+			if rule.ToPort < rule.FromPort || rule.ToPort > 65535 {
+				allErrs = append(allErrs, field.Invalid(idxPath.Child("toPort"), rule.ToPort, "must be between fromPort and 65535"))
+			}
+		case SecurityGroupProtocolAll, SecurityGroupProtocolICMP:
+		default:
+			allErrs = append(allErrs, field.NotSupported(idxPath.Child("protocol"), rule.Protocol, []string{
+				string(SecurityGroupProtocolAll), string(SecurityGroupProtocolTCP), string(SecurityGroupProtocolUDP), string(SecurityGroupProtocolICMP),
+			}))
+		}
+
+		if ip, _, err := net.ParseCIDR(rule.CidrBlock); err != nil || ip.To4() == nil {
+			allErrs = append(allErrs, field.Invalid(idxPath.Child("cidrBlock"), rule.CidrBlock, "must be an IPv4 CIDR block"))
+		}
+
+		if rule.Action != NetworkACLRuleActionAllow && rule.Action != NetworkACLRuleActionDeny {
+			allErrs = append(allErrs, field.NotSupported(idxPath.Child("action"), rule.Action, []string{
+				string(NetworkACLRuleActionAllow), string(NetworkACLRuleActionDeny),
+			}))
+		}
+	}
+
+	return allErrs
+}
+
+func validateNodeIngressRule(rule *NodeIngressRule, fldPath *field.Path) field.ErrorList {
+	var allErrs field.ErrorList
+
+	// Ports are either both unset, to keep the default range, or a valid range.
+	if rule.FromPort != 0 || rule.ToPort != 0 {
+		if rule.FromPort < 1 || rule.FromPort > 65535 {
+			allErrs = append(allErrs, field.Invalid(fldPath.Child("fromPort"), rule.FromPort, "must be between 1 and 65535"))
+		}
+		if rule.ToPort < rule.FromPort || rule.ToPort > 65535 {
+			allErrs = append(allErrs, field.Invalid(fldPath.Child("toPort"), rule.ToPort, "must be between fromPort and 65535"))
+		}
+	}
+
+	for i, cidr := range rule.CidrBlocks {
+		if ip, _, err := net.ParseCIDR(cidr); err != nil || ip.To4() == nil {
+			allErrs = append(allErrs, field.Invalid(fldPath.Child("cidrBlocks").Index(i), cidr, "must be an IPv4 CIDR block"))
+		}
+	}
+
+	for i, id := range rule.SourceSecurityGroupIDs {
+		if !strings.HasPrefix(id, "sg-") {
+			allErrs = append(allErrs, field.Invalid(fldPath.Child("sourceSecurityGroupIds").Index(i), id, "must be a security group ID"))
+		}
+	}
+
+	return allErrs
+}
+
+func validateVPCCidrBlocks(vpc *VPCSpec, fldPath *field.Path) field.ErrorList {
+	var allErrs field.ErrorList
+
+	type cidrBlock struct {
+		value string
+		net   *net.IPNet
+	}
+	blocks := []cidrBlock{}
+	add := func(path *field.Path, value string) {
+		ip, ipNet, err := net.ParseCIDR(value)
+		if err != nil || ip.To4() == nil {
+			allErrs = append(allErrs, field.Invalid(path, value, "must be an IPv4 CIDR block"))
+			return
+		}
+		if RequirePrivateVPCCidrBlocks && !isPrivateCidrBlock(ipNet) {
+			allErrs = append(allErrs, field.Invalid(path, value, "must be within the private ranges 10.0.0.0/8, 172.16.0.0/12 or 192.168.0.0/16"))
+		}
+		for _, other := range blocks {
+			if other.net.Contains(ipNet.IP) || ipNet.Contains(other.net.IP) {
+				allErrs = append(allErrs, field.Invalid(path, value, fmt.Sprintf("overlaps %s", other.value)))
+			}
+		}
+		blocks = append(blocks, cidrBlock{value: value, net: ipNet})
+	}
+
+	if vpc.CidrBlock != "" {
+		add(fldPath.Child("cidrBlock"), vpc.CidrBlock)
+	}
+	for i, cidr := range vpc.SecondaryCidrBlocks {
+		add(fldPath.Child("secondaryCidrBlocks").Index(i), cidr)
+	}
+
+	return allErrs
+}
+
+// validatePodSubnets checks the subnets reserved for pods are private and
+// carved out of the secondary CIDR blocks of the VPC.
+func validatePodSubnets(spec *NetworkSpec, fldPath *field.Path) field.ErrorList {
+	var allErrs field.ErrorList
+
+	for i, sn := range spec.Subnets {
+		if !sn.ForPods {
+			continue
+		}
+		idxPath := fldPath.Index(i)
+
+		if sn.IsPublic {
+			allErrs = append(allErrs, field.Forbidden(idxPath.Child("isPublic"), "cannot be true for a subnet reserved for pods"))
+		}
+
+		// Subnets discovered in an existing VPC are checked by AWS already.
+		if sn.ID != "" {
+			continue
+		}
+		ip, ipNet, err := net.ParseCIDR(sn.CidrBlock)
+		if err != nil || ip.To4() == nil {
+			allErrs = append(allErrs, field.Invalid(idxPath.Child("cidrBlock"), sn.CidrBlock, "must be an IPv4 CIDR block"))
+			continue
+		}
+		within := false
+		for _, cidr := range spec.VPC.SecondaryCidrBlocks {
+			if _, secondary, err := net.ParseCIDR(cidr); err == nil && secondary.Contains(ipNet.IP) {
+				within = true
+				break
+			}
+		}
+		if !within {
+			allErrs = append(allErrs, field.Invalid(idxPath.Child("cidrBlock"), sn.CidrBlock, "must be within one of the secondary CIDR blocks of the VPC"))
+		}
+	}
+
+	return allErrs
+}
+
+// isPrivateCidrBlock returns true if the block is within one of the RFC 1918
+// private address ranges.
+func isPrivateCidrBlock(block *net.IPNet) bool {
+	ones, _ := block.Mask.Size()
+	for _, cidr := range privateCidrBlocks {
+		_, private, _ := net.ParseCIDR(cidr)
+		privateOnes, _ := private.Mask.Size()
+		if private.Contains(block.IP) && ones >= privateOnes {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,1540 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha3
+
+import (
+	"strings"
+	"testing"
+
+	"k8s.io/utils/pointer"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1alpha3"
+)
+
+func TestAWSCluster_ValidateCreate(t *testing.T) {
+	tests := []struct {
+		name    string
+		sources []IngressSource
+		wantErr bool
+	}{
+		{
+			name: "mixed ingress sources",
+			sources: []IngressSource{
+				{CidrBlock: "10.0.0.0/8"},
+				{PrefixListID: "pl-12345678"},
+				{SecurityGroupID: "sg-12345678"},
+			},
+			wantErr: false,
+		},
+		{
+			name: "invalid CIDR block",
+			sources: []IngressSource{
+				{CidrBlock: "10.0.0.0"},
+			},
+			wantErr: true,
+		},
+		{
+			name: "IPv6 CIDR block",
+			sources: []IngressSource{
+				{CidrBlock: "2001:db8::/32"},
+			},
+			wantErr: true,
+		},
+		{
+			name: "invalid prefix list ID",
+			sources: []IngressSource{
+				{PrefixListID: "sg-12345678"},
+			},
+			wantErr: true,
+		},
+		{
+			name: "invalid security group ID",
+			sources: []IngressSource{
+				{SecurityGroupID: "pl-12345678"},
+			},
+			wantErr: true,
+		},
+		{
+			name: "more than one source type in an entry",
+			sources: []IngressSource{
+				{CidrBlock: "10.0.0.0/8", SecurityGroupID: "sg-12345678"},
+			},
+			wantErr: true,
+		},
+		{
+			name: "empty entry",
+			sources: []IngressSource{
+				{},
+			},
+			wantErr: true,
+		},
+		{
+			name: "duplicate entries",
+			sources: []IngressSource{
+				{CidrBlock: "10.0.0.0/8"},
+				{CidrBlock: "10.0.0.0/8"},
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cluster := &AWSCluster{
+				Spec: AWSClusterSpec{
+					ControlPlaneLoadBalancer: &AWSLoadBalancerSpec{
+						IngressSources: tt.sources,
+					},
+				},
+			}
+			if err := cluster.ValidateCreate(); (err != nil) != tt.wantErr {
+				t.Errorf("ValidateCreate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestAWSCluster_ValidateNetworkACL(t *testing.T) {
+	allowHTTPS := NetworkACLRule{
+		RuleNumber: 100,
+		Protocol:   SecurityGroupProtocolTCP,
+		FromPort:   443,
+		ToPort:     443,
+		CidrBlock:  "0.0.0.0/0",
+		Action:     NetworkACLRuleActionAllow,
+	}
+	withRule := func(rule NetworkACLRule, mutate func(*NetworkACLRule)) NetworkACLRule {
+		mutate(&rule)
+		return rule
+	}
+
+	tests := []struct {
+		name    string
+		rules   []NetworkACLRule
+		wantErr bool
+	}{
+		{
+			name: "ingress and egress rules",
+			rules: []NetworkACLRule{
+				allowHTTPS,
+				withRule(allowHTTPS, func(r *NetworkACLRule) {
+					r.RuleNumber = 200
+					r.Protocol = SecurityGroupProtocolAll
+					r.Action = NetworkACLRuleActionDeny
+				}),
+				withRule(allowHTTPS, func(r *NetworkACLRule) { r.Egress = true }),
+			},
+			wantErr: false,
+		},
+		{
+			name: "duplicate rule numbers",
+			rules: []NetworkACLRule{
+				allowHTTPS,
+				withRule(allowHTTPS, func(r *NetworkACLRule) { r.FromPort = 80; r.ToPort = 80 }),
+			},
+			wantErr: true,
+		},
+		{
+			name: "rule numbers out of order",
+			rules: []NetworkACLRule{
+				withRule(allowHTTPS, func(r *NetworkACLRule) { r.RuleNumber = 200 }),
+				allowHTTPS,
+			},
+			wantErr: true,
+		},
+		{
+			name: "rule number out of range",
+			rules: []NetworkACLRule{
+				withRule(allowHTTPS, func(r *NetworkACLRule) { r.RuleNumber = 32767 }),
+			},
+			wantErr: true,
+		},
+		{
+			name: "invalid port range",
+			rules: []NetworkACLRule{
+				withRule(allowHTTPS, func(r *NetworkACLRule) { r.FromPort = 443; r.ToPort = 80 }),
+			},
+			wantErr: true,
+		},
+		{
+			name: "unsupported protocol",
+			rules: []NetworkACLRule{
+				withRule(allowHTTPS, func(r *NetworkACLRule) { r.Protocol = SecurityGroupProtocolIPinIP }),
+			},
+			wantErr: true,
+		},
+		{
+			name: "invalid CIDR block",
+			rules: []NetworkACLRule{
+				withRule(allowHTTPS, func(r *NetworkACLRule) { r.CidrBlock = "0.0.0.0" }),
+			},
+			wantErr: true,
+		},
+		{
+			name: "unsupported action",
+			rules: []NetworkACLRule{
+				withRule(allowHTTPS, func(r *NetworkACLRule) { r.Action = "reject" }),
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cluster := &AWSCluster{
+				Spec: AWSClusterSpec{
+					NetworkSpec: NetworkSpec{
+						NetworkACL: &NetworkACLSpec{
+							Rules: tt.rules,
+						},
+					},
+				},
+			}
+			if err := cluster.ValidateCreate(); (err != nil) != tt.wantErr {
+				t.Errorf("ValidateCreate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestAWSCluster_ValidateNodeIngress(t *testing.T) {
+	tests := []struct {
+		name    string
+		ingress *NodeIngressSpec
+		wantErr bool
+	}{
+		{
+			name: "custom ports and sources",
+			ingress: &NodeIngressSpec{
+				NodePort: &NodeIngressRule{FromPort: 31000, ToPort: 31999, CidrBlocks: []string{"10.0.0.0/8"}},
+				Kubelet:  &NodeIngressRule{SourceSecurityGroupIDs: []string{"sg-12345678"}},
+			},
+			wantErr: false,
+		},
+		{
+			name: "only one port set",
+			ingress: &NodeIngressSpec{
+				NodePort: &NodeIngressRule{FromPort: 31000},
+			},
+			wantErr: true,
+		},
+		{
+			name: "port out of range",
+			ingress: &NodeIngressSpec{
+				NodePort: &NodeIngressRule{FromPort: 31000, ToPort: 70000},
+			},
+			wantErr: true,
+		},
+		{
+			name: "invalid CIDR block",
+			ingress: &NodeIngressSpec{
+				NodePort: &NodeIngressRule{CidrBlocks: []string{"10.0.0.0"}},
+			},
+			wantErr: true,
+		},
+		{
+			name: "invalid security group ID",
+			ingress: &NodeIngressSpec{
+				Kubelet: &NodeIngressRule{SourceSecurityGroupIDs: []string{"pl-12345678"}},
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cluster := &AWSCluster{
+				Spec: AWSClusterSpec{
+					NetworkSpec: NetworkSpec{
+						NodeIngress: tt.ingress,
+					},
+				},
+			}
+			if err := cluster.ValidateCreate(); (err != nil) != tt.wantErr {
+				t.Errorf("ValidateCreate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestAWSCluster_ValidateAdvertisedEndpoint(t *testing.T) {
+	tests := []struct {
+		name     string
+		endpoint *AdvertisedEndpoint
+		wantErr  bool
+	}{
+		{
+			name:     "DNS name",
+			endpoint: &AdvertisedEndpoint{Host: "api.example.com"},
+			wantErr:  false,
+		},
+		{
+			name:     "IP address and port",
+			endpoint: &AdvertisedEndpoint{Host: "192.0.2.10", Port: 443},
+			wantErr:  false,
+		},
+		{
+			name:     "missing host",
+			endpoint: &AdvertisedEndpoint{Port: 443},
+			wantErr:  true,
+		},
+		{
+			name:     "host with scheme",
+			endpoint: &AdvertisedEndpoint{Host: "https://api.example.com"},
+			wantErr:  true,
+		},
+		{
+			name:     "host with port",
+			endpoint: &AdvertisedEndpoint{Host: "api.example.com:443"},
+			wantErr:  true,
+		},
+		{
+			name:     "port out of range",
+			endpoint: &AdvertisedEndpoint{Host: "api.example.com", Port: 70000},
+			wantErr:  true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cluster := &AWSCluster{
+				Spec: AWSClusterSpec{
+					ControlPlaneLoadBalancer: &AWSLoadBalancerSpec{
+						AdvertisedEndpoint: tt.endpoint,
+					},
+				},
+			}
+			if err := cluster.ValidateCreate(); (err != nil) != tt.wantErr {
+				t.Errorf("ValidateCreate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestAWSCluster_ValidateUpdateAdvertisedEndpoint(t *testing.T) {
+	tests := []struct {
+		name          string
+		endpoint      clusterv1.APIEndpoint
+		oldAdvertised *AdvertisedEndpoint
+		newAdvertised *AdvertisedEndpoint
+		wantErr       bool
+	}{
+		{
+			name:          "set before the control plane endpoint",
+			newAdvertised: &AdvertisedEndpoint{Host: "api.example.com"},
+			wantErr:       false,
+		},
+		{
+			name:          "unchanged after the control plane endpoint is set",
+			endpoint:      clusterv1.APIEndpoint{Host: "api.example.com", Port: 6443},
+			oldAdvertised: &AdvertisedEndpoint{Host: "api.example.com"},
+			newAdvertised: &AdvertisedEndpoint{Host: "api.example.com"},
+			wantErr:       false,
+		},
+		{
+			name:          "changed after the control plane endpoint is set",
+			endpoint:      clusterv1.APIEndpoint{Host: "api.example.com", Port: 6443},
+			oldAdvertised: &AdvertisedEndpoint{Host: "api.example.com"},
+			newAdvertised: &AdvertisedEndpoint{Host: "api2.example.com"},
+			wantErr:       true,
+		},
+		{
+			name:          "added after the control plane endpoint is set",
+			endpoint:      clusterv1.APIEndpoint{Host: "elb.example.com", Port: 6443},
+			newAdvertised: &AdvertisedEndpoint{Host: "api.example.com"},
+			wantErr:       true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			oldCluster := &AWSCluster{
+				Spec: AWSClusterSpec{
+					ControlPlaneEndpoint:     tt.endpoint,
+					ControlPlaneLoadBalancer: &AWSLoadBalancerSpec{AdvertisedEndpoint: tt.oldAdvertised},
+				},
+			}
+			newCluster := oldCluster.DeepCopy()
+			newCluster.Spec.ControlPlaneLoadBalancer.AdvertisedEndpoint = tt.newAdvertised
+			if err := newCluster.ValidateUpdate(oldCluster); (err != nil) != tt.wantErr {
+				t.Errorf("ValidateUpdate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestAWSCluster_ValidateUpdateLoadBalancerType(t *testing.T) {
+	tests := []struct {
+		name       string
+		endpoint   clusterv1.APIEndpoint
+		oldType    LoadBalancerType
+		newType    LoadBalancerType
+		migrate    bool
+		advertised *AdvertisedEndpoint
+		wantErr    bool
+	}{
+		{
+			name:    "changed before the control plane endpoint is set",
+			newType: LoadBalancerTypeNLB,
+			wantErr: false,
+		},
+		{
+			name:     "defaulted type made explicit",
+			endpoint: clusterv1.APIEndpoint{Host: "elb.example.com", Port: 6443},
+			newType:  LoadBalancerTypeClassic,
+			wantErr:  false,
+		},
+		{
+			name:     "classic to nlb after the control plane endpoint is set",
+			endpoint: clusterv1.APIEndpoint{Host: "elb.example.com", Port: 6443},
+			newType:  LoadBalancerTypeNLB,
+			wantErr:  true,
+		},
+		{
+			name:     "nlb to classic after the control plane endpoint is set",
+			endpoint: clusterv1.APIEndpoint{Host: "nlb.example.com", Port: 6443},
+			oldType:  LoadBalancerTypeNLB,
+			newType:  LoadBalancerTypeClassic,
+			wantErr:  true,
+		},
+		{
+			name:       "classic to nlb migration",
+			endpoint:   clusterv1.APIEndpoint{Host: "api.example.com", Port: 6443},
+			newType:    LoadBalancerTypeNLB,
+			migrate:    true,
+			advertised: &AdvertisedEndpoint{Host: "api.example.com"},
+			wantErr:    false,
+		},
+		{
+			name:     "classic to nlb migration without an advertised endpoint",
+			endpoint: clusterv1.APIEndpoint{Host: "elb.example.com", Port: 6443},
+			newType:  LoadBalancerTypeNLB,
+			migrate:  true,
+			wantErr:  true,
+		},
+		{
+			name:       "nlb to classic migration",
+			endpoint:   clusterv1.APIEndpoint{Host: "api.example.com", Port: 6443},
+			oldType:    LoadBalancerTypeNLB,
+			newType:    LoadBalancerTypeClassic,
+			migrate:    true,
+			advertised: &AdvertisedEndpoint{Host: "api.example.com"},
+			wantErr:    true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			oldCluster := &AWSCluster{
+				Spec: AWSClusterSpec{
+					ControlPlaneEndpoint: tt.endpoint,
+					ControlPlaneLoadBalancer: &AWSLoadBalancerSpec{
+						LoadBalancerType:   tt.oldType,
+						AdvertisedEndpoint: tt.advertised,
+					},
+				},
+			}
+			newCluster := oldCluster.DeepCopy()
+			newCluster.Spec.ControlPlaneLoadBalancer.LoadBalancerType = tt.newType
+			if tt.migrate {
+				newCluster.Annotations = map[string]string{MigrateLoadBalancerAnnotation: ""}
+			}
+			if err := newCluster.ValidateUpdate(oldCluster); (err != nil) != tt.wantErr {
+				t.Errorf("ValidateUpdate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestAWSCluster_ValidateVPCEndpoints(t *testing.T) {
+	tests := []struct {
+		name      string
+		endpoints []VPCEndpointSpec
+		wantErr   bool
+	}{
+		{
+			name: "gateway and interface endpoints",
+			endpoints: []VPCEndpointSpec{
+				{Service: "s3", Type: VPCEndpointTypeGateway},
+				{Service: "ecr.api", Type: VPCEndpointTypeInterface},
+				{Service: "ecr.dkr", Type: VPCEndpointTypeInterface},
+			},
+			wantErr: false,
+		},
+		{
+			name: "missing service",
+			endpoints: []VPCEndpointSpec{
+				{Type: VPCEndpointTypeInterface},
+			},
+			wantErr: true,
+		},
+		{
+			name: "malformed service",
+			endpoints: []VPCEndpointSpec{
+				{Service: "ecr api", Type: VPCEndpointTypeInterface},
+			},
+			wantErr: true,
+		},
+		{
+			name: "duplicate service",
+			endpoints: []VPCEndpointSpec{
+				{Service: "s3", Type: VPCEndpointTypeGateway},
+				{Service: "s3", Type: VPCEndpointTypeInterface},
+			},
+			wantErr: true,
+		},
+		{
+			name: "unsupported type",
+			endpoints: []VPCEndpointSpec{
+				{Service: "s3", Type: "GatewayLoadBalancer"},
+			},
+			wantErr: true,
+		},
+		{
+			name: "interface endpoint with its own security groups",
+			endpoints: []VPCEndpointSpec{
+				{
+					Service: "sts",
+					Type:    VPCEndpointTypeInterface,
+					SecurityGroups: []AWSResourceReference{
+						{ID: pointer.StringPtr("sg-12345678")},
+						{Filters: []Filter{{Name: "tag:Name", Values: []string{"vpce"}}}},
+					},
+				},
+			},
+			wantErr: false,
+		},
+		{
+			name: "gateway endpoint with security groups",
+			endpoints: []VPCEndpointSpec{
+				{Service: "s3", Type: VPCEndpointTypeGateway, SecurityGroups: []AWSResourceReference{{ID: pointer.StringPtr("sg-12345678")}}},
+			},
+			wantErr: true,
+		},
+		{
+			name: "security group without id nor filters",
+			endpoints: []VPCEndpointSpec{
+				{Service: "sts", Type: VPCEndpointTypeInterface, SecurityGroups: []AWSResourceReference{{}}},
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cluster := &AWSCluster{
+				Spec: AWSClusterSpec{
+					NetworkSpec: NetworkSpec{
+						VPCEndpoints: tt.endpoints,
+					},
+				},
+			}
+			if err := cluster.ValidateCreate(); (err != nil) != tt.wantErr {
+				t.Errorf("ValidateCreate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestAWSCluster_ValidateAssumeRole(t *testing.T) {
+	tests := []struct {
+		name       string
+		assumeRole *AssumeRoleSpec
+		wantErr    bool
+	}{
+		{
+			name: "role with external id and session name",
+			assumeRole: &AssumeRoleSpec{
+				RoleARN:     "arn:aws:iam::123456789012:role/cluster-api",
+				ExternalID:  "a1b2c3d4",
+				SessionName: "capa-workload",
+			},
+			wantErr: false,
+		},
+		{
+			name:       "missing role",
+			assumeRole: &AssumeRoleSpec{ExternalID: "a1b2c3d4"},
+			wantErr:    true,
+		},
+		{
+			name:       "ARN of another resource",
+			assumeRole: &AssumeRoleSpec{RoleARN: "arn:aws:iam::123456789012:user/cluster-api"},
+			wantErr:    true,
+		},
+		{
+			name:       "invalid external id",
+			assumeRole: &AssumeRoleSpec{RoleARN: "arn:aws:iam::123456789012:role/cluster-api", ExternalID: "a b"},
+			wantErr:    true,
+		},
+		{
+			name:       "longest external id",
+			assumeRole: &AssumeRoleSpec{RoleARN: "arn:aws:iam::123456789012:role/cluster-api", ExternalID: strings.Repeat("a", 1224)},
+			wantErr:    false,
+		},
+		{
+			name:       "external id too long",
+			assumeRole: &AssumeRoleSpec{RoleARN: "arn:aws:iam::123456789012:role/cluster-api", ExternalID: strings.Repeat("a", 1225)},
+			wantErr:    true,
+		},
+		{
+			name:       "external id too short",
+			assumeRole: &AssumeRoleSpec{RoleARN: "arn:aws:iam::123456789012:role/cluster-api", ExternalID: "a"},
+			wantErr:    true,
+		},
+		{
+			name:       "session name too long",
+			assumeRole: &AssumeRoleSpec{RoleARN: "arn:aws:iam::123456789012:role/cluster-api", SessionName: strings.Repeat("a", 65)},
+			wantErr:    true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cluster := &AWSCluster{
+				Spec: AWSClusterSpec{
+					AssumeRole: tt.assumeRole,
+				},
+			}
+			if err := cluster.ValidateCreate(); (err != nil) != tt.wantErr {
+				t.Errorf("ValidateCreate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestAWSCluster_ValidateIdentityRef(t *testing.T) {
+	tests := []struct {
+		name       string
+		assumeRole *AssumeRoleSpec
+		wantErr    bool
+	}{
+		{
+			name:    "identity only",
+			wantErr: false,
+		},
+		{
+			name:       "identity and role",
+			assumeRole: &AssumeRoleSpec{RoleARN: "arn:aws:iam::123456789012:role/cluster-api"},
+			wantErr:    true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cluster := &AWSCluster{
+				Spec: AWSClusterSpec{
+					AssumeRole:  tt.assumeRole,
+					IdentityRef: &AWSIdentityReference{Name: "tenant-a", Kind: AWSClusterRoleIdentityKind},
+				},
+			}
+			if err := cluster.ValidateCreate(); (err != nil) != tt.wantErr {
+				t.Errorf("ValidateCreate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestAWSCluster_ValidatePodSubnets(t *testing.T) {
+	tests := []struct {
+		name    string
+		subnet  SubnetSpec
+		wantErr bool
+	}{
+		{
+			name:    "pod subnet in a secondary CIDR block",
+			subnet:  SubnetSpec{CidrBlock: "100.64.0.0/19", AvailabilityZone: "eu-west-1a", ForPods: true},
+			wantErr: false,
+		},
+		{
+			name:    "pod subnet in the primary CIDR block",
+			subnet:  SubnetSpec{CidrBlock: "10.0.64.0/19", AvailabilityZone: "eu-west-1a", ForPods: true},
+			wantErr: true,
+		},
+		{
+			name:    "public pod subnet",
+			subnet:  SubnetSpec{CidrBlock: "100.64.0.0/19", AvailabilityZone: "eu-west-1a", ForPods: true, IsPublic: true},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			subnet := tt.subnet
+			cluster := &AWSCluster{
+				Spec: AWSClusterSpec{
+					NetworkSpec: NetworkSpec{
+						VPC: VPCSpec{
+							CidrBlock:           "10.0.0.0/16",
+							SecondaryCidrBlocks: []string{"100.64.0.0/16"},
+						},
+						Subnets: Subnets{&subnet},
+					},
+				},
+			}
+			if err := cluster.ValidateCreate(); (err != nil) != tt.wantErr {
+				t.Errorf("ValidateCreate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestAWSCluster_ValidateExternallyManagedNetwork(t *testing.T) {
+	tests := []struct {
+		name    string
+		network NetworkSpec
+		wantErr bool
+	}{
+		{
+			name: "existing VPC and subnets",
+			network: NetworkSpec{
+				VPC:     VPCSpec{ID: "vpc-12345678", ExternallyManaged: true},
+				Subnets: Subnets{{ID: "subnet-12345678"}},
+			},
+			wantErr: false,
+		},
+		{
+			name: "missing VPC",
+			network: NetworkSpec{
+				VPC: VPCSpec{ExternallyManaged: true},
+			},
+			wantErr: true,
+		},
+		{
+			name: "secondary CIDR blocks",
+			network: NetworkSpec{
+				VPC: VPCSpec{ID: "vpc-12345678", ExternallyManaged: true, SecondaryCidrBlocks: []string{"100.64.0.0/16"}},
+			},
+			wantErr: true,
+		},
+		{
+			name: "VPC endpoints",
+			network: NetworkSpec{
+				VPC:          VPCSpec{ID: "vpc-12345678", ExternallyManaged: true},
+				VPCEndpoints: []VPCEndpointSpec{{Service: "s3", Type: VPCEndpointTypeGateway}},
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cluster := &AWSCluster{
+				Spec: AWSClusterSpec{
+					NetworkSpec: tt.network,
+				},
+			}
+			if err := cluster.ValidateCreate(); (err != nil) != tt.wantErr {
+				t.Errorf("ValidateCreate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestAWSCluster_ValidateVPCCidrBlocks(t *testing.T) {
+	tests := []struct {
+		name           string
+		vpc            VPCSpec
+		requirePrivate bool
+		wantErr        bool
+	}{
+		{
+			name: "primary and secondary blocks",
+			vpc: VPCSpec{
+				CidrBlock:           "10.0.0.0/16",
+				SecondaryCidrBlocks: []string{"10.1.0.0/16", "100.64.0.0/16"},
+			},
+			wantErr: false,
+		},
+		{
+			name: "malformed secondary block",
+			vpc: VPCSpec{
+				CidrBlock:           "10.0.0.0/16",
+				SecondaryCidrBlocks: []string{"10.1.0.0"},
+			},
+			wantErr: true,
+		},
+		{
+			name: "secondary block overlapping the primary block",
+			vpc: VPCSpec{
+				CidrBlock:           "10.0.0.0/16",
+				SecondaryCidrBlocks: []string{"10.0.128.0/17"},
+			},
+			wantErr: true,
+		},
+		{
+			name: "overlapping secondary blocks",
+			vpc: VPCSpec{
+				SecondaryCidrBlocks: []string{"10.1.0.0/16", "10.0.0.0/8"},
+			},
+			wantErr: true,
+		},
+		{
+			name: "public block when private blocks are required",
+			vpc: VPCSpec{
+				CidrBlock:           "10.0.0.0/16",
+				SecondaryCidrBlocks: []string{"100.64.0.0/16"},
+			},
+			requirePrivate: true,
+			wantErr:        true,
+		},
+		{
+			name: "private blocks when private blocks are required",
+			vpc: VPCSpec{
+				CidrBlock:           "10.0.0.0/16",
+				SecondaryCidrBlocks: []string{"172.16.0.0/16", "192.168.0.0/20"},
+			},
+			requirePrivate: true,
+			wantErr:        false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			RequirePrivateVPCCidrBlocks = tt.requirePrivate
+			defer func() { RequirePrivateVPCCidrBlocks = false }()
+
+			cluster := &AWSCluster{
+				Spec: AWSClusterSpec{
+					NetworkSpec: NetworkSpec{
+						VPC: tt.vpc,
+					},
+				},
+			}
+			if err := cluster.ValidateCreate(); (err != nil) != tt.wantErr {
+				t.Errorf("ValidateCreate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestAWSCluster_ValidateSharedSubnets(t *testing.T) {
+	tests := []struct {
+		name    string
+		network NetworkSpec
+		wantErr bool
+	}{
+		{
+			name: "subnets by id and by arn",
+			network: NetworkSpec{
+				SharedSubnets: []SharedSubnetReference{
+					{ID: "subnet-1"},
+					{ID: "arn:aws:ec2:us-east-1:123456789012:subnet/subnet-2", IsPublic: true},
+				},
+			},
+			wantErr: false,
+		},
+		{
+			name: "malformed subnet id",
+			network: NetworkSpec{
+				SharedSubnets: []SharedSubnetReference{{ID: "vpc-1"}},
+			},
+			wantErr: true,
+		},
+		{
+			name: "arn of another resource",
+			network: NetworkSpec{
+				SharedSubnets: []SharedSubnetReference{{ID: "arn:aws:ec2:us-east-1:123456789012:vpc/vpc-1"}},
+			},
+			wantErr: true,
+		},
+		{
+			name: "same subnet by id and by arn",
+			network: NetworkSpec{
+				SharedSubnets: []SharedSubnetReference{
+					{ID: "subnet-1"},
+					{ID: "arn:aws:ec2:us-east-1:123456789012:subnet/subnet-1"},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "only public subnets",
+			network: NetworkSpec{
+				SharedSubnets: []SharedSubnetReference{{ID: "subnet-1", IsPublic: true}},
+			},
+			wantErr: true,
+		},
+		{
+			name: "secondary cidr blocks on a shared vpc",
+			network: NetworkSpec{
+				VPC:           VPCSpec{SecondaryCidrBlocks: []string{"10.1.0.0/16"}},
+				SharedSubnets: []SharedSubnetReference{{ID: "subnet-1"}},
+			},
+			wantErr: true,
+		},
+		{
+			name: "vpc endpoints on a shared vpc",
+			network: NetworkSpec{
+				VPCEndpoints:  []VPCEndpointSpec{{Service: "s3", Type: VPCEndpointTypeGateway}},
+				SharedSubnets: []SharedSubnetReference{{ID: "subnet-1"}},
+			},
+			wantErr: true,
+		},
+		{
+			name: "dhcp options on a shared vpc",
+			network: NetworkSpec{
+				DHCPOptions:   &DHCPOptionsSpec{DomainName: "example.com"},
+				SharedSubnets: []SharedSubnetReference{{ID: "subnet-1"}},
+			},
+			wantErr: true,
+		},
+		{
+			name: "public ipv4 pool on a shared vpc",
+			network: NetworkSpec{
+				PublicIPv4Pool: "ipv4pool-ec2-0123456789abcdef0",
+				SharedSubnets:  []SharedSubnetReference{{ID: "subnet-1"}},
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cluster := &AWSCluster{
+				Spec: AWSClusterSpec{
+					NetworkSpec: tt.network,
+				},
+			}
+			if err := cluster.ValidateCreate(); (err != nil) != tt.wantErr {
+				t.Errorf("ValidateCreate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestAWSCluster_ValidateDHCPOptions(t *testing.T) {
+	tests := []struct {
+		name    string
+		options *DHCPOptionsSpec
+		wantErr bool
+	}{
+		{
+			name: "domain name, dns and ntp servers",
+			options: &DHCPOptionsSpec{
+				DomainName:        "corp.example.com",
+				DomainNameServers: []string{"10.0.0.2", "AmazonProvidedDNS"},
+				NTPServers:        []string{"169.254.169.123"},
+			},
+			wantErr: false,
+		},
+		{
+			name:    "no options",
+			options: &DHCPOptionsSpec{},
+			wantErr: true,
+		},
+		{
+			name:    "malformed domain name",
+			options: &DHCPOptionsSpec{DomainName: "corp example"},
+			wantErr: true,
+		},
+		{
+			name:    "dns server by host name",
+			options: &DHCPOptionsSpec{DomainNameServers: []string{"dns.example.com"}},
+			wantErr: true,
+		},
+		{
+			name:    "ntp server by host name",
+			options: &DHCPOptionsSpec{NTPServers: []string{"pool.ntp.org"}},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cluster := &AWSCluster{
+				Spec: AWSClusterSpec{
+					NetworkSpec: NetworkSpec{
+						DHCPOptions: tt.options,
+					},
+				},
+			}
+			if err := cluster.ValidateCreate(); (err != nil) != tt.wantErr {
+				t.Errorf("ValidateCreate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestAWSCluster_ValidateTransitGateway(t *testing.T) {
+	tests := []struct {
+		name    string
+		network NetworkSpec
+		wantErr bool
+	}{
+		{
+			name: "routes to on-premises networks",
+			network: NetworkSpec{
+				TransitGateway: &TransitGatewaySpec{ID: "tgw-12345678", Routes: []string{"10.100.0.0/16", "192.168.0.0/24"}},
+			},
+			wantErr: false,
+		},
+		{
+			name: "all egress without NAT gateways",
+			network: NetworkSpec{
+				NatGatewayMode: NatGatewayModeNone,
+				TransitGateway: &TransitGatewaySpec{ID: "tgw-12345678", Routes: []string{"0.0.0.0/0"}},
+			},
+			wantErr: false,
+		},
+		{
+			name: "all egress with NAT gateways",
+			network: NetworkSpec{
+				TransitGateway: &TransitGatewaySpec{ID: "tgw-12345678", Routes: []string{"0.0.0.0/0"}},
+			},
+			wantErr: true,
+		},
+		{
+			name: "missing transit gateway ID",
+			network: NetworkSpec{
+				TransitGateway: &TransitGatewaySpec{Routes: []string{"10.100.0.0/16"}},
+			},
+			wantErr: true,
+		},
+		{
+			name: "IPv6 route",
+			network: NetworkSpec{
+				TransitGateway: &TransitGatewaySpec{ID: "tgw-12345678", Routes: []string{"2001:db8::/32"}},
+			},
+			wantErr: true,
+		},
+		{
+			name: "duplicate routes",
+			network: NetworkSpec{
+				TransitGateway: &TransitGatewaySpec{ID: "tgw-12345678", Routes: []string{"10.100.0.0/16", "10.100.1.0/16"}},
+			},
+			wantErr: true,
+		},
+		{
+			name: "externally managed VPC",
+			network: NetworkSpec{
+				VPC:            VPCSpec{ID: "vpc-12345678", ExternallyManaged: true},
+				TransitGateway: &TransitGatewaySpec{ID: "tgw-12345678"},
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cluster := &AWSCluster{
+				Spec: AWSClusterSpec{
+					NetworkSpec: tt.network,
+				},
+			}
+			if err := cluster.ValidateCreate(); (err != nil) != tt.wantErr {
+				t.Errorf("ValidateCreate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestAWSCluster_ValidateUpdateTransitGateway(t *testing.T) {
+	tests := []struct {
+		name    string
+		oldTGW  *TransitGatewaySpec
+		newTGW  *TransitGatewaySpec
+		wantErr bool
+	}{
+		{
+			name:    "added",
+			newTGW:  &TransitGatewaySpec{ID: "tgw-12345678"},
+			wantErr: false,
+		},
+		{
+			name:    "changed",
+			oldTGW:  &TransitGatewaySpec{ID: "tgw-12345678"},
+			newTGW:  &TransitGatewaySpec{ID: "tgw-87654321", Routes: []string{"10.100.0.0/16"}},
+			wantErr: false,
+		},
+		{
+			name:    "removed",
+			oldTGW:  &TransitGatewaySpec{ID: "tgw-12345678"},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			oldCluster := &AWSCluster{
+				Spec: AWSClusterSpec{
+					NetworkSpec: NetworkSpec{TransitGateway: tt.oldTGW},
+				},
+			}
+			newCluster := oldCluster.DeepCopy()
+			newCluster.Spec.NetworkSpec.TransitGateway = tt.newTGW
+			if err := newCluster.ValidateUpdate(oldCluster); (err != nil) != tt.wantErr {
+				t.Errorf("ValidateUpdate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestAWSCluster_ValidateVPCPeering(t *testing.T) {
+	tests := []struct {
+		name    string
+		network NetworkSpec
+		wantErr bool
+	}{
+		{
+			name: "peering with the management cluster VPC",
+			network: NetworkSpec{
+				VPC:        VPCSpec{CidrBlock: "10.0.0.0/16"},
+				VPCPeering: &VPCPeeringSpec{PeerVPCID: "vpc-12345678", PeerCidrBlocks: []string{"10.1.0.0/16"}, PeerRouteTableIDs: []string{"rtb-12345678"}},
+			},
+			wantErr: false,
+		},
+		{
+			name: "peering with another account",
+			network: NetworkSpec{
+				VPCPeering: &VPCPeeringSpec{PeerVPCID: "vpc-12345678", PeerOwnerID: "123456789012", PeerCidrBlocks: []string{"10.1.0.0/16"}},
+			},
+			wantErr: false,
+		},
+		{
+			name: "invalid peer VPC ID",
+			network: NetworkSpec{
+				VPCPeering: &VPCPeeringSpec{PeerVPCID: "subnet-12345678", PeerCidrBlocks: []string{"10.1.0.0/16"}},
+			},
+			wantErr: true,
+		},
+		{
+			name: "invalid peer owner ID",
+			network: NetworkSpec{
+				VPCPeering: &VPCPeeringSpec{PeerVPCID: "vpc-12345678", PeerOwnerID: "1234", PeerCidrBlocks: []string{"10.1.0.0/16"}},
+			},
+			wantErr: true,
+		},
+		{
+			name: "peer CIDR block overlaps the VPC",
+			network: NetworkSpec{
+				VPC:        VPCSpec{CidrBlock: "10.0.0.0/16"},
+				VPCPeering: &VPCPeeringSpec{PeerVPCID: "vpc-12345678", PeerCidrBlocks: []string{"10.0.128.0/24"}},
+			},
+			wantErr: true,
+		},
+		{
+			name: "duplicate peer CIDR blocks",
+			network: NetworkSpec{
+				VPCPeering: &VPCPeeringSpec{PeerVPCID: "vpc-12345678", PeerCidrBlocks: []string{"10.1.0.0/16", "10.1.1.0/16"}},
+			},
+			wantErr: true,
+		},
+		{
+			name: "peer route tables in another region",
+			network: NetworkSpec{
+				VPCPeering: &VPCPeeringSpec{PeerVPCID: "vpc-12345678", PeerRegion: "eu-west-1", PeerCidrBlocks: []string{"10.1.0.0/16"}, PeerRouteTableIDs: []string{"rtb-12345678"}},
+			},
+			wantErr: true,
+		},
+		{
+			name: "invalid peer route table ID",
+			network: NetworkSpec{
+				VPCPeering: &VPCPeeringSpec{PeerVPCID: "vpc-12345678", PeerCidrBlocks: []string{"10.1.0.0/16"}, PeerRouteTableIDs: []string{"vpc-12345678"}},
+			},
+			wantErr: true,
+		},
+		{
+			name: "externally managed VPC",
+			network: NetworkSpec{
+				VPC:        VPCSpec{ID: "vpc-87654321", ExternallyManaged: true},
+				VPCPeering: &VPCPeeringSpec{PeerVPCID: "vpc-12345678", PeerCidrBlocks: []string{"10.1.0.0/16"}},
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cluster := &AWSCluster{
+				Spec: AWSClusterSpec{
+					Region:      "us-east-1",
+					NetworkSpec: tt.network,
+				},
+			}
+			if err := cluster.ValidateCreate(); (err != nil) != tt.wantErr {
+				t.Errorf("ValidateCreate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestAWSCluster_ValidateUpdateVPCPeering(t *testing.T) {
+	peering := &VPCPeeringSpec{PeerVPCID: "vpc-12345678", PeerCidrBlocks: []string{"10.1.0.0/16"}}
+	tests := []struct {
+		name       string
+		oldPeering *VPCPeeringSpec
+		newPeering *VPCPeeringSpec
+		wantErr    bool
+	}{
+		{
+			name:       "added",
+			newPeering: peering,
+			wantErr:    false,
+		},
+		{
+			name:       "removed",
+			oldPeering: peering,
+			wantErr:    true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			oldCluster := &AWSCluster{
+				Spec: AWSClusterSpec{
+					NetworkSpec: NetworkSpec{VPCPeering: tt.oldPeering},
+				},
+			}
+			newCluster := oldCluster.DeepCopy()
+			newCluster.Spec.NetworkSpec.VPCPeering = tt.newPeering
+			if err := newCluster.ValidateUpdate(oldCluster); (err != nil) != tt.wantErr {
+				t.Errorf("ValidateUpdate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestAWSCluster_ValidateDNSRecord(t *testing.T) {
+	tests := []struct {
+		name    string
+		record  *DNSRecordSpec
+		wantErr bool
+	}{
+		{
+			name:    "alias record",
+			record:  &DNSRecordSpec{HostedZoneID: "Z0123456789ABCDEFGHIJ", Name: "api.corp.example.com"},
+			wantErr: false,
+		},
+		{
+			name:    "fully qualified cname record",
+			record:  &DNSRecordSpec{HostedZoneID: "Z0123456789ABCDEFGHIJ", Name: "api.corp.example.com.", Type: DNSRecordTypeCNAME},
+			wantErr: false,
+		},
+		{
+			name:    "missing hosted zone",
+			record:  &DNSRecordSpec{Name: "api.corp.example.com"},
+			wantErr: true,
+		},
+		{
+			name:    "malformed name",
+			record:  &DNSRecordSpec{HostedZoneID: "Z0123456789ABCDEFGHIJ", Name: "https://api.corp.example.com"},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cluster := &AWSCluster{
+				Spec: AWSClusterSpec{
+					ControlPlaneLoadBalancer: &AWSLoadBalancerSpec{
+						DNSRecord: tt.record,
+					},
+				},
+			}
+			if err := cluster.ValidateCreate(); (err != nil) != tt.wantErr {
+				t.Errorf("ValidateCreate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestAWSCluster_ValidateBastion(t *testing.T) {
+	tests := []struct {
+		name           string
+		bastion        Bastion
+		sessionManager bool
+		wantErr        bool
+	}{
+		{
+			name:    "elastic ip for a public bastion",
+			bastion: Bastion{ElasticIPAllocationID: "eipalloc-12345678"},
+			wantErr: false,
+		},
+		{
+			name:    "elastic ip for a private bastion",
+			bastion: Bastion{PublicIP: pointer.BoolPtr(false), ElasticIPAllocationID: "eipalloc-12345678"},
+			wantErr: true,
+		},
+		{
+			name:    "allowed cidr blocks",
+			bastion: Bastion{AllowedCIDRBlocks: []string{"10.0.0.0/8", "192.168.1.0/24"}},
+			wantErr: false,
+		},
+		{
+			name:    "invalid allowed cidr block",
+			bastion: Bastion{AllowedCIDRBlocks: []string{"10.0.0.0"}},
+			wantErr: true,
+		},
+		{
+			name:    "ipv6 allowed cidr block",
+			bastion: Bastion{AllowedCIDRBlocks: []string{"2001:db8::/32"}},
+			wantErr: true,
+		},
+		{
+			name:           "session manager",
+			sessionManager: true,
+			wantErr:        false,
+		},
+		{
+			name:           "session manager with the bastion explicitly enabled",
+			bastion:        Bastion{Enabled: pointer.BoolPtr(true)},
+			sessionManager: true,
+			wantErr:        true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cluster := &AWSCluster{
+				Spec: AWSClusterSpec{
+					Bastion:        tt.bastion,
+					SessionManager: tt.sessionManager,
+				},
+			}
+			if err := cluster.ValidateCreate(); (err != nil) != tt.wantErr {
+				t.Errorf("ValidateCreate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestAWSCluster_ValidateHealthCheck(t *testing.T) {
+	tests := []struct {
+		name             string
+		loadBalancerType LoadBalancerType
+		healthCheck      ClassicELBHealthCheckSpec
+		wantErr          bool
+	}{
+		{
+			name: "custom health check",
+			healthCheck: ClassicELBHealthCheckSpec{
+				Protocol:           ClassicELBProtocolHTTPS,
+				IntervalSeconds:    30,
+				TimeoutSeconds:     10,
+				HealthyThreshold:   3,
+				UnhealthyThreshold: 5,
+			},
+			wantErr: false,
+		},
+		{
+			name:        "unsupported protocol",
+			healthCheck: ClassicELBHealthCheckSpec{Protocol: ClassicELBProtocolHTTP},
+			wantErr:     true,
+		},
+		{
+			name:        "interval out of range",
+			healthCheck: ClassicELBHealthCheckSpec{IntervalSeconds: 301},
+			wantErr:     true,
+		},
+		{
+			name:        "threshold out of range",
+			healthCheck: ClassicELBHealthCheckSpec{HealthyThreshold: 1},
+			wantErr:     true,
+		},
+		{
+			name:        "timeout not less than the default interval",
+			healthCheck: ClassicELBHealthCheckSpec{TimeoutSeconds: 10},
+			wantErr:     true,
+		},
+		{
+			name:             "health check of a network load balancer",
+			loadBalancerType: LoadBalancerTypeNLB,
+			healthCheck:      ClassicELBHealthCheckSpec{IntervalSeconds: 30},
+			wantErr:          true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			healthCheck := tt.healthCheck
+			cluster := &AWSCluster{
+				Spec: AWSClusterSpec{
+					ControlPlaneLoadBalancer: &AWSLoadBalancerSpec{
+						LoadBalancerType: tt.loadBalancerType,
+						HealthCheck:      &healthCheck,
+					},
+				},
+			}
+			if err := cluster.ValidateCreate(); (err != nil) != tt.wantErr {
+				t.Errorf("ValidateCreate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestAWSCluster_ValidateProxyProtocol(t *testing.T) {
+	tests := []struct {
+		name             string
+		loadBalancerType LoadBalancerType
+		wantErr          bool
+	}{
+		{
+			name:    "proxy protocol on a classic load balancer",
+			wantErr: false,
+		},
+		{
+			name:             "proxy protocol on a network load balancer",
+			loadBalancerType: LoadBalancerTypeNLB,
+			wantErr:          true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cluster := &AWSCluster{
+				Spec: AWSClusterSpec{
+					ControlPlaneLoadBalancer: &AWSLoadBalancerSpec{
+						LoadBalancerType: tt.loadBalancerType,
+						ProxyProtocol:    true,
+					},
+				},
+			}
+			if err := cluster.ValidateCreate(); (err != nil) != tt.wantErr {
+				t.Errorf("ValidateCreate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestAWSCluster_ValidateLoadBalancerAttributes(t *testing.T) {
+	tests := []struct {
+		name    string
+		lb      AWSLoadBalancerSpec
+		wantErr bool
+	}{
+		{
+			name:    "idle timeout of a classic load balancer",
+			lb:      AWSLoadBalancerSpec{IdleTimeoutSeconds: 120},
+			wantErr: false,
+		},
+		{
+			name:    "idle timeout of a network load balancer",
+			lb:      AWSLoadBalancerSpec{LoadBalancerType: LoadBalancerTypeNLB, IdleTimeoutSeconds: 120},
+			wantErr: true,
+		},
+		{
+			name:    "cross-zone load balancing of a classic load balancer",
+			lb:      AWSLoadBalancerSpec{CrossZoneLoadBalancing: true},
+			wantErr: false,
+		},
+		{
+			name:    "cross-zone load balancing of a network load balancer",
+			lb:      AWSLoadBalancerSpec{LoadBalancerType: LoadBalancerTypeNLB, CrossZoneLoadBalancing: true},
+			wantErr: false,
+		},
+		{
+			name:    "deletion protection of a classic load balancer",
+			lb:      AWSLoadBalancerSpec{DeletionProtection: true},
+			wantErr: true,
+		},
+		{
+			name:    "deletion protection of a network load balancer",
+			lb:      AWSLoadBalancerSpec{LoadBalancerType: LoadBalancerTypeNLB, DeletionProtection: true},
+			wantErr: false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cluster := &AWSCluster{
+				Spec: AWSClusterSpec{
+					ControlPlaneLoadBalancer: tt.lb.DeepCopy(),
+				},
+			}
+			if err := cluster.ValidateCreate(); (err != nil) != tt.wantErr {
+				t.Errorf("ValidateCreate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestAWSCluster_ValidateLoadBalancerARN(t *testing.T) {
+	const (
+		classicARN = "arn:aws:elasticloadbalancing:us-east-1:123456789012:loadbalancer/central-apiserver"
+		nlbARN     = "arn:aws:elasticloadbalancing:us-east-1:123456789012:loadbalancer/net/central-apiserver/50dc6c495c0c9188"
+	)
+
+	tests := []struct {
+		name    string
+		lb      AWSLoadBalancerSpec
+		wantErr bool
+	}{
+		{
+			name:    "classic load balancer",
+			lb:      AWSLoadBalancerSpec{ARN: pointer.StringPtr(classicARN)},
+			wantErr: false,
+		},
+		{
+			name:    "network load balancer",
+			lb:      AWSLoadBalancerSpec{LoadBalancerType: LoadBalancerTypeNLB, ARN: pointer.StringPtr(nlbARN)},
+			wantErr: false,
+		},
+		{
+			name:    "network load balancer of the classic type",
+			lb:      AWSLoadBalancerSpec{ARN: pointer.StringPtr(nlbARN)},
+			wantErr: true,
+		},
+		{
+			name:    "application load balancer",
+			lb:      AWSLoadBalancerSpec{ARN: pointer.StringPtr("arn:aws:elasticloadbalancing:us-east-1:123456789012:loadbalancer/app/central/50dc6c495c0c9188")},
+			wantErr: true,
+		},
+		{
+			name:    "target group",
+			lb:      AWSLoadBalancerSpec{ARN: pointer.StringPtr("arn:aws:elasticloadbalancing:us-east-1:123456789012:targetgroup/central/73e2d6bc24d8a067")},
+			wantErr: true,
+		},
+		{
+			name:    "not an ARN",
+			lb:      AWSLoadBalancerSpec{ARN: pointer.StringPtr("central-apiserver")},
+			wantErr: true,
+		},
+		{
+			name: "scheme of an existing load balancer",
+			lb: AWSLoadBalancerSpec{
+				ARN:    pointer.StringPtr(classicARN),
+				Scheme: &ClassicELBSchemeInternal,
+			},
+			wantErr: true,
+		},
+		{
+			name: "health check of an existing load balancer",
+			lb: AWSLoadBalancerSpec{
+				ARN:         pointer.StringPtr(classicARN),
+				HealthCheck: &ClassicELBHealthCheckSpec{},
+			},
+			wantErr: true,
+		},
+		{
+			name: "cross-zone load balancing of an existing load balancer",
+			lb: AWSLoadBalancerSpec{
+				ARN:                    pointer.StringPtr(classicARN),
+				CrossZoneLoadBalancing: true,
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cluster := &AWSCluster{
+				Spec: AWSClusterSpec{
+					ControlPlaneLoadBalancer: tt.lb.DeepCopy(),
+				},
+			}
+			if err := cluster.ValidateCreate(); (err != nil) != tt.wantErr {
+				t.Errorf("ValidateCreate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestAWSCluster_ValidateUpdateLoadBalancerARN(t *testing.T) {
+	oldCluster := &AWSCluster{
+		Spec: AWSClusterSpec{
+			ControlPlaneEndpoint: clusterv1.APIEndpoint{Host: "elb.example.com", Port: 6443},
+			ControlPlaneLoadBalancer: &AWSLoadBalancerSpec{
+				ARN: pointer.StringPtr("arn:aws:elasticloadbalancing:us-east-1:123456789012:loadbalancer/central-apiserver"),
+			},
+		},
+	}
+	newCluster := oldCluster.DeepCopy()
+	newCluster.Spec.ControlPlaneLoadBalancer.ARN = pointer.StringPtr("arn:aws:elasticloadbalancing:us-east-1:123456789012:loadbalancer/other-apiserver")
+	if err := newCluster.ValidateUpdate(oldCluster); err == nil {
+		t.Error("expected an error changing the load balancer ARN once the control plane endpoint is set")
+	}
+
+	newCluster.Spec.ControlPlaneLoadBalancer.ARN = nil
+	if err := newCluster.ValidateUpdate(oldCluster); err == nil {
+		t.Error("expected an error removing the load balancer ARN once the control plane endpoint is set")
+	}
+}
@@ -0,0 +1,140 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha3
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// AWSIdentityKind is the kind of an identity AWSClusters can reference.
+type AWSIdentityKind string
+
+const (
+	// AWSClusterStaticIdentityKind is the kind of AWSClusterStaticIdentity.
+	AWSClusterStaticIdentityKind = AWSIdentityKind("AWSClusterStaticIdentity")
+
+	// AWSClusterRoleIdentityKind is the kind of AWSClusterRoleIdentity.
+	AWSClusterRoleIdentityKind = AWSIdentityKind("AWSClusterRoleIdentity")
+)
+
+// AWSIdentityReference references an identity the AWS calls of a cluster are
+// made with.
+type AWSIdentityReference struct {
+	// Name is the name of the identity.
+	// +kubebuilder:validation:MinLength=1
+	Name string `json:"name"`
+
+	// Kind is the kind of the identity.
+	// +kubebuilder:validation:Enum=AWSClusterStaticIdentity;AWSClusterRoleIdentity
+	Kind AWSIdentityKind `json:"kind"`
+}
+
+// AWSClusterIdentitySpec defines the fields common to all identities.
+type AWSClusterIdentitySpec struct {
+	// AllowedNamespaces restricts the namespaces of the AWSClusters which can
+	// use the identity. When unset, no AWSCluster can use it.
+	// +optional
+	AllowedNamespaces *AllowedNamespaces `json:"allowedNamespaces,omitempty"`
+}
+
+// AllowedNamespaces selects namespaces by name or by labels. A namespace
+// matching either is allowed, and an empty AllowedNamespaces allows all of
+// them.
+type AllowedNamespaces struct {
+	// NamespaceList lists the names of the namespaces allowed.
+	// +optional
+	NamespaceList []string `json:"list,omitempty"`
+
+	// Selector selects the namespaces allowed by their labels.
+	// +optional
+	Selector *metav1.LabelSelector `json:"selector,omitempty"`
+}
+
+// AWSClusterStaticIdentitySpec defines the specifications for
+// AWSClusterStaticIdentity.
+type AWSClusterStaticIdentitySpec struct {
+	AWSClusterIdentitySpec `json:",inline"`
+
+	// SecretRef references the secret holding the credentials, under the
+	// AccessKeyID, SecretAccessKey and optional SessionToken keys.
+	SecretRef corev1.SecretReference `json:"secretRef"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:resource:path=awsclusterstaticidentities,scope=Cluster,categories=cluster-api
+// +kubebuilder:storageversion
+
+// AWSClusterStaticIdentity is the Schema for the awsclusterstaticidentities
+// API. It holds static AWS credentials AWSClusters can be provisioned with.
+type AWSClusterStaticIdentity struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec AWSClusterStaticIdentitySpec `json:"spec,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// AWSClusterStaticIdentityList contains a list of AWSClusterStaticIdentity
+type AWSClusterStaticIdentityList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []AWSClusterStaticIdentity `json:"items"`
+}
+
+// AWSClusterRoleIdentitySpec defines the specifications for
+// AWSClusterRoleIdentity.
+type AWSClusterRoleIdentitySpec struct {
+	AWSClusterIdentitySpec `json:",inline"`
+	AssumeRoleSpec         `json:",inline"`
+
+	// SourceIdentityRef references the identity the role is assumed with,
+	// e.g. an AWSClusterStaticIdentity of the account trusted by the role.
+	// When unset, the role is assumed with the controller's own credentials.
+	// +optional
+	SourceIdentityRef *AWSIdentityReference `json:"sourceIdentityRef,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:resource:path=awsclusterroleidentities,scope=Cluster,categories=cluster-api
+// +kubebuilder:storageversion
+// +kubebuilder:printcolumn:name="Role",type="string",JSONPath=".spec.roleARN",description="Role assumed by the identity"
+
+// AWSClusterRoleIdentity is the Schema for the awsclusterroleidentities API.
+// It configures a role AWSClusters are provisioned with, e.g. in other AWS
+// accounts than the one of the controller.
+type AWSClusterRoleIdentity struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec AWSClusterRoleIdentitySpec `json:"spec,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// AWSClusterRoleIdentityList contains a list of AWSClusterRoleIdentity
+type AWSClusterRoleIdentityList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []AWSClusterRoleIdentity `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&AWSClusterStaticIdentity{}, &AWSClusterStaticIdentityList{})
+	SchemeBuilder.Register(&AWSClusterRoleIdentity{}, &AWSClusterRoleIdentityList{})
+}
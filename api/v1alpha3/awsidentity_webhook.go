@@ -0,0 +1,121 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha3
+
+import (
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
+)
+
+func (r *AWSClusterStaticIdentity) SetupWebhookWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewWebhookManagedBy(mgr).
+		For(r).
+		Complete()
+}
+
+// +kubebuilder:webhook:verbs=create;update,path=/validate-infrastructure-cluster-x-k8s-io-v1alpha3-awsclusterstaticidentity,mutating=false,failurePolicy=fail,groups=infrastructure.cluster.x-k8s.io,resources=awsclusterstaticidentities,versions=v1alpha3,name=validation.awsclusterstaticidentity.infrastructure.cluster.x-k8s.io
+
+var _ webhook.Validator = &AWSClusterStaticIdentity{}
+
+// ValidateCreate implements webhook.Validator so a webhook will be registered for the type
+func (r *AWSClusterStaticIdentity) ValidateCreate() error {
+	return r.validateSpec()
+}
+
+// ValidateUpdate implements webhook.Validator so a webhook will be registered for the type
+func (r *AWSClusterStaticIdentity) ValidateUpdate(old runtime.Object) error {
+	return r.validateSpec()
+}
+
+// ValidateDelete implements webhook.Validator so a webhook will be registered for the type
+func (r *AWSClusterStaticIdentity) ValidateDelete() error {
+	return nil
+}
+
+func (r *AWSClusterStaticIdentity) validateSpec() error {
+	allErrs := validateAllowedNamespaces(r.Spec.AllowedNamespaces, field.NewPath("spec", "allowedNamespaces"))
+
+	// The identity is cluster-scoped, the namespace of its secret can't be
+	// defaulted.
+	if r.Spec.SecretRef.Name == "" {
+		allErrs = append(allErrs, field.Required(field.NewPath("spec", "secretRef", "name"), "must be set"))
+	}
+	if r.Spec.SecretRef.Namespace == "" {
+		allErrs = append(allErrs, field.Required(field.NewPath("spec", "secretRef", "namespace"), "must be set"))
+	}
+
+	if len(allErrs) == 0 {
+		return nil
+	}
+	return apierrors.NewInvalid(GroupVersion.WithKind(string(AWSClusterStaticIdentityKind)).GroupKind(), r.Name, allErrs)
+}
+
+func (r *AWSClusterRoleIdentity) SetupWebhookWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewWebhookManagedBy(mgr).
+		For(r).
+		Complete()
+}
+
+// +kubebuilder:webhook:verbs=create;update,path=/validate-infrastructure-cluster-x-k8s-io-v1alpha3-awsclusterroleidentity,mutating=false,failurePolicy=fail,groups=infrastructure.cluster.x-k8s.io,resources=awsclusterroleidentities,versions=v1alpha3,name=validation.awsclusterroleidentity.infrastructure.cluster.x-k8s.io
+
+var _ webhook.Validator = &AWSClusterRoleIdentity{}
+
+// ValidateCreate implements webhook.Validator so a webhook will be registered for the type
+func (r *AWSClusterRoleIdentity) ValidateCreate() error {
+	return r.validateSpec()
+}
+
+// ValidateUpdate implements webhook.Validator so a webhook will be registered for the type
+func (r *AWSClusterRoleIdentity) ValidateUpdate(old runtime.Object) error {
+	return r.validateSpec()
+}
+
+// ValidateDelete implements webhook.Validator so a webhook will be registered for the type
+func (r *AWSClusterRoleIdentity) ValidateDelete() error {
+	return nil
+}
+
+func (r *AWSClusterRoleIdentity) validateSpec() error {
+	allErrs := validateAllowedNamespaces(r.Spec.AllowedNamespaces, field.NewPath("spec", "allowedNamespaces"))
+	allErrs = append(allErrs, validateAssumeRole(&r.Spec.AssumeRoleSpec, field.NewPath("spec"))...)
+
+	if ref := r.Spec.SourceIdentityRef; ref != nil && ref.Kind == AWSClusterRoleIdentityKind && ref.Name == r.Name {
+		allErrs = append(allErrs, field.Invalid(field.NewPath("spec", "sourceIdentityRef", "name"), ref.Name, "cannot reference the identity itself"))
+	}
+
+	if len(allErrs) == 0 {
+		return nil
+	}
+	return apierrors.NewInvalid(GroupVersion.WithKind(string(AWSClusterRoleIdentityKind)).GroupKind(), r.Name, allErrs)
+}
+
+func validateAllowedNamespaces(allowed *AllowedNamespaces, fldPath *field.Path) field.ErrorList {
+	var allErrs field.ErrorList
+
+	if allowed == nil || allowed.Selector == nil {
+		return allErrs
+	}
+	if _, err := metav1.LabelSelectorAsSelector(allowed.Selector); err != nil {
+		allErrs = append(allErrs, field.Invalid(fldPath.Child("selector"), allowed.Selector, err.Error()))
+	}
+
+	return allErrs
+}
@@ -43,9 +43,33 @@ type AWSMachineSpec struct {
 	// image lookup the AMI is not set.
 	ImageLookupBaseOS string `json:"imageLookupBaseOS,omitempty"`
 
+	// ImageLookupFormat is the name glob to use for image lookup if AMI is not
+	// set. It is a Go template that can use {{.BaseOS}} and {{.K8sVersion}},
+	// e.g. capa-ami-{{.BaseOS}}-{{.K8sVersion}}-00-1580000000. The Kubernetes
+	// version is given without the leading "v". Unlike the default lookup,
+	// which picks the newest matching image, the lookup fails unless exactly
+	// one image matches, so the selected image is fully deterministic.
+	// +optional
+	ImageLookupFormat string `json:"imageLookupFormat,omitempty"`
+
+	// ImageLookupSSMParameter is the name of an SSM parameter holding the ID of
+	// the AMI to use, e.g. /my-org/k8s/1.17/ami. It is resolved each time an
+	// instance is created, so the AMI can be rotated by updating the
+	// parameter. It can't be set together with AMI.ID.
+	// +optional
+	// +kubebuilder:validation:MaxLength=2048
+	// +kubebuilder:validation:Pattern=`^[a-zA-Z0-9_.\-/]+$`
+	ImageLookupSSMParameter string `json:"imageLookupSSMParameter,omitempty"`
+
 	// InstanceType is the type of instance to create. Example: m4.xlarge
 	InstanceType string `json:"instanceType,omitempty"`
 
+	// AlternativeInstanceTypes are instance types to try, in order, when EC2
+	// has no capacity left for InstanceType. The type that was launched is
+	// recorded in the status.
+	// +optional
+	AlternativeInstanceTypes []string `json:"alternativeInstanceTypes,omitempty"`
+
 	// AdditionalTags is an optional set of tags to add to an instance, in addition to the ones added by default by the
 	// AWS provider. If both the AWSCluster and the AWSMachine specify the same tag name with different values, the
 	// AWSMachine's value takes precedence.
@@ -53,6 +77,9 @@ type AWSMachineSpec struct {
 	AdditionalTags Tags `json:"additionalTags,omitempty"`
 
 	// IAMInstanceProfile is a name of an IAM instance profile to assign to the instance
+	// Defaults to the AWSCluster's instance profile for the machine's role.
+	// Any existing instance profile can be used, it is never created or
+	// modified, and the instance is not launched until it exists.
 	// +optional
 	IAMInstanceProfile string `json:"iamInstanceProfile,omitempty"`
 
@@ -61,12 +88,23 @@ type AWSMachineSpec struct {
 	// 1. This field if set
 	// 2. Cluster/flavor setting
 	// 3. Subnet default
+	// When set, it is applied to the primary network interface and overrides
+	// the MapPublicIpOnLaunch setting of the subnet, including when false.
+	// Setting it to true for a subnet without a route to an internet gateway
+	// fails, such an address would not be reachable.
 	// +optional
 	PublicIP *bool `json:"publicIP,omitempty"`
 
+	// ElasticIPPool associates an Elastic IP with the instance once it is
+	// running, so that it keeps a static public IP address, e.g. for
+	// allow-listing. The instance must be in a public subnet.
+	// +optional
+	ElasticIPPool *ElasticIPPool `json:"elasticIPPool,omitempty"`
+
 	// AdditionalSecurityGroups is an array of references to security groups that should be applied to the
 	// instance. These security groups would be set in addition to any security groups defined
-	// at the cluster level or in the actuator.
+	// at the cluster level or in the actuator. Security groups can be referenced
+	// by ID or by filters, and must belong to the cluster VPC.
 	// +optional
 	AdditionalSecurityGroups []AWSResourceReference `json:"additionalSecurityGroups,omitempty"`
 
@@ -91,14 +129,139 @@ type AWSMachineSpec struct {
 	SSHKeyName string `json:"sshKeyName,omitempty"`
 
 	// RootDeviceSize is the size of the root volume in gigabytes(GB).
+	// It can be increased on an existing AWSMachine, in which case the root
+	// volume is grown in place, but it cannot be decreased.
 	// +optional
 	RootDeviceSize int64 `json:"rootDeviceSize,omitempty"`
 
+	// RootVolume encapsulates the configuration options for the root volume,
+	// such as its type, IOPS and encryption. It cannot be set together with
+	// RootDeviceSize.
+	// +optional
+	RootVolume *Volume `json:"rootVolume,omitempty"`
+
 	// NetworkInterfaces is a list of ENIs to associate with the instance.
 	// A maximum of 2 may be specified.
 	// +optional
 	// +kubebuilder:validation:MaxItems=2
 	NetworkInterfaces []string `json:"networkInterfaces,omitempty"`
+
+	// NetworkInterfaceSpecs are the network interfaces created with the
+	// instance, e.g. to give it a second interface in another subnet. The
+	// list must include the primary interface, with device index 0. When
+	// unset, the instance gets a single interface in its subnet. Cannot be
+	// set together with subnet or networkInterfaces.
+	// +optional
+	NetworkInterfaceSpecs []NetworkInterfaceSpec `json:"networkInterfaceSpecs,omitempty"`
+
+	// NetworkInterfaceType is the type of the network interfaces created with
+	// the instance. Elastic Fabric Adapters (efa) are only available on some
+	// instance types, which EC2 checks when the instance is launched. Cannot
+	// be set together with networkInterfaces.
+	// +optional
+	// +kubebuilder:validation:Enum=interface;efa
+	NetworkInterfaceType NetworkInterfaceType `json:"networkInterfaceType,omitempty"`
+
+	// AutoRecovery creates a CloudWatch alarm that recovers the instance onto
+	// new hardware when it fails the EC2 system status check.
+	// The instance type must support EC2 auto-recovery.
+	// +optional
+	AutoRecovery bool `json:"autoRecovery,omitempty"`
+
+	// KubeletRegistration, when set, passes node labels and taints to the kubelet
+	// through the instance user data, so the node registers with them.
+	// Leave it unset when the bootstrap provider already configures them.
+	// +optional
+	KubeletRegistration *KubeletRegistration `json:"kubeletRegistration,omitempty"`
+
+	// UserDataSecret references a secret in the namespace of the AWSMachine
+	// holding the raw user data of the instance, e.g. a cloud-init config of a
+	// bootstrap flow that doesn't use kubeadm. It is passed to EC2 as is,
+	// without compression or KubeletRegistration, and replaces the bootstrap
+	// data of the Machine, which must then have neither bootstrap.configRef
+	// nor bootstrap.dataSecretName.
+	// +optional
+	UserDataSecret *UserDataSecretReference `json:"userDataSecret,omitempty"`
+
+	// CloudInit configures how the bootstrap data reaches the instance.
+	// It cannot be set together with UserDataSecret.
+	// +optional
+	CloudInit *CloudInit `json:"cloudInit,omitempty"`
+
+	// SpotMarketOptions allows users to configure instances to be run using AWS Spot instances.
+	// An interrupted spot instance is handled like a deleted instance, so the Machine gets replaced,
+	// unless its interruptionBehavior is stop or hibernate.
+	// +optional
+	SpotMarketOptions *SpotMarketOptions `json:"spotMarketOptions,omitempty"`
+
+	// PlacementGroupName is the name of an existing placement group to launch
+	// the instance in. It cannot be changed once the instance is launched.
+	// +optional
+	PlacementGroupName string `json:"placementGroupName,omitempty"`
+
+	// PlacementGroupPartition is the partition to launch the instance in when
+	// PlacementGroupName refers to a partition placement group. EC2 picks one
+	// when it is not set.
+	// +optional
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=7
+	PlacementGroupPartition int64 `json:"placementGroupPartition,omitempty"`
+
+	// PlacementGroupStrategy makes the controllers create the placement group
+	// named PlacementGroupName with this strategy when it doesn't exist yet.
+	// Partition placement groups are created with 7 partitions. Created
+	// placement groups are left in place when the cluster is deleted.
+	// +optional
+	// +kubebuilder:validation:Enum=cluster;partition;spread
+	PlacementGroupStrategy PlacementGroupStrategy `json:"placementGroupStrategy,omitempty"`
+
+	// Tenancy is the tenancy of the instance, dedicated hardware can be
+	// required for license compliance. It cannot be changed once the
+	// instance is launched.
+	// +optional
+	// +kubebuilder:validation:Enum=default;dedicated;host
+	Tenancy Tenancy `json:"tenancy,omitempty"`
+
+	// HostID is the ID of the dedicated host to launch the instance on when
+	// Tenancy is host. EC2 picks one of the hosts of the account with auto
+	// placement enabled when it is not set. It cannot be changed once the
+	// instance is launched.
+	// +optional
+	// +kubebuilder:validation:Pattern=^h-
+	HostID string `json:"hostId,omitempty"`
+
+	// CapacityReservationPreference selects whether the instance runs in any
+	// open On-Demand Capacity Reservation matching its attributes, or in none.
+	// Defaults to open. It cannot be set together with CapacityReservationID.
+	// +optional
+	// +kubebuilder:validation:Enum=open;none
+	CapacityReservationPreference CapacityReservationPreference `json:"capacityReservationPreference,omitempty"`
+
+	// CapacityReservationID is the ID of the On-Demand Capacity Reservation to
+	// run the instance in. The instance type and availability zone of the
+	// machine must match the ones of the reservation, which is checked before
+	// the instance is launched.
+	// +optional
+	// +kubebuilder:validation:Pattern=^cr-
+	CapacityReservationID string `json:"capacityReservationId,omitempty"`
+
+	// InstanceMetadataOptions configures the instance metadata service of the
+	// instance. Changes, including the ones made outside of Kubernetes, are
+	// applied to the running instance.
+	// +optional
+	InstanceMetadataOptions *InstanceMetadataOptions `json:"instanceMetadataOptions,omitempty"`
+
+	// DisableAPITermination enables termination protection on the instance,
+	// so that it can't be terminated from the console or the API by accident.
+	// Protection is removed before the instance is deleted together with the
+	// AWSMachine, and is restored if it is disabled outside of Kubernetes.
+	// +optional
+	DisableAPITermination bool `json:"disableApiTermination,omitempty"`
+
+	// NonRootVolumes are additional EBS data volumes attached to the instance
+	// at launch. They can't be changed once the instance is launched.
+	// +optional
+	NonRootVolumes []Volume `json:"nonRootVolumes,omitempty"`
 }
 
 // AWSMachineStatus defines the observed state of AWSMachine
@@ -114,6 +277,17 @@ type AWSMachineStatus struct {
 	// +optional
 	InstanceState *InstanceState `json:"instanceState,omitempty"`
 
+	// InstanceType is the type of the AWS instance for this machine, one of
+	// the instance type and the alternative instance types of the spec.
+	// +optional
+	InstanceType string `json:"instanceType,omitempty"`
+
+	// InstanceProfileAssociation is the state of the association between the
+	// instance and its IAM instance profile, when one is set. It is notFound
+	// while the instance can't be launched because the profile is missing.
+	// +optional
+	InstanceProfileAssociation *InstanceProfileAssociationState `json:"instanceProfileAssociation,omitempty"`
+
 	// FailureReason will be set in the event that there is a terminal problem
 	// reconciling the Machine and will contain a succinct value suitable
 	// for machine interpretation.
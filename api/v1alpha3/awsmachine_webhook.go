@@ -17,11 +17,19 @@ limitations under the License.
 package v1alpha3
 
 import (
+	"io/ioutil"
+	"math"
 	"reflect"
+	"strconv"
+	"strings"
+	"text/template"
 
 	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/apimachinery/pkg/util/validation"
 	"k8s.io/apimachinery/pkg/util/validation/field"
 	ctrl "sigs.k8s.io/controller-runtime"
 	logf "sigs.k8s.io/controller-runtime/pkg/runtime/log"
@@ -41,8 +49,22 @@ func (r *AWSMachine) SetupWebhookWithManager(mgr ctrl.Manager) error {
 
 var _ webhook.Validator = &AWSMachine{}
 
+// autoRecoveryInstanceFamilies are the EC2 instance families that support auto-recovery.
+// See https://docs.aws.amazon.com/AWSEC2/latest/UserGuide/ec2-instance-recover.html
+var autoRecoveryInstanceFamilies = sets.NewString(
+	"a1", "c3", "c4", "c5", "c5n", "m3", "m4", "m5", "m5a", "m5n",
+	"p3", "r3", "r4", "r5", "r5a", "r5n", "t2", "t3", "t3a", "x1", "x1e",
+)
+
+// rootDeviceNames are the device names AMIs use for the root volume. Data
+// volumes can't be attached under these names.
+var rootDeviceNames = sets.NewString("/dev/sda1", "/dev/xvda")
+
 // ValidateCreate implements webhook.Validator so a webhook will be registered for the type
 func (r *AWSMachine) ValidateCreate() error {
+	if allErrs := validateAWSMachineSpec(&r.Spec, field.NewPath("spec")); len(allErrs) > 0 {
+		return apierrors.NewInvalid(GroupVersion.WithKind("AWSMachine").GroupKind(), r.Name, allErrs)
+	}
 	return nil
 }
 
@@ -78,8 +100,50 @@ func (r *AWSMachine) ValidateUpdate(old runtime.Object) error {
 	delete(oldAWSMachineSpec, "additionalSecurityGroups")
 	delete(newAWSMachineSpec, "additionalSecurityGroups")
 
+	// allow increasing rootDeviceSize, the root volume is grown in place
+	if oldMachine, ok := old.(*AWSMachine); ok && r.Spec.RootDeviceSize < oldMachine.Spec.RootDeviceSize {
+		allErrs = append(allErrs, field.Forbidden(field.NewPath("spec", "rootDeviceSize"), "cannot be decreased"))
+	}
+	delete(oldAWSMachineSpec, "rootDeviceSize")
+	delete(newAWSMachineSpec, "rootDeviceSize")
+
+	// placement can't be changed once the instance is launched
+	if oldMachine, ok := old.(*AWSMachine); ok {
+		if r.Spec.PlacementGroupName != oldMachine.Spec.PlacementGroupName {
+			allErrs = append(allErrs, field.Forbidden(field.NewPath("spec", "placementGroupName"), "cannot be changed after the instance is launched"))
+		}
+		if r.Spec.PlacementGroupPartition != oldMachine.Spec.PlacementGroupPartition {
+			allErrs = append(allErrs, field.Forbidden(field.NewPath("spec", "placementGroupPartition"), "cannot be changed after the instance is launched"))
+		}
+		if r.Spec.Tenancy != oldMachine.Spec.Tenancy {
+			allErrs = append(allErrs, field.Forbidden(field.NewPath("spec", "tenancy"), "cannot be changed after the instance is launched"))
+		}
+		if r.Spec.HostID != oldMachine.Spec.HostID {
+			allErrs = append(allErrs, field.Forbidden(field.NewPath("spec", "hostId"), "cannot be changed after the instance is launched"))
+		}
+	}
+	delete(oldAWSMachineSpec, "placementGroupName")
+	delete(newAWSMachineSpec, "placementGroupName")
+	delete(oldAWSMachineSpec, "placementGroupPartition")
+	delete(newAWSMachineSpec, "placementGroupPartition")
+	delete(oldAWSMachineSpec, "tenancy")
+	delete(newAWSMachineSpec, "tenancy")
+	delete(oldAWSMachineSpec, "hostId")
+	delete(newAWSMachineSpec, "hostId")
+
+	// allow changes to instanceMetadataOptions, they are applied to the running instance
+	delete(oldAWSMachineSpec, "instanceMetadataOptions")
+	delete(newAWSMachineSpec, "instanceMetadataOptions")
+
+	// allow changes to disableApiTermination, it is applied to the running instance
+	delete(oldAWSMachineSpec, "disableApiTermination")
+	delete(newAWSMachineSpec, "disableApiTermination")
+
 	if !reflect.DeepEqual(oldAWSMachineSpec, newAWSMachineSpec) {
 		allErrs = append(allErrs, field.Forbidden(field.NewPath("spec"), "cannot be modified"))
+	}
+
+	if len(allErrs) > 0 {
 		return apierrors.NewInvalid(
 			GroupVersion.WithKind("AWSMachine").GroupKind(),
 			r.Name, allErrs)
@@ -92,3 +156,343 @@ func (r *AWSMachine) ValidateUpdate(old runtime.Object) error {
 func (r *AWSMachine) ValidateDelete() error {
 	return nil
 }
+
+func validateAWSMachineSpec(spec *AWSMachineSpec, fldPath *field.Path) field.ErrorList {
+	var allErrs field.ErrorList
+	allErrs = append(allErrs, validateAutoRecovery(spec, fldPath)...)
+	if spec.AMI.ID != nil && spec.ImageLookupSSMParameter != "" {
+		allErrs = append(allErrs, field.Forbidden(fldPath.Child("imageLookupSSMParameter"), "cannot be set together with ami.id"))
+	}
+	allErrs = append(allErrs, validateImageLookupFormat(spec.ImageLookupFormat, fldPath.Child("imageLookupFormat"))...)
+	for i, ref := range spec.AdditionalSecurityGroups {
+		if ref.ID == nil && len(ref.Filters) == 0 {
+			allErrs = append(allErrs, field.Required(fldPath.Child("additionalSecurityGroups").Index(i), "either id or filters must be set"))
+		}
+	}
+	if spec.KubeletRegistration != nil {
+		allErrs = append(allErrs, validateTaints(spec.KubeletRegistration.Taints, fldPath.Child("kubeletRegistration", "taints"))...)
+	}
+	allErrs = append(allErrs, validateAlternativeInstanceTypes(spec, fldPath)...)
+	allErrs = append(allErrs, validateRootVolume(spec, fldPath)...)
+	allErrs = append(allErrs, validateNonRootVolumes(spec.NonRootVolumes, fldPath.Child("nonRootVolumes"))...)
+	if spec.PlacementGroupPartition != 0 && spec.PlacementGroupName == "" {
+		allErrs = append(allErrs, field.Required(fldPath.Child("placementGroupName"), "must be set when placementGroupPartition is set"))
+	}
+	if spec.PlacementGroupStrategy != "" {
+		if spec.PlacementGroupName == "" {
+			allErrs = append(allErrs, field.Required(fldPath.Child("placementGroupName"), "must be set when placementGroupStrategy is set"))
+		}
+		if spec.PlacementGroupPartition != 0 && spec.PlacementGroupStrategy != PlacementGroupStrategyPartition {
+			allErrs = append(allErrs, field.Forbidden(fldPath.Child("placementGroupPartition"), "can only be set when placementGroupStrategy is partition"))
+		}
+	}
+	if spec.HostID != "" && spec.Tenancy != TenancyHost {
+		allErrs = append(allErrs, field.Forbidden(fldPath.Child("hostId"), "can only be set when tenancy is host"))
+	}
+	allErrs = append(allErrs, validateCapacityReservation(spec, fldPath)...)
+	allErrs = append(allErrs, validateNetworkInterfaceSpecs(spec, fldPath)...)
+	if spec.NetworkInterfaceType != "" && len(spec.NetworkInterfaces) > 0 {
+		allErrs = append(allErrs, field.Forbidden(fldPath.Child("networkInterfaceType"), "cannot be set together with networkInterfaces, the type of an existing interface can't be changed"))
+	}
+	allErrs = append(allErrs, validatePublicIP(spec, fldPath)...)
+	allErrs = append(allErrs, validateElasticIPPool(spec, fldPath)...)
+	allErrs = append(allErrs, validateUserDataSecret(spec, fldPath)...)
+	if spec.SpotMarketOptions != nil && spec.SpotMarketOptions.MaxPrice != nil {
+		maxPrice := *spec.SpotMarketOptions.MaxPrice
+		if price, err := strconv.ParseFloat(maxPrice, 64); err != nil || !(price > 0) || math.IsInf(price, 1) {
+			allErrs = append(allErrs, field.Invalid(fldPath.Child("spotMarketOptions", "maxPrice"), maxPrice, "must be a positive decimal number"))
+		}
+	}
+	return allErrs
+}
+
+// validateImageLookupFormat checks that the format is a template which only
+// uses the values the image lookup provides.
+func validateImageLookupFormat(format string, fldPath *field.Path) field.ErrorList {
+	if format == "" {
+		return nil
+	}
+	tmpl, err := template.New("imageLookupFormat").Option("missingkey=error").Parse(format)
+	if err == nil {
+		err = tmpl.Execute(ioutil.Discard, struct{ BaseOS, K8sVersion string }{})
+	}
+	if err != nil {
+		return field.ErrorList{field.Invalid(fldPath, format, "must be a template using only {{.BaseOS}} and {{.K8sVersion}}")}
+	}
+	return nil
+}
+
+func validateAutoRecovery(spec *AWSMachineSpec, fldPath *field.Path) field.ErrorList {
+	if !spec.AutoRecovery {
+		return nil
+	}
+
+	var allErrs field.ErrorList
+	if !autoRecoveryInstanceFamilies.Has(instanceFamily(spec.InstanceType)) {
+		allErrs = append(allErrs, field.Invalid(fldPath.Child("instanceType"), spec.InstanceType, "instance type does not support auto-recovery"))
+	}
+	for i, instanceType := range spec.AlternativeInstanceTypes {
+		if !autoRecoveryInstanceFamilies.Has(instanceFamily(instanceType)) {
+			allErrs = append(allErrs, field.Invalid(fldPath.Child("alternativeInstanceTypes").Index(i), instanceType, "instance type does not support auto-recovery"))
+		}
+	}
+	return allErrs
+}
+
+func instanceFamily(instanceType string) string {
+	return strings.SplitN(instanceType, ".", 2)[0]
+}
+
+// validateNetworkInterfaceSpecs checks that the network interfaces can be
+// created with the instance.
+func validateNetworkInterfaceSpecs(spec *AWSMachineSpec, fldPath *field.Path) field.ErrorList {
+	if len(spec.NetworkInterfaceSpecs) == 0 {
+		return nil
+	}
+
+	var allErrs field.ErrorList
+	specsPath := fldPath.Child("networkInterfaceSpecs")
+	if spec.Subnet != nil {
+		allErrs = append(allErrs, field.Forbidden(fldPath.Child("subnet"), "cannot be set together with networkInterfaceSpecs, set the subnet of each interface instead"))
+	}
+	if len(spec.NetworkInterfaces) > 0 {
+		allErrs = append(allErrs, field.Forbidden(fldPath.Child("networkInterfaces"), "cannot be set together with networkInterfaceSpecs"))
+	}
+
+	seen := sets.NewInt64()
+	for i, iface := range spec.NetworkInterfaceSpecs {
+		idxPath := specsPath.Index(i)
+		if seen.Has(iface.DeviceIndex) {
+			allErrs = append(allErrs, field.Duplicate(idxPath.Child("deviceIndex"), iface.DeviceIndex))
+		}
+		seen.Insert(iface.DeviceIndex)
+
+		if iface.Subnet != nil && iface.Subnet.ID == nil && len(iface.Subnet.Filters) == 0 {
+			allErrs = append(allErrs, field.Required(idxPath.Child("subnet"), "either id or filters must be set"))
+		}
+		for j, ref := range iface.SecurityGroups {
+			if ref.ID == nil && len(ref.Filters) == 0 {
+				allErrs = append(allErrs, field.Required(idxPath.Child("securityGroups").Index(j), "either id or filters must be set"))
+			}
+		}
+
+		// EC2 only assigns a public IP to the primary interface of an
+		// instance launched with a single interface.
+		if iface.PublicIP != nil && *iface.PublicIP && (iface.DeviceIndex != 0 || len(spec.NetworkInterfaceSpecs) > 1) {
+			allErrs = append(allErrs, field.Forbidden(idxPath.Child("publicIP"), "can only be set on the primary interface of an instance with a single interface"))
+		}
+	}
+	if !seen.Has(0) {
+		allErrs = append(allErrs, field.Required(specsPath, "must include the primary interface, with device index 0"))
+	}
+
+	return allErrs
+}
+
+// validatePublicIP checks that the public IP setting of the machine can be
+// applied to its primary network interface.
+func validatePublicIP(spec *AWSMachineSpec, fldPath *field.Path) field.ErrorList {
+	if spec.PublicIP == nil {
+		return nil
+	}
+
+	var allErrs field.ErrorList
+	publicIPPath := fldPath.Child("publicIP")
+	if len(spec.NetworkInterfaces) > 0 {
+		allErrs = append(allErrs, field.Forbidden(publicIPPath, "cannot be set together with networkInterfaces, the public IP of an existing interface can't be changed at launch"))
+	}
+	if *spec.PublicIP && len(spec.NetworkInterfaceSpecs) > 1 {
+		allErrs = append(allErrs, field.Forbidden(publicIPPath, "cannot be true for an instance with more than one interface"))
+	}
+	for i, iface := range spec.NetworkInterfaceSpecs {
+		if iface.DeviceIndex == 0 && iface.PublicIP != nil && *iface.PublicIP != *spec.PublicIP {
+			allErrs = append(allErrs, field.Invalid(fldPath.Child("networkInterfaceSpecs").Index(i).Child("publicIP"), *iface.PublicIP, "must match publicIP of the machine"))
+		}
+	}
+
+	return allErrs
+}
+
+// validateElasticIPPool checks that an Elastic IP can be associated with the
+// instance.
+func validateElasticIPPool(spec *AWSMachineSpec, fldPath *field.Path) field.ErrorList {
+	if spec.ElasticIPPool == nil {
+		return nil
+	}
+
+	var allErrs field.ErrorList
+	poolPath := fldPath.Child("elasticIPPool")
+	if spec.PublicIP != nil && !*spec.PublicIP {
+		allErrs = append(allErrs, field.Forbidden(poolPath, "cannot be set when publicIP is false"))
+	}
+
+	seen := sets.NewString()
+	for i, id := range spec.ElasticIPPool.AllocationIDs {
+		idPath := poolPath.Child("allocationIDs").Index(i)
+		if !strings.HasPrefix(id, "eipalloc-") {
+			allErrs = append(allErrs, field.Invalid(idPath, id, "must be an Elastic IP allocation ID"))
+		}
+		if seen.Has(id) {
+			allErrs = append(allErrs, field.Duplicate(idPath, id))
+		}
+		seen.Insert(id)
+	}
+
+	return allErrs
+}
+
+// validateUserDataSecret checks the reference to the raw user data of the
+// machine. The secret is always in the namespace of the machine.
+func validateUserDataSecret(spec *AWSMachineSpec, fldPath *field.Path) field.ErrorList {
+	ref := spec.UserDataSecret
+	if ref == nil {
+		return nil
+	}
+
+	var allErrs field.ErrorList
+	refPath := fldPath.Child("userDataSecret")
+	for _, msg := range validation.IsDNS1123Subdomain(ref.Name) {
+		allErrs = append(allErrs, field.Invalid(refPath.Child("name"), ref.Name, msg))
+	}
+	if ref.Key != "" {
+		for _, msg := range validation.IsConfigMapKey(ref.Key) {
+			allErrs = append(allErrs, field.Invalid(refPath.Child("key"), ref.Key, msg))
+		}
+	}
+	if spec.KubeletRegistration != nil {
+		allErrs = append(allErrs, field.Forbidden(fldPath.Child("kubeletRegistration"), "cannot be set together with userDataSecret, raw user data is passed to the instance as is"))
+	}
+	if spec.CloudInit != nil && spec.CloudInit.SecureSecretsBackend != "" {
+		allErrs = append(allErrs, field.Forbidden(fldPath.Child("cloudInit", "secureSecretsBackend"), "cannot be set together with userDataSecret, raw user data is passed to the instance as is"))
+	}
+
+	return allErrs
+}
+
+// validateCapacityReservation checks that a targeted capacity reservation can
+// be used by the machine. Whether the reservation matches the instance type
+// and availability zone of the machine can only be checked at launch time.
+func validateCapacityReservation(spec *AWSMachineSpec, fldPath *field.Path) field.ErrorList {
+	if spec.CapacityReservationID == "" {
+		return nil
+	}
+
+	var allErrs field.ErrorList
+	idPath := fldPath.Child("capacityReservationId")
+	if spec.CapacityReservationPreference != "" {
+		allErrs = append(allErrs, field.Forbidden(fldPath.Child("capacityReservationPreference"), "cannot be set together with capacityReservationId"))
+	}
+	if spec.SpotMarketOptions != nil {
+		allErrs = append(allErrs, field.Forbidden(idPath, "cannot be set for spot instances"))
+	}
+	if len(spec.AlternativeInstanceTypes) > 0 {
+		allErrs = append(allErrs, field.Forbidden(idPath, "cannot be set together with alternativeInstanceTypes, the instance type must match the reservation"))
+	}
+	return allErrs
+}
+
+func validateAlternativeInstanceTypes(spec *AWSMachineSpec, fldPath *field.Path) field.ErrorList {
+	var allErrs field.ErrorList
+
+	seen := sets.NewString(spec.InstanceType)
+	for i, instanceType := range spec.AlternativeInstanceTypes {
+		idxPath := fldPath.Child("alternativeInstanceTypes").Index(i)
+		switch {
+		case instanceType == "":
+			allErrs = append(allErrs, field.Required(idxPath, "must not be empty"))
+		case seen.Has(instanceType):
+			allErrs = append(allErrs, field.Duplicate(idxPath, instanceType))
+		}
+		seen.Insert(instanceType)
+	}
+
+	return allErrs
+}
+
+func validateRootVolume(spec *AWSMachineSpec, fldPath *field.Path) field.ErrorList {
+	if spec.RootVolume == nil {
+		return nil
+	}
+
+	var allErrs field.ErrorList
+
+	rootPath := fldPath.Child("rootVolume")
+	if spec.RootDeviceSize != 0 {
+		allErrs = append(allErrs, field.Forbidden(rootPath, "cannot be set together with rootDeviceSize"))
+	}
+	if spec.RootVolume.DeviceName != "" {
+		allErrs = append(allErrs, field.Forbidden(rootPath.Child("deviceName"), "is taken from the AMI for the root volume"))
+	}
+	allErrs = append(allErrs, validateVolume(spec.RootVolume, rootPath)...)
+
+	return allErrs
+}
+
+func validateNonRootVolumes(volumes []Volume, fldPath *field.Path) field.ErrorList {
+	var allErrs field.ErrorList
+
+	seen := sets.NewString()
+	for i, volume := range volumes {
+		idxPath := fldPath.Index(i)
+		switch {
+		case volume.DeviceName == "":
+			allErrs = append(allErrs, field.Required(idxPath.Child("deviceName"), "must not be empty"))
+		case rootDeviceNames.Has(volume.DeviceName):
+			allErrs = append(allErrs, field.Invalid(idxPath.Child("deviceName"), volume.DeviceName, "is reserved for the root volume"))
+		case seen.Has(volume.DeviceName):
+			allErrs = append(allErrs, field.Duplicate(idxPath.Child("deviceName"), volume.DeviceName))
+		}
+		seen.Insert(volume.DeviceName)
+
+		allErrs = append(allErrs, validateVolume(&volumes[i], idxPath)...)
+	}
+
+	return allErrs
+}
+
+func validateVolume(volume *Volume, fldPath *field.Path) field.ErrorList {
+	var allErrs field.ErrorList
+
+	if volume.Size <= 0 {
+		allErrs = append(allErrs, field.Invalid(fldPath.Child("size"), volume.Size, "must be greater than 0"))
+	}
+
+	provisionedIOPS := volume.Type == VolumeTypeIO1 || volume.Type == VolumeTypeIO2
+	switch {
+	case provisionedIOPS && volume.IOPS == 0:
+		allErrs = append(allErrs, field.Required(fldPath.Child("iops"), "must be set for io1 and io2 volumes"))
+	case !provisionedIOPS && volume.IOPS != 0:
+		allErrs = append(allErrs, field.Forbidden(fldPath.Child("iops"), "can only be set for io1 and io2 volumes"))
+	}
+
+	if volume.KMSKeyID != "" && !volume.Encrypted {
+		allErrs = append(allErrs, field.Forbidden(fldPath.Child("kmsKeyID"), "can only be set for encrypted volumes"))
+	}
+
+	return allErrs
+}
+
+func validateTaints(taints []corev1.Taint, fldPath *field.Path) field.ErrorList {
+	var allErrs field.ErrorList
+
+	for i, taint := range taints {
+		idxPath := fldPath.Index(i)
+
+		for _, msg := range validation.IsQualifiedName(taint.Key) {
+			allErrs = append(allErrs, field.Invalid(idxPath.Child("key"), taint.Key, msg))
+		}
+		for _, msg := range validation.IsValidLabelValue(taint.Value) {
+			allErrs = append(allErrs, field.Invalid(idxPath.Child("value"), taint.Value, msg))
+		}
+
+		switch taint.Effect {
+		case corev1.TaintEffectNoSchedule, corev1.TaintEffectPreferNoSchedule, corev1.TaintEffectNoExecute:
+		default:
+			allErrs = append(allErrs, field.NotSupported(idxPath.Child("effect"), taint.Effect, []string{
+				string(corev1.TaintEffectNoSchedule), string(corev1.TaintEffectPreferNoSchedule), string(corev1.TaintEffectNoExecute),
+			}))
+		}
+	}
+
+	return allErrs
+}
@@ -19,6 +19,7 @@ package v1alpha3
 import (
 	"testing"
 
+	corev1 "k8s.io/api/core/v1"
 	"k8s.io/utils/pointer"
 )
 
@@ -79,6 +80,90 @@ func TestAWSMachine_ValidateUpdate(t *testing.T) {
 			},
 			wantErr: true,
 		},
+		{
+			name: "increase in root device size",
+			oldMachine: &AWSMachine{
+				Spec: AWSMachineSpec{
+					RootDeviceSize: 20,
+				},
+			},
+			newMachine: &AWSMachine{
+				Spec: AWSMachineSpec{
+					RootDeviceSize: 50,
+				},
+			},
+			wantErr: false,
+		},
+		{
+			name: "decrease in root device size",
+			oldMachine: &AWSMachine{
+				Spec: AWSMachineSpec{
+					RootDeviceSize: 50,
+				},
+			},
+			newMachine: &AWSMachine{
+				Spec: AWSMachineSpec{
+					RootDeviceSize: 20,
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "change in placement group",
+			oldMachine: &AWSMachine{
+				Spec: AWSMachineSpec{
+					PlacementGroupName: "hpc-1",
+				},
+			},
+			newMachine: &AWSMachine{
+				Spec: AWSMachineSpec{
+					PlacementGroupName: "hpc-2",
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "change in placement group partition",
+			oldMachine: &AWSMachine{
+				Spec: AWSMachineSpec{
+					PlacementGroupName:      "hpc-1",
+					PlacementGroupPartition: 1,
+				},
+			},
+			newMachine: &AWSMachine{
+				Spec: AWSMachineSpec{
+					PlacementGroupName:      "hpc-1",
+					PlacementGroupPartition: 2,
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "change in tenancy",
+			oldMachine: &AWSMachine{
+				Spec: AWSMachineSpec{
+					Tenancy: TenancyDefault,
+				},
+			},
+			newMachine: &AWSMachine{
+				Spec: AWSMachineSpec{
+					Tenancy: TenancyDedicated,
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "change in instance metadata options",
+			oldMachine: &AWSMachine{
+				Spec: AWSMachineSpec{},
+			},
+			newMachine: &AWSMachine{
+				Spec: AWSMachineSpec{
+					InstanceMetadataOptions: &InstanceMetadataOptions{HTTPPutResponseHopLimit: 2},
+				},
+			},
+			wantErr: false,
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -88,3 +173,583 @@ func TestAWSMachine_ValidateUpdate(t *testing.T) {
 		})
 	}
 }
+
+func TestAWSMachine_ValidateCreate(t *testing.T) {
+	tests := []struct {
+		name    string
+		machine *AWSMachine
+		wantErr bool
+	}{
+		{
+			name: "auto-recovery with a supported instance type",
+			machine: &AWSMachine{
+				Spec: AWSMachineSpec{
+					InstanceType: "m5.large",
+					AutoRecovery: true,
+				},
+			},
+			wantErr: false,
+		},
+		{
+			name: "auto-recovery with an unsupported instance type",
+			machine: &AWSMachine{
+				Spec: AWSMachineSpec{
+					InstanceType: "m5d.large",
+					AutoRecovery: true,
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "unsupported instance type without auto-recovery",
+			machine: &AWSMachine{
+				Spec: AWSMachineSpec{
+					InstanceType: "m5d.large",
+				},
+			},
+			wantErr: false,
+		},
+		{
+			name: "kubelet registration with valid taints",
+			machine: &AWSMachine{
+				Spec: AWSMachineSpec{
+					KubeletRegistration: &KubeletRegistration{
+						Taints: []corev1.Taint{
+							{Key: "example.com/dedicated", Value: "gpu", Effect: corev1.TaintEffectNoSchedule},
+							{Key: "spot", Effect: corev1.TaintEffectPreferNoSchedule},
+						},
+					},
+				},
+			},
+			wantErr: false,
+		},
+		{
+			name: "kubelet registration with an invalid taint key",
+			machine: &AWSMachine{
+				Spec: AWSMachineSpec{
+					KubeletRegistration: &KubeletRegistration{
+						Taints: []corev1.Taint{
+							{Key: "dedicated gpu", Effect: corev1.TaintEffectNoSchedule},
+						},
+					},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "spot instance with a max price",
+			machine: &AWSMachine{
+				Spec: AWSMachineSpec{
+					SpotMarketOptions: &SpotMarketOptions{MaxPrice: pointer.StringPtr("0.05")},
+				},
+			},
+			wantErr: false,
+		},
+		{
+			name: "spot instance without a max price",
+			machine: &AWSMachine{
+				Spec: AWSMachineSpec{
+					SpotMarketOptions: &SpotMarketOptions{},
+				},
+			},
+			wantErr: false,
+		},
+		{
+			name: "spot instance with a negative max price",
+			machine: &AWSMachine{
+				Spec: AWSMachineSpec{
+					SpotMarketOptions: &SpotMarketOptions{MaxPrice: pointer.StringPtr("-0.05")},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "spot instance with a malformed max price",
+			machine: &AWSMachine{
+				Spec: AWSMachineSpec{
+					SpotMarketOptions: &SpotMarketOptions{MaxPrice: pointer.StringPtr("$0.05")},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "placement group partition without a placement group",
+			machine: &AWSMachine{
+				Spec: AWSMachineSpec{
+					PlacementGroupPartition: 2,
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "partition placement group created by the controllers",
+			machine: &AWSMachine{
+				Spec: AWSMachineSpec{
+					PlacementGroupName:      "hpc-1",
+					PlacementGroupPartition: 2,
+					PlacementGroupStrategy:  PlacementGroupStrategyPartition,
+				},
+			},
+			wantErr: false,
+		},
+		{
+			name: "placement group strategy without a placement group",
+			machine: &AWSMachine{
+				Spec: AWSMachineSpec{
+					PlacementGroupStrategy: PlacementGroupStrategyCluster,
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "placement group partition with the cluster strategy",
+			machine: &AWSMachine{
+				Spec: AWSMachineSpec{
+					PlacementGroupName:      "hpc-1",
+					PlacementGroupPartition: 2,
+					PlacementGroupStrategy:  PlacementGroupStrategyCluster,
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "host tenancy with a host id",
+			machine: &AWSMachine{
+				Spec: AWSMachineSpec{
+					Tenancy: TenancyHost,
+					HostID:  "h-0123456789abcdef0",
+				},
+			},
+			wantErr: false,
+		},
+		{
+			name: "host id without host tenancy",
+			machine: &AWSMachine{
+				Spec: AWSMachineSpec{
+					Tenancy: TenancyDedicated,
+					HostID:  "h-0123456789abcdef0",
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "capacity reservation",
+			machine: &AWSMachine{
+				Spec: AWSMachineSpec{
+					InstanceType:          "m5.large",
+					CapacityReservationID: "cr-0123456789abcdef0",
+				},
+			},
+			wantErr: false,
+		},
+		{
+			name: "capacity reservation with a preference",
+			machine: &AWSMachine{
+				Spec: AWSMachineSpec{
+					CapacityReservationPreference: CapacityReservationPreferenceNone,
+					CapacityReservationID:         "cr-0123456789abcdef0",
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "capacity reservation with alternative instance types",
+			machine: &AWSMachine{
+				Spec: AWSMachineSpec{
+					InstanceType:             "m5.large",
+					AlternativeInstanceTypes: []string{"m5a.large"},
+					CapacityReservationID:    "cr-0123456789abcdef0",
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "image lookup format",
+			machine: &AWSMachine{
+				Spec: AWSMachineSpec{
+					ImageLookupFormat: "capa-ami-{{.BaseOS}}-{{.K8sVersion}}-00-1580000000",
+				},
+			},
+			wantErr: false,
+		},
+		{
+			name: "image lookup format using an unknown value",
+			machine: &AWSMachine{
+				Spec: AWSMachineSpec{
+					ImageLookupFormat: "capa-ami-{{.OS}}-{{.K8sVersion}}",
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "malformed image lookup format",
+			machine: &AWSMachine{
+				Spec: AWSMachineSpec{
+					ImageLookupFormat: "capa-ami-{{.BaseOS",
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "image lookup by ssm parameter",
+			machine: &AWSMachine{
+				Spec: AWSMachineSpec{
+					ImageLookupSSMParameter: "/my-org/k8s/ami",
+				},
+			},
+			wantErr: false,
+		},
+		{
+			name: "image lookup by ssm parameter with an ami id",
+			machine: &AWSMachine{
+				Spec: AWSMachineSpec{
+					AMI:                     AWSResourceReference{ID: pointer.StringPtr("ami-1234")},
+					ImageLookupSSMParameter: "/my-org/k8s/ami",
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "alternative instance types",
+			machine: &AWSMachine{
+				Spec: AWSMachineSpec{
+					InstanceType:             "m5.large",
+					AlternativeInstanceTypes: []string{"m5a.large", "m4.large"},
+				},
+			},
+			wantErr: false,
+		},
+		{
+			name: "alternative instance types repeating the instance type",
+			machine: &AWSMachine{
+				Spec: AWSMachineSpec{
+					InstanceType:             "m5.large",
+					AlternativeInstanceTypes: []string{"m5a.large", "m5.large"},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "auto recovery with an alternative instance type that does not support it",
+			machine: &AWSMachine{
+				Spec: AWSMachineSpec{
+					InstanceType:             "m5.large",
+					AlternativeInstanceTypes: []string{"m5d.large"},
+					AutoRecovery:             true,
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "additional security groups by id and by filters",
+			machine: &AWSMachine{
+				Spec: AWSMachineSpec{
+					AdditionalSecurityGroups: []AWSResourceReference{
+						{ID: pointer.StringPtr("sg-1")},
+						{Filters: []Filter{{Name: "tag:role", Values: []string{"monitoring"}}}},
+					},
+				},
+			},
+			wantErr: false,
+		},
+		{
+			name: "additional security group without id or filters",
+			machine: &AWSMachine{
+				Spec: AWSMachineSpec{
+					AdditionalSecurityGroups: []AWSResourceReference{{}},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "kubelet registration with an unsupported taint effect",
+			machine: &AWSMachine{
+				Spec: AWSMachineSpec{
+					KubeletRegistration: &KubeletRegistration{
+						Taints: []corev1.Taint{
+							{Key: "dedicated", Value: "gpu", Effect: "NoRun"},
+						},
+					},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "non root volumes",
+			machine: &AWSMachine{
+				Spec: AWSMachineSpec{
+					NonRootVolumes: []Volume{
+						{DeviceName: "/dev/sdf", Size: 100, Type: VolumeTypeGP2},
+						{DeviceName: "/dev/sdg", Size: 500, Type: VolumeTypeIO1, IOPS: 5000, DeleteOnTermination: pointer.BoolPtr(false)},
+					},
+				},
+			},
+			wantErr: false,
+		},
+		{
+			name: "non root volumes with a duplicate device name",
+			machine: &AWSMachine{
+				Spec: AWSMachineSpec{
+					NonRootVolumes: []Volume{
+						{DeviceName: "/dev/sdf", Size: 100},
+						{DeviceName: "/dev/sdf", Size: 200},
+					},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "non root volume with the root device name",
+			machine: &AWSMachine{
+				Spec: AWSMachineSpec{
+					NonRootVolumes: []Volume{{DeviceName: "/dev/xvda", Size: 100}},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "io1 non root volume without iops",
+			machine: &AWSMachine{
+				Spec: AWSMachineSpec{
+					NonRootVolumes: []Volume{{DeviceName: "/dev/sdf", Size: 100, Type: VolumeTypeIO1}},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "encrypted io2 root volume",
+			machine: &AWSMachine{
+				Spec: AWSMachineSpec{
+					RootVolume: &Volume{Size: 50, Type: VolumeTypeIO2, IOPS: 3000, Encrypted: true, KMSKeyID: "alias/etcd"},
+					NonRootVolumes: []Volume{
+						{DeviceName: "/dev/sdf", Size: 20, Type: VolumeTypeIO2, IOPS: 6000, Encrypted: true},
+					},
+				},
+			},
+			wantErr: false,
+		},
+		{
+			name: "root volume together with rootDeviceSize",
+			machine: &AWSMachine{
+				Spec: AWSMachineSpec{
+					RootDeviceSize: 50,
+					RootVolume:     &Volume{Size: 50},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "root volume with a device name",
+			machine: &AWSMachine{
+				Spec: AWSMachineSpec{
+					RootVolume: &Volume{DeviceName: "/dev/xvda", Size: 50},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "root volume with a kms key but not encrypted",
+			machine: &AWSMachine{
+				Spec: AWSMachineSpec{
+					RootVolume: &Volume{Size: 50, KMSKeyID: "alias/etcd"},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "primary and secondary network interfaces",
+			machine: &AWSMachine{
+				Spec: AWSMachineSpec{
+					NetworkInterfaceSpecs: []NetworkInterfaceSpec{
+						{DeviceIndex: 0},
+						{
+							DeviceIndex:    1,
+							Subnet:         &AWSResourceReference{ID: pointer.StringPtr("subnet-appliance")},
+							SecurityGroups: []AWSResourceReference{{ID: pointer.StringPtr("sg-appliance")}},
+						},
+					},
+				},
+			},
+			wantErr: false,
+		},
+		{
+			name: "duplicate network interface device index",
+			machine: &AWSMachine{
+				Spec: AWSMachineSpec{
+					NetworkInterfaceSpecs: []NetworkInterfaceSpec{{DeviceIndex: 0}, {DeviceIndex: 0}},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "network interfaces without the primary interface",
+			machine: &AWSMachine{
+				Spec: AWSMachineSpec{
+					NetworkInterfaceSpecs: []NetworkInterfaceSpec{{DeviceIndex: 1}},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "network interfaces with a subnet",
+			machine: &AWSMachine{
+				Spec: AWSMachineSpec{
+					Subnet:                &AWSResourceReference{ID: pointer.StringPtr("subnet-1")},
+					NetworkInterfaceSpecs: []NetworkInterfaceSpec{{DeviceIndex: 0}},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "efa network interfaces",
+			machine: &AWSMachine{
+				Spec: AWSMachineSpec{
+					InstanceType:          "p4d.24xlarge",
+					NetworkInterfaceType:  NetworkInterfaceTypeEFA,
+					NetworkInterfaceSpecs: []NetworkInterfaceSpec{{DeviceIndex: 0}, {DeviceIndex: 1}},
+				},
+			},
+			wantErr: false,
+		},
+		{
+			name: "efa with existing network interfaces",
+			machine: &AWSMachine{
+				Spec: AWSMachineSpec{
+					NetworkInterfaceType: NetworkInterfaceTypeEFA,
+					NetworkInterfaces:    []string{"eni-1"},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "public ip on a secondary network interface",
+			machine: &AWSMachine{
+				Spec: AWSMachineSpec{
+					NetworkInterfaceSpecs: []NetworkInterfaceSpec{{DeviceIndex: 0}, {DeviceIndex: 1, PublicIP: pointer.BoolPtr(true)}},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "no public ip with network interfaces",
+			machine: &AWSMachine{
+				Spec: AWSMachineSpec{
+					PublicIP:              pointer.BoolPtr(false),
+					NetworkInterfaceSpecs: []NetworkInterfaceSpec{{DeviceIndex: 0}, {DeviceIndex: 1}},
+				},
+			},
+			wantErr: false,
+		},
+		{
+			name: "public ip with existing network interfaces",
+			machine: &AWSMachine{
+				Spec: AWSMachineSpec{
+					PublicIP:          pointer.BoolPtr(false),
+					NetworkInterfaces: []string{"eni-1"},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "elastic ip allocated for the machine",
+			machine: &AWSMachine{
+				Spec: AWSMachineSpec{
+					ElasticIPPool: &ElasticIPPool{},
+				},
+			},
+			wantErr: false,
+		},
+		{
+			name: "elastic ip from a pool",
+			machine: &AWSMachine{
+				Spec: AWSMachineSpec{
+					ElasticIPPool: &ElasticIPPool{AllocationIDs: []string{"eipalloc-1", "eipalloc-2"}},
+				},
+			},
+			wantErr: false,
+		},
+		{
+			name: "elastic ip pool with an invalid allocation id",
+			machine: &AWSMachine{
+				Spec: AWSMachineSpec{
+					ElasticIPPool: &ElasticIPPool{AllocationIDs: []string{"203.0.113.10"}},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "elastic ip without a public ip",
+			machine: &AWSMachine{
+				Spec: AWSMachineSpec{
+					PublicIP:      pointer.BoolPtr(false),
+					ElasticIPPool: &ElasticIPPool{},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "raw user data secret",
+			machine: &AWSMachine{
+				Spec: AWSMachineSpec{
+					UserDataSecret: &UserDataSecretReference{Name: "worker-user-data", Key: "user-data.yaml"},
+				},
+			},
+			wantErr: false,
+		},
+		{
+			name: "raw user data secret with an invalid name",
+			machine: &AWSMachine{
+				Spec: AWSMachineSpec{
+					UserDataSecret: &UserDataSecretReference{Name: "Worker_User_Data"},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "raw user data secret with kubelet registration",
+			machine: &AWSMachine{
+				Spec: AWSMachineSpec{
+					UserDataSecret:      &UserDataSecretReference{Name: "worker-user-data"},
+					KubeletRegistration: &KubeletRegistration{},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "bootstrap data in ssm parameter store",
+			machine: &AWSMachine{
+				Spec: AWSMachineSpec{
+					CloudInit: &CloudInit{SecureSecretsBackend: SecretBackendSSMParameterStore},
+				},
+			},
+			wantErr: false,
+		},
+		{
+			name: "raw user data secret with bootstrap data in ssm parameter store",
+			machine: &AWSMachine{
+				Spec: AWSMachineSpec{
+					UserDataSecret: &UserDataSecretReference{Name: "worker-user-data"},
+					CloudInit:      &CloudInit{SecureSecretsBackend: SecretBackendSSMParameterStore},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "public ip conflicting with the primary network interface",
+			machine: &AWSMachine{
+				Spec: AWSMachineSpec{
+					PublicIP:              pointer.BoolPtr(false),
+					NetworkInterfaceSpecs: []NetworkInterfaceSpec{{DeviceIndex: 0, PublicIP: pointer.BoolPtr(true)}},
+				},
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.machine.ValidateCreate(); (err != nil) != tt.wantErr {
+				t.Errorf("ValidateCreate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -20,7 +20,9 @@ import (
 	"errors"
 	"reflect"
 
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/validation/field"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/webhook"
 )
@@ -37,6 +39,9 @@ var _ webhook.Validator = &AWSMachineTemplate{}
 
 // ValidateCreate implements webhook.Validator so a webhook will be registered for the type
 func (r *AWSMachineTemplate) ValidateCreate() error {
+	if allErrs := validateAWSMachineSpec(&r.Spec.Template.Spec, field.NewPath("spec", "template", "spec")); len(allErrs) > 0 {
+		return apierrors.NewInvalid(GroupVersion.WithKind("AWSMachineTemplate").GroupKind(), r.Name, allErrs)
+	}
 	return nil
 }
 
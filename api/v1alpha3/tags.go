@@ -104,6 +104,13 @@ const (
 	// dedicated to this cluster api provider implementation.
 	NameAWSClusterAPIRole = NameAWSProviderPrefix + "role"
 
+	// NameAWSProviderMarketType is the tag name we use to mark instances
+	// launched in a market other than on-demand, e.g. spot instances.
+	NameAWSProviderMarketType = NameAWSProviderPrefix + "market-type"
+
+	// SpotMarketTypeTagValue describes the value for spot instances
+	SpotMarketTypeTagValue = "spot"
+
 	// APIServerRoleTagValue describes the value for the apiserver role
 	APIServerRoleTagValue = "apiserver"
 
@@ -118,6 +125,13 @@ const (
 
 	// PrivateRoleTagValue describes the value for the private role
 	PrivateRoleTagValue = "private"
+
+	// PodRoleTagValue describes the value for the role of subnets reserved for pods
+	PodRoleTagValue = "pod"
+
+	// LoadBalancerRoleTagValue describes the value for the role of the Elastic
+	// IPs of the API server network load balancer
+	LoadBalancerRoleTagValue = "lb"
 )
 
 // ClusterTagKey generates the key for resources associated with a cluster.
@@ -19,6 +19,7 @@ package v1alpha3
 import (
 	"fmt"
 	"sort"
+	"strings"
 	"time"
 
 	corev1 "k8s.io/api/core/v1"
@@ -75,8 +76,40 @@ type Network struct {
 
 	// APIServerELB is the Kubernetes api server classic load balancer.
 	APIServerELB ClassicELB `json:"apiServerElb,omitempty"`
+
+	// APIServerDNSRecord is the Route53 record pointing at the API server
+	// load balancer, if any.
+	// +optional
+	APIServerDNSRecord *DNSRecord `json:"apiServerDnsRecord,omitempty"`
+}
+
+// DNSRecord is the state of a Route53 record managed for the cluster.
+type DNSRecord struct {
+	// HostedZoneID is the ID of the hosted zone of the record.
+	HostedZoneID string `json:"hostedZoneId"`
+
+	// Name is the fully qualified name of the record.
+	Name string `json:"name"`
+
+	// Ready is true once the record points at the load balancer.
+	Ready bool `json:"ready"`
+
+	// FailureMessage explains why the record could not be managed.
+	// +optional
+	FailureMessage *string `json:"failureMessage,omitempty"`
 }
 
+// LoadBalancerType defines the type of the control plane load balancer.
+type LoadBalancerType string
+
+var (
+	// LoadBalancerTypeClassic defines an AWS Classic ELB.
+	LoadBalancerTypeClassic = LoadBalancerType("classic")
+
+	// LoadBalancerTypeNLB defines an AWS Network Load Balancer.
+	LoadBalancerTypeNLB = LoadBalancerType("nlb")
+)
+
 // ClassicELBScheme defines the scheme of a classic load balancer.
 type ClassicELBScheme string
 
@@ -116,6 +149,10 @@ type ClassicELB struct {
 	// DNSName is the dns name of the load balancer.
 	DNSName string `json:"dnsName,omitempty"`
 
+	// CanonicalHostedZoneID is the ID of the Route53 hosted zone of the DNS
+	// name of the load balancer, used by alias records.
+	CanonicalHostedZoneID string `json:"canonicalHostedZoneId,omitempty"`
+
 	// Scheme is the load balancer scheme, either internet-facing or private.
 	Scheme ClassicELBScheme `json:"scheme,omitempty"`
 
@@ -134,6 +171,14 @@ type ClassicELB struct {
 	// HealthCheck is the classic elb health check associated with the load balancer.
 	HealthCheck *ClassicELBHealthCheck `json:"healthChecks,omitempty"`
 
+	// ProxyProtocol is true if the load balancer sends the PROXY protocol
+	// header to the API server port of the instances.
+	ProxyProtocol bool `json:"proxyProtocol,omitempty"`
+
+	// CrossZoneLoadBalancing is true if the load balancer spreads connections
+	// over the instances of all availability zones.
+	CrossZoneLoadBalancing bool `json:"crossZoneLoadBalancing,omitempty"`
+
 	// Attributes defines extra attributes associated with the load balancer.
 	Attributes ClassicELBAttributes `json:"attributes,omitempty"`
 
@@ -174,6 +219,304 @@ type NetworkSpec struct {
 	// Subnets configuration.
 	// +optional
 	Subnets Subnets `json:"subnets,omitempty"`
+
+	// NetworkACL configures a network ACL that is managed by the controller
+	// and associated with the cluster subnets. When unset, the subnets use the
+	// default network ACL of the VPC. Only supported for managed VPCs.
+	// +optional
+	NetworkACL *NetworkACLSpec `json:"networkACL,omitempty"`
+
+	// NodeIngress configures the kubelet and NodePort ingress rules of the
+	// node security group. When unset, the default rules apply.
+	// +optional
+	NodeIngress *NodeIngressSpec `json:"nodeIngress,omitempty"`
+
+	// VPCEndpoints are the VPC endpoints created for the cluster, letting
+	// instances in private subnets reach AWS services without egress to the
+	// internet. Only supported for managed VPCs.
+	// +optional
+	VPCEndpoints []VPCEndpointSpec `json:"vpcEndpoints,omitempty"`
+
+	// NatGatewayMode decides whether the private subnets of each availability
+	// zone egress through a NAT gateway in that zone, or all private subnets
+	// share a single NAT gateway. A single NAT gateway costs less, but egress
+	// fails for the whole cluster when its availability zone does. With None,
+	// no NAT gateway is created and the private subnets get no default route,
+	// e.g. for air-gapped clusters or egress through a transit gateway.
+	// Defaults to PerAZ.
+	// +optional
+	// +kubebuilder:validation:Enum=PerAZ;Single;None
+	NatGatewayMode NatGatewayMode `json:"natGatewayMode,omitempty"`
+
+	// SharedSubnets are subnets of a VPC owned by another account and shared
+	// with the cluster's account through AWS Resource Access Manager. When set,
+	// the cluster uses these subnets and never creates, modifies or deletes
+	// the VPC, its subnets, gateways or route tables. The security groups of
+	// the cluster are still created in the shared VPC.
+	// +optional
+	SharedSubnets []SharedSubnetReference `json:"sharedSubnets,omitempty"`
+
+	// DHCPOptions configures a DHCP option set that is managed by the
+	// controller and associated with the VPC. When unset, the VPC keeps the
+	// DHCP options it was created with; removing it leaves the VPC without a
+	// DHCP option set, using the Amazon provided DNS server. Only supported
+	// for managed VPCs.
+	// +optional
+	DHCPOptions *DHCPOptionsSpec `json:"dhcpOptions,omitempty"`
+
+	// TransitGateway attaches the VPC to an existing transit gateway and
+	// routes the given destinations of the private subnets through it. Only
+	// supported for managed VPCs.
+	// +optional
+	TransitGateway *TransitGatewaySpec `json:"transitGateway,omitempty"`
+
+	// VPCPeering peers the VPC with another VPC, e.g. the VPC of the
+	// management cluster, and routes the CIDR blocks of the peer VPC from the
+	// cluster subnets through the peering connection. Only supported for
+	// managed VPCs.
+	// +optional
+	VPCPeering *VPCPeeringSpec `json:"vpcPeering,omitempty"`
+
+	// PublicIPv4Pool is the ID of a public IPv4 address pool brought to AWS
+	// (BYOIP). The Elastic IPs allocated by the controllers, for the NAT
+	// gateways, an internet-facing network load balancer of the API server
+	// and machines, are taken from it. Elastic IPs allocated before it is set
+	// are kept. Cannot be set together with sharedSubnets.
+	// +optional
+	// +kubebuilder:validation:Pattern=`^ipv4pool-ec2-`
+	PublicIPv4Pool string `json:"publicIpv4Pool,omitempty"`
+}
+
+// IsShared returns true if the cluster uses subnets shared through AWS
+// Resource Access Manager.
+func (n *NetworkSpec) IsShared() bool {
+	return len(n.SharedSubnets) > 0
+}
+
+// SharedSubnetReference references a subnet shared with the cluster's
+// account through AWS Resource Access Manager.
+type SharedSubnetReference struct {
+	// ID is the ID or the ARN of the subnet.
+	// +kubebuilder:validation:MinLength=1
+	ID string `json:"id"`
+
+	// IsPublic marks a subnet for the internet-facing load balancer and the
+	// bastion. It is not discovered since the route tables of a shared VPC
+	// are not visible to the accounts it is shared with.
+	// +optional
+	IsPublic bool `json:"isPublic,omitempty"`
+}
+
+// SubnetID returns the ID of the subnet, extracted from the ARN if needed.
+func (r *SharedSubnetReference) SubnetID() string {
+	if i := strings.LastIndex(r.ID, "subnet/"); strings.HasPrefix(r.ID, "arn:") && i >= 0 {
+		return r.ID[i+len("subnet/"):]
+	}
+	return r.ID
+}
+
+// DHCPOptionsSpec configures the DHCP option set of the VPC.
+type DHCPOptionsSpec struct {
+	// DomainName is the domain name instances use to complete unqualified
+	// DNS host names, e.g. "example.com".
+	// +optional
+	DomainName string `json:"domainName,omitempty"`
+
+	// DomainNameServers are the IP addresses of up to four DNS servers, in
+	// order of preference, or "AmazonProvidedDNS".
+	// +optional
+	// +kubebuilder:validation:MaxItems=4
+	DomainNameServers []string `json:"domainNameServers,omitempty"`
+
+	// NTPServers are the IP addresses of up to four NTP servers.
+	// +optional
+	// +kubebuilder:validation:MaxItems=4
+	NTPServers []string `json:"ntpServers,omitempty"`
+}
+
+// TransitGatewaySpec configures the attachment of the VPC to a transit gateway.
+type TransitGatewaySpec struct {
+	// ID is the ID of the transit gateway, e.g. "tgw-0123456789abcdef0". A
+	// transit gateway of another account must be shared with the account of
+	// the cluster, and the attachment accepted by its owner unless automatic
+	// acceptance is enabled.
+	ID string `json:"id"`
+
+	// Routes are the IPv4 CIDR blocks the private subnets reach through the
+	// transit gateway. Routing "0.0.0.0/0" sends all egress through it, which
+	// requires natGatewayMode None.
+	// +optional
+	Routes []string `json:"routes,omitempty"`
+
+	// AttachmentID is the ID of the transit gateway attachment of the VPC,
+	// set by the controller.
+	// +optional
+	AttachmentID *string `json:"attachmentId,omitempty"`
+}
+
+// VPCPeeringSpec configures the peering connection of the VPC with another VPC.
+type VPCPeeringSpec struct {
+	// PeerVPCID is the ID of the VPC to peer with.
+	PeerVPCID string `json:"peerVpcId"`
+
+	// PeerOwnerID is the ID of the AWS account owning the peer VPC, its owner
+	// must accept the peering connection. Defaults to the account of the
+	// cluster, the controller then accepts the peering connection itself.
+	// +optional
+	PeerOwnerID string `json:"peerOwnerId,omitempty"`
+
+	// PeerRegion is the region of the peer VPC. Defaults to the region of the
+	// cluster.
+	// +optional
+	PeerRegion string `json:"peerRegion,omitempty"`
+
+	// PeerCidrBlocks are the IPv4 CIDR blocks of the peer VPC the cluster
+	// subnets reach through the peering connection.
+	// +kubebuilder:validation:MinItems=1
+	PeerCidrBlocks []string `json:"peerCidrBlocks"`
+
+	// PeerRouteTableIDs are route tables of the peer VPC that get routes to
+	// the CIDR blocks of the cluster VPC through the peering connection. Only
+	// supported when the peer VPC is in the account and region of the cluster.
+	// +optional
+	PeerRouteTableIDs []string `json:"peerRouteTableIds,omitempty"`
+
+	// ConnectionID is the ID of the peering connection, set by the controller.
+	// +optional
+	ConnectionID *string `json:"connectionId,omitempty"`
+}
+
+// NatGatewayMode decides how many NAT gateways are created for the private subnets.
+type NatGatewayMode string
+
+var (
+	// NatGatewayModePerAZ creates a NAT gateway in every public subnet, private
+	// subnets egress through a NAT gateway in their availability zone.
+	NatGatewayModePerAZ = NatGatewayMode("PerAZ")
+
+	// NatGatewayModeSingle creates one NAT gateway that all private subnets
+	// egress through.
+	NatGatewayModeSingle = NatGatewayMode("Single")
+
+	// NatGatewayModeNone creates no NAT gateway, private subnets don't egress
+	// to the internet over IPv4.
+	NatGatewayModeNone = NatGatewayMode("None")
+)
+
+// VPCEndpointType is the type of a VPC endpoint.
+type VPCEndpointType string
+
+var (
+	// VPCEndpointTypeGateway is a gateway endpoint, reached through a route
+	// in the private route tables. Only S3 and DynamoDB support it.
+	VPCEndpointTypeGateway = VPCEndpointType("Gateway")
+
+	// VPCEndpointTypeInterface is an interface endpoint, reached through a
+	// network interface in the private subnets.
+	VPCEndpointTypeInterface = VPCEndpointType("Interface")
+)
+
+// VPCEndpointSpec configures a VPC endpoint.
+type VPCEndpointSpec struct {
+	// Service is the name of the AWS service in the cluster region, e.g. "s3",
+	// "ecr.api" or "ecr.dkr".
+	Service string `json:"service"`
+
+	// Type is the type of the endpoint. Gateway endpoints are associated with
+	// the private route tables, interface endpoints are placed in the private
+	// subnets and accept HTTPS from the cluster instances.
+	// +kubebuilder:validation:Enum=Gateway;Interface
+	Type VPCEndpointType `json:"type"`
+
+	// SecurityGroups are existing security groups of the cluster VPC to
+	// attach to an interface endpoint, instead of the managed one which allows
+	// HTTPS from the control plane and node security groups. They must allow
+	// HTTPS from the instances using the endpoint.
+	// +optional
+	SecurityGroups []AWSResourceReference `json:"securityGroups,omitempty"`
+}
+
+// NodeIngressSpec configures the ingress rules of the node security group.
+type NodeIngressSpec struct {
+	// Kubelet configures access to the kubelet API. Defaults to port 10250
+	// from the node security group. The control plane security group is always
+	// allowed, as the API server needs to reach the kubelet.
+	// +optional
+	Kubelet *NodeIngressRule `json:"kubelet,omitempty"`
+
+	// NodePort configures access to NodePort services. Defaults to ports
+	// 30000-32767 from anywhere.
+	// +optional
+	NodePort *NodeIngressRule `json:"nodePort,omitempty"`
+}
+
+// NodeIngressRule configures the ports and sources of a node ingress rule.
+// Unset ports or sources keep their defaults.
+type NodeIngressRule struct {
+	// FromPort is the start of the port range.
+	// +optional
+	FromPort int64 `json:"fromPort,omitempty"`
+
+	// ToPort is the end of the port range.
+	// +optional
+	ToPort int64 `json:"toPort,omitempty"`
+
+	// CidrBlocks are the IPv4 CIDR blocks allowed to connect.
+	// +optional
+	CidrBlocks []string `json:"cidrBlocks,omitempty"`
+
+	// SourceSecurityGroupIDs are the security groups allowed to connect.
+	// +optional
+	SourceSecurityGroupIDs []string `json:"sourceSecurityGroupIds,omitempty"`
+}
+
+// NetworkACLSpec configures a managed network ACL.
+type NetworkACLSpec struct {
+	// Rules are the entries of the network ACL. Traffic that no rule
+	// matches is denied.
+	// +optional
+	Rules []NetworkACLRule `json:"rules,omitempty"`
+}
+
+// NetworkACLRuleAction is the action of a network ACL rule.
+type NetworkACLRuleAction string
+
+var (
+	// NetworkACLRuleActionAllow allows the traffic matching a network ACL rule
+	NetworkACLRuleActionAllow = NetworkACLRuleAction("allow")
+
+	// NetworkACLRuleActionDeny denies the traffic matching a network ACL rule
+	NetworkACLRuleActionDeny = NetworkACLRuleAction("deny")
+)
+
+// NetworkACLRule defines an entry of a network ACL.
+type NetworkACLRule struct {
+	// RuleNumber orders the evaluation of the rules, lowest first. It must
+	// be between 1 and 32766 and unique per direction.
+	RuleNumber int64 `json:"ruleNumber"`
+
+	// Egress is true for a rule matching outbound traffic, false for inbound traffic.
+	// +optional
+	Egress bool `json:"egress,omitempty"`
+
+	// Protocol is the protocol matched by the rule. ICMP rules match all
+	// ICMP types and codes. Supported values are "-1" (all), "tcp", "udp" and "icmp".
+	Protocol SecurityGroupProtocol `json:"protocol"`
+
+	// FromPort is the first port of the range matched by a TCP or UDP rule.
+	// +optional
+	FromPort int64 `json:"fromPort,omitempty"`
+
+	// ToPort is the last port of the range matched by a TCP or UDP rule.
+	// +optional
+	ToPort int64 `json:"toPort,omitempty"`
+
+	// CidrBlock is the IPv4 CIDR block matched by the rule.
+	CidrBlock string `json:"cidrBlock"`
+
+	// Action is whether the matching traffic is allowed or denied.
+	// +kubebuilder:validation:Enum=allow;deny
+	Action NetworkACLRuleAction `json:"action"`
 }
 
 // VPCSpec configures an AWS VPC.
@@ -181,14 +524,42 @@ type VPCSpec struct {
 	// ID is the vpc-id of the VPC this provider should use to create resources.
 	ID string `json:"id,omitempty"`
 
+	// ExternallyManaged makes the VPC referenced by ID unmanaged even when it
+	// is tagged as owned by the cluster. The controllers then only validate
+	// the VPC and its subnets, and never create, tag, modify or delete them.
+	// +optional
+	ExternallyManaged bool `json:"externallyManaged,omitempty"`
+
 	// CidrBlock is the CIDR block to be used when the provider creates a managed VPC.
 	// Defaults to 10.0.0.0/16.
 	CidrBlock string `json:"cidrBlock,omitempty"`
 
+	// SecondaryCidrBlocks are additional IPv4 CIDR blocks associated with a
+	// managed VPC, so that subnets can be added once CidrBlock is used up.
+	// They are not disassociated when removed from the list, only when the
+	// VPC is deleted.
+	// +optional
+	SecondaryCidrBlocks []string `json:"secondaryCidrBlocks,omitempty"`
+
 	// InternetGatewayID is the id of the internet gateway associated with the VPC.
 	// +optional
 	InternetGatewayID *string `json:"internetGatewayId,omitempty"`
 
+	// EnableIPv6 requests an Amazon-provided IPv6 CIDR block for a managed VPC,
+	// making the network dual-stack. A VPC which already has an IPv6 CIDR block
+	// is dual-stack regardless. IPv6 is not removed once enabled.
+	// +optional
+	EnableIPv6 bool `json:"enableIPv6,omitempty"`
+
+	// IPv6CidrBlock is the IPv6 CIDR block of the VPC, set once it is assigned.
+	// +optional
+	IPv6CidrBlock string `json:"ipv6CidrBlock,omitempty"`
+
+	// EgressOnlyInternetGatewayID is the id of the egress-only internet gateway
+	// routing the IPv6 traffic of the private subnets of a dual-stack VPC.
+	// +optional
+	EgressOnlyInternetGatewayID *string `json:"egressOnlyInternetGatewayId,omitempty"`
+
 	// Tags is a collection of tags describing the resource.
 	Tags Tags `json:"tags,omitempty"`
 }
@@ -200,7 +571,12 @@ func (v *VPCSpec) String() string {
 
 // IsUnmanaged returns true if the VPC is unmanaged.
 func (v *VPCSpec) IsUnmanaged(clusterName string) bool {
-	return v.ID != "" && !v.Tags.HasOwned(clusterName)
+	return v.ID != "" && (v.ExternallyManaged || !v.Tags.HasOwned(clusterName))
+}
+
+// IsDualStack returns true if the VPC has an IPv6 CIDR block.
+func (v *VPCSpec) IsDualStack() bool {
+	return v.IPv6CidrBlock != ""
 }
 
 // SubnetSpec configures an AWS Subnet.
@@ -211,6 +587,11 @@ type SubnetSpec struct {
 	// CidrBlock is the CIDR block to be used when the provider creates a managed VPC.
 	CidrBlock string `json:"cidrBlock,omitempty"`
 
+	// IPv6CidrBlock is the IPv6 CIDR block of the subnet in a dual-stack VPC,
+	// set once it is assigned.
+	// +optional
+	IPv6CidrBlock string `json:"ipv6CidrBlock,omitempty"`
+
 	// AvailabilityZone defines the availability zone to use for this subnet in the cluster's region.
 	AvailabilityZone string `json:"availabilityZone,omitempty"`
 
@@ -218,6 +599,18 @@ type SubnetSpec struct {
 	// +optional
 	IsPublic bool `json:"isPublic"`
 
+	// ForPods reserves the private subnet for the IPs of pods, e.g. with the
+	// custom networking of the Amazon VPC CNI. Its CIDR block must be within
+	// one of the secondary CIDR blocks of the VPC. Instances, load balancers
+	// and VPC endpoints are never placed in it.
+	// +optional
+	ForPods bool `json:"forPods,omitempty"`
+
+	// MapPublicIPOnLaunch overrides whether instances launched in a managed subnet are assigned a public IP address.
+	// Defaults to true for public subnets and false for private subnets. The attribute is reconciled on every pass.
+	// +optional
+	MapPublicIPOnLaunch *bool `json:"mapPublicIpOnLaunch,omitempty"`
+
 	// RouteTableID is the routing table id associated with the subnet.
 	// +optional
 	RouteTableID *string `json:"routeTableId,omitempty"`
@@ -227,6 +620,13 @@ type SubnetSpec struct {
 	// +optional
 	NatGatewayID *string `json:"natGatewayId,omitempty"`
 
+	// NatGatewayAllocationID is the allocation ID of an existing Elastic IP to
+	// use for the NAT gateway of this public subnet, so that the egress IP of
+	// the cluster stays the same when the NAT gateway is recreated. The Elastic
+	// IP is not released when the cluster is deleted.
+	// +optional
+	NatGatewayAllocationID string `json:"natGatewayAllocationId,omitempty"`
+
 	// Tags is a collection of tags describing the resource.
 	Tags Tags `json:"tags,omitempty"`
 }
@@ -259,10 +659,11 @@ func (s Subnets) FindByID(id string) *SubnetSpec {
 	return nil
 }
 
-// FilterPrivate returns a slice containing all subnets marked as private.
+// FilterPrivate returns a slice containing all subnets marked as private,
+// except the subnets reserved for pods.
 func (s Subnets) FilterPrivate() (res Subnets) {
 	for _, x := range s {
-		if !x.IsPublic {
+		if !x.IsPublic && !x.ForPods {
 			res = append(res, x)
 		}
 	}
@@ -279,6 +680,16 @@ func (s Subnets) FilterPublic() (res Subnets) {
 	return
 }
 
+// FilterPods returns a slice containing all subnets reserved for pods.
+func (s Subnets) FilterPods() (res Subnets) {
+	for _, x := range s {
+		if x.ForPods {
+			res = append(res, x)
+		}
+	}
+	return
+}
+
 // FilterByZone returns a slice containing all subnets that live in the availability zone specified.
 func (s Subnets) FilterByZone(zone string) (res Subnets) {
 	for _, x := range s {
@@ -309,6 +720,9 @@ var (
 
 	// SecurityGroupLB defines a container for the cloud provider to inject its load balancer ingress rules
 	SecurityGroupLB = SecurityGroupRole("lb")
+
+	// SecurityGroupVPCEndpoint defines the role of the interface VPC endpoints
+	SecurityGroupVPCEndpoint = SecurityGroupRole("vpcendpoint")
 )
 
 // SecurityGroup defines an AWS security group.
@@ -369,6 +783,10 @@ type IngressRule struct {
 	// The security group id to allow access from. Cannot be specified with CidrBlocks.
 	// +optional
 	SourceSecurityGroupIDs []string `json:"sourceSecurityGroupIds"`
+
+	// List of prefix list IDs to allow access from.
+	// +optional
+	PrefixListIDs []string `json:"prefixListIds,omitempty"`
 }
 
 // String returns a string representation of the ingress rule.
@@ -426,6 +844,19 @@ func (i *IngressRule) Equals(o *IngressRule) bool {
 		}
 	}
 
+	if len(i.PrefixListIDs) != len(o.PrefixListIDs) {
+		return false
+	}
+
+	sort.Strings(i.PrefixListIDs)
+	sort.Strings(o.PrefixListIDs)
+
+	for i, v := range i.PrefixListIDs {
+		if v != o.PrefixListIDs[i] {
+			return false
+		}
+	}
+
 	if i.Description != o.Description || i.Protocol != o.Protocol {
 		return false
 	}
@@ -469,6 +900,97 @@ var (
 	InstanceStateStopped = InstanceState("stopped")
 )
 
+// InstanceProfileAssociationState describes the state of the association
+// between an instance and its IAM instance profile.
+type InstanceProfileAssociationState string
+
+var (
+	// InstanceProfileAssociationStatePending is the string representing an instance
+	// profile association that has not completed yet
+	InstanceProfileAssociationStatePending = InstanceProfileAssociationState("pending")
+
+	// InstanceProfileAssociationStateAssociated is the string representing an instance
+	// profile that is associated with the instance
+	InstanceProfileAssociationStateAssociated = InstanceProfileAssociationState("associated")
+
+	// InstanceProfileAssociationStateFailed is the string representing an instance
+	// profile association that failed or did not complete in time
+	InstanceProfileAssociationStateFailed = InstanceProfileAssociationState("failed")
+
+	// InstanceProfileAssociationStateNotFound is the string representing an instance
+	// profile that does not exist or has no role, the instance is not launched
+	// until it is usable
+	InstanceProfileAssociationStateNotFound = InstanceProfileAssociationState("notFound")
+)
+
+// KubeletRegistration defines the labels and taints the kubelet registers its node with.
+type KubeletRegistration struct {
+	// PropagateMachineLabels passes the labels of the owning Machine to the kubelet --node-labels flag.
+	// Labels in the kubernetes.io and k8s.io namespaces are skipped unless the kubelet is
+	// allowed to set them, i.e. they are under kubelet.kubernetes.io or node.kubernetes.io.
+	// +optional
+	PropagateMachineLabels bool `json:"propagateMachineLabels,omitempty"`
+
+	// Taints are passed to the kubelet --register-with-taints flag.
+	// +optional
+	Taints []corev1.Taint `json:"taints,omitempty"`
+}
+
+// NetworkInterfaceSpec configures a network interface created with an
+// instance.
+type NetworkInterfaceSpec struct {
+	// DeviceIndex is the position of the interface on the instance. The
+	// primary interface has device index 0.
+	// +kubebuilder:validation:Minimum=0
+	DeviceIndex int64 `json:"deviceIndex"`
+
+	// Subnet is a reference to the subnet of the interface. If not specified,
+	// the subnet picked for the instance is used.
+	// +optional
+	Subnet *AWSResourceReference `json:"subnet,omitempty"`
+
+	// SecurityGroups are references to security groups applied to the
+	// interface, in addition to the security groups of the machine.
+	// +optional
+	SecurityGroups []AWSResourceReference `json:"securityGroups,omitempty"`
+
+	// PublicIP assigns a public IPv4 address to the interface. AWS only
+	// supports it on the primary interface of an instance launched with a
+	// single interface.
+	// +optional
+	PublicIP *bool `json:"publicIP,omitempty"`
+
+	// SecondaryPrivateIPAddressCount is the number of secondary private IPv4
+	// addresses EC2 assigns to the interface, e.g. for the pods of a CNI in
+	// ENI mode. The maximum depends on the instance type.
+	// +optional
+	// +kubebuilder:validation:Minimum=1
+	SecondaryPrivateIPAddressCount int64 `json:"secondaryPrivateIPAddressCount,omitempty"`
+}
+
+// ElasticIPPool configures where the Elastic IP of an instance comes from.
+type ElasticIPPool struct {
+	// AllocationIDs are the allocation IDs of pre-allocated Elastic IPs. The
+	// instance gets the first one that isn't associated with another
+	// resource, and it is not released when the instance is deleted. When
+	// empty, an Elastic IP is allocated for the instance and released when
+	// the instance is deleted.
+	// +optional
+	AllocationIDs []string `json:"allocationIDs,omitempty"`
+}
+
+// NetworkInterfaceType describes the type of a network interface.
+type NetworkInterfaceType string
+
+var (
+	// NetworkInterfaceTypeInterface is a standard elastic network interface.
+	NetworkInterfaceTypeInterface = NetworkInterfaceType("interface")
+
+	// NetworkInterfaceTypeEFA is an Elastic Fabric Adapter, an elastic
+	// network interface with OS-bypass support for HPC and ML workloads.
+	NetworkInterfaceTypeEFA = NetworkInterfaceType("efa")
+)
+
 // Instance describes an AWS instance.
 type Instance struct {
 	ID string `json:"id"`
@@ -516,9 +1038,324 @@ type Instance struct {
 	// Specifies size (in Gi) of the root storage device
 	RootDeviceSize int64 `json:"rootDeviceSize,omitempty"`
 
+	// Configuration options for the root storage device.
+	RootVolume *Volume `json:"rootVolume,omitempty"`
+
 	// Specifies ENIs attached to instance
 	NetworkInterfaces []string `json:"networkInterfaces,omitempty"`
 
+	// Specifies the network interfaces created with the instance, referencing
+	// their subnet and security groups by ID
+	NetworkInterfaceSpecs []NetworkInterfaceSpec `json:"networkInterfaceSpecs,omitempty"`
+
+	// The type of the network interfaces created with the instance.
+	NetworkInterfaceType NetworkInterfaceType `json:"networkInterfaceType,omitempty"`
+
 	// The tags associated with the instance.
 	Tags map[string]string `json:"tags,omitempty"`
+
+	// SpotMarketOptions, if set, requests the instance as a spot instance.
+	SpotMarketOptions *SpotMarketOptions `json:"spotMarketOptions,omitempty"`
+
+	// The name of the placement group the instance is in, if applicable.
+	PlacementGroupName string `json:"placementGroupName,omitempty"`
+
+	// The partition of the partition placement group the instance is in, if applicable.
+	PlacementGroupPartition int64 `json:"placementGroupPartition,omitempty"`
+
+	// The tenancy of the instance.
+	Tenancy Tenancy `json:"tenancy,omitempty"`
+
+	// The ID of the dedicated host the instance is on, if applicable.
+	HostID string `json:"hostId,omitempty"`
+
+	// The capacity reservation preference of the instance.
+	CapacityReservationPreference CapacityReservationPreference `json:"capacityReservationPreference,omitempty"`
+
+	// The ID of the capacity reservation the instance is targeted at, if applicable.
+	CapacityReservationID string `json:"capacityReservationId,omitempty"`
+
+	// The metadata options of the instance.
+	InstanceMetadataOptions *InstanceMetadataOptions `json:"instanceMetadataOptions,omitempty"`
+
+	// Indicates whether termination protection is enabled for the instance.
+	DisableAPITermination bool `json:"disableApiTermination,omitempty"`
+
+	// The additional EBS data volumes requested for the instance.
+	NonRootVolumes []Volume `json:"nonRootVolumes,omitempty"`
 }
+
+// InstanceMetadataOptions defines the options of the instance metadata service.
+type InstanceMetadataOptions struct {
+	// HTTPPutResponseHopLimit is the number of network hops the responses to
+	// instance metadata requests can travel. Containers that don't use the host
+	// network need a hop limit of at least 2 to reach the metadata service.
+	// Defaults to 1.
+	// +optional
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=64
+	HTTPPutResponseHopLimit int64 `json:"httpPutResponseHopLimit,omitempty"`
+
+	// HTTPTokens states whether the instance metadata service requires session
+	// tokens (IMDSv2). Requiring them is recommended, unset keeps the AWS
+	// default of optional so that IMDSv1 clients keep working.
+	// +optional
+	// +kubebuilder:validation:Enum=optional;required
+	HTTPTokens HTTPTokensState `json:"httpTokens,omitempty"`
+
+	// HTTPEndpoint enables or disables the instance metadata service. Unset
+	// keeps the AWS default of enabled.
+	// +optional
+	// +kubebuilder:validation:Enum=enabled;disabled
+	HTTPEndpoint InstanceMetadataEndpointState `json:"httpEndpoint,omitempty"`
+}
+
+// HTTPTokensState describes whether the instance metadata service requires
+// session tokens.
+type HTTPTokensState string
+
+var (
+	// HTTPTokensStateOptional allows requests without a session token (IMDSv1).
+	HTTPTokensStateOptional = HTTPTokensState("optional")
+
+	// HTTPTokensStateRequired only allows requests with a session token (IMDSv2).
+	HTTPTokensStateRequired = HTTPTokensState("required")
+)
+
+// InstanceMetadataEndpointState describes whether the instance metadata
+// service is enabled.
+type InstanceMetadataEndpointState string
+
+var (
+	// InstanceMetadataEndpointStateEnabled enables the instance metadata service.
+	InstanceMetadataEndpointStateEnabled = InstanceMetadataEndpointState("enabled")
+
+	// InstanceMetadataEndpointStateDisabled disables the instance metadata service.
+	InstanceMetadataEndpointStateDisabled = InstanceMetadataEndpointState("disabled")
+)
+
+// UserDataSecretReference references a key of a secret in the namespace of
+// the machine.
+type UserDataSecretReference struct {
+	// Name is the name of the secret.
+	// +kubebuilder:validation:MinLength=1
+	Name string `json:"name"`
+
+	// Key is the key of the secret holding the user data. Defaults to value.
+	// +optional
+	Key string `json:"key,omitempty"`
+}
+
+// DefaultUserDataSecretKey is the key of the user data in a secret when none
+// is set, the same key bootstrap providers use.
+const DefaultUserDataSecretKey = "value"
+
+// CloudInit configures how the cloud-init bootstrap data of an instance is
+// passed to it.
+type CloudInit struct {
+	// SecureSecretsBackend, when set, stores the bootstrap data in the given
+	// backend instead of the user data of the instance, which then only holds
+	// a script fetching it at boot. This lifts the 16KB limit of the user data
+	// and keeps the secrets of the bootstrap data from anyone allowed to
+	// describe the instance attributes. The image must have the AWS CLI
+	// installed. The stored data is deleted once the node has joined the
+	// cluster.
+	// +optional
+	// +kubebuilder:validation:Enum=ssm-parameter-store;secrets-manager
+	SecureSecretsBackend SecretBackend `json:"secureSecretsBackend,omitempty"`
+}
+
+// SecretBackend is a store of the bootstrap data of instances.
+type SecretBackend string
+
+const (
+	// SecretBackendSSMParameterStore stores the bootstrap data as
+	// SecureString parameters of SSM Parameter Store.
+	SecretBackendSSMParameterStore = SecretBackend("ssm-parameter-store")
+
+	// SecretBackendSecretsManager stores the bootstrap data as secrets of
+	// Secrets Manager, which hold larger chunks of it.
+	SecretBackendSecretsManager = SecretBackend("secrets-manager")
+)
+
+// BootstrapDataParameterPrefix is the SSM Parameter Store path under which
+// the bootstrap data of instances is stored, followed by the role, the
+// namespace and the name of the machine.
+const BootstrapDataParameterPrefix = "/cluster.x-k8s.io/aws"
+
+// BootstrapDataSecretPrefix is the prefix of the names of the Secrets Manager
+// secrets holding the bootstrap data of instances, followed by the role, the
+// namespace and the name of the machine.
+const BootstrapDataSecretPrefix = "aws.cluster.x-k8s.io"
+
+// SpotMarketOptions defines the options available to a user when configuring
+// Machines to run on Spot instances.
+type SpotMarketOptions struct {
+	// MaxPrice defines the maximum hourly price, in US dollars, to pay for the
+	// spot instance, e.g. "0.05". Defaults to the On-Demand price.
+	// +optional
+	MaxPrice *string `json:"maxPrice,omitempty"`
+
+	// AllocationStrategy decides in which order the instance type and the
+	// alternative instance types are tried. Defaults to Prioritized.
+	// +optional
+	// +kubebuilder:validation:Enum=Prioritized;LowestPrice
+	AllocationStrategy SpotAllocationStrategy `json:"allocationStrategy,omitempty"`
+
+	// InterruptionBehavior decides what EC2 does with the instance when it
+	// interrupts it. Defaults to terminate, the Machine is then replaced. A
+	// stopped or hibernated instance is started again by EC2 once capacity is
+	// available at the max price, and the Machine is not ready meanwhile.
+	// Hibernation requires an instance type and an AMI supporting it.
+	// +optional
+	// +kubebuilder:validation:Enum=terminate;stop;hibernate
+	InterruptionBehavior SpotInterruptionBehavior `json:"interruptionBehavior,omitempty"`
+}
+
+// IsPersistent returns true if the spot request of the instance outlives its
+// interruptions, which is the case unless interrupted instances terminate.
+func (o *SpotMarketOptions) IsPersistent() bool {
+	return o != nil && o.InterruptionBehavior != "" && o.InterruptionBehavior != SpotInterruptionBehaviorTerminate
+}
+
+// SpotInterruptionBehavior defines what happens to an interrupted spot instance.
+type SpotInterruptionBehavior string
+
+var (
+	// SpotInterruptionBehaviorTerminate terminates the instance.
+	SpotInterruptionBehaviorTerminate = SpotInterruptionBehavior("terminate")
+
+	// SpotInterruptionBehaviorStop stops the instance, keeping its EBS volumes.
+	SpotInterruptionBehaviorStop = SpotInterruptionBehavior("stop")
+
+	// SpotInterruptionBehaviorHibernate hibernates the instance, keeping its
+	// memory on the root volume.
+	SpotInterruptionBehaviorHibernate = SpotInterruptionBehavior("hibernate")
+)
+
+// SpotAllocationStrategy decides which instance type to try first when
+// launching a spot instance.
+type SpotAllocationStrategy string
+
+var (
+	// SpotAllocationStrategyPrioritized tries the instance type first, then
+	// the alternative instance types in the order they are listed.
+	SpotAllocationStrategyPrioritized = SpotAllocationStrategy("Prioritized")
+
+	// SpotAllocationStrategyLowestPrice tries the instance types with the
+	// lowest current spot price in the availability zone first.
+	SpotAllocationStrategyLowestPrice = SpotAllocationStrategy("LowestPrice")
+)
+
+// Volume encapsulates the configuration options for an EBS volume.
+type Volume struct {
+	// DeviceName is the device name the volume is attached as, e.g. /dev/sdf.
+	// It is required for non-root volumes and must not be set for the root
+	// volume, whose device name is taken from the AMI.
+	// +optional
+	DeviceName string `json:"deviceName,omitempty"`
+
+	// Size specifies the size (in Gi) of the volume.
+	// +kubebuilder:validation:Minimum=1
+	Size int64 `json:"size"`
+
+	// Type is the type of the volume. Defaults to gp2.
+	// +optional
+	// +kubebuilder:validation:Enum=standard;io1;io2;gp2;sc1;st1
+	Type VolumeType `json:"type,omitempty"`
+
+	// IOPS is the number of IOPS provisioned for the volume. It is required
+	// for, and only allowed with, io1 and io2 volumes.
+	// +optional
+	// +kubebuilder:validation:Minimum=100
+	IOPS int64 `json:"iops,omitempty"`
+
+	// Encrypted indicates whether the volume is encrypted, with the default
+	// EBS key of the account unless KMSKeyID is set.
+	// +optional
+	Encrypted bool `json:"encrypted,omitempty"`
+
+	// KMSKeyID is the ID or ARN of the KMS key used to encrypt the volume.
+	// Encrypted must be true when it is set, and the key must be in the region
+	// of the cluster.
+	// +optional
+	KMSKeyID string `json:"kmsKeyID,omitempty"`
+
+	// DeleteOnTermination indicates whether the volume is deleted when the
+	// instance is terminated. Defaults to true.
+	// +optional
+	DeleteOnTermination *bool `json:"deleteOnTermination,omitempty"`
+}
+
+// VolumeType describes the EBS volume type.
+// See https://docs.aws.amazon.com/AWSEC2/latest/UserGuide/ebs-volume-types.html
+type VolumeType string
+
+var (
+	// VolumeTypeStandard is the previous generation magnetic volume type.
+	VolumeTypeStandard = VolumeType("standard")
+
+	// VolumeTypeIO1 is the provisioned IOPS SSD volume type.
+	VolumeTypeIO1 = VolumeType("io1")
+
+	// VolumeTypeIO2 is the higher durability provisioned IOPS SSD volume type.
+	VolumeTypeIO2 = VolumeType("io2")
+
+	// VolumeTypeGP2 is the general purpose SSD volume type.
+	VolumeTypeGP2 = VolumeType("gp2")
+
+	// VolumeTypeSC1 is the cold HDD volume type.
+	VolumeTypeSC1 = VolumeType("sc1")
+
+	// VolumeTypeST1 is the throughput optimized HDD volume type.
+	VolumeTypeST1 = VolumeType("st1")
+)
+
+// Tenancy describes the tenancy of an instance.
+// See https://docs.aws.amazon.com/AWSEC2/latest/UserGuide/dedicated-instance.html
+type Tenancy string
+
+var (
+	// TenancyDefault runs the instance on shared hardware.
+	TenancyDefault = Tenancy("default")
+
+	// TenancyDedicated runs the instance on hardware dedicated to the account.
+	TenancyDedicated = Tenancy("dedicated")
+
+	// TenancyHost runs the instance on a dedicated host.
+	TenancyHost = Tenancy("host")
+)
+
+// PlacementGroupStrategy describes how the instances of a placement group are
+// placed on the underlying hardware.
+// See https://docs.aws.amazon.com/AWSEC2/latest/UserGuide/placement-groups.html
+type PlacementGroupStrategy string
+
+var (
+	// PlacementGroupStrategyCluster packs the instances close together in an
+	// availability zone, for low-latency networking.
+	PlacementGroupStrategyCluster = PlacementGroupStrategy("cluster")
+
+	// PlacementGroupStrategyPartition spreads the instances across logical
+	// partitions which don't share hardware.
+	PlacementGroupStrategyPartition = PlacementGroupStrategy("partition")
+
+	// PlacementGroupStrategySpread places every instance on distinct
+	// hardware.
+	PlacementGroupStrategySpread = PlacementGroupStrategy("spread")
+)
+
+// CapacityReservationPreference describes the On-Demand Capacity Reservations
+// an instance can run in.
+// See https://docs.aws.amazon.com/AWSEC2/latest/UserGuide/ec2-capacity-reservations.html
+type CapacityReservationPreference string
+
+var (
+	// CapacityReservationPreferenceOpen runs the instance in any open capacity
+	// reservation matching its attributes.
+	CapacityReservationPreferenceOpen = CapacityReservationPreference("open")
+
+	// CapacityReservationPreferenceNone never runs the instance in a capacity
+	// reservation.
+	CapacityReservationPreferenceNone = CapacityReservationPreference("none")
+)
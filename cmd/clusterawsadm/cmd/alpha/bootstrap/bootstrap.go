@@ -27,9 +27,12 @@ import (
 	"github.com/aws/aws-sdk-go/aws/defaults"
 	"github.com/aws/aws-sdk-go/aws/session"
 	cfn "github.com/aws/aws-sdk-go/service/cloudformation"
+	awsiam "github.com/aws/aws-sdk-go/service/iam"
 	awssts "github.com/aws/aws-sdk-go/service/sts"
 	"github.com/spf13/cobra"
+	awspartition "sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/partition"
 	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/services/cloudformation"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/services/iam"
 	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/services/sts"
 )
 
@@ -44,9 +47,15 @@ aws_session_token = {{ .SessionToken }}
 {{end}}
 `
 
+// stackName is the name of the bootstrap AWS CloudFormation stack.
+const stackName = "cluster-api-provider-aws-sigs-k8s-io"
+
 var (
 	extraControlPlanePolicies []string
 	extraNodePolicies         []string
+	policyConditions          string
+	bootstrapConfigFile       string
+	correctDrift              bool
 )
 
 // RootCmd is the root of the `alpha bootstrap command`
@@ -64,17 +73,61 @@ func RootCmd() *cobra.Command {
 	}
 	newCmd.AddCommand(generateCmd())
 	newCmd.AddCommand(createStackCmd())
+	newCmd.AddCommand(detectDriftCmd())
 	newCmd.AddCommand(generateIAMPolicyDocJSON())
 	newCmd.AddCommand(encodeAWSSecret())
 	newCmd.AddCommand(generateAWSDefaultProfileWithChain())
 
-	newCmd.PersistentFlags().String("partition", "aws", "AWS partition, for AWS GovCloud (US) it is aws-us-gov")
+	newCmd.PersistentFlags().String("partition", "", "AWS partition, for AWS GovCloud (US) it is aws-us-gov. Defaults to the partition of the configured AWS region")
 
 	return newCmd
 }
 
+// getPartitionFlag returns the --partition flag, or the partition of the
+// configured AWS region when it is not set, so ARNs match the account.
 func getPartitionFlag(cmd *cobra.Command) string {
-	return cmd.Flags().Lookup("partition").Value.String()
+	if partition := cmd.Flags().Lookup("partition").Value.String(); partition != "" {
+		return partition
+	}
+
+	sess, err := session.NewSessionWithOptions(session.Options{
+		SharedConfigState: session.SharedConfigEnable,
+	})
+	if err != nil {
+		return awspartition.Default
+	}
+	return awspartition.ForRegion(aws.StringValue(sess.Config.Region))
+}
+
+// getPolicyConditions parses the --policy-conditions flag.
+func getPolicyConditions() (iam.Conditions, error) {
+	if policyConditions == "" {
+		return nil, nil
+	}
+	return iam.ParseConditions(policyConditions)
+}
+
+func addPolicyConditionsFlag(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&policyConditions, "policy-conditions", "", `JSON condition block to add to the Allow statements of the managed policies, e.g. '{"StringEquals":{"aws:ResourceTag/owner":"team"}}'`)
+}
+
+// getBootstrapConfig loads the --config file, if any, and adds the policies
+// of the --extra-controlplane-policies and --extra-node-policies flags.
+func getBootstrapConfig() (cloudformation.BootstrapConfig, error) {
+	config := cloudformation.BootstrapConfig{}
+	if bootstrapConfigFile != "" {
+		var err error
+		if config, err = cloudformation.LoadBootstrapConfig(bootstrapConfigFile); err != nil {
+			return config, err
+		}
+	}
+	config.ControlPlane.ExtraPolicyARNs = append(config.ControlPlane.ExtraPolicyARNs, extraControlPlanePolicies...)
+	config.Nodes.ExtraPolicyARNs = append(config.Nodes.ExtraPolicyARNs, extraNodePolicies...)
+	return config, nil
+}
+
+func addBootstrapConfigFlag(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&bootstrapConfigFile, "config", "", "YAML file customizing the role and instance profile names, extra managed policies and extra inline policy statements of each role")
 }
 
 func generateCmd() *cobra.Command {
@@ -103,7 +156,19 @@ Instructions for obtaining the AWS account ID can be found on https://docs.aws.a
 		},
 		RunE: func(cmd *cobra.Command, args []string) error {
 			partition := getPartitionFlag(cmd)
-			template := cloudformation.BootstrapTemplate(args[0], partition, extraControlPlanePolicies, extraNodePolicies)
+			conditions, err := getPolicyConditions()
+			if err != nil {
+				return err
+			}
+			config, err := getBootstrapConfig()
+			if err != nil {
+				return err
+			}
+			if err := cloudformation.ValidateManagedIAMPolicyDocuments(args[0], partition, config, conditions); err != nil {
+				return err
+			}
+
+			template := cloudformation.BootstrapTemplate(args[0], partition, config, conditions)
 			j, err := template.YAML()
 			if err != nil {
 				return err
@@ -116,6 +181,8 @@ Instructions for obtaining the AWS account ID can be found on https://docs.aws.a
 
 	newCmd.Flags().StringSliceVar(&extraControlPlanePolicies, "extra-controlplane-policies", []string{}, "Comma-separated list of extra policies (ARNs) to add to the created control plane role (must already exist)")
 	newCmd.Flags().StringSliceVar(&extraNodePolicies, "extra-node-policies", []string{}, "Comma-separated list of extra policies (ARNs) to add to the created nodes role (must already exist)")
+	addPolicyConditionsFlag(newCmd)
+	addBootstrapConfigFlag(newCmd)
 
 	return newCmd
 }
@@ -127,7 +194,15 @@ func createStackCmd() *cobra.Command {
 		Long:  "Create a new AWS CloudFormation stack using the bootstrap template",
 		Args:  cobra.NoArgs,
 		RunE: func(cmd *cobra.Command, args []string) error {
-			stackName := "cluster-api-provider-aws-sigs-k8s-io"
+			conditions, err := getPolicyConditions()
+			if err != nil {
+				return err
+			}
+			config, err := getBootstrapConfig()
+			if err != nil {
+				return err
+			}
+
 			fmt.Printf("Attempting to create CloudFormation stack %s\n", stackName)
 			sess, err := session.NewSessionWithOptions(session.Options{
 				SharedConfigState: session.SharedConfigEnable,
@@ -145,8 +220,11 @@ func createStackCmd() *cobra.Command {
 			}
 
 			cfnSvc := cloudformation.NewService(cfn.New(sess))
+			if correctDrift {
+				cfnSvc.IAM = awsiam.New(sess)
+			}
 			partition := getPartitionFlag(cmd)
-			err = cfnSvc.ReconcileBootstrapStack(stackName, accountID, partition, extraControlPlanePolicies, extraNodePolicies)
+			err = cfnSvc.ReconcileBootstrapStack(stackName, accountID, partition, config, conditions, correctDrift)
 			if err != nil {
 				fmt.Printf("Error: %v", err)
 				return err
@@ -158,6 +236,47 @@ func createStackCmd() *cobra.Command {
 
 	newCmd.Flags().StringSliceVar(&extraControlPlanePolicies, "extra-controlplane-policies", []string{}, "Comma-separated list of extra policies (ARNs) to add to the created control plane role (must already exist)")
 	newCmd.Flags().StringSliceVar(&extraNodePolicies, "extra-node-policies", []string{}, "Comma-separated list of extra policies (ARNs) to add to the created nodes role (must already exist)")
+	newCmd.Flags().BoolVar(&correctDrift, "correct-drift", false, "Re-apply the template to the IAM roles and managed policies of an existing stack which were changed outside of AWS CloudFormation")
+	addPolicyConditionsFlag(newCmd)
+	addBootstrapConfigFlag(newCmd)
+
+	return newCmd
+}
+
+func detectDriftCmd() *cobra.Command {
+	newCmd := &cobra.Command{
+		Use:   "detect-drift",
+		Short: "Detect the drift of the bootstrap AWS CloudFormation stack",
+		Long: `Detect the resources of the bootstrap AWS CloudFormation stack which were changed or deleted outside of AWS CloudFormation,
+e.g. IAM roles edited in the console, and show how they differ from the template.
+
+Exits with a non-zero status when the stack has drifted.
+`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			sess, err := session.NewSessionWithOptions(session.Options{
+				SharedConfigState: session.SharedConfigEnable,
+			})
+			if err != nil {
+				fmt.Printf("Error: %v", err)
+				return err
+			}
+
+			fmt.Printf("Attempting to detect drift of CloudFormation stack %s\n", stackName)
+			cfnSvc := cloudformation.NewService(cfn.New(sess))
+			drifts, err := cfnSvc.DetectStackDrift(stackName)
+			if err != nil {
+				fmt.Printf("Error: %v", err)
+				return err
+			}
+
+			cloudformation.ShowStackDrift(drifts)
+			if len(drifts) > 0 {
+				return &cloudformation.StackDriftError{StackName: stackName, Resources: drifts}
+			}
+			return nil
+		},
+	}
 
 	return newCmd
 }
@@ -203,6 +322,15 @@ func generateIAMPolicyDocJSON() *cobra.Command {
 		RunE: func(cmd *cobra.Command, args []string) error {
 			accountID := args[0]
 			policyDocDir := args[1]
+			conditions, err := getPolicyConditions()
+			if err != nil {
+				return err
+			}
+			config, err := getBootstrapConfig()
+			if err != nil {
+				return err
+			}
+
 			sess, err := session.NewSessionWithOptions(session.Options{
 				SharedConfigState: session.SharedConfigEnable,
 			})
@@ -212,7 +340,7 @@ func generateIAMPolicyDocJSON() *cobra.Command {
 
 			cfnSvc := cloudformation.NewService(cfn.New(sess))
 			partition := getPartitionFlag(cmd)
-			err = cfnSvc.GenerateManagedIAMPolicyDocuments(policyDocDir, accountID, partition)
+			err = cfnSvc.GenerateManagedIAMPolicyDocuments(policyDocDir, accountID, partition, config, conditions)
 
 			if err != nil {
 				return fmt.Errorf("Error: failed to generate PolicyDocument for all ManagedIAMPolicies: %v", err)
@@ -222,6 +350,8 @@ func generateIAMPolicyDocJSON() *cobra.Command {
 			return nil
 		},
 	}
+	addPolicyConditionsFlag(newCmd)
+	addBootstrapConfigFlag(newCmd)
 	return newCmd
 }
 
@@ -0,0 +1,370 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resources
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/aws/aws-sdk-go/service/ec2/ec2iface"
+	"github.com/aws/aws-sdk-go/service/elb"
+	"github.com/aws/aws-sdk-go/service/elb/elbiface"
+	"github.com/aws/aws-sdk-go/service/elbv2"
+	"github.com/aws/aws-sdk-go/service/elbv2/elbv2iface"
+	"github.com/aws/aws-sdk-go/service/secretsmanager"
+	"github.com/aws/aws-sdk-go/service/secretsmanager/secretsmanageriface"
+	"github.com/pkg/errors"
+	errlist "k8s.io/apimachinery/pkg/util/errors"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/awserrors"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/services/wait"
+)
+
+type deleter struct {
+	out    io.Writer
+	dryRun bool
+
+	EC2            ec2iface.EC2API
+	ELB            elbiface.ELBAPI
+	ELBV2          elbv2iface.ELBV2API
+	SecretsManager secretsmanageriface.SecretsManagerAPI
+}
+
+// deleteStep deletes all the resources of a type.
+type deleteStep struct {
+	service string
+	typ     string
+	delete  func(d *deleter, resources []Resource) error
+}
+
+// deleteSteps are ordered so that every resource is deleted after the
+// resources depending on it.
+var deleteSteps = []deleteStep{
+	{service: "ec2", typ: "instance", delete: (*deleter).terminateInstances},
+	{service: "elasticloadbalancing", typ: "loadbalancer", delete: (*deleter).deleteLoadBalancers},
+	{service: "elasticloadbalancing", typ: "targetgroup", delete: (*deleter).deleteTargetGroups},
+	{service: "ec2", typ: "natgateway", delete: (*deleter).deleteNatGateways},
+	{service: "ec2", typ: "elastic-ip", delete: (*deleter).releaseAddresses},
+	{service: "ec2", typ: "internet-gateway", delete: (*deleter).deleteInternetGateways},
+	{service: "ec2", typ: "egress-only-internet-gateway", delete: (*deleter).deleteEgressOnlyInternetGateways},
+	{service: "ec2", typ: "vpc-endpoint", delete: (*deleter).deleteVPCEndpoints},
+	{service: "ec2", typ: "transit-gateway-attachment", delete: (*deleter).deleteTransitGatewayAttachments},
+	{service: "ec2", typ: "vpc-peering-connection", delete: (*deleter).deleteVPCPeeringConnections},
+	{service: "ec2", typ: "security-group", delete: (*deleter).deleteSecurityGroups},
+	{service: "ec2", typ: "subnet", delete: (*deleter).deleteSubnets},
+	{service: "ec2", typ: "route-table", delete: (*deleter).deleteRouteTables},
+	{service: "ec2", typ: "network-acl", delete: (*deleter).deleteNetworkACLs},
+	{service: "ec2", typ: "vpc", delete: (*deleter).deleteVPCs},
+	{service: "ec2", typ: "dhcp-options", delete: (*deleter).deleteDHCPOptions},
+	{service: "secretsmanager", typ: "secret", delete: (*deleter).deleteSecrets},
+}
+
+func (d *deleter) run(resources []Resource) error {
+	byType := map[string][]Resource{}
+	for _, r := range resources {
+		key := r.Service + "/" + r.Type
+		byType[key] = append(byType[key], r)
+	}
+
+	for _, step := range deleteSteps {
+		key := step.service + "/" + step.typ
+		rs := byType[key]
+		delete(byType, key)
+		if len(rs) == 0 {
+			continue
+		}
+
+		if d.dryRun {
+			for _, r := range rs {
+				fmt.Fprintf(d.out, "would delete %s %s %s\n", r.Service, r.Type, r.ID)
+			}
+			continue
+		}
+
+		// Later steps depend on this one, stop at the first failure.
+		if err := step.delete(d, rs); err != nil {
+			return err
+		}
+	}
+
+	for _, r := range resources {
+		if _, ok := byType[r.Service+"/"+r.Type]; ok {
+			fmt.Fprintf(d.out, "skipped %s %s %s: unsupported resource type\n", r.Service, r.Type, r.ID)
+		}
+	}
+
+	return nil
+}
+
+func (d *deleter) deleted(r Resource) {
+	fmt.Fprintf(d.out, "deleted %s %s %s\n", r.Service, r.Type, r.ID)
+}
+
+// each deletes the resources one by one, returning the aggregated errors.
+func (d *deleter) each(resources []Resource, fn func(r Resource) error) error {
+	var errs []error
+	for _, r := range resources {
+		if err := fn(r); err != nil {
+			errs = append(errs, errors.Wrapf(err, "failed to delete %s %s %s", r.Service, r.Type, r.ID))
+			continue
+		}
+		d.deleted(r)
+	}
+	return errlist.NewAggregate(errs)
+}
+
+// retryDependencyViolation retries fn while other resources still depend on
+// the resource, e.g. network interfaces of deleted load balancers which take
+// a few minutes to be released.
+func retryDependencyViolation(fn func() error) error {
+	return wait.WaitForWithRetryable(wait.NewBackoff(), func() (bool, error) {
+		if err := fn(); err != nil {
+			return false, err
+		}
+		return true, nil
+	}, awserrors.DependencyViolation)
+}
+
+func ids(resources []Resource) []string {
+	res := make([]string, 0, len(resources))
+	for _, r := range resources {
+		res = append(res, r.ID)
+	}
+	return res
+}
+
+func (d *deleter) terminateInstances(resources []Resource) error {
+	input := &ec2.TerminateInstancesInput{InstanceIds: aws.StringSlice(ids(resources))}
+	if _, err := d.EC2.TerminateInstances(input); err != nil && !awserrors.IsNotFound(err) {
+		return errors.Wrap(err, "failed to terminate instances")
+	}
+
+	if err := d.EC2.WaitUntilInstanceTerminated(&ec2.DescribeInstancesInput{InstanceIds: input.InstanceIds}); err != nil {
+		return errors.Wrap(err, "failed to wait for instances to terminate")
+	}
+
+	for _, r := range resources {
+		d.deleted(r)
+	}
+	return nil
+}
+
+func (d *deleter) deleteLoadBalancers(resources []Resource) error {
+	return d.each(resources, func(r Resource) error {
+		// Classic load balancers are loadbalancer/<name>, the others
+		// loadbalancer/<net|app>/<name>/<id>.
+		if !strings.Contains(r.ID, "/") {
+			_, err := d.ELB.DeleteLoadBalancer(&elb.DeleteLoadBalancerInput{LoadBalancerName: aws.String(r.ID)})
+			return err
+		}
+		_, err := d.ELBV2.DeleteLoadBalancer(&elbv2.DeleteLoadBalancerInput{LoadBalancerArn: aws.String(r.ARN)})
+		return err
+	})
+}
+
+func (d *deleter) deleteTargetGroups(resources []Resource) error {
+	return d.each(resources, func(r Resource) error {
+		// Deleted load balancers release their target groups shortly after.
+		return wait.WaitForWithRetryable(wait.NewBackoff(), func() (bool, error) {
+			if _, err := d.ELBV2.DeleteTargetGroup(&elbv2.DeleteTargetGroupInput{TargetGroupArn: aws.String(r.ARN)}); err != nil {
+				return false, err
+			}
+			return true, nil
+		}, elbv2.ErrCodeResourceInUseException)
+	})
+}
+
+func (d *deleter) deleteNatGateways(resources []Resource) error {
+	if err := d.each(resources, func(r Resource) error {
+		_, err := d.EC2.DeleteNatGateway(&ec2.DeleteNatGatewayInput{NatGatewayId: aws.String(r.ID)})
+		return err
+	}); err != nil {
+		return err
+	}
+
+	// The Elastic IPs and the subnets are only released once the NAT
+	// gateways are deleted.
+	return wait.WaitForWithRetryable(wait.NewBackoff(), func() (bool, error) {
+		out, err := d.EC2.DescribeNatGateways(&ec2.DescribeNatGatewaysInput{NatGatewayIds: aws.StringSlice(ids(resources))})
+		if err != nil {
+			return false, err
+		}
+		for _, ng := range out.NatGateways {
+			if aws.StringValue(ng.State) != ec2.NatGatewayStateDeleted {
+				return false, nil
+			}
+		}
+		return true, nil
+	})
+}
+
+func (d *deleter) releaseAddresses(resources []Resource) error {
+	return d.each(resources, func(r Resource) error {
+		return retryDependencyViolation(func() error {
+			_, err := d.EC2.ReleaseAddress(&ec2.ReleaseAddressInput{AllocationId: aws.String(r.ID)})
+			return err
+		})
+	})
+}
+
+func (d *deleter) deleteInternetGateways(resources []Resource) error {
+	return d.each(resources, func(r Resource) error {
+		out, err := d.EC2.DescribeInternetGateways(&ec2.DescribeInternetGatewaysInput{InternetGatewayIds: aws.StringSlice([]string{r.ID})})
+		if err != nil {
+			return err
+		}
+		for _, ig := range out.InternetGateways {
+			for _, attachment := range ig.Attachments {
+				if err := retryDependencyViolation(func() error {
+					_, err := d.EC2.DetachInternetGateway(&ec2.DetachInternetGatewayInput{
+						InternetGatewayId: ig.InternetGatewayId,
+						VpcId:             attachment.VpcId,
+					})
+					return err
+				}); err != nil {
+					return err
+				}
+			}
+		}
+		_, err = d.EC2.DeleteInternetGateway(&ec2.DeleteInternetGatewayInput{InternetGatewayId: aws.String(r.ID)})
+		return err
+	})
+}
+
+func (d *deleter) deleteEgressOnlyInternetGateways(resources []Resource) error {
+	return d.each(resources, func(r Resource) error {
+		_, err := d.EC2.DeleteEgressOnlyInternetGateway(&ec2.DeleteEgressOnlyInternetGatewayInput{EgressOnlyInternetGatewayId: aws.String(r.ID)})
+		return err
+	})
+}
+
+func (d *deleter) deleteVPCEndpoints(resources []Resource) error {
+	return d.each(resources, func(r Resource) error {
+		_, err := d.EC2.DeleteVpcEndpoints(&ec2.DeleteVpcEndpointsInput{VpcEndpointIds: aws.StringSlice([]string{r.ID})})
+		return err
+	})
+}
+
+func (d *deleter) deleteTransitGatewayAttachments(resources []Resource) error {
+	return d.each(resources, func(r Resource) error {
+		_, err := d.EC2.DeleteTransitGatewayVpcAttachment(&ec2.DeleteTransitGatewayVpcAttachmentInput{TransitGatewayAttachmentId: aws.String(r.ID)})
+		return err
+	})
+}
+
+func (d *deleter) deleteVPCPeeringConnections(resources []Resource) error {
+	return d.each(resources, func(r Resource) error {
+		_, err := d.EC2.DeleteVpcPeeringConnection(&ec2.DeleteVpcPeeringConnectionInput{VpcPeeringConnectionId: aws.String(r.ID)})
+		return err
+	})
+}
+
+func (d *deleter) deleteSecurityGroups(resources []Resource) error {
+	// The security groups of a cluster reference each other, revoke all their
+	// ingress rules before deleting them.
+	out, err := d.EC2.DescribeSecurityGroups(&ec2.DescribeSecurityGroupsInput{GroupIds: aws.StringSlice(ids(resources))})
+	if err != nil {
+		return errors.Wrap(err, "failed to describe security groups")
+	}
+	for _, sg := range out.SecurityGroups {
+		if len(sg.IpPermissions) == 0 {
+			continue
+		}
+		if _, err := d.EC2.RevokeSecurityGroupIngress(&ec2.RevokeSecurityGroupIngressInput{
+			GroupId:       sg.GroupId,
+			IpPermissions: sg.IpPermissions,
+		}); awserrors.IsIgnorableSecurityGroupError(err) != nil {
+			return errors.Wrapf(err, "failed to revoke the ingress rules of security group %q", aws.StringValue(sg.GroupId))
+		}
+	}
+
+	return d.each(resources, func(r Resource) error {
+		return retryDependencyViolation(func() error {
+			_, err := d.EC2.DeleteSecurityGroup(&ec2.DeleteSecurityGroupInput{GroupId: aws.String(r.ID)})
+			return err
+		})
+	})
+}
+
+func (d *deleter) deleteSubnets(resources []Resource) error {
+	return d.each(resources, func(r Resource) error {
+		return retryDependencyViolation(func() error {
+			_, err := d.EC2.DeleteSubnet(&ec2.DeleteSubnetInput{SubnetId: aws.String(r.ID)})
+			return err
+		})
+	})
+}
+
+func (d *deleter) deleteRouteTables(resources []Resource) error {
+	return d.each(resources, func(r Resource) error {
+		out, err := d.EC2.DescribeRouteTables(&ec2.DescribeRouteTablesInput{RouteTableIds: aws.StringSlice([]string{r.ID})})
+		if err != nil {
+			return err
+		}
+		for _, rt := range out.RouteTables {
+			for _, association := range rt.Associations {
+				if aws.BoolValue(association.Main) {
+					continue
+				}
+				if _, err := d.EC2.DisassociateRouteTable(&ec2.DisassociateRouteTableInput{AssociationId: association.RouteTableAssociationId}); err != nil && !awserrors.IsNotFound(err) {
+					return err
+				}
+			}
+		}
+		_, err = d.EC2.DeleteRouteTable(&ec2.DeleteRouteTableInput{RouteTableId: aws.String(r.ID)})
+		return err
+	})
+}
+
+func (d *deleter) deleteNetworkACLs(resources []Resource) error {
+	return d.each(resources, func(r Resource) error {
+		return retryDependencyViolation(func() error {
+			_, err := d.EC2.DeleteNetworkAcl(&ec2.DeleteNetworkAclInput{NetworkAclId: aws.String(r.ID)})
+			return err
+		})
+	})
+}
+
+func (d *deleter) deleteVPCs(resources []Resource) error {
+	return d.each(resources, func(r Resource) error {
+		return retryDependencyViolation(func() error {
+			_, err := d.EC2.DeleteVpc(&ec2.DeleteVpcInput{VpcId: aws.String(r.ID)})
+			return err
+		})
+	})
+}
+
+func (d *deleter) deleteDHCPOptions(resources []Resource) error {
+	return d.each(resources, func(r Resource) error {
+		return retryDependencyViolation(func() error {
+			_, err := d.EC2.DeleteDhcpOptions(&ec2.DeleteDhcpOptionsInput{DhcpOptionsId: aws.String(r.ID)})
+			return err
+		})
+	})
+}
+
+func (d *deleter) deleteSecrets(resources []Resource) error {
+	return d.each(resources, func(r Resource) error {
+		_, err := d.SecretsManager.DeleteSecret(&secretsmanager.DeleteSecretInput{
+			SecretId:                   aws.String(r.ARN),
+			ForceDeleteWithoutRecovery: aws.Bool(true),
+		})
+		return err
+	})
+}
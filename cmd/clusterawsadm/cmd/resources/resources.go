@@ -0,0 +1,244 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resources
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/arn"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/aws/aws-sdk-go/service/elb"
+	"github.com/aws/aws-sdk-go/service/elbv2"
+	rgapi "github.com/aws/aws-sdk-go/service/resourcegroupstaggingapi"
+	"github.com/aws/aws-sdk-go/service/secretsmanager"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	infrav1 "sigs.k8s.io/cluster-api-provider-aws/api/v1alpha3"
+)
+
+// Resource is an AWS resource tagged for a cluster.
+type Resource struct {
+	ARN     string `json:"arn"`
+	Service string `json:"service"`
+	Type    string `json:"type"`
+	ID      string `json:"id"`
+}
+
+// ResourcesCmd is the top-level set of commands to manage the AWS resources of a cluster.
+func ResourcesCmd(out io.Writer) *cobra.Command { // nolint
+	newCmd := &cobra.Command{
+		Use:   "resources",
+		Short: "List and delete the AWS resources tagged for a cluster",
+		Run: func(cmd *cobra.Command, args []string) {
+			cmd.Help()
+		},
+	}
+	newCmd.AddCommand(ListCmd(out))
+	newCmd.AddCommand(DeleteCmd(out))
+	return newCmd
+}
+
+// ListCmd prints the AWS resources tagged for a cluster.
+func ListCmd(out io.Writer) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "list",
+		Short: "List the AWS resources tagged for a cluster",
+		Long: `List the AWS resources of the region tagged with sigs.k8s.io/cluster-api-provider-aws/cluster/<cluster name>,
+across all services, e.g. to find the resources left behind by a failed cluster deletion.`,
+		Args: cobra.ExactArgs(0),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return RunList(out, cmd)
+		},
+	}
+	addFlags(cmd)
+	cmd.Flags().StringP("output", "o", "", "Output format; available options are 'json'")
+	return cmd
+}
+
+// DeleteCmd deletes the AWS resources tagged for a cluster.
+func DeleteCmd(out io.Writer) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "delete",
+		Short: "Delete the AWS resources tagged for a cluster",
+		Long: `Delete the AWS resources of the region tagged with sigs.k8s.io/cluster-api-provider-aws/cluster/<cluster name>,
+e.g. to clean up after a failed end-to-end test run or an abandoned cluster.
+
+The resources are deleted in dependency order, instances and load balancers first and the VPC last.
+Resources of types the command doesn't know how to delete are reported and left in place. The
+deletion can be run again after a failure.
+
+Only run it for clusters whose AWSCluster is gone, the controllers would recreate the resources
+otherwise.`,
+		Args: cobra.ExactArgs(0),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return RunDelete(out, cmd)
+		},
+	}
+	addFlags(cmd)
+	cmd.Flags().Bool("dry-run", false, "Print the resources that would be deleted without deleting them")
+	return cmd
+}
+
+func addFlags(cmd *cobra.Command) {
+	cmd.Flags().String("cluster-name", "", "Name of the cluster the resources are tagged for")
+	cmd.Flags().String("region", "", "AWS region of the resources. If unspecified, the region of the AWS configuration is used")
+	_ = cmd.MarkFlagRequired("cluster-name")
+}
+
+// RunList prints the AWS resources tagged for the cluster given to the cobra.Command.
+func RunList(out io.Writer, cmd *cobra.Command) error {
+	of, _ := cmd.Flags().GetString("output")
+	if of != "" && of != "json" {
+		return errors.Errorf("invalid output format: %s", of)
+	}
+
+	sess, err := newSession(cmd)
+	if err != nil {
+		return err
+	}
+
+	clusterName, _ := cmd.Flags().GetString("cluster-name")
+	resources, err := listResources(rgapi.New(sess), clusterName)
+	if err != nil {
+		return err
+	}
+
+	switch of {
+	case "":
+		w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "SERVICE\tTYPE\tID")
+		for _, r := range resources {
+			fmt.Fprintf(w, "%s\t%s\t%s\n", r.Service, r.Type, r.ID)
+		}
+		return w.Flush()
+	case "json":
+		j, err := json.MarshalIndent(resources, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Fprintln(out, string(j))
+	}
+
+	return nil
+}
+
+// RunDelete deletes the AWS resources tagged for the cluster given to the cobra.Command.
+func RunDelete(out io.Writer, cmd *cobra.Command) error {
+	dryRun, _ := cmd.Flags().GetBool("dry-run")
+
+	sess, err := newSession(cmd)
+	if err != nil {
+		return err
+	}
+
+	clusterName, _ := cmd.Flags().GetString("cluster-name")
+	resources, err := listResources(rgapi.New(sess), clusterName)
+	if err != nil {
+		return err
+	}
+
+	d := &deleter{
+		out:            out,
+		dryRun:         dryRun,
+		EC2:            ec2.New(sess),
+		ELB:            elb.New(sess),
+		ELBV2:          elbv2.New(sess),
+		SecretsManager: secretsmanager.New(sess),
+	}
+	return d.run(resources)
+}
+
+func newSession(cmd *cobra.Command) (*session.Session, error) {
+	region, _ := cmd.Flags().GetString("region")
+
+	config := aws.Config{}
+	if region != "" {
+		config.Region = aws.String(region)
+	}
+	sess, err := session.NewSessionWithOptions(session.Options{
+		Config:            config,
+		SharedConfigState: session.SharedConfigEnable,
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create AWS session")
+	}
+	return sess, nil
+}
+
+// listResources returns the resources tagged for the cluster, sorted by
+// service, type and ID.
+func listResources(client *rgapi.ResourceGroupsTaggingAPI, clusterName string) ([]Resource, error) {
+	input := &rgapi.GetResourcesInput{
+		TagFilters: []*rgapi.TagFilter{
+			{Key: aws.String(infrav1.ClusterTagKey(clusterName))},
+		},
+	}
+
+	resources := []Resource{}
+	var parseErr error
+	if err := client.GetResourcesPages(input, func(out *rgapi.GetResourcesOutput, last bool) bool {
+		for _, mapping := range out.ResourceTagMappingList {
+			r, err := parseResource(aws.StringValue(mapping.ResourceARN))
+			if err != nil {
+				parseErr = err
+				return false
+			}
+			resources = append(resources, r)
+		}
+		return true
+	}); err != nil {
+		return nil, errors.Wrapf(err, "failed to list the resources of cluster %q", clusterName)
+	}
+	if parseErr != nil {
+		return nil, parseErr
+	}
+
+	sort.Slice(resources, func(i, j int) bool {
+		a, b := resources[i], resources[j]
+		if a.Service != b.Service {
+			return a.Service < b.Service
+		}
+		if a.Type != b.Type {
+			return a.Type < b.Type
+		}
+		return a.ID < b.ID
+	})
+	return resources, nil
+}
+
+// parseResource splits the resource of an ARN into its type and ID, e.g.
+// "instance/i-0123456789abcdef0", "loadbalancer/net/name/50dc6c495c0c9188" or
+// "secret:name-AbCdEf".
+func parseResource(s string) (Resource, error) {
+	a, err := arn.Parse(s)
+	if err != nil {
+		return Resource{}, errors.Wrapf(err, "failed to parse ARN %q", s)
+	}
+
+	r := Resource{ARN: s, Service: a.Service, Type: a.Resource}
+	if i := strings.IndexAny(a.Resource, "/:"); i >= 0 {
+		r.Type, r.ID = a.Resource[:i], a.Resource[i+1:]
+	}
+	return r, nil
+}
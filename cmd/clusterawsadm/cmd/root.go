@@ -24,6 +24,9 @@ import (
 	"github.com/spf13/cobra"
 	"github.com/spf13/pflag"
 	"sigs.k8s.io/cluster-api-provider-aws/cmd/clusterawsadm/cmd/alpha"
+	"sigs.k8s.io/cluster-api-provider-aws/cmd/clusterawsadm/cmd/resources"
+	"sigs.k8s.io/cluster-api-provider-aws/cmd/clusterawsadm/cmd/tags"
+	"sigs.k8s.io/cluster-api-provider-aws/cmd/clusterawsadm/cmd/validate"
 	"sigs.k8s.io/cluster-api-provider-aws/cmd/clusterawsadm/cmd/version"
 )
 
@@ -41,6 +44,9 @@ func RootCmd() *cobra.Command {
 		},
 	}
 	newCmd.AddCommand(alpha.AlphaCmd())
+	newCmd.AddCommand(resources.ResourcesCmd(os.Stdout))
+	newCmd.AddCommand(tags.TagsCmd(os.Stdout))
+	newCmd.AddCommand(validate.ValidateCmd(os.Stdout))
 	newCmd.AddCommand(version.VersionCmd(os.Stdout))
 	return newCmd
 }
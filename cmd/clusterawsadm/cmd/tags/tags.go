@@ -0,0 +1,148 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tags
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"sort"
+	"text/tabwriter"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	infrav1 "sigs.k8s.io/cluster-api-provider-aws/api/v1alpha3"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/scope"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/services/ec2"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/services/elb"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1alpha3"
+	"sigs.k8s.io/yaml"
+)
+
+// TagsCmd is the top-level set of commands to inspect the tags of AWS resources.
+func TagsCmd(out io.Writer) *cobra.Command { // nolint
+	newCmd := &cobra.Command{
+		Use:   "tags",
+		Short: "Inspect the tags applied to AWS resources",
+		Run: func(cmd *cobra.Command, args []string) {
+			cmd.Help()
+		},
+	}
+	newCmd.AddCommand(ShowCmd(out))
+	return newCmd
+}
+
+// ShowCmd prints the tags the controllers apply to the AWS resources of a cluster.
+func ShowCmd(out io.Writer) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "show",
+		Short: "Print the tags applied to the AWS resources of an AWSCluster",
+		Long: `Print the tags the controllers apply to each type of AWS resource they manage for an AWSCluster,
+computed from the AWSCluster spec without contacting AWS.
+
+Subnets additionally get the tags from their own spec, and instances get a Name tag with the name
+of their AWSMachine and the AWSMachine's additionalTags.`,
+		Args: cobra.ExactArgs(0),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return RunShow(out, cmd)
+		},
+	}
+	cmd.Flags().StringP("filename", "f", "", "File containing the AWSCluster")
+	cmd.Flags().String("cluster-name", "", "Name of the Cluster owning the AWSCluster. If unspecified, the name of the AWSCluster is used")
+	cmd.Flags().StringP("output", "o", "", "Output format; available options are 'json'")
+	_ = cmd.MarkFlagRequired("filename")
+	return cmd
+}
+
+// RunShow prints the tags of the AWSCluster given to the cobra.Command.
+func RunShow(out io.Writer, cmd *cobra.Command) error {
+	filename, _ := cmd.Flags().GetString("filename")
+	clusterName, _ := cmd.Flags().GetString("cluster-name")
+	of, _ := cmd.Flags().GetString("output")
+
+	if of != "" && of != "json" {
+		return errors.Errorf("invalid output format: %s", of)
+	}
+
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return errors.Wrapf(err, "failed to read %q", filename)
+	}
+	awsCluster := &infrav1.AWSCluster{}
+	if err := yaml.UnmarshalStrict(data, awsCluster); err != nil {
+		return errors.Wrapf(err, "failed to parse AWSCluster from %q", filename)
+	}
+
+	if clusterName == "" {
+		clusterName = awsCluster.Name
+	}
+	if clusterName == "" {
+		return errors.New("the AWSCluster has no name, set --cluster-name")
+	}
+
+	clusterScope, err := scope.NewClusterScope(scope.ClusterScopeParams{
+		Cluster: &clusterv1.Cluster{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      clusterName,
+				Namespace: awsCluster.Namespace,
+			},
+		},
+		AWSCluster: awsCluster,
+	})
+	if err != nil {
+		return errors.Wrap(err, "failed to create cluster scope")
+	}
+
+	resourceTags := ec2.NewService(clusterScope).ResourceTags()
+	for resource, tags := range elb.NewService(clusterScope).ResourceTags() {
+		resourceTags[resource] = tags
+	}
+
+	switch of {
+	case "":
+		resources := make([]string, 0, len(resourceTags))
+		for resource := range resourceTags {
+			resources = append(resources, resource)
+		}
+		sort.Strings(resources)
+
+		w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "RESOURCE\tKEY\tVALUE")
+		for _, resource := range resources {
+			tags := resourceTags[resource]
+			keys := make([]string, 0, len(tags))
+			for k := range tags {
+				keys = append(keys, k)
+			}
+			sort.Strings(keys)
+			for _, k := range keys {
+				fmt.Fprintf(w, "%s\t%s\t%s\n", resource, k, tags[k])
+			}
+		}
+		return w.Flush()
+	case "json":
+		j, err := json.MarshalIndent(resourceTags, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Fprintln(out, string(j))
+	}
+
+	return nil
+}
@@ -0,0 +1,369 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package validate
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/endpoints"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/aws/aws-sdk-go/service/ec2/ec2iface"
+	awsiam "github.com/aws/aws-sdk-go/service/iam"
+	"github.com/aws/aws-sdk-go/service/iam/iamiface"
+	"github.com/aws/aws-sdk-go/service/servicequotas"
+	"github.com/aws/aws-sdk-go/service/servicequotas/servicequotasiface"
+	"github.com/pkg/errors"
+	infrav1 "sigs.k8s.io/cluster-api-provider-aws/api/v1alpha3"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/awserrors"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/filter"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/partition"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/services/cloudformation"
+	capaec2 "sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/services/ec2"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/services/iam"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/services/sts"
+)
+
+const (
+	// vpcQuotaCode is the Service Quotas code for "VPCs per Region".
+	vpcQuotaCode = "L-F678F1CE"
+
+	// accountAttributeMaxEIPs and accountAttributeMaxInstances are the EC2
+	// account attributes holding the Elastic IP and instance limits.
+	accountAttributeMaxEIPs      = "vpc-max-elastic-ips"
+	accountAttributeMaxInstances = "max-instances"
+)
+
+// Result is the outcome of a single validation check.
+type Result struct {
+	Name    string `json:"name"`
+	Passed  bool   `json:"passed"`
+	Message string `json:"message"`
+}
+
+func pass(name, format string, args ...interface{}) Result {
+	return Result{Name: name, Passed: true, Message: fmt.Sprintf(format, args...)}
+}
+
+func fail(name, format string, args ...interface{}) Result {
+	return Result{Name: name, Passed: false, Message: fmt.Sprintf(format, args...)}
+}
+
+// validator runs the validation checks of an AWSCluster against an account.
+type validator struct {
+	cluster           *infrav1.AWSCluster
+	kubernetesVersion string
+	machines          int
+	principalARN      string
+
+	EC2           ec2iface.EC2API
+	IAM           iamiface.IAMAPI
+	ServiceQuotas servicequotasiface.ServiceQuotasAPI
+	STS           *sts.Service
+}
+
+func (v *validator) run() []Result {
+	region := v.checkRegion()
+	if !region.Passed {
+		// Every other check talks to the region, don't bother.
+		return []Result{region}
+	}
+
+	results := []Result{
+		region,
+		v.checkAvailabilityZones(),
+		v.checkVPC(),
+		v.checkElasticIPQuota(),
+		v.checkInstanceQuota(),
+	}
+	if v.kubernetesVersion != "" {
+		results = append(results, v.checkAMI())
+	}
+	return append(results, v.checkIAMPermissions())
+}
+
+func (v *validator) managedVPC() bool {
+	return v.cluster.Spec.NetworkSpec.VPC.ID == ""
+}
+
+func (v *validator) checkRegion() Result {
+	const name = "region"
+	region := v.cluster.Spec.Region
+
+	if region == "" {
+		return fail(name, "spec.region is not set")
+	}
+	if _, ok := endpoints.PartitionForRegion(endpoints.DefaultPartitions(), region); !ok {
+		return fail(name, "region %q is not a known AWS region", region)
+	}
+
+	out, err := v.EC2.DescribeRegions(&ec2.DescribeRegionsInput{
+		AllRegions:  aws.Bool(true),
+		RegionNames: []*string{aws.String(region)},
+	})
+	if err != nil {
+		return fail(name, "failed to describe region %q: %v", region, err)
+	}
+	if len(out.Regions) == 0 {
+		return fail(name, "region %q is not available to this account", region)
+	}
+	if status := aws.StringValue(out.Regions[0].OptInStatus); status == "not-opted-in" {
+		return fail(name, "region %q has not been enabled for this account", region)
+	}
+
+	return pass(name, "region %q is available", region)
+}
+
+func (v *validator) checkAvailabilityZones() Result {
+	const name = "availability-zones"
+
+	out, err := v.EC2.DescribeAvailabilityZones(&ec2.DescribeAvailabilityZonesInput{
+		Filters: []*ec2.Filter{filter.EC2.Available()},
+	})
+	if err != nil {
+		return fail(name, "failed to describe availability zones: %v", err)
+	}
+	available := map[string]bool{}
+	for _, zone := range out.AvailabilityZones {
+		available[aws.StringValue(zone.ZoneName)] = true
+	}
+
+	missing := []string{}
+	for _, sn := range v.cluster.Spec.NetworkSpec.Subnets {
+		if sn.AvailabilityZone != "" && !available[sn.AvailabilityZone] {
+			missing = append(missing, sn.AvailabilityZone)
+		}
+	}
+	if len(missing) > 0 {
+		sort.Strings(missing)
+		return fail(name, "availability zones %s are not available in region %q", strings.Join(missing, ", "), v.cluster.Spec.Region)
+	}
+	if len(available) == 0 {
+		return fail(name, "no availability zones are available in region %q", v.cluster.Spec.Region)
+	}
+
+	return pass(name, "%d availability zones are available", len(available))
+}
+
+func (v *validator) checkVPC() Result {
+	const name = "vpc"
+
+	if !v.managedVPC() {
+		id := v.cluster.Spec.NetworkSpec.VPC.ID
+		_, err := v.EC2.DescribeVpcs(&ec2.DescribeVpcsInput{
+			VpcIds: []*string{aws.String(id)},
+		})
+		if err != nil {
+			return fail(name, "failed to find existing VPC %q: %v", id, err)
+		}
+		return pass(name, "existing VPC %q found", id)
+	}
+
+	quota, err := v.serviceQuota("vpc", vpcQuotaCode)
+	if err != nil {
+		return fail(name, "failed to get the VPC quota: %v", err)
+	}
+
+	used := 0
+	err = v.EC2.DescribeVpcsPages(&ec2.DescribeVpcsInput{}, func(out *ec2.DescribeVpcsOutput, last bool) bool {
+		used += len(out.Vpcs)
+		return true
+	})
+	if err != nil {
+		return fail(name, "failed to describe VPCs: %v", err)
+	}
+
+	return headroom(name, "VPCs", quota, used, 1)
+}
+
+func (v *validator) checkElasticIPQuota() Result {
+	const name = "elastic-ip-quota"
+
+	// One Elastic IP is allocated per NAT gateway, that is per public subnet
+	// of a managed VPC.
+	needed := 0
+	if v.managedVPC() {
+		needed = 1
+		if subnets := v.cluster.Spec.NetworkSpec.Subnets; len(subnets) > 0 {
+			needed = len(subnets.FilterPublic())
+		}
+	}
+
+	quota, err := v.accountAttribute(accountAttributeMaxEIPs)
+	if err != nil {
+		return fail(name, "failed to get the Elastic IP quota: %v", err)
+	}
+
+	out, err := v.EC2.DescribeAddresses(&ec2.DescribeAddressesInput{
+		Filters: []*ec2.Filter{
+			{
+				Name:   aws.String("domain"),
+				Values: aws.StringSlice([]string{ec2.DomainTypeVpc}),
+			},
+		},
+	})
+	if err != nil {
+		return fail(name, "failed to describe addresses: %v", err)
+	}
+
+	return headroom(name, "Elastic IPs", quota, len(out.Addresses), needed)
+}
+
+func (v *validator) checkInstanceQuota() Result {
+	const name = "instance-quota"
+
+	needed := v.machines
+	if v.managedVPC() {
+		// The bastion host.
+		needed++
+	}
+
+	quota, err := v.accountAttribute(accountAttributeMaxInstances)
+	if err != nil {
+		return fail(name, "failed to get the instance quota: %v", err)
+	}
+
+	used := 0
+	input := &ec2.DescribeInstancesInput{
+		Filters: []*ec2.Filter{
+			filter.EC2.InstanceStates(ec2.InstanceStateNamePending, ec2.InstanceStateNameRunning),
+		},
+	}
+	err = v.EC2.DescribeInstancesPages(input, func(out *ec2.DescribeInstancesOutput, last bool) bool {
+		for _, r := range out.Reservations {
+			used += len(r.Instances)
+		}
+		return true
+	})
+	if err != nil {
+		return fail(name, "failed to describe instances: %v", err)
+	}
+
+	return headroom(name, "instances", quota, used, needed)
+}
+
+func (v *validator) checkAMI() Result {
+	const name = "ami"
+
+	spec := v.cluster.Spec
+	image, err := capaec2.DefaultAMILookup(v.EC2, spec.ImageLookupOrg, spec.ImageLookupBaseOS, spec.ImageLookupFormat, v.kubernetesVersion)
+	if err != nil {
+		return fail(name, "no AMI for Kubernetes %s: %v", v.kubernetesVersion, err)
+	}
+
+	return pass(name, "found AMI %q for Kubernetes %s", aws.StringValue(image.ImageId), v.kubernetesVersion)
+}
+
+func (v *validator) checkIAMPermissions() Result {
+	const name = "iam-permissions"
+
+	principal := v.principalARN
+	if principal == "" {
+		accountID, err := v.STS.AccountID()
+		if err != nil {
+			return fail(name, "failed to get the account ID: %v", err)
+		}
+		principal = partition.ARN(partition.ForRegion(v.cluster.Spec.Region), "iam", "", accountID, "role/"+iam.NewManagedName("controllers"))
+	}
+
+	denied := []string{}
+	input := &awsiam.SimulatePrincipalPolicyInput{
+		PolicySourceArn: aws.String(principal),
+		ActionNames:     aws.StringSlice(cloudformation.ControllersPolicyActions()),
+	}
+	err := v.IAM.SimulatePrincipalPolicyPages(input, func(out *awsiam.SimulatePolicyResponse, last bool) bool {
+		for _, r := range out.EvaluationResults {
+			if aws.StringValue(r.EvalDecision) != awsiam.PolicyEvaluationDecisionTypeAllowed {
+				denied = append(denied, aws.StringValue(r.EvalActionName))
+			}
+		}
+		return true
+	})
+	if err != nil {
+		return fail(name, "failed to simulate the policies of %q: %v", principal, err)
+	}
+	if len(denied) > 0 {
+		sort.Strings(denied)
+		return fail(name, "%q is not allowed %s", principal, strings.Join(denied, ", "))
+	}
+
+	return pass(name, "%q has all the permissions the controllers need", principal)
+}
+
+// headroom checks that needed more resources fit in quota given used.
+func headroom(name, resource string, quota, used, needed int) Result {
+	if used+needed > quota {
+		return fail(name, "%d more %s are needed but only %d of %d are left", needed, resource, quota-used, quota)
+	}
+	return pass(name, "%d more %s are needed, %d of %d are left", needed, resource, quota-used, quota)
+}
+
+// serviceQuota returns the applied value of a quota, or its AWS default when
+// it was never changed for the account.
+func (v *validator) serviceQuota(serviceCode, quotaCode string) (int, error) {
+	var quota *servicequotas.ServiceQuota
+
+	out, err := v.ServiceQuotas.GetServiceQuota(&servicequotas.GetServiceQuotaInput{
+		ServiceCode: aws.String(serviceCode),
+		QuotaCode:   aws.String(quotaCode),
+	})
+	if code, _ := awserrors.Code(err); code == servicequotas.ErrCodeNoSuchResourceException {
+		defaultOut, defaultErr := v.ServiceQuotas.GetAWSDefaultServiceQuota(&servicequotas.GetAWSDefaultServiceQuotaInput{
+			ServiceCode: aws.String(serviceCode),
+			QuotaCode:   aws.String(quotaCode),
+		})
+		if defaultErr != nil {
+			return 0, errors.Wrapf(defaultErr, "failed to get default quota %s/%s", serviceCode, quotaCode)
+		}
+		quota = defaultOut.Quota
+	} else if err != nil {
+		return 0, errors.Wrapf(err, "failed to get quota %s/%s", serviceCode, quotaCode)
+	} else {
+		quota = out.Quota
+	}
+
+	if quota == nil || quota.Value == nil {
+		return 0, errors.Errorf("quota %s/%s has no value", serviceCode, quotaCode)
+	}
+	return int(aws.Float64Value(quota.Value)), nil
+}
+
+// accountAttribute returns the numeric value of an EC2 account attribute.
+func (v *validator) accountAttribute(attribute string) (int, error) {
+	out, err := v.EC2.DescribeAccountAttributes(&ec2.DescribeAccountAttributesInput{
+		AttributeNames: aws.StringSlice([]string{attribute}),
+	})
+	if err != nil {
+		return 0, errors.Wrapf(err, "failed to describe account attribute %q", attribute)
+	}
+
+	for _, attr := range out.AccountAttributes {
+		if aws.StringValue(attr.AttributeName) != attribute || len(attr.AttributeValues) == 0 {
+			continue
+		}
+		value, err := strconv.Atoi(aws.StringValue(attr.AttributeValues[0].AttributeValue))
+		if err != nil {
+			return 0, errors.Wrapf(err, "failed to parse account attribute %q", attribute)
+		}
+		return value, nil
+	}
+
+	return 0, errors.Errorf("account attribute %q not found", attribute)
+}
@@ -0,0 +1,619 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package validate
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	awsiam "github.com/aws/aws-sdk-go/service/iam"
+	"github.com/aws/aws-sdk-go/service/iam/iamiface"
+	"github.com/aws/aws-sdk-go/service/servicequotas"
+	"github.com/aws/aws-sdk-go/service/servicequotas/servicequotasiface"
+	awssts "github.com/aws/aws-sdk-go/service/sts"
+	"github.com/aws/aws-sdk-go/service/sts/stsiface"
+	"github.com/golang/mock/gomock"
+	infrav1 "sigs.k8s.io/cluster-api-provider-aws/api/v1alpha3"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/services/ec2/mock_ec2iface"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/services/iam"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/services/sts"
+)
+
+// fakeIAM, fakeServiceQuotas and fakeSTS implement the calls the checks make
+// to the clients there are no generated mocks for.
+type fakeIAM struct {
+	iamiface.IAMAPI
+	results []*awsiam.EvaluationResult
+	err     error
+	input   *awsiam.SimulatePrincipalPolicyInput
+}
+
+func (f *fakeIAM) SimulatePrincipalPolicyPages(input *awsiam.SimulatePrincipalPolicyInput, fn func(*awsiam.SimulatePolicyResponse, bool) bool) error {
+	f.input = input
+	if f.err != nil {
+		return f.err
+	}
+	fn(&awsiam.SimulatePolicyResponse{EvaluationResults: f.results}, true)
+	return nil
+}
+
+type fakeServiceQuotas struct {
+	servicequotasiface.ServiceQuotasAPI
+	applied      *float64
+	defaultValue *float64
+	err          error
+}
+
+func (f *fakeServiceQuotas) GetServiceQuota(*servicequotas.GetServiceQuotaInput) (*servicequotas.GetServiceQuotaOutput, error) {
+	if f.err != nil {
+		return nil, f.err
+	}
+	if f.applied == nil {
+		return nil, awserr.New(servicequotas.ErrCodeNoSuchResourceException, "not found", nil)
+	}
+	return &servicequotas.GetServiceQuotaOutput{Quota: &servicequotas.ServiceQuota{Value: f.applied}}, nil
+}
+
+func (f *fakeServiceQuotas) GetAWSDefaultServiceQuota(*servicequotas.GetAWSDefaultServiceQuotaInput) (*servicequotas.GetAWSDefaultServiceQuotaOutput, error) {
+	return &servicequotas.GetAWSDefaultServiceQuotaOutput{Quota: &servicequotas.ServiceQuota{Value: f.defaultValue}}, nil
+}
+
+type fakeSTS struct {
+	stsiface.STSAPI
+	account string
+	err     error
+}
+
+func (f *fakeSTS) GetCallerIdentity(*awssts.GetCallerIdentityInput) (*awssts.GetCallerIdentityOutput, error) {
+	if f.err != nil {
+		return nil, f.err
+	}
+	return &awssts.GetCallerIdentityOutput{Account: aws.String(f.account)}, nil
+}
+
+func expectAccountAttribute(m *mock_ec2iface.MockEC2APIMockRecorder, name, value string) {
+	m.DescribeAccountAttributes(gomock.Eq(&ec2.DescribeAccountAttributesInput{
+		AttributeNames: aws.StringSlice([]string{name}),
+	})).Return(&ec2.DescribeAccountAttributesOutput{
+		AccountAttributes: []*ec2.AccountAttribute{
+			{
+				AttributeName:   aws.String(name),
+				AttributeValues: []*ec2.AccountAttributeValue{{AttributeValue: aws.String(value)}},
+			},
+		},
+	}, nil)
+}
+
+func checkResult(t *testing.T, got Result, passed bool, message string) {
+	t.Helper()
+	if got.Passed != passed {
+		t.Fatalf("expected passed to be %t, got %+v", passed, got)
+	}
+	if !strings.Contains(got.Message, message) {
+		t.Fatalf("expected message to contain %q, got %q", message, got.Message)
+	}
+}
+
+func TestCheckRegion(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	testCases := []struct {
+		name    string
+		region  string
+		expect  func(m *mock_ec2iface.MockEC2APIMockRecorder)
+		passed  bool
+		message string
+	}{
+		{
+			name:    "region is not set",
+			expect:  func(m *mock_ec2iface.MockEC2APIMockRecorder) {},
+			message: "spec.region is not set",
+		},
+		{
+			name:    "unknown region",
+			region:  "mars-north-1",
+			expect:  func(m *mock_ec2iface.MockEC2APIMockRecorder) {},
+			message: "is not a known AWS region",
+		},
+		{
+			name:   "region is not available",
+			region: "eu-west-1",
+			expect: func(m *mock_ec2iface.MockEC2APIMockRecorder) {
+				m.DescribeRegions(gomock.AssignableToTypeOf(&ec2.DescribeRegionsInput{})).
+					Return(&ec2.DescribeRegionsOutput{}, nil)
+			},
+			message: "is not available to this account",
+		},
+		{
+			name:   "region is not enabled",
+			region: "ap-east-1",
+			expect: func(m *mock_ec2iface.MockEC2APIMockRecorder) {
+				m.DescribeRegions(gomock.AssignableToTypeOf(&ec2.DescribeRegionsInput{})).
+					Return(&ec2.DescribeRegionsOutput{
+						Regions: []*ec2.Region{{RegionName: aws.String("ap-east-1"), OptInStatus: aws.String("not-opted-in")}},
+					}, nil)
+			},
+			message: "has not been enabled",
+		},
+		{
+			name:   "describe fails",
+			region: "eu-west-1",
+			expect: func(m *mock_ec2iface.MockEC2APIMockRecorder) {
+				m.DescribeRegions(gomock.AssignableToTypeOf(&ec2.DescribeRegionsInput{})).
+					Return(nil, errors.New("access denied"))
+			},
+			message: "access denied",
+		},
+		{
+			name:   "region is available",
+			region: "eu-west-1",
+			expect: func(m *mock_ec2iface.MockEC2APIMockRecorder) {
+				m.DescribeRegions(gomock.Eq(&ec2.DescribeRegionsInput{
+					AllRegions:  aws.Bool(true),
+					RegionNames: aws.StringSlice([]string{"eu-west-1"}),
+				})).Return(&ec2.DescribeRegionsOutput{
+					Regions: []*ec2.Region{{RegionName: aws.String("eu-west-1"), OptInStatus: aws.String("opt-in-not-required")}},
+				}, nil)
+			},
+			passed:  true,
+			message: `region "eu-west-1" is available`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ec2Mock := mock_ec2iface.NewMockEC2API(mockCtrl)
+			tc.expect(ec2Mock.EXPECT())
+
+			v := &validator{
+				cluster: &infrav1.AWSCluster{Spec: infrav1.AWSClusterSpec{Region: tc.region}},
+				EC2:     ec2Mock,
+			}
+			checkResult(t, v.checkRegion(), tc.passed, tc.message)
+		})
+	}
+}
+
+func TestCheckAvailabilityZones(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	zones := func(names ...string) *ec2.DescribeAvailabilityZonesOutput {
+		out := &ec2.DescribeAvailabilityZonesOutput{}
+		for _, name := range names {
+			out.AvailabilityZones = append(out.AvailabilityZones, &ec2.AvailabilityZone{ZoneName: aws.String(name)})
+		}
+		return out
+	}
+
+	testCases := []struct {
+		name    string
+		subnets infrav1.Subnets
+		out     *ec2.DescribeAvailabilityZonesOutput
+		err     error
+		passed  bool
+		message string
+	}{
+		{
+			name:    "zones of the subnets are available",
+			subnets: infrav1.Subnets{{AvailabilityZone: "eu-west-1a"}, {AvailabilityZone: "eu-west-1b"}},
+			out:     zones("eu-west-1a", "eu-west-1b", "eu-west-1c"),
+			passed:  true,
+			message: "3 availability zones are available",
+		},
+		{
+			name:    "zones of the subnets are not available",
+			subnets: infrav1.Subnets{{AvailabilityZone: "eu-west-1d"}, {AvailabilityZone: "eu-west-1a"}, {AvailabilityZone: "eu-west-1e"}},
+			out:     zones("eu-west-1a"),
+			message: "availability zones eu-west-1d, eu-west-1e are not available",
+		},
+		{
+			name:    "no zones are available",
+			out:     zones(),
+			message: "no availability zones are available",
+		},
+		{
+			name:    "describe fails",
+			err:     errors.New("access denied"),
+			message: "access denied",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ec2Mock := mock_ec2iface.NewMockEC2API(mockCtrl)
+			ec2Mock.EXPECT().DescribeAvailabilityZones(gomock.AssignableToTypeOf(&ec2.DescribeAvailabilityZonesInput{})).
+				Return(tc.out, tc.err)
+
+			v := &validator{
+				cluster: &infrav1.AWSCluster{
+					Spec: infrav1.AWSClusterSpec{
+						Region:      "eu-west-1",
+						NetworkSpec: infrav1.NetworkSpec{Subnets: tc.subnets},
+					},
+				},
+				EC2: ec2Mock,
+			}
+			checkResult(t, v.checkAvailabilityZones(), tc.passed, tc.message)
+		})
+	}
+}
+
+func TestCheckVPC(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	describeVpcsPages := func(used int) func(m *mock_ec2iface.MockEC2APIMockRecorder) {
+		return func(m *mock_ec2iface.MockEC2APIMockRecorder) {
+			m.DescribeVpcsPages(gomock.Any(), gomock.Any()).
+				DoAndReturn(func(input *ec2.DescribeVpcsInput, fn func(*ec2.DescribeVpcsOutput, bool) bool) error {
+					fn(&ec2.DescribeVpcsOutput{Vpcs: make([]*ec2.Vpc, used)}, true)
+					return nil
+				})
+		}
+	}
+
+	testCases := []struct {
+		name    string
+		vpcID   string
+		quotas  *fakeServiceQuotas
+		expect  func(m *mock_ec2iface.MockEC2APIMockRecorder)
+		passed  bool
+		message string
+	}{
+		{
+			name:  "existing VPC is found",
+			vpcID: "vpc-existing",
+			expect: func(m *mock_ec2iface.MockEC2APIMockRecorder) {
+				m.DescribeVpcs(gomock.Eq(&ec2.DescribeVpcsInput{
+					VpcIds: aws.StringSlice([]string{"vpc-existing"}),
+				})).Return(&ec2.DescribeVpcsOutput{Vpcs: []*ec2.Vpc{{VpcId: aws.String("vpc-existing")}}}, nil)
+			},
+			passed:  true,
+			message: `existing VPC "vpc-existing" found`,
+		},
+		{
+			name:  "existing VPC is not found",
+			vpcID: "vpc-existing",
+			expect: func(m *mock_ec2iface.MockEC2APIMockRecorder) {
+				m.DescribeVpcs(gomock.AssignableToTypeOf(&ec2.DescribeVpcsInput{})).
+					Return(nil, awserr.New("InvalidVpcID.NotFound", "not found", nil))
+			},
+			message: `failed to find existing VPC "vpc-existing"`,
+		},
+		{
+			name:    "applied quota has headroom",
+			quotas:  &fakeServiceQuotas{applied: aws.Float64(10)},
+			expect:  describeVpcsPages(9),
+			passed:  true,
+			message: "1 more VPCs are needed, 1 of 10 are left",
+		},
+		{
+			name:    "default quota has headroom",
+			quotas:  &fakeServiceQuotas{defaultValue: aws.Float64(5)},
+			expect:  describeVpcsPages(1),
+			passed:  true,
+			message: "4 of 5 are left",
+		},
+		{
+			name:    "quota is used up",
+			quotas:  &fakeServiceQuotas{applied: aws.Float64(5)},
+			expect:  describeVpcsPages(5),
+			message: "1 more VPCs are needed but only 0 of 5 are left",
+		},
+		{
+			name:    "quota cannot be read",
+			quotas:  &fakeServiceQuotas{err: errors.New("access denied")},
+			expect:  func(m *mock_ec2iface.MockEC2APIMockRecorder) {},
+			message: "failed to get the VPC quota",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ec2Mock := mock_ec2iface.NewMockEC2API(mockCtrl)
+			tc.expect(ec2Mock.EXPECT())
+
+			v := &validator{
+				cluster: &infrav1.AWSCluster{
+					Spec: infrav1.AWSClusterSpec{
+						NetworkSpec: infrav1.NetworkSpec{VPC: infrav1.VPCSpec{ID: tc.vpcID}},
+					},
+				},
+				EC2:           ec2Mock,
+				ServiceQuotas: tc.quotas,
+			}
+			checkResult(t, v.checkVPC(), tc.passed, tc.message)
+		})
+	}
+}
+
+func TestCheckElasticIPQuota(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	testCases := []struct {
+		name    string
+		network infrav1.NetworkSpec
+		quota   string
+		used    int
+		passed  bool
+		message string
+	}{
+		{
+			name: "one address per public subnet fits",
+			network: infrav1.NetworkSpec{
+				Subnets: infrav1.Subnets{
+					{AvailabilityZone: "eu-west-1a", IsPublic: true},
+					{AvailabilityZone: "eu-west-1a"},
+					{AvailabilityZone: "eu-west-1b", IsPublic: true},
+				},
+			},
+			quota:   "5",
+			used:    3,
+			passed:  true,
+			message: "2 more Elastic IPs are needed, 2 of 5 are left",
+		},
+		{
+			name:    "the default public subnet does not fit",
+			quota:   "5",
+			used:    5,
+			message: "1 more Elastic IPs are needed but only 0 of 5 are left",
+		},
+		{
+			name:    "an existing VPC needs none",
+			network: infrav1.NetworkSpec{VPC: infrav1.VPCSpec{ID: "vpc-existing"}},
+			quota:   "5",
+			used:    5,
+			passed:  true,
+			message: "0 more Elastic IPs are needed",
+		},
+		{
+			name:    "quota cannot be parsed",
+			quota:   "many",
+			message: "failed to get the Elastic IP quota",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ec2Mock := mock_ec2iface.NewMockEC2API(mockCtrl)
+			expectAccountAttribute(ec2Mock.EXPECT(), "vpc-max-elastic-ips", tc.quota)
+			// The addresses are only described once the quota is read.
+			if tc.used > 0 {
+				ec2Mock.EXPECT().DescribeAddresses(gomock.AssignableToTypeOf(&ec2.DescribeAddressesInput{})).
+					Return(&ec2.DescribeAddressesOutput{Addresses: make([]*ec2.Address, tc.used)}, nil)
+			}
+
+			v := &validator{
+				cluster: &infrav1.AWSCluster{Spec: infrav1.AWSClusterSpec{NetworkSpec: tc.network}},
+				EC2:     ec2Mock,
+			}
+			checkResult(t, v.checkElasticIPQuota(), tc.passed, tc.message)
+		})
+	}
+}
+
+func TestCheckInstanceQuota(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	testCases := []struct {
+		name     string
+		vpcID    string
+		machines int
+		quota    string
+		used     int
+		passed   bool
+		message  string
+	}{
+		{
+			name:     "machines and the bastion fit",
+			machines: 3,
+			quota:    "20",
+			used:     10,
+			passed:   true,
+			message:  "4 more instances are needed, 10 of 20 are left",
+		},
+		{
+			name:     "machines and the bastion do not fit",
+			machines: 3,
+			quota:    "20",
+			used:     17,
+			message:  "4 more instances are needed but only 3 of 20 are left",
+		},
+		{
+			name:     "an existing VPC has no bastion",
+			vpcID:    "vpc-existing",
+			machines: 3,
+			quota:    "20",
+			used:     17,
+			passed:   true,
+			message:  "3 more instances are needed",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ec2Mock := mock_ec2iface.NewMockEC2API(mockCtrl)
+			expectAccountAttribute(ec2Mock.EXPECT(), "max-instances", tc.quota)
+			ec2Mock.EXPECT().DescribeInstancesPages(gomock.Any(), gomock.Any()).
+				DoAndReturn(func(input *ec2.DescribeInstancesInput, fn func(*ec2.DescribeInstancesOutput, bool) bool) error {
+					fn(&ec2.DescribeInstancesOutput{
+						Reservations: []*ec2.Reservation{{Instances: make([]*ec2.Instance, tc.used)}},
+					}, true)
+					return nil
+				})
+
+			v := &validator{
+				cluster: &infrav1.AWSCluster{
+					Spec: infrav1.AWSClusterSpec{
+						NetworkSpec: infrav1.NetworkSpec{VPC: infrav1.VPCSpec{ID: tc.vpcID}},
+					},
+				},
+				machines: tc.machines,
+				EC2:      ec2Mock,
+			}
+			checkResult(t, v.checkInstanceQuota(), tc.passed, tc.message)
+		})
+	}
+
+	t.Run("quota attribute is missing", func(t *testing.T) {
+		ec2Mock := mock_ec2iface.NewMockEC2API(mockCtrl)
+		ec2Mock.EXPECT().DescribeAccountAttributes(gomock.AssignableToTypeOf(&ec2.DescribeAccountAttributesInput{})).
+			Return(&ec2.DescribeAccountAttributesOutput{}, nil)
+
+		v := &validator{cluster: &infrav1.AWSCluster{}, EC2: ec2Mock}
+		checkResult(t, v.checkInstanceQuota(), false, `account attribute "max-instances" not found`)
+	})
+}
+
+func TestCheckAMI(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	testCases := []struct {
+		name    string
+		images  []*ec2.Image
+		passed  bool
+		message string
+	}{
+		{
+			name: "an AMI is found",
+			images: []*ec2.Image{
+				{ImageId: aws.String("ami-1234"), CreationDate: aws.String("2019-10-01T00:00:00.000Z")},
+			},
+			passed:  true,
+			message: `found AMI "ami-1234" for Kubernetes v1.16.2`,
+		},
+		{
+			name:    "no AMI is found",
+			message: "no AMI for Kubernetes v1.16.2",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ec2Mock := mock_ec2iface.NewMockEC2API(mockCtrl)
+			ec2Mock.EXPECT().DescribeImages(gomock.AssignableToTypeOf(&ec2.DescribeImagesInput{})).
+				Return(&ec2.DescribeImagesOutput{Images: tc.images}, nil)
+
+			v := &validator{
+				cluster:           &infrav1.AWSCluster{},
+				kubernetesVersion: "v1.16.2",
+				EC2:               ec2Mock,
+			}
+			checkResult(t, v.checkAMI(), tc.passed, tc.message)
+		})
+	}
+}
+
+func TestCheckIAMPermissions(t *testing.T) {
+	controllersRole := "arn:aws:iam::123456789012:role/" + iam.NewManagedName("controllers")
+
+	testCases := []struct {
+		name          string
+		principalARN  string
+		iam           *fakeIAM
+		sts           *fakeSTS
+		passed        bool
+		message       string
+		wantPrincipal string
+	}{
+		{
+			name:         "all actions are allowed",
+			principalARN: "arn:aws:iam::123456789012:role/custom",
+			iam: &fakeIAM{
+				results: []*awsiam.EvaluationResult{
+					{EvalActionName: aws.String("ec2:RunInstances"), EvalDecision: aws.String(awsiam.PolicyEvaluationDecisionTypeAllowed)},
+				},
+			},
+			passed:        true,
+			message:       "has all the permissions the controllers need",
+			wantPrincipal: "arn:aws:iam::123456789012:role/custom",
+		},
+		{
+			name:         "actions are denied",
+			principalARN: "arn:aws:iam::123456789012:role/custom",
+			iam: &fakeIAM{
+				results: []*awsiam.EvaluationResult{
+					{EvalActionName: aws.String("ec2:RunInstances"), EvalDecision: aws.String(awsiam.PolicyEvaluationDecisionTypeImplicitDeny)},
+					{EvalActionName: aws.String("ec2:CreateVpc"), EvalDecision: aws.String(awsiam.PolicyEvaluationDecisionTypeAllowed)},
+					{EvalActionName: aws.String("ec2:AllocateAddress"), EvalDecision: aws.String(awsiam.PolicyEvaluationDecisionTypeExplicitDeny)},
+				},
+			},
+			message:       "is not allowed ec2:AllocateAddress, ec2:RunInstances",
+			wantPrincipal: "arn:aws:iam::123456789012:role/custom",
+		},
+		{
+			name:          "the controllers role of the account is checked by default",
+			iam:           &fakeIAM{},
+			sts:           &fakeSTS{account: "123456789012"},
+			passed:        true,
+			message:       controllersRole,
+			wantPrincipal: controllersRole,
+		},
+		{
+			name:    "the account cannot be looked up",
+			iam:     &fakeIAM{},
+			sts:     &fakeSTS{err: errors.New("expired token")},
+			message: "failed to get the account ID",
+		},
+		{
+			name:          "the simulation fails",
+			principalARN:  "arn:aws:iam::123456789012:role/custom",
+			iam:           &fakeIAM{err: errors.New("access denied")},
+			message:       "failed to simulate the policies",
+			wantPrincipal: "arn:aws:iam::123456789012:role/custom",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			v := &validator{
+				cluster:      &infrav1.AWSCluster{Spec: infrav1.AWSClusterSpec{Region: "eu-west-1"}},
+				principalARN: tc.principalARN,
+				IAM:          tc.iam,
+			}
+			if tc.sts != nil {
+				v.STS = sts.NewService(tc.sts)
+			}
+			checkResult(t, v.checkIAMPermissions(), tc.passed, tc.message)
+
+			if tc.wantPrincipal == "" {
+				return
+			}
+			if got := aws.StringValue(tc.iam.input.PolicySourceArn); got != tc.wantPrincipal {
+				t.Fatalf("expected the policies of %q to be simulated, got %q", tc.wantPrincipal, got)
+			}
+		})
+	}
+}
+
+func TestRunStopsAtRegion(t *testing.T) {
+	v := &validator{cluster: &infrav1.AWSCluster{}}
+
+	results := v.run()
+	if len(results) != 1 || results[0].Name != "region" || results[0].Passed {
+		t.Fatalf("expected only the failed region check, got %+v", results)
+	}
+}
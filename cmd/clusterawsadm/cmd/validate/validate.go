@@ -0,0 +1,135 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package validate
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	awsiam "github.com/aws/aws-sdk-go/service/iam"
+	"github.com/aws/aws-sdk-go/service/servicequotas"
+	awssts "github.com/aws/aws-sdk-go/service/sts"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	infrav1 "sigs.k8s.io/cluster-api-provider-aws/api/v1alpha3"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/services/sts"
+	"sigs.k8s.io/yaml"
+)
+
+// Report is the outcome of validating an AWSCluster.
+type Report struct {
+	Passed bool     `json:"passed"`
+	Checks []Result `json:"checks"`
+}
+
+// ValidateCmd validates an AWSCluster spec against the target AWS account.
+func ValidateCmd(out io.Writer) *cobra.Command { // nolint
+	cmd := &cobra.Command{
+		Use:   "validate",
+		Short: "Validate an AWSCluster against an AWS account",
+		Long: `Validate an AWSCluster spec against the AWS account of the current credentials before applying it.
+
+Checks that the region and availability zones exist, that the VPC, Elastic IP and instance quotas
+have enough headroom, that a default AMI exists for the Kubernetes version and that the controllers
+have the IAM permissions they need.`,
+		Args: cobra.ExactArgs(0),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return RunValidate(out, cmd)
+		},
+	}
+	cmd.Flags().StringP("filename", "f", "", "File containing the AWSCluster to validate")
+	cmd.Flags().String("kubernetes-version", "", "Kubernetes version to look up a default AMI for (e.g. v1.16.2). If unspecified, the AMI check is skipped")
+	cmd.Flags().Int("machines", 1, "Number of machines that will be created for the cluster")
+	cmd.Flags().String("principal-arn", "", "ARN of the IAM principal the controllers run as. If unspecified, the role created by 'clusterawsadm alpha bootstrap' is used")
+	cmd.Flags().StringP("output", "o", "", "Output format; available options are 'json'")
+	_ = cmd.MarkFlagRequired("filename")
+	return cmd
+}
+
+// RunValidate validates the AWSCluster given to the cobra.Command and prints a report.
+// It returns an error if any of the checks failed.
+func RunValidate(out io.Writer, cmd *cobra.Command) error {
+	filename, _ := cmd.Flags().GetString("filename")
+	kubernetesVersion, _ := cmd.Flags().GetString("kubernetes-version")
+	machines, _ := cmd.Flags().GetInt("machines")
+	principalARN, _ := cmd.Flags().GetString("principal-arn")
+	of, _ := cmd.Flags().GetString("output")
+
+	if of != "" && of != "json" {
+		return errors.Errorf("invalid output format: %s", of)
+	}
+
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return errors.Wrapf(err, "failed to read %q", filename)
+	}
+	cluster := &infrav1.AWSCluster{}
+	if err := yaml.UnmarshalStrict(data, cluster); err != nil {
+		return errors.Wrapf(err, "failed to parse AWSCluster from %q", filename)
+	}
+
+	sess, err := session.NewSessionWithOptions(session.Options{
+		Config:            aws.Config{Region: aws.String(cluster.Spec.Region)},
+		SharedConfigState: session.SharedConfigEnable,
+	})
+	if err != nil {
+		return errors.Wrap(err, "failed to create AWS session")
+	}
+
+	v := &validator{
+		cluster:           cluster,
+		kubernetesVersion: kubernetesVersion,
+		machines:          machines,
+		principalARN:      principalARN,
+		EC2:               ec2.New(sess),
+		IAM:               awsiam.New(sess),
+		ServiceQuotas:     servicequotas.New(sess),
+		STS:               sts.NewService(awssts.New(sess)),
+	}
+
+	report := Report{Passed: true, Checks: v.run()}
+	for _, r := range report.Checks {
+		report.Passed = report.Passed && r.Passed
+	}
+
+	switch of {
+	case "":
+		for _, r := range report.Checks {
+			status := "PASS"
+			if !r.Passed {
+				status = "FAIL"
+			}
+			fmt.Fprintf(out, "[%s] %s: %s\n", status, r.Name, r.Message)
+		}
+	case "json":
+		j, err := json.MarshalIndent(&report, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Fprintln(out, string(j))
+	}
+
+	if !report.Passed {
+		return errors.Errorf("AWSCluster %q failed validation", cluster.Name)
+	}
+	return nil
+}
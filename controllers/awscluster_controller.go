@@ -22,12 +22,14 @@ import (
 
 	"github.com/go-logr/logr"
 	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/client-go/tools/record"
 	infrav1 "sigs.k8s.io/cluster-api-provider-aws/api/v1alpha3"
 	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/scope"
 	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/services/ec2"
 	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/services/elb"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/services/gc"
 	clusterv1 "sigs.k8s.io/cluster-api/api/v1alpha3"
 	"sigs.k8s.io/cluster-api/util"
 	ctrl "sigs.k8s.io/controller-runtime"
@@ -36,6 +38,10 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 )
 
+// assumeRoleRetryInterval is how often the role or the identity of a cluster
+// is tried again after it failed.
+const assumeRoleRetryInterval = time.Minute
+
 // AWSClusterReconciler reconciles a AwsCluster object
 type AWSClusterReconciler struct {
 	client.Client
@@ -46,6 +52,8 @@ type AWSClusterReconciler struct {
 // +kubebuilder:rbac:groups=infrastructure.cluster.x-k8s.io,resources=awsclusters,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=infrastructure.cluster.x-k8s.io,resources=awsclusters/status,verbs=get;update;patch
 // +kubebuilder:rbac:groups=cluster.x-k8s.io,resources=clusters;clusters/status,verbs=get;list;watch
+// +kubebuilder:rbac:groups=infrastructure.cluster.x-k8s.io,resources=awsclusterroleidentities;awsclusterstaticidentities,verbs=get;list;watch
+// +kubebuilder:rbac:groups="",resources=namespaces,verbs=get;list;watch
 
 func (r *AWSClusterReconciler) Reconcile(req ctrl.Request) (_ ctrl.Result, reterr error) {
 	ctx := context.TODO()
@@ -80,6 +88,12 @@ func (r *AWSClusterReconciler) Reconcile(req ctrl.Request) (_ ctrl.Result, reter
 		Cluster:    cluster,
 		AWSCluster: awsCluster,
 	})
+	if assumeRoleErr, ok := err.(*scope.AssumeRoleError); ok {
+		return r.reconcileAssumeRoleFailure(ctx, awsCluster, assumeRoleErr)
+	}
+	if identityErr, ok := err.(*scope.IdentityError); ok {
+		return r.reconcileIdentityFailure(ctx, awsCluster, identityErr)
+	}
 	if err != nil {
 		return reconcile.Result{}, errors.Errorf("failed to create scope: %+v", err)
 	}
@@ -100,6 +114,38 @@ func (r *AWSClusterReconciler) Reconcile(req ctrl.Request) (_ ctrl.Result, reter
 	return reconcileNormal(clusterScope)
 }
 
+// reconcileAssumeRoleFailure reports that the role of the cluster can't be
+// assumed, without a scope no AWS call can be made. The role is tried again
+// periodically, e.g. while its trust policy is being fixed.
+func (r *AWSClusterReconciler) reconcileAssumeRoleFailure(ctx context.Context, awsCluster *infrav1.AWSCluster, assumeRoleErr *scope.AssumeRoleError) (reconcile.Result, error) {
+	r.Recorder.Eventf(awsCluster, corev1.EventTypeWarning, "FailedAssumeRole", "Failed to assume role %q: %v", assumeRoleErr.RoleARN, assumeRoleErr.Err)
+	return r.reportCredentialsFailure(ctx, awsCluster, scope.AssumeRoleFailedReason, assumeRoleErr)
+}
+
+// reconcileIdentityFailure reports that the identity of the cluster can't be
+// used, e.g. while it doesn't allow the namespace of the cluster yet. It is
+// tried again periodically.
+func (r *AWSClusterReconciler) reconcileIdentityFailure(ctx context.Context, awsCluster *infrav1.AWSCluster, identityErr *scope.IdentityError) (reconcile.Result, error) {
+	r.Recorder.Eventf(awsCluster, corev1.EventTypeWarning, "FailedIdentity", "Failed to use %s %q: %v", identityErr.Kind, identityErr.Name, identityErr.Err)
+	return r.reportCredentialsFailure(ctx, awsCluster, scope.IdentityFailedReason, identityErr)
+}
+
+// reportCredentialsFailure sets the credentials failure of the cluster whose
+// credentials can't be used and requeues it. The failure is transient, so it
+// is not reported as the terminal FailureReason of the cluster.
+func (r *AWSClusterReconciler) reportCredentialsFailure(ctx context.Context, awsCluster *infrav1.AWSCluster, reason string, err error) (reconcile.Result, error) {
+	// The whole status is written rather than patched: a cluster whose
+	// credentials never worked has no status yet, and a patch with only the
+	// credentials failure would lack the required ready field.
+	awsCluster.Status.CredentialsFailureReason = reason
+	awsCluster.Status.CredentialsFailureMessage = err.Error()
+	if err := r.Status().Update(ctx, awsCluster); err != nil {
+		return reconcile.Result{}, errors.Wrap(err, "failed to update AWSCluster status")
+	}
+
+	return reconcile.Result{RequeueAfter: assumeRoleRetryInterval}, nil
+}
+
 // TODO(ncdc): should this be a function on ClusterScope?
 func reconcileDelete(clusterScope *scope.ClusterScope) (reconcile.Result, error) {
 	clusterScope.Info("Reconciling AWSCluster delete")
@@ -108,6 +154,10 @@ func reconcileDelete(clusterScope *scope.ClusterScope) (reconcile.Result, error)
 	elbsvc := elb.NewService(clusterScope)
 	awsCluster := clusterScope.AWSCluster
 
+	if err := gc.NewService(clusterScope).ReconcileDelete(); err != nil {
+		return reconcile.Result{}, errors.Wrapf(err, "error deleting resources created by the workload cluster for AWSCluster %s/%s", awsCluster.Namespace, awsCluster.Name)
+	}
+
 	if err := elbsvc.DeleteLoadbalancers(); err != nil {
 		return reconcile.Result{}, errors.Wrapf(err, "error deleting load balancer for AWSCluster %s/%s", awsCluster.Namespace, awsCluster.Name)
 	}
@@ -152,6 +202,12 @@ func reconcileNormal(clusterScope *scope.ClusterScope) (reconcile.Result, error)
 		return reconcile.Result{}, errors.Wrapf(err, "failed to reconcile load balancers for AWSCluster %s/%s", awsCluster.Namespace, awsCluster.Name)
 	}
 
+	// Every cluster resource has the current additional tags now, remember them
+	// so the ones removed from the spec are removed from the resources later.
+	if err := clusterScope.SetLastAppliedAdditionalTags(); err != nil {
+		return reconcile.Result{}, errors.Wrapf(err, "failed to record applied tags for AWSCluster %s/%s", awsCluster.Namespace, awsCluster.Name)
+	}
+
 	if awsCluster.Status.Network.APIServerELB.DNSName == "" {
 		clusterScope.Info("Waiting on API server ELB DNS name")
 		return reconcile.Result{RequeueAfter: 15 * time.Second}, nil
@@ -161,6 +217,12 @@ func reconcileNormal(clusterScope *scope.ClusterScope) (reconcile.Result, error)
 		Host: awsCluster.Status.Network.APIServerELB.DNSName,
 		Port: clusterScope.APIServerPort(),
 	}
+	if advertised := clusterScope.AdvertisedEndpoint(); advertised != nil {
+		awsCluster.Spec.ControlPlaneEndpoint.Host = advertised.Host
+		if advertised.Port != 0 {
+			awsCluster.Spec.ControlPlaneEndpoint.Port = advertised.Port
+		}
+	}
 
 	for _, subnet := range clusterScope.Subnets().FilterPrivate() {
 		found := false
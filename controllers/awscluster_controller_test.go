@@ -19,9 +19,12 @@ package controllers
 import (
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
+	"github.com/pkg/errors"
 	"golang.org/x/net/context"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
 	infrav1 "sigs.k8s.io/cluster-api-provider-aws/api/v1alpha3"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/scope"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/log"
@@ -55,5 +58,34 @@ var _ = Describe("AWSClusterReconciler", func() {
 			Expect(err).To(BeNil())
 			Expect(result.RequeueAfter).To(BeZero())
 		})
+
+		It("should report a role that can't be assumed without failing the cluster", func() {
+			ctx := context.Background()
+
+			recorder := record.NewFakeRecorder(1)
+			reconciler := &AWSClusterReconciler{
+				Client:   k8sClient,
+				Log:      log.Log,
+				Recorder: recorder,
+			}
+
+			instance := &infrav1.AWSCluster{ObjectMeta: metav1.ObjectMeta{Name: "assume-role", Namespace: "default"}}
+			Expect(k8sClient.Create(ctx, instance)).To(Succeed())
+
+			result, err := reconciler.reconcileAssumeRoleFailure(ctx, instance, &scope.AssumeRoleError{
+				RoleARN: "arn:aws:iam::123456789012:role/cluster-api",
+				Err:     errors.New("AccessDenied"),
+			})
+			Expect(err).To(BeNil())
+			Expect(result.RequeueAfter).To(Equal(assumeRoleRetryInterval))
+			Expect(recorder.Events).To(Receive(ContainSubstring("FailedAssumeRole")))
+
+			got := &infrav1.AWSCluster{}
+			Expect(k8sClient.Get(ctx, client.ObjectKey{Namespace: "default", Name: "assume-role"}, got)).To(Succeed())
+			Expect(got.Status.CredentialsFailureReason).To(Equal(scope.AssumeRoleFailedReason))
+			Expect(got.Status.CredentialsFailureMessage).To(ContainSubstring("AccessDenied"))
+			Expect(got.Status.FailureReason).To(BeNil())
+			Expect(got.Status.FailureMessage).To(BeNil())
+		})
 	})
 })
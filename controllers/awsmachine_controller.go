@@ -19,16 +19,21 @@ package controllers
 import (
 	"context"
 	"fmt"
+	"strings"
+	"time"
 
 	"github.com/go-logr/logr"
 	"github.com/pkg/errors"
 	corev1 "k8s.io/api/core/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/client-go/tools/record"
 	"k8s.io/utils/pointer"
 	infrav1 "sigs.k8s.io/cluster-api-provider-aws/api/v1alpha3"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/awserrors"
 	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/scope"
 	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/services"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/services/cloudwatch"
 	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/services/ec2"
 	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/services/elb"
 	clusterv1 "sigs.k8s.io/cluster-api/api/v1alpha3"
@@ -43,12 +48,62 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/source"
 )
 
+const (
+	// minInsufficientCapacityRequeue and maxInsufficientCapacityRequeue bound
+	// how long to wait before retrying an instance launch that failed because
+	// AWS did not have enough capacity.
+	minInsufficientCapacityRequeue = 30 * time.Second
+	maxInsufficientCapacityRequeue = 10 * time.Minute
+
+	// pendingInstanceProfileAssociationRequeue and failedInstanceProfileAssociationRequeue
+	// are how long to wait before checking again on an IAM instance profile
+	// association that is not complete.
+	pendingInstanceProfileAssociationRequeue = 15 * time.Second
+	failedInstanceProfileAssociationRequeue  = 5 * time.Minute
+
+	// amiResolutionRetryInterval is how long to wait before trying again to
+	// resolve the AMI of a machine from an SSM parameter.
+	amiResolutionRetryInterval = time.Minute
+
+	// missingInstanceProfileRetryInterval is how long to wait before checking
+	// again on an IAM instance profile that does not exist yet.
+	missingInstanceProfileRetryInterval = time.Minute
+)
+
 // AWSMachineReconciler reconciles a AwsMachine object
 type AWSMachineReconciler struct {
 	client.Client
-	Log            logr.Logger
-	Recorder       record.EventRecorder
+	Log      logr.Logger
+	Recorder record.EventRecorder
+
+	// InsufficientCapacityMaxAge is how old an AWSMachine can get while its
+	// instance launch keeps failing for lack of capacity before it is marked
+	// as failed. Zero means keep retrying until capacity becomes available.
+	InsufficientCapacityMaxAge time.Duration
+
+	// InstanceProfileAssociationTimeout is how old an AWSMachine with a
+	// running instance can get while its IAM instance profile association is
+	// pending before the association is reported as failed. Zero means the
+	// association is never reported as failed while it is pending.
+	InstanceProfileAssociationTimeout time.Duration
+
+	// ClusterConcurrency is the maximum number of AWSMachines of a single
+	// cluster reconciled at the same time, so that a cluster with many
+	// machines cannot take all the workers. Zero means no limit.
+	ClusterConcurrency int
+
+	// InstanceReadyTimeout is how long to wait for a new instance to be
+	// running before checking on it again in a later reconcile. Zero means
+	// ec2.DefaultInstanceReadyTimeout.
+	InstanceReadyTimeout time.Duration
+
+	// InstanceReadyPollInterval is how often the state of a new instance is
+	// checked while waiting for it to be running. Zero means
+	// ec2.DefaultInstanceReadyPollInterval.
+	InstanceReadyPollInterval time.Duration
+
 	serviceFactory func(*scope.ClusterScope) services.EC2MachineInterface
+	clusterLimiter *clusterLimiter
 }
 
 func (r *AWSMachineReconciler) getEC2Service(scope *scope.ClusterScope) services.EC2MachineInterface {
@@ -56,7 +111,14 @@ func (r *AWSMachineReconciler) getEC2Service(scope *scope.ClusterScope) services
 		return r.serviceFactory(scope)
 	}
 
-	return ec2.NewService(scope)
+	svc := ec2.NewService(scope)
+	if r.InstanceReadyTimeout > 0 {
+		svc.InstanceReadyTimeout = r.InstanceReadyTimeout
+	}
+	if r.InstanceReadyPollInterval > 0 {
+		svc.InstanceReadyPollInterval = r.InstanceReadyPollInterval
+	}
+	return svc
 }
 
 // +kubebuilder:rbac:groups=infrastructure.cluster.x-k8s.io,resources=awsmachines,verbs=get;list;watch;create;update;patch;delete
@@ -100,6 +162,15 @@ func (r *AWSMachineReconciler) Reconcile(req ctrl.Request) (_ ctrl.Result, reter
 
 	logger = logger.WithValues("cluster", cluster.Name)
 
+	if r.clusterLimiter != nil {
+		clusterKey := types.NamespacedName{Namespace: cluster.Namespace, Name: cluster.Name}.String()
+		if !r.clusterLimiter.TryAcquire(clusterKey, req.String()) {
+			logger.V(4).Info("Too many AWSMachines of the cluster are being reconciled, requeuing")
+			return reconcile.Result{RequeueAfter: clusterLimiterRetryAfter}, nil
+		}
+		defer r.clusterLimiter.Release(clusterKey)
+	}
+
 	awsCluster := &infrav1.AWSCluster{}
 
 	awsClusterName := client.ObjectKey{
@@ -120,6 +191,16 @@ func (r *AWSMachineReconciler) Reconcile(req ctrl.Request) (_ ctrl.Result, reter
 		Cluster:    cluster,
 		AWSCluster: awsCluster,
 	})
+	if _, ok := err.(*scope.AssumeRoleError); ok {
+		// The AWSCluster reports the failure, wait for the role to be assumable.
+		logger.Info("Role of the AWSCluster can't be assumed", "error", err.Error())
+		return reconcile.Result{RequeueAfter: assumeRoleRetryInterval}, nil
+	}
+	if _, ok := err.(*scope.IdentityError); ok {
+		// The AWSCluster reports the failure, wait for the identity to be usable.
+		logger.Info("Identity of the AWSCluster can't be used", "error", err.Error())
+		return reconcile.Result{RequeueAfter: assumeRoleRetryInterval}, nil
+	}
 	if err != nil {
 		return reconcile.Result{}, err
 	}
@@ -154,6 +235,10 @@ func (r *AWSMachineReconciler) Reconcile(req ctrl.Request) (_ ctrl.Result, reter
 }
 
 func (r *AWSMachineReconciler) SetupWithManager(mgr ctrl.Manager, options controller.Options) error {
+	if r.ClusterConcurrency > 0 {
+		r.clusterLimiter = newClusterLimiter("awsmachine", r.ClusterConcurrency)
+	}
+
 	return ctrl.NewControllerManagedBy(mgr).
 		WithOptions(options).
 		For(&infrav1.AWSMachine{}).
@@ -180,6 +265,11 @@ func (r *AWSMachineReconciler) reconcileDelete(machineScope *scope.MachineScope,
 		return reconcile.Result{}, err
 	}
 
+	// The bootstrap data is stored even when the instance fails to launch.
+	if err := r.deleteBootstrapData(machineScope, ec2Service); err != nil {
+		return reconcile.Result{}, err
+	}
+
 	if instance == nil {
 		// The machine was never created or was deleted by some other entity
 		// One way to reach this state:
@@ -196,6 +286,18 @@ func (r *AWSMachineReconciler) reconcileDelete(machineScope *scope.MachineScope,
 
 	machineScope.V(3).Info("EC2 instance found matching deleted AWSMachine", "instance-id", instance.ID)
 
+	if err := r.reconcileLBDetachment(machineScope, clusterScope, instance); err != nil {
+		return reconcile.Result{}, err
+	}
+
+	// A persistent spot request would launch the instance again once terminated.
+	if machineScope.AWSMachine.Spec.SpotMarketOptions.IsPersistent() {
+		if err := ec2Service.CancelSpotInstanceRequests(instance.ID); err != nil {
+			r.Recorder.Eventf(machineScope.AWSMachine, corev1.EventTypeWarning, "FailedCancelSpotRequest", "Failed to cancel spot requests of instance %q: %v", instance.ID, err)
+			return reconcile.Result{}, errors.Wrap(err, "failed to cancel spot requests")
+		}
+	}
+
 	// Check the instance state. If it's already shutting down or terminated,
 	// do nothing. Otherwise attempt to delete it.
 	// This decision is based on the ec2-instance-lifecycle graph at
@@ -204,6 +306,14 @@ func (r *AWSMachineReconciler) reconcileDelete(machineScope *scope.MachineScope,
 	case infrav1.InstanceStateShuttingDown, infrav1.InstanceStateTerminated:
 		machineScope.Info("EC2 instance is shutting down or already terminated", "instance-id", instance.ID)
 	default:
+		// Termination protection would make the instance refuse to terminate.
+		if machineScope.AWSMachine.Spec.DisableAPITermination {
+			if err := ec2Service.SetInstanceTerminationProtection(instance.ID, false); err != nil {
+				r.Recorder.Eventf(machineScope.AWSMachine, corev1.EventTypeWarning, "FailedDisableTerminationProtection", "Failed to disable termination protection of instance %q: %v", instance.ID, err)
+				return reconcile.Result{}, errors.Wrap(err, "failed to disable termination protection")
+			}
+		}
+
 		machineScope.Info("Terminating EC2 instance", "instance-id", instance.ID)
 		if err := ec2Service.TerminateInstanceAndWait(instance.ID); err != nil {
 			r.Recorder.Eventf(machineScope.AWSMachine, corev1.EventTypeWarning, "FailedTerminate", "Failed to terminate instance %q: %v", instance.ID, err)
@@ -234,6 +344,20 @@ func (r *AWSMachineReconciler) reconcileDelete(machineScope *scope.MachineScope,
 		r.Recorder.Eventf(machineScope.AWSMachine, corev1.EventTypeNormal, "SuccessfulTerminate", "Terminated instance %q", instance.ID)
 	}
 
+	// The Elastic IP allocated for the machine outlives the instance.
+	if machineScope.AWSMachine.Spec.ElasticIPPool != nil {
+		if err := ec2Service.ReleaseElasticIP(machineScope); err != nil {
+			return reconcile.Result{}, errors.Wrap(err, "failed to release Elastic IP")
+		}
+	}
+
+	// The alarm outlives the instance, so delete it whatever state the instance was found in.
+	if machineScope.AWSMachine.Spec.AutoRecovery {
+		if err := cloudwatch.NewService(clusterScope).DeleteRecoveryAlarm(instance.ID); err != nil {
+			return reconcile.Result{}, errors.Wrap(err, "failed to delete auto-recovery alarm")
+		}
+	}
+
 	// Instance is deleted so remove the finalizer.
 	machineScope.AWSMachine.Finalizers = util.Filter(machineScope.AWSMachine.Finalizers, infrav1.MachineFinalizer)
 
@@ -285,8 +409,18 @@ func (r *AWSMachineReconciler) reconcileNormal(ctx context.Context, machineScope
 		return reconcile.Result{}, nil
 	}
 
-	// Make sure bootstrap data is available and populated.
-	if machineScope.Machine.Spec.Bootstrap.DataSecretName == nil {
+	// Make sure bootstrap data is available and populated, from exactly one source.
+	bootstrap := machineScope.Machine.Spec.Bootstrap
+	if machineScope.HasRawUserData() {
+		if bootstrap.ConfigRef != nil || bootstrap.DataSecretName != nil {
+			err := errors.New("spec.userDataSecret cannot be set when the Machine has a bootstrap.configRef or bootstrap.dataSecretName")
+			machineScope.Error(err, "Invalid user data source, not retrying")
+			r.Recorder.Eventf(machineScope.AWSMachine, corev1.EventTypeWarning, "InvalidUserData", err.Error())
+			machineScope.SetFailureReason(capierrors.InvalidConfigurationMachineError)
+			machineScope.SetFailureMessage(err)
+			return reconcile.Result{}, nil
+		}
+	} else if bootstrap.DataSecretName == nil {
 		machineScope.Info("Bootstrap data secret reference is not yet available")
 		return reconcile.Result{}, nil
 	}
@@ -296,6 +430,28 @@ func (r *AWSMachineReconciler) reconcileNormal(ctx context.Context, machineScope
 	// Get or create the instance.
 	instance, err := r.getOrCreate(machineScope, ec2svc)
 	if err != nil {
+		cause := errors.Cause(err)
+		switch {
+		case awserrors.IsInsufficientCapacity(cause):
+			return r.handleInsufficientCapacity(machineScope, err), nil
+		case awserrors.IsInvalidInstanceType(cause):
+			machineScope.Error(err, "Invalid instance type, not retrying", "instance-type", machineScope.AWSMachine.Spec.InstanceType)
+			r.Recorder.Eventf(machineScope.AWSMachine, corev1.EventTypeWarning, "InvalidInstanceType", "Instance type %q cannot be launched: %v", machineScope.AWSMachine.Spec.InstanceType, err)
+			machineScope.SetFailureReason(capierrors.InvalidConfigurationMachineError)
+			machineScope.SetFailureMessage(errors.Wrapf(err, "instance type %q is not valid", machineScope.AWSMachine.Spec.InstanceType))
+			return reconcile.Result{}, nil
+		}
+		if amiErr, ok := cause.(*ec2.AMIResolutionError); ok {
+			machineScope.Error(err, "Failed to resolve AMI, will retry", "parameter", amiErr.Parameter)
+			r.Recorder.Eventf(machineScope.AWSMachine, corev1.EventTypeWarning, "FailedResolveAMI", "Failed to resolve AMI from SSM parameter %q, retrying in %s: %v", amiErr.Parameter, amiResolutionRetryInterval, amiErr.Err)
+			return reconcile.Result{RequeueAfter: amiResolutionRetryInterval}, nil
+		}
+		if profileErr, ok := cause.(*ec2.InstanceProfileNotFoundError); ok {
+			machineScope.Error(err, "IAM instance profile is not usable, will retry", "instance-profile", profileErr.Name)
+			r.Recorder.Eventf(machineScope.AWSMachine, corev1.EventTypeWarning, "InstanceProfileNotFound", "Cannot launch instance, retrying in %s: %v", missingInstanceProfileRetryInterval, profileErr)
+			machineScope.SetInstanceProfileAssociationState(infrav1.InstanceProfileAssociationStateNotFound)
+			return reconcile.Result{RequeueAfter: missingInstanceProfileRetryInterval}, nil
+		}
 		return reconcile.Result{}, err
 	}
 
@@ -314,6 +470,7 @@ func (r *AWSMachineReconciler) reconcileNormal(ctx context.Context, machineScope
 
 	existingInstanceState := machineScope.GetInstanceState()
 	machineScope.SetInstanceState(instance.State)
+	machineScope.SetInstanceType(instance.Type)
 
 	// Proceed to reconcile the AWSMachine state.
 	if existingInstanceState == nil || *existingInstanceState != instance.State {
@@ -323,14 +480,34 @@ func (r *AWSMachineReconciler) reconcileNormal(ctx context.Context, machineScope
 	machineScope.SetAddresses(instance.Addresses)
 
 	switch instance.State {
-	case infrav1.InstanceStatePending, infrav1.InstanceStateStopping, infrav1.InstanceStateStopped:
+	case infrav1.InstanceStatePending:
+		machineScope.SetNotReady()
+		r.Recorder.Eventf(machineScope.AWSMachine, corev1.EventTypeNormal, "InstancePending", "EC2 instance %q is %s", instance.ID, instance.State)
+	case infrav1.InstanceStateStopping, infrav1.InstanceStateStopped:
 		machineScope.SetNotReady()
+		if machineScope.AWSMachine.Spec.SpotMarketOptions.IsPersistent() {
+			// EC2 starts the instance again once spot capacity is available.
+			r.Recorder.Eventf(machineScope.AWSMachine, corev1.EventTypeWarning, "SpotInstanceInterrupted", "EC2 spot instance %q was interrupted and is %s until capacity is available", instance.ID, instance.State)
+		}
 	case infrav1.InstanceStateRunning:
 		machineScope.SetReady()
 	case infrav1.InstanceStateShuttingDown, infrav1.InstanceStateTerminated:
 		machineScope.SetNotReady()
+		if instance.SpotMarketOptions != nil {
+			// Spot instances can be reclaimed by EC2 at any time, once that happens the
+			// instance is gone for good and the Machine needs to be replaced.
+			machineScope.Info("EC2 spot instance was interrupted", "state", instance.State, "instance-id", *machineScope.GetInstanceID())
+			r.Recorder.Eventf(machineScope.AWSMachine, corev1.EventTypeWarning, "SpotInstanceInterrupted", "EC2 spot instance was interrupted")
+			machineScope.SetFailureReason(capierrors.UpdateMachineError)
+			machineScope.SetFailureMessage(errors.Errorf("EC2 spot instance %q was interrupted", instance.ID))
+			break
+		}
 		machineScope.Info("Unexpected EC2 instance termination", "state", instance.State, "instance-id", *machineScope.GetInstanceID())
 		r.Recorder.Eventf(machineScope.AWSMachine, corev1.EventTypeWarning, "InstanceUnexpectedTermination", "Unexpected EC2 instance termination")
+		if instance.State == infrav1.InstanceStateTerminated {
+			machineScope.SetFailureReason(capierrors.UpdateMachineError)
+			machineScope.SetFailureMessage(errors.Errorf("EC2 instance state %q is unexpected", instance.State))
+		}
 	default:
 		machineScope.SetNotReady()
 		machineScope.Info("EC2 instance state is undefined", "state", instance.State, "instance-id", *machineScope.GetInstanceID())
@@ -339,15 +516,60 @@ func (r *AWSMachineReconciler) reconcileNormal(ctx context.Context, machineScope
 		machineScope.SetFailureMessage(errors.Errorf("EC2 instance state %q is undefined", instance.State))
 	}
 
-	if instance.State == infrav1.InstanceStateTerminated {
-		machineScope.SetFailureReason(capierrors.UpdateMachineError)
-		machineScope.SetFailureMessage(errors.Errorf("EC2 instance state %q is unexpected", instance.State))
+	result := reconcile.Result{}
+	if instance.State == infrav1.InstanceStateRunning && machineScope.IAMInstanceProfile() != "" {
+		result, err = r.reconcileInstanceProfileAssociation(machineScope, ec2svc)
+		if err != nil {
+			return reconcile.Result{}, err
+		}
 	}
 
 	if err := r.reconcileLBAttachment(machineScope, clusterScope, instance); err != nil {
 		return reconcile.Result{}, errors.Errorf("failed to reconcile LB attachment: %+v", err)
 	}
 
+	// The node no longer needs its bootstrap data once it has joined the cluster.
+	if machineScope.Machine.Status.NodeRef != nil {
+		if err := r.deleteBootstrapData(machineScope, ec2svc); err != nil {
+			return reconcile.Result{}, err
+		}
+	}
+
+	if instance.State == infrav1.InstanceStateRunning && machineScope.AWSMachine.Spec.RootDeviceSize > instance.RootDeviceSize {
+		resizeResult, err := r.reconcileRootVolumeSize(machineScope, ec2svc, instance)
+		if err != nil {
+			return reconcile.Result{}, err
+		}
+		if result.RequeueAfter == 0 || (resizeResult.RequeueAfter > 0 && resizeResult.RequeueAfter < result.RequeueAfter) {
+			result = resizeResult
+		}
+	}
+
+	if instance.State == infrav1.InstanceStateRunning && instanceMetadataOptionsDrifted(machineScope.AWSMachine.Spec.InstanceMetadataOptions, instance.InstanceMetadataOptions) {
+		if err := r.reconcileInstanceMetadataOptions(machineScope, ec2svc, instance); err != nil {
+			return reconcile.Result{}, err
+		}
+	}
+
+	if instance.State == infrav1.InstanceStateRunning && machineScope.AWSMachine.Spec.DisableAPITermination {
+		if err := r.reconcileTerminationProtection(machineScope, ec2svc, instance); err != nil {
+			return reconcile.Result{}, err
+		}
+	}
+
+	if instance.State == infrav1.InstanceStateRunning && machineScope.AWSMachine.Spec.ElasticIPPool != nil {
+		if err := ec2svc.ReconcileElasticIP(machineScope, instance); err != nil {
+			return reconcile.Result{}, errors.Wrap(err, "failed to reconcile Elastic IP")
+		}
+	}
+
+	if instance.State == infrav1.InstanceStateRunning && machineScope.AWSMachine.Spec.AutoRecovery {
+		if err := cloudwatch.NewService(clusterScope).ReconcileRecoveryAlarm(instance.ID); err != nil {
+			r.Recorder.Eventf(machineScope.AWSMachine, corev1.EventTypeWarning, "FailedReconcileRecoveryAlarm", "Failed to reconcile auto-recovery alarm for instance %q: %v", instance.ID, err)
+			return reconcile.Result{}, errors.Wrap(err, "failed to reconcile auto-recovery alarm")
+		}
+	}
+
 	// TODO(vincepri): Remove this annotation when clusterctl is no longer relevant.
 	machineScope.SetAnnotation("cluster-api-provider-aws", "true")
 
@@ -356,8 +578,17 @@ func (r *AWSMachineReconciler) reconcileNormal(ctx context.Context, machineScope
 		return reconcile.Result{}, err
 	}
 
+	var additionalSecurityGroups []string
+	if len(machineScope.AWSMachine.Spec.AdditionalSecurityGroups) > 0 {
+		additionalSecurityGroups, err = ec2svc.GetAdditionalSecurityGroupsIDs(machineScope)
+		if err != nil {
+			r.Recorder.Eventf(machineScope.AWSMachine, corev1.EventTypeWarning, "FailedResolveSecurityGroups", "Failed to resolve additional security groups: %v", err)
+			return reconcile.Result{}, errors.Errorf("failed to resolve additional security groups: %+v", err)
+		}
+	}
+
 	// Ensure that the security groups are correct.
-	_, err = r.ensureSecurityGroups(ec2svc, machineScope, machineScope.AWSMachine.Spec.AdditionalSecurityGroups, existingSecurityGroups)
+	_, err = r.ensureSecurityGroups(ec2svc, machineScope, additionalSecurityGroups, existingSecurityGroups)
 	if err != nil {
 		return reconcile.Result{}, errors.Errorf("failed to apply security groups: %+v", err)
 	}
@@ -368,9 +599,172 @@ func (r *AWSMachineReconciler) reconcileNormal(ctx context.Context, machineScope
 		return reconcile.Result{}, errors.Errorf("failed to ensure tags: %+v", err)
 	}
 
+	return result, nil
+}
+
+// reconcileInstanceProfileAssociation records the state of the association
+// between the instance and its IAM instance profile. The AWSMachine is not
+// ready until the association completes, and a pending association older than
+// InstanceProfileAssociationTimeout is reported as failed.
+func (r *AWSMachineReconciler) reconcileInstanceProfileAssociation(machineScope *scope.MachineScope, ec2svc services.EC2MachineInterface) (reconcile.Result, error) {
+	instanceID := *machineScope.GetInstanceID()
+	profile := machineScope.IAMInstanceProfile()
+
+	state, err := ec2svc.GetInstanceProfileAssociationState(instanceID)
+	if err != nil {
+		return reconcile.Result{}, errors.Wrapf(err, "failed to get IAM instance profile association state for instance %q", instanceID)
+	}
+
+	age := time.Since(machineScope.AWSMachine.CreationTimestamp.Time)
+	if state == infrav1.InstanceProfileAssociationStatePending && r.InstanceProfileAssociationTimeout > 0 && age > r.InstanceProfileAssociationTimeout {
+		state = infrav1.InstanceProfileAssociationStateFailed
+	}
+
+	existingState := machineScope.AWSMachine.Status.InstanceProfileAssociation
+	machineScope.SetInstanceProfileAssociationState(state)
+
+	switch state {
+	case infrav1.InstanceProfileAssociationStateAssociated:
+		return reconcile.Result{}, nil
+	case infrav1.InstanceProfileAssociationStatePending:
+		machineScope.SetNotReady()
+		machineScope.Info("Waiting for IAM instance profile association", "instance-profile", profile, "instance-id", instanceID)
+		return reconcile.Result{RequeueAfter: pendingInstanceProfileAssociationRequeue}, nil
+	default:
+		machineScope.SetNotReady()
+		machineScope.Info("IAM instance profile association failed", "instance-profile", profile, "instance-id", instanceID)
+		if existingState == nil || *existingState != state {
+			r.Recorder.Eventf(machineScope.AWSMachine, corev1.EventTypeWarning, "InstanceProfileAssociationFailed",
+				"IAM instance profile %q could not be associated with instance %q", profile, instanceID)
+		}
+		return reconcile.Result{RequeueAfter: failedInstanceProfileAssociationRequeue}, nil
+	}
+}
+
+// reconcileRootVolumeSize grows the root volume of the instance to the size in
+// the spec, so that nodes don't need to be replaced to get a bigger disk. EBS
+// only allows a volume to be modified every six hours, so the resize may have
+// to wait for a previous modification.
+func (r *AWSMachineReconciler) reconcileRootVolumeSize(machineScope *scope.MachineScope, ec2svc services.EC2MachineInterface, instance *infrav1.Instance) (reconcile.Result, error) {
+	size := machineScope.AWSMachine.Spec.RootDeviceSize
+
+	wait, err := ec2svc.GrowRootVolume(instance.ID, size)
+	if err != nil {
+		r.Recorder.Eventf(machineScope.AWSMachine, corev1.EventTypeWarning, "FailedResizeRootVolume", "Failed to resize root volume of instance %q to %dGB: %v", instance.ID, size, err)
+		return reconcile.Result{}, errors.Wrap(err, "failed to resize root volume")
+	}
+
+	if wait > 0 {
+		machineScope.Info("Waiting for the EBS modification window to resize the root volume", "instance-id", instance.ID, "requeue-after", wait.String())
+		return reconcile.Result{RequeueAfter: wait}, nil
+	}
+
+	r.Recorder.Eventf(machineScope.AWSMachine, corev1.EventTypeNormal, "RootVolumeResized", "Resized root volume of instance %q from %dGB to %dGB", instance.ID, instance.RootDeviceSize, size)
 	return reconcile.Result{}, nil
 }
 
+// reconcileInstanceMetadataOptions brings the metadata options of the instance
+// back in line with the spec, e.g. after they were changed in the console.
+func (r *AWSMachineReconciler) reconcileInstanceMetadataOptions(machineScope *scope.MachineScope, ec2svc services.EC2MachineInterface, instance *infrav1.Instance) error {
+	desired := machineScope.AWSMachine.Spec.InstanceMetadataOptions
+
+	if err := ec2svc.ModifyInstanceMetadataOptions(instance.ID, desired); err != nil {
+		r.Recorder.Eventf(machineScope.AWSMachine, corev1.EventTypeWarning, "FailedModifyInstanceMetadataOptions", "Failed to set the metadata options of instance %q to %s: %v", instance.ID, formatInstanceMetadataOptions(desired), err)
+		return errors.Wrap(err, "failed to modify instance metadata options")
+	}
+
+	r.Recorder.Eventf(machineScope.AWSMachine, corev1.EventTypeNormal, "InstanceMetadataOptionsModified", "Changed the metadata options of instance %q from %s to %s", instance.ID, formatInstanceMetadataOptions(instance.InstanceMetadataOptions), formatInstanceMetadataOptions(desired))
+	return nil
+}
+
+// instanceMetadataOptionsDrifted returns whether the observed metadata options
+// differ from the desired ones. Unset options are left as they are.
+func instanceMetadataOptionsDrifted(desired, observed *infrav1.InstanceMetadataOptions) bool {
+	// Instances that don't report their metadata options can't be compared.
+	if desired == nil || observed == nil {
+		return false
+	}
+	if desired.HTTPPutResponseHopLimit != 0 && desired.HTTPPutResponseHopLimit != observed.HTTPPutResponseHopLimit {
+		return true
+	}
+	if desired.HTTPTokens != "" && desired.HTTPTokens != observed.HTTPTokens {
+		return true
+	}
+	return desired.HTTPEndpoint != "" && desired.HTTPEndpoint != observed.HTTPEndpoint
+}
+
+// formatInstanceMetadataOptions describes the set metadata options for events.
+func formatInstanceMetadataOptions(options *infrav1.InstanceMetadataOptions) string {
+	if options == nil {
+		return "unknown"
+	}
+	var set []string
+	if options.HTTPTokens != "" {
+		set = append(set, fmt.Sprintf("httpTokens=%s", options.HTTPTokens))
+	}
+	if options.HTTPEndpoint != "" {
+		set = append(set, fmt.Sprintf("httpEndpoint=%s", options.HTTPEndpoint))
+	}
+	if options.HTTPPutResponseHopLimit != 0 {
+		set = append(set, fmt.Sprintf("httpPutResponseHopLimit=%d", options.HTTPPutResponseHopLimit))
+	}
+	return "[" + strings.Join(set, ", ") + "]"
+}
+
+// reconcileTerminationProtection enables termination protection again when it
+// was disabled outside of Kubernetes, e.g. in the console.
+func (r *AWSMachineReconciler) reconcileTerminationProtection(machineScope *scope.MachineScope, ec2svc services.EC2MachineInterface, instance *infrav1.Instance) error {
+	enabled, err := ec2svc.GetInstanceTerminationProtection(instance.ID)
+	if err != nil {
+		return errors.Wrap(err, "failed to get instance termination protection")
+	}
+	if enabled {
+		return nil
+	}
+
+	if err := ec2svc.SetInstanceTerminationProtection(instance.ID, true); err != nil {
+		r.Recorder.Eventf(machineScope.AWSMachine, corev1.EventTypeWarning, "FailedEnableTerminationProtection", "Failed to enable termination protection of instance %q: %v", instance.ID, err)
+		return errors.Wrap(err, "failed to enable instance termination protection")
+	}
+
+	r.Recorder.Eventf(machineScope.AWSMachine, corev1.EventTypeNormal, "TerminationProtectionEnabled", "Enabled termination protection of instance %q", instance.ID)
+	return nil
+}
+
+// handleInsufficientCapacity requeues an AWSMachine whose instance could not be
+// launched because AWS was out of capacity, so that it heals by itself once
+// capacity returns. The wait grows with the age of the AWSMachine. Once the
+// AWSMachine is older than InsufficientCapacityMaxAge it is marked as failed.
+func (r *AWSMachineReconciler) handleInsufficientCapacity(machineScope *scope.MachineScope, err error) reconcile.Result {
+	age := time.Since(machineScope.AWSMachine.CreationTimestamp.Time)
+
+	if r.InsufficientCapacityMaxAge > 0 && age > r.InsufficientCapacityMaxAge {
+		machineScope.Error(err, "Insufficient capacity, giving up", "age", age.String())
+		r.Recorder.Eventf(machineScope.AWSMachine, corev1.EventTypeWarning, "FailedCreate", "Giving up on creating instance after %s of insufficient capacity: %v", age.Round(time.Second), err)
+		machineScope.SetFailureReason(capierrors.CreateMachineError)
+		machineScope.SetFailureMessage(errors.Wrapf(err, "insufficient capacity for more than %s", r.InsufficientCapacityMaxAge))
+		return reconcile.Result{}
+	}
+
+	requeueAfter := insufficientCapacityRequeueAfter(age)
+	machineScope.Info("Insufficient capacity to create EC2 instance, will retry", "requeue-after", requeueAfter.String())
+	r.Recorder.Eventf(machineScope.AWSMachine, corev1.EventTypeWarning, "InsufficientCapacity", "Insufficient capacity to create instance, retrying in %s: %v", requeueAfter, err)
+	return reconcile.Result{RequeueAfter: requeueAfter}
+}
+
+// insufficientCapacityRequeueAfter returns half the given age, bounded by
+// minInsufficientCapacityRequeue and maxInsufficientCapacityRequeue.
+func insufficientCapacityRequeueAfter(age time.Duration) time.Duration {
+	requeueAfter := age / 2
+	if requeueAfter < minInsufficientCapacityRequeue {
+		return minInsufficientCapacityRequeue
+	}
+	if requeueAfter > maxInsufficientCapacityRequeue {
+		return maxInsufficientCapacityRequeue
+	}
+	return requeueAfter
+}
+
 func (r *AWSMachineReconciler) getOrCreate(scope *scope.MachineScope, ec2svc services.EC2MachineInterface) (*infrav1.Instance, error) {
 	instance, err := r.findInstance(scope, ec2svc)
 	if err != nil {
@@ -403,6 +797,37 @@ func (r *AWSMachineReconciler) reconcileLBAttachment(machineScope *scope.Machine
 	return nil
 }
 
+// deleteBootstrapData deletes the bootstrap data of the machine from the
+// backend it is stored in, if any.
+func (r *AWSMachineReconciler) deleteBootstrapData(machineScope *scope.MachineScope, ec2svc services.EC2MachineInterface) error {
+	backend := machineScope.SecureSecretsBackend()
+	if backend == "" {
+		return nil
+	}
+
+	if err := ec2svc.DeleteBootstrapData(machineScope); err != nil {
+		r.Recorder.Eventf(machineScope.AWSMachine, corev1.EventTypeWarning, "FailedDeleteBootstrapData", "Failed to delete bootstrap data from %s: %v", backend, err)
+		return errors.Wrap(err, "failed to delete bootstrap data")
+	}
+	return nil
+}
+
+// reconcileLBDetachment deregisters a control plane instance from an existing
+// API server load balancer, which outlives the instance.
+func (r *AWSMachineReconciler) reconcileLBDetachment(machineScope *scope.MachineScope, clusterScope *scope.ClusterScope, i *infrav1.Instance) error {
+	if !machineScope.IsControlPlane() || clusterScope.ControlPlaneLoadBalancerARN() == "" {
+		return nil
+	}
+
+	elbsvc := elb.NewService(clusterScope)
+	if err := elbsvc.DeregisterInstanceFromAPIServerELB(i); err != nil {
+		r.Recorder.Eventf(machineScope.AWSMachine, corev1.EventTypeWarning, "FailedDetachControlPlaneELB",
+			"Failed to deregister control plane instance %q from load balancer: %v", i.ID, err)
+		return errors.Wrapf(err, "could not deregister control plane instance %q from load balancer", i.ID)
+	}
+	return nil
+}
+
 // AWSClusterToAWSMachine is a handler.ToRequestsFunc to be used to enqeue requests for reconciliation
 // of AWSMachines.
 func (r *AWSMachineReconciler) AWSClusterToAWSMachines(o handler.MapObject) []ctrl.Request {
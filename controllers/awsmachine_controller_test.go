@@ -21,7 +21,9 @@ import (
 	"context"
 	"flag"
 	"fmt"
+	"time"
 
+	"github.com/aws/aws-sdk-go/aws/awserr"
 	"github.com/golang/mock/gomock"
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
@@ -39,6 +41,7 @@ import (
 	infrav1 "sigs.k8s.io/cluster-api-provider-aws/api/v1alpha3"
 	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/scope" //nolint
 	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/services"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/services/ec2"
 	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/services/mock_services" //nolint
 )
 
@@ -189,6 +192,66 @@ var _ = Describe("AWSMachineReconciler", func() {
 			})
 		})
 
+		When("instance creation fails", func() {
+			BeforeEach(func() {
+				ms.AWSMachine.Spec.InstanceType = "m5.large"
+				ms.AWSMachine.CreationTimestamp = metav1.NewTime(time.Now().Add(-time.Hour))
+				ec2Svc.EXPECT().GetRunningInstanceByTags(gomock.Any()).Return(nil, nil)
+			})
+
+			It("should requeue without failing the machine when capacity is insufficient", func() {
+				ec2Svc.EXPECT().CreateInstance(gomock.Any()).Return(nil, awserr.New("InsufficientInstanceCapacity", "no capacity", nil))
+
+				result, err := reconciler.reconcileNormal(context.Background(), ms, cs)
+				Expect(err).To(BeNil())
+				Expect(result.RequeueAfter).To(Equal(10 * time.Minute))
+				Expect(ms.AWSMachine.Status.FailureReason).To(BeNil())
+				Expect(ms.AWSMachine.Status.FailureMessage).To(BeNil())
+				Expect(recorder.Events).To(Receive(ContainSubstring("InsufficientCapacity")))
+			})
+
+			It("should fail the machine when capacity is insufficient for longer than the max age", func() {
+				reconciler.InsufficientCapacityMaxAge = 30 * time.Minute
+				ec2Svc.EXPECT().CreateInstance(gomock.Any()).Return(nil, awserr.New("InsufficientInstanceCapacity", "no capacity", nil))
+
+				result, err := reconciler.reconcileNormal(context.Background(), ms, cs)
+				Expect(err).To(BeNil())
+				Expect(result.RequeueAfter).To(BeZero())
+				Expect(ms.AWSMachine.Status.FailureReason).To(PointTo(Equal(capierrors.CreateMachineError)))
+			})
+
+			It("should fail the machine when the instance type is invalid", func() {
+				ec2Svc.EXPECT().CreateInstance(gomock.Any()).Return(nil, awserr.New("InvalidParameterValue", "Invalid value 'm5.large' for InstanceType.", nil))
+
+				result, err := reconciler.reconcileNormal(context.Background(), ms, cs)
+				Expect(err).To(BeNil())
+				Expect(result.RequeueAfter).To(BeZero())
+				Expect(ms.AWSMachine.Status.FailureReason).To(PointTo(Equal(capierrors.InvalidConfigurationMachineError)))
+				Expect(recorder.Events).To(Receive(ContainSubstring("InvalidInstanceType")))
+			})
+
+			It("should requeue without failing the machine when the AMI can't be resolved", func() {
+				ec2Svc.EXPECT().CreateInstance(gomock.Any()).Return(nil, &ec2.AMIResolutionError{Parameter: "/my-org/ami", Err: errors.New("parameter not found")})
+
+				result, err := reconciler.reconcileNormal(context.Background(), ms, cs)
+				Expect(err).To(BeNil())
+				Expect(result.RequeueAfter).To(Equal(amiResolutionRetryInterval))
+				Expect(ms.AWSMachine.Status.FailureReason).To(BeNil())
+				Expect(recorder.Events).To(Receive(ContainSubstring("FailedResolveAMI")))
+			})
+
+			It("should requeue and report the instance profile when it does not exist", func() {
+				ec2Svc.EXPECT().CreateInstance(gomock.Any()).Return(nil, &ec2.InstanceProfileNotFoundError{Name: "my-profile", Reason: "not found"})
+
+				result, err := reconciler.reconcileNormal(context.Background(), ms, cs)
+				Expect(err).To(BeNil())
+				Expect(result.RequeueAfter).To(Equal(missingInstanceProfileRetryInterval))
+				Expect(ms.AWSMachine.Status.FailureReason).To(BeNil())
+				Expect(ms.AWSMachine.Status.InstanceProfileAssociation).To(PointTo(Equal(infrav1.InstanceProfileAssociationStateNotFound)))
+				Expect(recorder.Events).To(Receive(ContainSubstring("InstanceProfileNotFound")))
+			})
+		})
+
 		When("instance creation succeeds", func() {
 			var instance *infrav1.Instance
 			BeforeEach(func() {
@@ -225,6 +288,7 @@ var _ = Describe("AWSMachineReconciler", func() {
 						Expect(ms.AWSMachine.Status.InstanceState).To(PointTo(Equal(infrav1.InstanceStatePending)))
 						Expect(ms.AWSMachine.Status.Ready).To(Equal(false))
 						Expect(buf.String()).To(ContainSubstring(("EC2 instance state changed")))
+						Expect(recorder.Events).To(Receive(ContainSubstring("InstancePending")))
 					})
 
 					It("should set instance to running", func() {
@@ -251,6 +315,115 @@ var _ = Describe("AWSMachineReconciler", func() {
 				})
 			})
 
+			Context("instance profile association", func() {
+				BeforeEach(func() {
+					instance.State = infrav1.InstanceStateRunning
+					ms.AWSMachine.Spec.IAMInstanceProfile = "nodes.cluster-api-provider-aws.sigs.k8s.io"
+					ms.AWSMachine.CreationTimestamp = metav1.NewTime(time.Now().Add(-time.Hour))
+					ec2Svc.EXPECT().GetInstanceSecurityGroups(gomock.Any()).Return(nil, errors.New("stop here"))
+				})
+
+				It("should be ready when the instance profile is associated", func() {
+					ec2Svc.EXPECT().GetInstanceProfileAssociationState("myMachine").Return(infrav1.InstanceProfileAssociationStateAssociated, nil)
+					_, _ = reconciler.reconcileNormal(context.Background(), ms, cs)
+					Expect(ms.AWSMachine.Status.InstanceProfileAssociation).To(PointTo(Equal(infrav1.InstanceProfileAssociationStateAssociated)))
+					Expect(ms.AWSMachine.Status.Ready).To(Equal(true))
+				})
+
+				It("should not be ready when the association failed", func() {
+					ec2Svc.EXPECT().GetInstanceProfileAssociationState("myMachine").Return(infrav1.InstanceProfileAssociationStateFailed, nil)
+					_, _ = reconciler.reconcileNormal(context.Background(), ms, cs)
+					Expect(ms.AWSMachine.Status.InstanceProfileAssociation).To(PointTo(Equal(infrav1.InstanceProfileAssociationStateFailed)))
+					Expect(ms.AWSMachine.Status.Ready).To(Equal(false))
+					Expect(ms.AWSMachine.Status.FailureReason).To(BeNil())
+					Expect(recorder.Events).To(Receive(ContainSubstring("InstanceProfileAssociationFailed")))
+				})
+
+				It("should report a pending association as failed after the timeout", func() {
+					reconciler.InstanceProfileAssociationTimeout = 30 * time.Minute
+					ec2Svc.EXPECT().GetInstanceProfileAssociationState("myMachine").Return(infrav1.InstanceProfileAssociationStatePending, nil)
+					_, _ = reconciler.reconcileNormal(context.Background(), ms, cs)
+					Expect(ms.AWSMachine.Status.InstanceProfileAssociation).To(PointTo(Equal(infrav1.InstanceProfileAssociationStateFailed)))
+					Expect(ms.AWSMachine.Status.Ready).To(Equal(false))
+					Expect(recorder.Events).To(Receive(ContainSubstring("InstanceProfileAssociationFailed")))
+				})
+			})
+
+			Context("instance metadata options", func() {
+				BeforeEach(func() {
+					instance.State = infrav1.InstanceStateRunning
+					instance.InstanceMetadataOptions = &infrav1.InstanceMetadataOptions{HTTPPutResponseHopLimit: 1}
+					ms.AWSMachine.Spec.InstanceMetadataOptions = &infrav1.InstanceMetadataOptions{HTTPPutResponseHopLimit: 2}
+					ec2Svc.EXPECT().GetInstanceSecurityGroups(gomock.Any()).Return(nil, errors.New("stop here"))
+				})
+
+				It("should correct a drifted hop limit", func() {
+					ec2Svc.EXPECT().ModifyInstanceMetadataOptions("myMachine", ms.AWSMachine.Spec.InstanceMetadataOptions).Return(nil)
+					_, _ = reconciler.reconcileNormal(context.Background(), ms, cs)
+					Expect(recorder.Events).To(Receive(ContainSubstring("InstanceMetadataOptionsModified")))
+				})
+
+				It("should leave a hop limit that matches the spec alone", func() {
+					instance.InstanceMetadataOptions.HTTPPutResponseHopLimit = 2
+					ec2Svc.EXPECT().ModifyInstanceMetadataOptions(gomock.Any(), gomock.Any()).Times(0)
+					_, _ = reconciler.reconcileNormal(context.Background(), ms, cs)
+				})
+
+				It("should require session tokens again when they were made optional", func() {
+					instance.InstanceMetadataOptions = &infrav1.InstanceMetadataOptions{
+						HTTPPutResponseHopLimit: 2,
+						HTTPTokens:              infrav1.HTTPTokensStateOptional,
+						HTTPEndpoint:            infrav1.InstanceMetadataEndpointStateEnabled,
+					}
+					ms.AWSMachine.Spec.InstanceMetadataOptions.HTTPTokens = infrav1.HTTPTokensStateRequired
+					ec2Svc.EXPECT().ModifyInstanceMetadataOptions("myMachine", ms.AWSMachine.Spec.InstanceMetadataOptions).Return(nil)
+					_, _ = reconciler.reconcileNormal(context.Background(), ms, cs)
+					Expect(recorder.Events).To(Receive(ContainSubstring("httpTokens=required")))
+				})
+
+				It("should enable the metadata service again when it was disabled", func() {
+					instance.InstanceMetadataOptions = &infrav1.InstanceMetadataOptions{
+						HTTPPutResponseHopLimit: 2,
+						HTTPEndpoint:            infrav1.InstanceMetadataEndpointStateDisabled,
+					}
+					ms.AWSMachine.Spec.InstanceMetadataOptions.HTTPEndpoint = infrav1.InstanceMetadataEndpointStateEnabled
+					ec2Svc.EXPECT().ModifyInstanceMetadataOptions("myMachine", ms.AWSMachine.Spec.InstanceMetadataOptions).Return(nil)
+					_, _ = reconciler.reconcileNormal(context.Background(), ms, cs)
+					Expect(recorder.Events).To(Receive(ContainSubstring("InstanceMetadataOptionsModified")))
+				})
+
+				It("should leave unset options alone", func() {
+					instance.InstanceMetadataOptions = &infrav1.InstanceMetadataOptions{
+						HTTPPutResponseHopLimit: 2,
+						HTTPTokens:              infrav1.HTTPTokensStateOptional,
+						HTTPEndpoint:            infrav1.InstanceMetadataEndpointStateEnabled,
+					}
+					ec2Svc.EXPECT().ModifyInstanceMetadataOptions(gomock.Any(), gomock.Any()).Times(0)
+					_, _ = reconciler.reconcileNormal(context.Background(), ms, cs)
+				})
+			})
+
+			Context("termination protection", func() {
+				BeforeEach(func() {
+					instance.State = infrav1.InstanceStateRunning
+					ms.AWSMachine.Spec.DisableAPITermination = true
+					ec2Svc.EXPECT().GetInstanceSecurityGroups(gomock.Any()).Return(nil, errors.New("stop here"))
+				})
+
+				It("should enable termination protection when it was disabled", func() {
+					ec2Svc.EXPECT().GetInstanceTerminationProtection("myMachine").Return(false, nil)
+					ec2Svc.EXPECT().SetInstanceTerminationProtection("myMachine", true).Return(nil)
+					_, _ = reconciler.reconcileNormal(context.Background(), ms, cs)
+					Expect(recorder.Events).To(Receive(ContainSubstring("TerminationProtectionEnabled")))
+				})
+
+				It("should leave enabled termination protection alone", func() {
+					ec2Svc.EXPECT().GetInstanceTerminationProtection("myMachine").Return(true, nil)
+					ec2Svc.EXPECT().SetInstanceTerminationProtection(gomock.Any(), gomock.Any()).Times(0)
+					_, _ = reconciler.reconcileNormal(context.Background(), ms, cs)
+				})
+			})
+
 			Context("Security Groups succeed", func() {
 				BeforeEach(func() {
 					ec2Svc.EXPECT().GetInstanceSecurityGroups(gomock.Any()).
@@ -265,6 +438,7 @@ var _ = Describe("AWSMachineReconciler", func() {
 						},
 					}
 					// ms.AWSMachine.Spec.AdditionalSecurityGroups = []infrav1
+					ec2Svc.EXPECT().GetAdditionalSecurityGroupsIDs(gomock.Any()).Return([]string{"sg-2345"}, nil)
 					ec2Svc.EXPECT().UpdateInstanceSecurityGroups(instance.ID, []string{"sg-2345"})
 
 					_, _ = reconciler.reconcileNormal(context.Background(), ms, cs)
@@ -356,6 +530,16 @@ var _ = Describe("AWSMachineReconciler", func() {
 					Expect(ms.AWSMachine.Status.FailureMessage).To(PointTo(Equal("EC2 instance state \"terminated\" is unexpected")))
 				})
 
+				It("should error when a spot instance is interrupted", func() {
+					instance.State = infrav1.InstanceStateShuttingDown
+					instance.SpotMarketOptions = &infrav1.SpotMarketOptions{}
+					_, _ = reconciler.reconcileNormal(context.Background(), ms, cs)
+					Expect(ms.AWSMachine.Status.Ready).To(Equal(false))
+					Expect(buf.String()).To(ContainSubstring(("EC2 spot instance was interrupted")))
+					Expect(recorder.Events).To(Receive(ContainSubstring("SpotInstanceInterrupted")))
+					Expect(ms.AWSMachine.Status.FailureMessage).To(PointTo(Equal("EC2 spot instance \"myMachine\" was interrupted")))
+				})
+
 			})
 
 		})
@@ -439,6 +623,41 @@ var _ = Describe("AWSMachineReconciler", func() {
 				Expect(recorder.Events).To(Receive(ContainSubstring("FailedTerminate")))
 			})
 
+			It("should disable termination protection before terminating the instance", func() {
+				ms.AWSMachine.Spec.DisableAPITermination = true
+				gomock.InOrder(
+					ec2Svc.EXPECT().SetInstanceTerminationProtection(id, false).Return(nil),
+					ec2Svc.EXPECT().TerminateInstanceAndWait(id).Return(nil),
+				)
+
+				_, err := reconciler.reconcileDelete(ms, cs)
+				Expect(err).To(BeNil())
+			})
+
+			It("should cancel a persistent spot request before terminating the instance", func() {
+				ms.AWSMachine.Spec.SpotMarketOptions = &infrav1.SpotMarketOptions{
+					InterruptionBehavior: infrav1.SpotInterruptionBehaviorStop,
+				}
+				gomock.InOrder(
+					ec2Svc.EXPECT().CancelSpotInstanceRequests(id).Return(nil),
+					ec2Svc.EXPECT().TerminateInstanceAndWait(id).Return(nil),
+				)
+
+				_, err := reconciler.reconcileDelete(ms, cs)
+				Expect(err).To(BeNil())
+			})
+
+			It("should delete the bootstrap data stored in SSM Parameter Store", func() {
+				ms.AWSMachine.Spec.CloudInit = &infrav1.CloudInit{
+					SecureSecretsBackend: infrav1.SecretBackendSSMParameterStore,
+				}
+				ec2Svc.EXPECT().DeleteBootstrapData(ms).Return(nil)
+				ec2Svc.EXPECT().TerminateInstanceAndWait(id).Return(nil)
+
+				_, err := reconciler.reconcileDelete(ms, cs)
+				Expect(err).To(BeNil())
+			})
+
 			When("instance can be shut down", func() {
 				BeforeEach(func() {
 					ec2Svc.EXPECT().TerminateInstanceAndWait(gomock.Any()).Return(nil)
@@ -19,7 +19,6 @@ package controllers
 import (
 	"sort"
 
-	infrav1 "sigs.k8s.io/cluster-api-provider-aws/api/v1alpha3"
 	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/scope"
 	service "sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/services"
 )
@@ -38,7 +37,7 @@ const (
 // Returns bool, error
 // Bool indicates if changes were made or not, allowing the caller to decide
 // if the machine should be updated.
-func (r *AWSMachineReconciler) ensureSecurityGroups(ec2svc service.EC2MachineInterface, scope *scope.MachineScope, additional []infrav1.AWSResourceReference, existing map[string][]string) (bool, error) {
+func (r *AWSMachineReconciler) ensureSecurityGroups(ec2svc service.EC2MachineInterface, scope *scope.MachineScope, additional []string, existing map[string][]string) (bool, error) {
 	annotation, err := r.machineAnnotationJSON(scope.AWSMachine, SecurityGroupsLastAppliedAnnotation)
 	if err != nil {
 		return false, err
@@ -60,7 +59,7 @@ func (r *AWSMachineReconciler) ensureSecurityGroups(ec2svc service.EC2MachineInt
 	// Build and store annotation.
 	newAnnotation := make(map[string]interface{}, len(additional))
 	for _, id := range additional {
-		newAnnotation[*id.ID] = struct{}{}
+		newAnnotation[id] = struct{}{}
 	}
 
 	if err := r.updateMachineAnnotationJSON(scope.AWSMachine, SecurityGroupsLastAppliedAnnotation, newAnnotation); err != nil {
@@ -71,10 +70,10 @@ func (r *AWSMachineReconciler) ensureSecurityGroups(ec2svc service.EC2MachineInt
 }
 
 // securityGroupsChanged determines which security groups to delete and which to add.
-func (r *AWSMachineReconciler) securityGroupsChanged(annotation map[string]interface{}, core []string, additional []infrav1.AWSResourceReference, existing map[string][]string) (bool, []string) {
+func (r *AWSMachineReconciler) securityGroupsChanged(annotation map[string]interface{}, core []string, additional []string, existing map[string][]string) (bool, []string) {
 	state := map[string]bool{}
 	for _, s := range additional {
-		state[*s.ID] = true
+		state[s] = true
 	}
 
 	// Loop over `annotation`, checking the state for things that were deleted since last time.
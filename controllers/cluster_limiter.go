@@ -0,0 +1,133 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+const (
+	// clusterLimiterRetryAfter is how long a reconcile turned away by the
+	// cluster limiter waits before it is retried.
+	clusterLimiterRetryAfter = 2 * time.Second
+
+	// clusterLimiterWaitingExpiry is how long a turned away reconcile is
+	// counted as waiting without being retried, e.g. because its object was
+	// deleted in the meantime.
+	clusterLimiterWaitingExpiry = 10 * clusterLimiterRetryAfter
+)
+
+var (
+	clusterReconcilesActive = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "capa_cluster_reconciles_active",
+		Help: "Number of reconciles in progress per cluster.",
+	}, []string{"controller", "cluster"})
+
+	clusterReconcilesWaiting = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "capa_cluster_reconciles_waiting",
+		Help: "Number of reconciles waiting for the per cluster concurrency limit.",
+	}, []string{"controller", "cluster"})
+)
+
+func init() {
+	metrics.Registry.MustRegister(clusterReconcilesActive, clusterReconcilesWaiting)
+}
+
+// clusterLimiter limits the number of reconciles running at the same time for
+// objects of a single cluster, so that a cluster with many objects cannot take
+// all the workers of a controller and starve the other clusters.
+type clusterLimiter struct {
+	controller string
+	max        int
+
+	mu      sync.Mutex
+	active  map[string]int
+	waiting map[string]map[string]time.Time
+	clock   func() time.Time
+}
+
+func newClusterLimiter(controller string, max int) *clusterLimiter {
+	return &clusterLimiter{
+		controller: controller,
+		max:        max,
+		active:     map[string]int{},
+		waiting:    map[string]map[string]time.Time{},
+		clock:      time.Now,
+	}
+}
+
+// TryAcquire takes a slot for the request in the cluster and returns true, or
+// returns false when the cluster already has the maximum number of reconciles
+// in progress. Every successful TryAcquire must be followed by a Release.
+func (l *clusterLimiter) TryAcquire(cluster string, request string) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.clock()
+	waiting := l.waiting[cluster]
+	for r, seen := range waiting {
+		if now.Sub(seen) > clusterLimiterWaitingExpiry {
+			delete(waiting, r)
+		}
+	}
+
+	if l.active[cluster] >= l.max {
+		if waiting == nil {
+			waiting = map[string]time.Time{}
+			l.waiting[cluster] = waiting
+		}
+		waiting[request] = now
+		l.updateMetrics(cluster)
+		return false
+	}
+
+	delete(waiting, request)
+	l.active[cluster]++
+	l.updateMetrics(cluster)
+	return true
+}
+
+// Release gives back a slot taken by TryAcquire.
+func (l *clusterLimiter) Release(cluster string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.active[cluster] > 0 {
+		l.active[cluster]--
+	}
+	l.updateMetrics(cluster)
+}
+
+// updateMetrics reports the state of the cluster, and forgets the cluster once
+// it has nothing in progress or waiting so deleted clusters do not linger.
+func (l *clusterLimiter) updateMetrics(cluster string) {
+	active, waiting := l.active[cluster], len(l.waiting[cluster])
+	if active == 0 && waiting == 0 {
+		delete(l.active, cluster)
+		delete(l.waiting, cluster)
+		clusterReconcilesActive.DeleteLabelValues(l.controller, cluster)
+		clusterReconcilesWaiting.DeleteLabelValues(l.controller, cluster)
+		return
+	}
+
+	clusterReconcilesActive.WithLabelValues(l.controller, cluster).Set(float64(active))
+	clusterReconcilesWaiting.WithLabelValues(l.controller, cluster).Set(float64(waiting))
+}
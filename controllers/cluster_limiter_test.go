@@ -0,0 +1,64 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"testing"
+	"time"
+)
+
+func TestClusterLimiter(t *testing.T) {
+	now := time.Now()
+	l := newClusterLimiter("test", 2)
+	l.clock = func() time.Time { return now }
+
+	if !l.TryAcquire("default/big", "default/m-1") || !l.TryAcquire("default/big", "default/m-2") {
+		t.Fatal("expected the first two reconciles of the cluster to acquire a slot")
+	}
+	if l.TryAcquire("default/big", "default/m-3") {
+		t.Fatal("expected a third reconcile of the cluster to be turned away")
+	}
+	if !l.TryAcquire("default/small", "default/m-4") {
+		t.Fatal("expected a reconcile of another cluster to acquire a slot")
+	}
+	if got := len(l.waiting["default/big"]); got != 1 {
+		t.Fatalf("expected 1 waiting reconcile, got %d", got)
+	}
+
+	l.Release("default/big")
+	if !l.TryAcquire("default/big", "default/m-3") {
+		t.Fatal("expected the waiting reconcile to acquire the released slot")
+	}
+	if got := len(l.waiting["default/big"]); got != 0 {
+		t.Fatalf("expected no waiting reconciles, got %d", got)
+	}
+
+	// A reconcile that is never retried stops being counted as waiting.
+	if l.TryAcquire("default/big", "default/m-5") {
+		t.Fatal("expected a reconcile over the limit to be turned away")
+	}
+	now = now.Add(clusterLimiterWaitingExpiry + time.Second)
+	l.TryAcquire("default/big", "default/m-6")
+	if _, ok := l.waiting["default/big"]["default/m-5"]; ok {
+		t.Fatal("expected the expired waiting reconcile to be forgotten")
+	}
+
+	l.Release("default/small")
+	if _, ok := l.active["default/small"]; ok {
+		t.Fatal("expected an idle cluster to be forgotten")
+	}
+}
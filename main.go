@@ -17,6 +17,7 @@ limitations under the License.
 package main
 
 import (
+	"errors"
 	"flag"
 	"net/http"
 	_ "net/http/pprof"
@@ -31,6 +32,9 @@ import (
 	infrav1alpha2 "sigs.k8s.io/cluster-api-provider-aws/api/v1alpha2"
 	infrav1alpha3 "sigs.k8s.io/cluster-api-provider-aws/api/v1alpha3"
 	"sigs.k8s.io/cluster-api-provider-aws/controllers"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/awserrors"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/scope"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/services/ec2"
 	"sigs.k8s.io/cluster-api-provider-aws/pkg/record"
 	clusterv1 "sigs.k8s.io/cluster-api/api/v1alpha3"
 	ctrl "sigs.k8s.io/controller-runtime"
@@ -38,6 +42,10 @@ import (
 	// +kubebuilder:scaffold:imports
 )
 
+// insecureSkipVerifyEnv must be set to "true" alongside --aws-insecure-skip-verify,
+// so that TLS verification cannot be disabled by a flag alone.
+const insecureSkipVerifyEnv = "CAPA_TEST_ALLOW_INSECURE_SKIP_VERIFY"
+
 var (
 	scheme   = runtime.NewScheme()
 	setupLog = ctrl.Log.WithName("setup")
@@ -62,8 +70,19 @@ func main() {
 		profilerAddress         string
 		awsClusterConcurrency   int
 		awsMachineConcurrency   int
+		perClusterConcurrency   int
+		capacityRetryMaxAge     time.Duration
+		profileAssocTimeout     time.Duration
+		instanceReadyTimeout    time.Duration
+		instanceReadyInterval   time.Duration
 		syncPeriod              time.Duration
 		webhookPort             int
+		requirePrivateVPCCidrs  bool
+		awsCABundle             string
+		awsInsecureSkipVerify   bool
+		awsErrorOverrides       string
+		awsThrottlingMaxElapsed time.Duration
+		awsAPIMetrics           bool
 	)
 
 	flag.StringVar(
@@ -113,6 +132,36 @@ func main() {
 		"Number of AWSMachines to process simultaneously",
 	)
 
+	flag.IntVar(&perClusterConcurrency,
+		"awsmachine-concurrency-per-cluster",
+		0,
+		"Number of AWSMachines of a single cluster to process simultaneously, so that a cluster with many machines cannot starve the others. If unspecified, there is no per cluster limit",
+	)
+
+	flag.DurationVar(&capacityRetryMaxAge,
+		"insufficient-capacity-max-age",
+		0,
+		"How old an AWSMachine can get while its instance launch keeps failing for insufficient capacity before it is marked as failed (e.g. 2h). If unspecified, launches are retried until capacity becomes available",
+	)
+
+	flag.DurationVar(&profileAssocTimeout,
+		"instance-profile-association-timeout",
+		5*time.Minute,
+		"How long a running instance can wait for its IAM instance profile to be associated before the association is reported as failed (e.g. 10m)",
+	)
+
+	flag.DurationVar(&instanceReadyTimeout,
+		"instance-ready-timeout",
+		ec2.DefaultInstanceReadyTimeout,
+		"How long to wait for a new EC2 instance to be running before checking on it again in a later reconcile (e.g. 5m for large instances)",
+	)
+
+	flag.DurationVar(&instanceReadyInterval,
+		"instance-ready-poll-interval",
+		ec2.DefaultInstanceReadyPollInterval,
+		"How often the state of a new EC2 instance is checked while waiting for it to be running",
+	)
+
 	flag.DurationVar(&syncPeriod,
 		"sync-period",
 		10*time.Minute,
@@ -125,6 +174,42 @@ func main() {
 		"Webhook server port (set to 0 to disable)",
 	)
 
+	flag.BoolVar(&requirePrivateVPCCidrs,
+		"require-private-vpc-cidr-blocks",
+		false,
+		"Reject AWSClusters whose VPC CIDR blocks are outside of the RFC 1918 private address ranges",
+	)
+
+	flag.StringVar(&awsCABundle,
+		"aws-ca-bundle",
+		"",
+		"Path to a PEM encoded CA bundle used instead of the system roots to verify AWS endpoints (e.g. a self-signed test endpoint)",
+	)
+
+	flag.BoolVar(&awsInsecureSkipVerify,
+		"aws-insecure-skip-verify",
+		false,
+		"DANGEROUS, test only: skip TLS certificate verification for all AWS endpoints. Requires "+insecureSkipVerifyEnv+"=true to be set in the environment",
+	)
+
+	flag.StringVar(&awsErrorOverrides,
+		"aws-error-classification-overrides",
+		"",
+		"Comma separated list of AWS error code=class pairs that change how these errors are handled, where class is one of retryable, terminal or throttling (e.g. InvalidSubnetID.NotFound=retryable,SlowDown=throttling)",
+	)
+
+	flag.DurationVar(&awsThrottlingMaxElapsed,
+		"aws-throttling-max-elapsed-time",
+		awserrors.DefaultThrottlingMaxElapsedTime,
+		"How long a throttled AWS call is retried with exponential backoff before the reconcile fails with the throttling error (e.g. 2m). Set to 0 to disable the retries",
+	)
+
+	flag.BoolVar(&awsAPIMetrics,
+		"aws-api-metrics",
+		true,
+		"Publish metrics of the AWS API calls on the metrics endpoint, by service, operation and error code. Set to false to disable them",
+	)
+
 	flag.Parse()
 
 	if watchNamespace != "" {
@@ -139,6 +224,36 @@ func main() {
 	}
 
 	ctrl.SetLogger(klogr.New())
+
+	errorOverrides, err := awserrors.ParseClassificationOverrides(awsErrorOverrides)
+	if err != nil {
+		setupLog.Error(err, "invalid --aws-error-classification-overrides")
+		os.Exit(1)
+	}
+	if len(errorOverrides) > 0 {
+		setupLog.Info("Overriding the classification of AWS errors", "overrides", awsErrorOverrides)
+	}
+	awserrors.SetClassificationOverrides(errorOverrides)
+	awserrors.SetThrottlingMaxElapsedTime(awsThrottlingMaxElapsed)
+
+	if awsInsecureSkipVerify && os.Getenv(insecureSkipVerifyEnv) != "true" {
+		setupLog.Error(errors.New("refusing to disable TLS verification"), "--aws-insecure-skip-verify is only allowed in test environments with "+insecureSkipVerifyEnv+"=true")
+		os.Exit(1)
+	}
+	sessionTLSConfig, err := scope.NewSessionTLSConfig(scope.TLSOptions{
+		CABundleFile:       awsCABundle,
+		InsecureSkipVerify: awsInsecureSkipVerify,
+	})
+	if err != nil {
+		setupLog.Error(err, "invalid AWS TLS options")
+		os.Exit(1)
+	}
+	if err := scope.SetSessionTLSConfig(sessionTLSConfig); err != nil {
+		setupLog.Error(err, "unable to configure AWS sessions")
+		os.Exit(1)
+	}
+	scope.AWSAPIMetrics = awsAPIMetrics
+
 	// Machine and cluster operations can create enough events to trigger the event recorder spam filter
 	// Setting the burst size higher ensures all events will be recorded and submitted to the API
 	broadcaster := cgrecord.NewBroadcasterWithCorrelatorOptions(cgrecord.CorrelatorOptions{
@@ -168,6 +283,12 @@ func main() {
 		Client:   mgr.GetClient(),
 		Log:      ctrl.Log.WithName("controllers").WithName("AWSMachine"),
 		Recorder: mgr.GetEventRecorderFor("awsmachine-controller"),
+
+		InsufficientCapacityMaxAge:        capacityRetryMaxAge,
+		InstanceProfileAssociationTimeout: profileAssocTimeout,
+		ClusterConcurrency:                perClusterConcurrency,
+		InstanceReadyTimeout:              instanceReadyTimeout,
+		InstanceReadyPollInterval:         instanceReadyInterval,
 	}).SetupWithManager(mgr, controller.Options{MaxConcurrentReconciles: awsMachineConcurrency}); err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "AWSMachine")
 		os.Exit(1)
@@ -182,6 +303,7 @@ func main() {
 	}
 
 	if webhookPort != 0 {
+		infrav1alpha3.RequirePrivateVPCCidrBlocks = requirePrivateVPCCidrs
 		if err = (&infrav1alpha3.AWSMachineTemplate{}).SetupWebhookWithManager(mgr); err != nil {
 			setupLog.Error(err, "unable to create webhook", "webhook", "AWSMachineTemplate")
 			os.Exit(1)
@@ -206,6 +328,14 @@ func main() {
 			setupLog.Error(err, "unable to create webhook", "webhook", "AWSClusterList")
 			os.Exit(1)
 		}
+		if err = (&infrav1alpha3.AWSClusterStaticIdentity{}).SetupWebhookWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to create webhook", "webhook", "AWSClusterStaticIdentity")
+			os.Exit(1)
+		}
+		if err = (&infrav1alpha3.AWSClusterRoleIdentity{}).SetupWebhookWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to create webhook", "webhook", "AWSClusterRoleIdentity")
+			os.Exit(1)
+		}
 	}
 	// +kubebuilder:scaffold:builder
 
@@ -0,0 +1,122 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package awserrors
+
+import (
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+// Classification describes how an AWS error is handled.
+type Classification string
+
+const (
+	// ClassificationRetryable is for errors that may go away when the same
+	// request is retried.
+	ClassificationRetryable = Classification("retryable")
+
+	// ClassificationTerminal is for errors that retrying won't fix.
+	ClassificationTerminal = Classification("terminal")
+
+	// ClassificationThrottling is for errors returned when AWS rate limits
+	// the requests, they are retried after backing off.
+	ClassificationThrottling = Classification("throttling")
+)
+
+var codePattern = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9.]*$`)
+
+var (
+	overridesMu sync.RWMutex
+	overrides   map[string]Classification
+)
+
+// ParseClassificationOverrides parses a comma separated list of code=class
+// pairs, e.g. "InvalidSubnetID.NotFound=retryable,SlowDown=throttling", where
+// class is one of retryable, terminal or throttling.
+func ParseClassificationOverrides(s string) (map[string]Classification, error) {
+	result := map[string]Classification{}
+	if strings.TrimSpace(s) == "" {
+		return result, nil
+	}
+
+	for _, entry := range strings.Split(s, ",") {
+		parts := strings.SplitN(strings.TrimSpace(entry), "=", 2)
+		if len(parts) != 2 {
+			return nil, errors.Errorf("invalid error classification override %q, expected code=class", entry)
+		}
+
+		code, class := strings.TrimSpace(parts[0]), Classification(strings.TrimSpace(parts[1]))
+		if !codePattern.MatchString(code) {
+			return nil, errors.Errorf("invalid AWS error code %q in error classification override %q", code, entry)
+		}
+		switch class {
+		case ClassificationRetryable, ClassificationTerminal, ClassificationThrottling:
+		default:
+			return nil, errors.Errorf("invalid classification %q for AWS error code %q, must be one of %s, %s or %s",
+				class, code, ClassificationRetryable, ClassificationTerminal, ClassificationThrottling)
+		}
+		if _, ok := result[code]; ok {
+			return nil, errors.Errorf("AWS error code %q is classified more than once", code)
+		}
+
+		result[code] = class
+	}
+
+	return result, nil
+}
+
+// SetClassificationOverrides replaces the classifications of the given error
+// codes. It is meant to be called once at startup.
+func SetClassificationOverrides(o map[string]Classification) {
+	overridesMu.Lock()
+	defer overridesMu.Unlock()
+	overrides = o
+}
+
+// Classify returns how the error is handled. The default classification of an
+// AWS error code is overridden by SetClassificationOverrides. Otherwise
+// throttling and insufficient capacity errors, as well as the given retryable
+// codes, are retryable and all other errors are terminal.
+func Classify(err error, retryableCodes ...string) Classification {
+	code, ok := Code(errors.Cause(err))
+	if !ok {
+		return ClassificationTerminal
+	}
+
+	overridesMu.RLock()
+	class, ok := overrides[code]
+	overridesMu.RUnlock()
+	if ok {
+		return class
+	}
+
+	switch code {
+	case RequestLimitExceeded, Throttling, ThrottlingException:
+		return ClassificationThrottling
+	case InsufficientInstanceCapacity, InsufficientHostCapacity, InsufficientCapacity:
+		return ClassificationRetryable
+	}
+	for _, r := range retryableCodes {
+		if code == r {
+			return ClassificationRetryable
+		}
+	}
+	return ClassificationTerminal
+}
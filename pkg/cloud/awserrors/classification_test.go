@@ -0,0 +1,153 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package awserrors
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	pkgerrors "github.com/pkg/errors"
+)
+
+func TestParseClassificationOverrides(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		want    map[string]Classification
+		wantErr bool
+	}{
+		{
+			name:  "empty",
+			input: "",
+			want:  map[string]Classification{},
+		},
+		{
+			name:  "several overrides",
+			input: "InvalidSubnetID.NotFound=retryable, SlowDown=throttling,InvalidParameterValue=terminal",
+			want: map[string]Classification{
+				"InvalidSubnetID.NotFound": ClassificationRetryable,
+				"SlowDown":                 ClassificationThrottling,
+				"InvalidParameterValue":    ClassificationTerminal,
+			},
+		},
+		{
+			name:    "missing class",
+			input:   "SlowDown",
+			wantErr: true,
+		},
+		{
+			name:    "unknown class",
+			input:   "SlowDown=later",
+			wantErr: true,
+		},
+		{
+			name:    "invalid code",
+			input:   "Slow Down=throttling",
+			wantErr: true,
+		},
+		{
+			name:    "duplicate code",
+			input:   "SlowDown=throttling,SlowDown=retryable",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseClassificationOverrides(tt.input)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseClassificationOverrides() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseClassificationOverrides() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestClassify(t *testing.T) {
+	SetClassificationOverrides(map[string]Classification{
+		"SlowDown":                     ClassificationThrottling,
+		InsufficientInstanceCapacity:   ClassificationTerminal,
+		"InvalidSubnetID.NotFound":     ClassificationRetryable,
+		"InvalidRouteTableID.NotFound": ClassificationTerminal,
+	})
+	defer SetClassificationOverrides(nil)
+
+	tests := []struct {
+		name           string
+		err            error
+		retryableCodes []string
+		want           Classification
+	}{
+		{
+			name: "not an AWS error",
+			err:  errors.New("boom"),
+			want: ClassificationTerminal,
+		},
+		{
+			name: "throttling",
+			err:  awserr.New(RequestLimitExceeded, "", nil),
+			want: ClassificationThrottling,
+		},
+		{
+			name: "wrapped throttling",
+			err:  pkgerrors.Wrap(awserr.New(Throttling, "", nil), "failed to describe instances"),
+			want: ClassificationThrottling,
+		},
+		{
+			name:           "retryable code",
+			err:            awserr.New(GatewayNotFound, "", nil),
+			retryableCodes: []string{GatewayNotFound},
+			want:           ClassificationRetryable,
+		},
+		{
+			name: "unknown code",
+			err:  awserr.New(AuthFailure, "", nil),
+			want: ClassificationTerminal,
+		},
+		{
+			name: "overridden as throttling",
+			err:  awserr.New("SlowDown", "", nil),
+			want: ClassificationThrottling,
+		},
+		{
+			name: "overridden as terminal",
+			err:  awserr.New(InsufficientInstanceCapacity, "", nil),
+			want: ClassificationTerminal,
+		},
+		{
+			name: "overridden as retryable",
+			err:  awserr.New("InvalidSubnetID.NotFound", "", nil),
+			want: ClassificationRetryable,
+		},
+		{
+			name:           "override takes precedence over the retryable codes",
+			err:            awserr.New("InvalidRouteTableID.NotFound", "", nil),
+			retryableCodes: []string{"InvalidRouteTableID.NotFound"},
+			want:           ClassificationTerminal,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Classify(tt.err, tt.retryableCodes...); got != tt.want {
+				t.Errorf("Classify() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
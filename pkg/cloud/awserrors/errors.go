@@ -18,26 +18,49 @@ package awserrors
 
 import (
 	"net/http"
+	"strings"
 
 	"github.com/aws/aws-sdk-go/aws/awserr"
 )
 
 const (
-	AuthFailure             = "AuthFailure"
-	InUseIPAddress          = "InvalidIPAddress.InUse"
-	GroupNotFound           = "InvalidGroup.NotFound"
-	PermissionNotFound      = "InvalidPermission.NotFound"
-	VPCNotFound             = "InvalidVpcID.NotFound"
-	SubnetNotFound          = "InvalidSubnetID.NotFound"
-	InternetGatewayNotFound = "InvalidInternetGatewayID.NotFound"
-	NATGatewayNotFound      = "InvalidNatGatewayID.NotFound"
-	GatewayNotFound         = "InvalidGatewayID.NotFound"
-	EIPNotFound             = "InvalidElasticIpID.NotFound"
-	RouteTableNotFound      = "InvalidRouteTableID.NotFound"
-	LoadBalancerNotFound    = "LoadBalancerNotFound"
-	ResourceNotFound        = "InvalidResourceID.NotFound"
-	InvalidSubnet           = "InvalidSubnet"
-	AssociationIDNotFound   = "InvalidAssociationID.NotFound"
+	AuthFailure                = "AuthFailure"
+	InUseIPAddress             = "InvalidIPAddress.InUse"
+	ResourceAlreadyAssociated  = "Resource.AlreadyAssociated"
+	GroupNotFound              = "InvalidGroup.NotFound"
+	DependencyViolation        = "DependencyViolation"
+	PermissionNotFound         = "InvalidPermission.NotFound"
+	VPCNotFound                = "InvalidVpcID.NotFound"
+	SubnetNotFound             = "InvalidSubnetID.NotFound"
+	InternetGatewayNotFound    = "InvalidInternetGatewayID.NotFound"
+	NATGatewayNotFound         = "InvalidNatGatewayID.NotFound"
+	GatewayNotFound            = "InvalidGatewayID.NotFound"
+	EIPNotFound                = "InvalidElasticIpID.NotFound"
+	RouteTableNotFound         = "InvalidRouteTableID.NotFound"
+	NetworkACLNotFound         = "InvalidNetworkAclID.NotFound"
+	VPCEndpointNotFound        = "InvalidVpcEndpointId.NotFound"
+	TGWAttachmentNotFound      = "InvalidTransitGatewayAttachmentID.NotFound"
+	VPCPeeringNotFound         = "InvalidVpcPeeringConnectionID.NotFound"
+	DHCPOptionsNotFound        = "InvalidDhcpOptionID.NotFound"
+	LoadBalancerNotFound       = "LoadBalancerNotFound"
+	ResourceNotFound           = "InvalidResourceID.NotFound"
+	InvalidSubnet              = "InvalidSubnet"
+	AssociationIDNotFound      = "InvalidAssociationID.NotFound"
+	PlacementGroupNotFound     = "InvalidPlacementGroup.Unknown"
+	PlacementGroupDuplicate    = "InvalidPlacementGroup.Duplicate"
+	VolumeModificationNotFound = "InvalidVolumeModification.NotFound"
+	InvalidParameterValue      = "InvalidParameterValue"
+	Unsupported                = "Unsupported"
+
+	CapacityReservationNotFound = "InvalidCapacityReservationId.NotFound"
+
+	InsufficientInstanceCapacity = "InsufficientInstanceCapacity"
+	InsufficientHostCapacity     = "InsufficientHostCapacity"
+	InsufficientCapacity         = "InsufficientCapacity"
+
+	RequestLimitExceeded = "RequestLimitExceeded"
+	Throttling           = "Throttling"
+	ThrottlingException  = "ThrottlingException"
 )
 
 var _ error = &EC2Error{}
@@ -152,3 +175,31 @@ func IsIgnorableSecurityGroupError(err error) error {
 	}
 	return nil
 }
+
+// IsInsufficientCapacity returns true if the error reports that AWS does not
+// currently have enough capacity to satisfy the request. These errors are
+// transient, the same request may succeed once capacity becomes available.
+func IsInsufficientCapacity(err error) bool {
+	if code, ok := Code(err); ok {
+		switch code {
+		case InsufficientInstanceCapacity, InsufficientHostCapacity, InsufficientCapacity:
+			return true
+		}
+	}
+	return false
+}
+
+// IsInvalidInstanceType returns true if the error reports that the requested
+// instance type does not exist or is not offered where it was requested.
+// Retrying the same request will never succeed.
+func IsInvalidInstanceType(err error) bool {
+	if code, ok := Code(err); ok {
+		switch code {
+		case Unsupported:
+			return true
+		case InvalidParameterValue:
+			return strings.Contains(strings.ToLower(Message(err)), "instancetype")
+		}
+	}
+	return false
+}
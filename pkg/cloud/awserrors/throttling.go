@@ -0,0 +1,89 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package awserrors
+
+import (
+	"sync/atomic"
+	"time"
+
+	"k8s.io/apimachinery/pkg/util/wait"
+)
+
+const (
+	// throttlingBaseDelay is the longest wait before the first retry.
+	throttlingBaseDelay = 500 * time.Millisecond
+
+	// throttlingMaxDelay caps the wait between two retries.
+	throttlingMaxDelay = 20 * time.Second
+
+	// DefaultThrottlingMaxElapsedTime is how long RetryOnThrottling retries
+	// unless SetThrottlingMaxElapsedTime was called.
+	DefaultThrottlingMaxElapsedTime = time.Minute
+)
+
+var throttlingMaxElapsedTime = int64(DefaultThrottlingMaxElapsedTime)
+
+// sleep and now are replaced in tests.
+var (
+	sleep = time.Sleep
+	now   = time.Now
+)
+
+// SetThrottlingMaxElapsedTime sets how long RetryOnThrottling keeps retrying
+// a throttled call. Zero disables the retries.
+func SetThrottlingMaxElapsedTime(d time.Duration) {
+	atomic.StoreInt64(&throttlingMaxElapsedTime, int64(d))
+}
+
+// IsThrottling returns true if AWS rejected the request because the rate
+// limits of the account were exceeded.
+func IsThrottling(err error) bool {
+	return Classify(err) == ClassificationThrottling
+}
+
+// RetryOnThrottling calls fn until it returns an error that isn't a throttling
+// error. The wait between two calls grows exponentially up to a cap and is
+// randomized, so that the reconciles throttled at the same time don't retry
+// at the same time. Once the max elapsed time would be exceeded, the last
+// throttling error is returned.
+func RetryOnThrottling(fn func() error) error {
+	start := now()
+	maxElapsed := time.Duration(atomic.LoadInt64(&throttlingMaxElapsedTime))
+
+	for attempt := 0; ; attempt++ {
+		err := fn()
+		if err == nil || !IsThrottling(err) {
+			return err
+		}
+
+		delay := throttlingDelay(attempt)
+		if now().Sub(start)+delay > maxElapsed {
+			return err
+		}
+		sleep(delay)
+	}
+}
+
+// throttlingDelay returns a random delay between half and the whole of the
+// exponential backoff of the attempt.
+func throttlingDelay(attempt int) time.Duration {
+	backoff := throttlingMaxDelay
+	if attempt < 16 && throttlingBaseDelay<<uint(attempt) < backoff {
+		backoff = throttlingBaseDelay << uint(attempt)
+	}
+	return wait.Jitter(backoff/2, 1)
+}
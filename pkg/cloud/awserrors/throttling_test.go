@@ -0,0 +1,116 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package awserrors
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws/awserr"
+)
+
+func TestRetryOnThrottling(t *testing.T) {
+	throttled := awserr.New(RequestLimitExceeded, "Request limit exceeded.", nil)
+	terminal := awserr.New(AuthFailure, "", nil)
+
+	tests := []struct {
+		name       string
+		maxElapsed time.Duration
+		errs       []error
+		wantCalls  int
+		wantErr    error
+	}{
+		{
+			name:       "success",
+			maxElapsed: time.Minute,
+			errs:       []error{nil},
+			wantCalls:  1,
+		},
+		{
+			name:       "throttled, then success",
+			maxElapsed: time.Minute,
+			errs:       []error{throttled, throttled, nil},
+			wantCalls:  3,
+		},
+		{
+			name:       "other errors are not retried",
+			maxElapsed: time.Minute,
+			errs:       []error{terminal},
+			wantCalls:  1,
+			wantErr:    terminal,
+		},
+		{
+			name:       "non AWS errors are not retried",
+			maxElapsed: time.Minute,
+			errs:       []error{errors.New("boom")},
+			wantCalls:  1,
+			wantErr:    errors.New("boom"),
+		},
+		{
+			name:       "gives up after the max elapsed time",
+			maxElapsed: 600 * time.Millisecond,
+			errs:       []error{throttled, throttled, throttled},
+			wantCalls:  2,
+			wantErr:    throttled,
+		},
+		{
+			name:       "retries disabled",
+			maxElapsed: 0,
+			errs:       []error{throttled, nil},
+			wantCalls:  1,
+			wantErr:    throttled,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Fake the clock, so that the retries don't actually wait.
+			clock := time.Now()
+			now = func() time.Time { return clock }
+			sleep = func(d time.Duration) { clock = clock.Add(d) }
+			defer func() {
+				now = time.Now
+				sleep = time.Sleep
+			}()
+			SetThrottlingMaxElapsedTime(tt.maxElapsed)
+			defer SetThrottlingMaxElapsedTime(DefaultThrottlingMaxElapsedTime)
+
+			calls := 0
+			err := RetryOnThrottling(func() error {
+				err := tt.errs[calls]
+				calls++
+				return err
+			})
+
+			if calls != tt.wantCalls {
+				t.Errorf("expected %d calls, got %d", tt.wantCalls, calls)
+			}
+			if (err == nil) != (tt.wantErr == nil) || (err != nil && err.Error() != tt.wantErr.Error()) {
+				t.Errorf("expected error %v, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestThrottlingDelay(t *testing.T) {
+	for attempt := 0; attempt < 64; attempt++ {
+		d := throttlingDelay(attempt)
+		if d <= 0 || d > throttlingMaxDelay {
+			t.Errorf("attempt %d: delay %s is not within (0, %s]", attempt, d, throttlingMaxDelay)
+		}
+	}
+}
@@ -20,6 +20,8 @@ import (
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/service/ec2"
 	"github.com/aws/aws-sdk-go/service/elb"
+	"github.com/aws/aws-sdk-go/service/elbv2"
+	"github.com/aws/aws-sdk-go/service/secretsmanager"
 	infrav1 "sigs.k8s.io/cluster-api-provider-aws/api/v1alpha3"
 )
 
@@ -76,3 +78,46 @@ func MapToELBTags(src infrav1.Tags) []*elb.Tag {
 
 	return tags
 }
+
+// ELBV2TagsToMap converts a []*elbv2.Tag into a infrav1.Tags.
+func ELBV2TagsToMap(src []*elbv2.Tag) infrav1.Tags {
+	tags := make(infrav1.Tags, len(src))
+
+	for _, t := range src {
+		tags[*t.Key] = *t.Value
+	}
+
+	return tags
+}
+
+// MapToELBV2Tags converts a infrav1.Tags to a []*elbv2.Tag
+func MapToELBV2Tags(src infrav1.Tags) []*elbv2.Tag {
+	tags := make([]*elbv2.Tag, 0, len(src))
+
+	for k, v := range src {
+		tag := &elbv2.Tag{
+			Key:   aws.String(k),
+			Value: aws.String(v),
+		}
+
+		tags = append(tags, tag)
+	}
+
+	return tags
+}
+
+// MapToSecretsManagerTags converts a infrav1.Tags to a []*secretsmanager.Tag
+func MapToSecretsManagerTags(src infrav1.Tags) []*secretsmanager.Tag {
+	tags := make([]*secretsmanager.Tag, 0, len(src))
+
+	for k, v := range src {
+		tag := &secretsmanager.Tag{
+			Key:   aws.String(k),
+			Value: aws.String(v),
+		}
+
+		tags = append(tags, tag)
+	}
+
+	return tags
+}
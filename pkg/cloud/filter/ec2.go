@@ -128,6 +128,22 @@ func (ec2Filters) InstanceStates(states ...string) *ec2.Filter {
 	}
 }
 
+// InstanceID returns a filter based on the ID of an instance.
+func (ec2Filters) InstanceID(instanceID string) *ec2.Filter {
+	return &ec2.Filter{
+		Name:   aws.String("instance-id"),
+		Values: aws.StringSlice([]string{instanceID}),
+	}
+}
+
+// SpotInstanceRequestStates returns a filter based on the list of states passed in.
+func (ec2Filters) SpotInstanceRequestStates(states ...string) *ec2.Filter {
+	return &ec2.Filter{
+		Name:   aws.String("state"),
+		Values: aws.StringSlice(states),
+	}
+}
+
 // VPCStates returns a filter based on the list of states passed in.
 func (ec2Filters) VPCStates(states ...string) *ec2.Filter {
 	return &ec2.Filter{
@@ -143,3 +159,35 @@ func (ec2Filters) SubnetStates(states ...string) *ec2.Filter {
 		Values: aws.StringSlice(states),
 	}
 }
+
+// VPCEndpointStates returns a filter based on the list of states passed in.
+func (ec2Filters) VPCEndpointStates(states ...string) *ec2.Filter {
+	return &ec2.Filter{
+		Name:   aws.String("vpc-endpoint-state"),
+		Values: aws.StringSlice(states),
+	}
+}
+
+// TransitGatewayAttachmentStates returns a filter based on the list of states passed in.
+func (ec2Filters) TransitGatewayAttachmentStates(states ...string) *ec2.Filter {
+	return &ec2.Filter{
+		Name:   aws.String("state"),
+		Values: aws.StringSlice(states),
+	}
+}
+
+// RequesterVPC returns a filter based on the id of the VPC requesting a peering connection.
+func (ec2Filters) RequesterVPC(vpcID string) *ec2.Filter {
+	return &ec2.Filter{
+		Name:   aws.String("requester-vpc-info.vpc-id"),
+		Values: aws.StringSlice([]string{vpcID}),
+	}
+}
+
+// VPCPeeringStates returns a filter based on the list of states passed in.
+func (ec2Filters) VPCPeeringStates(states ...string) *ec2.Filter {
+	return &ec2.Filter{
+		Name:   aws.String("status-code"),
+		Values: aws.StringSlice(states),
+	}
+}
@@ -0,0 +1,58 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package partition resolves the AWS partition of a region, e.g. aws-us-gov
+// for AWS GovCloud (US), and builds the ARNs and service principals which
+// differ between partitions.
+package partition
+
+import (
+	"github.com/aws/aws-sdk-go/aws/arn"
+	"github.com/aws/aws-sdk-go/aws/endpoints"
+)
+
+// Default is the partition of the commercial AWS regions.
+const Default = endpoints.AwsPartitionID
+
+// ForRegion returns the ID of the partition of the region, or the default
+// partition when the region is unknown to the SDK.
+func ForRegion(region string) string {
+	if p, ok := endpoints.PartitionForRegion(endpoints.DefaultPartitions(), region); ok {
+		return p.ID()
+	}
+	return Default
+}
+
+// ARN returns the ARN of a resource in the partition. Region is empty for
+// global services like IAM.
+func ARN(partition, service, region, accountID, resource string) string {
+	return arn.ARN{
+		Partition: partition,
+		Service:   service,
+		Region:    region,
+		AccountID: accountID,
+		Resource:  resource,
+	}.String()
+}
+
+// ServicePrincipal returns the principal of an AWS service in the partition,
+// to be trusted by IAM roles. The China regions use their own DNS suffix.
+func ServicePrincipal(partition, service string) string {
+	if partition == endpoints.AwsCnPartitionID {
+		return service + ".amazonaws.com.cn"
+	}
+	return service + ".amazonaws.com"
+}
@@ -0,0 +1,76 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package partition
+
+import (
+	"testing"
+)
+
+func TestPartition(t *testing.T) {
+	testCases := []struct {
+		region    string
+		partition string
+		roleARN   string
+		principal string
+	}{
+		{
+			region:    "us-east-1",
+			partition: "aws",
+			roleARN:   "arn:aws:iam::123456789012:role/controllers.cluster-api-provider-aws.sigs.k8s.io",
+			principal: "ec2.amazonaws.com",
+		},
+		{
+			region:    "us-gov-west-1",
+			partition: "aws-us-gov",
+			roleARN:   "arn:aws-us-gov:iam::123456789012:role/controllers.cluster-api-provider-aws.sigs.k8s.io",
+			principal: "ec2.amazonaws.com",
+		},
+		{
+			region:    "cn-north-1",
+			partition: "aws-cn",
+			roleARN:   "arn:aws-cn:iam::123456789012:role/controllers.cluster-api-provider-aws.sigs.k8s.io",
+			principal: "ec2.amazonaws.com.cn",
+		},
+		{
+			region:    "us-iso-east-1",
+			partition: "aws-iso",
+			roleARN:   "arn:aws-iso:iam::123456789012:role/controllers.cluster-api-provider-aws.sigs.k8s.io",
+			principal: "ec2.amazonaws.com",
+		},
+		{
+			region:    "unknown-region-1",
+			partition: "aws",
+			roleARN:   "arn:aws:iam::123456789012:role/controllers.cluster-api-provider-aws.sigs.k8s.io",
+			principal: "ec2.amazonaws.com",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.region, func(t *testing.T) {
+			p := ForRegion(tc.region)
+			if p != tc.partition {
+				t.Fatalf("ForRegion(%q) = %q, expected %q", tc.region, p, tc.partition)
+			}
+			if got := ARN(p, "iam", "", "123456789012", "role/controllers.cluster-api-provider-aws.sigs.k8s.io"); got != tc.roleARN {
+				t.Fatalf("ARN() = %q, expected %q", got, tc.roleARN)
+			}
+			if got := ServicePrincipal(p, "ec2"); got != tc.principal {
+				t.Fatalf("ServicePrincipal() = %q, expected %q", got, tc.principal)
+			}
+		})
+	}
+}
@@ -17,14 +17,26 @@ limitations under the License.
 package scope
 
 import (
+	"github.com/aws/aws-sdk-go/service/cloudwatch/cloudwatchiface"
 	"github.com/aws/aws-sdk-go/service/ec2/ec2iface"
 	"github.com/aws/aws-sdk-go/service/elb/elbiface"
+	"github.com/aws/aws-sdk-go/service/elbv2/elbv2iface"
+	"github.com/aws/aws-sdk-go/service/iam/iamiface"
 	"github.com/aws/aws-sdk-go/service/resourcegroupstaggingapi/resourcegroupstaggingapiiface"
+	"github.com/aws/aws-sdk-go/service/route53/route53iface"
+	"github.com/aws/aws-sdk-go/service/secretsmanager/secretsmanageriface"
+	"github.com/aws/aws-sdk-go/service/ssm/ssmiface"
 )
 
 // AWSClients contains all the aws clients used by the scopes.
 type AWSClients struct {
+	CloudWatch      cloudwatchiface.CloudWatchAPI
 	EC2             ec2iface.EC2API
 	ELB             elbiface.ELBAPI
+	ELBV2           elbv2iface.ELBV2API
+	IAM             iamiface.IAMAPI
 	ResourceTagging resourcegroupstaggingapiiface.ResourceGroupsTaggingAPIAPI
+	Route53         route53iface.Route53API
+	SecretsManager  secretsmanageriface.SecretsManagerAPI
+	SSM             ssmiface.SSMAPI
 }
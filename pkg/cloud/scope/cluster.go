@@ -18,25 +18,54 @@ package scope
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 
 	"github.com/aws/aws-sdk-go/aws/awserr"
 	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/cloudwatch"
 	"github.com/aws/aws-sdk-go/service/ec2"
 	"github.com/aws/aws-sdk-go/service/elb"
+	"github.com/aws/aws-sdk-go/service/elbv2"
+	"github.com/aws/aws-sdk-go/service/iam"
 	"github.com/aws/aws-sdk-go/service/resourcegroupstaggingapi"
+	"github.com/aws/aws-sdk-go/service/route53"
+	"github.com/aws/aws-sdk-go/service/secretsmanager"
+	"github.com/aws/aws-sdk-go/service/ssm"
 	"github.com/go-logr/logr"
 	"github.com/pkg/errors"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/klog/klogr"
+	"k8s.io/utils/pointer"
 	infrav1 "sigs.k8s.io/cluster-api-provider-aws/api/v1alpha3"
 	"sigs.k8s.io/cluster-api-provider-aws/pkg/record"
 	"sigs.k8s.io/cluster-api-provider-aws/version"
 	clusterv1 "sigs.k8s.io/cluster-api/api/v1alpha3"
+	capierrors "sigs.k8s.io/cluster-api/errors"
 	"sigs.k8s.io/cluster-api/util/patch"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 )
 
+// LastAppliedTagsAnnotation is the key for the AWSCluster annotation which
+// tracks the AdditionalTags applied to the cluster's resources, so tags removed
+// from the spec can be removed from the resources on the next reconcile.
+const LastAppliedTagsAnnotation = "sigs.k8s.io/cluster-api-provider-aws-last-applied-tags"
+
+// AssumeRoleFailedReason is the credentials failure reason of an AWSCluster
+// whose AssumeRole configuration can't be assumed. It is cleared once the role
+// can be assumed again.
+const AssumeRoleFailedReason = "AssumeRoleFailed"
+
+// IdentityFailedReason is the credentials failure reason of an AWSCluster
+// whose identity can't be used. It is cleared once the identity can be used
+// again.
+const IdentityFailedReason = "IdentityFailed"
+
+// sessionManagerVPCEndpointServices are the services the SSM agent reaches to
+// open Session Manager sessions.
+var sessionManagerVPCEndpointServices = []string{"ssm", "ssmmessages", "ec2messages"}
+
 // ClusterScopeParams defines the input parameters used to create a new Scope.
 type ClusterScopeParams struct {
 	AWSClients
@@ -60,11 +89,35 @@ func NewClusterScope(params ClusterScopeParams) (*ClusterScope, error) {
 		params.Logger = klogr.New()
 	}
 
-	session, err := sessionForRegion(params.AWSCluster.Spec.Region)
+	var session *session.Session
+	var err error
+	sessionName := defaultRoleSessionName(params.AWSCluster.Namespace, params.AWSCluster.Name)
+	switch {
+	case params.AWSCluster.Spec.IdentityRef != nil:
+		if params.Client == nil {
+			return nil, errors.New("failed to resolve the identity of the AWSCluster without a client")
+		}
+		session, err = sessionForIdentity(context.TODO(), params.Client, params.AWSCluster.Spec.Region,
+			params.AWSCluster.Spec.IdentityRef, params.AWSCluster.Namespace, sessionName, params.Logger)
+		if _, ok := err.(*IdentityError); ok {
+			return nil, err
+		}
+	case params.AWSCluster.Spec.AssumeRole != nil:
+		session, err = assumeRoleSession(params.AWSCluster.Spec.Region, params.AWSCluster.Spec.AssumeRole, sessionName, params.Logger)
+		if _, ok := err.(*AssumeRoleError); ok {
+			return nil, err
+		}
+	default:
+		session, err = sessionForRegion(params.AWSCluster.Spec.Region, params.Logger)
+	}
 	if err != nil {
 		return nil, errors.Errorf("failed to create aws session: %v", err)
 	}
 
+	// The credentials can be used again, clear the failure reported while they couldn't.
+	params.AWSCluster.Status.CredentialsFailureReason = ""
+	params.AWSCluster.Status.CredentialsFailureMessage = ""
+
 	userAgentHandler := request.NamedHandler{
 		Name: "capa/user-agent",
 		Fn:   request.MakeAddToUserAgentHandler("aws.cluster.x-k8s.io", version.Get().String()),
@@ -84,6 +137,27 @@ func NewClusterScope(params ClusterScopeParams) (*ClusterScope, error) {
 		params.AWSClients.ELB = elbClient
 	}
 
+	if params.AWSClients.ELBV2 == nil {
+		elbv2Client := elbv2.New(session)
+		elbv2Client.Handlers.Build.PushFrontNamed(userAgentHandler)
+		elbv2Client.Handlers.Complete.PushBack(recordAWSPermissionsIssue(params.AWSCluster))
+		params.AWSClients.ELBV2 = elbv2Client
+	}
+
+	if params.AWSClients.IAM == nil {
+		iamClient := iam.New(session)
+		iamClient.Handlers.Build.PushFrontNamed(userAgentHandler)
+		iamClient.Handlers.Complete.PushBack(recordAWSPermissionsIssue(params.AWSCluster))
+		params.AWSClients.IAM = iamClient
+	}
+
+	if params.AWSClients.CloudWatch == nil {
+		cloudWatchClient := cloudwatch.New(session)
+		cloudWatchClient.Handlers.Build.PushFrontNamed(userAgentHandler)
+		cloudWatchClient.Handlers.Complete.PushBack(recordAWSPermissionsIssue(params.AWSCluster))
+		params.AWSClients.CloudWatch = cloudWatchClient
+	}
+
 	if params.AWSClients.ResourceTagging == nil {
 		resourceTagging := resourcegroupstaggingapi.New(session)
 		resourceTagging.Handlers.Build.PushFrontNamed(userAgentHandler)
@@ -91,6 +165,27 @@ func NewClusterScope(params ClusterScopeParams) (*ClusterScope, error) {
 		params.AWSClients.ResourceTagging = resourceTagging
 	}
 
+	if params.AWSClients.Route53 == nil {
+		route53Client := route53.New(session)
+		route53Client.Handlers.Build.PushFrontNamed(userAgentHandler)
+		route53Client.Handlers.Complete.PushBack(recordAWSPermissionsIssue(params.AWSCluster))
+		params.AWSClients.Route53 = route53Client
+	}
+
+	if params.AWSClients.SecretsManager == nil {
+		secretsManagerClient := secretsmanager.New(session)
+		secretsManagerClient.Handlers.Build.PushFrontNamed(userAgentHandler)
+		secretsManagerClient.Handlers.Complete.PushBack(recordAWSPermissionsIssue(params.AWSCluster))
+		params.AWSClients.SecretsManager = secretsManagerClient
+	}
+
+	if params.AWSClients.SSM == nil {
+		ssmClient := ssm.New(session)
+		ssmClient.Handlers.Build.PushFrontNamed(userAgentHandler)
+		ssmClient.Handlers.Complete.PushBack(recordAWSPermissionsIssue(params.AWSCluster))
+		params.AWSClients.SSM = ssmClient
+	}
+
 	helper, err := patch.NewHelper(params.AWSCluster, params.Client)
 	if err != nil {
 		return nil, errors.Wrap(err, "failed to init patch helper")
@@ -142,6 +237,70 @@ func (s *ClusterScope) Subnets() infrav1.Subnets {
 	return s.AWSCluster.Spec.NetworkSpec.Subnets
 }
 
+// NetworkACL returns the configuration of the cluster managed network ACL.
+func (s *ClusterScope) NetworkACL() *infrav1.NetworkACLSpec {
+	return s.AWSCluster.Spec.NetworkSpec.NetworkACL
+}
+
+// DHCPOptions returns the configuration of the cluster managed DHCP option set.
+func (s *ClusterScope) DHCPOptions() *infrav1.DHCPOptionsSpec {
+	return s.AWSCluster.Spec.NetworkSpec.DHCPOptions
+}
+
+// NodeIngress returns the configuration of the node security group ingress rules.
+func (s *ClusterScope) NodeIngress() *infrav1.NodeIngressSpec {
+	return s.AWSCluster.Spec.NetworkSpec.NodeIngress
+}
+
+// VPCEndpoints returns the configuration of the cluster VPC endpoints,
+// including the endpoints Session Manager needs when it is enabled.
+func (s *ClusterScope) VPCEndpoints() []infrav1.VPCEndpointSpec {
+	endpoints := s.AWSCluster.Spec.NetworkSpec.VPCEndpoints
+	if !s.AWSCluster.Spec.SessionManager {
+		return endpoints
+	}
+
+	configured := make(map[string]bool, len(endpoints))
+	for _, endpoint := range endpoints {
+		configured[endpoint.Service] = true
+	}
+
+	res := append([]infrav1.VPCEndpointSpec{}, endpoints...)
+	for _, service := range sessionManagerVPCEndpointServices {
+		if !configured[service] {
+			res = append(res, infrav1.VPCEndpointSpec{Service: service, Type: infrav1.VPCEndpointTypeInterface})
+		}
+	}
+	return res
+}
+
+// BastionEnabled returns true if the cluster should have a bastion host. It
+// never has one when Session Manager is enabled.
+func (s *ClusterScope) BastionEnabled() bool {
+	return s.AWSCluster.Spec.Bastion.IsEnabled() && !s.AWSCluster.Spec.SessionManager
+}
+
+// NatGatewayMode returns whether the private subnets share a single NAT gateway
+// or get one per availability zone.
+func (s *ClusterScope) NatGatewayMode() infrav1.NatGatewayMode {
+	if s.AWSCluster.Spec.NetworkSpec.NatGatewayMode != "" {
+		return s.AWSCluster.Spec.NetworkSpec.NatGatewayMode
+	}
+	return infrav1.NatGatewayModePerAZ
+}
+
+// TransitGateway returns the configuration of the transit gateway attachment
+// of the VPC, nil when the VPC isn't attached to a transit gateway.
+func (s *ClusterScope) TransitGateway() *infrav1.TransitGatewaySpec {
+	return s.AWSCluster.Spec.NetworkSpec.TransitGateway
+}
+
+// VPCPeering returns the configuration of the peering connection of the VPC,
+// nil when the VPC isn't peered.
+func (s *ClusterScope) VPCPeering() *infrav1.VPCPeeringSpec {
+	return s.AWSCluster.Spec.NetworkSpec.VPCPeering
+}
+
 // SecurityGroups returns the cluster security groups as a map, it creates the map if empty.
 func (s *ClusterScope) SecurityGroups() map[infrav1.SecurityGroupRole]infrav1.SecurityGroup {
 	return s.AWSCluster.Status.Network.SecurityGroups
@@ -175,6 +334,41 @@ func (s *ClusterScope) ControlPlaneLoadBalancerScheme() infrav1.ClassicELBScheme
 	return infrav1.ClassicELBSchemeInternetFacing
 }
 
+// ControlPlaneLoadBalancerType returns the type of the control plane load balancer.
+func (s *ClusterScope) ControlPlaneLoadBalancerType() infrav1.LoadBalancerType {
+	if s.ControlPlaneLoadBalancer() != nil && s.ControlPlaneLoadBalancer().LoadBalancerType != "" {
+		return s.ControlPlaneLoadBalancer().LoadBalancerType
+	}
+	return infrav1.LoadBalancerTypeClassic
+}
+
+// ControlPlaneLoadBalancerARN returns the ARN of the existing load balancer to
+// use for the control plane, or an empty string when the cluster owns it.
+func (s *ClusterScope) ControlPlaneLoadBalancerARN() string {
+	if s.ControlPlaneLoadBalancer() != nil && s.ControlPlaneLoadBalancer().ARN != nil {
+		return *s.ControlPlaneLoadBalancer().ARN
+	}
+	return ""
+}
+
+// AdvertisedEndpoint returns the control plane endpoint to advertise in place
+// of the load balancer's own, if any.
+func (s *ClusterScope) AdvertisedEndpoint() *infrav1.AdvertisedEndpoint {
+	if s.AWSCluster.Spec.ControlPlaneLoadBalancer == nil {
+		return nil
+	}
+	return s.AWSCluster.Spec.ControlPlaneLoadBalancer.AdvertisedEndpoint
+}
+
+// ControlPlaneDNSRecord returns the Route53 record to manage for the control
+// plane load balancer, if any.
+func (s *ClusterScope) ControlPlaneDNSRecord() *infrav1.DNSRecordSpec {
+	if s.AWSCluster.Spec.ControlPlaneLoadBalancer == nil {
+		return nil
+	}
+	return s.AWSCluster.Spec.ControlPlaneLoadBalancer.DNSRecord
+}
+
 // ControlPlaneConfigMapName returns the name of the ConfigMap used to
 // coordinate the bootstrapping of control plane nodes.
 func (s *ClusterScope) ControlPlaneConfigMapName() string {
@@ -202,6 +396,58 @@ func (s *ClusterScope) AdditionalTags() infrav1.Tags {
 	return s.AWSCluster.Spec.AdditionalTags.DeepCopy()
 }
 
+// AdditionalTagsChanged returns true if AdditionalTags changed since they were
+// last applied to the cluster's resources.
+func (s *ClusterScope) AdditionalTagsChanged() bool {
+	return !s.lastAppliedAdditionalTags().Equals(s.AdditionalTags())
+}
+
+// RemovedAdditionalTags returns the tags that were last applied from
+// AdditionalTags but are no longer part of it. The returned value will never be nil.
+func (s *ClusterScope) RemovedAdditionalTags() infrav1.Tags {
+	removed := infrav1.Tags{}
+
+	current := s.AdditionalTags()
+	for k, v := range s.lastAppliedAdditionalTags() {
+		if _, ok := current[k]; !ok {
+			removed[k] = v
+		}
+	}
+	return removed
+}
+
+func (s *ClusterScope) lastAppliedAdditionalTags() infrav1.Tags {
+	lastApplied := infrav1.Tags{}
+
+	annotation, ok := s.AWSCluster.GetAnnotations()[LastAppliedTagsAnnotation]
+	if !ok {
+		return lastApplied
+	}
+
+	if err := json.Unmarshal([]byte(annotation), &lastApplied); err != nil {
+		s.Info("Ignoring invalid last applied tags annotation", "annotation", annotation, "error", err.Error())
+		return infrav1.Tags{}
+	}
+	return lastApplied
+}
+
+// SetLastAppliedAdditionalTags records the current AdditionalTags as applied
+// to the cluster's resources.
+func (s *ClusterScope) SetLastAppliedAdditionalTags() error {
+	b, err := json.Marshal(s.AdditionalTags())
+	if err != nil {
+		return errors.Wrap(err, "failed to marshal additional tags")
+	}
+
+	annotations := s.AWSCluster.GetAnnotations()
+	if annotations == nil {
+		annotations = map[string]string{}
+	}
+	annotations[LastAppliedTagsAnnotation] = string(b)
+	s.AWSCluster.SetAnnotations(annotations)
+	return nil
+}
+
 // APIServerPort returns the APIServerPort to use when creating the load balancer.
 func (s *ClusterScope) APIServerPort() int32 {
 	if s.Cluster.Spec.ClusterNetwork != nil && s.Cluster.Spec.ClusterNetwork.APIServerPort != nil {
@@ -217,3 +463,20 @@ func (s *ClusterScope) SetFailureDomain(id string, spec clusterv1.FailureDomainS
 	}
 	s.AWSCluster.Status.FailureDomains[id] = spec
 }
+
+// SetFailureReason sets the AWSCluster status failure reason.
+func (s *ClusterScope) SetFailureReason(v capierrors.ClusterStatusError) {
+	s.AWSCluster.Status.FailureReason = &v
+}
+
+// SetFailureMessage sets the AWSCluster status failure message.
+func (s *ClusterScope) SetFailureMessage(v error) {
+	s.AWSCluster.Status.FailureMessage = pointer.StringPtr(v.Error())
+}
+
+// ClearFailure clears the AWSCluster status failure reason and message once
+// the problem they describe is resolved.
+func (s *ClusterScope) ClearFailure() {
+	s.AWSCluster.Status.FailureReason = nil
+	s.AWSCluster.Status.FailureMessage = nil
+}
@@ -0,0 +1,76 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package scope
+
+import (
+	"reflect"
+	"testing"
+
+	infrav1 "sigs.k8s.io/cluster-api-provider-aws/api/v1alpha3"
+)
+
+func TestSessionManager(t *testing.T) {
+	testCases := []struct {
+		name            string
+		clusterSpec     infrav1.AWSClusterSpec
+		expectEndpoints []infrav1.VPCEndpointSpec
+		expectBastion   bool
+	}{
+		{
+			name: "configured endpoints and a bastion without session manager",
+			clusterSpec: infrav1.AWSClusterSpec{
+				NetworkSpec: infrav1.NetworkSpec{
+					VPCEndpoints: []infrav1.VPCEndpointSpec{{Service: "s3", Type: infrav1.VPCEndpointTypeGateway}},
+				},
+			},
+			expectEndpoints: []infrav1.VPCEndpointSpec{{Service: "s3", Type: infrav1.VPCEndpointTypeGateway}},
+			expectBastion:   true,
+		},
+		{
+			name: "session manager adds its endpoints and disables the bastion",
+			clusterSpec: infrav1.AWSClusterSpec{
+				NetworkSpec: infrav1.NetworkSpec{
+					VPCEndpoints: []infrav1.VPCEndpointSpec{
+						{Service: "s3", Type: infrav1.VPCEndpointTypeGateway},
+						{Service: "ssm", Type: infrav1.VPCEndpointTypeInterface},
+					},
+				},
+				SessionManager: true,
+			},
+			expectEndpoints: []infrav1.VPCEndpointSpec{
+				{Service: "s3", Type: infrav1.VPCEndpointTypeGateway},
+				{Service: "ssm", Type: infrav1.VPCEndpointTypeInterface},
+				{Service: "ssmmessages", Type: infrav1.VPCEndpointTypeInterface},
+				{Service: "ec2messages", Type: infrav1.VPCEndpointTypeInterface},
+			},
+			expectBastion: false,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			s := &ClusterScope{AWSCluster: &infrav1.AWSCluster{Spec: tc.clusterSpec}}
+
+			if got := s.VPCEndpoints(); !reflect.DeepEqual(got, tc.expectEndpoints) {
+				t.Errorf("VPCEndpoints() = %v, expected %v", got, tc.expectEndpoints)
+			}
+			if got := s.BastionEnabled(); got != tc.expectBastion {
+				t.Errorf("BastionEnabled() = %v, expected %v", got, tc.expectBastion)
+			}
+		})
+	}
+}
@@ -0,0 +1,187 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package scope
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/go-logr/logr"
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	infrav1 "sigs.k8s.io/cluster-api-provider-aws/api/v1alpha3"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// maxIdentityChainLength is how many identities can be chained through
+	// their source identity, which also breaks reference cycles.
+	maxIdentityChainLength = 5
+
+	// Keys of the credentials in the secret of an AWSClusterStaticIdentity.
+	accessKeyIDKey     = "AccessKeyID"
+	secretAccessKeyKey = "SecretAccessKey"
+	sessionTokenKey    = "SessionToken"
+)
+
+// IdentityError is returned when the identity referenced by a cluster can't be
+// used, e.g. because it doesn't exist, doesn't allow the namespace of the
+// cluster or its role can't be assumed.
+type IdentityError struct {
+	Kind infrav1.AWSIdentityKind
+	Name string
+	Err  error
+}
+
+func (e *IdentityError) Error() string {
+	return fmt.Sprintf("failed to use %s %q: %v", e.Kind, e.Name, e.Err)
+}
+
+// sessionForIdentity returns a session of the region using the credentials of
+// the referenced identity, which must allow the namespace of the cluster. The
+// source identities of a role identity are trusted as chosen by whoever
+// created it, only the referenced identity must allow the namespace. An
+// *IdentityError is returned if the identity can't be used.
+func sessionForIdentity(ctx context.Context, c client.Client, region string, ref *infrav1.AWSIdentityReference, namespace, defaultSessionName string, logger logr.Logger) (*session.Session, error) {
+	s, _, err := identitySession(ctx, c, region, ref, namespace, defaultSessionName, logger, 0)
+	return s, err
+}
+
+func identitySession(ctx context.Context, c client.Client, region string, ref *infrav1.AWSIdentityReference, namespace, defaultSessionName string, logger logr.Logger, depth int) (*session.Session, string, error) {
+	s, key, err := resolveIdentity(ctx, c, region, ref, namespace, defaultSessionName, logger, depth)
+	if err != nil {
+		return nil, "", &IdentityError{Kind: ref.Kind, Name: ref.Name, Err: err}
+	}
+	return s, key, nil
+}
+
+func resolveIdentity(ctx context.Context, c client.Client, region string, ref *infrav1.AWSIdentityReference, namespace, defaultSessionName string, logger logr.Logger, depth int) (*session.Session, string, error) {
+	if depth >= maxIdentityChainLength {
+		return nil, "", errors.Errorf("more than %d identities are chained through their source identity", maxIdentityChainLength)
+	}
+
+	switch ref.Kind {
+	case infrav1.AWSClusterStaticIdentityKind:
+		identity := &infrav1.AWSClusterStaticIdentity{}
+		if err := c.Get(ctx, client.ObjectKey{Name: ref.Name}, identity); err != nil {
+			return nil, "", err
+		}
+		if depth == 0 {
+			if err := checkNamespaceAllowed(ctx, c, identity.Spec.AllowedNamespaces, namespace); err != nil {
+				return nil, "", err
+			}
+		}
+		return staticIdentitySession(ctx, c, region, identity, logger)
+
+	case infrav1.AWSClusterRoleIdentityKind:
+		identity := &infrav1.AWSClusterRoleIdentity{}
+		if err := c.Get(ctx, client.ObjectKey{Name: ref.Name}, identity); err != nil {
+			return nil, "", err
+		}
+		if depth == 0 {
+			if err := checkNamespaceAllowed(ctx, c, identity.Spec.AllowedNamespaces, namespace); err != nil {
+				return nil, "", err
+			}
+		}
+
+		var base *session.Session
+		var baseKey string
+		var err error
+		if source := identity.Spec.SourceIdentityRef; source != nil {
+			base, baseKey, err = identitySession(ctx, c, region, source, namespace, defaultSessionName, logger, depth+1)
+		} else {
+			baseKey = region
+			base, err = sessionForRegion(region, logger)
+		}
+		if err != nil {
+			return nil, "", err
+		}
+		return assumeRoleFromSession(base, baseKey, &identity.Spec.AssumeRoleSpec, defaultSessionName)
+
+	default:
+		return nil, "", errors.Errorf("unknown identity kind %q", ref.Kind)
+	}
+}
+
+// staticIdentitySession returns a session of the region using the credentials
+// held by the secret of the identity. Sessions are cached by version of the
+// secret, so rotated credentials are picked up on the next reconcile.
+func staticIdentitySession(ctx context.Context, c client.Client, region string, identity *infrav1.AWSClusterStaticIdentity, logger logr.Logger) (*session.Session, string, error) {
+	secret := &corev1.Secret{}
+	secretKey := client.ObjectKey{Namespace: identity.Spec.SecretRef.Namespace, Name: identity.Spec.SecretRef.Name}
+	if err := c.Get(ctx, secretKey, secret); err != nil {
+		return nil, "", errors.Wrapf(err, "failed to get secret %s", secretKey)
+	}
+
+	key := strings.Join([]string{region, string(infrav1.AWSClusterStaticIdentityKind), identity.Name, string(secret.UID), secret.ResourceVersion}, "/")
+	if s, ok := sessionCache.Load(key); ok {
+		return s.(*session.Session), key, nil
+	}
+
+	accessKeyID, secretAccessKey := string(secret.Data[accessKeyIDKey]), string(secret.Data[secretAccessKeyKey])
+	if accessKeyID == "" || secretAccessKey == "" {
+		return nil, "", errors.Errorf("secret %s must hold the %s and %s keys", secretKey, accessKeyIDKey, secretAccessKeyKey)
+	}
+
+	base, err := sessionForRegion(region, logger)
+	if err != nil {
+		return nil, "", err
+	}
+
+	creds := credentials.NewStaticCredentials(accessKeyID, secretAccessKey, string(secret.Data[sessionTokenKey]))
+	s, _ := sessionCache.LoadOrStore(key, base.Copy(aws.NewConfig().WithCredentials(creds)))
+	return s.(*session.Session), key, nil
+}
+
+// checkNamespaceAllowed returns an error unless the identity allows the
+// namespace, by name or by its labels.
+func checkNamespaceAllowed(ctx context.Context, c client.Client, allowed *infrav1.AllowedNamespaces, namespace string) error {
+	if allowed == nil {
+		return errors.Errorf("the identity doesn't allow any namespace")
+	}
+	if len(allowed.NamespaceList) == 0 && allowed.Selector == nil {
+		return nil
+	}
+
+	for _, name := range allowed.NamespaceList {
+		if name == namespace {
+			return nil
+		}
+	}
+
+	if allowed.Selector != nil {
+		selector, err := metav1.LabelSelectorAsSelector(allowed.Selector)
+		if err != nil {
+			return errors.Wrap(err, "invalid namespace selector")
+		}
+		ns := &corev1.Namespace{}
+		if err := c.Get(ctx, client.ObjectKey{Name: namespace}, ns); err != nil {
+			return errors.Wrapf(err, "failed to get namespace %q", namespace)
+		}
+		if selector.Matches(labels.Set(ns.Labels)) {
+			return nil
+		}
+	}
+
+	return errors.Errorf("the identity doesn't allow namespace %q", namespace)
+}
@@ -0,0 +1,183 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package scope
+
+import (
+	"context"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials/stscreds"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/sts"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/klog/klogr"
+	infrav1 "sigs.k8s.io/cluster-api-provider-aws/api/v1alpha3"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestSessionForIdentity(t *testing.T) {
+	scheme := runtime.NewScheme()
+	_ = clientgoscheme.AddToScheme(scheme)
+	_ = infrav1.AddToScheme(scheme)
+
+	objects := []runtime.Object{
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "tenant-a", Labels: map[string]string{"tenant": "a"}}},
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "tenant-b"}},
+		&corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: "management", Namespace: "capa-system"},
+			Data: map[string][]byte{
+				accessKeyIDKey:     []byte("AKIDSTATIC"),
+				secretAccessKeyKey: []byte("secret"),
+			},
+		},
+		&infrav1.AWSClusterStaticIdentity{
+			ObjectMeta: metav1.ObjectMeta{Name: "management"},
+			Spec: infrav1.AWSClusterStaticIdentitySpec{
+				AWSClusterIdentitySpec: infrav1.AWSClusterIdentitySpec{
+					AllowedNamespaces: &infrav1.AllowedNamespaces{NamespaceList: []string{"tenant-b"}},
+				},
+				SecretRef: corev1.SecretReference{Name: "management", Namespace: "capa-system"},
+			},
+		},
+		&infrav1.AWSClusterRoleIdentity{
+			ObjectMeta: metav1.ObjectMeta{Name: "tenant-a"},
+			Spec: infrav1.AWSClusterRoleIdentitySpec{
+				AWSClusterIdentitySpec: infrav1.AWSClusterIdentitySpec{
+					AllowedNamespaces: &infrav1.AllowedNamespaces{
+						Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"tenant": "a"}},
+					},
+				},
+				AssumeRoleSpec: infrav1.AssumeRoleSpec{
+					RoleARN:    "arn:aws:iam::123456789012:role/capa",
+					ExternalID: "tenant-a",
+				},
+				SourceIdentityRef: &infrav1.AWSIdentityReference{Name: "management", Kind: infrav1.AWSClusterStaticIdentityKind},
+			},
+		},
+		&infrav1.AWSClusterRoleIdentity{
+			ObjectMeta: metav1.ObjectMeta{Name: "nobody"},
+			Spec: infrav1.AWSClusterRoleIdentitySpec{
+				AssumeRoleSpec: infrav1.AssumeRoleSpec{RoleARN: "arn:aws:iam::123456789012:role/capa"},
+			},
+		},
+		&infrav1.AWSClusterRoleIdentity{
+			ObjectMeta: metav1.ObjectMeta{Name: "cycle-a"},
+			Spec: infrav1.AWSClusterRoleIdentitySpec{
+				AWSClusterIdentitySpec: infrav1.AWSClusterIdentitySpec{AllowedNamespaces: &infrav1.AllowedNamespaces{}},
+				AssumeRoleSpec:         infrav1.AssumeRoleSpec{RoleARN: "arn:aws:iam::123456789012:role/capa"},
+				SourceIdentityRef:      &infrav1.AWSIdentityReference{Name: "cycle-b", Kind: infrav1.AWSClusterRoleIdentityKind},
+			},
+		},
+		&infrav1.AWSClusterRoleIdentity{
+			ObjectMeta: metav1.ObjectMeta{Name: "cycle-b"},
+			Spec: infrav1.AWSClusterRoleIdentitySpec{
+				AssumeRoleSpec:    infrav1.AssumeRoleSpec{RoleARN: "arn:aws:iam::123456789012:role/capa"},
+				SourceIdentityRef: &infrav1.AWSIdentityReference{Name: "cycle-a", Kind: infrav1.AWSClusterRoleIdentityKind},
+			},
+		},
+	}
+	c := fake.NewFakeClientWithScheme(scheme, objects...)
+
+	fakeSTS := &fakeAssumeRoler{}
+	var sourceKeys []string
+	newAssumeRoleClient = func(s *session.Session) stscreds.AssumeRoler {
+		creds, _ := s.Config.Credentials.Get()
+		sourceKeys = append(sourceKeys, creds.AccessKeyID)
+		return fakeSTS
+	}
+	defer func() {
+		newAssumeRoleClient = func(s *session.Session) stscreds.AssumeRoler { return sts.New(s) }
+	}()
+
+	testCases := []struct {
+		name      string
+		ref       infrav1.AWSIdentityReference
+		namespace string
+		expectKey string
+		expectErr bool
+	}{
+		{
+			name:      "static identity allowing the namespace by name",
+			ref:       infrav1.AWSIdentityReference{Name: "management", Kind: infrav1.AWSClusterStaticIdentityKind},
+			namespace: "tenant-b",
+			expectKey: "AKIDSTATIC",
+		},
+		{
+			name:      "static identity not allowing the namespace",
+			ref:       infrav1.AWSIdentityReference{Name: "management", Kind: infrav1.AWSClusterStaticIdentityKind},
+			namespace: "tenant-a",
+			expectErr: true,
+		},
+		{
+			name:      "role identity allowing the namespace by labels, assumed with its source identity",
+			ref:       infrav1.AWSIdentityReference{Name: "tenant-a", Kind: infrav1.AWSClusterRoleIdentityKind},
+			namespace: "tenant-a",
+			expectKey: "AKIDASSUMED",
+		},
+		{
+			name:      "role identity without allowed namespaces",
+			ref:       infrav1.AWSIdentityReference{Name: "nobody", Kind: infrav1.AWSClusterRoleIdentityKind},
+			namespace: "tenant-a",
+			expectErr: true,
+		},
+		{
+			name:      "missing identity",
+			ref:       infrav1.AWSIdentityReference{Name: "missing", Kind: infrav1.AWSClusterStaticIdentityKind},
+			namespace: "tenant-a",
+			expectErr: true,
+		},
+		{
+			name:      "identities referencing each other",
+			ref:       infrav1.AWSIdentityReference{Name: "cycle-a", Kind: infrav1.AWSClusterRoleIdentityKind},
+			namespace: "tenant-a",
+			expectErr: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			s, err := sessionForIdentity(context.Background(), c, "eu-north-1", &tc.ref, tc.namespace, "capa-test", klogr.New())
+			if tc.expectErr {
+				if _, ok := err.(*IdentityError); !ok {
+					t.Fatalf("expected an IdentityError, got %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("got an unexpected error: %v", err)
+			}
+			creds, err := s.Config.Credentials.Get()
+			if err != nil {
+				t.Fatalf("got an unexpected error: %v", err)
+			}
+			if creds.AccessKeyID != tc.expectKey {
+				t.Errorf("expected access key %q, got %q", tc.expectKey, creds.AccessKeyID)
+			}
+		})
+	}
+
+	if len(sourceKeys) != 1 || sourceKeys[0] != "AKIDSTATIC" {
+		t.Errorf("expected the role to be assumed once with the static identity, got %v", sourceKeys)
+	}
+	if len(fakeSTS.calls) != 1 || aws.StringValue(fakeSTS.calls[0].ExternalId) != "tenant-a" {
+		t.Errorf("expected the role to be assumed with external ID %q, got %v", "tenant-a", fakeSTS.calls)
+	}
+}
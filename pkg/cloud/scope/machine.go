@@ -27,6 +27,7 @@ import (
 	"k8s.io/klog/klogr"
 	"k8s.io/utils/pointer"
 	infrav1 "sigs.k8s.io/cluster-api-provider-aws/api/v1alpha3"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/services/iam"
 	clusterv1 "sigs.k8s.io/cluster-api/api/v1alpha3"
 	"sigs.k8s.io/cluster-api/controllers/noderefutil"
 	capierrors "sigs.k8s.io/cluster-api/errors"
@@ -120,6 +121,28 @@ func (m *MachineScope) Role() string {
 	return "node"
 }
 
+// IAMInstanceProfile returns the IAM instance profile to assign to the instance.
+// The AWSMachine's own profile takes precedence over the AWSCluster's default
+// for the machine's role.
+func (m *MachineScope) IAMInstanceProfile() string {
+	if m.AWSMachine.Spec.IAMInstanceProfile != "" {
+		return m.AWSMachine.Spec.IAMInstanceProfile
+	}
+	if m.IsControlPlane() {
+		return m.AWSCluster.Spec.ControlPlaneIAMInstanceProfile
+	}
+	return m.AWSCluster.Spec.NodeIAMInstanceProfile
+}
+
+// ControlPlaneIAMInstanceProfile returns the IAM instance profile reserved for
+// control plane machines.
+func (m *MachineScope) ControlPlaneIAMInstanceProfile() string {
+	if m.AWSCluster.Spec.ControlPlaneIAMInstanceProfile != "" {
+		return m.AWSCluster.Spec.ControlPlaneIAMInstanceProfile
+	}
+	return iam.NewManagedName("control-plane")
+}
+
 // GetInstanceID returns the AWSMachine instance id by parsing Spec.ProviderID.
 func (m *MachineScope) GetInstanceID() *string {
 	parsed, err := noderefutil.NewProviderID(m.GetProviderID())
@@ -152,6 +175,16 @@ func (m *MachineScope) SetInstanceState(v infrav1.InstanceState) {
 	m.AWSMachine.Status.InstanceState = &v
 }
 
+// SetInstanceType sets the AWSMachine instance type in the status.
+func (m *MachineScope) SetInstanceType(v string) {
+	m.AWSMachine.Status.InstanceType = v
+}
+
+// SetInstanceProfileAssociationState sets the AWSMachine instance profile association state.
+func (m *MachineScope) SetInstanceProfileAssociationState(v infrav1.InstanceProfileAssociationState) {
+	m.AWSMachine.Status.InstanceProfileAssociation = &v
+}
+
 // SetReady sets the AWSMachine Ready Status
 func (m *MachineScope) SetReady() {
 	m.AWSMachine.Status.Ready = true
@@ -185,8 +218,13 @@ func (m *MachineScope) SetAddresses(addrs []corev1.NodeAddress) {
 	m.AWSMachine.Status.Addresses = addrs
 }
 
-// GetBootstrapData returns the bootstrap data from the secret in the Machine's bootstrap.dataSecretName.
+// GetBootstrapData returns the bootstrap data from the secret in the Machine's bootstrap.dataSecretName,
+// or the raw user data of the AWSMachine's userDataSecret when set.
 func (m *MachineScope) GetBootstrapData() (string, error) {
+	if ref := m.AWSMachine.Spec.UserDataSecret; ref != nil {
+		return m.getUserData(ref)
+	}
+
 	if m.Machine.Spec.Bootstrap.DataSecretName == nil {
 		return "", errors.New("error retrieving bootstrap data: linked Machine's bootstrap.dataSecretName is nil")
 	}
@@ -205,6 +243,42 @@ func (m *MachineScope) GetBootstrapData() (string, error) {
 	return base64.StdEncoding.EncodeToString(value), nil
 }
 
+// SecureSecretsBackend returns the backend the bootstrap data of the instance
+// is stored in instead of its user data, empty when it is not stored.
+func (m *MachineScope) SecureSecretsBackend() infrav1.SecretBackend {
+	if m.AWSMachine.Spec.CloudInit == nil {
+		return ""
+	}
+	return m.AWSMachine.Spec.CloudInit.SecureSecretsBackend
+}
+
+// HasRawUserData returns true if the user data of the instance is read from
+// the user data secret of the AWSMachine instead of the bootstrap provider.
+func (m *MachineScope) HasRawUserData() bool {
+	return m.AWSMachine.Spec.UserDataSecret != nil
+}
+
+// getUserData returns the base64 encoded raw user data of the AWSMachine. The
+// secret is always looked up in the namespace of the AWSMachine.
+func (m *MachineScope) getUserData(ref *infrav1.UserDataSecretReference) (string, error) {
+	secret := &corev1.Secret{}
+	key := types.NamespacedName{Namespace: m.Namespace(), Name: ref.Name}
+	if err := m.client.Get(context.TODO(), key, secret); err != nil {
+		return "", errors.Wrapf(err, "failed to retrieve user data secret for AWSMachine %s/%s", m.Namespace(), m.Name())
+	}
+
+	dataKey := ref.Key
+	if dataKey == "" {
+		dataKey = infrav1.DefaultUserDataSecretKey
+	}
+	value, ok := secret.Data[dataKey]
+	if !ok {
+		return "", errors.Errorf("error retrieving user data: secret %s/%s has no key %q", m.Namespace(), ref.Name, dataKey)
+	}
+
+	return base64.StdEncoding.EncodeToString(value), nil
+}
+
 // Close the MachineScope by updating the machine spec, machine status.
 func (m *MachineScope) Close() error {
 	return m.patchHelper.Patch(context.TODO(), m.AWSMachine)
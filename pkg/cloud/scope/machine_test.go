@@ -0,0 +1,96 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package scope
+
+import (
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	infrav1 "sigs.k8s.io/cluster-api-provider-aws/api/v1alpha3"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1alpha3"
+)
+
+func TestIAMInstanceProfile(t *testing.T) {
+	clusterSpec := infrav1.AWSClusterSpec{
+		ControlPlaneIAMInstanceProfile: "control-plane-profile",
+		NodeIAMInstanceProfile:         "node-profile",
+	}
+
+	testCases := []struct {
+		name         string
+		controlPlane bool
+		machineSpec  infrav1.AWSMachineSpec
+		clusterSpec  infrav1.AWSClusterSpec
+		expected     string
+	}{
+		{
+			name:         "control plane machine gets the control plane profile",
+			controlPlane: true,
+			clusterSpec:  clusterSpec,
+			expected:     "control-plane-profile",
+		},
+		{
+			name:        "worker machine gets the node profile",
+			clusterSpec: clusterSpec,
+			expected:    "node-profile",
+		},
+		{
+			name:         "machine profile overrides the cluster default",
+			controlPlane: true,
+			machineSpec:  infrav1.AWSMachineSpec{IAMInstanceProfile: "machine-profile"},
+			clusterSpec:  clusterSpec,
+			expected:     "machine-profile",
+		},
+		{
+			name:     "no profile without defaults",
+			expected: "",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			machine := &clusterv1.Machine{}
+			if tc.controlPlane {
+				machine.ObjectMeta = metav1.ObjectMeta{
+					Labels: map[string]string{clusterv1.MachineControlPlaneLabelName: "true"},
+				}
+			}
+
+			m := &MachineScope{
+				Machine:    machine,
+				AWSMachine: &infrav1.AWSMachine{Spec: tc.machineSpec},
+				AWSCluster: &infrav1.AWSCluster{Spec: tc.clusterSpec},
+			}
+
+			if got := m.IAMInstanceProfile(); got != tc.expected {
+				t.Fatalf("IAMInstanceProfile() = %q, expected %q", got, tc.expected)
+			}
+		})
+	}
+}
+
+func TestControlPlaneIAMInstanceProfile(t *testing.T) {
+	m := &MachineScope{AWSCluster: &infrav1.AWSCluster{}}
+	if got, expected := m.ControlPlaneIAMInstanceProfile(), "control-plane.cluster-api-provider-aws.sigs.k8s.io"; got != expected {
+		t.Fatalf("ControlPlaneIAMInstanceProfile() = %q, expected %q", got, expected)
+	}
+
+	m.AWSCluster.Spec.ControlPlaneIAMInstanceProfile = "custom"
+	if got, expected := m.ControlPlaneIAMInstanceProfile(), "custom"; got != expected {
+		t.Fatalf("ControlPlaneIAMInstanceProfile() = %q, expected %q", got, expected)
+	}
+}
@@ -0,0 +1,76 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package scope
+
+import (
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/prometheus/client_golang/prometheus"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+// AWSAPIMetrics enables the metrics of the AWS API calls. It must be set
+// before the first session is created.
+var AWSAPIMetrics = true
+
+// awsAPISuccessCode is the code recorded for AWS API calls which succeeded.
+const awsAPISuccessCode = "Success"
+
+var (
+	awsAPIRequestsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "capa_aws_api_requests_total",
+		Help: "Number of AWS API call attempts, retries included, by service, operation and error code.",
+	}, []string{"service", "operation", "code"})
+
+	awsAPIRequestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "capa_aws_api_request_duration_seconds",
+		Help:    "Duration of AWS API call attempts, by service and operation.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"service", "operation"})
+
+	// awsAPIMetricsHandler records every attempt of a request once it is
+	// complete, so that throttled attempts which are retried are counted too.
+	awsAPIMetricsHandler = request.NamedHandler{
+		Name: "capa/awsAPIMetrics",
+		Fn:   recordAWSAPIRequest,
+	}
+)
+
+func init() {
+	metrics.Registry.MustRegister(awsAPIRequestsTotal, awsAPIRequestDuration)
+}
+
+func recordAWSAPIRequest(r *request.Request) {
+	service := r.ClientInfo.ServiceName
+	operation := ""
+	if r.Operation != nil {
+		operation = r.Operation.Name
+	}
+
+	code := awsAPISuccessCode
+	if r.Error != nil {
+		code = "Unknown"
+		if aerr, ok := r.Error.(awserr.Error); ok {
+			code = aerr.Code()
+		}
+	}
+
+	awsAPIRequestsTotal.WithLabelValues(service, operation, code).Inc()
+	awsAPIRequestDuration.WithLabelValues(service, operation).Observe(time.Since(r.AttemptTime).Seconds())
+}
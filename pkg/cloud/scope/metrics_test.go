@@ -0,0 +1,87 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package scope
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/client/metadata"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestRecordAWSAPIRequest(t *testing.T) {
+	testCases := []struct {
+		name     string
+		err      error
+		wantCode string
+	}{
+		{
+			name:     "successful call",
+			wantCode: "Success",
+		},
+		{
+			name:     "throttled call",
+			err:      awserr.New("RequestLimitExceeded", "Request limit exceeded.", nil),
+			wantCode: "RequestLimitExceeded",
+		},
+		{
+			name:     "call failing without an AWS error",
+			err:      errors.New("connection reset"),
+			wantCode: "Unknown",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			counter := awsAPIRequestsTotal.WithLabelValues("ec2", "DescribeInstances", tc.wantCode)
+			before := testutil.ToFloat64(counter)
+
+			recordAWSAPIRequest(&request.Request{
+				ClientInfo:  metadata.ClientInfo{ServiceName: "ec2"},
+				Operation:   &request.Operation{Name: "DescribeInstances"},
+				Error:       tc.err,
+				AttemptTime: time.Now(),
+			})
+
+			if got := testutil.ToFloat64(counter) - before; got != 1 {
+				t.Fatalf("expected the call to be counted once with code %q, got %v", tc.wantCode, got)
+			}
+		})
+	}
+}
+
+func TestNewSessionAWSAPIMetrics(t *testing.T) {
+	defer func(enabled bool) { AWSAPIMetrics = enabled }(AWSAPIMetrics)
+
+	for _, enabled := range []bool{true, false} {
+		AWSAPIMetrics = enabled
+		s, err := newSession("us-east-1", nil)
+		if err != nil {
+			t.Fatalf("failed to create session: %v", err)
+		}
+
+		before := s.Handlers.CompleteAttempt.Len()
+		s.Handlers.CompleteAttempt.RemoveByName(awsAPIMetricsHandler.Name)
+		if registered := before != s.Handlers.CompleteAttempt.Len(); registered != enabled {
+			t.Errorf("expected the metrics handler to be registered %t, got %t", enabled, registered)
+		}
+	}
+}
@@ -17,23 +17,147 @@ limitations under the License.
 package scope
 
 import (
+	"bytes"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
 	"sync"
+	"time"
 
 	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials/stscreds"
 	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/sts"
+	"github.com/go-logr/logr"
+	"github.com/pkg/errors"
+	infrav1 "sigs.k8s.io/cluster-api-provider-aws/api/v1alpha3"
 )
 
+const (
+	// assumeRoleExpiryWindow is how long before they expire assumed role
+	// credentials are refreshed, so that calls never use expired credentials.
+	assumeRoleExpiryWindow = 5 * time.Minute
+
+	// maxRoleSessionNameLength is the maximum length STS accepts for a role
+	// session name.
+	maxRoleSessionNameLength = 64
+)
+
+// newAssumeRoleClient returns the STS client used to assume roles, it is
+// replaced in tests.
+var newAssumeRoleClient = func(s *session.Session) stscreds.AssumeRoler {
+	return sts.New(s)
+}
+
+// AssumeRoleError is returned when the role configured for a cluster can't be
+// assumed, e.g. because its trust policy doesn't allow the controller or the
+// external ID doesn't match.
+type AssumeRoleError struct {
+	RoleARN string
+	Err     error
+}
+
+func (e *AssumeRoleError) Error() string {
+	return fmt.Sprintf("failed to assume role %q: %v", e.RoleARN, e.Err)
+}
+
 var (
 	sessionCache sync.Map
+
+	// sessionTLSOnce guards sessionTLSConfig. It is consumed either by
+	// SetSessionTLSConfig or by the first session being created, whichever
+	// comes first, so the configuration can never change under a cached session.
+	sessionTLSOnce   sync.Once
+	sessionTLSConfig *SessionTLSConfig
 )
 
-func sessionForRegion(region string) (*session.Session, error) {
+// TLSOptions defines how the AWS service clients verify the endpoints they talk to.
+type TLSOptions struct {
+	// CABundleFile is the path to a PEM encoded CA bundle used to verify AWS
+	// endpoints, e.g. a self-signed test endpoint. It is handed to the SDK as
+	// a custom CA bundle, which replaces the system roots rather than adding
+	// to them, the same as setting AWS_CA_BUNDLE.
+	CABundleFile string
+
+	// InsecureSkipVerify disables TLS certificate verification for all AWS
+	// endpoints. This is DANGEROUS and must only ever be used in tests.
+	InsecureSkipVerify bool
+}
+
+// SessionTLSConfig is a validated TLS configuration for AWS sessions.
+// Use NewSessionTLSConfig to build one.
+type SessionTLSConfig struct {
+	caBundle   []byte
+	httpClient *http.Client
+}
+
+// NewSessionTLSConfig validates the given options and builds the TLS
+// configuration used for every AWS session.
+func NewSessionTLSConfig(opts TLSOptions) (*SessionTLSConfig, error) {
+	if opts.CABundleFile != "" && opts.InsecureSkipVerify {
+		return nil, errors.New("a custom CA bundle cannot be used when TLS verification is disabled")
+	}
+
+	cfg := &SessionTLSConfig{}
+
+	if opts.CABundleFile != "" {
+		pem, err := ioutil.ReadFile(opts.CABundleFile)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to read CA bundle %q", opts.CABundleFile)
+		}
+		if !x509.NewCertPool().AppendCertsFromPEM(pem) {
+			return nil, errors.Errorf("no valid certificates found in CA bundle %q", opts.CABundleFile)
+		}
+		cfg.caBundle = pem
+	}
+
+	if opts.InsecureSkipVerify {
+		transport := http.DefaultTransport.(*http.Transport).Clone()
+		transport.TLSClientConfig = &tls.Config{
+			InsecureSkipVerify: true, //nolint:gosec
+		}
+		cfg.httpClient = &http.Client{Transport: transport}
+	}
+
+	return cfg, nil
+}
+
+// InsecureSkipVerify returns true if TLS certificate verification is disabled.
+func (c *SessionTLSConfig) InsecureSkipVerify() bool {
+	return c != nil && c.httpClient != nil
+}
+
+// SetSessionTLSConfig installs the TLS configuration used by all AWS sessions.
+// It can only be called once, and only before the first session is created.
+func SetSessionTLSConfig(cfg *SessionTLSConfig) error {
+	set := false
+	sessionTLSOnce.Do(func() {
+		sessionTLSConfig = cfg
+		set = true
+	})
+	if !set {
+		return errors.New("session TLS configuration can only be set once, before any session is created")
+	}
+	return nil
+}
+
+func sessionForRegion(region string, logger logr.Logger) (*session.Session, error) {
 	s, ok := sessionCache.Load(region)
 	if ok {
 		return s.(*session.Session), nil
 	}
 
-	ns, err := session.NewSession(aws.NewConfig().WithRegion(region))
+	// Freeze the TLS configuration, see sessionTLSOnce.
+	sessionTLSOnce.Do(func() {})
+
+	if sessionTLSConfig.InsecureSkipVerify() {
+		logger.Error(nil, "Creating AWS session with TLS certificate verification DISABLED, this must never be used in production", "region", region)
+	}
+
+	ns, err := newSession(region, sessionTLSConfig)
 	if err != nil {
 		return nil, err
 	}
@@ -41,3 +165,91 @@ func sessionForRegion(region string) (*session.Session, error) {
 	sessionCache.Store(region, ns)
 	return ns, nil
 }
+
+// assumeRoleSession returns a session of the region using the credentials of
+// the given role, assumed with the controller's own credentials. An
+// *AssumeRoleError is returned if the role can't be assumed.
+func assumeRoleSession(region string, spec *infrav1.AssumeRoleSpec, defaultSessionName string, logger logr.Logger) (*session.Session, error) {
+	base, err := sessionForRegion(region, logger)
+	if err != nil {
+		return nil, err
+	}
+
+	s, _, err := assumeRoleFromSession(base, region, spec, defaultSessionName)
+	return s, err
+}
+
+// assumeRoleFromSession returns a session using the credentials of the given
+// role, assumed with the credentials of the base session, and its key in the
+// session cache. baseKey is the cache key of the base session. Sessions are
+// cached so that the credentials are only refreshed when they are about to
+// expire. An *AssumeRoleError is returned if the role can't be assumed.
+func assumeRoleFromSession(base *session.Session, baseKey string, spec *infrav1.AssumeRoleSpec, defaultSessionName string) (*session.Session, string, error) {
+	sessionName := spec.SessionName
+	if sessionName == "" {
+		sessionName = defaultSessionName
+	}
+
+	key := strings.Join([]string{baseKey, spec.RoleARN, spec.ExternalID, sessionName}, "/")
+	s, ok := sessionCache.Load(key)
+	if !ok {
+		creds := stscreds.NewCredentialsWithClient(newAssumeRoleClient(base), spec.RoleARN, func(p *stscreds.AssumeRoleProvider) {
+			p.RoleSessionName = sessionName
+			if spec.ExternalID != "" {
+				p.ExternalID = aws.String(spec.ExternalID)
+			}
+			p.ExpiryWindow = assumeRoleExpiryWindow
+		})
+
+		s, _ = sessionCache.LoadOrStore(key, base.Copy(aws.NewConfig().WithCredentials(creds)))
+	}
+
+	// Retrieve the credentials up front, they are only refreshed from STS when
+	// about to expire, so that a role which can't be assumed is reported here
+	// rather than by every AWS call.
+	ns := s.(*session.Session)
+	if _, err := ns.Config.Credentials.Get(); err != nil {
+		return nil, "", &AssumeRoleError{RoleARN: spec.RoleARN, Err: err}
+	}
+
+	return ns, key, nil
+}
+
+// defaultRoleSessionName returns the role session name of a cluster which
+// doesn't configure one.
+func defaultRoleSessionName(namespace, name string) string {
+	sessionName := fmt.Sprintf("capa-%s-%s", namespace, name)
+	if len(sessionName) > maxRoleSessionNameLength {
+		sessionName = sessionName[:maxRoleSessionNameLength]
+	}
+	return sessionName
+}
+
+func newSession(region string, tlsConfig *SessionTLSConfig) (*session.Session, error) {
+	opts := session.Options{
+		Config: *aws.NewConfig().WithRegion(region),
+	}
+
+	if tlsConfig != nil {
+		if tlsConfig.httpClient != nil {
+			opts.Config.HTTPClient = tlsConfig.httpClient
+		}
+		if tlsConfig.caBundle != nil {
+			// The SDK installs the bundle on the client's transport, give it
+			// its own client so http.DefaultClient is never modified.
+			opts.Config.HTTPClient = &http.Client{}
+			opts.CustomCABundle = bytes.NewReader(tlsConfig.caBundle)
+		}
+	}
+
+	ns, err := session.NewSessionWithOptions(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	if AWSAPIMetrics {
+		ns.Handlers.CompleteAttempt.PushBackNamed(awsAPIMetricsHandler)
+	}
+
+	return ns, nil
+}
@@ -0,0 +1,311 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package scope
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/credentials/stscreds"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/sts"
+	"github.com/pkg/errors"
+	"k8s.io/klog/klogr"
+	infrav1 "sigs.k8s.io/cluster-api-provider-aws/api/v1alpha3"
+)
+
+func writeTestFile(t *testing.T, dir, name string, data []byte) string {
+	path := filepath.Join(dir, name)
+	if err := ioutil.WriteFile(path, data, 0600); err != nil {
+		t.Fatalf("failed to write %s: %v", path, err)
+	}
+	return path
+}
+
+func newTestCertificate(t *testing.T) (*x509.Certificate, []byte) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "localstack.test"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("failed to create certificate: %v", err)
+	}
+
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatalf("failed to parse certificate: %v", err)
+	}
+
+	return cert, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+}
+
+func sessionTransport(t *testing.T, tlsConfig *SessionTLSConfig) *http.Transport {
+	s, err := newSession("us-east-1", tlsConfig)
+	if err != nil {
+		t.Fatalf("failed to create session: %v", err)
+	}
+
+	transport, ok := s.Config.HTTPClient.Transport.(*http.Transport)
+	if !ok || transport == nil {
+		t.Fatalf("expected an *http.Transport, got %T", s.Config.HTTPClient.Transport)
+	}
+	return transport
+}
+
+// unsetEnv unsets the environment variable key and returns a function that
+// restores its previous value.
+func unsetEnv(t *testing.T, key string) func() {
+	value, ok := os.LookupEnv(key)
+	if err := os.Unsetenv(key); err != nil {
+		t.Fatalf("failed to unset %s: %v", key, err)
+	}
+	return func() {
+		if ok {
+			os.Setenv(key, value)
+		}
+	}
+}
+
+func TestNewSessionTLSConfig(t *testing.T) {
+	dir, err := ioutil.TempDir("", "capa-session")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	cert, certPEM := newTestCertificate(t)
+
+	t.Run("valid CA bundle", func(t *testing.T) {
+		cfg, err := NewSessionTLSConfig(TLSOptions{
+			CABundleFile: writeTestFile(t, dir, "ca.pem", certPEM),
+		})
+		if err != nil {
+			t.Fatalf("got an unexpected error: %v", err)
+		}
+
+		transport := sessionTransport(t, cfg)
+		if transport.TLSClientConfig == nil || transport.TLSClientConfig.RootCAs == nil {
+			t.Fatal("expected RootCAs to be set")
+		}
+
+		found := false
+		for _, subject := range transport.TLSClientConfig.RootCAs.Subjects() { //nolint:staticcheck
+			if bytes.Equal(subject, cert.RawSubject) {
+				found = true
+			}
+		}
+		if !found {
+			t.Error("expected RootCAs to contain the CA bundle certificate")
+		}
+		if transport.TLSClientConfig.InsecureSkipVerify {
+			t.Error("expected TLS verification to be enabled")
+		}
+	})
+
+	t.Run("missing CA bundle", func(t *testing.T) {
+		_, err := NewSessionTLSConfig(TLSOptions{
+			CABundleFile: filepath.Join(dir, "does-not-exist.pem"),
+		})
+		if err == nil {
+			t.Fatal("expected an error")
+		}
+		if !os.IsNotExist(errors.Cause(err)) {
+			t.Errorf("expected a wrapped not exist error, got %v", err)
+		}
+	})
+
+	t.Run("CA bundle without certificates", func(t *testing.T) {
+		_, err := NewSessionTLSConfig(TLSOptions{
+			CABundleFile: writeTestFile(t, dir, "invalid.pem", []byte("not a certificate")),
+		})
+		if err == nil || !strings.Contains(err.Error(), "no valid certificates") {
+			t.Errorf("expected a no valid certificates error, got %v", err)
+		}
+	})
+
+	t.Run("CA bundle with insecure skip verify", func(t *testing.T) {
+		_, err := NewSessionTLSConfig(TLSOptions{
+			CABundleFile:       writeTestFile(t, dir, "ca-insecure.pem", certPEM),
+			InsecureSkipVerify: true,
+		})
+		if err == nil {
+			t.Error("expected an error")
+		}
+	})
+
+	t.Run("insecure skip verify", func(t *testing.T) {
+		// The session loads AWS_CA_BUNDLE into RootCAs.
+		defer unsetEnv(t, "AWS_CA_BUNDLE")()
+
+		cfg, err := NewSessionTLSConfig(TLSOptions{InsecureSkipVerify: true})
+		if err != nil {
+			t.Fatalf("got an unexpected error: %v", err)
+		}
+		if !cfg.InsecureSkipVerify() {
+			t.Error("expected InsecureSkipVerify to be true")
+		}
+
+		transport := sessionTransport(t, cfg)
+		if transport.TLSClientConfig == nil || !transport.TLSClientConfig.InsecureSkipVerify {
+			t.Fatal("expected TLS verification to be disabled")
+		}
+		if transport.TLSClientConfig.RootCAs != nil {
+			t.Error("expected RootCAs to be nil")
+		}
+	})
+
+	t.Run("default options", func(t *testing.T) {
+		defer unsetEnv(t, "AWS_CA_BUNDLE")()
+
+		cfg, err := NewSessionTLSConfig(TLSOptions{})
+		if err != nil {
+			t.Fatalf("got an unexpected error: %v", err)
+		}
+		if cfg.InsecureSkipVerify() {
+			t.Error("expected InsecureSkipVerify to be false")
+		}
+
+		for _, c := range []*SessionTLSConfig{nil, cfg} {
+			s, err := newSession("us-east-1", c)
+			if err != nil {
+				t.Fatalf("failed to create session: %v", err)
+			}
+			if s.Config.HTTPClient != http.DefaultClient {
+				t.Error("expected the default HTTP client to be used")
+			}
+		}
+
+		s, err := sessionForRegion("us-west-2", klogr.New())
+		if err != nil {
+			t.Fatalf("failed to create session: %v", err)
+		}
+		if s.Config.HTTPClient != http.DefaultClient {
+			t.Error("expected the default HTTP client to be used")
+		}
+		if err := SetSessionTLSConfig(cfg); err == nil {
+			t.Error("expected setting the TLS configuration after a session was created to fail")
+		}
+	})
+}
+
+type fakeAssumeRoler struct {
+	calls  []*sts.AssumeRoleInput
+	errors []error
+}
+
+func (f *fakeAssumeRoler) AssumeRole(input *sts.AssumeRoleInput) (*sts.AssumeRoleOutput, error) {
+	f.calls = append(f.calls, input)
+	if len(f.errors) > 0 {
+		err := f.errors[0]
+		f.errors = f.errors[1:]
+		if err != nil {
+			return nil, err
+		}
+	}
+	return &sts.AssumeRoleOutput{
+		Credentials: &sts.Credentials{
+			AccessKeyId:     aws.String("AKIDASSUMED"),
+			SecretAccessKey: aws.String("secret"),
+			SessionToken:    aws.String("token"),
+			Expiration:      aws.Time(time.Now().Add(time.Hour)),
+		},
+	}, nil
+}
+
+func TestAssumeRoleSession(t *testing.T) {
+	fake := &fakeAssumeRoler{
+		errors: []error{awserr.New("AccessDenied", "not authorized to perform sts:AssumeRole", nil)},
+	}
+	newAssumeRoleClient = func(*session.Session) stscreds.AssumeRoler { return fake }
+	defer func() {
+		newAssumeRoleClient = func(s *session.Session) stscreds.AssumeRoler { return sts.New(s) }
+	}()
+
+	spec := &infrav1.AssumeRoleSpec{
+		RoleARN:    "arn:aws:iam::123456789012:role/capa",
+		ExternalID: "external-id",
+	}
+
+	_, err := assumeRoleSession("eu-north-1", spec, defaultRoleSessionName("default", "test-cluster"), klogr.New())
+	if _, ok := err.(*AssumeRoleError); !ok {
+		t.Fatalf("expected an AssumeRoleError, got %v", err)
+	}
+
+	for i := 0; i < 2; i++ {
+		s, err := assumeRoleSession("eu-north-1", spec, defaultRoleSessionName("default", "test-cluster"), klogr.New())
+		if err != nil {
+			t.Fatalf("got an unexpected error: %v", err)
+		}
+		creds, err := s.Config.Credentials.Get()
+		if err != nil {
+			t.Fatalf("got an unexpected error: %v", err)
+		}
+		if creds.AccessKeyID != "AKIDASSUMED" {
+			t.Errorf("expected the assumed credentials to be used, got access key %q", creds.AccessKeyID)
+		}
+	}
+
+	// The role is assumed again after the failure, then the credentials are
+	// reused until they are about to expire.
+	if len(fake.calls) != 2 {
+		t.Fatalf("expected the role to be assumed 2 times, got %d", len(fake.calls))
+	}
+	input := fake.calls[1]
+	if aws.StringValue(input.RoleArn) != spec.RoleARN {
+		t.Errorf("expected role %q to be assumed, got %q", spec.RoleARN, aws.StringValue(input.RoleArn))
+	}
+	if aws.StringValue(input.ExternalId) != spec.ExternalID {
+		t.Errorf("expected external ID %q, got %q", spec.ExternalID, aws.StringValue(input.ExternalId))
+	}
+	if aws.StringValue(input.RoleSessionName) != "capa-default-test-cluster" {
+		t.Errorf("expected the default session name, got %q", aws.StringValue(input.RoleSessionName))
+	}
+}
+
+func TestDefaultRoleSessionName(t *testing.T) {
+	name := defaultRoleSessionName("default", strings.Repeat("a", 100))
+	if len(name) != maxRoleSessionNameLength {
+		t.Errorf("expected the session name to be truncated to %d characters, got %d", maxRoleSessionNameLength, len(name))
+	}
+}
@@ -26,7 +26,9 @@ import (
 	"github.com/pkg/errors"
 	"k8s.io/klog"
 
+	infrav1 "sigs.k8s.io/cluster-api-provider-aws/api/v1alpha3"
 	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/awserrors"
+	awspartition "sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/partition"
 	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/services/iam"
 )
 
@@ -40,14 +42,16 @@ const (
 var ManagedIAMPolicyNames = [...]string{ControllersPolicy, ControlPlanePolicy, NodePolicy}
 
 // BootstrapTemplate is an AWS CloudFormation template to bootstrap
-// IAM policies, users and roles for use by Cluster API Provider AWS
-func BootstrapTemplate(accountID, partition string, extraControlPlanePolicies, extraNodePolicies []string) *cloudformation.Template {
+// IAM policies, users and roles for use by Cluster API Provider AWS.
+// The config customizes the roles and instance profiles. The policy
+// conditions, if any, are added to the Allow statements of the managed policies.
+func BootstrapTemplate(accountID, partition string, config BootstrapConfig, policyConditions iam.Conditions) *cloudformation.Template {
 	template := cloudformation.NewTemplate()
 
 	template.Resources[ControllersPolicy] = &cfn_iam.ManagedPolicy{
 		ManagedPolicyName: iam.NewManagedName("controllers"),
 		Description:       `For the Kubernetes Cluster API Provider AWS Controllers`,
-		PolicyDocument:    controllersPolicy(accountID, partition),
+		PolicyDocument:    withConditions(controllersPolicy(accountID, partition, config), policyConditions),
 		Groups: []string{
 			cloudformation.Ref("AWSIAMGroupBootstrapper"),
 		},
@@ -60,7 +64,7 @@ func BootstrapTemplate(accountID, partition string, extraControlPlanePolicies, e
 	template.Resources[ControlPlanePolicy] = &cfn_iam.ManagedPolicy{
 		ManagedPolicyName: iam.NewManagedName("control-plane"),
 		Description:       `For the Kubernetes Cloud Provider AWS Control Plane`,
-		PolicyDocument:    cloudProviderControlPlaneAwsPolicy(),
+		PolicyDocument:    withConditions(cloudProviderControlPlaneAwsPolicy(accountID, partition), policyConditions),
 		Roles: []string{
 			cloudformation.Ref("AWSIAMRoleControlPlane"),
 		},
@@ -69,7 +73,7 @@ func BootstrapTemplate(accountID, partition string, extraControlPlanePolicies, e
 	template.Resources[NodePolicy] = &cfn_iam.ManagedPolicy{
 		ManagedPolicyName: iam.NewManagedName("nodes"),
 		Description:       `For the Kubernetes Cloud Provider AWS nodes`,
-		PolicyDocument:    cloudProviderNodeAwsPolicy(),
+		PolicyDocument:    withConditions(cloudProviderNodeAwsPolicy(accountID, partition), policyConditions),
 		Roles: []string{
 			cloudformation.Ref("AWSIAMRoleControlPlane"),
 			cloudformation.Ref("AWSIAMRoleNodes"),
@@ -88,38 +92,42 @@ func BootstrapTemplate(accountID, partition string, extraControlPlanePolicies, e
 	}
 
 	template.Resources["AWSIAMRoleControlPlane"] = &cfn_iam.Role{
-		RoleName:                 iam.NewManagedName("control-plane"),
-		AssumeRolePolicyDocument: ec2AssumeRolePolicy(),
-		ManagedPolicyArns:        extraControlPlanePolicies,
+		RoleName:                 config.ControlPlane.roleName("control-plane"),
+		AssumeRolePolicyDocument: ec2AssumeRolePolicy(partition),
+		ManagedPolicyArns:        config.ControlPlane.ExtraPolicyARNs,
+		Policies:                 config.ControlPlane.inlinePolicies(),
 	}
 
 	template.Resources["AWSIAMRoleControllers"] = &cfn_iam.Role{
-		RoleName:                 iam.NewManagedName("controllers"),
-		AssumeRolePolicyDocument: ec2AssumeRolePolicy(),
+		RoleName:                 config.Controllers.roleName("controllers"),
+		AssumeRolePolicyDocument: ec2AssumeRolePolicy(partition),
+		ManagedPolicyArns:        config.Controllers.ExtraPolicyARNs,
+		Policies:                 config.Controllers.inlinePolicies(),
 	}
 
 	template.Resources["AWSIAMRoleNodes"] = &cfn_iam.Role{
-		RoleName:                 iam.NewManagedName("nodes"),
-		AssumeRolePolicyDocument: ec2AssumeRolePolicy(),
-		ManagedPolicyArns:        extraNodePolicies,
+		RoleName:                 config.Nodes.roleName("nodes"),
+		AssumeRolePolicyDocument: ec2AssumeRolePolicy(partition),
+		ManagedPolicyArns:        config.Nodes.ExtraPolicyARNs,
+		Policies:                 config.Nodes.inlinePolicies(),
 	}
 
 	template.Resources["AWSIAMInstanceProfileControlPlane"] = &cfn_iam.InstanceProfile{
-		InstanceProfileName: iam.NewManagedName("control-plane"),
+		InstanceProfileName: config.ControlPlane.instanceProfileName("control-plane"),
 		Roles: []string{
 			cloudformation.Ref("AWSIAMRoleControlPlane"),
 		},
 	}
 
 	template.Resources["AWSIAMInstanceProfileControllers"] = &cfn_iam.InstanceProfile{
-		InstanceProfileName: iam.NewManagedName("controllers"),
+		InstanceProfileName: config.Controllers.instanceProfileName("controllers"),
 		Roles: []string{
 			cloudformation.Ref("AWSIAMRoleControllers"),
 		},
 	}
 
 	template.Resources["AWSIAMInstanceProfileNodes"] = &cfn_iam.InstanceProfile{
-		InstanceProfileName: iam.NewManagedName("nodes"),
+		InstanceProfileName: config.Nodes.instanceProfileName("nodes"),
 		Roles: []string{
 			cloudformation.Ref("AWSIAMRoleNodes"),
 		},
@@ -128,20 +136,31 @@ func BootstrapTemplate(accountID, partition string, extraControlPlanePolicies, e
 	return template
 }
 
-func ec2AssumeRolePolicy() *iam.PolicyDocument {
+func ec2AssumeRolePolicy(partition string) *iam.PolicyDocument {
 	return &iam.PolicyDocument{
 		Version: iam.CurrentVersion,
 		Statement: []iam.StatementEntry{
 			{
 				Effect:    "Allow",
-				Principal: iam.Principals{"Service": iam.PrincipalID{"ec2.amazonaws.com"}},
+				Principal: iam.Principals{"Service": iam.PrincipalID{awspartition.ServicePrincipal(partition, "ec2")}},
 				Action:    iam.Actions{"sts:AssumeRole"},
 			},
 		},
 	}
 }
 
-func controllersPolicy(accountID, partition string) *iam.PolicyDocument {
+// ControllersPolicyActions returns the IAM actions the controllers need to be
+// allowed on all resources.
+func ControllersPolicyActions() []string {
+	return controllersPolicy("", "", BootstrapConfig{}).Statement[0].Action
+}
+
+func controllersPolicy(accountID, partition string, config BootstrapConfig) *iam.PolicyDocument {
+	passRoles := iam.Resources{awspartition.ARN(partition, "iam", "", accountID, "role/"+iam.NewManagedName("*"))}
+	for _, name := range config.customRoleNames() {
+		passRoles = append(passRoles, awspartition.ARN(partition, "iam", "", accountID, "role/"+name))
+	}
+
 	return &iam.PolicyDocument{
 		Version: iam.CurrentVersion,
 		Statement: []iam.StatementEntry{
@@ -149,70 +168,131 @@ func controllersPolicy(accountID, partition string) *iam.PolicyDocument {
 				Effect:   iam.EffectAllow,
 				Resource: iam.Resources{"*"},
 				Action: iam.Actions{
+					"ec2:AcceptVpcPeeringConnection",
 					"ec2:AllocateAddress",
+					"ec2:AssociateAddress",
+					"ec2:AssociateDhcpOptions",
 					"ec2:AssociateRouteTable",
+					"ec2:AssociateSubnetCidrBlock",
+					"ec2:AssociateVpcCidrBlock",
 					"ec2:AttachInternetGateway",
 					"ec2:AuthorizeSecurityGroupIngress",
+					"ec2:CancelSpotInstanceRequests",
+					"ec2:CreateDhcpOptions",
+					"ec2:CreateEgressOnlyInternetGateway",
 					"ec2:CreateInternetGateway",
 					"ec2:CreateNatGateway",
+					"ec2:CreateNetworkAcl",
+					"ec2:CreateNetworkAclEntry",
+					"ec2:CreatePlacementGroup",
 					"ec2:CreateRoute",
 					"ec2:CreateRouteTable",
 					"ec2:CreateSecurityGroup",
 					"ec2:CreateSubnet",
 					"ec2:CreateTags",
+					"ec2:CreateTransitGatewayVpcAttachment",
 					"ec2:CreateVpc",
+					"ec2:CreateVpcEndpoint",
+					"ec2:CreateVpcPeeringConnection",
 					"ec2:ModifyVpcAttribute",
+					"ec2:DeleteDhcpOptions",
+					"ec2:DeleteEgressOnlyInternetGateway",
 					"ec2:DeleteInternetGateway",
 					"ec2:DeleteNatGateway",
+					"ec2:DeleteNetworkAcl",
+					"ec2:DeleteNetworkAclEntry",
+					"ec2:DeleteRoute",
 					"ec2:DeleteRouteTable",
 					"ec2:DeleteSecurityGroup",
 					"ec2:DeleteSubnet",
 					"ec2:DeleteTags",
+					"ec2:DeleteTransitGatewayVpcAttachment",
 					"ec2:DeleteVpc",
+					"ec2:DeleteVpcEndpoints",
+					"ec2:DeleteVpcPeeringConnection",
 					"ec2:DescribeAccountAttributes",
 					"ec2:DescribeAddresses",
+					"ec2:DescribeCapacityReservations",
 					"ec2:DescribeAvailabilityZones",
+					"ec2:DescribeDhcpOptions",
+					"ec2:DescribeEgressOnlyInternetGateways",
+					"ec2:DescribeIamInstanceProfileAssociations",
+					"ec2:DescribeInstanceAttribute",
 					"ec2:DescribeInstances",
 					"ec2:DescribeInternetGateways",
 					"ec2:DescribeImages",
 					"ec2:DescribeNatGateways",
+					"ec2:DescribeNetworkAcls",
 					"ec2:DescribeNetworkInterfaces",
 					"ec2:DescribeNetworkInterfaceAttribute",
+					"ec2:DescribePlacementGroups",
 					"ec2:DescribeRouteTables",
 					"ec2:DescribeSecurityGroups",
+					"ec2:DescribeSpotInstanceRequests",
+					"ec2:DescribeSpotPriceHistory",
 					"ec2:DescribeSubnets",
+					"ec2:DescribeTransitGatewayVpcAttachments",
 					"ec2:DescribeVpcs",
 					"ec2:DescribeVpcAttribute",
+					"ec2:DescribeVpcEndpoints",
+					"ec2:DescribeVpcPeeringConnections",
 					"ec2:DescribeVolumes",
+					"ec2:DescribeVolumesModifications",
 					"ec2:DetachInternetGateway",
 					"ec2:DisassociateRouteTable",
 					"ec2:DisassociateAddress",
+					"ec2:DisassociateVpcCidrBlock",
 					"ec2:ModifyInstanceAttribute",
+					"ec2:ModifyInstanceMetadataOptions",
 					"ec2:ModifyNetworkInterfaceAttribute",
 					"ec2:ModifySubnetAttribute",
+					"ec2:ModifyTransitGatewayVpcAttachment",
+					"ec2:ModifyVolume",
+					"ec2:ModifyVpcEndpoint",
 					"ec2:ReleaseAddress",
+					"ec2:ReplaceRoute",
+					"ec2:ReplaceNetworkAclAssociation",
+					"ec2:ReplaceNetworkAclEntry",
 					"ec2:RevokeSecurityGroupIngress",
 					"ec2:RunInstances",
 					"ec2:TerminateInstances",
+					"ec2:UpdateSecurityGroupRuleDescriptionsIngress",
 					"tag:GetResources",
+					"cloudwatch:DeleteAlarms",
+					"cloudwatch:PutMetricAlarm",
+					"cloudwatch:TagResource",
 					"elasticloadbalancing:AddTags",
+					"elasticloadbalancing:CreateListener",
 					"elasticloadbalancing:CreateLoadBalancer",
+					"elasticloadbalancing:CreateLoadBalancerPolicy",
+					"elasticloadbalancing:CreateTargetGroup",
 					"elasticloadbalancing:ConfigureHealthCheck",
 					"elasticloadbalancing:DeleteLoadBalancer",
+					"elasticloadbalancing:DeleteLoadBalancerPolicy",
+					"elasticloadbalancing:DeleteTargetGroup",
+					"elasticloadbalancing:DeregisterInstancesFromLoadBalancer",
+					"elasticloadbalancing:DeregisterTargets",
+					"elasticloadbalancing:DescribeListeners",
 					"elasticloadbalancing:DescribeLoadBalancers",
 					"elasticloadbalancing:DescribeLoadBalancerAttributes",
 					"elasticloadbalancing:DescribeTags",
+					"elasticloadbalancing:DescribeTargetGroups",
 					"elasticloadbalancing:ModifyLoadBalancerAttributes",
 					"elasticloadbalancing:RegisterInstancesWithLoadBalancer",
+					"elasticloadbalancing:RegisterTargets",
 					"elasticloadbalancing:RemoveTags",
+					"elasticloadbalancing:SetLoadBalancerPoliciesForBackendServer",
+					"iam:GetInstanceProfile",
+					"route53:ChangeResourceRecordSets",
+					"route53:ListResourceRecordSets",
+					"ssm:GetParameter",
+					"sts:AssumeRole",
 				},
 			},
 			{
 				Effect: iam.EffectAllow,
-				Resource: iam.Resources{fmt.Sprintf(
-					"arn:%s:iam::%s:role/aws-service-role/elasticloadbalancing.amazonaws.com/AWSServiceRoleForElasticLoadBalancing",
-					partition,
-					accountID,
+				Resource: iam.Resources{awspartition.ARN(partition, "iam", "", accountID,
+					"role/aws-service-role/elasticloadbalancing.amazonaws.com/AWSServiceRoleForElasticLoadBalancing",
 				)},
 				Action: iam.Actions{
 					"iam:CreateServiceLinkedRole",
@@ -222,23 +302,49 @@ func controllersPolicy(accountID, partition string) *iam.PolicyDocument {
 				},
 			},
 			{
-				Effect: iam.EffectAllow,
-				Resource: iam.Resources{fmt.Sprintf(
-					"arn:%s:iam::%s:role/%s",
-					partition,
-					accountID,
-					iam.NewManagedName("*"),
-				)},
+				Effect:   iam.EffectAllow,
+				Resource: passRoles,
 				Action: iam.Actions{
 					"iam:PassRole",
 				},
 			},
+			{
+				Effect:   iam.EffectAllow,
+				Resource: iam.Resources{bootstrapDataParameters(accountID, partition, "*")},
+				Action: iam.Actions{
+					"ssm:DeleteParameters",
+					"ssm:GetParametersByPath",
+					"ssm:PutParameter",
+				},
+			},
+			{
+				Effect:   iam.EffectAllow,
+				Resource: iam.Resources{bootstrapDataSecrets(accountID, partition, "*")},
+				Action: iam.Actions{
+					"secretsmanager:CreateSecret",
+					"secretsmanager:DeleteSecret",
+					"secretsmanager:PutSecretValue",
+					"secretsmanager:TagResource",
+				},
+			},
 		},
 	}
 }
 
+// bootstrapDataParameters returns the ARN of the SSM parameters holding the
+// bootstrap data of the machines of the given role.
+func bootstrapDataParameters(accountID, partition, role string) string {
+	return awspartition.ARN(partition, "ssm", "*", accountID, "parameter"+path.Join(infrav1.BootstrapDataParameterPrefix, role, "*"))
+}
+
+// bootstrapDataSecrets returns the ARN of the Secrets Manager secrets holding
+// the bootstrap data of the machines of the given role.
+func bootstrapDataSecrets(accountID, partition, role string) string {
+	return awspartition.ARN(partition, "secretsmanager", "*", accountID, "secret:"+path.Join(infrav1.BootstrapDataSecretPrefix, role, "*"))
+}
+
 // From https://github.com/kubernetes/cloud-provider-aws
-func cloudProviderControlPlaneAwsPolicy() *iam.PolicyDocument {
+func cloudProviderControlPlaneAwsPolicy(accountID, partition string) *iam.PolicyDocument {
 	return &iam.PolicyDocument{
 		Version: iam.CurrentVersion,
 		Statement: []iam.StatementEntry{
@@ -303,12 +409,24 @@ func cloudProviderControlPlaneAwsPolicy() *iam.PolicyDocument {
 					"kms:DescribeKey",
 				},
 			},
+			// Lets control plane instances fetch their bootstrap data.
+			{
+				Effect: iam.EffectAllow,
+				Resource: iam.Resources{
+					bootstrapDataParameters(accountID, partition, "control-plane"),
+					bootstrapDataSecrets(accountID, partition, "control-plane"),
+				},
+				Action: iam.Actions{
+					"secretsmanager:GetSecretValue",
+					"ssm:GetParameter",
+				},
+			},
 		},
 	}
 }
 
 // From https://github.com/kubernetes/cloud-provider-aws
-func cloudProviderNodeAwsPolicy() *iam.PolicyDocument {
+func cloudProviderNodeAwsPolicy(accountID, partition string) *iam.PolicyDocument {
 	return &iam.PolicyDocument{
 		Version: iam.CurrentVersion,
 		Statement: []iam.StatementEntry{
@@ -327,30 +445,99 @@ func cloudProviderNodeAwsPolicy() *iam.PolicyDocument {
 					"ecr:BatchGetImage",
 				},
 			},
+			// Lets the SSM agent register the instance and open Session
+			// Manager sessions, for clusters using Session Manager instead
+			// of a bastion host.
+			{
+				Effect:   iam.EffectAllow,
+				Resource: iam.Resources{"*"},
+				Action: iam.Actions{
+					"ssm:UpdateInstanceInformation",
+					"ssmmessages:CreateControlChannel",
+					"ssmmessages:CreateDataChannel",
+					"ssmmessages:OpenControlChannel",
+					"ssmmessages:OpenDataChannel",
+					"ec2messages:AcknowledgeMessage",
+					"ec2messages:DeleteMessage",
+					"ec2messages:FailMessage",
+					"ec2messages:GetEndpoint",
+					"ec2messages:GetMessages",
+					"ec2messages:SendReply",
+				},
+			},
+			// Lets worker instances fetch their bootstrap data.
+			{
+				Effect: iam.EffectAllow,
+				Resource: iam.Resources{
+					bootstrapDataParameters(accountID, partition, "node"),
+					bootstrapDataSecrets(accountID, partition, "node"),
+				},
+				Action: iam.Actions{
+					"secretsmanager:GetSecretValue",
+					"ssm:GetParameter",
+				},
+			},
 		},
 	}
 }
 
-func getPolicyDocFromPolicyName(policyName, accountID, partition string) (*iam.PolicyDocument, error) {
+func getPolicyDocFromPolicyName(policyName, accountID, partition string, config BootstrapConfig, policyConditions iam.Conditions) (*iam.PolicyDocument, error) {
 	switch policyName {
 	case ControllersPolicy:
-		return controllersPolicy(accountID, partition), nil
+		return withConditions(controllersPolicy(accountID, partition, config), policyConditions), nil
 	case ControlPlanePolicy:
-		return cloudProviderControlPlaneAwsPolicy(), nil
+		return withConditions(cloudProviderControlPlaneAwsPolicy(accountID, partition), policyConditions), nil
 	case NodePolicy:
-		return cloudProviderNodeAwsPolicy(), nil
+		return withConditions(cloudProviderNodeAwsPolicy(accountID, partition), policyConditions), nil
 	}
 	return nil, fmt.Errorf("PolicyName %q did not match with any ManagedIAMPolicy", policyName)
 }
 
+// withConditions adds the conditions to the Allow statements of the policy document.
+// This scopes the permissions, e.g. to resources carrying a given tag.
+func withConditions(doc *iam.PolicyDocument, conditions iam.Conditions) *iam.PolicyDocument {
+	if len(conditions) == 0 {
+		return doc
+	}
+	for i := range doc.Statement {
+		if doc.Statement[i].Effect == iam.EffectAllow {
+			doc.Statement[i].Condition = doc.Statement[i].Condition.Merge(conditions)
+		}
+	}
+	return doc
+}
+
+// ValidateManagedIAMPolicyDocuments checks that the bootstrap config is valid
+// and that all ManagedIAMPolicy documents, with the policy conditions added,
+// are valid managed policies.
+func ValidateManagedIAMPolicyDocuments(accountID, partition string, config BootstrapConfig, policyConditions iam.Conditions) error {
+	if err := config.Validate(); err != nil {
+		return err
+	}
+	for _, pn := range ManagedIAMPolicyNames {
+		pd, err := getPolicyDocFromPolicyName(pn, accountID, partition, config, policyConditions)
+		if err != nil {
+			return err
+		}
+		if err := pd.Validate(); err != nil {
+			return errors.Wrapf(err, "invalid policy document for ManagedIAMPolicy %q", pn)
+		}
+	}
+	return nil
+}
+
 // GenerateManagedIAMPolicyDocuments generates JSON representation of policy documents for all ManagedIAMPolicy
-func (s *Service) GenerateManagedIAMPolicyDocuments(policyDocDir, accountID, partition string) error {
+func (s *Service) GenerateManagedIAMPolicyDocuments(policyDocDir, accountID, partition string, config BootstrapConfig, policyConditions iam.Conditions) error {
 	for _, pn := range ManagedIAMPolicyNames {
-		pd, err := getPolicyDocFromPolicyName(pn, accountID, partition)
+		pd, err := getPolicyDocFromPolicyName(pn, accountID, partition, config, policyConditions)
 		if err != nil {
 			return fmt.Errorf("Error: failed to get PolicyDocument for ManagedIAMPolicy %q, %v", pn, err)
 		}
 
+		if err := pd.Validate(); err != nil {
+			return fmt.Errorf("Error: invalid PolicyDocument for ManagedIAMPolicy %q, %v", pn, err)
+		}
+
 		pds, err := pd.JSON()
 		if err != nil {
 			return fmt.Errorf("ERROR: failed to marshal policy document for ManagedIAMPolicy %q: %v", pn, err)
@@ -366,9 +553,12 @@ func (s *Service) GenerateManagedIAMPolicyDocuments(policyDocDir, accountID, par
 }
 
 // ReconcileBootstrapStack creates or updates bootstrap CloudFormation
-func (s *Service) ReconcileBootstrapStack(stackName, accountID, partition string, extraControlPlanePolicies, extraNodePolicies []string) error {
+func (s *Service) ReconcileBootstrapStack(stackName, accountID, partition string, config BootstrapConfig, policyConditions iam.Conditions, correctDrift bool) error {
+	if err := ValidateManagedIAMPolicyDocuments(accountID, partition, config, policyConditions); err != nil {
+		return err
+	}
 
-	template := BootstrapTemplate(accountID, partition, extraControlPlanePolicies, extraNodePolicies)
+	template := BootstrapTemplate(accountID, partition, config, policyConditions)
 	yaml, err := template.YAML()
 	processedYaml := string(yaml)
 	if err != nil {
@@ -386,6 +576,9 @@ func (s *Service) ReconcileBootstrapStack(stackName, accountID, partition string
 					return updateErr
 				}
 			}
+			if correctDrift {
+				return s.correctStackDrift(stackName)
+			}
 			return nil
 		}
 		return err
@@ -0,0 +1,162 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cloudformation
+
+import (
+	"io/ioutil"
+	"regexp"
+
+	"github.com/aws/aws-sdk-go/aws/arn"
+	cfn_iam "github.com/awslabs/goformation/v3/cloudformation/iam"
+	"github.com/pkg/errors"
+	"sigs.k8s.io/yaml"
+
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/services/iam"
+)
+
+var (
+	// reRoleName matches the names IAM accepts for roles and instance profiles.
+	reRoleName = regexp.MustCompile(`^[\w+=,.@-]+$`)
+
+	maxRoleNameLength            = 64
+	maxInstanceProfileNameLength = 128
+)
+
+// BootstrapConfig customizes the IAM resources created by the bootstrap
+// template. The zero value creates the default resources.
+type BootstrapConfig struct {
+	// ControlPlane customizes the control plane role and instance profile.
+	ControlPlane BootstrapRoleConfig `json:"controlPlane,omitempty"`
+
+	// Controllers customizes the controllers role and instance profile.
+	Controllers BootstrapRoleConfig `json:"controllers,omitempty"`
+
+	// Nodes customizes the nodes role and instance profile.
+	Nodes BootstrapRoleConfig `json:"nodes,omitempty"`
+}
+
+// BootstrapRoleConfig customizes an IAM role and its instance profile.
+type BootstrapRoleConfig struct {
+	// RoleName overrides the name of the role.
+	RoleName string `json:"roleName,omitempty"`
+
+	// InstanceProfileName overrides the name of the instance profile.
+	InstanceProfileName string `json:"instanceProfileName,omitempty"`
+
+	// ExtraPolicyARNs are existing managed policies to attach to the role.
+	ExtraPolicyARNs []string `json:"extraPolicyARNs,omitempty"`
+
+	// ExtraStatements are added to the role as an inline policy, e.g. to
+	// deny actions the managed policies allow.
+	ExtraStatements iam.Statements `json:"extraStatements,omitempty"`
+}
+
+// LoadBootstrapConfig reads a BootstrapConfig from a YAML or JSON file.
+func LoadBootstrapConfig(path string) (BootstrapConfig, error) {
+	config := BootstrapConfig{}
+
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return config, errors.Wrapf(err, "failed to read bootstrap config %q", path)
+	}
+	if err := yaml.UnmarshalStrict(b, &config); err != nil {
+		return config, errors.Wrapf(err, "failed to parse bootstrap config %q", path)
+	}
+	return config, nil
+}
+
+// Validate checks the names, policy ARNs and statements of each role.
+func (c BootstrapConfig) Validate() error {
+	roles := map[string]BootstrapRoleConfig{
+		"controlPlane": c.ControlPlane,
+		"controllers":  c.Controllers,
+		"nodes":        c.Nodes,
+	}
+	for name, role := range roles {
+		if err := role.validate(); err != nil {
+			return errors.Wrapf(err, "invalid bootstrap config for %s", name)
+		}
+	}
+	return nil
+}
+
+func (r BootstrapRoleConfig) validate() error {
+	if r.RoleName != "" && (len(r.RoleName) > maxRoleNameLength || !reRoleName.MatchString(r.RoleName)) {
+		return errors.Errorf("invalid role name %q", r.RoleName)
+	}
+	if r.InstanceProfileName != "" && (len(r.InstanceProfileName) > maxInstanceProfileNameLength || !reRoleName.MatchString(r.InstanceProfileName)) {
+		return errors.Errorf("invalid instance profile name %q", r.InstanceProfileName)
+	}
+	for _, policyARN := range r.ExtraPolicyARNs {
+		parsed, err := arn.Parse(policyARN)
+		if err != nil || parsed.Service != "iam" {
+			return errors.Errorf("invalid policy ARN %q", policyARN)
+		}
+	}
+	if len(r.ExtraStatements) > 0 {
+		doc := &iam.PolicyDocument{Version: iam.CurrentVersion, Statement: r.ExtraStatements}
+		if err := doc.Validate(); err != nil {
+			return errors.Wrap(err, "invalid extra statements")
+		}
+	}
+	return nil
+}
+
+// roleName returns the name of the role, defaulting to a managed name.
+func (r BootstrapRoleConfig) roleName(prefix string) string {
+	if r.RoleName != "" {
+		return r.RoleName
+	}
+	return iam.NewManagedName(prefix)
+}
+
+// instanceProfileName returns the name of the instance profile, defaulting
+// to a managed name.
+func (r BootstrapRoleConfig) instanceProfileName(prefix string) string {
+	if r.InstanceProfileName != "" {
+		return r.InstanceProfileName
+	}
+	return iam.NewManagedName(prefix)
+}
+
+// inlinePolicies returns the inline policy holding the extra statements, if any.
+func (r BootstrapRoleConfig) inlinePolicies() []cfn_iam.Role_Policy {
+	if len(r.ExtraStatements) == 0 {
+		return nil
+	}
+	return []cfn_iam.Role_Policy{
+		{
+			PolicyName: iam.NewManagedName("extra"),
+			PolicyDocument: &iam.PolicyDocument{
+				Version:   iam.CurrentVersion,
+				Statement: r.ExtraStatements,
+			},
+		},
+	}
+}
+
+// customRoleNames returns the role names that don't match the managed name
+// pattern, so the controllers are still allowed to pass them to instances.
+func (c BootstrapConfig) customRoleNames() []string {
+	var names []string
+	for _, role := range []BootstrapRoleConfig{c.ControlPlane, c.Controllers, c.Nodes} {
+		if role.RoleName != "" {
+			names = append(names, role.RoleName)
+		}
+	}
+	return names
+}
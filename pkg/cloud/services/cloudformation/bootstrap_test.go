@@ -0,0 +1,245 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cloudformation
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	cfn_iam "github.com/awslabs/goformation/v3/cloudformation/iam"
+
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/services/iam"
+)
+
+func TestManagedIAMPolicyDocumentsWithConditions(t *testing.T) {
+	conditions, err := iam.ParseConditions(`{"StringEquals": {"aws:ResourceTag/owner": "team"}}`)
+	if err != nil {
+		t.Fatalf("got an unexpected error: %v", err)
+	}
+
+	if err := ValidateManagedIAMPolicyDocuments("123456789012", "aws", BootstrapConfig{}, conditions); err != nil {
+		t.Fatalf("got an unexpected error: %v", err)
+	}
+
+	pd, err := getPolicyDocFromPolicyName(ControllersPolicy, "123456789012", "aws", BootstrapConfig{}, conditions)
+	if err != nil {
+		t.Fatalf("got an unexpected error: %v", err)
+	}
+	for i, statement := range pd.Statement {
+		keys, ok := statement.Condition["StringEquals"].(map[string]interface{})
+		if !ok || keys["aws:ResourceTag/owner"] != "team" {
+			t.Fatalf("expected statement %d to have the tag condition, got %v", i, statement.Condition)
+		}
+	}
+
+	// Existing conditions are kept.
+	keys, ok := pd.Statement[1].Condition["StringLike"].(map[string]interface{})
+	if !ok || keys["iam:AWSServiceName"] != "elasticloadbalancing.amazonaws.com" {
+		t.Fatalf("expected the service linked role condition to be kept, got %v", pd.Statement[1].Condition)
+	}
+}
+
+func TestParseConditions(t *testing.T) {
+	tests := []struct {
+		name       string
+		conditions string
+		wantErr    bool
+	}{
+		{
+			name:       "single value",
+			conditions: `{"StringEquals": {"aws:ResourceTag/owner": "team"}}`,
+		},
+		{
+			name:       "multiple values",
+			conditions: `{"StringLike": {"aws:RequestTag/owner": ["team-a", "team-b"]}}`,
+		},
+		{
+			name:       "not JSON",
+			conditions: `StringEquals=aws:ResourceTag/owner`,
+			wantErr:    true,
+		},
+		{
+			name:       "operator without keys",
+			conditions: `{"StringEquals": {}}`,
+			wantErr:    true,
+		},
+		{
+			name:       "operator with a value instead of keys",
+			conditions: `{"StringEquals": "team"}`,
+			wantErr:    true,
+		},
+		{
+			name:       "nested value",
+			conditions: `{"StringEquals": {"aws:ResourceTag/owner": {"team": "a"}}}`,
+			wantErr:    true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := iam.ParseConditions(tt.conditions); (err != nil) != tt.wantErr {
+				t.Errorf("ParseConditions() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestPolicyDocumentValidateSize(t *testing.T) {
+	conditions := iam.Conditions{
+		"StringEquals": map[string]interface{}{"aws:ResourceTag/owner": strings.Repeat("a", iam.MaxManagedPolicySize)},
+	}
+	if err := ValidateManagedIAMPolicyDocuments("123456789012", "aws", BootstrapConfig{}, conditions); err == nil {
+		t.Fatal("expected an error for a policy document over the managed policy size limit")
+	}
+}
+
+func TestBootstrapTemplateWithConfig(t *testing.T) {
+	config := BootstrapConfig{
+		Nodes: BootstrapRoleConfig{
+			RoleName:            "acme-nodes",
+			InstanceProfileName: "acme-nodes-profile",
+			ExtraPolicyARNs:     []string{"arn:aws:iam::aws:policy/AmazonSSMManagedInstanceCore"},
+			ExtraStatements: iam.Statements{
+				{Effect: iam.EffectDeny, Action: iam.Actions{"ec2:DescribeRegions"}, Resource: iam.Resources{"*"}},
+			},
+		},
+	}
+	if err := ValidateManagedIAMPolicyDocuments("123456789012", "aws", config, nil); err != nil {
+		t.Fatalf("got an unexpected error: %v", err)
+	}
+
+	template := BootstrapTemplate("123456789012", "aws", config, nil)
+
+	role := template.Resources["AWSIAMRoleNodes"].(*cfn_iam.Role)
+	if role.RoleName != "acme-nodes" {
+		t.Fatalf("expected the nodes role name to be overridden, got %q", role.RoleName)
+	}
+	if !reflect.DeepEqual(role.ManagedPolicyArns, config.Nodes.ExtraPolicyARNs) {
+		t.Fatalf("expected the extra policies to be attached, got %v", role.ManagedPolicyArns)
+	}
+	if len(role.Policies) != 1 {
+		t.Fatalf("expected an inline policy with the extra statements, got %v", role.Policies)
+	}
+
+	profile := template.Resources["AWSIAMInstanceProfileNodes"].(*cfn_iam.InstanceProfile)
+	if profile.InstanceProfileName != "acme-nodes-profile" {
+		t.Fatalf("expected the nodes instance profile name to be overridden, got %q", profile.InstanceProfileName)
+	}
+
+	// Roles keep their defaults when they are not customized.
+	controlPlane := template.Resources["AWSIAMRoleControlPlane"].(*cfn_iam.Role)
+	if controlPlane.RoleName != iam.NewManagedName("control-plane") || len(controlPlane.Policies) != 0 {
+		t.Fatalf("expected the control plane role to keep its defaults, got %+v", controlPlane)
+	}
+
+	// The controllers must still be allowed to pass the renamed role.
+	pd := controllersPolicy("123456789012", "aws", config)
+	passRole := pd.Statement[2]
+	if !reflect.DeepEqual(passRole.Resource, iam.Resources{
+		"arn:aws:iam::123456789012:role/*.cluster-api-provider-aws.sigs.k8s.io",
+		"arn:aws:iam::123456789012:role/acme-nodes",
+	}) {
+		t.Fatalf("expected the renamed role to be passable, got %v", passRole.Resource)
+	}
+}
+
+func TestBootstrapConfigValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  BootstrapConfig
+		wantErr bool
+	}{
+		{
+			name:   "defaults",
+			config: BootstrapConfig{},
+		},
+		{
+			name:    "invalid role name",
+			config:  BootstrapConfig{ControlPlane: BootstrapRoleConfig{RoleName: "control plane"}},
+			wantErr: true,
+		},
+		{
+			name:    "invalid policy ARN",
+			config:  BootstrapConfig{Nodes: BootstrapRoleConfig{ExtraPolicyARNs: []string{"AmazonSSMManagedInstanceCore"}}},
+			wantErr: true,
+		},
+		{
+			name: "statement without actions",
+			config: BootstrapConfig{Controllers: BootstrapRoleConfig{ExtraStatements: iam.Statements{
+				{Effect: iam.EffectDeny, Resource: iam.Resources{"*"}},
+			}}},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.config.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestPoliciesUsePartition(t *testing.T) {
+	tests := []struct {
+		partition     string
+		wantPassRole  string
+		wantPrincipal string
+	}{
+		{
+			partition:     "aws",
+			wantPassRole:  "arn:aws:iam::123456789012:role/*.cluster-api-provider-aws.sigs.k8s.io",
+			wantPrincipal: "ec2.amazonaws.com",
+		},
+		{
+			partition:     "aws-us-gov",
+			wantPassRole:  "arn:aws-us-gov:iam::123456789012:role/*.cluster-api-provider-aws.sigs.k8s.io",
+			wantPrincipal: "ec2.amazonaws.com",
+		},
+		{
+			partition:     "aws-cn",
+			wantPassRole:  "arn:aws-cn:iam::123456789012:role/*.cluster-api-provider-aws.sigs.k8s.io",
+			wantPrincipal: "ec2.amazonaws.com.cn",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.partition, func(t *testing.T) {
+			pd := controllersPolicy("123456789012", tc.partition, BootstrapConfig{})
+			if got := pd.Statement[2].Resource; !reflect.DeepEqual(got, iam.Resources{tc.wantPassRole}) {
+				t.Fatalf("expected pass role resource %q, got %v", tc.wantPassRole, got)
+			}
+			if got := pd.Statement[1].Resource[0]; !strings.HasPrefix(got, "arn:"+tc.partition+":iam::") {
+				t.Fatalf("expected the service linked role in partition %q, got %q", tc.partition, got)
+			}
+
+			nodes := cloudProviderNodeAwsPolicy("123456789012", tc.partition)
+			wantBootstrapData := iam.Resources{
+				"arn:" + tc.partition + ":ssm:*:123456789012:parameter/cluster.x-k8s.io/aws/node/*",
+				"arn:" + tc.partition + ":secretsmanager:*:123456789012:secret:aws.cluster.x-k8s.io/node/*",
+			}
+			if got := nodes.Statement[len(nodes.Statement)-1].Resource; !reflect.DeepEqual(got, wantBootstrapData) {
+				t.Fatalf("expected the bootstrap data resources %v, got %v", wantBootstrapData, got)
+			}
+
+			principal := ec2AssumeRolePolicy(tc.partition).Statement[0].Principal["Service"]
+			if !reflect.DeepEqual(principal, iam.PrincipalID{tc.wantPrincipal}) {
+				t.Fatalf("expected principal %q, got %v", tc.wantPrincipal, principal)
+			}
+		})
+	}
+}
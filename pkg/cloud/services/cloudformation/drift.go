@@ -0,0 +1,344 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cloudformation
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	cfn "github.com/aws/aws-sdk-go/service/cloudformation"
+	awsiam "github.com/aws/aws-sdk-go/service/iam"
+	"github.com/pkg/errors"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/klog"
+)
+
+// Drift detection takes from seconds to minutes depending on the number of
+// resources in the stack.
+var (
+	driftDetectionInterval = 5 * time.Second
+	driftDetectionTimeout  = 10 * time.Minute
+)
+
+// maxPolicyVersions is the number of versions a managed policy can have.
+const maxPolicyVersions = 5
+
+// ResourceDrift describes how a resource of a stack differs from the stack
+// template.
+type ResourceDrift struct {
+	LogicalResourceID  string
+	PhysicalResourceID string
+	ResourceType       string
+
+	// Status is either MODIFIED or DELETED.
+	Status string
+
+	// Differences lists the properties which differ from the template.
+	Differences []PropertyDifference
+
+	// expectedProperties are the properties of the resource in the template,
+	// as JSON.
+	expectedProperties string
+}
+
+// PropertyDifference describes a property of a resource whose actual value
+// differs from the template.
+type PropertyDifference struct {
+	PropertyPath   string
+	DifferenceType string
+	ExpectedValue  string
+	ActualValue    string
+}
+
+// StackDriftError is returned when resources of a stack differ from the stack
+// template.
+type StackDriftError struct {
+	StackName string
+	Resources []ResourceDrift
+}
+
+// Error implements the error interface.
+func (e *StackDriftError) Error() string {
+	names := make([]string, 0, len(e.Resources))
+	for _, r := range e.Resources {
+		names = append(names, r.LogicalResourceID)
+	}
+	return fmt.Sprintf("AWS CloudFormation stack %q has drifted: %s", e.StackName, strings.Join(names, ", "))
+}
+
+// DetectStackDrift detects the resources of the stack which were changed or
+// deleted outside of CloudFormation, e.g. IAM roles edited in the console.
+func (s *Service) DetectStackDrift(stackName string) ([]ResourceDrift, error) {
+	out, err := s.CFN.DetectStackDrift(&cfn.DetectStackDriftInput{StackName: aws.String(stackName)})
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to detect drift of AWS CloudFormation stack %q", stackName)
+	}
+
+	klog.V(2).Infof("waiting for drift detection of stack %q", stackName)
+	var status *cfn.DescribeStackDriftDetectionStatusOutput
+	if err := wait.PollImmediate(driftDetectionInterval, driftDetectionTimeout, func() (bool, error) {
+		status, err = s.CFN.DescribeStackDriftDetectionStatus(&cfn.DescribeStackDriftDetectionStatusInput{
+			StackDriftDetectionId: out.StackDriftDetectionId,
+		})
+		if err != nil {
+			return false, err
+		}
+		return aws.StringValue(status.DetectionStatus) != cfn.StackDriftDetectionStatusDetectionInProgress, nil
+	}); err != nil {
+		return nil, errors.Wrapf(err, "failed to wait for drift detection of AWS CloudFormation stack %q", stackName)
+	}
+
+	if aws.StringValue(status.DetectionStatus) == cfn.StackDriftDetectionStatusDetectionFailed {
+		return nil, errors.Errorf("failed to detect drift of AWS CloudFormation stack %q: %s", stackName, aws.StringValue(status.DetectionStatusReason))
+	}
+
+	input := &cfn.DescribeStackResourceDriftsInput{
+		StackName:                       aws.String(stackName),
+		StackResourceDriftStatusFilters: aws.StringSlice([]string{cfn.StackResourceDriftStatusModified, cfn.StackResourceDriftStatusDeleted}),
+	}
+
+	var drifts []ResourceDrift
+	for {
+		out, err := s.CFN.DescribeStackResourceDrifts(input)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to describe resource drifts of AWS CloudFormation stack %q", stackName)
+		}
+
+		for _, r := range out.StackResourceDrifts {
+			drift := ResourceDrift{
+				LogicalResourceID:  aws.StringValue(r.LogicalResourceId),
+				PhysicalResourceID: aws.StringValue(r.PhysicalResourceId),
+				ResourceType:       aws.StringValue(r.ResourceType),
+				Status:             aws.StringValue(r.StackResourceDriftStatus),
+				expectedProperties: aws.StringValue(r.ExpectedProperties),
+			}
+			for _, d := range r.PropertyDifferences {
+				drift.Differences = append(drift.Differences, PropertyDifference{
+					PropertyPath:   aws.StringValue(d.PropertyPath),
+					DifferenceType: aws.StringValue(d.DifferenceType),
+					ExpectedValue:  aws.StringValue(d.ExpectedValue),
+					ActualValue:    aws.StringValue(d.ActualValue),
+				})
+			}
+			drifts = append(drifts, drift)
+		}
+
+		if out.NextToken == nil {
+			break
+		}
+		input.NextToken = out.NextToken
+	}
+
+	klog.V(2).Infof("%d resources of stack %q have drifted", len(drifts), stackName)
+	return drifts, nil
+}
+
+// ShowStackDrift prints out in tabular format the resources of the stack
+// which differ from the template, and how.
+func ShowStackDrift(drifts []ResourceDrift) {
+	if len(drifts) == 0 {
+		fmt.Print("\nNo resource of the stack has drifted.\n\n")
+		return
+	}
+
+	fmt.Print("\nFollowing resources have drifted: \n\n")
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 1, ' ', tabwriter.Debug)
+
+	fmt.Fprintln(w, "Resource\tType\tStatus\tProperty\tDifference\tExpected\tActual")
+
+	for _, r := range drifts {
+		if len(r.Differences) == 0 {
+			fmt.Fprintf(w, "%s\t%s\t%s\t\t\t\t\n", r.LogicalResourceID, r.ResourceType, r.Status)
+			continue
+		}
+		for _, d := range r.Differences {
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\n",
+				r.LogicalResourceID, r.ResourceType, r.Status,
+				d.PropertyPath, d.DifferenceType, d.ExpectedValue, d.ActualValue)
+		}
+	}
+
+	w.Flush()
+
+	fmt.Print("\n\n")
+}
+
+// correctStackDrift re-applies the template to the drifted IAM roles and
+// managed policies of the stack. CloudFormation only updates resources whose
+// template changed, so it never corrects drift on its own. Deleted resources
+// and other resource types have to be fixed manually.
+func (s *Service) correctStackDrift(stackName string) error {
+	drifts, err := s.DetectStackDrift(stackName)
+	if err != nil {
+		return err
+	}
+
+	var uncorrected []ResourceDrift
+	for _, drift := range drifts {
+		if drift.Status != cfn.StackResourceDriftStatusModified {
+			uncorrected = append(uncorrected, drift)
+			continue
+		}
+
+		switch drift.ResourceType {
+		case "AWS::IAM::ManagedPolicy":
+			err = s.correctManagedPolicyDrift(drift)
+		case "AWS::IAM::Role":
+			err = s.correctRoleDrift(drift)
+		default:
+			uncorrected = append(uncorrected, drift)
+			continue
+		}
+		if err != nil {
+			return errors.Wrapf(err, "failed to correct drift of %q", drift.LogicalResourceID)
+		}
+		klog.Infof("corrected drift of %s %q", drift.ResourceType, drift.LogicalResourceID)
+	}
+
+	if len(uncorrected) > 0 {
+		return errors.Wrap(&StackDriftError{StackName: stackName, Resources: uncorrected}, "drift of these resources has to be corrected manually")
+	}
+	return nil
+}
+
+// correctManagedPolicyDrift makes the policy document of the template the
+// default version of the managed policy.
+func (s *Service) correctManagedPolicyDrift(drift ResourceDrift) error {
+	var expected struct {
+		PolicyDocument json.RawMessage
+	}
+	if err := json.Unmarshal([]byte(drift.expectedProperties), &expected); err != nil || len(expected.PolicyDocument) == 0 {
+		return errors.Errorf("failed to get the expected policy document of %q", drift.LogicalResourceID)
+	}
+
+	arn := drift.PhysicalResourceID
+	out, err := s.IAM.ListPolicyVersions(&awsiam.ListPolicyVersionsInput{PolicyArn: aws.String(arn)})
+	if err != nil {
+		return errors.Wrapf(err, "failed to list versions of policy %q", arn)
+	}
+
+	// Make room for the new version by deleting the oldest non default one.
+	if len(out.Versions) >= maxPolicyVersions {
+		var oldest *awsiam.PolicyVersion
+		for _, v := range out.Versions {
+			if aws.BoolValue(v.IsDefaultVersion) {
+				continue
+			}
+			if oldest == nil || aws.TimeValue(v.CreateDate).Before(aws.TimeValue(oldest.CreateDate)) {
+				oldest = v
+			}
+		}
+		if oldest != nil {
+			if _, err := s.IAM.DeletePolicyVersion(&awsiam.DeletePolicyVersionInput{
+				PolicyArn: aws.String(arn),
+				VersionId: oldest.VersionId,
+			}); err != nil {
+				return errors.Wrapf(err, "failed to delete version %q of policy %q", aws.StringValue(oldest.VersionId), arn)
+			}
+		}
+	}
+
+	if _, err := s.IAM.CreatePolicyVersion(&awsiam.CreatePolicyVersionInput{
+		PolicyArn:      aws.String(arn),
+		PolicyDocument: aws.String(string(expected.PolicyDocument)),
+		SetAsDefault:   aws.Bool(true),
+	}); err != nil {
+		return errors.Wrapf(err, "failed to create version of policy %q", arn)
+	}
+	return nil
+}
+
+// correctRoleDrift re-applies the trust policy, the managed policies and the
+// inline policies of the template to the role.
+func (s *Service) correctRoleDrift(drift ResourceDrift) error {
+	var expected struct {
+		AssumeRolePolicyDocument json.RawMessage
+		ManagedPolicyArns        []string
+		Policies                 []struct {
+			PolicyName     string
+			PolicyDocument json.RawMessage
+		}
+	}
+	if err := json.Unmarshal([]byte(drift.expectedProperties), &expected); err != nil || len(expected.AssumeRolePolicyDocument) == 0 {
+		return errors.Errorf("failed to get the expected properties of %q", drift.LogicalResourceID)
+	}
+
+	role := aws.String(drift.PhysicalResourceID)
+
+	if _, err := s.IAM.UpdateAssumeRolePolicy(&awsiam.UpdateAssumeRolePolicyInput{
+		RoleName:       role,
+		PolicyDocument: aws.String(string(expected.AssumeRolePolicyDocument)),
+	}); err != nil {
+		return errors.Wrapf(err, "failed to update trust policy of role %q", *role)
+	}
+
+	attached := sets.NewString()
+	if err := s.IAM.ListAttachedRolePoliciesPages(&awsiam.ListAttachedRolePoliciesInput{RoleName: role}, func(out *awsiam.ListAttachedRolePoliciesOutput, _ bool) bool {
+		for _, p := range out.AttachedPolicies {
+			attached.Insert(aws.StringValue(p.PolicyArn))
+		}
+		return true
+	}); err != nil {
+		return errors.Wrapf(err, "failed to list managed policies of role %q", *role)
+	}
+
+	wanted := sets.NewString(expected.ManagedPolicyArns...)
+	for _, arn := range wanted.Difference(attached).List() {
+		if _, err := s.IAM.AttachRolePolicy(&awsiam.AttachRolePolicyInput{RoleName: role, PolicyArn: aws.String(arn)}); err != nil {
+			return errors.Wrapf(err, "failed to attach policy %q to role %q", arn, *role)
+		}
+	}
+	for _, arn := range attached.Difference(wanted).List() {
+		if _, err := s.IAM.DetachRolePolicy(&awsiam.DetachRolePolicyInput{RoleName: role, PolicyArn: aws.String(arn)}); err != nil {
+			return errors.Wrapf(err, "failed to detach policy %q from role %q", arn, *role)
+		}
+	}
+
+	inline := sets.NewString()
+	if err := s.IAM.ListRolePoliciesPages(&awsiam.ListRolePoliciesInput{RoleName: role}, func(out *awsiam.ListRolePoliciesOutput, _ bool) bool {
+		inline.Insert(aws.StringValueSlice(out.PolicyNames)...)
+		return true
+	}); err != nil {
+		return errors.Wrapf(err, "failed to list inline policies of role %q", *role)
+	}
+
+	wanted = sets.NewString()
+	for _, p := range expected.Policies {
+		wanted.Insert(p.PolicyName)
+		if _, err := s.IAM.PutRolePolicy(&awsiam.PutRolePolicyInput{
+			RoleName:       role,
+			PolicyName:     aws.String(p.PolicyName),
+			PolicyDocument: aws.String(string(p.PolicyDocument)),
+		}); err != nil {
+			return errors.Wrapf(err, "failed to put inline policy %q of role %q", p.PolicyName, *role)
+		}
+	}
+	for _, name := range inline.Difference(wanted).List() {
+		if _, err := s.IAM.DeleteRolePolicy(&awsiam.DeleteRolePolicyInput{RoleName: role, PolicyName: aws.String(name)}); err != nil {
+			return errors.Wrapf(err, "failed to delete inline policy %q of role %q", name, *role)
+		}
+	}
+
+	return nil
+}
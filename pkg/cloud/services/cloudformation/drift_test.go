@@ -0,0 +1,204 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cloudformation
+
+import (
+	"reflect"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	cfn "github.com/aws/aws-sdk-go/service/cloudformation"
+	"github.com/aws/aws-sdk-go/service/cloudformation/cloudformationiface"
+	awsiam "github.com/aws/aws-sdk-go/service/iam"
+	"github.com/aws/aws-sdk-go/service/iam/iamiface"
+	"github.com/pkg/errors"
+)
+
+// fakeCFN returns the canned resource drifts, one per page.
+type fakeCFN struct {
+	cloudformationiface.CloudFormationAPI
+	drifts []*cfn.StackResourceDrift
+}
+
+func (f *fakeCFN) DetectStackDrift(input *cfn.DetectStackDriftInput) (*cfn.DetectStackDriftOutput, error) {
+	return &cfn.DetectStackDriftOutput{StackDriftDetectionId: aws.String("detection")}, nil
+}
+
+func (f *fakeCFN) DescribeStackDriftDetectionStatus(input *cfn.DescribeStackDriftDetectionStatusInput) (*cfn.DescribeStackDriftDetectionStatusOutput, error) {
+	return &cfn.DescribeStackDriftDetectionStatusOutput{
+		DetectionStatus:  aws.String(cfn.StackDriftDetectionStatusDetectionComplete),
+		StackDriftStatus: aws.String(cfn.StackDriftStatusDrifted),
+	}, nil
+}
+
+func (f *fakeCFN) DescribeStackResourceDrifts(input *cfn.DescribeStackResourceDriftsInput) (*cfn.DescribeStackResourceDriftsOutput, error) {
+	i := 0
+	if input.NextToken != nil {
+		i, _ = strconv.Atoi(aws.StringValue(input.NextToken))
+	}
+	out := &cfn.DescribeStackResourceDriftsOutput{StackResourceDrifts: f.drifts[i : i+1]}
+	if i+1 < len(f.drifts) {
+		out.NextToken = aws.String(strconv.Itoa(i + 1))
+	}
+	return out, nil
+}
+
+// fakeIAM records the calls correcting the drift.
+type fakeIAM struct {
+	iamiface.IAMAPI
+	versions []*awsiam.PolicyVersion
+	attached []string
+	inline   []string
+	calls    []string
+}
+
+func (f *fakeIAM) ListPolicyVersions(input *awsiam.ListPolicyVersionsInput) (*awsiam.ListPolicyVersionsOutput, error) {
+	return &awsiam.ListPolicyVersionsOutput{Versions: f.versions}, nil
+}
+
+func (f *fakeIAM) DeletePolicyVersion(input *awsiam.DeletePolicyVersionInput) (*awsiam.DeletePolicyVersionOutput, error) {
+	f.calls = append(f.calls, "DeletePolicyVersion "+aws.StringValue(input.VersionId))
+	return &awsiam.DeletePolicyVersionOutput{}, nil
+}
+
+func (f *fakeIAM) CreatePolicyVersion(input *awsiam.CreatePolicyVersionInput) (*awsiam.CreatePolicyVersionOutput, error) {
+	f.calls = append(f.calls, "CreatePolicyVersion "+aws.StringValue(input.PolicyDocument))
+	return &awsiam.CreatePolicyVersionOutput{}, nil
+}
+
+func (f *fakeIAM) UpdateAssumeRolePolicy(input *awsiam.UpdateAssumeRolePolicyInput) (*awsiam.UpdateAssumeRolePolicyOutput, error) {
+	f.calls = append(f.calls, "UpdateAssumeRolePolicy "+aws.StringValue(input.RoleName))
+	return &awsiam.UpdateAssumeRolePolicyOutput{}, nil
+}
+
+func (f *fakeIAM) ListAttachedRolePoliciesPages(input *awsiam.ListAttachedRolePoliciesInput, fn func(*awsiam.ListAttachedRolePoliciesOutput, bool) bool) error {
+	out := &awsiam.ListAttachedRolePoliciesOutput{}
+	for _, arn := range f.attached {
+		out.AttachedPolicies = append(out.AttachedPolicies, &awsiam.AttachedPolicy{PolicyArn: aws.String(arn)})
+	}
+	fn(out, true)
+	return nil
+}
+
+func (f *fakeIAM) AttachRolePolicy(input *awsiam.AttachRolePolicyInput) (*awsiam.AttachRolePolicyOutput, error) {
+	f.calls = append(f.calls, "AttachRolePolicy "+aws.StringValue(input.PolicyArn))
+	return &awsiam.AttachRolePolicyOutput{}, nil
+}
+
+func (f *fakeIAM) DetachRolePolicy(input *awsiam.DetachRolePolicyInput) (*awsiam.DetachRolePolicyOutput, error) {
+	f.calls = append(f.calls, "DetachRolePolicy "+aws.StringValue(input.PolicyArn))
+	return &awsiam.DetachRolePolicyOutput{}, nil
+}
+
+func (f *fakeIAM) ListRolePoliciesPages(input *awsiam.ListRolePoliciesInput, fn func(*awsiam.ListRolePoliciesOutput, bool) bool) error {
+	fn(&awsiam.ListRolePoliciesOutput{PolicyNames: aws.StringSlice(f.inline)}, true)
+	return nil
+}
+
+func (f *fakeIAM) PutRolePolicy(input *awsiam.PutRolePolicyInput) (*awsiam.PutRolePolicyOutput, error) {
+	f.calls = append(f.calls, "PutRolePolicy "+aws.StringValue(input.PolicyName))
+	return &awsiam.PutRolePolicyOutput{}, nil
+}
+
+func (f *fakeIAM) DeleteRolePolicy(input *awsiam.DeleteRolePolicyInput) (*awsiam.DeleteRolePolicyOutput, error) {
+	f.calls = append(f.calls, "DeleteRolePolicy "+aws.StringValue(input.PolicyName))
+	return &awsiam.DeleteRolePolicyOutput{}, nil
+}
+
+func TestCorrectStackDrift(t *testing.T) {
+	driftDetectionInterval = time.Millisecond
+
+	cfnFake := &fakeCFN{
+		drifts: []*cfn.StackResourceDrift{
+			{
+				LogicalResourceId:        aws.String(ControllersPolicy),
+				PhysicalResourceId:       aws.String("arn:aws:iam::123456789012:policy/controllers.cluster-api-provider-aws.sigs.k8s.io"),
+				ResourceType:             aws.String("AWS::IAM::ManagedPolicy"),
+				StackResourceDriftStatus: aws.String(cfn.StackResourceDriftStatusModified),
+				ExpectedProperties:       aws.String(`{"PolicyDocument":{"Version":"2012-10-17"}}`),
+				PropertyDifferences: []*cfn.PropertyDifference{
+					{
+						PropertyPath:   aws.String("/PolicyDocument/Statement/0/Action/1"),
+						DifferenceType: aws.String(cfn.DifferenceTypeRemove),
+						ExpectedValue:  aws.String("ec2:AllocateAddress"),
+					},
+				},
+			},
+			{
+				LogicalResourceId:        aws.String("AWSIAMRoleNodes"),
+				PhysicalResourceId:       aws.String("nodes.cluster-api-provider-aws.sigs.k8s.io"),
+				ResourceType:             aws.String("AWS::IAM::Role"),
+				StackResourceDriftStatus: aws.String(cfn.StackResourceDriftStatusModified),
+				ExpectedProperties:       aws.String(`{"AssumeRolePolicyDocument":{"Version":"2012-10-17"},"ManagedPolicyArns":["arn:nodes"],"Policies":[{"PolicyName":"extra","PolicyDocument":{}}]}`),
+			},
+			{
+				LogicalResourceId:        aws.String("AWSIAMInstanceProfileNodes"),
+				PhysicalResourceId:       aws.String("nodes.cluster-api-provider-aws.sigs.k8s.io"),
+				ResourceType:             aws.String("AWS::IAM::InstanceProfile"),
+				StackResourceDriftStatus: aws.String(cfn.StackResourceDriftStatusDeleted),
+			},
+		},
+	}
+	iamFake := &fakeIAM{
+		versions: []*awsiam.PolicyVersion{
+			{VersionId: aws.String("v5"), IsDefaultVersion: aws.Bool(true), CreateDate: aws.Time(time.Unix(5, 0))},
+			{VersionId: aws.String("v1"), CreateDate: aws.Time(time.Unix(1, 0))},
+			{VersionId: aws.String("v2"), CreateDate: aws.Time(time.Unix(2, 0))},
+			{VersionId: aws.String("v3"), CreateDate: aws.Time(time.Unix(3, 0))},
+			{VersionId: aws.String("v4"), CreateDate: aws.Time(time.Unix(4, 0))},
+		},
+		attached: []string{"arn:other"},
+		inline:   []string{"manual"},
+	}
+
+	s := &Service{CFN: cfnFake, IAM: iamFake}
+
+	drifts, err := s.DetectStackDrift("stack")
+	if err != nil {
+		t.Fatalf("got an unexpected error: %v", err)
+	}
+	if len(drifts) != 3 {
+		t.Fatalf("expected 3 drifted resources, got %+v", drifts)
+	}
+	if len(drifts[0].Differences) != 1 || drifts[0].Differences[0].ExpectedValue != "ec2:AllocateAddress" {
+		t.Fatalf("expected the property difference of the policy, got %+v", drifts[0].Differences)
+	}
+
+	err = s.correctStackDrift("stack")
+	driftErr, ok := errors.Cause(err).(*StackDriftError)
+	if !ok {
+		t.Fatalf("expected a stack drift error, got %v", err)
+	}
+	if len(driftErr.Resources) != 1 || driftErr.Resources[0].LogicalResourceID != "AWSIAMInstanceProfileNodes" {
+		t.Fatalf("expected only the deleted instance profile to be left, got %+v", driftErr.Resources)
+	}
+
+	expected := []string{
+		"DeletePolicyVersion v1",
+		`CreatePolicyVersion {"Version":"2012-10-17"}`,
+		"UpdateAssumeRolePolicy nodes.cluster-api-provider-aws.sigs.k8s.io",
+		"AttachRolePolicy arn:nodes",
+		"DetachRolePolicy arn:other",
+		"PutRolePolicy extra",
+		"DeleteRolePolicy manual",
+	}
+	if !reflect.DeepEqual(iamFake.calls, expected) {
+		t.Fatalf("expected calls %v, got %v", expected, iamFake.calls)
+	}
+}
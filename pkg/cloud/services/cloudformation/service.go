@@ -18,6 +18,7 @@ package cloudformation
 
 import (
 	"github.com/aws/aws-sdk-go/service/cloudformation/cloudformationiface"
+	"github.com/aws/aws-sdk-go/service/iam/iamiface"
 )
 
 // Service holds a collection of interfaces.
@@ -25,6 +26,9 @@ import (
 // One alternative is to have a large list of functions from the ec2 client.
 type Service struct {
 	CFN cloudformationiface.CloudFormationAPI
+
+	// IAM is only required to correct the drift of the bootstrap stack.
+	IAM iamiface.IAMAPI
 }
 
 // NewService returns a new service given the CloudFormation api client.
@@ -0,0 +1,98 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cloudwatch
+
+import (
+	"fmt"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/cloudwatch"
+	"github.com/pkg/errors"
+	infrav1 "sigs.k8s.io/cluster-api-provider-aws/api/v1alpha3"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/partition"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/record"
+)
+
+// ReconcileRecoveryAlarm creates or updates the CloudWatch alarm that
+// recovers the given instance when it fails the EC2 system status check.
+func (s *Service) ReconcileRecoveryAlarm(instanceID string) error {
+	s.scope.V(2).Info("Reconciling auto-recovery alarm", "instance-id", instanceID)
+
+	name := s.recoveryAlarmName(instanceID)
+	input := &cloudwatch.PutMetricAlarmInput{
+		AlarmName:        aws.String(name),
+		AlarmDescription: aws.String(fmt.Sprintf("Recover instance %s of cluster %s when it fails the EC2 system status check", instanceID, s.scope.Name())),
+		Namespace:        aws.String("AWS/EC2"),
+		MetricName:       aws.String("StatusCheckFailed_System"),
+		Dimensions: []*cloudwatch.Dimension{
+			{
+				Name:  aws.String("InstanceId"),
+				Value: aws.String(instanceID),
+			},
+		},
+		Statistic:          aws.String(cloudwatch.StatisticMinimum),
+		Period:             aws.Int64(60),
+		EvaluationPeriods:  aws.Int64(2),
+		Threshold:          aws.Float64(0),
+		ComparisonOperator: aws.String(cloudwatch.ComparisonOperatorGreaterThanThreshold),
+		AlarmActions:       aws.StringSlice([]string{s.recoverActionARN()}),
+	}
+
+	tags := infrav1.Build(infrav1.BuildParams{
+		ClusterName: s.scope.Name(),
+		Lifecycle:   infrav1.ResourceLifecycleOwned,
+		Name:        aws.String(name),
+		Role:        aws.String(infrav1.CommonRoleTagValue),
+		Additional:  s.scope.AdditionalTags(),
+	})
+	for k, v := range tags {
+		input.Tags = append(input.Tags, &cloudwatch.Tag{Key: aws.String(k), Value: aws.String(v)})
+	}
+
+	// PutMetricAlarm creates the alarm, or replaces it if it already exists.
+	if _, err := s.scope.CloudWatch.PutMetricAlarm(input); err != nil {
+		record.Warnf(s.scope.AWSCluster, "FailedPutRecoveryAlarm", "Failed to create auto-recovery alarm for instance %q: %v", instanceID, err)
+		return errors.Wrapf(err, "failed to create auto-recovery alarm for instance %q", instanceID)
+	}
+
+	return nil
+}
+
+// DeleteRecoveryAlarm deletes the auto-recovery alarm of the given instance, if any.
+func (s *Service) DeleteRecoveryAlarm(instanceID string) error {
+	s.scope.V(2).Info("Deleting auto-recovery alarm", "instance-id", instanceID)
+
+	name := s.recoveryAlarmName(instanceID)
+	if _, err := s.scope.CloudWatch.DeleteAlarms(&cloudwatch.DeleteAlarmsInput{
+		AlarmNames: aws.StringSlice([]string{name}),
+	}); err != nil {
+		record.Warnf(s.scope.AWSCluster, "FailedDeleteRecoveryAlarm", "Failed to delete auto-recovery alarm %q: %v", name, err)
+		return errors.Wrapf(err, "failed to delete auto-recovery alarm %q", name)
+	}
+
+	record.Eventf(s.scope.AWSCluster, "SuccessfulDeleteRecoveryAlarm", "Deleted auto-recovery alarm %q", name)
+	return nil
+}
+
+func (s *Service) recoveryAlarmName(instanceID string) string {
+	return fmt.Sprintf("%s-%s-auto-recovery", s.scope.Name(), instanceID)
+}
+
+// recoverActionARN returns the ARN of the EC2 recover action in the cluster region.
+func (s *Service) recoverActionARN() string {
+	return partition.ARN(partition.ForRegion(s.scope.Region()), "automate", s.scope.Region(), "", "ec2:recover")
+}
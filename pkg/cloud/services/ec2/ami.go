@@ -17,14 +17,20 @@ limitations under the License.
 package ec2
 
 import (
+	"bytes"
 	"fmt"
 	"sort"
 	"strings"
+	"text/template"
 	"time"
 
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/aws/aws-sdk-go/service/ec2/ec2iface"
+	"github.com/aws/aws-sdk-go/service/ssm"
 	"github.com/pkg/errors"
+
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/awserrors"
 )
 
 const (
@@ -52,14 +58,92 @@ func amiName(baseOS, kubernetesVersion string) string {
 	return fmt.Sprintf(amiNameFormat, baseOS, strings.TrimPrefix(kubernetesVersion, "v"))
 }
 
+// amiNameData holds the values available to an image lookup format.
+type amiNameData struct {
+	BaseOS     string
+	K8sVersion string
+}
+
+// amiNameFromFormat renders the given image lookup format into a name glob.
+func amiNameFromFormat(format, baseOS, kubernetesVersion string) (string, error) {
+	tmpl, err := template.New("imageLookupFormat").Option("missingkey=error").Parse(format)
+	if err != nil {
+		return "", errors.Wrapf(err, "failed to parse image lookup format %q", format)
+	}
+	var buf bytes.Buffer
+	data := amiNameData{BaseOS: baseOS, K8sVersion: strings.TrimPrefix(kubernetesVersion, "v")}
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return "", errors.Wrapf(err, "failed to render image lookup format %q", format)
+	}
+	return buf.String(), nil
+}
+
 // defaultAMILookup returns the default AMI based on region
-func (s *Service) defaultAMILookup(ownerID, baseOS, kubernetesVersion string) (string, error) {
+func (s *Service) defaultAMILookup(ownerID, baseOS, format, kubernetesVersion string) (string, error) {
+	latestImage, err := DefaultAMILookup(s.scope.EC2, ownerID, baseOS, format, kubernetesVersion)
+	if err != nil {
+		return "", err
+	}
+	s.scope.V(2).Info("Found and using an existing AMI", "ami-id", aws.StringValue(latestImage.ImageId))
+	return aws.StringValue(latestImage.ImageId), nil
+}
+
+// AMIResolutionError is returned when the AMI of a machine can't be resolved
+// from an SSM parameter.
+type AMIResolutionError struct {
+	Parameter string
+	Err       error
+}
+
+// Error implements the error interface.
+func (e *AMIResolutionError) Error() string {
+	return fmt.Sprintf("failed to resolve AMI from SSM parameter %q: %v", e.Parameter, e.Err)
+}
+
+// getAMIFromSSMParameter returns the AMI ID stored in the given SSM parameter.
+func (s *Service) getAMIFromSSMParameter(name string) (string, error) {
+	out, err := s.scope.SSM.GetParameter(&ssm.GetParameterInput{
+		Name:           aws.String(name),
+		WithDecryption: aws.Bool(true),
+	})
+	if err != nil {
+		if code, ok := awserrors.Code(err); ok && code == ssm.ErrCodeParameterNotFound {
+			return "", &AMIResolutionError{Parameter: name, Err: errors.New("parameter not found")}
+		}
+		return "", &AMIResolutionError{Parameter: name, Err: err}
+	}
+
+	var imageID string
+	if out.Parameter != nil {
+		imageID = strings.TrimSpace(aws.StringValue(out.Parameter.Value))
+	}
+	if !strings.HasPrefix(imageID, "ami-") {
+		return "", &AMIResolutionError{Parameter: name, Err: errors.Errorf("value %q is not an AMI ID", imageID)}
+	}
+
+	s.scope.V(2).Info("Resolved AMI from SSM parameter", "parameter", name, "ami-id", imageID)
+	return imageID, nil
+}
+
+// DefaultAMILookup returns the latest default AMI for the given Kubernetes
+// version. An empty ownerID or baseOS selects the project defaults. A
+// non-empty format replaces the default name glob, in which case exactly one
+// image must match.
+func DefaultAMILookup(ec2Client ec2iface.EC2API, ownerID, baseOS, format, kubernetesVersion string) (*ec2.Image, error) {
 	if ownerID == "" {
 		ownerID = defaultMachineAMIOwnerID
 	}
 	if baseOS == "" {
 		baseOS = defaultMachineAMILookupBaseOS
 	}
+	name := amiName(baseOS, kubernetesVersion)
+	if format != "" {
+		var err error
+		name, err = amiNameFromFormat(format, baseOS, kubernetesVersion)
+		if err != nil {
+			return nil, err
+		}
+	}
 	describeImageInput := &ec2.DescribeImagesInput{
 		Filters: []*ec2.Filter{
 			{
@@ -68,7 +152,7 @@ func (s *Service) defaultAMILookup(ownerID, baseOS, kubernetesVersion string) (s
 			},
 			{
 				Name:   aws.String("name"),
-				Values: []*string{aws.String(amiName(baseOS, kubernetesVersion))},
+				Values: []*string{aws.String(name)},
 			},
 			{
 				Name:   aws.String("architecture"),
@@ -85,20 +169,26 @@ func (s *Service) defaultAMILookup(ownerID, baseOS, kubernetesVersion string) (s
 		},
 	}
 
-	out, err := s.scope.EC2.DescribeImages(describeImageInput)
+	out, err := ec2Client.DescribeImages(describeImageInput)
 	if err != nil {
-		return "", errors.Wrapf(err, "failed to find ami: %q", amiName(baseOS, kubernetesVersion))
+		return nil, errors.Wrapf(err, "failed to find ami: %q", name)
 	}
 	if len(out.Images) == 0 {
-		return "", errors.Errorf("found no AMIs with the name: %q", amiName(baseOS, kubernetesVersion))
+		return nil, errors.Errorf("found no AMIs with the name: %q", name)
+	}
+	if format != "" {
+		// A custom format must select a single image, the newest one is not
+		// picked on the user's behalf.
+		if len(out.Images) > 1 {
+			return nil, errors.Errorf("found %d AMIs with the name %q owned by %q, expected exactly one", len(out.Images), name, ownerID)
+		}
+		return out.Images[0], nil
 	}
 	latestImage, err := getLatestImage(out.Images)
 	if err != nil {
-		s.scope.Error(err, "failed getting latest image from AMI list")
-		return "", err
+		return nil, errors.Wrap(err, "failed getting latest image from AMI list")
 	}
-	s.scope.V(2).Info("Found and using an existing AMI", "ami-id", aws.StringValue(latestImage.ImageId))
-	return aws.StringValue(latestImage.ImageId), nil
+	return latestImage, nil
 }
 
 type images []*ec2.Image
@@ -19,6 +19,10 @@ package ec2
 import (
 	"testing"
 
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/ssm"
+	"github.com/aws/aws-sdk-go/service/ssm/ssmiface"
+
 	infrav1 "sigs.k8s.io/cluster-api-provider-aws/api/v1alpha3"
 
 	"github.com/aws/aws-sdk-go/aws"
@@ -79,7 +83,7 @@ func TestAMIs(t *testing.T) {
 			tc.expect(ec2Mock.EXPECT())
 
 			s := NewService(scope)
-			id, err := s.defaultAMILookup("", "base os-baseos version", "1.11.1")
+			id, err := s.defaultAMILookup("", "base os-baseos version", "", "1.11.1")
 			if err != nil {
 				t.Fatalf("did not expect error calling a mock: %v", err)
 			}
@@ -140,10 +144,140 @@ func TestAMIsWithInvalidCreationDate(t *testing.T) {
 			tc.expect(ec2Mock.EXPECT())
 
 			s := NewService(scope)
-			_, err = s.defaultAMILookup("", "base os-baseos version", "1.11.1")
+			_, err = s.defaultAMILookup("", "base os-baseos version", "", "1.11.1")
 			if err == nil {
 				t.Fatalf("expected an error but did not get one")
 			}
 		})
 	}
 }
+
+func TestAMIsWithLookupFormat(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	testCases := []struct {
+		name    string
+		images  []*ec2.Image
+		want    string
+		wantErr bool
+	}{
+		{
+			name: "single match",
+			images: []*ec2.Image{
+				{ImageId: aws.String("ami-1234"), CreationDate: aws.String("2019-02-08T17:02:31.000Z")},
+			},
+			want: "ami-1234",
+		},
+		{
+			name: "more than one match",
+			images: []*ec2.Image{
+				{ImageId: aws.String("ami-1234"), CreationDate: aws.String("2019-02-08T17:02:31.000Z")},
+				{ImageId: aws.String("ami-5678"), CreationDate: aws.String("2020-02-08T17:02:31.000Z")},
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ec2Mock := mock_ec2iface.NewMockEC2API(mockCtrl)
+			ec2Mock.EXPECT().
+				DescribeImages(gomock.AssignableToTypeOf(&ec2.DescribeImagesInput{})).
+				DoAndReturn(func(input *ec2.DescribeImagesInput) (*ec2.DescribeImagesOutput, error) {
+					for _, filter := range input.Filters {
+						if aws.StringValue(filter.Name) == "owner-id" && aws.StringValue(filter.Values[0]) != "123456789012" {
+							t.Fatalf("unexpected owner filter %v", filter.Values)
+						}
+						if aws.StringValue(filter.Name) == "name" && aws.StringValue(filter.Values[0]) != "my-ami-centos-7-1.16.1" {
+							t.Fatalf("unexpected name filter %v", filter.Values)
+						}
+					}
+					return &ec2.DescribeImagesOutput{Images: tc.images}, nil
+				})
+
+			image, err := DefaultAMILookup(ec2Mock, "123456789012", "centos-7", "my-ami-{{.BaseOS}}-{{.K8sVersion}}", "v1.16.1")
+			if tc.wantErr {
+				if err == nil {
+					t.Fatalf("expected an error but did not get one")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("did not expect error: %v", err)
+			}
+			if aws.StringValue(image.ImageId) != tc.want {
+				t.Fatalf("returned %q expected %q", aws.StringValue(image.ImageId), tc.want)
+			}
+		})
+	}
+}
+
+// fakeSSM returns a canned response to GetParameter.
+type fakeSSM struct {
+	ssmiface.SSMAPI
+	value *string
+	err   error
+}
+
+func (f *fakeSSM) GetParameter(input *ssm.GetParameterInput) (*ssm.GetParameterOutput, error) {
+	if f.err != nil {
+		return nil, f.err
+	}
+	return &ssm.GetParameterOutput{Parameter: &ssm.Parameter{Name: input.Name, Value: f.value}}, nil
+}
+
+func TestGetAMIFromSSMParameter(t *testing.T) {
+	testCases := []struct {
+		name    string
+		ssm     *fakeSSM
+		want    string
+		wantErr bool
+	}{
+		{
+			name: "parameter holding an AMI ID",
+			ssm:  &fakeSSM{value: aws.String("ami-1234\n")},
+			want: "ami-1234",
+		},
+		{
+			name:    "parameter not found",
+			ssm:     &fakeSSM{err: awserr.New(ssm.ErrCodeParameterNotFound, "not found", nil)},
+			wantErr: true,
+		},
+		{
+			name:    "parameter holding something else",
+			ssm:     &fakeSSM{value: aws.String("ubuntu")},
+			wantErr: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			scope, err := scope.NewClusterScope(scope.ClusterScopeParams{
+				Cluster:    &clusterv1.Cluster{},
+				AWSCluster: &infrav1.AWSCluster{},
+				AWSClients: scope.AWSClients{
+					SSM: tc.ssm,
+				},
+			})
+			if err != nil {
+				t.Fatalf("did not expect err: %v", err)
+			}
+
+			s := NewService(scope)
+			id, err := s.getAMIFromSSMParameter("/my-org/ami")
+			if tc.wantErr {
+				if _, ok := err.(*AMIResolutionError); !ok {
+					t.Fatalf("expected an AMIResolutionError, got %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("did not expect error: %v", err)
+			}
+			if id != tc.want {
+				t.Fatalf("returned %q expected %q", id, tc.want)
+			}
+		})
+	}
+}
@@ -27,6 +27,7 @@ import (
 	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/awserrors"
 	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/filter"
 	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/services/userdata"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/tags"
 	"sigs.k8s.io/cluster-api-provider-aws/pkg/record"
 )
 
@@ -41,13 +42,22 @@ func (s *Service) ReconcileBastion() error {
 		return nil
 	}
 
+	if !s.scope.BastionEnabled() {
+		s.scope.V(4).Info("Bastion host is disabled, removing it if it exists", "session-manager", s.scope.AWSCluster.Spec.SessionManager)
+		if err := s.DeleteBastion(); err != nil {
+			return err
+		}
+		s.scope.AWSCluster.Status.Bastion = infrav1.Instance{}
+		return nil
+	}
+
 	s.scope.V(2).Info("Reconciling bastion host")
 
 	subnets := s.scope.Subnets()
 	if len(subnets.FilterPrivate()) == 0 {
 		s.scope.V(2).Info("No private subnets available, skipping bastion host")
 		return nil
-	} else if len(subnets.FilterPublic()) == 0 {
+	} else if s.scope.AWSCluster.Spec.Bastion.IsPublic() && len(subnets.FilterPublic()) == 0 {
 		return errors.New("failed to reconcile bastion host, no public subnets are available")
 	}
 
@@ -71,6 +81,24 @@ func (s *Service) ReconcileBastion() error {
 
 	// TODO(vincepri): check for possible changes between the default spec and the instance.
 
+	// Make sure tags are up to date.
+	params := s.getBastionTagParams(fmt.Sprintf("%s-bastion", s.scope.Name()))
+	params.ResourceID = instance.ID
+	if err := tags.Ensure(instance.Tags, &tags.ApplyParams{
+		EC2Client:   s.scope.EC2,
+		BuildParams: params,
+		Removed:     s.scope.RemovedAdditionalTags(),
+	}); err != nil {
+		record.Warnf(s.scope.AWSCluster, "FailedTagBastion", "Failed to tag bastion instance %q: %v", instance.ID, err)
+		return errors.Wrapf(err, "failed to ensure tags on bastion instance %q", instance.ID)
+	}
+
+	if id := s.scope.AWSCluster.Spec.Bastion.ElasticIPAllocationID; id != "" {
+		if err := s.associateBastionAddress(instance, id); err != nil {
+			return err
+		}
+	}
+
 	instance.DeepCopyInto(&s.scope.AWSCluster.Status.Bastion)
 	s.scope.V(2).Info("Reconcile bastion completed successfully")
 	return nil
@@ -101,6 +129,43 @@ func (s *Service) DeleteBastion() error {
 	return nil
 }
 
+// associateBastionAddress associates the pre-allocated Elastic IP with the
+// bastion host. The Elastic IP isn't tagged as owned by the cluster, so it is
+// only disassociated when the bastion host is terminated, never released.
+func (s *Service) associateBastionAddress(instance *infrav1.Instance, allocationID string) error {
+	out, err := s.scope.EC2.DescribeAddresses(&ec2.DescribeAddressesInput{
+		AllocationIds: []*string{aws.String(allocationID)},
+	})
+	if err != nil {
+		return errors.Wrapf(err, "failed to describe elastic IP %q", allocationID)
+	}
+	if len(out.Addresses) == 0 {
+		return errors.Errorf("elastic IP %q was not found", allocationID)
+	}
+
+	address := out.Addresses[0]
+	switch {
+	case aws.StringValue(address.InstanceId) == instance.ID:
+		// Already associated with the bastion host.
+	case address.AssociationId != nil:
+		err := errors.Errorf("elastic IP %q is already associated with another resource", allocationID)
+		record.Warnf(s.scope.AWSCluster, "FailedAssociateBastionEIP", "Failed to associate Elastic IP %q with bastion instance %q: %v", allocationID, instance.ID, err)
+		return err
+	default:
+		if _, err := s.scope.EC2.AssociateAddress(&ec2.AssociateAddressInput{
+			AllocationId: aws.String(allocationID),
+			InstanceId:   aws.String(instance.ID),
+		}); err != nil {
+			record.Warnf(s.scope.AWSCluster, "FailedAssociateBastionEIP", "Failed to associate Elastic IP %q with bastion instance %q: %v", allocationID, instance.ID, err)
+			return errors.Wrapf(err, "failed to associate elastic IP %q with bastion instance %q", allocationID, instance.ID)
+		}
+		record.Eventf(s.scope.AWSCluster, "SuccessfulAssociateBastionEIP", "Associated Elastic IP %q with bastion instance %q", allocationID, instance.ID)
+	}
+
+	instance.PublicIP = address.PublicIp
+	return nil
+}
+
 func (s *Service) describeBastionInstance() (*infrav1.Instance, error) {
 	input := &ec2.DescribeInstancesInput{
 		Filters: []*ec2.Filter{
@@ -110,7 +175,11 @@ func (s *Service) describeBastionInstance() (*infrav1.Instance, error) {
 		},
 	}
 
-	out, err := s.scope.EC2.DescribeInstances(input)
+	var out *ec2.DescribeInstancesOutput
+	err := awserrors.RetryOnThrottling(func() (err error) {
+		out, err = s.scope.EC2.DescribeInstances(input)
+		return err
+	})
 	if err != nil {
 		return nil, errors.Wrap(err, "failed to describe bastion host")
 	}
@@ -131,29 +200,48 @@ func (s *Service) describeBastionInstance() (*infrav1.Instance, error) {
 func (s *Service) getDefaultBastion() *infrav1.Instance {
 	name := fmt.Sprintf("%s-bastion", s.scope.Name())
 	userData, _ := userdata.NewBastion(&userdata.BastionInput{})
+	userData, _ = gzipUserData(base64.StdEncoding.EncodeToString([]byte(userData)))
 
 	keyName := defaultSSHKeyName
 	if s.scope.AWSCluster.Spec.SSHKeyName != "" {
 		keyName = s.scope.AWSCluster.Spec.SSHKeyName
 	}
 
+	// A bastion without a public IP lives in a private subnet and is reached
+	// through private connectivity.
+	subnet := s.scope.Subnets().FilterPublic()
+	if !s.scope.AWSCluster.Spec.Bastion.IsPublic() {
+		subnet = s.scope.Subnets().FilterPrivate()
+	}
+
 	i := &infrav1.Instance{
 		Type:       "t2.micro",
-		SubnetID:   s.scope.Subnets().FilterPublic()[0].ID,
+		SubnetID:   subnet[0].ID,
 		ImageID:    s.defaultBastionAMILookup(s.scope.AWSCluster.Spec.Region),
 		SSHKeyName: aws.String(keyName),
-		UserData:   aws.String(base64.StdEncoding.EncodeToString([]byte(userData))),
+		UserData:   aws.String(userData),
 		SecurityGroupIDs: []string{
 			s.scope.Network().SecurityGroups[infrav1.SecurityGroupBastion].ID,
 		},
-		Tags: infrav1.Build(infrav1.BuildParams{
-			ClusterName: s.scope.Name(),
-			Lifecycle:   infrav1.ResourceLifecycleOwned,
-			Name:        aws.String(name),
-			Role:        aws.String(infrav1.BastionRoleTagValue),
-			Additional:  s.scope.AdditionalTags(),
-		}),
+		Tags: infrav1.Build(s.getBastionTagParams(name)),
+	}
+
+	// Don't rely on the subnet not mapping public IPs on launch, a private
+	// subnet brought by the user may still do so.
+	if !s.scope.AWSCluster.Spec.Bastion.IsPublic() {
+		ensurePrimaryNetworkInterface(i)
+		i.NetworkInterfaceSpecs[0].PublicIP = aws.Bool(false)
 	}
 
 	return i
 }
+
+func (s *Service) getBastionTagParams(name string) infrav1.BuildParams {
+	return infrav1.BuildParams{
+		ClusterName: s.scope.Name(),
+		Lifecycle:   infrav1.ResourceLifecycleOwned,
+		Name:        aws.String(name),
+		Role:        aws.String(infrav1.BastionRoleTagValue),
+		Additional:  s.scope.AdditionalTags(),
+	}
+}
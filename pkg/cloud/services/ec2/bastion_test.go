@@ -0,0 +1,371 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ec2
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/golang/mock/gomock"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	infrav1 "sigs.k8s.io/cluster-api-provider-aws/api/v1alpha3"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/scope"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/services/ec2/mock_ec2iface"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/services/elb/mock_elbiface"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1alpha3"
+)
+
+func TestReconcileBastionDisabled(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	testCases := []struct {
+		name   string
+		expect func(m *mock_ec2iface.MockEC2APIMockRecorder)
+	}{
+		{
+			name: "existing bastion is terminated",
+			expect: func(m *mock_ec2iface.MockEC2APIMockRecorder) {
+				m.DescribeInstances(gomock.AssignableToTypeOf(&ec2.DescribeInstancesInput{})).
+					Return(&ec2.DescribeInstancesOutput{
+						Reservations: []*ec2.Reservation{
+							{
+								Instances: []*ec2.Instance{
+									{
+										InstanceId:     aws.String("i-bastion"),
+										State:          &ec2.InstanceState{Name: aws.String(ec2.InstanceStateNameRunning)},
+										RootDeviceName: aws.String("/dev/sda1"),
+										BlockDeviceMappings: []*ec2.InstanceBlockDeviceMapping{
+											{
+												DeviceName: aws.String("/dev/sda1"),
+												Ebs:        &ec2.EbsInstanceBlockDevice{VolumeId: aws.String("vol-bastion")},
+											},
+										},
+									},
+								},
+							},
+						},
+					}, nil)
+				m.DescribeVolumes(gomock.Eq(&ec2.DescribeVolumesInput{
+					VolumeIds: []*string{aws.String("vol-bastion")},
+				})).
+					Return(&ec2.DescribeVolumesOutput{
+						Volumes: []*ec2.Volume{{Size: aws.Int64(8)}},
+					}, nil)
+				m.TerminateInstances(gomock.Eq(&ec2.TerminateInstancesInput{
+					InstanceIds: aws.StringSlice([]string{"i-bastion"}),
+				})).
+					Return(&ec2.TerminateInstancesOutput{}, nil)
+				m.WaitUntilInstanceTerminated(gomock.Eq(&ec2.DescribeInstancesInput{
+					InstanceIds: aws.StringSlice([]string{"i-bastion"}),
+				})).
+					Return(nil)
+			},
+		},
+		{
+			name: "no bastion to terminate",
+			expect: func(m *mock_ec2iface.MockEC2APIMockRecorder) {
+				m.DescribeInstances(gomock.AssignableToTypeOf(&ec2.DescribeInstancesInput{})).
+					Return(&ec2.DescribeInstancesOutput{}, nil)
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ec2Mock := mock_ec2iface.NewMockEC2API(mockCtrl)
+			elbMock := mock_elbiface.NewMockELBAPI(mockCtrl)
+
+			scope, err := scope.NewClusterScope(scope.ClusterScopeParams{
+				Cluster: &clusterv1.Cluster{
+					ObjectMeta: metav1.ObjectMeta{Name: "test-cluster"},
+				},
+				AWSClients: scope.AWSClients{
+					EC2: ec2Mock,
+					ELB: elbMock,
+				},
+				AWSCluster: &infrav1.AWSCluster{
+					Spec: infrav1.AWSClusterSpec{
+						NetworkSpec: infrav1.NetworkSpec{
+							VPC: infrav1.VPCSpec{
+								ID: "vpc-bastion",
+								Tags: infrav1.Tags{
+									infrav1.ClusterTagKey("test-cluster"): "owned",
+								},
+							},
+						},
+						Bastion: infrav1.Bastion{Enabled: aws.Bool(false)},
+					},
+					Status: infrav1.AWSClusterStatus{
+						Bastion: infrav1.Instance{ID: "i-bastion"},
+					},
+				},
+			})
+			if err != nil {
+				t.Fatalf("Failed to create test context: %v", err)
+			}
+
+			tc.expect(ec2Mock.EXPECT())
+
+			s := NewService(scope)
+			if err := s.ReconcileBastion(); err != nil {
+				t.Fatalf("got an unexpected error: %v", err)
+			}
+			if scope.AWSCluster.Status.Bastion.ID != "" {
+				t.Fatalf("expected the bastion status to be cleared, got %+v", scope.AWSCluster.Status.Bastion)
+			}
+		})
+	}
+}
+
+func TestBastionSSHIngressRules(t *testing.T) {
+	scope, err := scope.NewClusterScope(scope.ClusterScopeParams{
+		Cluster: &clusterv1.Cluster{
+			ObjectMeta: metav1.ObjectMeta{Name: "test-cluster"},
+		},
+		AWSCluster: &infrav1.AWSCluster{
+			Status: infrav1.AWSClusterStatus{
+				Network: infrav1.Network{
+					SecurityGroups: map[infrav1.SecurityGroupRole]infrav1.SecurityGroup{
+						infrav1.SecurityGroupBastion:      {ID: "sg-bastion"},
+						infrav1.SecurityGroupControlPlane: {ID: "sg-control"},
+						infrav1.SecurityGroupNode:         {ID: "sg-node"},
+					},
+				},
+			},
+		},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create test context: %v", err)
+	}
+	s := NewService(scope)
+
+	hasSSH := func(role infrav1.SecurityGroupRole) bool {
+		rules, err := s.getSecurityGroupIngressRules(role)
+		if err != nil {
+			t.Fatalf("got an unexpected error: %v", err)
+		}
+		for _, rule := range rules {
+			if rule.FromPort == 22 {
+				return true
+			}
+		}
+		return false
+	}
+
+	roles := []infrav1.SecurityGroupRole{
+		infrav1.SecurityGroupBastion,
+		infrav1.SecurityGroupControlPlane,
+		infrav1.SecurityGroupNode,
+	}
+
+	for _, role := range roles {
+		if !hasSSH(role) {
+			t.Errorf("expected an SSH ingress rule for role %q with the bastion enabled", role)
+		}
+	}
+
+	scope.AWSCluster.Spec.Bastion.Enabled = aws.Bool(false)
+	for _, role := range roles {
+		if hasSSH(role) {
+			t.Errorf("expected no SSH ingress rule for role %q with the bastion disabled", role)
+		}
+	}
+}
+
+func TestAssociateBastionAddress(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	describeInput := &ec2.DescribeAddressesInput{AllocationIds: aws.StringSlice([]string{"eipalloc-bastion"})}
+
+	testCases := []struct {
+		name    string
+		expect  func(m *mock_ec2iface.MockEC2APIMockRecorder)
+		wantErr bool
+	}{
+		{
+			name: "unassociated elastic ip is associated with the bastion",
+			expect: func(m *mock_ec2iface.MockEC2APIMockRecorder) {
+				m.DescribeAddresses(gomock.Eq(describeInput)).
+					Return(&ec2.DescribeAddressesOutput{
+						Addresses: []*ec2.Address{
+							{AllocationId: aws.String("eipalloc-bastion"), PublicIp: aws.String("203.0.113.10")},
+						},
+					}, nil)
+				m.AssociateAddress(gomock.Eq(&ec2.AssociateAddressInput{
+					AllocationId: aws.String("eipalloc-bastion"),
+					InstanceId:   aws.String("i-bastion"),
+				})).
+					Return(&ec2.AssociateAddressOutput{}, nil)
+			},
+		},
+		{
+			name: "elastic ip already associated with the bastion",
+			expect: func(m *mock_ec2iface.MockEC2APIMockRecorder) {
+				m.DescribeAddresses(gomock.Eq(describeInput)).
+					Return(&ec2.DescribeAddressesOutput{
+						Addresses: []*ec2.Address{
+							{
+								AllocationId:  aws.String("eipalloc-bastion"),
+								AssociationId: aws.String("eipassoc-bastion"),
+								InstanceId:    aws.String("i-bastion"),
+								PublicIp:      aws.String("203.0.113.10"),
+							},
+						},
+					}, nil)
+			},
+		},
+		{
+			name: "elastic ip associated with another resource",
+			expect: func(m *mock_ec2iface.MockEC2APIMockRecorder) {
+				m.DescribeAddresses(gomock.Eq(describeInput)).
+					Return(&ec2.DescribeAddressesOutput{
+						Addresses: []*ec2.Address{
+							{
+								AllocationId:       aws.String("eipalloc-bastion"),
+								AssociationId:      aws.String("eipassoc-other"),
+								NetworkInterfaceId: aws.String("eni-other"),
+								PublicIp:           aws.String("203.0.113.10"),
+							},
+						},
+					}, nil)
+			},
+			wantErr: true,
+		},
+		{
+			name: "elastic ip not found",
+			expect: func(m *mock_ec2iface.MockEC2APIMockRecorder) {
+				m.DescribeAddresses(gomock.Eq(describeInput)).
+					Return(&ec2.DescribeAddressesOutput{}, nil)
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ec2Mock := mock_ec2iface.NewMockEC2API(mockCtrl)
+
+			scope, err := scope.NewClusterScope(scope.ClusterScopeParams{
+				Cluster: &clusterv1.Cluster{
+					ObjectMeta: metav1.ObjectMeta{Name: "test-cluster"},
+				},
+				AWSClients: scope.AWSClients{
+					EC2: ec2Mock,
+				},
+				AWSCluster: &infrav1.AWSCluster{
+					Spec: infrav1.AWSClusterSpec{
+						Bastion: infrav1.Bastion{ElasticIPAllocationID: "eipalloc-bastion"},
+					},
+				},
+			})
+			if err != nil {
+				t.Fatalf("Failed to create test context: %v", err)
+			}
+
+			tc.expect(ec2Mock.EXPECT())
+
+			s := NewService(scope)
+			instance := &infrav1.Instance{ID: "i-bastion"}
+			err = s.associateBastionAddress(instance, "eipalloc-bastion")
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("associateBastionAddress() error = %v, wantErr %v", err, tc.wantErr)
+			}
+			if !tc.wantErr && aws.StringValue(instance.PublicIP) != "203.0.113.10" {
+				t.Errorf("expected the bastion public IP to be the elastic IP, got %q", aws.StringValue(instance.PublicIP))
+			}
+		})
+	}
+}
+
+func TestGetDefaultBastion(t *testing.T) {
+	testCases := []struct {
+		name                   string
+		publicIP               *bool
+		expectedSubnet         string
+		expectedAssociatePubIP *bool
+	}{
+		{
+			name:           "public bastion is launched in a public subnet",
+			expectedSubnet: "subnet-public",
+		},
+		{
+			name:                   "private bastion is launched in a private subnet without a public ip",
+			publicIP:               aws.Bool(false),
+			expectedSubnet:         "subnet-private",
+			expectedAssociatePubIP: aws.Bool(false),
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			scope, err := scope.NewClusterScope(scope.ClusterScopeParams{
+				Cluster: &clusterv1.Cluster{
+					ObjectMeta: metav1.ObjectMeta{Name: "test-cluster"},
+				},
+				AWSCluster: &infrav1.AWSCluster{
+					Spec: infrav1.AWSClusterSpec{
+						Region: "us-east-1",
+						NetworkSpec: infrav1.NetworkSpec{
+							Subnets: infrav1.Subnets{
+								{ID: "subnet-public", IsPublic: true},
+								{ID: "subnet-private", IsPublic: false},
+							},
+						},
+						Bastion: infrav1.Bastion{PublicIP: tc.publicIP},
+					},
+					Status: infrav1.AWSClusterStatus{
+						Network: infrav1.Network{
+							SecurityGroups: map[infrav1.SecurityGroupRole]infrav1.SecurityGroup{
+								infrav1.SecurityGroupBastion: {ID: "sg-bastion"},
+							},
+						},
+					},
+				},
+			})
+			if err != nil {
+				t.Fatalf("Failed to create test context: %v", err)
+			}
+
+			s := NewService(scope)
+			bastion := s.getDefaultBastion()
+			if bastion.SubnetID != tc.expectedSubnet {
+				t.Fatalf("expected the bastion in subnet %q, got %q", tc.expectedSubnet, bastion.SubnetID)
+			}
+
+			specifications := getNetworkInterfaceSpecifications(bastion)
+			if tc.expectedAssociatePubIP == nil {
+				if len(specifications) != 0 {
+					t.Fatalf("expected the public IP to be left to the subnet, got %v", specifications)
+				}
+				return
+			}
+			if len(specifications) != 1 {
+				t.Fatalf("expected a single network interface, got %v", specifications)
+			}
+			if !reflect.DeepEqual(specifications[0].AssociatePublicIpAddress, tc.expectedAssociatePubIP) {
+				t.Fatalf("expected AssociatePublicIpAddress %v, got %v", aws.BoolValue(tc.expectedAssociatePubIP), specifications[0].AssociatePublicIpAddress)
+			}
+			if aws.StringValue(specifications[0].SubnetId) != tc.expectedSubnet {
+				t.Fatalf("expected the network interface in subnet %q, got %q", tc.expectedSubnet, aws.StringValue(specifications[0].SubnetId))
+			}
+		})
+	}
+}
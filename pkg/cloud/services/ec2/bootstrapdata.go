@@ -0,0 +1,174 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ec2
+
+import (
+	"encoding/base64"
+	"fmt"
+	"path"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ssm"
+	"github.com/pkg/errors"
+	infrav1 "sigs.k8s.io/cluster-api-provider-aws/api/v1alpha3"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/awserrors"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/scope"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/services/secretsmanager"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/services/userdata"
+)
+
+const (
+	// bootstrapDataFile is where the instance writes its bootstrap data once
+	// fetched from its secrets backend.
+	bootstrapDataFile = "/etc/secret-userdata.txt"
+
+	// maxParameterValueLength is the size limit of a standard SSM parameter.
+	maxParameterValueLength = 4096
+
+	// maxDeleteParameters is the number of parameters DeleteParameters
+	// accepts at once.
+	maxDeleteParameters = 10
+)
+
+// bootstrapDataParameterPath returns the path of the SSM parameters holding
+// the bootstrap data of the machine. The role in the path lets the instance
+// profiles only read the parameters of their kind of machines.
+func bootstrapDataParameterPath(scope *scope.MachineScope) string {
+	return path.Join(infrav1.BootstrapDataParameterPrefix, scope.Role(), scope.Namespace(), scope.Name())
+}
+
+// storeBootstrapData stores the gzipped, base64 encoded bootstrap data of the
+// machine in its secrets backend and returns the user data fetching it from
+// the instance.
+func (s *Service) storeBootstrapData(scope *scope.MachineScope, userData string) (string, error) {
+	input := &userdata.SecretFetchInput{File: bootstrapDataFile}
+
+	var err error
+	switch backend := scope.SecureSecretsBackend(); backend {
+	case infrav1.SecretBackendSSMParameterStore:
+		input.Chunks, err = s.putBootstrapDataParameters(scope, userData)
+		input.FetchCommand = fmt.Sprintf(`aws ssm get-parameter --region "%s" --with-decryption --name "%s/$1" --query Parameter.Value --output text`, s.scope.Region(), bootstrapDataParameterPath(scope))
+	case infrav1.SecretBackendSecretsManager:
+		input.Chunks, err = secretsmanager.NewService(s.scope).Create(scope, userData)
+		input.FetchCommand = secretsmanager.FetchCommand(s.scope.Region(), secretsmanager.SecretPrefix(scope))
+	default:
+		return "", errors.Errorf("unknown secrets backend %q", backend)
+	}
+	if err != nil {
+		return "", err
+	}
+
+	fetch, err := userdata.NewSecretFetch(input)
+	if err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString([]byte(fetch)), nil
+}
+
+// putBootstrapDataParameters stores the bootstrap data of the machine in SSM
+// Parameter Store, split in as many parameters as needed, and returns their
+// number.
+func (s *Service) putBootstrapDataParameters(scope *scope.MachineScope, userData string) (int, error) {
+	parameterPath := bootstrapDataParameterPath(scope)
+	chunks := splitString(userData, maxParameterValueLength)
+
+	for i, chunk := range chunks {
+		name := fmt.Sprintf("%s/%d", parameterPath, i)
+		input := &ssm.PutParameterInput{
+			Name:        aws.String(name),
+			Description: aws.String(fmt.Sprintf("Bootstrap data of machine %s/%s, part %d of %d", scope.Namespace(), scope.Name(), i+1, len(chunks))),
+			Type:        aws.String(ssm.ParameterTypeSecureString),
+			Tier:        aws.String(ssm.ParameterTierStandard),
+			Value:       aws.String(chunk),
+			Overwrite:   aws.Bool(true),
+		}
+		if err := awserrors.RetryOnThrottling(func() error {
+			_, err := s.scope.SSM.PutParameter(input)
+			return err
+		}); err != nil {
+			return 0, errors.Wrapf(err, "failed to store bootstrap data in SSM parameter %q", name)
+		}
+	}
+
+	s.scope.V(2).Info("Stored bootstrap data in SSM Parameter Store", "path", parameterPath, "parameters", len(chunks))
+	return len(chunks), nil
+}
+
+// DeleteBootstrapData deletes the bootstrap data of the machine from its
+// secrets backend. Nothing is done when it was already deleted.
+func (s *Service) DeleteBootstrapData(scope *scope.MachineScope) error {
+	switch backend := scope.SecureSecretsBackend(); backend {
+	case infrav1.SecretBackendSSMParameterStore:
+		return s.deleteBootstrapDataParameters(scope)
+	case infrav1.SecretBackendSecretsManager:
+		return secretsmanager.NewService(s.scope).Delete(scope)
+	default:
+		return errors.Errorf("unknown secrets backend %q", backend)
+	}
+}
+
+// deleteBootstrapDataParameters deletes the SSM parameters holding the
+// bootstrap data of the machine.
+func (s *Service) deleteBootstrapDataParameters(scope *scope.MachineScope) error {
+	parameterPath := bootstrapDataParameterPath(scope)
+
+	names := []string{}
+	err := awserrors.RetryOnThrottling(func() error {
+		names = names[:0]
+		return s.scope.SSM.GetParametersByPathPages(&ssm.GetParametersByPathInput{
+			Path: aws.String(parameterPath),
+		}, func(out *ssm.GetParametersByPathOutput, _ bool) bool {
+			for _, p := range out.Parameters {
+				names = append(names, aws.StringValue(p.Name))
+			}
+			return true
+		})
+	})
+	if err != nil {
+		return errors.Wrapf(err, "failed to list SSM parameters under %q", parameterPath)
+	}
+
+	for len(names) > 0 {
+		batch := names
+		if len(batch) > maxDeleteParameters {
+			batch = batch[:maxDeleteParameters]
+		}
+		names = names[len(batch):]
+
+		if err := awserrors.RetryOnThrottling(func() error {
+			_, err := s.scope.SSM.DeleteParameters(&ssm.DeleteParametersInput{
+				Names: aws.StringSlice(batch),
+			})
+			return err
+		}); err != nil {
+			return errors.Wrapf(err, "failed to delete SSM parameters under %q", parameterPath)
+		}
+		s.scope.V(2).Info("Deleted bootstrap data from SSM Parameter Store", "parameters", batch)
+	}
+
+	return nil
+}
+
+// splitString splits s in chunks of at most size bytes.
+func splitString(s string, size int) []string {
+	chunks := make([]string, 0, len(s)/size+1)
+	for len(s) > size {
+		chunks = append(chunks, s[:size])
+		s = s[size:]
+	}
+	return append(chunks, s)
+}
@@ -0,0 +1,161 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ec2
+
+import (
+	"encoding/base64"
+	"fmt"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ssm"
+	"github.com/aws/aws-sdk-go/service/ssm/ssmiface"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	infrav1 "sigs.k8s.io/cluster-api-provider-aws/api/v1alpha3"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/scope"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1alpha3"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+// fakeParameterStore keeps the parameters in memory, listing them one per page.
+type fakeParameterStore struct {
+	ssmiface.SSMAPI
+	parameters map[string]string
+}
+
+func (f *fakeParameterStore) PutParameter(input *ssm.PutParameterInput) (*ssm.PutParameterOutput, error) {
+	if aws.StringValue(input.Type) != ssm.ParameterTypeSecureString {
+		return nil, fmt.Errorf("parameter %q is not a SecureString", aws.StringValue(input.Name))
+	}
+	if len(aws.StringValue(input.Value)) > maxParameterValueLength {
+		return nil, fmt.Errorf("parameter %q is too long", aws.StringValue(input.Name))
+	}
+	f.parameters[aws.StringValue(input.Name)] = aws.StringValue(input.Value)
+	return &ssm.PutParameterOutput{}, nil
+}
+
+func (f *fakeParameterStore) GetParametersByPathPages(input *ssm.GetParametersByPathInput, fn func(*ssm.GetParametersByPathOutput, bool) bool) error {
+	names := []string{}
+	for name := range f.parameters {
+		if strings.HasPrefix(name, aws.StringValue(input.Path)+"/") {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	for i, name := range names {
+		out := &ssm.GetParametersByPathOutput{Parameters: []*ssm.Parameter{{Name: aws.String(name)}}}
+		if !fn(out, i == len(names)-1) {
+			break
+		}
+	}
+	return nil
+}
+
+func (f *fakeParameterStore) DeleteParameters(input *ssm.DeleteParametersInput) (*ssm.DeleteParametersOutput, error) {
+	if len(input.Names) > maxDeleteParameters {
+		return nil, fmt.Errorf("too many parameters: %d", len(input.Names))
+	}
+	for _, name := range input.Names {
+		delete(f.parameters, aws.StringValue(name))
+	}
+	return &ssm.DeleteParametersOutput{}, nil
+}
+
+func TestBootstrapDataInParameterStore(t *testing.T) {
+	store := &fakeParameterStore{
+		parameters: map[string]string{
+			"/cluster.x-k8s.io/aws/node/default/other/0": "other",
+		},
+	}
+
+	cluster := &clusterv1.Cluster{ObjectMeta: metav1.ObjectMeta{Name: "test-cluster"}}
+	awsCluster := &infrav1.AWSCluster{Spec: infrav1.AWSClusterSpec{Region: "eu-west-1"}}
+	clusterScope, err := scope.NewClusterScope(scope.ClusterScopeParams{
+		Cluster:    cluster,
+		AWSCluster: awsCluster,
+		AWSClients: scope.AWSClients{SSM: store},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create test context: %v", err)
+	}
+	machineScope, err := scope.NewMachineScope(scope.MachineScopeParams{
+		Client:     fake.NewFakeClient(),
+		Cluster:    cluster,
+		Machine:    &clusterv1.Machine{},
+		AWSCluster: awsCluster,
+		AWSMachine: &infrav1.AWSMachine{
+			ObjectMeta: metav1.ObjectMeta{Name: "worker", Namespace: "default"},
+			Spec: infrav1.AWSMachineSpec{
+				CloudInit: &infrav1.CloudInit{SecureSecretsBackend: infrav1.SecretBackendSSMParameterStore},
+			},
+		},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create test context: %v", err)
+	}
+
+	s := NewService(clusterScope)
+
+	data := strings.Repeat("a", 2*maxParameterValueLength+1)
+	userData, err := s.storeBootstrapData(machineScope, data)
+	if err != nil {
+		t.Fatalf("got an unexpected error: %v", err)
+	}
+
+	var stored strings.Builder
+	for i := 0; i < 3; i++ {
+		stored.WriteString(store.parameters[fmt.Sprintf("/cluster.x-k8s.io/aws/node/default/worker/%d", i)])
+	}
+	if stored.String() != data {
+		t.Fatalf("expected the bootstrap data to be split in 3 parameters, got %v", store.parameters)
+	}
+
+	decoded, err := base64.StdEncoding.DecodeString(userData)
+	if err != nil {
+		t.Fatalf("expected base64 encoded user data: %v", err)
+	}
+	for _, expected := range []string{
+		"Content-Type: text/cloud-boothook",
+		`--region "eu-west-1"`,
+		`--name "/cluster.x-k8s.io/aws/node/default/worker/$1"`,
+		"$(seq 0 $((3 - 1)))",
+		"file:///etc/secret-userdata.txt",
+	} {
+		if !strings.Contains(string(decoded), expected) {
+			t.Fatalf("expected user data to contain %q, got:\n%s", expected, decoded)
+		}
+	}
+	if strings.Contains(string(decoded), data[:100]) {
+		t.Fatalf("expected the bootstrap data not to be in the user data")
+	}
+
+	// Left over parameters of a previous launch are deleted too.
+	for i := 3; i < 12; i++ {
+		store.parameters[fmt.Sprintf("/cluster.x-k8s.io/aws/node/default/worker/%d", i)] = "stale"
+	}
+	if err := s.DeleteBootstrapData(machineScope); err != nil {
+		t.Fatalf("got an unexpected error: %v", err)
+	}
+	if len(store.parameters) != 1 || store.parameters["/cluster.x-k8s.io/aws/node/default/other/0"] == "" {
+		t.Fatalf("expected only the parameters of the machine to be deleted, got %v", store.parameters)
+	}
+	if err := s.DeleteBootstrapData(machineScope); err != nil {
+		t.Fatalf("expected deleting the bootstrap data twice to succeed, got %v", err)
+	}
+}
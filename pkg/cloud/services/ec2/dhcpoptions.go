@@ -0,0 +1,281 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ec2
+
+import (
+	"fmt"
+	"reflect"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/pkg/errors"
+	infrav1 "sigs.k8s.io/cluster-api-provider-aws/api/v1alpha3"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/awserrors"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/converters"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/filter"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/services/wait"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/tags"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/record"
+)
+
+const (
+	// defaultDHCPOptionsID is associated with a VPC to stop using a DHCP
+	// option set, its instances then get the Amazon provided DNS server.
+	defaultDHCPOptionsID = "default"
+
+	dhcpOptionDomainName        = "domain-name"
+	dhcpOptionDomainNameServers = "domain-name-servers"
+	dhcpOptionNTPServers        = "ntp-servers"
+)
+
+func (s *Service) reconcileDHCPOptions() error {
+	if s.scope.VPC().IsUnmanaged(s.scope.Name()) {
+		s.scope.V(4).Info("Skipping DHCP options reconcile in unmanaged mode")
+		return nil
+	}
+
+	spec := s.scope.DHCPOptions()
+	if spec == nil {
+		// Remove the DHCP option set if it is no longer wanted.
+		return s.deleteDHCPOptions()
+	}
+
+	s.scope.V(2).Info("Reconciling DHCP options")
+
+	current, err := s.describeClusterDHCPOptions()
+	if err != nil {
+		return err
+	}
+
+	// DHCP option sets can't be modified, a new set is created when the spec
+	// changes and the previous ones are deleted once the VPC stopped using them.
+	var options *ec2.DhcpOptions
+	var stale []*ec2.DhcpOptions
+	for _, o := range current {
+		if options == nil && dhcpOptionsMatch(o, spec) {
+			options = o
+			continue
+		}
+		stale = append(stale, o)
+	}
+
+	if options == nil {
+		options, err = s.createDHCPOptions(spec)
+		if err != nil {
+			return err
+		}
+	} else {
+		// Make sure tags are up to date.
+		if err := wait.WaitForWithRetryable(wait.NewBackoff(), func() (bool, error) {
+			if err := tags.Ensure(converters.TagsToMap(options.Tags), &tags.ApplyParams{
+				EC2Client:   s.scope.EC2,
+				BuildParams: s.getDHCPOptionsTagParams(*options.DhcpOptionsId),
+				Removed:     s.scope.RemovedAdditionalTags(),
+			}); err != nil {
+				return false, err
+			}
+			return true, nil
+		}, awserrors.DHCPOptionsNotFound); err != nil {
+			record.Warnf(s.scope.AWSCluster, "FailedTagDHCPOptions", "Failed to tag managed DHCPOptions %q: %v", *options.DhcpOptionsId, err)
+			return errors.Wrapf(err, "failed to ensure tags on DHCP options %q", *options.DhcpOptionsId)
+		}
+	}
+
+	if err := s.associateDHCPOptions(*options.DhcpOptionsId); err != nil {
+		return err
+	}
+
+	for _, o := range stale {
+		if err := s.deleteDHCPOptionsSet(*o.DhcpOptionsId); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (s *Service) deleteDHCPOptions() error {
+	if s.scope.VPC().IsUnmanaged(s.scope.Name()) {
+		s.scope.V(4).Info("Skipping DHCP options deletion in unmanaged mode")
+		return nil
+	}
+
+	current, err := s.describeClusterDHCPOptions()
+	if err != nil {
+		return err
+	}
+
+	if len(current) == 0 {
+		return nil
+	}
+
+	// A DHCP option set cannot be deleted while a VPC uses it, hand the VPC
+	// back to the default options.
+	if s.scope.VPC().ID != "" {
+		if err := s.associateDHCPOptions(defaultDHCPOptionsID); err != nil {
+			if code, _ := awserrors.Code(errors.Cause(err)); code != awserrors.VPCNotFound {
+				return err
+			}
+		}
+	}
+
+	for _, o := range current {
+		if err := s.deleteDHCPOptionsSet(*o.DhcpOptionsId); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (s *Service) deleteDHCPOptionsSet(id string) error {
+	if _, err := s.scope.EC2.DeleteDhcpOptions(&ec2.DeleteDhcpOptionsInput{DhcpOptionsId: aws.String(id)}); err != nil {
+		if code, _ := awserrors.Code(errors.Cause(err)); code == awserrors.DHCPOptionsNotFound {
+			return nil
+		}
+		record.Warnf(s.scope.AWSCluster, "FailedDeleteDHCPOptions", "Failed to delete managed DHCPOptions %q: %v", id, err)
+		return errors.Wrapf(err, "failed to delete DHCP options %q", id)
+	}
+
+	record.Eventf(s.scope.AWSCluster, "SuccessfulDeleteDHCPOptions", "Deleted managed DHCPOptions %q", id)
+	s.scope.Info("Deleted DHCP options", "dhcp-options-id", id)
+	return nil
+}
+
+func (s *Service) describeClusterDHCPOptions() ([]*ec2.DhcpOptions, error) {
+	out, err := s.scope.EC2.DescribeDhcpOptions(&ec2.DescribeDhcpOptionsInput{
+		Filters: []*ec2.Filter{
+			filter.EC2.ClusterOwned(s.scope.Name()),
+		},
+	})
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to describe DHCP options of cluster %q", s.scope.Name())
+	}
+
+	return out.DhcpOptions, nil
+}
+
+func (s *Service) createDHCPOptions(spec *infrav1.DHCPOptionsSpec) (*ec2.DhcpOptions, error) {
+	out, err := s.scope.EC2.CreateDhcpOptions(&ec2.CreateDhcpOptionsInput{
+		DhcpConfigurations: dhcpConfigurations(spec),
+	})
+	if err != nil {
+		record.Warnf(s.scope.AWSCluster, "FailedCreateDHCPOptions", "Failed to create managed DHCPOptions: %v", err)
+		return nil, errors.Wrap(err, "failed to create DHCP options")
+	}
+	record.Eventf(s.scope.AWSCluster, "SuccessfulCreateDHCPOptions", "Created managed DHCPOptions %q", *out.DhcpOptions.DhcpOptionsId)
+
+	if err := wait.WaitForWithRetryable(wait.NewBackoff(), func() (bool, error) {
+		if err := tags.Apply(&tags.ApplyParams{
+			EC2Client:   s.scope.EC2,
+			BuildParams: s.getDHCPOptionsTagParams(*out.DhcpOptions.DhcpOptionsId),
+		}); err != nil {
+			return false, err
+		}
+		return true, nil
+	}, awserrors.DHCPOptionsNotFound); err != nil {
+		record.Warnf(s.scope.AWSCluster, "FailedTagDHCPOptions", "Failed to tag managed DHCPOptions %q: %v", *out.DhcpOptions.DhcpOptionsId, err)
+		return nil, errors.Wrapf(err, "failed to tag DHCP options %q", *out.DhcpOptions.DhcpOptionsId)
+	}
+	record.Eventf(s.scope.AWSCluster, "SuccessfulTagDHCPOptions", "Tagged managed DHCPOptions %q", *out.DhcpOptions.DhcpOptionsId)
+
+	return out.DhcpOptions, nil
+}
+
+// associateDHCPOptions makes the VPC use the given DHCP option set, unless
+// it already does.
+func (s *Service) associateDHCPOptions(id string) error {
+	out, err := s.scope.EC2.DescribeVpcs(&ec2.DescribeVpcsInput{
+		VpcIds: aws.StringSlice([]string{s.scope.VPC().ID}),
+	})
+	if err != nil {
+		return errors.Wrapf(err, "failed to describe vpc %q", s.scope.VPC().ID)
+	}
+
+	if len(out.Vpcs) == 0 {
+		return errors.Errorf("vpc %q not found", s.scope.VPC().ID)
+	}
+
+	if aws.StringValue(out.Vpcs[0].DhcpOptionsId) == id {
+		return nil
+	}
+
+	if _, err := s.scope.EC2.AssociateDhcpOptions(&ec2.AssociateDhcpOptionsInput{
+		DhcpOptionsId: aws.String(id),
+		VpcId:         aws.String(s.scope.VPC().ID),
+	}); err != nil {
+		record.Warnf(s.scope.AWSCluster, "FailedAssociateDHCPOptions", "Failed to associate DHCPOptions %q with VPC %q: %v", id, s.scope.VPC().ID, err)
+		return errors.Wrapf(err, "failed to associate DHCP options %q with vpc %q", id, s.scope.VPC().ID)
+	}
+
+	record.Eventf(s.scope.AWSCluster, "SuccessfulAssociateDHCPOptions", "Associated DHCPOptions %q with VPC %q", id, s.scope.VPC().ID)
+	return nil
+}
+
+func (s *Service) getDHCPOptionsTagParams(id string) infrav1.BuildParams {
+	name := fmt.Sprintf("%s-dhcp-options", s.scope.Name())
+
+	return infrav1.BuildParams{
+		ClusterName: s.scope.Name(),
+		ResourceID:  id,
+		Lifecycle:   infrav1.ResourceLifecycleOwned,
+		Name:        aws.String(name),
+		Role:        aws.String(infrav1.CommonRoleTagValue),
+		Additional:  s.scope.AdditionalTags(),
+	}
+}
+
+// dhcpOptionValues returns the values of each option of the spec, the
+// servers in their order of preference.
+func dhcpOptionValues(spec *infrav1.DHCPOptionsSpec) map[string][]string {
+	values := make(map[string][]string)
+	if spec.DomainName != "" {
+		values[dhcpOptionDomainName] = []string{spec.DomainName}
+	}
+	if len(spec.DomainNameServers) > 0 {
+		values[dhcpOptionDomainNameServers] = spec.DomainNameServers
+	}
+	if len(spec.NTPServers) > 0 {
+		values[dhcpOptionNTPServers] = spec.NTPServers
+	}
+	return values
+}
+
+func dhcpConfigurations(spec *infrav1.DHCPOptionsSpec) []*ec2.NewDhcpConfiguration {
+	options := dhcpOptionValues(spec)
+
+	var configurations []*ec2.NewDhcpConfiguration
+	for _, key := range []string{dhcpOptionDomainName, dhcpOptionDomainNameServers, dhcpOptionNTPServers} {
+		if values, ok := options[key]; ok {
+			configurations = append(configurations, &ec2.NewDhcpConfiguration{
+				Key:    aws.String(key),
+				Values: aws.StringSlice(values),
+			})
+		}
+	}
+	return configurations
+}
+
+func dhcpOptionsMatch(options *ec2.DhcpOptions, spec *infrav1.DHCPOptionsSpec) bool {
+	current := make(map[string][]string)
+	for _, c := range options.DhcpConfigurations {
+		for _, v := range c.Values {
+			current[aws.StringValue(c.Key)] = append(current[aws.StringValue(c.Key)], aws.StringValue(v.Value))
+		}
+	}
+	return reflect.DeepEqual(current, dhcpOptionValues(spec))
+}
@@ -0,0 +1,204 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ec2
+
+import (
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/golang/mock/gomock"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	infrav1 "sigs.k8s.io/cluster-api-provider-aws/api/v1alpha3"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/scope"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/services/ec2/mock_ec2iface"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1alpha3"
+)
+
+func TestReconcileDHCPOptions(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	spec := &infrav1.DHCPOptionsSpec{
+		DomainName:        "corp.example.com",
+		DomainNameServers: []string{"10.0.0.2", "10.0.0.3"},
+	}
+
+	testCases := []struct {
+		name      string
+		spec      *infrav1.DHCPOptionsSpec
+		unmanaged bool
+		expect    func(m *mock_ec2iface.MockEC2APIMockRecorder)
+	}{
+		{
+			name: "option set is created and associated with the vpc",
+			spec: spec,
+			expect: func(m *mock_ec2iface.MockEC2APIMockRecorder) {
+				m.DescribeDhcpOptions(gomock.AssignableToTypeOf(&ec2.DescribeDhcpOptionsInput{})).
+					Return(&ec2.DescribeDhcpOptionsOutput{}, nil)
+				m.CreateDhcpOptions(gomock.Eq(&ec2.CreateDhcpOptionsInput{
+					DhcpConfigurations: []*ec2.NewDhcpConfiguration{
+						{Key: aws.String("domain-name"), Values: aws.StringSlice([]string{"corp.example.com"})},
+						{Key: aws.String("domain-name-servers"), Values: aws.StringSlice([]string{"10.0.0.2", "10.0.0.3"})},
+					},
+				})).
+					Return(&ec2.CreateDhcpOptionsOutput{DhcpOptions: &ec2.DhcpOptions{DhcpOptionsId: aws.String("dopt-cluster")}}, nil)
+				m.CreateTags(gomock.AssignableToTypeOf(&ec2.CreateTagsInput{})).
+					Return(&ec2.CreateTagsOutput{}, nil)
+				m.DescribeVpcs(gomock.AssignableToTypeOf(&ec2.DescribeVpcsInput{})).
+					Return(&ec2.DescribeVpcsOutput{
+						Vpcs: []*ec2.Vpc{{VpcId: aws.String("vpc-dhcp"), DhcpOptionsId: aws.String("dopt-region")}},
+					}, nil)
+				m.AssociateDhcpOptions(gomock.Eq(&ec2.AssociateDhcpOptionsInput{
+					DhcpOptionsId: aws.String("dopt-cluster"),
+					VpcId:         aws.String("vpc-dhcp"),
+				})).
+					Return(&ec2.AssociateDhcpOptionsOutput{}, nil)
+			},
+		},
+		{
+			name: "matching option set already associated with the vpc",
+			spec: spec,
+			expect: func(m *mock_ec2iface.MockEC2APIMockRecorder) {
+				m.DescribeDhcpOptions(gomock.AssignableToTypeOf(&ec2.DescribeDhcpOptionsInput{})).
+					Return(&ec2.DescribeDhcpOptionsOutput{
+						DhcpOptions: []*ec2.DhcpOptions{
+							{
+								DhcpOptionsId: aws.String("dopt-cluster"),
+								DhcpConfigurations: []*ec2.DhcpConfiguration{
+									{Key: aws.String("domain-name"), Values: []*ec2.AttributeValue{{Value: aws.String("corp.example.com")}}},
+									{Key: aws.String("domain-name-servers"), Values: []*ec2.AttributeValue{{Value: aws.String("10.0.0.2")}, {Value: aws.String("10.0.0.3")}}},
+								},
+							},
+						},
+					}, nil)
+				m.CreateTags(gomock.AssignableToTypeOf(&ec2.CreateTagsInput{})).
+					Return(&ec2.CreateTagsOutput{}, nil)
+				m.DescribeVpcs(gomock.AssignableToTypeOf(&ec2.DescribeVpcsInput{})).
+					Return(&ec2.DescribeVpcsOutput{
+						Vpcs: []*ec2.Vpc{{VpcId: aws.String("vpc-dhcp"), DhcpOptionsId: aws.String("dopt-cluster")}},
+					}, nil)
+			},
+		},
+		{
+			name: "changed options replace the option set",
+			spec: spec,
+			expect: func(m *mock_ec2iface.MockEC2APIMockRecorder) {
+				m.DescribeDhcpOptions(gomock.AssignableToTypeOf(&ec2.DescribeDhcpOptionsInput{})).
+					Return(&ec2.DescribeDhcpOptionsOutput{
+						DhcpOptions: []*ec2.DhcpOptions{
+							{
+								DhcpOptionsId: aws.String("dopt-old"),
+								DhcpConfigurations: []*ec2.DhcpConfiguration{
+									{Key: aws.String("domain-name"), Values: []*ec2.AttributeValue{{Value: aws.String("corp.example.com")}}},
+									{Key: aws.String("domain-name-servers"), Values: []*ec2.AttributeValue{{Value: aws.String("10.0.0.3")}, {Value: aws.String("10.0.0.2")}}},
+								},
+							},
+						},
+					}, nil)
+				m.CreateDhcpOptions(gomock.AssignableToTypeOf(&ec2.CreateDhcpOptionsInput{})).
+					Return(&ec2.CreateDhcpOptionsOutput{DhcpOptions: &ec2.DhcpOptions{DhcpOptionsId: aws.String("dopt-cluster")}}, nil)
+				m.CreateTags(gomock.AssignableToTypeOf(&ec2.CreateTagsInput{})).
+					Return(&ec2.CreateTagsOutput{}, nil)
+				m.DescribeVpcs(gomock.AssignableToTypeOf(&ec2.DescribeVpcsInput{})).
+					Return(&ec2.DescribeVpcsOutput{
+						Vpcs: []*ec2.Vpc{{VpcId: aws.String("vpc-dhcp"), DhcpOptionsId: aws.String("dopt-old")}},
+					}, nil)
+				m.AssociateDhcpOptions(gomock.Eq(&ec2.AssociateDhcpOptionsInput{
+					DhcpOptionsId: aws.String("dopt-cluster"),
+					VpcId:         aws.String("vpc-dhcp"),
+				})).
+					Return(&ec2.AssociateDhcpOptionsOutput{}, nil)
+				m.DeleteDhcpOptions(gomock.Eq(&ec2.DeleteDhcpOptionsInput{
+					DhcpOptionsId: aws.String("dopt-old"),
+				})).
+					Return(&ec2.DeleteDhcpOptionsOutput{}, nil)
+			},
+		},
+		{
+			name: "option set is deleted when no longer specified",
+			expect: func(m *mock_ec2iface.MockEC2APIMockRecorder) {
+				m.DescribeDhcpOptions(gomock.AssignableToTypeOf(&ec2.DescribeDhcpOptionsInput{})).
+					Return(&ec2.DescribeDhcpOptionsOutput{
+						DhcpOptions: []*ec2.DhcpOptions{{DhcpOptionsId: aws.String("dopt-cluster")}},
+					}, nil)
+				m.DescribeVpcs(gomock.AssignableToTypeOf(&ec2.DescribeVpcsInput{})).
+					Return(&ec2.DescribeVpcsOutput{
+						Vpcs: []*ec2.Vpc{{VpcId: aws.String("vpc-dhcp"), DhcpOptionsId: aws.String("dopt-cluster")}},
+					}, nil)
+				m.AssociateDhcpOptions(gomock.Eq(&ec2.AssociateDhcpOptionsInput{
+					DhcpOptionsId: aws.String("default"),
+					VpcId:         aws.String("vpc-dhcp"),
+				})).
+					Return(&ec2.AssociateDhcpOptionsOutput{}, nil)
+				m.DeleteDhcpOptions(gomock.Eq(&ec2.DeleteDhcpOptionsInput{
+					DhcpOptionsId: aws.String("dopt-cluster"),
+				})).
+					Return(&ec2.DeleteDhcpOptionsOutput{}, nil)
+			},
+		},
+		{
+			name:      "unmanaged vpc is left alone",
+			spec:      spec,
+			unmanaged: true,
+			expect:    func(m *mock_ec2iface.MockEC2APIMockRecorder) {},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ec2Mock := mock_ec2iface.NewMockEC2API(mockCtrl)
+
+			vpc := infrav1.VPCSpec{
+				ID: "vpc-dhcp",
+				Tags: infrav1.Tags{
+					infrav1.ClusterTagKey("test-cluster"): "owned",
+				},
+			}
+			if tc.unmanaged {
+				vpc.Tags = nil
+			}
+
+			scope, err := scope.NewClusterScope(scope.ClusterScopeParams{
+				Cluster: &clusterv1.Cluster{
+					ObjectMeta: metav1.ObjectMeta{Name: "test-cluster"},
+				},
+				AWSClients: scope.AWSClients{
+					EC2: ec2Mock,
+				},
+				AWSCluster: &infrav1.AWSCluster{
+					Spec: infrav1.AWSClusterSpec{
+						NetworkSpec: infrav1.NetworkSpec{
+							VPC:         vpc,
+							DHCPOptions: tc.spec,
+						},
+					},
+				},
+			})
+			if err != nil {
+				t.Fatalf("Failed to create test context: %v", err)
+			}
+
+			tc.expect(ec2Mock.EXPECT())
+
+			s := NewService(scope)
+			if err := s.reconcileDHCPOptions(); err != nil {
+				t.Fatalf("got an unexpected error: %v", err)
+			}
+		})
+	}
+}
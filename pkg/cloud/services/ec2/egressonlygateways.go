@@ -0,0 +1,129 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ec2
+
+import (
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/pkg/errors"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/awserrors"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/record"
+)
+
+// reconcileEgressOnlyInternetGateways makes sure a dual-stack VPC has an
+// egress-only internet gateway, private subnets use it as their IPv6 default
+// route since IPv6 addresses are never translated by NAT gateways. IPv4-only
+// clusters and clusters without private subnets don't need one.
+func (s *Service) reconcileEgressOnlyInternetGateways() error {
+	if s.scope.VPC().IsUnmanaged(s.scope.Name()) {
+		s.scope.V(4).Info("Skipping egress-only internet gateways reconcile in unmanaged mode")
+		return nil
+	}
+
+	if !s.scope.VPC().IsDualStack() || len(s.scope.Subnets().FilterPrivate()) == 0 {
+		return nil
+	}
+
+	s.scope.V(2).Info("Reconciling egress-only internet gateways")
+
+	gateway, err := s.describeVpcEgressOnlyInternetGateway()
+	if awserrors.IsNotFound(err) {
+		gateway, err = s.createEgressOnlyInternetGateway()
+		if err != nil {
+			return err
+		}
+	} else if err != nil {
+		return err
+	}
+
+	s.scope.VPC().EgressOnlyInternetGatewayID = gateway.EgressOnlyInternetGatewayId
+	return nil
+}
+
+func (s *Service) deleteEgressOnlyInternetGateways() error {
+	if s.scope.VPC().IsUnmanaged(s.scope.Name()) {
+		s.scope.V(4).Info("Skipping egress-only internet gateway deletion in unmanaged mode")
+		return nil
+	}
+
+	// IPv4-only clusters never had an egress-only internet gateway, and may
+	// run with a controllers policy predating IPv6 support, without
+	// ec2:DescribeEgressOnlyInternetGateways.
+	if s.scope.VPC().ID == "" || (!s.scope.VPC().EnableIPv6 && s.scope.VPC().EgressOnlyInternetGatewayID == nil) {
+		return nil
+	}
+
+	gateway, err := s.describeVpcEgressOnlyInternetGateway()
+	if awserrors.IsNotFound(err) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	if _, err := s.scope.EC2.DeleteEgressOnlyInternetGateway(&ec2.DeleteEgressOnlyInternetGatewayInput{
+		EgressOnlyInternetGatewayId: gateway.EgressOnlyInternetGatewayId,
+	}); err != nil {
+		record.Warnf(s.scope.AWSCluster, "FailedDeleteEgressOnlyInternetGateway", "Failed to delete Egress-Only Internet Gateway %q attached to VPC %q: %v", *gateway.EgressOnlyInternetGatewayId, s.scope.VPC().ID, err)
+		return errors.Wrapf(err, "failed to delete egress-only internet gateway %q", *gateway.EgressOnlyInternetGatewayId)
+	}
+
+	record.Eventf(s.scope.AWSCluster, "SuccessfulDeleteEgressOnlyInternetGateway", "Deleted Egress-Only Internet Gateway %q attached to VPC %q", *gateway.EgressOnlyInternetGatewayId, s.scope.VPC().ID)
+	s.scope.Info("Deleted egress-only internet gateway in VPC", "egress-only-internet-gateway-id", *gateway.EgressOnlyInternetGatewayId, "vpc-id", s.scope.VPC().ID)
+	s.scope.VPC().EgressOnlyInternetGatewayID = nil
+	return nil
+}
+
+func (s *Service) createEgressOnlyInternetGateway() (*ec2.EgressOnlyInternetGateway, error) {
+	out, err := s.scope.EC2.CreateEgressOnlyInternetGateway(&ec2.CreateEgressOnlyInternetGatewayInput{
+		VpcId: aws.String(s.scope.VPC().ID),
+	})
+	if err != nil {
+		record.Warnf(s.scope.AWSCluster, "FailedCreateEgressOnlyInternetGateway", "Failed to create new managed Egress-Only Internet Gateway: %v", err)
+		return nil, errors.Wrapf(err, "failed to create egress-only internet gateway in vpc %q", s.scope.VPC().ID)
+	}
+
+	record.Eventf(s.scope.AWSCluster, "SuccessfulCreateEgressOnlyInternetGateway", "Created new managed Egress-Only Internet Gateway %q", *out.EgressOnlyInternetGateway.EgressOnlyInternetGatewayId)
+	s.scope.Info("Created egress-only internet gateway for VPC", "egress-only-internet-gateway-id", *out.EgressOnlyInternetGateway.EgressOnlyInternetGatewayId, "vpc-id", s.scope.VPC().ID)
+	return out.EgressOnlyInternetGateway, nil
+}
+
+// describeVpcEgressOnlyInternetGateway returns the egress-only internet gateway
+// attached to the VPC. They can't be tagged or filtered, so the attachments of
+// every gateway in the region are checked.
+func (s *Service) describeVpcEgressOnlyInternetGateway() (*ec2.EgressOnlyInternetGateway, error) {
+	var gateway *ec2.EgressOnlyInternetGateway
+	if err := s.scope.EC2.DescribeEgressOnlyInternetGatewaysPages(&ec2.DescribeEgressOnlyInternetGatewaysInput{},
+		func(page *ec2.DescribeEgressOnlyInternetGatewaysOutput, lastPage bool) bool {
+			for _, eigw := range page.EgressOnlyInternetGateways {
+				for _, attachment := range eigw.Attachments {
+					if aws.StringValue(attachment.VpcId) == s.scope.VPC().ID {
+						gateway = eigw
+						return false
+					}
+				}
+			}
+			return true
+		}); err != nil {
+		return nil, errors.Wrapf(err, "failed to describe egress-only internet gateways in vpc %q", s.scope.VPC().ID)
+	}
+
+	if gateway == nil {
+		return nil, awserrors.NewNotFound(errors.Errorf("no egress-only internet gateway found in vpc %q", s.scope.VPC().ID))
+	}
+
+	return gateway, nil
+}
@@ -0,0 +1,130 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ec2
+
+import (
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/golang/mock/gomock"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	infrav1 "sigs.k8s.io/cluster-api-provider-aws/api/v1alpha3"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/scope"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/services/ec2/mock_ec2iface"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1alpha3"
+)
+
+func TestReconcileEgressOnlyInternetGateways(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	vpc := infrav1.VPCSpec{
+		ID:            "vpc-eigw",
+		IPv6CidrBlock: "2600:1f18:abc:de00::/56",
+		Tags: infrav1.Tags{
+			infrav1.ClusterTagKey("test-cluster"): "owned",
+		},
+	}
+	private := infrav1.Subnets{{ID: "subnet-private", IsPublic: false}}
+
+	describe := func(gateways ...*ec2.EgressOnlyInternetGateway) func(*ec2.DescribeEgressOnlyInternetGatewaysInput, func(*ec2.DescribeEgressOnlyInternetGatewaysOutput, bool) bool) error {
+		return func(_ *ec2.DescribeEgressOnlyInternetGatewaysInput, fn func(*ec2.DescribeEgressOnlyInternetGatewaysOutput, bool) bool) error {
+			fn(&ec2.DescribeEgressOnlyInternetGatewaysOutput{EgressOnlyInternetGateways: gateways}, true)
+			return nil
+		}
+	}
+
+	testCases := []struct {
+		name   string
+		input  infrav1.NetworkSpec
+		expect func(m *mock_ec2iface.MockEC2APIMockRecorder)
+		want   *string
+	}{
+		{
+			name:   "ipv4 only cluster",
+			input:  infrav1.NetworkSpec{VPC: infrav1.VPCSpec{ID: "vpc-eigw", Tags: vpc.Tags}, Subnets: private},
+			expect: func(m *mock_ec2iface.MockEC2APIMockRecorder) {},
+		},
+		{
+			name:   "dual-stack cluster without private subnets",
+			input:  infrav1.NetworkSpec{VPC: vpc, Subnets: infrav1.Subnets{{ID: "subnet-public", IsPublic: true}}},
+			expect: func(m *mock_ec2iface.MockEC2APIMockRecorder) {},
+		},
+		{
+			name:  "has eigw",
+			input: infrav1.NetworkSpec{VPC: vpc, Subnets: private},
+			expect: func(m *mock_ec2iface.MockEC2APIMockRecorder) {
+				m.DescribeEgressOnlyInternetGatewaysPages(gomock.Any(), gomock.Any()).
+					DoAndReturn(describe(&ec2.EgressOnlyInternetGateway{
+						EgressOnlyInternetGatewayId: aws.String("eigw-0"),
+						Attachments:                 []*ec2.InternetGatewayAttachment{{VpcId: aws.String("vpc-eigw")}},
+					}))
+			},
+			want: aws.String("eigw-0"),
+		},
+		{
+			name:  "no eigw attached, creates one",
+			input: infrav1.NetworkSpec{VPC: vpc, Subnets: private},
+			expect: func(m *mock_ec2iface.MockEC2APIMockRecorder) {
+				m.DescribeEgressOnlyInternetGatewaysPages(gomock.Any(), gomock.Any()).
+					DoAndReturn(describe(&ec2.EgressOnlyInternetGateway{
+						EgressOnlyInternetGatewayId: aws.String("eigw-other"),
+						Attachments:                 []*ec2.InternetGatewayAttachment{{VpcId: aws.String("vpc-other")}},
+					}))
+				m.CreateEgressOnlyInternetGateway(gomock.Eq(&ec2.CreateEgressOnlyInternetGatewayInput{VpcId: aws.String("vpc-eigw")})).
+					Return(&ec2.CreateEgressOnlyInternetGatewayOutput{
+						EgressOnlyInternetGateway: &ec2.EgressOnlyInternetGateway{EgressOnlyInternetGatewayId: aws.String("eigw-1")},
+					}, nil)
+			},
+			want: aws.String("eigw-1"),
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ec2Mock := mock_ec2iface.NewMockEC2API(mockCtrl)
+
+			scope, err := scope.NewClusterScope(scope.ClusterScopeParams{
+				Cluster: &clusterv1.Cluster{
+					ObjectMeta: metav1.ObjectMeta{Name: "test-cluster"},
+				},
+				AWSClients: scope.AWSClients{
+					EC2: ec2Mock,
+				},
+				AWSCluster: &infrav1.AWSCluster{
+					Spec: infrav1.AWSClusterSpec{
+						NetworkSpec: tc.input,
+					},
+				},
+			})
+			if err != nil {
+				t.Fatalf("Failed to create test context: %v", err)
+			}
+
+			tc.expect(ec2Mock.EXPECT())
+
+			s := NewService(scope)
+			if err := s.reconcileEgressOnlyInternetGateways(); err != nil {
+				t.Fatalf("got an unexpected error: %v", err)
+			}
+			if got := scope.VPC().EgressOnlyInternetGatewayID; aws.StringValue(got) != aws.StringValue(tc.want) {
+				t.Fatalf("expected egress-only internet gateway %v, got %v", aws.StringValue(tc.want), aws.StringValue(got))
+			}
+		})
+	}
+}
@@ -24,9 +24,12 @@ import (
 	"github.com/pkg/errors"
 	infrav1 "sigs.k8s.io/cluster-api-provider-aws/api/v1alpha3"
 	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/awserrors"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/converters"
 	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/filter"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/scope"
 	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/services/wait"
 	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/tags"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/record"
 )
 
 func (s *Service) getOrAllocateAddress(role string) (string, error) {
@@ -46,34 +49,81 @@ func (s *Service) getOrAllocateAddress(role string) (string, error) {
 }
 
 func (s *Service) allocateAddress(role string) (string, error) {
-	out, err := s.scope.EC2.AllocateAddress(&ec2.AllocateAddressInput{
-		Domain: aws.String("vpc"),
-	})
+	out, err := s.scope.EC2.AllocateAddress(s.getAllocateAddressInput())
 
 	if err != nil {
 		return "", errors.Wrap(err, "failed to create Elastic IP address")
 	}
 
+	if err := s.tagAddress(s.getEIPTagParams(*out.AllocationId, role)); err != nil {
+		return "", err
+	}
+
+	return aws.StringValue(out.AllocationId), nil
+}
+
+// getAllocateAddressInput requests an Elastic IP from the public IPv4 pool of
+// the cluster when it has one, or from Amazon's pool.
+func (s *Service) getAllocateAddressInput() *ec2.AllocateAddressInput {
+	input := &ec2.AllocateAddressInput{
+		Domain: aws.String("vpc"),
+	}
+	if pool := s.scope.AWSCluster.Spec.NetworkSpec.PublicIPv4Pool; pool != "" {
+		input.PublicIpv4Pool = aws.String(pool)
+	}
+	return input
+}
+
+// tagAddress tags a newly allocated Elastic IP, waiting for it to be visible.
+func (s *Service) tagAddress(params infrav1.BuildParams) error {
 	if err := wait.WaitForWithRetryable(wait.NewBackoff(), func() (bool, error) {
 		if err := tags.Apply(&tags.ApplyParams{
-			EC2Client: s.scope.EC2,
-			BuildParams: infrav1.BuildParams{
-				ClusterName: s.scope.Name(),
-				ResourceID:  *out.AllocationId,
-				Lifecycle:   infrav1.ResourceLifecycleOwned,
-				Name:        aws.String(fmt.Sprintf("%s-eip-%s", s.scope.Name(), role)),
-				Role:        aws.String(role),
-				Additional:  s.scope.AdditionalTags(),
-			},
+			EC2Client:   s.scope.EC2,
+			BuildParams: params,
 		}); err != nil {
 			return false, err
 		}
 		return true, nil
 	}, awserrors.EIPNotFound); err != nil {
-		return "", errors.Wrapf(err, "failed to tag elastic IP %q", aws.StringValue(out.AllocationId))
+		return errors.Wrapf(err, "failed to tag elastic IP %q", params.ResourceID)
 	}
+	return nil
+}
 
-	return aws.StringValue(out.AllocationId), nil
+// ensureAddressesTags makes sure the tags of the cluster's Elastic IPs for the
+// given role are up to date.
+func (s *Service) ensureAddressesTags(role string) error {
+	out, err := s.describeAddresses(role)
+	if err != nil {
+		return errors.Wrap(err, "failed to query addresses")
+	}
+
+	for _, address := range out.Addresses {
+		id := aws.StringValue(address.AllocationId)
+		if err := tags.Ensure(converters.TagsToMap(address.Tags), &tags.ApplyParams{
+			EC2Client:   s.scope.EC2,
+			BuildParams: s.getEIPTagParams(id, role),
+			Removed:     s.scope.RemovedAdditionalTags(),
+		}); err != nil {
+			record.Warnf(s.scope.AWSCluster, "FailedTagEIP", "Failed to tag managed Elastic IP %q: %v", id, err)
+			return errors.Wrapf(err, "failed to ensure tags on elastic IP %q", id)
+		}
+	}
+
+	return nil
+}
+
+func (s *Service) getEIPTagParams(id string, role string) infrav1.BuildParams {
+	name := fmt.Sprintf("%s-eip-%s", s.scope.Name(), role)
+
+	return infrav1.BuildParams{
+		ClusterName: s.scope.Name(),
+		ResourceID:  id,
+		Lifecycle:   infrav1.ResourceLifecycleOwned,
+		Name:        aws.String(name),
+		Role:        aws.String(role),
+		Additional:  s.scope.AdditionalTags(),
+	}
 }
 
 func (s *Service) describeAddresses(role string) (*ec2.DescribeAddressesOutput, error) {
@@ -146,3 +196,170 @@ func (s *Service) releaseAddresses() error {
 	}
 	return nil
 }
+
+// ReconcileElasticIP associates an Elastic IP with the running instance of the
+// machine. The Elastic IP is taken from the pool of the machine, or allocated
+// for the machine when the pool is empty.
+func (s *Service) ReconcileElasticIP(scope *scope.MachineScope, instance *infrav1.Instance) error {
+	pool := scope.AWSMachine.Spec.ElasticIPPool
+	if pool == nil {
+		return nil
+	}
+
+	// Only known subnets can be checked, the cluster subnets are all
+	// described when reconciling the network.
+	if sn := s.scope.Subnets().FindByID(instance.SubnetID); sn != nil && !sn.IsPublic {
+		err := errors.Errorf("subnet %q has no route to an internet gateway, an Elastic IP would not be reachable", instance.SubnetID)
+		record.Warnf(scope.AWSMachine, "FailedAssociateEIP", "Failed to associate Elastic IP with instance %q: %v", instance.ID, err)
+		return err
+	}
+
+	addresses, err := s.describeMachineAddresses(scope)
+	if err != nil {
+		return err
+	}
+
+	var free *ec2.Address
+	for _, address := range addresses {
+		if aws.StringValue(address.InstanceId) == instance.ID {
+			return nil
+		}
+		if free == nil && address.AssociationId == nil {
+			free = address
+		}
+	}
+
+	var allocationID string
+	switch {
+	case free != nil:
+		allocationID = aws.StringValue(free.AllocationId)
+	case len(pool.AllocationIDs) > 0:
+		err := awserrors.NewFailedDependency(errors.New("all Elastic IPs of the pool are associated with other resources"))
+		record.Warnf(scope.AWSMachine, "FailedAssociateEIP", "Failed to associate Elastic IP with instance %q: %v", instance.ID, err)
+		return err
+	default:
+		allocationID, err = s.allocateMachineAddress(scope)
+		if err != nil {
+			record.Warnf(scope.AWSMachine, "FailedAllocateEIP", "Failed to allocate Elastic IP for instance %q: %v", instance.ID, err)
+			return err
+		}
+		record.Eventf(scope.AWSMachine, "SuccessfulAllocateEIP", "Allocated Elastic IP %q", allocationID)
+	}
+
+	if err := awserrors.RetryOnThrottling(func() error {
+		_, err := s.scope.EC2.AssociateAddress(&ec2.AssociateAddressInput{
+			AllocationId: aws.String(allocationID),
+			InstanceId:   aws.String(instance.ID),
+		})
+		return err
+	}); err != nil {
+		record.Warnf(scope.AWSMachine, "FailedAssociateEIP", "Failed to associate Elastic IP %q with instance %q: %v", allocationID, instance.ID, err)
+		return errors.Wrapf(err, "failed to associate elastic IP %q with instance %q", allocationID, instance.ID)
+	}
+	record.Eventf(scope.AWSMachine, "SuccessfulAssociateEIP", "Associated Elastic IP %q with instance %q", allocationID, instance.ID)
+
+	return nil
+}
+
+// ReleaseElasticIP releases the Elastic IP allocated for the machine once its
+// instance is terminated. Elastic IPs from the pool of the machine are only
+// disassociated by the termination, never released.
+func (s *Service) ReleaseElasticIP(scope *scope.MachineScope) error {
+	pool := scope.AWSMachine.Spec.ElasticIPPool
+	if pool == nil || len(pool.AllocationIDs) > 0 {
+		return nil
+	}
+
+	addresses, err := s.describeMachineAddresses(scope)
+	if err != nil {
+		return err
+	}
+
+	for _, ip := range addresses {
+		err := wait.WaitForWithRetryable(wait.NewBackoff(), func() (bool, error) {
+			if _, err := s.scope.EC2.ReleaseAddress(&ec2.ReleaseAddressInput{AllocationId: ip.AllocationId}); err != nil {
+				return false, err
+			}
+			return true, nil
+		}, awserrors.AuthFailure, awserrors.InUseIPAddress)
+		if err != nil {
+			record.Warnf(scope.AWSMachine, "FailedReleaseEIP", "Failed to release Elastic IP %q: %v", aws.StringValue(ip.AllocationId), err)
+			return errors.Wrapf(err, "failed to release ElasticIP %q", aws.StringValue(ip.AllocationId))
+		}
+
+		record.Eventf(scope.AWSMachine, "SuccessfulReleaseEIP", "Released Elastic IP %q", aws.StringValue(ip.AllocationId))
+		s.scope.Info("released ElasticIP", "eip", aws.StringValue(ip.PublicIp), "allocation-id", aws.StringValue(ip.AllocationId), "machine", scope.Name())
+	}
+
+	return nil
+}
+
+// describeMachineAddresses returns the Elastic IPs of the pool of the machine,
+// in the order of the pool, or the ones allocated for the machine.
+func (s *Service) describeMachineAddresses(scope *scope.MachineScope) ([]*ec2.Address, error) {
+	pool := scope.AWSMachine.Spec.ElasticIPPool
+
+	input := &ec2.DescribeAddressesInput{
+		Filters: []*ec2.Filter{
+			filter.EC2.Cluster(s.scope.Name()),
+			filter.EC2.Name(scope.Name()),
+		},
+	}
+	if len(pool.AllocationIDs) > 0 {
+		input = &ec2.DescribeAddressesInput{
+			AllocationIds: aws.StringSlice(pool.AllocationIDs),
+		}
+	}
+
+	var out *ec2.DescribeAddressesOutput
+	err := awserrors.RetryOnThrottling(func() (err error) {
+		out, err = s.scope.EC2.DescribeAddresses(input)
+		return err
+	})
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to describe elastic IPs of machine %q", scope.Name())
+	}
+
+	if len(pool.AllocationIDs) == 0 {
+		return out.Addresses, nil
+	}
+
+	byID := make(map[string]*ec2.Address, len(out.Addresses))
+	for _, address := range out.Addresses {
+		byID[aws.StringValue(address.AllocationId)] = address
+	}
+	addresses := make([]*ec2.Address, 0, len(out.Addresses))
+	for _, id := range pool.AllocationIDs {
+		if address, ok := byID[id]; ok {
+			addresses = append(addresses, address)
+		}
+	}
+	return addresses, nil
+}
+
+// allocateMachineAddress allocates an Elastic IP owned by the cluster and
+// named after the machine.
+func (s *Service) allocateMachineAddress(scope *scope.MachineScope) (string, error) {
+	var out *ec2.AllocateAddressOutput
+	err := awserrors.RetryOnThrottling(func() (err error) {
+		out, err = s.scope.EC2.AllocateAddress(s.getAllocateAddressInput())
+		return err
+	})
+	if err != nil {
+		return "", errors.Wrap(err, "failed to create Elastic IP address")
+	}
+
+	id := aws.StringValue(out.AllocationId)
+	if err := s.tagAddress(infrav1.BuildParams{
+		ClusterName: s.scope.Name(),
+		ResourceID:  id,
+		Lifecycle:   infrav1.ResourceLifecycleOwned,
+		Name:        aws.String(scope.Name()),
+		Role:        aws.String(scope.Role()),
+		Additional:  scope.AdditionalTags(),
+	}); err != nil {
+		return "", err
+	}
+
+	return id, nil
+}
@@ -0,0 +1,256 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ec2
+
+import (
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/golang/mock/gomock"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	infrav1 "sigs.k8s.io/cluster-api-provider-aws/api/v1alpha3"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/scope"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/services/ec2/mock_ec2iface"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1alpha3"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestReconcileElasticIP(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	ownedInput := &ec2.DescribeAddressesInput{
+		Filters: []*ec2.Filter{
+			{Name: aws.String("tag-key"), Values: aws.StringSlice([]string{"sigs.k8s.io/cluster-api-provider-aws/cluster/test-cluster"})},
+			{Name: aws.String("tag:Name"), Values: aws.StringSlice([]string{"edge-0"})},
+		},
+	}
+	poolInput := &ec2.DescribeAddressesInput{AllocationIds: aws.StringSlice([]string{"eipalloc-1", "eipalloc-2"})}
+
+	testCases := []struct {
+		name           string
+		pool           *infrav1.ElasticIPPool
+		subnet         *infrav1.SubnetSpec
+		publicIPv4Pool string
+		expect         func(m *mock_ec2iface.MockEC2APIMockRecorder)
+		wantErr        bool
+	}{
+		{
+			name: "first free elastic ip of the pool is associated",
+			pool: &infrav1.ElasticIPPool{AllocationIDs: []string{"eipalloc-1", "eipalloc-2"}},
+			expect: func(m *mock_ec2iface.MockEC2APIMockRecorder) {
+				m.DescribeAddresses(gomock.Eq(poolInput)).
+					Return(&ec2.DescribeAddressesOutput{
+						Addresses: []*ec2.Address{
+							{AllocationId: aws.String("eipalloc-2")},
+							{AllocationId: aws.String("eipalloc-1")},
+						},
+					}, nil)
+				m.AssociateAddress(gomock.Eq(&ec2.AssociateAddressInput{
+					AllocationId: aws.String("eipalloc-1"),
+					InstanceId:   aws.String("i-edge"),
+				})).
+					Return(&ec2.AssociateAddressOutput{}, nil)
+			},
+		},
+		{
+			name: "elastic ip of the pool is already associated with the instance",
+			pool: &infrav1.ElasticIPPool{AllocationIDs: []string{"eipalloc-1", "eipalloc-2"}},
+			expect: func(m *mock_ec2iface.MockEC2APIMockRecorder) {
+				m.DescribeAddresses(gomock.Eq(poolInput)).
+					Return(&ec2.DescribeAddressesOutput{
+						Addresses: []*ec2.Address{
+							{AllocationId: aws.String("eipalloc-1"), AssociationId: aws.String("eipassoc-1"), InstanceId: aws.String("i-other")},
+							{AllocationId: aws.String("eipalloc-2"), AssociationId: aws.String("eipassoc-2"), InstanceId: aws.String("i-edge")},
+						},
+					}, nil)
+			},
+		},
+		{
+			name: "all elastic ips of the pool are associated with other resources",
+			pool: &infrav1.ElasticIPPool{AllocationIDs: []string{"eipalloc-1", "eipalloc-2"}},
+			expect: func(m *mock_ec2iface.MockEC2APIMockRecorder) {
+				m.DescribeAddresses(gomock.Eq(poolInput)).
+					Return(&ec2.DescribeAddressesOutput{
+						Addresses: []*ec2.Address{
+							{AllocationId: aws.String("eipalloc-1"), AssociationId: aws.String("eipassoc-1"), InstanceId: aws.String("i-other")},
+							{AllocationId: aws.String("eipalloc-2"), AssociationId: aws.String("eipassoc-2"), NetworkInterfaceId: aws.String("eni-nat")},
+						},
+					}, nil)
+			},
+			wantErr: true,
+		},
+		{
+			name: "elastic ip is allocated for the machine",
+			pool: &infrav1.ElasticIPPool{},
+			expect: func(m *mock_ec2iface.MockEC2APIMockRecorder) {
+				m.DescribeAddresses(gomock.Eq(ownedInput)).
+					Return(&ec2.DescribeAddressesOutput{}, nil)
+				m.AllocateAddress(gomock.Eq(&ec2.AllocateAddressInput{Domain: aws.String("vpc")})).
+					Return(&ec2.AllocateAddressOutput{AllocationId: aws.String("eipalloc-edge")}, nil)
+				m.CreateTags(gomock.AssignableToTypeOf(&ec2.CreateTagsInput{})).
+					Return(&ec2.CreateTagsOutput{}, nil)
+				m.AssociateAddress(gomock.Eq(&ec2.AssociateAddressInput{
+					AllocationId: aws.String("eipalloc-edge"),
+					InstanceId:   aws.String("i-edge"),
+				})).
+					Return(&ec2.AssociateAddressOutput{}, nil)
+			},
+		},
+		{
+			name:           "elastic ip is allocated from the public ipv4 pool of the cluster",
+			pool:           &infrav1.ElasticIPPool{},
+			publicIPv4Pool: "ipv4pool-ec2-0123456789abcdef0",
+			expect: func(m *mock_ec2iface.MockEC2APIMockRecorder) {
+				m.DescribeAddresses(gomock.Eq(ownedInput)).
+					Return(&ec2.DescribeAddressesOutput{}, nil)
+				m.AllocateAddress(gomock.Eq(&ec2.AllocateAddressInput{
+					Domain:         aws.String("vpc"),
+					PublicIpv4Pool: aws.String("ipv4pool-ec2-0123456789abcdef0"),
+				})).
+					Return(&ec2.AllocateAddressOutput{AllocationId: aws.String("eipalloc-edge")}, nil)
+				m.CreateTags(gomock.AssignableToTypeOf(&ec2.CreateTagsInput{})).
+					Return(&ec2.CreateTagsOutput{}, nil)
+				m.AssociateAddress(gomock.AssignableToTypeOf(&ec2.AssociateAddressInput{})).
+					Return(&ec2.AssociateAddressOutput{}, nil)
+			},
+		},
+		{
+			name:    "instance in a private subnet",
+			pool:    &infrav1.ElasticIPPool{},
+			subnet:  &infrav1.SubnetSpec{ID: "subnet-edge", IsPublic: false},
+			expect:  func(m *mock_ec2iface.MockEC2APIMockRecorder) {},
+			wantErr: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ec2Mock := mock_ec2iface.NewMockEC2API(mockCtrl)
+
+			awsCluster := &infrav1.AWSCluster{}
+			awsCluster.Spec.NetworkSpec.PublicIPv4Pool = tc.publicIPv4Pool
+			if tc.subnet != nil {
+				awsCluster.Spec.NetworkSpec.Subnets = infrav1.Subnets{tc.subnet}
+			}
+			cluster := &clusterv1.Cluster{
+				ObjectMeta: metav1.ObjectMeta{Name: "test-cluster"},
+			}
+			awsMachine := &infrav1.AWSMachine{
+				ObjectMeta: metav1.ObjectMeta{Name: "edge-0"},
+				Spec:       infrav1.AWSMachineSpec{ElasticIPPool: tc.pool},
+			}
+
+			clusterScope, err := scope.NewClusterScope(scope.ClusterScopeParams{
+				Cluster:    cluster,
+				AWSClients: scope.AWSClients{EC2: ec2Mock},
+				AWSCluster: awsCluster,
+			})
+			if err != nil {
+				t.Fatalf("Failed to create test context: %v", err)
+			}
+			machineScope, err := scope.NewMachineScope(scope.MachineScopeParams{
+				Client:     fake.NewFakeClient(),
+				Cluster:    cluster,
+				Machine:    &clusterv1.Machine{},
+				AWSCluster: awsCluster,
+				AWSMachine: awsMachine,
+			})
+			if err != nil {
+				t.Fatalf("Failed to create test context: %v", err)
+			}
+
+			tc.expect(ec2Mock.EXPECT())
+
+			s := NewService(clusterScope)
+			err = s.ReconcileElasticIP(machineScope, &infrav1.Instance{ID: "i-edge", SubnetID: "subnet-edge"})
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("ReconcileElasticIP() error = %v, wantErr %v", err, tc.wantErr)
+			}
+		})
+	}
+}
+
+func TestReleaseElasticIP(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	testCases := []struct {
+		name   string
+		pool   *infrav1.ElasticIPPool
+		expect func(m *mock_ec2iface.MockEC2APIMockRecorder)
+	}{
+		{
+			name: "elastic ip allocated for the machine is released",
+			pool: &infrav1.ElasticIPPool{},
+			expect: func(m *mock_ec2iface.MockEC2APIMockRecorder) {
+				m.DescribeAddresses(gomock.AssignableToTypeOf(&ec2.DescribeAddressesInput{})).
+					Return(&ec2.DescribeAddressesOutput{
+						Addresses: []*ec2.Address{
+							{AllocationId: aws.String("eipalloc-edge"), PublicIp: aws.String("203.0.113.10")},
+						},
+					}, nil)
+				m.ReleaseAddress(gomock.Eq(&ec2.ReleaseAddressInput{AllocationId: aws.String("eipalloc-edge")})).
+					Return(&ec2.ReleaseAddressOutput{}, nil)
+			},
+		},
+		{
+			name:   "elastic ips of the pool are not released",
+			pool:   &infrav1.ElasticIPPool{AllocationIDs: []string{"eipalloc-1"}},
+			expect: func(m *mock_ec2iface.MockEC2APIMockRecorder) {},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ec2Mock := mock_ec2iface.NewMockEC2API(mockCtrl)
+
+			cluster := &clusterv1.Cluster{
+				ObjectMeta: metav1.ObjectMeta{Name: "test-cluster"},
+			}
+			clusterScope, err := scope.NewClusterScope(scope.ClusterScopeParams{
+				Cluster:    cluster,
+				AWSClients: scope.AWSClients{EC2: ec2Mock},
+				AWSCluster: &infrav1.AWSCluster{},
+			})
+			if err != nil {
+				t.Fatalf("Failed to create test context: %v", err)
+			}
+			machineScope, err := scope.NewMachineScope(scope.MachineScopeParams{
+				Client:     fake.NewFakeClient(),
+				Cluster:    cluster,
+				Machine:    &clusterv1.Machine{},
+				AWSCluster: &infrav1.AWSCluster{},
+				AWSMachine: &infrav1.AWSMachine{
+					ObjectMeta: metav1.ObjectMeta{Name: "edge-0"},
+					Spec:       infrav1.AWSMachineSpec{ElasticIPPool: tc.pool},
+				},
+			})
+			if err != nil {
+				t.Fatalf("Failed to create test context: %v", err)
+			}
+
+			tc.expect(ec2Mock.EXPECT())
+
+			s := NewService(clusterScope)
+			if err := s.ReleaseElasticIP(machineScope); err != nil {
+				t.Fatalf("ReleaseElasticIP() error = %v", err)
+			}
+		})
+	}
+}
@@ -62,6 +62,7 @@ func (s *Service) reconcileInternetGateways() error {
 		if err := tags.Ensure(converters.TagsToMap(gateway.Tags), &tags.ApplyParams{
 			EC2Client:   s.scope.EC2,
 			BuildParams: s.getGatewayTagParams(*gateway.InternetGatewayId),
+			Removed:     s.scope.RemovedAdditionalTags(),
 		}); err != nil {
 			return false, err
 		}
@@ -0,0 +1,63 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ec2
+
+import (
+	"fmt"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/iam"
+	"github.com/pkg/errors"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/awserrors"
+)
+
+// InstanceProfileNotFoundError is returned when the IAM instance profile of a
+// machine does not exist, or has no role to pass to the instance.
+type InstanceProfileNotFoundError struct {
+	Name   string
+	Reason string
+}
+
+// Error implements the error interface.
+func (e *InstanceProfileNotFoundError) Error() string {
+	return fmt.Sprintf("IAM instance profile %q %s", e.Name, e.Reason)
+}
+
+// validateIAMInstanceProfile checks that the IAM instance profile exists and
+// has a role. The profile is never created or modified, it may be managed
+// outside of the bootstrap stack.
+func (s *Service) validateIAMInstanceProfile(name string) error {
+	var out *iam.GetInstanceProfileOutput
+	err := awserrors.RetryOnThrottling(func() (err error) {
+		out, err = s.scope.IAM.GetInstanceProfile(&iam.GetInstanceProfileInput{
+			InstanceProfileName: aws.String(name),
+		})
+		return err
+	})
+	if code, _ := awserrors.Code(errors.Cause(err)); code == iam.ErrCodeNoSuchEntityException {
+		return &InstanceProfileNotFoundError{Name: name, Reason: "not found"}
+	} else if err != nil {
+		return errors.Wrapf(err, "failed to get IAM instance profile %q", name)
+	}
+
+	if out.InstanceProfile == nil || len(out.InstanceProfile.Roles) == 0 {
+		return &InstanceProfileNotFoundError{Name: name, Reason: "has no role"}
+	}
+
+	s.scope.V(2).Info("Found IAM instance profile", "instance-profile", name, "role", aws.StringValue(out.InstanceProfile.Roles[0].RoleName))
+	return nil
+}
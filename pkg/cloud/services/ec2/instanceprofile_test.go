@@ -0,0 +1,101 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ec2
+
+import (
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/iam"
+	"github.com/aws/aws-sdk-go/service/iam/iamiface"
+	infrav1 "sigs.k8s.io/cluster-api-provider-aws/api/v1alpha3"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/scope"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1alpha3"
+)
+
+// fakeIAM returns a canned response to GetInstanceProfile.
+type fakeIAM struct {
+	iamiface.IAMAPI
+	profile *iam.InstanceProfile
+	err     error
+}
+
+func (f *fakeIAM) GetInstanceProfile(input *iam.GetInstanceProfileInput) (*iam.GetInstanceProfileOutput, error) {
+	if f.err != nil {
+		return nil, f.err
+	}
+	return &iam.GetInstanceProfileOutput{InstanceProfile: f.profile}, nil
+}
+
+func TestValidateIAMInstanceProfile(t *testing.T) {
+	testCases := []struct {
+		name         string
+		iam          *fakeIAM
+		wantErr      bool
+		wantNotFound bool
+	}{
+		{
+			name: "existing profile with a role",
+			iam: &fakeIAM{profile: &iam.InstanceProfile{
+				InstanceProfileName: aws.String("security-team-nodes"),
+				Roles:               []*iam.Role{{RoleName: aws.String("security-team-nodes")}},
+			}},
+		},
+		{
+			name:         "profile not found",
+			iam:          &fakeIAM{err: awserr.New(iam.ErrCodeNoSuchEntityException, "not found", nil)},
+			wantErr:      true,
+			wantNotFound: true,
+		},
+		{
+			name:         "profile without a role",
+			iam:          &fakeIAM{profile: &iam.InstanceProfile{InstanceProfileName: aws.String("security-team-nodes")}},
+			wantErr:      true,
+			wantNotFound: true,
+		},
+		{
+			name:    "access denied",
+			iam:     &fakeIAM{err: awserr.New("AccessDenied", "not authorized", nil)},
+			wantErr: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			scope, err := scope.NewClusterScope(scope.ClusterScopeParams{
+				Cluster:    &clusterv1.Cluster{},
+				AWSCluster: &infrav1.AWSCluster{},
+				AWSClients: scope.AWSClients{
+					IAM: tc.iam,
+				},
+			})
+			if err != nil {
+				t.Fatalf("did not expect err: %v", err)
+			}
+
+			s := NewService(scope)
+			err = s.validateIAMInstanceProfile("security-team-nodes")
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("validateIAMInstanceProfile() error = %v, wantErr %v", err, tc.wantErr)
+			}
+			if _, ok := err.(*InstanceProfileNotFoundError); ok != tc.wantNotFound {
+				t.Fatalf("expected an InstanceProfileNotFoundError %t, got %v", tc.wantNotFound, err)
+			}
+		})
+	}
+}
@@ -21,25 +21,32 @@ import (
 	"compress/gzip"
 	"context"
 	"encoding/base64"
+	"fmt"
 	"strings"
-	"time"
 
 	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/arn"
 	"github.com/aws/aws-sdk-go/aws/request"
 	"github.com/aws/aws-sdk-go/service/ec2"
 	"github.com/go-logr/logr"
 	"github.com/pkg/errors"
 	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
 	"k8s.io/utils/pointer"
 	infrav1 "sigs.k8s.io/cluster-api-provider-aws/api/v1alpha3"
 	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/awserrors"
 	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/converters"
 	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/filter"
 	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/scope"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/services/userdata"
 	"sigs.k8s.io/cluster-api-provider-aws/pkg/record"
 	"sigs.k8s.io/cluster-api/util"
 )
 
+// maxPlacementGroupPartitions is the number of partitions a partition
+// placement group can have at most in an availability zone.
+const maxPlacementGroupPartitions = 7
+
 // GetRunningInstanceByTags returns the existing instance or nothing if it doesn't exist.
 func (s *Service) GetRunningInstanceByTags(scope *scope.MachineScope) (*infrav1.Instance, error) {
 	s.scope.V(2).Info("Looking for existing machine instance by tags")
@@ -53,7 +60,11 @@ func (s *Service) GetRunningInstanceByTags(scope *scope.MachineScope) (*infrav1.
 		},
 	}
 
-	out, err := s.scope.EC2.DescribeInstances(input)
+	var out *ec2.DescribeInstancesOutput
+	err := awserrors.RetryOnThrottling(func() (err error) {
+		out, err = s.scope.EC2.DescribeInstances(input)
+		return err
+	})
 	switch {
 	case awserrors.IsNotFound(err):
 		return nil, nil
@@ -86,7 +97,11 @@ func (s *Service) InstanceIfExists(id *string) (*infrav1.Instance, error) {
 		InstanceIds: []*string{id},
 	}
 
-	out, err := s.scope.EC2.DescribeInstances(input)
+	var out *ec2.DescribeInstancesOutput
+	err := awserrors.RetryOnThrottling(func() (err error) {
+		out, err = s.scope.EC2.DescribeInstances(input)
+		return err
+	})
 	switch {
 	case awserrors.IsNotFound(err):
 		return nil, nil
@@ -106,29 +121,55 @@ func (s *Service) CreateInstance(scope *scope.MachineScope) (*infrav1.Instance,
 	s.scope.V(2).Info("Creating an instance for a machine")
 
 	input := &infrav1.Instance{
-		Type:              scope.AWSMachine.Spec.InstanceType,
-		IAMProfile:        scope.AWSMachine.Spec.IAMInstanceProfile,
-		RootDeviceSize:    scope.AWSMachine.Spec.RootDeviceSize,
-		NetworkInterfaces: scope.AWSMachine.Spec.NetworkInterfaces,
+		Type:                    scope.AWSMachine.Spec.InstanceType,
+		IAMProfile:              scope.IAMInstanceProfile(),
+		RootDeviceSize:          scope.AWSMachine.Spec.RootDeviceSize,
+		RootVolume:              scope.AWSMachine.Spec.RootVolume,
+		NetworkInterfaces:       scope.AWSMachine.Spec.NetworkInterfaces,
+		SpotMarketOptions:       scope.AWSMachine.Spec.SpotMarketOptions,
+		InstanceMetadataOptions: scope.AWSMachine.Spec.InstanceMetadataOptions,
+		DisableAPITermination:   scope.AWSMachine.Spec.DisableAPITermination,
+		NonRootVolumes:          scope.AWSMachine.Spec.NonRootVolumes,
+	}
+
+	// Worker nodes must never get the permissions of the control plane.
+	if !scope.IsControlPlane() && input.IAMProfile != "" && input.IAMProfile == scope.ControlPlaneIAMInstanceProfile() {
+		return nil, errors.Errorf("refusing to launch worker machine %q with the control plane IAM instance profile %q", scope.Name(), input.IAMProfile)
+	}
+
+	// The instance profile may be managed outside of the bootstrap stack, make
+	// sure it exists rather than letting the launch fail opaquely.
+	if input.IAMProfile != "" {
+		if err := s.validateIAMInstanceProfile(input.IAMProfile); err != nil {
+			record.Warnf(scope.AWSMachine, "InvalidIAMInstanceProfile", "Cannot launch instance with IAM instance profile %q: %v", input.IAMProfile, err)
+			return nil, err
+		}
+	}
+
+	// EBS can only use KMS keys of the region the volume is created in.
+	if err := validateVolumeKMSKeyRegions(s.scope.Region(), input.RootVolume, input.NonRootVolumes); err != nil {
+		record.Warnf(scope.AWSMachine, "InvalidVolumeEncryptionKey", "Cannot launch instance with the volume encryption keys: %v", err)
+		return nil, err
 	}
 
 	// Make sure to use the MachineScope here to get the merger of AWSCluster and AWSMachine tags
 	additionalTags := scope.AdditionalTags()
-	// Set the cloud provider tag
-	additionalTags[infrav1.ClusterAWSCloudProviderTagKey(s.scope.Name())] = string(infrav1.ResourceLifecycleOwned)
+	// Mark spot instances, so they can be told apart from on-demand ones
+	if input.SpotMarketOptions != nil {
+		additionalTags[infrav1.NameAWSProviderMarketType] = infrav1.SpotMarketTypeTagValue
+	}
 
-	input.Tags = infrav1.Build(infrav1.BuildParams{
-		ClusterName: s.scope.Name(),
-		Lifecycle:   infrav1.ResourceLifecycleOwned,
-		Name:        aws.String(scope.Name()),
-		Role:        aws.String(scope.Role()),
-		Additional:  additionalTags,
-	})
+	input.Tags = infrav1.Build(s.getInstanceTagParams(aws.String(scope.Name()), scope.Role(), additionalTags))
 
 	var err error
 	// Pick image from the machine configuration, or use a default one.
 	if scope.AWSMachine.Spec.AMI.ID != nil {
 		input.ImageID = *scope.AWSMachine.Spec.AMI.ID
+	} else if scope.AWSMachine.Spec.ImageLookupSSMParameter != "" {
+		input.ImageID, err = s.getAMIFromSSMParameter(scope.AWSMachine.Spec.ImageLookupSSMParameter)
+		if err != nil {
+			return nil, err
+		}
 	} else {
 		imageLookupOrg := scope.AWSMachine.Spec.ImageLookupOrg
 		if imageLookupOrg == "" {
@@ -140,7 +181,12 @@ func (s *Service) CreateInstance(scope *scope.MachineScope) (*infrav1.Instance,
 			imageLookupBaseOS = scope.AWSCluster.Spec.ImageLookupBaseOS
 		}
 
-		input.ImageID, err = s.defaultAMILookup(imageLookupOrg, imageLookupBaseOS, *scope.Machine.Spec.Version)
+		imageLookupFormat := scope.AWSMachine.Spec.ImageLookupFormat
+		if imageLookupFormat == "" {
+			imageLookupFormat = scope.AWSCluster.Spec.ImageLookupFormat
+		}
+
+		input.ImageID, err = s.defaultAMILookup(imageLookupOrg, imageLookupBaseOS, imageLookupFormat, *scope.Machine.Spec.Version)
 		if err != nil {
 			return nil, err
 		}
@@ -180,18 +226,100 @@ func (s *Service) CreateInstance(scope *scope.MachineScope) (*infrav1.Instance,
 		input.SubnetID = sns[0].ID
 	}
 
+	// The network interfaces of the machine are created with the instance,
+	// which is placed in the subnet of its primary interface.
+	if specs := scope.AWSMachine.Spec.NetworkInterfaceSpecs; len(specs) > 0 {
+		input.NetworkInterfaceSpecs, err = s.getNetworkInterfaceSpecs(specs, input.SubnetID)
+		if err != nil {
+			record.Warnf(scope.AWSMachine, "InvalidNetworkInterfaces", "Cannot launch instance with the network interfaces: %v", err)
+			return nil, err
+		}
+		for _, iface := range input.NetworkInterfaceSpecs {
+			if iface.DeviceIndex == 0 {
+				input.SubnetID = aws.StringValue(iface.Subnet.ID)
+			}
+		}
+	}
+
+	if publicIP := scope.AWSMachine.Spec.PublicIP; publicIP != nil {
+		if err := s.applyPublicIP(input, *publicIP); err != nil {
+			record.Warnf(scope.AWSMachine, "InvalidPublicIP", "Cannot launch instance with a public IP: %v", err)
+			return nil, err
+		}
+	}
+
+	if ifaceType := scope.AWSMachine.Spec.NetworkInterfaceType; ifaceType != "" {
+		if err := applyNetworkInterfaceType(input, ifaceType); err != nil {
+			record.Warnf(scope.AWSMachine, "InvalidNetworkInterfaceType", "Cannot launch instance with %s network interfaces: %v", ifaceType, err)
+			return nil, err
+		}
+	}
+
 	if s.scope.Network().APIServerELB.DNSName == "" {
 		return nil, awserrors.NewFailedDependency(
 			errors.New("failed to run controlplane, APIServer ELB not available"),
 		)
 	}
 
+	// Placement can't be changed once the instance is launched, make sure it's valid first.
+	if name := scope.AWSMachine.Spec.PlacementGroupName; name != "" {
+		if strategy := scope.AWSMachine.Spec.PlacementGroupStrategy; strategy != "" {
+			created, err := s.ensurePlacementGroup(name, strategy)
+			if err != nil {
+				record.Warnf(scope.AWSMachine, "FailedCreatePlacementGroup", "Failed to create placement group %q: %v", name, err)
+				return nil, err
+			}
+			if created {
+				record.Eventf(scope.AWSMachine, "SuccessfulCreatePlacementGroup", "Created placement group %q with strategy %q", name, strategy)
+			}
+		}
+		if err := s.validatePlacementGroup(name, scope.AWSMachine.Spec.PlacementGroupPartition); err != nil {
+			record.Warnf(scope.AWSMachine, "InvalidPlacementGroup", "Cannot launch instance in placement group %q: %v", name, err)
+			return nil, err
+		}
+		input.PlacementGroupName = name
+		input.PlacementGroupPartition = scope.AWSMachine.Spec.PlacementGroupPartition
+	}
+	input.Tenancy = scope.AWSMachine.Spec.Tenancy
+	input.HostID = scope.AWSMachine.Spec.HostID
+
+	input.CapacityReservationPreference = scope.AWSMachine.Spec.CapacityReservationPreference
+	if id := scope.AWSMachine.Spec.CapacityReservationID; id != "" {
+		if err := s.validateCapacityReservation(id, scope.AWSMachine.Spec.InstanceType, input.SubnetID); err != nil {
+			record.Warnf(scope.AWSMachine, "InvalidCapacityReservation", "Cannot launch instance in capacity reservation %q: %v", id, err)
+			return nil, err
+		}
+		input.CapacityReservationID = id
+	}
+
 	// Set userdata.
 	userData, err := scope.GetBootstrapData()
 	if err != nil {
 		record.Warnf(scope.AWSMachine, corev1.EventTypeWarning, "FailedGetBootstrapData", err.Error())
 		return nil, err
 	}
+	// Raw user data is passed through as is, it may not be cloud-init or kubeadm.
+	if !scope.HasRawUserData() {
+		if scope.AWSMachine.Spec.KubeletRegistration != nil {
+			userData, err = withKubeletRegistration(scope, userData)
+			if err != nil {
+				record.Warnf(scope.AWSMachine, "FailedKubeletRegistration", "Failed to add node labels and taints to the bootstrap data: %v", err)
+				return nil, err
+			}
+		}
+		userData, err = gzipUserData(userData)
+		if err != nil {
+			return nil, err
+		}
+		// Only a script fetching the bootstrap data is left in the user data.
+		if backend := scope.SecureSecretsBackend(); backend != "" {
+			userData, err = s.storeBootstrapData(scope, userData)
+			if err != nil {
+				record.Warnf(scope.AWSMachine, "FailedStoreBootstrapData", "Failed to store bootstrap data in %s: %v", backend, err)
+				return nil, err
+			}
+		}
+	}
 	input.UserData = pointer.StringPtr(userData)
 
 	// Set security groups.
@@ -209,8 +337,22 @@ func (s *Service) CreateInstance(scope *scope.MachineScope) (*infrav1.Instance,
 		input.SSHKeyName = aws.String(scope.AWSCluster.Spec.SSHKeyName)
 	}
 
-	s.scope.V(2).Info("Running instance", "machine-role", scope.Role())
-	out, err := s.runInstance(scope.Role(), input)
+	instanceTypes, err := s.instanceTypeCandidates(scope, input.SubnetID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Try the next instance type only when EC2 is out of capacity for this one.
+	var out *infrav1.Instance
+	for i, instanceType := range instanceTypes {
+		input.Type = instanceType
+		s.scope.V(2).Info("Running instance", "machine-role", scope.Role(), "instance-type", instanceType)
+		out, err = s.runInstance(scope.Role(), input)
+		if err == nil || i == len(instanceTypes)-1 || !awserrors.IsInsufficientCapacity(errors.Cause(err)) {
+			break
+		}
+		record.Warnf(scope.AWSMachine, "InstanceTypeUnavailable", "Insufficient capacity for instance type %q, trying %q", instanceType, instanceTypes[i+1])
+	}
 	if err != nil {
 		// Only record the failure event if the error is not related to failed dependencies.
 		// This is to avoid spamming failure events since the machine will be requeued by the actuator.
@@ -233,6 +375,55 @@ func (s *Service) CreateInstance(scope *scope.MachineScope) (*infrav1.Instance,
 	return out, nil
 }
 
+// withKubeletRegistration merges the node labels and taints of the machine into its
+// base64 encoded bootstrap data.
+func withKubeletRegistration(scope *scope.MachineScope, userData string) (string, error) {
+	reg := scope.AWSMachine.Spec.KubeletRegistration
+
+	input := &userdata.KubeletRegistrationInput{
+		Taints: reg.Taints,
+	}
+	if reg.PropagateMachineLabels {
+		input.NodeLabels = map[string]string{}
+		for k, v := range scope.Machine.Labels {
+			if kubeletAllowedLabel(k) {
+				input.NodeLabels[k] = v
+			}
+		}
+	}
+
+	decoded, err := base64.StdEncoding.DecodeString(userData)
+	if err != nil {
+		return "", errors.Wrap(err, "failed to decode bootstrap data")
+	}
+
+	merged, err := userdata.WithKubeletRegistration(decoded, input)
+	if err != nil {
+		return "", errors.Wrap(err, "failed to merge bootstrap data")
+	}
+
+	return base64.StdEncoding.EncodeToString(merged), nil
+}
+
+// kubeletAllowedLabel reports whether the kubelet may set the label on its own node.
+// The NodeRestriction admission plugin only lets it set labels in the kubernetes.io
+// and k8s.io namespaces under kubelet.kubernetes.io and node.kubernetes.io.
+func kubeletAllowedLabel(key string) bool {
+	i := strings.Index(key, "/")
+	if i < 0 {
+		return true
+	}
+	domain := key[:i]
+
+	inNamespace := func(namespace string) bool {
+		return domain == namespace || strings.HasSuffix(domain, "."+namespace)
+	}
+	if inNamespace("kubelet.kubernetes.io") || inNamespace("node.kubernetes.io") {
+		return true
+	}
+	return !inNamespace("kubernetes.io") && !inNamespace("k8s.io")
+}
+
 // GetCoreSecurityGroups looks up the security group IDs managed by this actuator
 // They are considered "core" to its proper functioning
 func (s *Service) GetCoreSecurityGroups(scope *scope.MachineScope) ([]string, error) {
@@ -261,6 +452,74 @@ func (s *Service) GetCoreSecurityGroups(scope *scope.MachineScope) ([]string, er
 	return ids, nil
 }
 
+// GetAdditionalSecurityGroupsIDs resolves the additional security groups of the
+// machine to their IDs, in order and without duplicates. Security groups can be
+// referenced by ID or by filters, and must belong to the cluster VPC.
+func (s *Service) GetAdditionalSecurityGroupsIDs(scope *scope.MachineScope) ([]string, error) {
+	ids, err := s.getSecurityGroupIDs(scope.AWSMachine.Spec.AdditionalSecurityGroups)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to resolve additional security groups of machine %q", scope.Name())
+	}
+	return ids, nil
+}
+
+// getSecurityGroupIDs resolves references to security groups of the cluster
+// VPC to their IDs, in order and without duplicates.
+func (s *Service) getSecurityGroupIDs(refs []infrav1.AWSResourceReference) ([]string, error) {
+	seen := sets.NewString()
+	ids := []string{}
+
+	for i, ref := range refs {
+		input := &ec2.DescribeSecurityGroupsInput{
+			Filters: []*ec2.Filter{filter.EC2.VPC(s.scope.VPC().ID)},
+		}
+
+		var description string
+		switch {
+		case ref.ID != nil:
+			input.GroupIds = []*string{ref.ID}
+			description = fmt.Sprintf("with id %q", *ref.ID)
+		case len(ref.Filters) > 0:
+			for _, f := range ref.Filters {
+				input.Filters = append(input.Filters, &ec2.Filter{
+					Name:   aws.String(f.Name),
+					Values: aws.StringSlice(f.Values),
+				})
+			}
+			description = fmt.Sprintf("matching filters %v", ref.Filters)
+		default:
+			return nil, errors.Errorf("security group %d has neither an id nor filters", i)
+		}
+
+		var out *ec2.DescribeSecurityGroupsOutput
+		err := awserrors.RetryOnThrottling(func() (err error) {
+			out, err = s.scope.EC2.DescribeSecurityGroups(input)
+			return err
+		})
+		switch {
+		case awserrors.IsNotFound(err):
+			out = &ec2.DescribeSecurityGroupsOutput{}
+		case err != nil:
+			return nil, errors.Wrapf(err, "failed to describe security groups %s", description)
+		}
+
+		if len(out.SecurityGroups) == 0 {
+			return nil, errors.Errorf("no security group %s found in vpc %q", description, s.scope.VPC().ID)
+		}
+
+		for _, sg := range out.SecurityGroups {
+			id := aws.StringValue(sg.GroupId)
+			if seen.Has(id) {
+				continue
+			}
+			seen.Insert(id)
+			ids = append(ids, id)
+		}
+	}
+
+	return ids, nil
+}
+
 // TerminateInstance terminates an EC2 instance.
 // Returns nil on success, error in all other cases.
 func (s *Service) TerminateInstance(instanceID string) error {
@@ -278,6 +537,42 @@ func (s *Service) TerminateInstance(instanceID string) error {
 	return nil
 }
 
+// CancelSpotInstanceRequests cancels the open spot requests of an instance, so
+// persistent requests don't launch it again once it is terminated.
+func (s *Service) CancelSpotInstanceRequests(instanceID string) error {
+	var out *ec2.DescribeSpotInstanceRequestsOutput
+	err := awserrors.RetryOnThrottling(func() (err error) {
+		out, err = s.scope.EC2.DescribeSpotInstanceRequests(&ec2.DescribeSpotInstanceRequestsInput{
+			Filters: []*ec2.Filter{
+				filter.EC2.InstanceID(instanceID),
+				// Requests are disabled while their instance is stopped.
+				filter.EC2.SpotInstanceRequestStates(ec2.SpotInstanceStateOpen, ec2.SpotInstanceStateActive, "disabled"),
+			},
+		})
+		return err
+	})
+	if err != nil {
+		return errors.Wrapf(err, "failed to describe spot requests of instance %q", instanceID)
+	}
+
+	var ids []*string
+	for _, request := range out.SpotInstanceRequests {
+		ids = append(ids, request.SpotInstanceRequestId)
+	}
+	if len(ids) == 0 {
+		return nil
+	}
+
+	if _, err := s.scope.EC2.CancelSpotInstanceRequests(&ec2.CancelSpotInstanceRequestsInput{
+		SpotInstanceRequestIds: ids,
+	}); err != nil {
+		return errors.Wrapf(err, "failed to cancel spot requests of instance %q", instanceID)
+	}
+
+	s.scope.V(2).Info("Cancelled spot requests", "instance-id", instanceID, "spot-request-ids", aws.StringValueSlice(ids))
+	return nil
+}
+
 // TerminateInstanceAndWait terminates and waits
 // for an EC2 instance to terminate.
 func (s *Service) TerminateInstanceAndWait(instanceID string) error {
@@ -298,6 +593,28 @@ func (s *Service) TerminateInstanceAndWait(instanceID string) error {
 	return nil
 }
 
+// gzipUserData compresses base64 encoded user data, cloud-init decompresses
+// it transparently.
+func gzipUserData(userData string) (string, error) {
+	var buf bytes.Buffer
+
+	decoded, err := base64.StdEncoding.DecodeString(userData)
+	if err != nil {
+		return "", errors.Wrap(err, "failed to decode bootstrapData")
+	}
+
+	gz := gzip.NewWriter(&buf)
+	if _, err := gz.Write(decoded); err != nil {
+		return "", errors.Wrap(err, "failed to gzip userdata")
+	}
+
+	if err := gz.Close(); err != nil {
+		return "", errors.Wrap(err, "failed to gzip userdata")
+	}
+
+	return base64.StdEncoding.EncodeToString(buf.Bytes()), nil
+}
+
 func (s *Service) runInstance(role string, i *infrav1.Instance) (*infrav1.Instance, error) {
 	input := &ec2.RunInstancesInput{
 		InstanceType: aws.String(i.Type),
@@ -308,26 +625,13 @@ func (s *Service) runInstance(role string, i *infrav1.Instance) (*infrav1.Instan
 		MinCount:     aws.Int64(1),
 	}
 
-	if i.UserData != nil {
-		var buf bytes.Buffer
-
-		decoded, err := base64.StdEncoding.DecodeString(*i.UserData)
-		if err != nil {
-			return nil, errors.Wrap(err, "failed to decode bootstrapData")
-		}
-
-		gz := gzip.NewWriter(&buf)
-		if _, err := gz.Write(decoded); err != nil {
-			return nil, errors.Wrap(err, "failed to gzip userdata")
-		}
-
-		if err := gz.Close(); err != nil {
-			return nil, errors.Wrap(err, "failed to gzip userdata")
-		}
-
-		s.scope.V(2).Info("userData size", "bytes", buf.Len(), "role", role)
+	if i.DisableAPITermination {
+		input.DisableApiTermination = aws.Bool(true)
+	}
 
-		input.UserData = aws.String(base64.StdEncoding.EncodeToString(buf.Bytes()))
+	if i.UserData != nil {
+		s.scope.V(2).Info("userData size", "bytes", base64.StdEncoding.DecodedLen(len(*i.UserData)), "role", role)
+		input.UserData = i.UserData
 	}
 
 	if len(i.NetworkInterfaces) > 0 {
@@ -341,6 +645,8 @@ func (s *Service) runInstance(role string, i *infrav1.Instance) (*infrav1.Instan
 		}
 
 		input.NetworkInterfaces = netInterfaces
+	} else if len(i.NetworkInterfaceSpecs) > 0 {
+		input.NetworkInterfaces = getNetworkInterfaceSpecifications(i)
 	} else {
 		input.SubnetId = aws.String(i.SubnetID)
 
@@ -355,21 +661,70 @@ func (s *Service) runInstance(role string, i *infrav1.Instance) (*infrav1.Instan
 		}
 	}
 
-	if i.RootDeviceSize != 0 {
+	if i.RootDeviceSize != 0 || i.RootVolume != nil || len(i.NonRootVolumes) > 0 {
 		rootDeviceName, err := s.getImageRootDevice(i.ImageID)
 		if err != nil {
 			return nil, errors.Wrapf(err, "failed to get root volume from image %q", i.ImageID)
 		}
 
-		input.BlockDeviceMappings = []*ec2.BlockDeviceMapping{
-			{
+		if i.RootDeviceSize != 0 {
+			input.BlockDeviceMappings = append(input.BlockDeviceMappings, &ec2.BlockDeviceMapping{
 				DeviceName: rootDeviceName,
 				Ebs: &ec2.EbsBlockDevice{
 					DeleteOnTermination: aws.Bool(true),
 					VolumeSize:          aws.Int64(i.RootDeviceSize),
 				},
+			})
+		}
+
+		if i.RootVolume != nil {
+			input.BlockDeviceMappings = append(input.BlockDeviceMappings, getBlockDeviceMapping(aws.StringValue(rootDeviceName), i.RootVolume))
+		}
+
+		for idx := range i.NonRootVolumes {
+			volume := &i.NonRootVolumes[idx]
+			if volume.DeviceName == aws.StringValue(rootDeviceName) {
+				return nil, errors.Errorf("non root volume device name %q is the root device of image %q", volume.DeviceName, i.ImageID)
+			}
+			input.BlockDeviceMappings = append(input.BlockDeviceMappings, getBlockDeviceMapping(volume.DeviceName, volume))
+		}
+	}
+
+	if i.SpotMarketOptions != nil {
+		input.InstanceMarketOptions = getInstanceMarketOptionsRequest(i.SpotMarketOptions)
+	}
+
+	if i.PlacementGroupName != "" || i.Tenancy != "" || i.HostID != "" {
+		input.Placement = &ec2.Placement{}
+		if i.PlacementGroupName != "" {
+			input.Placement.GroupName = aws.String(i.PlacementGroupName)
+		}
+		if i.PlacementGroupPartition != 0 {
+			input.Placement.PartitionNumber = aws.Int64(i.PlacementGroupPartition)
+		}
+		if i.Tenancy != "" {
+			input.Placement.Tenancy = aws.String(string(i.Tenancy))
+		}
+		if i.HostID != "" {
+			input.Placement.HostId = aws.String(i.HostID)
+		}
+	}
+
+	switch {
+	case i.CapacityReservationID != "":
+		input.CapacityReservationSpecification = &ec2.CapacityReservationSpecification{
+			CapacityReservationTarget: &ec2.CapacityReservationTarget{
+				CapacityReservationId: aws.String(i.CapacityReservationID),
 			},
 		}
+	case i.CapacityReservationPreference != "":
+		input.CapacityReservationSpecification = &ec2.CapacityReservationSpecification{
+			CapacityReservationPreference: aws.String(string(i.CapacityReservationPreference)),
+		}
+	}
+
+	if i.InstanceMetadataOptions != nil {
+		input.MetadataOptions = getInstanceMetadataOptionsRequest(i.InstanceMetadataOptions)
 	}
 
 	if len(i.Tags) > 0 {
@@ -384,7 +739,11 @@ func (s *Service) runInstance(role string, i *infrav1.Instance) (*infrav1.Instan
 		input.TagSpecifications = append(input.TagSpecifications, spec)
 	}
 
-	out, err := s.scope.EC2.RunInstances(input)
+	var out *ec2.Reservation
+	err := awserrors.RetryOnThrottling(func() (err error) {
+		out, err = s.scope.EC2.RunInstances(input)
+		return err
+	})
 	if err != nil {
 		return nil, errors.Wrap(err, "failed to run instance")
 	}
@@ -393,20 +752,185 @@ func (s *Service) runInstance(role string, i *infrav1.Instance) (*infrav1.Instan
 		return nil, errors.Errorf("no instance returned for reservation %v", out.GoString())
 	}
 
-	waitTimeout := 1 * time.Minute
-	s.scope.V(2).Info("Waiting for instance to be in running state", "instance-id", *out.Instances[0].InstanceId, "timeout", waitTimeout.String())
-	ctx, cancel := context.WithTimeout(aws.BackgroundContext(), waitTimeout)
+	if s.InstanceReadyTimeout > 0 {
+		s.waitForInstanceRunning(aws.StringValue(out.Instances[0].InstanceId))
+	}
+
+	return s.SDKToInstance(out.Instances[0])
+}
+
+// waitForInstanceRunning waits up to InstanceReadyTimeout for the instance to
+// be running. Running late is not an error, the instance state is checked
+// again on the next reconcile.
+func (s *Service) waitForInstanceRunning(instanceID string) {
+	interval := s.InstanceReadyPollInterval
+	if interval <= 0 {
+		interval = DefaultInstanceReadyPollInterval
+	}
+
+	s.scope.V(2).Info("Waiting for instance to be in running state", "instance-id", instanceID, "timeout", s.InstanceReadyTimeout.String(), "interval", interval.String())
+	ctx, cancel := context.WithTimeout(aws.BackgroundContext(), s.InstanceReadyTimeout)
 	defer cancel()
 
 	if err := s.scope.EC2.WaitUntilInstanceRunningWithContext(
 		ctx,
-		&ec2.DescribeInstancesInput{InstanceIds: []*string{out.Instances[0].InstanceId}},
+		&ec2.DescribeInstancesInput{InstanceIds: []*string{aws.String(instanceID)}},
 		request.WithWaiterLogger(&awslog{s.scope.Logger}),
+		request.WithWaiterDelay(request.ConstantWaiterDelay(interval)),
+		// The context deadline ends the wait, not the number of attempts.
+		request.WithWaiterMaxAttempts(int(s.InstanceReadyTimeout/interval)+1),
 	); err != nil {
-		s.scope.V(2).Info("Could not determine if Machine is running. Machine state might be unavailable until next renconciliation.")
+		s.scope.V(2).Info("Could not determine if Machine is running. Machine state might be unavailable until next renconciliation.", "instance-id", instanceID, "timeout", s.InstanceReadyTimeout.String())
 	}
+}
 
-	return s.SDKToInstance(out.Instances[0])
+func (s *Service) getInstanceTagParams(name *string, role string, additionalTags infrav1.Tags) infrav1.BuildParams {
+	// Set the cloud provider tag
+	additionalTags[infrav1.ClusterAWSCloudProviderTagKey(s.scope.Name())] = string(infrav1.ResourceLifecycleOwned)
+
+	return infrav1.BuildParams{
+		ClusterName: s.scope.Name(),
+		Lifecycle:   infrav1.ResourceLifecycleOwned,
+		Name:        name,
+		Role:        aws.String(role),
+		Additional:  additionalTags,
+	}
+}
+
+// validateCapacityReservation checks that the capacity reservation is active,
+// and that the instance type and the availability zone of the subnet match the
+// ones of the reservation.
+func (s *Service) validateCapacityReservation(id string, instanceType string, subnetID string) error {
+	var out *ec2.DescribeCapacityReservationsOutput
+	err := awserrors.RetryOnThrottling(func() (err error) {
+		out, err = s.scope.EC2.DescribeCapacityReservations(&ec2.DescribeCapacityReservationsInput{
+			CapacityReservationIds: aws.StringSlice([]string{id}),
+		})
+		return err
+	})
+	if code, _ := awserrors.Code(errors.Cause(err)); code == awserrors.CapacityReservationNotFound || (err == nil && len(out.CapacityReservations) == 0) {
+		return awserrors.NewFailedDependency(errors.Errorf("capacity reservation %q not found", id))
+	} else if err != nil {
+		return errors.Wrapf(err, "failed to describe capacity reservation %q", id)
+	}
+
+	cr := out.CapacityReservations[0]
+	if state := aws.StringValue(cr.State); state != ec2.CapacityReservationStateActive {
+		return awserrors.NewFailedDependency(errors.Errorf("capacity reservation %q is %s", id, state))
+	}
+
+	if reserved := aws.StringValue(cr.InstanceType); reserved != instanceType {
+		return errors.Errorf("capacity reservation %q is for instance type %q, not %q", id, reserved, instanceType)
+	}
+
+	for _, sn := range s.scope.Subnets() {
+		if sn.ID != subnetID {
+			continue
+		}
+		if reserved := aws.StringValue(cr.AvailabilityZone); sn.AvailabilityZone != "" && reserved != sn.AvailabilityZone {
+			return errors.Errorf("capacity reservation %q is in availability zone %q, but subnet %q is in %q", id, reserved, subnetID, sn.AvailabilityZone)
+		}
+	}
+
+	return nil
+}
+
+// ensurePlacementGroup creates the placement group unless it exists, and
+// returns whether it was created. Placement groups can't be tagged with the
+// SDK in use, so they aren't deleted with the cluster.
+func (s *Service) ensurePlacementGroup(name string, strategy infrav1.PlacementGroupStrategy) (bool, error) {
+	var out *ec2.DescribePlacementGroupsOutput
+	err := awserrors.RetryOnThrottling(func() (err error) {
+		out, err = s.scope.EC2.DescribePlacementGroups(&ec2.DescribePlacementGroupsInput{
+			GroupNames: aws.StringSlice([]string{name}),
+		})
+		return err
+	})
+	if code, _ := awserrors.Code(errors.Cause(err)); err != nil && code != awserrors.PlacementGroupNotFound {
+		return false, errors.Wrapf(err, "failed to describe placement group %q", name)
+	}
+	if err == nil && len(out.PlacementGroups) > 0 {
+		if existing := aws.StringValue(out.PlacementGroups[0].Strategy); existing != string(strategy) {
+			return false, errors.Errorf("placement group %q exists with strategy %q instead of %q", name, existing, strategy)
+		}
+		return false, nil
+	}
+
+	input := &ec2.CreatePlacementGroupInput{
+		GroupName: aws.String(name),
+		Strategy:  aws.String(string(strategy)),
+	}
+	if strategy == infrav1.PlacementGroupStrategyPartition {
+		input.PartitionCount = aws.Int64(maxPlacementGroupPartitions)
+	}
+	if _, err := s.scope.EC2.CreatePlacementGroup(input); err != nil {
+		// Another machine created it in the meantime.
+		if code, _ := awserrors.Code(errors.Cause(err)); code == awserrors.PlacementGroupDuplicate {
+			return false, nil
+		}
+		return false, errors.Wrapf(err, "failed to create placement group %q", name)
+	}
+
+	s.scope.Info("Created placement group", "placement-group", name, "strategy", strategy)
+	return true, nil
+}
+
+// getInstanceMarketOptionsRequest returns the market options to request a one-time
+// spot instance, which is terminated on interruption.
+// validatePlacementGroup checks that the placement group exists and, when a
+// partition is requested, that it is a partition placement group with that partition.
+func (s *Service) validatePlacementGroup(name string, partition int64) error {
+	var out *ec2.DescribePlacementGroupsOutput
+	err := awserrors.RetryOnThrottling(func() (err error) {
+		out, err = s.scope.EC2.DescribePlacementGroups(&ec2.DescribePlacementGroupsInput{
+			GroupNames: aws.StringSlice([]string{name}),
+		})
+		return err
+	})
+	if code, _ := awserrors.Code(errors.Cause(err)); code == awserrors.PlacementGroupNotFound || (err == nil && len(out.PlacementGroups) == 0) {
+		return awserrors.NewFailedDependency(errors.Errorf("placement group %q not found", name))
+	} else if err != nil {
+		return errors.Wrapf(err, "failed to describe placement group %q", name)
+	}
+
+	pg := out.PlacementGroups[0]
+	if state := aws.StringValue(pg.State); state != ec2.PlacementGroupStateAvailable {
+		return awserrors.NewFailedDependency(errors.Errorf("placement group %q is %s", name, state))
+	}
+
+	if partition != 0 {
+		if strategy := aws.StringValue(pg.Strategy); strategy != ec2.PlacementStrategyPartition {
+			return errors.Errorf("placement group %q has strategy %q, a partition can only be set for the %q strategy", name, strategy, ec2.PlacementStrategyPartition)
+		}
+		if count := aws.Int64Value(pg.PartitionCount); partition > count {
+			return errors.Errorf("placement group %q has %d partitions, partition %d does not exist", name, count, partition)
+		}
+	}
+
+	return nil
+}
+
+func getInstanceMarketOptionsRequest(spotMarketOptions *infrav1.SpotMarketOptions) *ec2.InstanceMarketOptionsRequest {
+	spotOptions := &ec2.SpotMarketOptions{
+		SpotInstanceType:             aws.String(ec2.SpotInstanceTypeOneTime),
+		InstanceInterruptionBehavior: aws.String(ec2.InstanceInterruptionBehaviorTerminate),
+	}
+
+	// An unset MaxPrice defaults to the On-Demand price.
+	if spotMarketOptions.MaxPrice != nil && *spotMarketOptions.MaxPrice != "" {
+		spotOptions.MaxPrice = spotMarketOptions.MaxPrice
+	}
+
+	// EC2 only stops or hibernates the instances of persistent requests.
+	if spotMarketOptions.IsPersistent() {
+		spotOptions.SpotInstanceType = aws.String(ec2.SpotInstanceTypePersistent)
+		spotOptions.InstanceInterruptionBehavior = aws.String(string(spotMarketOptions.InterruptionBehavior))
+	}
+
+	return &ec2.InstanceMarketOptionsRequest{
+		MarketType:  aws.String(ec2.MarketTypeSpot),
+		SpotOptions: spotOptions,
+	}
 }
 
 // An internal type to satisfy aws' log interface.
@@ -437,6 +961,42 @@ func (s *Service) GetInstanceSecurityGroups(instanceID string) (map[string][]str
 	return out, nil
 }
 
+// GetInstanceProfileAssociationState returns the state of the association
+// between the given EC2 instance and its IAM instance profile. An instance
+// without an association yet is reported as pending, since associations are
+// eventually consistent after launch.
+func (s *Service) GetInstanceProfileAssociationState(instanceID string) (infrav1.InstanceProfileAssociationState, error) {
+	input := &ec2.DescribeIamInstanceProfileAssociationsInput{
+		Filters: []*ec2.Filter{
+			{
+				Name:   aws.String("instance-id"),
+				Values: aws.StringSlice([]string{instanceID}),
+			},
+		},
+	}
+
+	var out *ec2.DescribeIamInstanceProfileAssociationsOutput
+	err := awserrors.RetryOnThrottling(func() (err error) {
+		out, err = s.scope.EC2.DescribeIamInstanceProfileAssociations(input)
+		return err
+	})
+	if err != nil {
+		return "", errors.Wrapf(err, "failed to describe IAM instance profile associations for instance %q", instanceID)
+	}
+
+	state := infrav1.InstanceProfileAssociationStatePending
+	for _, association := range out.IamInstanceProfileAssociations {
+		switch aws.StringValue(association.State) {
+		case ec2.IamInstanceProfileAssociationStateAssociated:
+			return infrav1.InstanceProfileAssociationStateAssociated, nil
+		case ec2.IamInstanceProfileAssociationStateDisassociating, ec2.IamInstanceProfileAssociationStateDisassociated:
+			state = infrav1.InstanceProfileAssociationStateFailed
+		}
+	}
+
+	return state, nil
+}
+
 // UpdateInstanceSecurityGroups modifies the security groups of the given
 // EC2 instance.
 func (s *Service) UpdateInstanceSecurityGroups(instanceID string, ids []string) error {
@@ -458,6 +1018,89 @@ func (s *Service) UpdateInstanceSecurityGroups(instanceID string, ids []string)
 	return nil
 }
 
+// getInstanceMetadataOptionsRequest returns the metadata options to launch an
+// instance with, or nil when all of them are left to the AWS defaults.
+func getInstanceMetadataOptionsRequest(options *infrav1.InstanceMetadataOptions) *ec2.InstanceMetadataOptionsRequest {
+	request := &ec2.InstanceMetadataOptionsRequest{}
+	if options.HTTPPutResponseHopLimit != 0 {
+		request.HttpPutResponseHopLimit = aws.Int64(options.HTTPPutResponseHopLimit)
+	}
+	if options.HTTPTokens != "" {
+		request.HttpTokens = aws.String(string(options.HTTPTokens))
+	}
+	if options.HTTPEndpoint != "" {
+		request.HttpEndpoint = aws.String(string(options.HTTPEndpoint))
+	}
+
+	if *request == (ec2.InstanceMetadataOptionsRequest{}) {
+		return nil
+	}
+	return request
+}
+
+// ModifyInstanceMetadataOptions applies the metadata options to the given EC2
+// instance, so that changes made outside of Kubernetes are reverted.
+func (s *Service) ModifyInstanceMetadataOptions(instanceID string, options *infrav1.InstanceMetadataOptions) error {
+	s.scope.V(2).Info("Attempting to modify metadata options of instance", "instance-id", instanceID)
+
+	input := &ec2.ModifyInstanceMetadataOptionsInput{
+		InstanceId: aws.String(instanceID),
+	}
+	if options.HTTPPutResponseHopLimit != 0 {
+		input.HttpPutResponseHopLimit = aws.Int64(options.HTTPPutResponseHopLimit)
+	}
+	if options.HTTPTokens != "" {
+		input.HttpTokens = aws.String(string(options.HTTPTokens))
+	}
+	if options.HTTPEndpoint != "" {
+		input.HttpEndpoint = aws.String(string(options.HTTPEndpoint))
+	}
+
+	if _, err := s.scope.EC2.ModifyInstanceMetadataOptions(input); err != nil {
+		return errors.Wrapf(err, "failed to modify metadata options of instance %q", instanceID)
+	}
+
+	s.scope.V(2).Info("Modified metadata options of instance", "instance-id", instanceID)
+	return nil
+}
+
+// GetInstanceTerminationProtection returns whether termination protection is
+// enabled for the given EC2 instance.
+func (s *Service) GetInstanceTerminationProtection(instanceID string) (bool, error) {
+	input := &ec2.DescribeInstanceAttributeInput{
+		InstanceId: aws.String(instanceID),
+		Attribute:  aws.String(ec2.InstanceAttributeNameDisableApiTermination),
+	}
+
+	out, err := s.scope.EC2.DescribeInstanceAttribute(input)
+	if err != nil {
+		return false, errors.Wrapf(err, "failed to describe termination protection of instance %q", instanceID)
+	}
+
+	if out.DisableApiTermination == nil {
+		return false, nil
+	}
+	return aws.BoolValue(out.DisableApiTermination.Value), nil
+}
+
+// SetInstanceTerminationProtection enables or disables termination protection
+// for the given EC2 instance.
+func (s *Service) SetInstanceTerminationProtection(instanceID string, enabled bool) error {
+	s.scope.V(2).Info("Attempting to set termination protection of instance", "instance-id", instanceID, "enabled", enabled)
+
+	input := &ec2.ModifyInstanceAttributeInput{
+		InstanceId:            aws.String(instanceID),
+		DisableApiTermination: &ec2.AttributeBooleanValue{Value: aws.Bool(enabled)},
+	}
+
+	if _, err := s.scope.EC2.ModifyInstanceAttribute(input); err != nil {
+		return errors.Wrapf(err, "failed to set termination protection of instance %q", instanceID)
+	}
+
+	s.scope.V(2).Info("Set termination protection of instance", "instance-id", instanceID, "enabled", enabled)
+	return nil
+}
+
 // UpdateResourceTags updates the tags for an instance.
 // This will be called if there is anything to create (update) or delete.
 // We may not always have to perform each action, so we check what we're
@@ -479,7 +1122,10 @@ func (s *Service) UpdateResourceTags(resourceID *string, create map[string]strin
 		}
 
 		// Create/Update tags in AWS.
-		if _, err := s.scope.EC2.CreateTags(input); err != nil {
+		if err := awserrors.RetryOnThrottling(func() error {
+			_, err := s.scope.EC2.CreateTags(input)
+			return err
+		}); err != nil {
 			return errors.Wrapf(err, "failed to create tags for resource %q: %+v", *resourceID, create)
 		}
 	}
@@ -516,7 +1162,11 @@ func (s *Service) getInstanceENIs(instanceID string) ([]*ec2.NetworkInterface, e
 		},
 	}
 
-	output, err := s.scope.EC2.DescribeNetworkInterfaces(input)
+	var output *ec2.DescribeNetworkInterfacesOutput
+	err := awserrors.RetryOnThrottling(func() (err error) {
+		output, err = s.scope.EC2.DescribeNetworkInterfaces(input)
+		return err
+	})
 	if err != nil {
 		return nil, err
 	}
@@ -524,12 +1174,70 @@ func (s *Service) getInstanceENIs(instanceID string) ([]*ec2.NetworkInterface, e
 	return output.NetworkInterfaces, nil
 }
 
+// getBlockDeviceMapping returns the block device mapping that attaches a new
+// EBS volume to the instance as deviceName. It is used for the root volume,
+// whose device name comes from the AMI, as well as for data volumes.
+func getBlockDeviceMapping(deviceName string, volume *infrav1.Volume) *ec2.BlockDeviceMapping {
+	ebs := &ec2.EbsBlockDevice{
+		DeleteOnTermination: aws.Bool(true),
+		VolumeSize:          aws.Int64(volume.Size),
+	}
+	if volume.DeleteOnTermination != nil {
+		ebs.DeleteOnTermination = volume.DeleteOnTermination
+	}
+	if volume.Type != "" {
+		ebs.VolumeType = aws.String(string(volume.Type))
+	}
+	if volume.IOPS != 0 {
+		ebs.Iops = aws.Int64(volume.IOPS)
+	}
+	if volume.Encrypted {
+		ebs.Encrypted = aws.Bool(true)
+	}
+	if volume.KMSKeyID != "" {
+		ebs.KmsKeyId = aws.String(volume.KMSKeyID)
+	}
+
+	return &ec2.BlockDeviceMapping{
+		DeviceName: aws.String(deviceName),
+		Ebs:        ebs,
+	}
+}
+
+// validateVolumeKMSKeyRegions returns an error when a volume is encrypted with
+// the ARN of a KMS key of another region. Key IDs and aliases are always
+// resolved in the region of the volume.
+func validateVolumeKMSKeyRegions(region string, rootVolume *infrav1.Volume, nonRootVolumes []infrav1.Volume) error {
+	volumes := nonRootVolumes
+	if rootVolume != nil {
+		volumes = append([]infrav1.Volume{*rootVolume}, nonRootVolumes...)
+	}
+
+	for _, volume := range volumes {
+		if !strings.HasPrefix(volume.KMSKeyID, "arn:") {
+			continue
+		}
+		keyARN, err := arn.Parse(volume.KMSKeyID)
+		if err != nil {
+			return errors.Wrapf(err, "invalid KMS key ARN %q", volume.KMSKeyID)
+		}
+		if keyARN.Region != region {
+			return errors.Errorf("KMS key %q is in region %q, not in the region of the cluster %q", volume.KMSKeyID, keyARN.Region, region)
+		}
+	}
+	return nil
+}
+
 func (s *Service) getImageRootDevice(imageID string) (*string, error) {
 	input := &ec2.DescribeImagesInput{
 		ImageIds: []*string{aws.String(imageID)},
 	}
 
-	output, err := s.scope.EC2.DescribeImages(input)
+	var output *ec2.DescribeImagesOutput
+	err := awserrors.RetryOnThrottling(func() (err error) {
+		output, err = s.scope.EC2.DescribeImages(input)
+		return err
+	})
 	if err != nil {
 		return nil, err
 	}
@@ -548,7 +1256,11 @@ func (s *Service) getInstanceRootDeviceSize(instance *ec2.Instance) (*int64, err
 				VolumeIds: []*string{bdm.Ebs.VolumeId},
 			}
 
-			out, err := s.scope.EC2.DescribeVolumes(input)
+			var out *ec2.DescribeVolumesOutput
+			err := awserrors.RetryOnThrottling(func() (err error) {
+				out, err = s.scope.EC2.DescribeVolumes(input)
+				return err
+			})
 			if err != nil {
 				return nil, err
 			}
@@ -599,6 +1311,39 @@ func (s *Service) SDKToInstance(v *ec2.Instance) (*infrav1.Instance, error) {
 		i.Tags = converters.TagsToMap(v.Tags)
 	}
 
+	// The max price is not returned by DescribeInstances, so only the market type is known.
+	if aws.StringValue(v.InstanceLifecycle) == ec2.InstanceLifecycleTypeSpot {
+		i.SpotMarketOptions = &infrav1.SpotMarketOptions{}
+	}
+
+	if v.Placement != nil {
+		i.PlacementGroupName = aws.StringValue(v.Placement.GroupName)
+		i.PlacementGroupPartition = aws.Int64Value(v.Placement.PartitionNumber)
+		i.Tenancy = infrav1.Tenancy(aws.StringValue(v.Placement.Tenancy))
+		i.HostID = aws.StringValue(v.Placement.HostId)
+	}
+
+	for _, eni := range v.NetworkInterfaces {
+		if eni.Attachment != nil && aws.Int64Value(eni.Attachment.DeviceIndex) == 0 {
+			i.NetworkInterfaceType = infrav1.NetworkInterfaceType(aws.StringValue(eni.InterfaceType))
+		}
+	}
+
+	if v.CapacityReservationSpecification != nil {
+		i.CapacityReservationPreference = infrav1.CapacityReservationPreference(aws.StringValue(v.CapacityReservationSpecification.CapacityReservationPreference))
+		if target := v.CapacityReservationSpecification.CapacityReservationTarget; target != nil {
+			i.CapacityReservationID = aws.StringValue(target.CapacityReservationId)
+		}
+	}
+
+	if v.MetadataOptions != nil {
+		i.InstanceMetadataOptions = &infrav1.InstanceMetadataOptions{
+			HTTPPutResponseHopLimit: aws.Int64Value(v.MetadataOptions.HttpPutResponseHopLimit),
+			HTTPTokens:              infrav1.HTTPTokensState(aws.StringValue(v.MetadataOptions.HttpTokens)),
+			HTTPEndpoint:            infrav1.InstanceMetadataEndpointState(aws.StringValue(v.MetadataOptions.HttpEndpoint)),
+		}
+	}
+
 	rootSize, err := s.getInstanceRootDeviceSize(v)
 	if err != nil {
 		return nil, errors.Wrapf(err, "unable to get root volume size for instance: %q", aws.StringValue(v.InstanceId))
@@ -645,7 +1390,11 @@ func (s *Service) getNetworkInterfaceSecurityGroups(interfaceID string) ([]strin
 		NetworkInterfaceId: aws.String(interfaceID),
 	}
 
-	output, err := s.scope.EC2.DescribeNetworkInterfaceAttribute(input)
+	var output *ec2.DescribeNetworkInterfaceAttributeOutput
+	err := awserrors.RetryOnThrottling(func() (err error) {
+		output, err = s.scope.EC2.DescribeNetworkInterfaceAttribute(input)
+		return err
+	})
 	if err != nil {
 		return nil, err
 	}
@@ -17,9 +17,13 @@ limitations under the License.
 package ec2
 
 import (
+	"encoding/base64"
+	"reflect"
+	"strings"
 	"testing"
 
 	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
 	"github.com/aws/aws-sdk-go/service/ec2"
 	"github.com/golang/mock/gomock"
 	"github.com/pkg/errors"
@@ -285,6 +289,140 @@ func TestTerminateInstance(t *testing.T) {
 	}
 }
 
+func TestGetInstanceProfileAssociationState(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	testCases := []struct {
+		name          string
+		associations  []*ec2.IamInstanceProfileAssociation
+		expectedState infrav1.InstanceProfileAssociationState
+	}{
+		{
+			name:          "no association yet",
+			expectedState: infrav1.InstanceProfileAssociationStatePending,
+		},
+		{
+			name: "associating",
+			associations: []*ec2.IamInstanceProfileAssociation{
+				{State: aws.String(ec2.IamInstanceProfileAssociationStateAssociating)},
+			},
+			expectedState: infrav1.InstanceProfileAssociationStatePending,
+		},
+		{
+			name: "associated",
+			associations: []*ec2.IamInstanceProfileAssociation{
+				{State: aws.String(ec2.IamInstanceProfileAssociationStateAssociated)},
+			},
+			expectedState: infrav1.InstanceProfileAssociationStateAssociated,
+		},
+		{
+			name: "disassociated",
+			associations: []*ec2.IamInstanceProfileAssociation{
+				{State: aws.String(ec2.IamInstanceProfileAssociationStateDisassociated)},
+			},
+			expectedState: infrav1.InstanceProfileAssociationStateFailed,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ec2Mock := mock_ec2iface.NewMockEC2API(mockCtrl)
+
+			scope, err := scope.NewClusterScope(scope.ClusterScopeParams{
+				AWSClients: scope.AWSClients{
+					EC2: ec2Mock,
+				},
+				Cluster:    &clusterv1.Cluster{},
+				AWSCluster: &infrav1.AWSCluster{},
+			})
+			if err != nil {
+				t.Fatalf("Failed to create test context: %v", err)
+			}
+
+			ec2Mock.EXPECT().DescribeIamInstanceProfileAssociations(gomock.Eq(&ec2.DescribeIamInstanceProfileAssociationsInput{
+				Filters: []*ec2.Filter{
+					{
+						Name:   aws.String("instance-id"),
+						Values: aws.StringSlice([]string{"i-1234"}),
+					},
+				},
+			})).
+				Return(&ec2.DescribeIamInstanceProfileAssociationsOutput{
+					IamInstanceProfileAssociations: tc.associations,
+				}, nil)
+
+			s := NewService(scope)
+			state, err := s.GetInstanceProfileAssociationState("i-1234")
+			if err != nil {
+				t.Fatalf("did not expect error: %v", err)
+			}
+			if state != tc.expectedState {
+				t.Fatalf("expected state %q, got %q", tc.expectedState, state)
+			}
+		})
+	}
+}
+
+func TestModifyInstanceMetadataOptions(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	testCases := []struct {
+		name     string
+		options  *infrav1.InstanceMetadataOptions
+		expected *ec2.ModifyInstanceMetadataOptionsInput
+	}{
+		{
+			name:    "hop limit only",
+			options: &infrav1.InstanceMetadataOptions{HTTPPutResponseHopLimit: 2},
+			expected: &ec2.ModifyInstanceMetadataOptionsInput{
+				InstanceId:              aws.String("i-1234"),
+				HttpPutResponseHopLimit: aws.Int64(2),
+			},
+		},
+		{
+			name: "all options",
+			options: &infrav1.InstanceMetadataOptions{
+				HTTPPutResponseHopLimit: 1,
+				HTTPTokens:              infrav1.HTTPTokensStateRequired,
+				HTTPEndpoint:            infrav1.InstanceMetadataEndpointStateEnabled,
+			},
+			expected: &ec2.ModifyInstanceMetadataOptionsInput{
+				InstanceId:              aws.String("i-1234"),
+				HttpPutResponseHopLimit: aws.Int64(1),
+				HttpTokens:              aws.String(ec2.HttpTokensStateRequired),
+				HttpEndpoint:            aws.String(ec2.InstanceMetadataEndpointStateEnabled),
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ec2Mock := mock_ec2iface.NewMockEC2API(mockCtrl)
+
+			scope, err := scope.NewClusterScope(scope.ClusterScopeParams{
+				AWSClients: scope.AWSClients{
+					EC2: ec2Mock,
+				},
+				Cluster:    &clusterv1.Cluster{},
+				AWSCluster: &infrav1.AWSCluster{},
+			})
+			if err != nil {
+				t.Fatalf("Failed to create test context: %v", err)
+			}
+
+			ec2Mock.EXPECT().ModifyInstanceMetadataOptions(gomock.Eq(tc.expected)).
+				Return(&ec2.ModifyInstanceMetadataOptionsOutput{}, nil)
+
+			s := NewService(scope)
+			if err := s.ModifyInstanceMetadataOptions("i-1234", tc.options); err != nil {
+				t.Fatalf("did not expect error: %v", err)
+			}
+		})
+	}
+}
+
 func TestCreateInstance(t *testing.T) {
 	secret := &corev1.Secret{
 		ObjectMeta: metav1.ObjectMeta{
@@ -319,7 +457,950 @@ func TestCreateInstance(t *testing.T) {
 				AMI: infrav1.AWSResourceReference{
 					ID: aws.String("abc"),
 				},
-				InstanceType: "m5.large",
+				InstanceType: "m5.large",
+			},
+			awsCluster: &infrav1.AWSCluster{
+				Spec: infrav1.AWSClusterSpec{
+					NetworkSpec: infrav1.NetworkSpec{
+						Subnets: infrav1.Subnets{
+							&infrav1.SubnetSpec{
+								ID:       "subnet-1",
+								IsPublic: false,
+							},
+							&infrav1.SubnetSpec{
+								IsPublic: false,
+							},
+						},
+					},
+				},
+				Status: infrav1.AWSClusterStatus{
+					Network: infrav1.Network{
+						SecurityGroups: map[infrav1.SecurityGroupRole]infrav1.SecurityGroup{
+							infrav1.SecurityGroupControlPlane: {
+								ID: "1",
+							},
+							infrav1.SecurityGroupNode: {
+								ID: "2",
+							},
+							infrav1.SecurityGroupLB: {
+								ID: "3",
+							},
+						},
+						APIServerELB: infrav1.ClassicELB{
+							DNSName: "test-apiserver.us-east-1.aws",
+						},
+					},
+				},
+			},
+			expect: func(m *mock_ec2iface.MockEC2APIMockRecorder) {
+				m.
+					DescribeImages(gomock.Any()).
+					Return(&ec2.DescribeImagesOutput{
+						Images: []*ec2.Image{
+							{
+								Name: aws.String("ami-1"),
+							},
+						},
+					}, nil)
+				m. // TODO: Restore these parameters, but with the tags as well
+					RunInstances(gomock.Any()).
+					Return(&ec2.Reservation{
+						Instances: []*ec2.Instance{
+							{
+								State: &ec2.InstanceState{
+									Name: aws.String(ec2.InstanceStateNamePending),
+								},
+								IamInstanceProfile: &ec2.IamInstanceProfile{
+									Arn: aws.String("arn:aws:iam::123456789012:instance-profile/foo"),
+								},
+								InstanceId:     aws.String("two"),
+								InstanceType:   aws.String("m5.large"),
+								SubnetId:       aws.String("subnet-1"),
+								ImageId:        aws.String("ami-1"),
+								RootDeviceName: aws.String("device-1"),
+								BlockDeviceMappings: []*ec2.InstanceBlockDeviceMapping{
+									{
+										DeviceName: aws.String("device-1"),
+										Ebs: &ec2.EbsInstanceBlockDevice{
+											VolumeId: aws.String("volume-1"),
+										},
+									},
+								},
+							},
+						},
+					}, nil)
+				m.WaitUntilInstanceRunningWithContext(gomock.Any(), gomock.Any(), gomock.Any()).
+					Return(nil)
+
+				m.DescribeVolumes(gomock.Eq(&ec2.DescribeVolumesInput{
+					VolumeIds: []*string{aws.String("volume-1")},
+				})).Return(&ec2.DescribeVolumesOutput{
+					Volumes: []*ec2.Volume{
+						{
+							VolumeId: aws.String("volume-1"),
+							Size:     aws.Int64(60),
+						},
+					},
+				}, nil)
+			},
+			check: func(instance *infrav1.Instance, err error) {
+				if err != nil {
+					t.Fatalf("did not expect error: %v", err)
+				}
+			},
+		},
+		{
+			name: "raw user data is passed through",
+			machine: clusterv1.Machine{
+				ObjectMeta: metav1.ObjectMeta{
+					Labels: map[string]string{"set": "node"},
+				},
+			},
+			machineConfig: &infrav1.AWSMachineSpec{
+				AMI: infrav1.AWSResourceReference{
+					ID: aws.String("abc"),
+				},
+				InstanceType:   "m5.large",
+				UserDataSecret: &infrav1.UserDataSecretReference{Name: "bootstrap-data"},
+			},
+			awsCluster: &infrav1.AWSCluster{
+				Spec: infrav1.AWSClusterSpec{
+					NetworkSpec: infrav1.NetworkSpec{
+						Subnets: infrav1.Subnets{
+							&infrav1.SubnetSpec{
+								ID:       "subnet-1",
+								IsPublic: false,
+							},
+							&infrav1.SubnetSpec{
+								IsPublic: false,
+							},
+						},
+					},
+				},
+				Status: infrav1.AWSClusterStatus{
+					Network: infrav1.Network{
+						SecurityGroups: map[infrav1.SecurityGroupRole]infrav1.SecurityGroup{
+							infrav1.SecurityGroupControlPlane: {
+								ID: "1",
+							},
+							infrav1.SecurityGroupNode: {
+								ID: "2",
+							},
+							infrav1.SecurityGroupLB: {
+								ID: "3",
+							},
+						},
+						APIServerELB: infrav1.ClassicELB{
+							DNSName: "test-apiserver.us-east-1.aws",
+						},
+					},
+				},
+			},
+			expect: func(m *mock_ec2iface.MockEC2APIMockRecorder) {
+				m.
+					DescribeImages(gomock.Any()).
+					Return(&ec2.DescribeImagesOutput{
+						Images: []*ec2.Image{
+							{
+								Name: aws.String("ami-1"),
+							},
+						},
+					}, nil)
+				m.
+					RunInstances(gomock.Any()).
+					DoAndReturn(func(input *ec2.RunInstancesInput) (*ec2.Reservation, error) {
+						if got, expected := aws.StringValue(input.UserData), base64.StdEncoding.EncodeToString([]byte("data")); got != expected {
+							t.Fatalf("expected user data %q, got %q", expected, got)
+						}
+						return &ec2.Reservation{
+							Instances: []*ec2.Instance{
+								{
+									State: &ec2.InstanceState{
+										Name: aws.String(ec2.InstanceStateNamePending),
+									},
+									IamInstanceProfile: &ec2.IamInstanceProfile{
+										Arn: aws.String("arn:aws:iam::123456789012:instance-profile/foo"),
+									},
+									InstanceId:     aws.String("two"),
+									InstanceType:   aws.String("m5.large"),
+									SubnetId:       aws.String("subnet-1"),
+									ImageId:        aws.String("ami-1"),
+									RootDeviceName: aws.String("device-1"),
+									BlockDeviceMappings: []*ec2.InstanceBlockDeviceMapping{
+										{
+											DeviceName: aws.String("device-1"),
+											Ebs: &ec2.EbsInstanceBlockDevice{
+												VolumeId: aws.String("volume-1"),
+											},
+										},
+									},
+								},
+							},
+						}, nil
+					})
+				m.WaitUntilInstanceRunningWithContext(gomock.Any(), gomock.Any(), gomock.Any()).
+					Return(nil)
+
+				m.DescribeVolumes(gomock.Eq(&ec2.DescribeVolumesInput{
+					VolumeIds: []*string{aws.String("volume-1")},
+				})).Return(&ec2.DescribeVolumesOutput{
+					Volumes: []*ec2.Volume{
+						{
+							VolumeId: aws.String("volume-1"),
+							Size:     aws.Int64(60),
+						},
+					},
+				}, nil)
+			},
+			check: func(instance *infrav1.Instance, err error) {
+				if err != nil {
+					t.Fatalf("did not expect error: %v", err)
+				}
+			},
+		},
+		{
+			name: "falls back to an alternative instance type on insufficient capacity",
+			machine: clusterv1.Machine{
+				ObjectMeta: metav1.ObjectMeta{
+					Labels: map[string]string{"set": "node"},
+				},
+				Spec: clusterv1.MachineSpec{
+					Bootstrap: clusterv1.Bootstrap{
+						DataSecretName: pointer.StringPtr("bootstrap-data"),
+					},
+				},
+			},
+			machineConfig: &infrav1.AWSMachineSpec{
+				AMI: infrav1.AWSResourceReference{
+					ID: aws.String("abc"),
+				},
+				InstanceType:             "m5.large",
+				AlternativeInstanceTypes: []string{"m5a.large"},
+			},
+			awsCluster: &infrav1.AWSCluster{
+				Spec: infrav1.AWSClusterSpec{
+					NetworkSpec: infrav1.NetworkSpec{
+						Subnets: infrav1.Subnets{
+							&infrav1.SubnetSpec{
+								ID:       "subnet-1",
+								IsPublic: false,
+							},
+						},
+					},
+				},
+				Status: infrav1.AWSClusterStatus{
+					Network: infrav1.Network{
+						SecurityGroups: map[infrav1.SecurityGroupRole]infrav1.SecurityGroup{
+							infrav1.SecurityGroupControlPlane: {
+								ID: "1",
+							},
+							infrav1.SecurityGroupNode: {
+								ID: "2",
+							},
+							infrav1.SecurityGroupLB: {
+								ID: "3",
+							},
+						},
+						APIServerELB: infrav1.ClassicELB{
+							DNSName: "test-apiserver.us-east-1.aws",
+						},
+					},
+				},
+			},
+			expect: func(m *mock_ec2iface.MockEC2APIMockRecorder) {
+				m.
+					DescribeImages(gomock.Any()).
+					Return(&ec2.DescribeImagesOutput{
+						Images: []*ec2.Image{
+							{
+								Name: aws.String("ami-1"),
+							},
+						},
+					}, nil).AnyTimes()
+				m.
+					RunInstances(gomock.Any()).
+					Return(nil, awserr.New(awserrors.InsufficientInstanceCapacity, "insufficient capacity", nil))
+				m.
+					RunInstances(gomock.Any()).
+					Return(&ec2.Reservation{
+						Instances: []*ec2.Instance{
+							{
+								State: &ec2.InstanceState{
+									Name: aws.String(ec2.InstanceStateNamePending),
+								},
+								InstanceId:     aws.String("two"),
+								InstanceType:   aws.String("m5a.large"),
+								SubnetId:       aws.String("subnet-1"),
+								ImageId:        aws.String("ami-1"),
+								RootDeviceName: aws.String("device-1"),
+								BlockDeviceMappings: []*ec2.InstanceBlockDeviceMapping{
+									{
+										DeviceName: aws.String("device-1"),
+										Ebs: &ec2.EbsInstanceBlockDevice{
+											VolumeId: aws.String("volume-1"),
+										},
+									},
+								},
+							},
+						},
+					}, nil)
+				m.WaitUntilInstanceRunningWithContext(gomock.Any(), gomock.Any(), gomock.Any()).
+					Return(nil)
+				m.DescribeVolumes(gomock.Any()).
+					Return(&ec2.DescribeVolumesOutput{
+						Volumes: []*ec2.Volume{
+							{
+								VolumeId: aws.String("volume-1"),
+								Size:     aws.Int64(60),
+							},
+						},
+					}, nil)
+			},
+			check: func(instance *infrav1.Instance, err error) {
+				if err != nil {
+					t.Fatalf("did not expect error: %v", err)
+				}
+				if instance.Type != "m5a.large" {
+					t.Fatalf("expected the alternative instance type to be launched, got %q", instance.Type)
+				}
+			},
+		},
+		{
+			name: "in a placement group",
+			machine: clusterv1.Machine{
+				ObjectMeta: metav1.ObjectMeta{
+					Labels: map[string]string{"set": "node"},
+				},
+				Spec: clusterv1.MachineSpec{
+					Bootstrap: clusterv1.Bootstrap{
+						DataSecretName: pointer.StringPtr("bootstrap-data"),
+					},
+				},
+			},
+			machineConfig: &infrav1.AWSMachineSpec{
+				AMI: infrav1.AWSResourceReference{
+					ID: aws.String("abc"),
+				},
+				InstanceType:       "c5n.18xlarge",
+				PlacementGroupName: "hpc",
+			},
+			awsCluster: &infrav1.AWSCluster{
+				Spec: infrav1.AWSClusterSpec{
+					NetworkSpec: infrav1.NetworkSpec{
+						Subnets: infrav1.Subnets{
+							&infrav1.SubnetSpec{
+								ID:       "subnet-1",
+								IsPublic: false,
+							},
+						},
+					},
+				},
+				Status: infrav1.AWSClusterStatus{
+					Network: infrav1.Network{
+						SecurityGroups: map[infrav1.SecurityGroupRole]infrav1.SecurityGroup{
+							infrav1.SecurityGroupControlPlane: {
+								ID: "1",
+							},
+							infrav1.SecurityGroupNode: {
+								ID: "2",
+							},
+							infrav1.SecurityGroupLB: {
+								ID: "3",
+							},
+						},
+						APIServerELB: infrav1.ClassicELB{
+							DNSName: "test-apiserver.us-east-1.aws",
+						},
+					},
+				},
+			},
+			expect: func(m *mock_ec2iface.MockEC2APIMockRecorder) {
+				m.
+					DescribePlacementGroups(gomock.Eq(&ec2.DescribePlacementGroupsInput{
+						GroupNames: aws.StringSlice([]string{"hpc"}),
+					})).
+					Return(&ec2.DescribePlacementGroupsOutput{
+						PlacementGroups: []*ec2.PlacementGroup{
+							{
+								GroupName: aws.String("hpc"),
+								State:     aws.String(ec2.PlacementGroupStateAvailable),
+								Strategy:  aws.String(ec2.PlacementStrategyCluster),
+							},
+						},
+					}, nil)
+				m.
+					RunInstances(gomock.Any()).
+					Do(func(input *ec2.RunInstancesInput) {
+						if aws.StringValue(input.Placement.GroupName) != "hpc" || input.Placement.PartitionNumber != nil {
+							t.Fatalf("expected the instance to be launched in placement group %q, got %v", "hpc", input.Placement)
+						}
+					}).
+					Return(&ec2.Reservation{
+						Instances: []*ec2.Instance{
+							{
+								State: &ec2.InstanceState{
+									Name: aws.String(ec2.InstanceStateNamePending),
+								},
+								InstanceId:     aws.String("two"),
+								InstanceType:   aws.String("c5n.18xlarge"),
+								SubnetId:       aws.String("subnet-1"),
+								ImageId:        aws.String("ami-1"),
+								Placement:      &ec2.Placement{GroupName: aws.String("hpc")},
+								RootDeviceName: aws.String("device-1"),
+								BlockDeviceMappings: []*ec2.InstanceBlockDeviceMapping{
+									{
+										DeviceName: aws.String("device-1"),
+										Ebs: &ec2.EbsInstanceBlockDevice{
+											VolumeId: aws.String("volume-1"),
+										},
+									},
+								},
+							},
+						},
+					}, nil)
+				m.WaitUntilInstanceRunningWithContext(gomock.Any(), gomock.Any(), gomock.Any()).
+					Return(nil)
+				m.DescribeVolumes(gomock.Any()).
+					Return(&ec2.DescribeVolumesOutput{
+						Volumes: []*ec2.Volume{
+							{
+								VolumeId: aws.String("volume-1"),
+								Size:     aws.Int64(60),
+							},
+						},
+					}, nil)
+			},
+			check: func(instance *infrav1.Instance, err error) {
+				if err != nil {
+					t.Fatalf("did not expect error: %v", err)
+				}
+				if instance.PlacementGroupName != "hpc" {
+					t.Fatalf("expected the instance to be in placement group %q, got %q", "hpc", instance.PlacementGroupName)
+				}
+			},
+		},
+		{
+			name: "with instance metadata options",
+			machine: clusterv1.Machine{
+				ObjectMeta: metav1.ObjectMeta{
+					Labels: map[string]string{"set": "node"},
+				},
+				Spec: clusterv1.MachineSpec{
+					Bootstrap: clusterv1.Bootstrap{
+						DataSecretName: pointer.StringPtr("bootstrap-data"),
+					},
+				},
+			},
+			machineConfig: &infrav1.AWSMachineSpec{
+				AMI: infrav1.AWSResourceReference{
+					ID: aws.String("abc"),
+				},
+				InstanceType: "m5.large",
+				InstanceMetadataOptions: &infrav1.InstanceMetadataOptions{
+					HTTPTokens:   infrav1.HTTPTokensStateRequired,
+					HTTPEndpoint: infrav1.InstanceMetadataEndpointStateEnabled,
+				},
+			},
+			awsCluster: &infrav1.AWSCluster{
+				Spec: infrav1.AWSClusterSpec{
+					NetworkSpec: infrav1.NetworkSpec{
+						Subnets: infrav1.Subnets{
+							&infrav1.SubnetSpec{
+								ID:       "subnet-1",
+								IsPublic: false,
+							},
+						},
+					},
+				},
+				Status: infrav1.AWSClusterStatus{
+					Network: infrav1.Network{
+						SecurityGroups: map[infrav1.SecurityGroupRole]infrav1.SecurityGroup{
+							infrav1.SecurityGroupControlPlane: {
+								ID: "1",
+							},
+							infrav1.SecurityGroupNode: {
+								ID: "2",
+							},
+							infrav1.SecurityGroupLB: {
+								ID: "3",
+							},
+						},
+						APIServerELB: infrav1.ClassicELB{
+							DNSName: "test-apiserver.us-east-1.aws",
+						},
+					},
+				},
+			},
+			expect: func(m *mock_ec2iface.MockEC2APIMockRecorder) {
+				m.
+					RunInstances(gomock.Any()).
+					Do(func(input *ec2.RunInstancesInput) {
+						expected := &ec2.InstanceMetadataOptionsRequest{
+							HttpTokens:   aws.String(ec2.HttpTokensStateRequired),
+							HttpEndpoint: aws.String(ec2.InstanceMetadataEndpointStateEnabled),
+						}
+						if !reflect.DeepEqual(input.MetadataOptions, expected) {
+							t.Fatalf("expected the instance to be launched with metadata options %v, got %v", expected, input.MetadataOptions)
+						}
+					}).
+					Return(&ec2.Reservation{
+						Instances: []*ec2.Instance{
+							{
+								State: &ec2.InstanceState{
+									Name: aws.String(ec2.InstanceStateNamePending),
+								},
+								InstanceId:   aws.String("two"),
+								InstanceType: aws.String("m5.large"),
+								SubnetId:     aws.String("subnet-1"),
+								ImageId:      aws.String("ami-1"),
+								MetadataOptions: &ec2.InstanceMetadataOptionsResponse{
+									HttpTokens:              aws.String(ec2.HttpTokensStateRequired),
+									HttpEndpoint:            aws.String(ec2.InstanceMetadataEndpointStateEnabled),
+									HttpPutResponseHopLimit: aws.Int64(1),
+								},
+								RootDeviceName: aws.String("device-1"),
+								BlockDeviceMappings: []*ec2.InstanceBlockDeviceMapping{
+									{
+										DeviceName: aws.String("device-1"),
+										Ebs: &ec2.EbsInstanceBlockDevice{
+											VolumeId: aws.String("volume-1"),
+										},
+									},
+								},
+							},
+						},
+					}, nil)
+				m.WaitUntilInstanceRunningWithContext(gomock.Any(), gomock.Any(), gomock.Any()).
+					Return(nil)
+				m.DescribeVolumes(gomock.Any()).
+					Return(&ec2.DescribeVolumesOutput{
+						Volumes: []*ec2.Volume{
+							{
+								VolumeId: aws.String("volume-1"),
+								Size:     aws.Int64(60),
+							},
+						},
+					}, nil)
+			},
+			check: func(instance *infrav1.Instance, err error) {
+				if err != nil {
+					t.Fatalf("did not expect error: %v", err)
+				}
+				expected := &infrav1.InstanceMetadataOptions{
+					HTTPPutResponseHopLimit: 1,
+					HTTPTokens:              infrav1.HTTPTokensStateRequired,
+					HTTPEndpoint:            infrav1.InstanceMetadataEndpointStateEnabled,
+				}
+				if !reflect.DeepEqual(instance.InstanceMetadataOptions, expected) {
+					t.Fatalf("expected instance metadata options %v, got %v", expected, instance.InstanceMetadataOptions)
+				}
+			},
+		},
+		{
+			name: "on a dedicated host",
+			machine: clusterv1.Machine{
+				ObjectMeta: metav1.ObjectMeta{
+					Labels: map[string]string{"set": "node"},
+				},
+				Spec: clusterv1.MachineSpec{
+					Bootstrap: clusterv1.Bootstrap{
+						DataSecretName: pointer.StringPtr("bootstrap-data"),
+					},
+				},
+			},
+			machineConfig: &infrav1.AWSMachineSpec{
+				AMI: infrav1.AWSResourceReference{
+					ID: aws.String("abc"),
+				},
+				InstanceType: "m5.large",
+				Tenancy:      infrav1.TenancyHost,
+				HostID:       "h-0123456789abcdef0",
+			},
+			awsCluster: &infrav1.AWSCluster{
+				Spec: infrav1.AWSClusterSpec{
+					NetworkSpec: infrav1.NetworkSpec{
+						Subnets: infrav1.Subnets{
+							&infrav1.SubnetSpec{
+								ID:       "subnet-1",
+								IsPublic: false,
+							},
+						},
+					},
+				},
+				Status: infrav1.AWSClusterStatus{
+					Network: infrav1.Network{
+						SecurityGroups: map[infrav1.SecurityGroupRole]infrav1.SecurityGroup{
+							infrav1.SecurityGroupControlPlane: {
+								ID: "1",
+							},
+							infrav1.SecurityGroupNode: {
+								ID: "2",
+							},
+							infrav1.SecurityGroupLB: {
+								ID: "3",
+							},
+						},
+						APIServerELB: infrav1.ClassicELB{
+							DNSName: "test-apiserver.us-east-1.aws",
+						},
+					},
+				},
+			},
+			expect: func(m *mock_ec2iface.MockEC2APIMockRecorder) {
+				m.
+					RunInstances(gomock.Any()).
+					Do(func(input *ec2.RunInstancesInput) {
+						want := &ec2.Placement{Tenancy: aws.String("host"), HostId: aws.String("h-0123456789abcdef0")}
+						if !reflect.DeepEqual(input.Placement, want) {
+							t.Fatalf("expected the instance to be launched with placement %v, got %v", want, input.Placement)
+						}
+					}).
+					Return(&ec2.Reservation{
+						Instances: []*ec2.Instance{
+							{
+								State: &ec2.InstanceState{
+									Name: aws.String(ec2.InstanceStateNamePending),
+								},
+								InstanceId:     aws.String("two"),
+								InstanceType:   aws.String("m5.large"),
+								SubnetId:       aws.String("subnet-1"),
+								ImageId:        aws.String("ami-1"),
+								Placement:      &ec2.Placement{Tenancy: aws.String("host"), HostId: aws.String("h-0123456789abcdef0")},
+								RootDeviceName: aws.String("device-1"),
+								BlockDeviceMappings: []*ec2.InstanceBlockDeviceMapping{
+									{
+										DeviceName: aws.String("device-1"),
+										Ebs: &ec2.EbsInstanceBlockDevice{
+											VolumeId: aws.String("volume-1"),
+										},
+									},
+								},
+							},
+						},
+					}, nil)
+				m.WaitUntilInstanceRunningWithContext(gomock.Any(), gomock.Any(), gomock.Any()).
+					Return(nil)
+				m.DescribeVolumes(gomock.Any()).
+					Return(&ec2.DescribeVolumesOutput{
+						Volumes: []*ec2.Volume{
+							{
+								VolumeId: aws.String("volume-1"),
+								Size:     aws.Int64(60),
+							},
+						},
+					}, nil)
+			},
+			check: func(instance *infrav1.Instance, err error) {
+				if err != nil {
+					t.Fatalf("did not expect error: %v", err)
+				}
+				if instance.Tenancy != infrav1.TenancyHost || instance.HostID != "h-0123456789abcdef0" {
+					t.Fatalf("expected the instance to be on host %q, got tenancy %q and host %q", "h-0123456789abcdef0", instance.Tenancy, instance.HostID)
+				}
+			},
+		},
+		{
+			name: "in a capacity reservation",
+			machine: clusterv1.Machine{
+				ObjectMeta: metav1.ObjectMeta{
+					Labels: map[string]string{"set": "node"},
+				},
+				Spec: clusterv1.MachineSpec{
+					Bootstrap: clusterv1.Bootstrap{
+						DataSecretName: pointer.StringPtr("bootstrap-data"),
+					},
+				},
+			},
+			machineConfig: &infrav1.AWSMachineSpec{
+				AMI: infrav1.AWSResourceReference{
+					ID: aws.String("abc"),
+				},
+				InstanceType:          "m5.large",
+				CapacityReservationID: "cr-0123456789abcdef0",
+			},
+			awsCluster: &infrav1.AWSCluster{
+				Spec: infrav1.AWSClusterSpec{
+					NetworkSpec: infrav1.NetworkSpec{
+						Subnets: infrav1.Subnets{
+							&infrav1.SubnetSpec{
+								ID:               "subnet-1",
+								IsPublic:         false,
+								AvailabilityZone: "us-east-1a",
+							},
+						},
+					},
+				},
+				Status: infrav1.AWSClusterStatus{
+					Network: infrav1.Network{
+						SecurityGroups: map[infrav1.SecurityGroupRole]infrav1.SecurityGroup{
+							infrav1.SecurityGroupControlPlane: {
+								ID: "1",
+							},
+							infrav1.SecurityGroupNode: {
+								ID: "2",
+							},
+							infrav1.SecurityGroupLB: {
+								ID: "3",
+							},
+						},
+						APIServerELB: infrav1.ClassicELB{
+							DNSName: "test-apiserver.us-east-1.aws",
+						},
+					},
+				},
+			},
+			expect: func(m *mock_ec2iface.MockEC2APIMockRecorder) {
+				m.
+					DescribeCapacityReservations(gomock.Eq(&ec2.DescribeCapacityReservationsInput{
+						CapacityReservationIds: aws.StringSlice([]string{"cr-0123456789abcdef0"}),
+					})).
+					Return(&ec2.DescribeCapacityReservationsOutput{
+						CapacityReservations: []*ec2.CapacityReservation{
+							{
+								CapacityReservationId: aws.String("cr-0123456789abcdef0"),
+								InstanceType:          aws.String("m5.large"),
+								AvailabilityZone:      aws.String("us-east-1a"),
+								State:                 aws.String(ec2.CapacityReservationStateActive),
+							},
+						},
+					}, nil)
+				m.
+					RunInstances(gomock.Any()).
+					Do(func(input *ec2.RunInstancesInput) {
+						want := &ec2.CapacityReservationSpecification{
+							CapacityReservationTarget: &ec2.CapacityReservationTarget{
+								CapacityReservationId: aws.String("cr-0123456789abcdef0"),
+							},
+						}
+						if !reflect.DeepEqual(input.CapacityReservationSpecification, want) {
+							t.Fatalf("expected the instance to be launched with capacity reservation %v, got %v", want, input.CapacityReservationSpecification)
+						}
+					}).
+					Return(&ec2.Reservation{
+						Instances: []*ec2.Instance{
+							{
+								State: &ec2.InstanceState{
+									Name: aws.String(ec2.InstanceStateNamePending),
+								},
+								InstanceId:   aws.String("two"),
+								InstanceType: aws.String("m5.large"),
+								SubnetId:     aws.String("subnet-1"),
+								ImageId:      aws.String("ami-1"),
+								CapacityReservationSpecification: &ec2.CapacityReservationSpecificationResponse{
+									CapacityReservationTarget: &ec2.CapacityReservationTargetResponse{
+										CapacityReservationId: aws.String("cr-0123456789abcdef0"),
+									},
+								},
+								RootDeviceName: aws.String("device-1"),
+								BlockDeviceMappings: []*ec2.InstanceBlockDeviceMapping{
+									{
+										DeviceName: aws.String("device-1"),
+										Ebs: &ec2.EbsInstanceBlockDevice{
+											VolumeId: aws.String("volume-1"),
+										},
+									},
+								},
+							},
+						},
+					}, nil)
+				m.WaitUntilInstanceRunningWithContext(gomock.Any(), gomock.Any(), gomock.Any()).
+					Return(nil)
+				m.DescribeVolumes(gomock.Any()).
+					Return(&ec2.DescribeVolumesOutput{
+						Volumes: []*ec2.Volume{
+							{
+								VolumeId: aws.String("volume-1"),
+								Size:     aws.Int64(60),
+							},
+						},
+					}, nil)
+			},
+			check: func(instance *infrav1.Instance, err error) {
+				if err != nil {
+					t.Fatalf("did not expect error: %v", err)
+				}
+				if instance.CapacityReservationID != "cr-0123456789abcdef0" {
+					t.Fatalf("expected the instance to be in capacity reservation %q, got %q", "cr-0123456789abcdef0", instance.CapacityReservationID)
+				}
+			},
+		},
+		{
+			name: "in a capacity reservation of another availability zone",
+			machine: clusterv1.Machine{
+				ObjectMeta: metav1.ObjectMeta{
+					Labels: map[string]string{"set": "node"},
+				},
+				Spec: clusterv1.MachineSpec{
+					Bootstrap: clusterv1.Bootstrap{
+						DataSecretName: pointer.StringPtr("bootstrap-data"),
+					},
+				},
+			},
+			machineConfig: &infrav1.AWSMachineSpec{
+				AMI: infrav1.AWSResourceReference{
+					ID: aws.String("abc"),
+				},
+				InstanceType:          "m5.large",
+				CapacityReservationID: "cr-0123456789abcdef0",
+			},
+			awsCluster: &infrav1.AWSCluster{
+				Spec: infrav1.AWSClusterSpec{
+					NetworkSpec: infrav1.NetworkSpec{
+						Subnets: infrav1.Subnets{
+							&infrav1.SubnetSpec{
+								ID:               "subnet-1",
+								IsPublic:         false,
+								AvailabilityZone: "us-east-1a",
+							},
+						},
+					},
+				},
+				Status: infrav1.AWSClusterStatus{
+					Network: infrav1.Network{
+						SecurityGroups: map[infrav1.SecurityGroupRole]infrav1.SecurityGroup{
+							infrav1.SecurityGroupControlPlane: {
+								ID: "1",
+							},
+							infrav1.SecurityGroupNode: {
+								ID: "2",
+							},
+							infrav1.SecurityGroupLB: {
+								ID: "3",
+							},
+						},
+						APIServerELB: infrav1.ClassicELB{
+							DNSName: "test-apiserver.us-east-1.aws",
+						},
+					},
+				},
+			},
+			expect: func(m *mock_ec2iface.MockEC2APIMockRecorder) {
+				m.
+					DescribeCapacityReservations(gomock.Any()).
+					Return(&ec2.DescribeCapacityReservationsOutput{
+						CapacityReservations: []*ec2.CapacityReservation{
+							{
+								CapacityReservationId: aws.String("cr-0123456789abcdef0"),
+								InstanceType:          aws.String("m5.large"),
+								AvailabilityZone:      aws.String("us-east-1b"),
+								State:                 aws.String(ec2.CapacityReservationStateActive),
+							},
+						},
+					}, nil)
+			},
+			check: func(instance *infrav1.Instance, err error) {
+				if err == nil {
+					t.Fatalf("expected an error launching the instance in a capacity reservation of another availability zone")
+				}
+			},
+		},
+		{
+			name: "with a root volume key of another region",
+			machine: clusterv1.Machine{
+				ObjectMeta: metav1.ObjectMeta{
+					Labels: map[string]string{"set": "node"},
+				},
+				Spec: clusterv1.MachineSpec{
+					Bootstrap: clusterv1.Bootstrap{
+						DataSecretName: pointer.StringPtr("bootstrap-data"),
+					},
+				},
+			},
+			machineConfig: &infrav1.AWSMachineSpec{
+				AMI: infrav1.AWSResourceReference{
+					ID: aws.String("abc"),
+				},
+				InstanceType: "m5.large",
+				RootVolume: &infrav1.Volume{
+					Size:      100,
+					Encrypted: true,
+					KMSKeyID:  "arn:aws:kms:eu-west-1:111122223333:key/1234abcd-12ab-34cd-56ef-1234567890ab",
+				},
+			},
+			awsCluster: &infrav1.AWSCluster{
+				Spec: infrav1.AWSClusterSpec{
+					Region: "us-east-1",
+				},
+			},
+			expect: func(m *mock_ec2iface.MockEC2APIMockRecorder) {
+				m.RunInstances(gomock.Any()).Times(0)
+			},
+			check: func(instance *infrav1.Instance, err error) {
+				if err == nil || !strings.Contains(err.Error(), "eu-west-1") {
+					t.Fatalf("expected an error about the region of the KMS key, got %v", err)
+				}
+			},
+		},
+		{
+			name: "in a placement group that does not exist",
+			machine: clusterv1.Machine{
+				ObjectMeta: metav1.ObjectMeta{
+					Labels: map[string]string{"set": "node"},
+				},
+				Spec: clusterv1.MachineSpec{
+					Bootstrap: clusterv1.Bootstrap{
+						DataSecretName: pointer.StringPtr("bootstrap-data"),
+					},
+				},
+			},
+			machineConfig: &infrav1.AWSMachineSpec{
+				AMI: infrav1.AWSResourceReference{
+					ID: aws.String("abc"),
+				},
+				InstanceType:            "c5n.18xlarge",
+				PlacementGroupName:      "hpc",
+				PlacementGroupPartition: 2,
+			},
+			awsCluster: &infrav1.AWSCluster{
+				Spec: infrav1.AWSClusterSpec{
+					NetworkSpec: infrav1.NetworkSpec{
+						Subnets: infrav1.Subnets{
+							&infrav1.SubnetSpec{
+								ID:       "subnet-1",
+								IsPublic: false,
+							},
+						},
+					},
+				},
+				Status: infrav1.AWSClusterStatus{
+					Network: infrav1.Network{
+						APIServerELB: infrav1.ClassicELB{
+							DNSName: "test-apiserver.us-east-1.aws",
+						},
+					},
+				},
+			},
+			expect: func(m *mock_ec2iface.MockEC2APIMockRecorder) {
+				m.
+					DescribePlacementGroups(gomock.Any()).
+					Return(nil, awserr.New(awserrors.PlacementGroupNotFound, "The Placement Group 'hpc' is unknown.", nil))
+			},
+			check: func(instance *infrav1.Instance, err error) {
+				if !awserrors.IsFailedDependency(errors.Cause(err)) {
+					t.Fatalf("expected a failed dependency error, got %v", err)
+				}
+			},
+		},
+		{
+			name: "in a placement group created by the controllers",
+			machine: clusterv1.Machine{
+				ObjectMeta: metav1.ObjectMeta{
+					Labels: map[string]string{"set": "node"},
+				},
+				Spec: clusterv1.MachineSpec{
+					Bootstrap: clusterv1.Bootstrap{
+						DataSecretName: pointer.StringPtr("bootstrap-data"),
+					},
+				},
+			},
+			machineConfig: &infrav1.AWSMachineSpec{
+				AMI: infrav1.AWSResourceReference{
+					ID: aws.String("abc"),
+				},
+				InstanceType:            "c5n.18xlarge",
+				PlacementGroupName:      "hpc",
+				PlacementGroupPartition: 2,
+				PlacementGroupStrategy:  infrav1.PlacementGroupStrategyPartition,
 			},
 			awsCluster: &infrav1.AWSCluster{
 				Spec: infrav1.AWSClusterSpec{
@@ -329,25 +1410,11 @@ func TestCreateInstance(t *testing.T) {
 								ID:       "subnet-1",
 								IsPublic: false,
 							},
-							&infrav1.SubnetSpec{
-								IsPublic: false,
-							},
 						},
 					},
 				},
 				Status: infrav1.AWSClusterStatus{
 					Network: infrav1.Network{
-						SecurityGroups: map[infrav1.SecurityGroupRole]infrav1.SecurityGroup{
-							infrav1.SecurityGroupControlPlane: {
-								ID: "1",
-							},
-							infrav1.SecurityGroupNode: {
-								ID: "2",
-							},
-							infrav1.SecurityGroupLB: {
-								ID: "3",
-							},
-						},
 						APIServerELB: infrav1.ClassicELB{
 							DNSName: "test-apiserver.us-east-1.aws",
 						},
@@ -356,58 +1423,31 @@ func TestCreateInstance(t *testing.T) {
 			},
 			expect: func(m *mock_ec2iface.MockEC2APIMockRecorder) {
 				m.
-					DescribeImages(gomock.Any()).
-					Return(&ec2.DescribeImagesOutput{
-						Images: []*ec2.Image{
-							{
-								Name: aws.String("ami-1"),
-							},
-						},
-					}, nil)
-				m. // TODO: Restore these parameters, but with the tags as well
-					RunInstances(gomock.Any()).
-					Return(&ec2.Reservation{
-						Instances: []*ec2.Instance{
+					DescribePlacementGroups(gomock.Any()).
+					Return(nil, awserr.New(awserrors.PlacementGroupNotFound, "The Placement Group 'hpc' is unknown.", nil))
+				m.
+					CreatePlacementGroup(gomock.Eq(&ec2.CreatePlacementGroupInput{
+						GroupName:      aws.String("hpc"),
+						Strategy:       aws.String(ec2.PlacementStrategyPartition),
+						PartitionCount: aws.Int64(7),
+					})).
+					Return(&ec2.CreatePlacementGroupOutput{}, nil)
+				m.
+					DescribePlacementGroups(gomock.Any()).
+					Return(&ec2.DescribePlacementGroupsOutput{
+						PlacementGroups: []*ec2.PlacementGroup{
 							{
-								State: &ec2.InstanceState{
-									Name: aws.String(ec2.InstanceStateNamePending),
-								},
-								IamInstanceProfile: &ec2.IamInstanceProfile{
-									Arn: aws.String("arn:aws:iam::123456789012:instance-profile/foo"),
-								},
-								InstanceId:     aws.String("two"),
-								InstanceType:   aws.String("m5.large"),
-								SubnetId:       aws.String("subnet-1"),
-								ImageId:        aws.String("ami-1"),
-								RootDeviceName: aws.String("device-1"),
-								BlockDeviceMappings: []*ec2.InstanceBlockDeviceMapping{
-									{
-										DeviceName: aws.String("device-1"),
-										Ebs: &ec2.EbsInstanceBlockDevice{
-											VolumeId: aws.String("volume-1"),
-										},
-									},
-								},
+								GroupName:      aws.String("hpc"),
+								State:          aws.String(ec2.PlacementGroupStatePending),
+								Strategy:       aws.String(ec2.PlacementStrategyPartition),
+								PartitionCount: aws.Int64(7),
 							},
 						},
 					}, nil)
-				m.WaitUntilInstanceRunningWithContext(gomock.Any(), gomock.Any(), gomock.Any()).
-					Return(nil)
-
-				m.DescribeVolumes(gomock.Eq(&ec2.DescribeVolumesInput{
-					VolumeIds: []*string{aws.String("volume-1")},
-				})).Return(&ec2.DescribeVolumesOutput{
-					Volumes: []*ec2.Volume{
-						{
-							VolumeId: aws.String("volume-1"),
-							Size:     aws.Int64(60),
-						},
-					},
-				}, nil)
 			},
 			check: func(instance *infrav1.Instance, err error) {
-				if err != nil {
-					t.Fatalf("did not expect error: %v", err)
+				if !awserrors.IsFailedDependency(errors.Cause(err)) {
+					t.Fatalf("expected a failed dependency error until the placement group is available, got %v", err)
 				}
 			},
 		},
@@ -939,6 +1979,34 @@ func TestCreateInstance(t *testing.T) {
 				}
 			},
 		},
+		{
+			name: "worker machine with the control plane instance profile",
+			machine: clusterv1.Machine{
+				ObjectMeta: metav1.ObjectMeta{
+					Labels: map[string]string{"set": "node"},
+				},
+				Spec: clusterv1.MachineSpec{
+					Bootstrap: clusterv1.Bootstrap{
+						DataSecretName: pointer.StringPtr("bootstrap-data"),
+					},
+				},
+			},
+			machineConfig: &infrav1.AWSMachineSpec{
+				AMI: infrav1.AWSResourceReference{
+					ID: aws.String("abc"),
+				},
+				InstanceType:       "m5.large",
+				IAMInstanceProfile: "control-plane.cluster-api-provider-aws.sigs.k8s.io",
+			},
+			awsCluster: &infrav1.AWSCluster{},
+			expect: func(m *mock_ec2iface.MockEC2APIMockRecorder) {
+			},
+			check: func(instance *infrav1.Instance, err error) {
+				if err == nil {
+					t.Fatalf("expected an error when launching a worker with the control plane instance profile")
+				}
+			},
+		},
 	}
 
 	for _, tc := range testcases {
@@ -1019,3 +2087,368 @@ func TestCreateInstance(t *testing.T) {
 		})
 	}
 }
+
+func TestKubeletAllowedLabel(t *testing.T) {
+	testCases := []struct {
+		key      string
+		expected bool
+	}{
+		{key: "role", expected: true},
+		{key: "example.com/role", expected: true},
+		{key: "cluster.x-k8s.io/cluster-name", expected: true},
+		{key: "node.kubernetes.io/instance-group", expected: true},
+		{key: "pool.kubelet.kubernetes.io/name", expected: true},
+		{key: "node-role.kubernetes.io/worker", expected: false},
+		{key: "kubernetes.io/role", expected: false},
+		{key: "example.k8s.io/role", expected: false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.key, func(t *testing.T) {
+			if got := kubeletAllowedLabel(tc.key); got != tc.expected {
+				t.Fatalf("kubeletAllowedLabel(%q) = %v, expected %v", tc.key, got, tc.expected)
+			}
+		})
+	}
+}
+
+func TestGetInstanceMarketOptionsRequest(t *testing.T) {
+	testCases := []struct {
+		name     string
+		options  *infrav1.SpotMarketOptions
+		expected *ec2.InstanceMarketOptionsRequest
+	}{
+		{
+			name:    "no max price defaults to the on-demand price",
+			options: &infrav1.SpotMarketOptions{},
+			expected: &ec2.InstanceMarketOptionsRequest{
+				MarketType: aws.String(ec2.MarketTypeSpot),
+				SpotOptions: &ec2.SpotMarketOptions{
+					SpotInstanceType:             aws.String(ec2.SpotInstanceTypeOneTime),
+					InstanceInterruptionBehavior: aws.String(ec2.InstanceInterruptionBehaviorTerminate),
+				},
+			},
+		},
+		{
+			name:    "max price is passed through",
+			options: &infrav1.SpotMarketOptions{MaxPrice: aws.String("0.05")},
+			expected: &ec2.InstanceMarketOptionsRequest{
+				MarketType: aws.String(ec2.MarketTypeSpot),
+				SpotOptions: &ec2.SpotMarketOptions{
+					SpotInstanceType:             aws.String(ec2.SpotInstanceTypeOneTime),
+					InstanceInterruptionBehavior: aws.String(ec2.InstanceInterruptionBehaviorTerminate),
+					MaxPrice:                     aws.String("0.05"),
+				},
+			},
+		},
+		{
+			name:    "explicit terminate keeps a one-time request",
+			options: &infrav1.SpotMarketOptions{InterruptionBehavior: infrav1.SpotInterruptionBehaviorTerminate},
+			expected: &ec2.InstanceMarketOptionsRequest{
+				MarketType: aws.String(ec2.MarketTypeSpot),
+				SpotOptions: &ec2.SpotMarketOptions{
+					SpotInstanceType:             aws.String(ec2.SpotInstanceTypeOneTime),
+					InstanceInterruptionBehavior: aws.String(ec2.InstanceInterruptionBehaviorTerminate),
+				},
+			},
+		},
+		{
+			name:    "hibernation needs a persistent request",
+			options: &infrav1.SpotMarketOptions{InterruptionBehavior: infrav1.SpotInterruptionBehaviorHibernate},
+			expected: &ec2.InstanceMarketOptionsRequest{
+				MarketType: aws.String(ec2.MarketTypeSpot),
+				SpotOptions: &ec2.SpotMarketOptions{
+					SpotInstanceType:             aws.String(ec2.SpotInstanceTypePersistent),
+					InstanceInterruptionBehavior: aws.String(ec2.InstanceInterruptionBehaviorHibernate),
+				},
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := getInstanceMarketOptionsRequest(tc.options); !reflect.DeepEqual(got, tc.expected) {
+				t.Fatalf("got %v, expected %v", got, tc.expected)
+			}
+		})
+	}
+}
+
+func TestValidateVolumeKMSKeyRegions(t *testing.T) {
+	testCases := []struct {
+		name           string
+		rootVolume     *infrav1.Volume
+		nonRootVolumes []infrav1.Volume
+		expectError    bool
+	}{
+		{
+			name: "no encryption keys",
+			rootVolume: &infrav1.Volume{
+				Size:      100,
+				Encrypted: true,
+			},
+		},
+		{
+			name: "key IDs and aliases are resolved in the region of the volume",
+			rootVolume: &infrav1.Volume{
+				Size:      100,
+				Encrypted: true,
+				KMSKeyID:  "1234abcd-12ab-34cd-56ef-1234567890ab",
+			},
+			nonRootVolumes: []infrav1.Volume{
+				{DeviceName: "/dev/sdf", Size: 100, Encrypted: true, KMSKeyID: "alias/ebs"},
+			},
+		},
+		{
+			name: "key ARN in the region of the cluster",
+			rootVolume: &infrav1.Volume{
+				Size:      100,
+				Encrypted: true,
+				KMSKeyID:  "arn:aws:kms:us-east-1:111122223333:key/1234abcd-12ab-34cd-56ef-1234567890ab",
+			},
+		},
+		{
+			name: "root volume key ARN in another region",
+			rootVolume: &infrav1.Volume{
+				Size:      100,
+				Encrypted: true,
+				KMSKeyID:  "arn:aws:kms:eu-west-1:111122223333:key/1234abcd-12ab-34cd-56ef-1234567890ab",
+			},
+			expectError: true,
+		},
+		{
+			name: "data volume key ARN in another region",
+			nonRootVolumes: []infrav1.Volume{
+				{
+					DeviceName: "/dev/sdf",
+					Size:       100,
+					Encrypted:  true,
+					KMSKeyID:   "arn:aws:kms:eu-west-1:111122223333:alias/ebs",
+				},
+			},
+			expectError: true,
+		},
+		{
+			name: "malformed key ARN",
+			rootVolume: &infrav1.Volume{
+				Size:      100,
+				Encrypted: true,
+				KMSKeyID:  "arn:aws:kms",
+			},
+			expectError: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := validateVolumeKMSKeyRegions("us-east-1", tc.rootVolume, tc.nonRootVolumes)
+			if tc.expectError && err == nil {
+				t.Fatal("expected an error")
+			}
+			if !tc.expectError && err != nil {
+				t.Fatalf("did not expect error: %v", err)
+			}
+		})
+	}
+}
+
+func TestGetBlockDeviceMapping(t *testing.T) {
+	testCases := []struct {
+		name       string
+		deviceName string
+		volume     infrav1.Volume
+		expected   *ec2.BlockDeviceMapping
+	}{
+		{
+			name:   "defaults are left to EC2 and the volume is deleted on termination",
+			volume: infrav1.Volume{DeviceName: "/dev/sdf", Size: 100},
+			expected: &ec2.BlockDeviceMapping{
+				DeviceName: aws.String("/dev/sdf"),
+				Ebs: &ec2.EbsBlockDevice{
+					DeleteOnTermination: aws.Bool(true),
+					VolumeSize:          aws.Int64(100),
+				},
+			},
+		},
+		{
+			name: "all options are passed through",
+			volume: infrav1.Volume{
+				DeviceName:          "/dev/sdg",
+				Size:                500,
+				Type:                infrav1.VolumeTypeIO1,
+				IOPS:                5000,
+				Encrypted:           true,
+				DeleteOnTermination: aws.Bool(false),
+			},
+			expected: &ec2.BlockDeviceMapping{
+				DeviceName: aws.String("/dev/sdg"),
+				Ebs: &ec2.EbsBlockDevice{
+					DeleteOnTermination: aws.Bool(false),
+					VolumeSize:          aws.Int64(500),
+					VolumeType:          aws.String("io1"),
+					Iops:                aws.Int64(5000),
+					Encrypted:           aws.Bool(true),
+				},
+			},
+		},
+		{
+			name:       "root volume is attached as the root device of the image",
+			deviceName: "/dev/xvda",
+			volume: infrav1.Volume{
+				Size:      50,
+				Type:      infrav1.VolumeTypeIO2,
+				IOPS:      3000,
+				Encrypted: true,
+				KMSKeyID:  "arn:aws:kms:us-east-1:123456789012:alias/etcd",
+			},
+			expected: &ec2.BlockDeviceMapping{
+				DeviceName: aws.String("/dev/xvda"),
+				Ebs: &ec2.EbsBlockDevice{
+					DeleteOnTermination: aws.Bool(true),
+					VolumeSize:          aws.Int64(50),
+					VolumeType:          aws.String("io2"),
+					Iops:                aws.Int64(3000),
+					Encrypted:           aws.Bool(true),
+					KmsKeyId:            aws.String("arn:aws:kms:us-east-1:123456789012:alias/etcd"),
+				},
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			deviceName := tc.deviceName
+			if deviceName == "" {
+				deviceName = tc.volume.DeviceName
+			}
+			if got := getBlockDeviceMapping(deviceName, &tc.volume); !reflect.DeepEqual(got, tc.expected) {
+				t.Fatalf("got %v, expected %v", got, tc.expected)
+			}
+		})
+	}
+}
+
+func TestGetAdditionalSecurityGroupsIDs(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	testCases := []struct {
+		name        string
+		refs        []infrav1.AWSResourceReference
+		expect      func(m *mock_ec2iface.MockEC2APIMockRecorder)
+		expected    []string
+		expectError bool
+	}{
+		{
+			name: "security groups by id and by filters are resolved and deduplicated",
+			refs: []infrav1.AWSResourceReference{
+				{ID: aws.String("sg-1")},
+				{Filters: []infrav1.Filter{{Name: "tag:role", Values: []string{"monitoring"}}}},
+				{ID: aws.String("sg-1")},
+			},
+			expect: func(m *mock_ec2iface.MockEC2APIMockRecorder) {
+				m.DescribeSecurityGroups(gomock.Eq(&ec2.DescribeSecurityGroupsInput{
+					GroupIds: []*string{aws.String("sg-1")},
+					Filters: []*ec2.Filter{
+						{Name: aws.String("vpc-id"), Values: []*string{aws.String("vpc-1")}},
+					},
+				})).
+					Return(&ec2.DescribeSecurityGroupsOutput{
+						SecurityGroups: []*ec2.SecurityGroup{{GroupId: aws.String("sg-1")}},
+					}, nil).Times(2)
+				m.DescribeSecurityGroups(gomock.Eq(&ec2.DescribeSecurityGroupsInput{
+					Filters: []*ec2.Filter{
+						{Name: aws.String("vpc-id"), Values: []*string{aws.String("vpc-1")}},
+						{Name: aws.String("tag:role"), Values: []*string{aws.String("monitoring")}},
+					},
+				})).
+					Return(&ec2.DescribeSecurityGroupsOutput{
+						SecurityGroups: []*ec2.SecurityGroup{{GroupId: aws.String("sg-2")}, {GroupId: aws.String("sg-1")}},
+					}, nil)
+			},
+			expected: []string{"sg-1", "sg-2"},
+		},
+		{
+			name: "security group outside of the cluster vpc",
+			refs: []infrav1.AWSResourceReference{
+				{ID: aws.String("sg-other")},
+			},
+			expect: func(m *mock_ec2iface.MockEC2APIMockRecorder) {
+				m.DescribeSecurityGroups(gomock.AssignableToTypeOf(&ec2.DescribeSecurityGroupsInput{})).
+					Return(&ec2.DescribeSecurityGroupsOutput{}, nil)
+			},
+			expectError: true,
+		},
+		{
+			name: "security group that does not exist",
+			refs: []infrav1.AWSResourceReference{
+				{ID: aws.String("sg-missing")},
+			},
+			expect: func(m *mock_ec2iface.MockEC2APIMockRecorder) {
+				m.DescribeSecurityGroups(gomock.AssignableToTypeOf(&ec2.DescribeSecurityGroupsInput{})).
+					Return(nil, awserr.New(awserrors.GroupNotFound, "not found", nil))
+			},
+			expectError: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ec2Mock := mock_ec2iface.NewMockEC2API(mockCtrl)
+
+			cluster := &clusterv1.Cluster{
+				ObjectMeta: metav1.ObjectMeta{Name: "test-cluster"},
+			}
+			awsCluster := &infrav1.AWSCluster{
+				Spec: infrav1.AWSClusterSpec{
+					NetworkSpec: infrav1.NetworkSpec{
+						VPC: infrav1.VPCSpec{ID: "vpc-1"},
+					},
+				},
+			}
+
+			clusterScope, err := scope.NewClusterScope(scope.ClusterScopeParams{
+				AWSClients: scope.AWSClients{
+					EC2: ec2Mock,
+				},
+				Cluster:    cluster,
+				AWSCluster: awsCluster,
+			})
+			if err != nil {
+				t.Fatalf("Failed to create test context: %v", err)
+			}
+
+			machineScope, err := scope.NewMachineScope(scope.MachineScopeParams{
+				Client:     fake.NewFakeClient(),
+				Cluster:    cluster,
+				Machine:    &clusterv1.Machine{},
+				AWSCluster: awsCluster,
+				AWSMachine: &infrav1.AWSMachine{
+					Spec: infrav1.AWSMachineSpec{
+						AdditionalSecurityGroups: tc.refs,
+					},
+				},
+			})
+			if err != nil {
+				t.Fatalf("Failed to create test context: %v", err)
+			}
+
+			tc.expect(ec2Mock.EXPECT())
+
+			s := NewService(clusterScope)
+			ids, err := s.GetAdditionalSecurityGroupsIDs(machineScope)
+			if tc.expectError {
+				if err == nil {
+					t.Fatalf("expected an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("got an unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(ids, tc.expected) {
+				t.Fatalf("expected %v, got %v", tc.expected, ids)
+			}
+		})
+	}
+}
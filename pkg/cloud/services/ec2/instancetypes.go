@@ -0,0 +1,99 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ec2
+
+import (
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/pkg/errors"
+	infrav1 "sigs.k8s.io/cluster-api-provider-aws/api/v1alpha3"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/scope"
+)
+
+const spotPriceProductDescription = "Linux/UNIX"
+
+// instanceTypeCandidates returns the instance types to try, in order, when
+// launching the instance of a machine in the given subnet.
+func (s *Service) instanceTypeCandidates(scope *scope.MachineScope, subnetID string) ([]string, error) {
+	spec := scope.AWSMachine.Spec
+	instanceTypes := append([]string{spec.InstanceType}, spec.AlternativeInstanceTypes...)
+
+	if len(instanceTypes) < 2 || spec.SpotMarketOptions == nil || spec.SpotMarketOptions.AllocationStrategy != infrav1.SpotAllocationStrategyLowestPrice {
+		return instanceTypes, nil
+	}
+
+	var availabilityZone string
+	for _, sn := range s.scope.Subnets() {
+		if sn.ID == subnetID {
+			availabilityZone = sn.AvailabilityZone
+		}
+	}
+
+	prices, err := s.spotPrices(instanceTypes, availabilityZone)
+	if err != nil {
+		return nil, err
+	}
+
+	// Instance types without a spot price in the zone are tried last.
+	sort.SliceStable(instanceTypes, func(i, j int) bool {
+		pi, iok := prices[instanceTypes[i]]
+		pj, jok := prices[instanceTypes[j]]
+		if iok != jok {
+			return iok
+		}
+		return pi < pj
+	})
+
+	s.scope.V(2).Info("Ordered instance types by spot price", "instance-types", instanceTypes, "availability-zone", availabilityZone)
+	return instanceTypes, nil
+}
+
+// spotPrices returns the current spot price of the given instance types, the
+// lowest across zones when no availability zone is given.
+func (s *Service) spotPrices(instanceTypes []string, availabilityZone string) (map[string]float64, error) {
+	input := &ec2.DescribeSpotPriceHistoryInput{
+		InstanceTypes:       aws.StringSlice(instanceTypes),
+		ProductDescriptions: aws.StringSlice([]string{spotPriceProductDescription}),
+		StartTime:           aws.Time(time.Now()),
+	}
+	if availabilityZone != "" {
+		input.AvailabilityZone = aws.String(availabilityZone)
+	}
+
+	prices := make(map[string]float64, len(instanceTypes))
+	if err := s.scope.EC2.DescribeSpotPriceHistoryPages(input, func(out *ec2.DescribeSpotPriceHistoryOutput, lastPage bool) bool {
+		for _, sp := range out.SpotPriceHistory {
+			price, err := strconv.ParseFloat(aws.StringValue(sp.SpotPrice), 64)
+			if err != nil {
+				continue
+			}
+			instanceType := aws.StringValue(sp.InstanceType)
+			if current, ok := prices[instanceType]; !ok || price < current {
+				prices[instanceType] = price
+			}
+		}
+		return true
+	}); err != nil {
+		return nil, errors.Wrap(err, "failed to describe spot price history")
+	}
+
+	return prices, nil
+}
@@ -39,6 +39,11 @@ func (s *Service) reconcileNatGateways() error {
 
 	s.scope.V(2).Info("Reconciling NAT gateways")
 
+	if s.scope.NatGatewayMode() == infrav1.NatGatewayModeNone {
+		s.scope.V(2).Info("NAT gateways are disabled, skipping NAT gateways")
+		return nil
+	}
+
 	if len(s.scope.Subnets().FilterPrivate()) == 0 {
 		s.scope.V(2).Info("No private subnets available, skipping NAT gateways")
 		return nil
@@ -52,17 +57,14 @@ func (s *Service) reconcileNatGateways() error {
 		return err
 	}
 
-	for _, sn := range s.scope.Subnets().FilterPublic() {
-		if sn.ID == "" {
-			continue
-		}
-
+	for _, sn := range s.natGatewaySubnets() {
 		if ngw, ok := existing[sn.ID]; ok {
 			// Make sure tags are up to date.
 			if err := wait.WaitForWithRetryable(wait.NewBackoff(), func() (bool, error) {
 				if err := tags.Ensure(converters.TagsToMap(ngw.Tags), &tags.ApplyParams{
 					EC2Client:   s.scope.EC2,
 					BuildParams: s.getNatGatewayTagParams(*ngw.NatGatewayId),
+					Removed:     s.scope.RemovedAdditionalTags(),
 				}); err != nil {
 					return false, err
 				}
@@ -75,7 +77,7 @@ func (s *Service) reconcileNatGateways() error {
 			continue
 		}
 
-		ng, err := s.createNatGateway(sn.ID)
+		ng, err := s.createNatGateway(sn)
 		if err != nil {
 			return err
 		}
@@ -83,9 +85,84 @@ func (s *Service) reconcileNatGateways() error {
 		sn.NatGatewayID = ng.NatGatewayId
 	}
 
+	// Elastic IPs are only tagged when allocated, refresh their tags when the
+	// additional tags changed.
+	if s.scope.AdditionalTagsChanged() {
+		if err := s.ensureAddressesTags(infrav1.APIServerRoleTagValue); err != nil {
+			return err
+		}
+	}
+
 	return nil
 }
 
+// deleteUnusedNatGateways deletes the NAT gateways of the public subnets that
+// no longer need one, e.g. after switching to a single NAT gateway or to no
+// NAT gateway at all. It runs once the route tables point at the remaining
+// NAT gateways, so that egress of the private subnets isn't interrupted.
+func (s *Service) deleteUnusedNatGateways() error {
+	if s.scope.VPC().IsUnmanaged(s.scope.Name()) {
+		return nil
+	}
+
+	if len(s.scope.Subnets().FilterPrivate()) == 0 || len(s.scope.Subnets().FilterPublic()) == 0 {
+		return nil
+	}
+
+	used := make(map[string]bool)
+	for _, sn := range s.natGatewaySubnets() {
+		used[sn.ID] = true
+	}
+
+	existing, err := s.describeNatGatewaysBySubnet()
+	if err != nil {
+		return err
+	}
+
+	for _, sn := range s.scope.Subnets().FilterPublic() {
+		if sn.ID == "" || used[sn.ID] {
+			continue
+		}
+
+		if ngw, ok := existing[sn.ID]; ok {
+			s.scope.Info("Deleting NAT gateway that is no longer used", "nat-gateway-id", *ngw.NatGatewayId, "subnet-id", sn.ID)
+			if err := s.deleteNatGateway(*ngw.NatGatewayId); err != nil {
+				return err
+			}
+		}
+		sn.NatGatewayID = nil
+	}
+
+	return nil
+}
+
+// natGatewaySubnets returns the public subnets that get a NAT gateway, none
+// when NAT gateways are disabled. With a single NAT gateway, a public subnet that already has one is preferred, so
+// that switching from one NAT gateway per availability zone keeps one of them.
+func (s *Service) natGatewaySubnets() infrav1.Subnets {
+	var subnets infrav1.Subnets
+	if s.scope.NatGatewayMode() == infrav1.NatGatewayModeNone {
+		return subnets
+	}
+
+	for _, sn := range s.scope.Subnets().FilterPublic() {
+		if sn.ID != "" {
+			subnets = append(subnets, sn)
+		}
+	}
+
+	if s.scope.NatGatewayMode() != infrav1.NatGatewayModeSingle || len(subnets) == 0 {
+		return subnets
+	}
+
+	for _, sn := range subnets {
+		if sn.NatGatewayID != nil {
+			return infrav1.Subnets{sn}
+		}
+	}
+	return subnets[:1]
+}
+
 func (s *Service) deleteNatGateways() error {
 	if s.scope.VPC().IsUnmanaged(s.scope.Name()) {
 		s.scope.V(4).Info("Skipping NAT gateway deletion in unmanaged mode")
@@ -159,18 +236,39 @@ func (s *Service) getNatGatewayTagParams(id string) infrav1.BuildParams {
 	}
 }
 
-func (s *Service) createNatGateway(subnetID string) (*ec2.NatGateway, error) {
-	ip, err := s.getOrAllocateAddress(infrav1.APIServerRoleTagValue)
-	if err != nil {
-		return nil, errors.Wrapf(err, "failed to create IP address for NAT gateway for subnet ID %q", subnetID)
+func (s *Service) createNatGateway(sn *infrav1.SubnetSpec) (*ec2.NatGateway, error) {
+	subnetID := sn.ID
+
+	ip := sn.NatGatewayAllocationID
+	if ip == "" {
+		var err error
+		ip, err = s.getOrAllocateAddress(infrav1.APIServerRoleTagValue)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to create IP address for NAT gateway for subnet ID %q", subnetID)
+		}
+	}
+
+	input := &ec2.CreateNatGatewayInput{
+		SubnetId:     aws.String(subnetID),
+		AllocationId: aws.String(ip),
 	}
 
 	var out *ec2.CreateNatGatewayOutput
 	if err := wait.WaitForWithRetryable(wait.NewBackoff(), func() (bool, error) {
-		if out, err = s.scope.EC2.CreateNatGateway(&ec2.CreateNatGatewayInput{
-			SubnetId:     aws.String(subnetID),
-			AllocationId: aws.String(ip),
-		}); err != nil {
+		var err error
+		out, err = s.scope.EC2.CreateNatGateway(input)
+
+		// A provided Elastic IP may still be associated with the NAT gateway
+		// that is being replaced.
+		code, _ := awserrors.Code(errors.Cause(err))
+		if sn.NatGatewayAllocationID != "" && (code == awserrors.ResourceAlreadyAssociated || code == awserrors.InUseIPAddress) {
+			if err := s.disassociateNatGatewayAddress(ip); err != nil {
+				return false, err
+			}
+			out, err = s.scope.EC2.CreateNatGateway(input)
+		}
+
+		if err != nil {
 			return false, err
 		}
 		return true, nil
@@ -205,6 +303,78 @@ func (s *Service) createNatGateway(subnetID string) (*ec2.NatGateway, error) {
 	return out.NatGateway, nil
 }
 
+// disassociateNatGatewayAddress frees a provided Elastic IP so that it can be
+// associated with a new NAT gateway. NAT gateways can't give up their Elastic
+// IP, so the failed or deleting NAT gateway holding it is deleted.
+func (s *Service) disassociateNatGatewayAddress(allocationID string) error {
+	out, err := s.scope.EC2.DescribeAddresses(&ec2.DescribeAddressesInput{
+		AllocationIds: aws.StringSlice([]string{allocationID}),
+	})
+	if err != nil {
+		return errors.Wrapf(err, "failed to describe elastic IP %q", allocationID)
+	}
+	if len(out.Addresses) == 0 {
+		return errors.Errorf("no elastic IP found with allocation ID %q", allocationID)
+	}
+
+	address := out.Addresses[0]
+	if address.AssociationId == nil {
+		return nil
+	}
+
+	ngw, err := s.describeNatGatewayByAllocationID(allocationID)
+	if err != nil {
+		return err
+	}
+
+	if ngw == nil {
+		if err := s.disassociateAddress(address); err != nil {
+			return err
+		}
+	} else {
+		id := aws.StringValue(ngw.NatGatewayId)
+		switch state := aws.StringValue(ngw.State); state {
+		case ec2.NatGatewayStateFailed, ec2.NatGatewayStateDeleting:
+			if err := s.deleteNatGateway(id); err != nil {
+				return err
+			}
+		default:
+			return errors.Errorf("elastic IP %q is in use by NAT gateway %q in state %q", allocationID, id, state)
+		}
+	}
+
+	record.Eventf(s.scope.AWSCluster, "SuccessfulDisassociateEIP", "Disassociated Elastic IP %q to associate it with a new NAT Gateway", allocationID)
+	return nil
+}
+
+// describeNatGatewayByAllocationID returns the NAT gateway of the VPC the
+// Elastic IP is associated with, if any.
+func (s *Service) describeNatGatewayByAllocationID(allocationID string) (*ec2.NatGateway, error) {
+	input := &ec2.DescribeNatGatewaysInput{
+		Filter: []*ec2.Filter{
+			filter.EC2.VPC(s.scope.VPC().ID),
+			filter.EC2.NATGatewayStates(ec2.NatGatewayStatePending, ec2.NatGatewayStateAvailable, ec2.NatGatewayStateFailed, ec2.NatGatewayStateDeleting),
+		},
+	}
+
+	var gateway *ec2.NatGateway
+	if err := s.scope.EC2.DescribeNatGatewaysPages(input, func(page *ec2.DescribeNatGatewaysOutput, lastPage bool) bool {
+		for _, ngw := range page.NatGateways {
+			for _, address := range ngw.NatGatewayAddresses {
+				if aws.StringValue(address.AllocationId) == allocationID {
+					gateway = ngw
+					return false
+				}
+			}
+		}
+		return !lastPage
+	}); err != nil {
+		return nil, errors.Wrapf(err, "failed to describe NAT gateways with VPC ID %q", s.scope.VPC().ID)
+	}
+
+	return gateway, nil
+}
+
 func (s *Service) deleteNatGateway(id string) error {
 	_, err := s.scope.EC2.DeleteNatGateway(&ec2.DeleteNatGatewayInput{
 		NatGatewayId: aws.String(id),
@@ -250,11 +420,58 @@ func (s *Service) deleteNatGateway(id string) error {
 	return nil
 }
 
+// waitForNatGatewayAvailable waits for a NAT gateway to become available, routes
+// targeting a NAT gateway that is still pending fail with a transient error.
+// If the gateway is still pending after the backoff, an error is returned so
+// the reconcile is requeued.
+func (s *Service) waitForNatGatewayAvailable(id string) error {
+	describeInput := &ec2.DescribeNatGatewaysInput{
+		NatGatewayIds: []*string{aws.String(id)},
+	}
+
+	if err := wait.WaitForWithRetryable(wait.NewBackoff(), func() (bool, error) {
+		out, err := s.scope.EC2.DescribeNatGateways(describeInput)
+		if err != nil {
+			return false, err
+		}
+
+		if len(out.NatGateways) == 0 {
+			return false, errors.Errorf("no NAT gateway returned for id %q", id)
+		}
+
+		ng := out.NatGateways[0]
+		switch state := aws.StringValue(ng.State); state {
+		case ec2.NatGatewayStateAvailable:
+			return true, nil
+		case ec2.NatGatewayStatePending:
+			s.scope.V(2).Info("Waiting for NAT gateway to become available", "nat-gateway-id", id)
+			return false, nil
+		case ec2.NatGatewayStateFailed:
+			return false, errors.Errorf("in failed state: %q - %s", aws.StringValue(ng.FailureCode), aws.StringValue(ng.FailureMessage))
+		default:
+			return false, errors.Errorf("in unexpected state %q", state)
+		}
+	}, awserrors.NATGatewayNotFound); err != nil {
+		return errors.Wrapf(err, "NAT gateway %q is not available yet", id)
+	}
+
+	return nil
+}
+
 func (s *Service) getNatGatewayForSubnet(sn *infrav1.SubnetSpec) (string, error) {
 	if sn.IsPublic {
 		return "", errors.Errorf("cannot get NAT gateway for a public subnet, got id %q", sn.ID)
 	}
 
+	if s.scope.NatGatewayMode() == infrav1.NatGatewayModeSingle {
+		for _, psn := range s.natGatewaySubnets() {
+			if psn.NatGatewayID != nil {
+				return *psn.NatGatewayID, nil
+			}
+		}
+		return "", errors.Errorf("no nat gateway available for private subnet %q", sn.ID)
+	}
+
 	azGateways := make(map[string][]string)
 	for _, psn := range s.scope.Subnets().FilterPublic() {
 		if psn.NatGatewayID == nil {
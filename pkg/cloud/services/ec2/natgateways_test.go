@@ -20,6 +20,7 @@ import (
 	"testing"
 
 	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
 	"github.com/aws/aws-sdk-go/service/ec2"
 	"github.com/golang/mock/gomock"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -259,6 +260,111 @@ func TestReconcileNatGateways(t *testing.T) {
 				m.CreateNatGateway(gomock.Any()).Times(0)
 			},
 		},
+		{
+			name: "public & private subnet, NAT gateway recreated with a user provided Elastic IP",
+			input: []*infrav1.SubnetSpec{
+				{
+					ID:                     "subnet-1",
+					AvailabilityZone:       "us-east-1a",
+					CidrBlock:              "10.0.10.0/24",
+					IsPublic:               true,
+					NatGatewayAllocationID: "eipalloc-user",
+				},
+				{
+					ID:               "subnet-2",
+					AvailabilityZone: "us-east-1a",
+					CidrBlock:        "10.0.12.0/24",
+					IsPublic:         false,
+				},
+			},
+			expect: func(m *mock_ec2iface.MockEC2APIMockRecorder) {
+				m.DescribeNatGatewaysPages(
+					gomock.Eq(&ec2.DescribeNatGatewaysInput{
+						Filter: []*ec2.Filter{
+							{
+								Name:   aws.String("vpc-id"),
+								Values: []*string{aws.String(subnetsVPCID)},
+							},
+							{
+								Name:   aws.String("state"),
+								Values: []*string{aws.String("pending"), aws.String("available")},
+							},
+						},
+					}),
+					gomock.Any()).Return(nil)
+
+				createInput := &ec2.CreateNatGatewayInput{
+					AllocationId: aws.String("eipalloc-user"),
+					SubnetId:     aws.String("subnet-1"),
+				}
+
+				gomock.InOrder(
+					m.CreateNatGateway(createInput).
+						Return(nil, awserr.New("Resource.AlreadyAssociated", "Elastic IP address [eipalloc-user] is already associated", nil)),
+					m.CreateNatGateway(createInput).
+						Return(&ec2.CreateNatGatewayOutput{
+							NatGateway: &ec2.NatGateway{
+								NatGatewayId: aws.String("natgateway"),
+							},
+						}, nil),
+				)
+
+				m.DescribeAddresses(&ec2.DescribeAddressesInput{
+					AllocationIds: aws.StringSlice([]string{"eipalloc-user"}),
+				}).Return(&ec2.DescribeAddressesOutput{
+					Addresses: []*ec2.Address{
+						{
+							AllocationId:  aws.String("eipalloc-user"),
+							AssociationId: aws.String("eipassoc-old"),
+						},
+					},
+				}, nil)
+
+				m.DescribeNatGatewaysPages(
+					gomock.Eq(&ec2.DescribeNatGatewaysInput{
+						Filter: []*ec2.Filter{
+							{
+								Name:   aws.String("vpc-id"),
+								Values: []*string{aws.String(subnetsVPCID)},
+							},
+							{
+								Name:   aws.String("state"),
+								Values: []*string{aws.String("pending"), aws.String("available"), aws.String("failed"), aws.String("deleting")},
+							},
+						},
+					}),
+					gomock.Any()).DoAndReturn(func(_ *ec2.DescribeNatGatewaysInput, fn func(*ec2.DescribeNatGatewaysOutput, bool) bool) error {
+					fn(&ec2.DescribeNatGatewaysOutput{NatGateways: []*ec2.NatGateway{{
+						NatGatewayId: aws.String("nat-old"),
+						SubnetId:     aws.String("subnet-1"),
+						State:        aws.String(ec2.NatGatewayStateFailed),
+						NatGatewayAddresses: []*ec2.NatGatewayAddress{
+							{AllocationId: aws.String("eipalloc-user")},
+						},
+					}}}, true)
+					return nil
+				})
+
+				m.DeleteNatGateway(&ec2.DeleteNatGatewayInput{NatGatewayId: aws.String("nat-old")}).
+					Return(&ec2.DeleteNatGatewayOutput{}, nil)
+
+				m.DescribeNatGateways(&ec2.DescribeNatGatewaysInput{NatGatewayIds: []*string{aws.String("nat-old")}}).
+					Return(&ec2.DescribeNatGatewaysOutput{NatGateways: []*ec2.NatGateway{{
+						NatGatewayId: aws.String("nat-old"),
+						State:        aws.String(ec2.NatGatewayStateDeleted),
+					}}}, nil)
+
+				m.WaitUntilNatGatewayAvailable(&ec2.DescribeNatGatewaysInput{
+					NatGatewayIds: []*string{aws.String("natgateway")},
+				}).Return(nil)
+
+				m.CreateTags(gomock.AssignableToTypeOf(&ec2.CreateTagsInput{})).
+					Return(nil, nil).
+					Times(1)
+
+				m.AllocateAddress(gomock.Any()).Times(0)
+			},
+		},
 		{
 			name: "public & private subnet declared, but don't exist yet",
 			input: []*infrav1.SubnetSpec{
@@ -323,3 +429,161 @@ func TestReconcileNatGateways(t *testing.T) {
 		})
 	}
 }
+
+func TestDeleteUnusedNatGateways(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	ec2Mock := mock_ec2iface.NewMockEC2API(mockCtrl)
+
+	subnets := []*infrav1.SubnetSpec{
+		{
+			ID:               "subnet-1",
+			AvailabilityZone: "us-east-1a",
+			IsPublic:         true,
+			NatGatewayID:     aws.String("gateway-1"),
+		},
+		{
+			ID:               "subnet-2",
+			AvailabilityZone: "us-east-1b",
+			IsPublic:         false,
+		},
+		{
+			ID:               "subnet-3",
+			AvailabilityZone: "us-east-1b",
+			IsPublic:         true,
+			NatGatewayID:     aws.String("gateway-3"),
+		},
+	}
+
+	clusterScope, err := scope.NewClusterScope(scope.ClusterScopeParams{
+		Cluster: &clusterv1.Cluster{
+			ObjectMeta: metav1.ObjectMeta{Name: "test-cluster"},
+		},
+		AWSClients: scope.AWSClients{
+			EC2: ec2Mock,
+		},
+		AWSCluster: &infrav1.AWSCluster{
+			Spec: infrav1.AWSClusterSpec{
+				NetworkSpec: infrav1.NetworkSpec{
+					VPC: infrav1.VPCSpec{
+						ID: subnetsVPCID,
+						Tags: infrav1.Tags{
+							infrav1.ClusterTagKey("test-cluster"): "owned",
+						},
+					},
+					Subnets:        subnets,
+					NatGatewayMode: infrav1.NatGatewayModeSingle,
+				},
+			},
+		},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create test context: %v", err)
+	}
+
+	ec2Mock.EXPECT().DescribeNatGatewaysPages(gomock.Any(), gomock.Any()).Do(func(_, y interface{}) {
+		funct := y.(func(page *ec2.DescribeNatGatewaysOutput, lastPage bool) bool)
+		funct(&ec2.DescribeNatGatewaysOutput{NatGateways: []*ec2.NatGateway{
+			{NatGatewayId: aws.String("gateway-1"), SubnetId: aws.String("subnet-1")},
+			{NatGatewayId: aws.String("gateway-3"), SubnetId: aws.String("subnet-3")},
+		}}, true)
+	}).Return(nil)
+	ec2Mock.EXPECT().DeleteNatGateway(&ec2.DeleteNatGatewayInput{NatGatewayId: aws.String("gateway-3")}).
+		Return(&ec2.DeleteNatGatewayOutput{}, nil)
+	ec2Mock.EXPECT().DescribeNatGateways(&ec2.DescribeNatGatewaysInput{NatGatewayIds: []*string{aws.String("gateway-3")}}).
+		Return(&ec2.DescribeNatGatewaysOutput{NatGateways: []*ec2.NatGateway{
+			{NatGatewayId: aws.String("gateway-3"), State: aws.String(ec2.NatGatewayStateDeleted)},
+		}}, nil)
+
+	s := NewService(clusterScope)
+	if err := s.deleteUnusedNatGateways(); err != nil {
+		t.Fatalf("got an unexpected error: %v", err)
+	}
+
+	if subnets[2].NatGatewayID != nil {
+		t.Fatalf("expected the NAT gateway of subnet-3 to be cleared, got %q", *subnets[2].NatGatewayID)
+	}
+
+	// The private subnet in another availability zone uses the single NAT gateway.
+	id, err := s.getNatGatewayForSubnet(subnets[1])
+	if err != nil {
+		t.Fatalf("got an unexpected error: %v", err)
+	}
+	if id != "gateway-1" {
+		t.Fatalf("expected private subnet to use gateway-1, got %q", id)
+	}
+}
+
+func TestNatGatewaysDisabled(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	ec2Mock := mock_ec2iface.NewMockEC2API(mockCtrl)
+
+	subnets := []*infrav1.SubnetSpec{
+		{
+			ID:               "subnet-1",
+			AvailabilityZone: "us-east-1a",
+			IsPublic:         true,
+			NatGatewayID:     aws.String("gateway-1"),
+		},
+		{
+			ID:               "subnet-2",
+			AvailabilityZone: "us-east-1a",
+			IsPublic:         false,
+		},
+	}
+
+	clusterScope, err := scope.NewClusterScope(scope.ClusterScopeParams{
+		Cluster: &clusterv1.Cluster{
+			ObjectMeta: metav1.ObjectMeta{Name: "test-cluster"},
+		},
+		AWSClients: scope.AWSClients{
+			EC2: ec2Mock,
+		},
+		AWSCluster: &infrav1.AWSCluster{
+			Spec: infrav1.AWSClusterSpec{
+				NetworkSpec: infrav1.NetworkSpec{
+					VPC: infrav1.VPCSpec{
+						ID: subnetsVPCID,
+						Tags: infrav1.Tags{
+							infrav1.ClusterTagKey("test-cluster"): "owned",
+						},
+					},
+					Subnets:        subnets,
+					NatGatewayMode: infrav1.NatGatewayModeNone,
+				},
+			},
+		},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create test context: %v", err)
+	}
+
+	// No NAT gateway is created, the existing one is deleted as unused.
+	ec2Mock.EXPECT().DescribeNatGatewaysPages(gomock.Any(), gomock.Any()).Do(func(_, y interface{}) {
+		funct := y.(func(page *ec2.DescribeNatGatewaysOutput, lastPage bool) bool)
+		funct(&ec2.DescribeNatGatewaysOutput{NatGateways: []*ec2.NatGateway{
+			{NatGatewayId: aws.String("gateway-1"), SubnetId: aws.String("subnet-1")},
+		}}, true)
+	}).Return(nil)
+	ec2Mock.EXPECT().DeleteNatGateway(&ec2.DeleteNatGatewayInput{NatGatewayId: aws.String("gateway-1")}).
+		Return(&ec2.DeleteNatGatewayOutput{}, nil)
+	ec2Mock.EXPECT().DescribeNatGateways(&ec2.DescribeNatGatewaysInput{NatGatewayIds: []*string{aws.String("gateway-1")}}).
+		Return(&ec2.DescribeNatGatewaysOutput{NatGateways: []*ec2.NatGateway{
+			{NatGatewayId: aws.String("gateway-1"), State: aws.String(ec2.NatGatewayStateDeleted)},
+		}}, nil)
+
+	s := NewService(clusterScope)
+	if err := s.reconcileNatGateways(); err != nil {
+		t.Fatalf("got an unexpected error: %v", err)
+	}
+	if err := s.deleteUnusedNatGateways(); err != nil {
+		t.Fatalf("got an unexpected error: %v", err)
+	}
+
+	if subnets[0].NatGatewayID != nil {
+		t.Fatalf("expected the NAT gateway of subnet-1 to be cleared, got %q", *subnets[0].NatGatewayID)
+	}
+}
@@ -20,6 +20,21 @@ package ec2
 func (s *Service) ReconcileNetwork() (err error) {
 	s.scope.V(2).Info("Reconciling network for cluster", "cluster-name", s.scope.Cluster.Name, "cluster-namespace", s.scope.Cluster.Namespace)
 
+	// Shared networks belong to another account, only the security groups of
+	// the cluster are managed in them.
+	if s.scope.AWSCluster.Spec.NetworkSpec.IsShared() {
+		if err := s.reconcileSharedNetwork(); err != nil {
+			return err
+		}
+
+		if err := s.reconcileSecurityGroups(); err != nil {
+			return err
+		}
+
+		s.scope.V(2).Info("Reconcile network completed successfully")
+		return nil
+	}
+
 	// VPC.
 	if err := s.reconcileVPC(); err != nil {
 		return err
@@ -30,26 +45,69 @@ func (s *Service) ReconcileNetwork() (err error) {
 		return err
 	}
 
+	// Unmanaged networks are only validated, gateways, route tables and
+	// network ACLs are left untouched below.
+	if s.scope.VPC().IsUnmanaged(s.scope.Name()) {
+		if err := s.validateUnmanagedNetwork(); err != nil {
+			return err
+		}
+	}
+
+	// DHCP options.
+	if err := s.reconcileDHCPOptions(); err != nil {
+		return err
+	}
+
 	// Internet Gateways.
 	if err := s.reconcileInternetGateways(); err != nil {
 		return err
 	}
 
+	// Egress-only Internet Gateways.
+	if err := s.reconcileEgressOnlyInternetGateways(); err != nil {
+		return err
+	}
+
 	// NAT Gateways.
 	if err := s.reconcileNatGateways(); err != nil {
 		return err
 	}
 
+	// Transit gateway attachment.
+	if err := s.reconcileTransitGatewayAttachment(); err != nil {
+		return err
+	}
+
+	// VPC peering.
+	if err := s.reconcileVPCPeering(); err != nil {
+		return err
+	}
+
 	// Routing tables.
 	if err := s.reconcileRouteTables(); err != nil {
 		return err
 	}
 
+	// NAT gateways the private subnets no longer route through.
+	if err := s.deleteUnusedNatGateways(); err != nil {
+		return err
+	}
+
+	// Network ACLs.
+	if err := s.reconcileNetworkACL(); err != nil {
+		return err
+	}
+
 	// Security groups.
 	if err := s.reconcileSecurityGroups(); err != nil {
 		return err
 	}
 
+	// VPC endpoints.
+	if err := s.reconcileVPCEndpoints(); err != nil {
+		return err
+	}
+
 	s.scope.V(2).Info("Reconcile network completed successfully")
 	return nil
 }
@@ -58,11 +116,42 @@ func (s *Service) ReconcileNetwork() (err error) {
 func (s *Service) DeleteNetwork() (err error) {
 	s.scope.V(2).Info("Deleting network")
 
+	// Shared networks are left untouched, apart from the security groups of
+	// the cluster.
+	if s.scope.AWSCluster.Spec.NetworkSpec.IsShared() {
+		if err := s.deleteSecurityGroups(); err != nil {
+			return err
+		}
+
+		s.scope.V(2).Info("Delete network completed successfully")
+		return nil
+	}
+
+	// VPC endpoints.
+	if err := s.deleteVPCEndpoints(); err != nil {
+		return err
+	}
+
+	// Transit gateway attachment.
+	if err := s.deleteTransitGatewayAttachments(); err != nil {
+		return err
+	}
+
+	// VPC peering.
+	if err := s.deleteVPCPeering(); err != nil {
+		return err
+	}
+
 	// Security groups.
 	if err := s.deleteSecurityGroups(); err != nil {
 		return err
 	}
 
+	// Network ACLs.
+	if err := s.deleteNetworkACL(); err != nil {
+		return err
+	}
+
 	// Routing tables.
 	if err := s.deleteRouteTables(); err != nil {
 		return err
@@ -83,11 +172,26 @@ func (s *Service) DeleteNetwork() (err error) {
 		return err
 	}
 
+	// Egress-only Internet Gateways.
+	if err := s.deleteEgressOnlyInternetGateways(); err != nil {
+		return err
+	}
+
 	// Subnets.
 	if err := s.deleteSubnets(); err != nil {
 		return err
 	}
 
+	// Secondary CIDR blocks, once no subnet uses them.
+	if err := s.deleteVPCSecondaryCidrBlocks(); err != nil {
+		return err
+	}
+
+	// DHCP options.
+	if err := s.deleteDHCPOptions(); err != nil {
+		return err
+	}
+
 	// VPC.
 	if err := s.deleteVPC(); err != nil {
 		return err
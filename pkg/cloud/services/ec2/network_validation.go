@@ -0,0 +1,161 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ec2
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/pkg/errors"
+	"k8s.io/apimachinery/pkg/util/sets"
+	infrav1 "sigs.k8s.io/cluster-api-provider-aws/api/v1alpha3"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/record"
+	capierrors "sigs.k8s.io/cluster-api/errors"
+)
+
+// minLoadBalancerSubnetFreeIPs is the number of free IP addresses a load
+// balancer needs in each of its subnets to scale.
+const minLoadBalancerSubnetFreeIPs = 8
+
+// validateUnmanagedNetwork checks that a provided VPC and its subnets can host
+// the cluster, since they are never modified to fix them. A failed validation
+// is reported on the AWSCluster status until the network is fixed.
+func (s *Service) validateUnmanagedNetwork() error {
+	s.scope.V(2).Info("Validating unmanaged network", "vpc-id", s.scope.VPC().ID)
+
+	problems, err := s.unmanagedNetworkProblems()
+	if err != nil {
+		return err
+	}
+
+	if len(problems) > 0 {
+		err := errors.Errorf("invalid unmanaged network in VPC %q: %s", s.scope.VPC().ID, strings.Join(problems, "; "))
+		record.Warnf(s.scope.AWSCluster, "InvalidUnmanagedNetwork", "Unmanaged network failed validation: %s", strings.Join(problems, "; "))
+		s.scope.SetFailureReason(capierrors.InvalidConfigurationClusterError)
+		s.scope.SetFailureMessage(err)
+		return err
+	}
+
+	if reason := s.scope.AWSCluster.Status.FailureReason; reason != nil && *reason == capierrors.InvalidConfigurationClusterError {
+		record.Eventf(s.scope.AWSCluster, "ValidUnmanagedNetwork", "Unmanaged network in VPC %q passed validation", s.scope.VPC().ID)
+		s.scope.ClearFailure()
+	}
+
+	return nil
+}
+
+func (s *Service) unmanagedNetworkProblems() ([]string, error) {
+	out, err := s.describeSubnets()
+	if err != nil {
+		return nil, err
+	}
+	available := make(map[string]*ec2.Subnet, len(out.Subnets))
+	for _, ec2sn := range out.Subnets {
+		available[aws.StringValue(ec2sn.SubnetId)] = ec2sn
+	}
+
+	routeTables, err := s.describeVpcRouteTablesBySubnet()
+	if err != nil {
+		return nil, err
+	}
+
+	var problems []string
+	subnets := make(infrav1.Subnets, 0, len(s.scope.Subnets()))
+	for _, sn := range s.scope.Subnets() {
+		if _, ok := available[sn.ID]; !ok {
+			if sn.ID == "" {
+				problems = append(problems, fmt.Sprintf("subnet with cidr block %q was not found, subnets are not created in unmanaged mode", sn.CidrBlock))
+			} else {
+				problems = append(problems, fmt.Sprintf("subnet %q was not found in the VPC", sn.ID))
+			}
+			continue
+		}
+
+		rt := routeTables[sn.ID]
+		if rt == nil {
+			rt = routeTables[mainRouteTableInVPCKey]
+		}
+		if sn.IsPublic && !hasInternetGatewayRoute(rt) {
+			problems = append(problems, fmt.Sprintf("public subnet %q has no default route to an internet gateway", sn.ID))
+		}
+
+		subnets = append(subnets, sn)
+	}
+
+	private, public := subnets.FilterPrivate(), subnets.FilterPublic()
+	if len(private) == 0 {
+		problems = append(problems, "expected at least one private subnet available for use, got 0")
+	}
+
+	// The load balancer is placed in the public subnets unless it is internal,
+	// and must reach the control plane instances in every zone.
+	lbSubnets := private
+	if s.scope.ControlPlaneLoadBalancerScheme() == infrav1.ClassicELBSchemeInternetFacing {
+		lbSubnets = public
+		if len(public) == 0 {
+			problems = append(problems, "expected at least one public subnet available for the internet-facing load balancer, got 0")
+		}
+
+		publicZones := sets.NewString()
+		for _, sn := range public {
+			publicZones.Insert(sn.AvailabilityZone)
+		}
+		missing := sets.NewString()
+		for _, sn := range private {
+			if !publicZones.Has(sn.AvailabilityZone) {
+				missing.Insert(sn.AvailabilityZone)
+			}
+		}
+		for _, zone := range missing.List() {
+			problems = append(problems, fmt.Sprintf("availability zone %q has private subnets but no public subnet for the load balancer", zone))
+		}
+	}
+
+	for _, sn := range lbSubnets {
+		if free := aws.Int64Value(available[sn.ID].AvailableIpAddressCount); free < minLoadBalancerSubnetFreeIPs {
+			problems = append(problems, fmt.Sprintf("subnet %q has %d free IP addresses, the load balancer needs at least %d", sn.ID, free, minLoadBalancerSubnetFreeIPs))
+		}
+	}
+
+	if len(private) > 0 {
+		var free int64
+		for _, sn := range private {
+			free += aws.Int64Value(available[sn.ID].AvailableIpAddressCount)
+		}
+		if free == 0 {
+			problems = append(problems, "private subnets have no free IP addresses for instances")
+		}
+	}
+
+	return problems, nil
+}
+
+// hasInternetGatewayRoute returns true if the route table sends the default
+// route to an internet gateway.
+func hasInternetGatewayRoute(rt *ec2.RouteTable) bool {
+	if rt == nil {
+		return false
+	}
+	for _, route := range rt.Routes {
+		if aws.StringValue(route.DestinationCidrBlock) == anyIPv4CidrBlock && strings.HasPrefix(aws.StringValue(route.GatewayId), "igw") {
+			return true
+		}
+	}
+	return false
+}
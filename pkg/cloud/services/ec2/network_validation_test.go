@@ -0,0 +1,180 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ec2
+
+import (
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/golang/mock/gomock"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	infrav1 "sigs.k8s.io/cluster-api-provider-aws/api/v1alpha3"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/scope"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/services/ec2/mock_ec2iface"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1alpha3"
+	capierrors "sigs.k8s.io/cluster-api/errors"
+)
+
+func TestValidateUnmanagedNetwork(t *testing.T) {
+	publicRouteTable := &ec2.RouteTable{
+		RouteTableId: aws.String("rtb-public"),
+		Associations: []*ec2.RouteTableAssociation{{SubnetId: aws.String("subnet-public-1a")}},
+		Routes: []*ec2.Route{
+			{DestinationCidrBlock: aws.String("10.0.0.0/16"), GatewayId: aws.String("local")},
+			{DestinationCidrBlock: aws.String("0.0.0.0/0"), GatewayId: aws.String("igw-0")},
+		},
+	}
+	privateRouteTable := &ec2.RouteTable{
+		RouteTableId: aws.String("rtb-private"),
+		Associations: []*ec2.RouteTableAssociation{{Main: aws.Bool(true)}},
+		Routes: []*ec2.Route{
+			{DestinationCidrBlock: aws.String("10.0.0.0/16"), GatewayId: aws.String("local")},
+			{DestinationCidrBlock: aws.String("0.0.0.0/0"), NatGatewayId: aws.String("nat-0")},
+		},
+	}
+	ec2Subnet := func(id, zone string, free int64) *ec2.Subnet {
+		return &ec2.Subnet{
+			VpcId:                   aws.String(subnetsVPCID),
+			SubnetId:                aws.String(id),
+			AvailabilityZone:        aws.String(zone),
+			AvailableIpAddressCount: aws.Int64(free),
+		}
+	}
+
+	testCases := []struct {
+		name    string
+		subnets infrav1.Subnets
+		ec2     []*ec2.Subnet
+		wantErr bool
+	}{
+		{
+			name: "public and private subnets in the same zone",
+			subnets: infrav1.Subnets{
+				{ID: "subnet-public-1a", AvailabilityZone: "us-east-1a", IsPublic: true},
+				{ID: "subnet-private-1a", AvailabilityZone: "us-east-1a"},
+			},
+			ec2: []*ec2.Subnet{
+				ec2Subnet("subnet-public-1a", "us-east-1a", 250),
+				ec2Subnet("subnet-private-1a", "us-east-1a", 250),
+			},
+			wantErr: false,
+		},
+		{
+			name: "private subnet in a zone without public subnet",
+			subnets: infrav1.Subnets{
+				{ID: "subnet-public-1a", AvailabilityZone: "us-east-1a", IsPublic: true},
+				{ID: "subnet-private-1a", AvailabilityZone: "us-east-1a"},
+				{ID: "subnet-private-1b", AvailabilityZone: "us-east-1b"},
+			},
+			ec2: []*ec2.Subnet{
+				ec2Subnet("subnet-public-1a", "us-east-1a", 250),
+				ec2Subnet("subnet-private-1a", "us-east-1a", 250),
+				ec2Subnet("subnet-private-1b", "us-east-1b", 250),
+			},
+			wantErr: true,
+		},
+		{
+			name: "load balancer subnet without enough free IP addresses",
+			subnets: infrav1.Subnets{
+				{ID: "subnet-public-1a", AvailabilityZone: "us-east-1a", IsPublic: true},
+				{ID: "subnet-private-1a", AvailabilityZone: "us-east-1a"},
+			},
+			ec2: []*ec2.Subnet{
+				ec2Subnet("subnet-public-1a", "us-east-1a", 3),
+				ec2Subnet("subnet-private-1a", "us-east-1a", 250),
+			},
+			wantErr: true,
+		},
+		{
+			name: "public subnet without a route to an internet gateway",
+			subnets: infrav1.Subnets{
+				{ID: "subnet-public-1a", AvailabilityZone: "us-east-1a", IsPublic: true},
+				{ID: "subnet-public-1b", AvailabilityZone: "us-east-1b", IsPublic: true},
+				{ID: "subnet-private-1a", AvailabilityZone: "us-east-1a"},
+			},
+			ec2: []*ec2.Subnet{
+				ec2Subnet("subnet-public-1a", "us-east-1a", 250),
+				ec2Subnet("subnet-public-1b", "us-east-1b", 250),
+				ec2Subnet("subnet-private-1a", "us-east-1a", 250),
+			},
+			wantErr: true,
+		},
+		{
+			name: "provided subnet missing from the VPC",
+			subnets: infrav1.Subnets{
+				{ID: "subnet-public-1a", AvailabilityZone: "us-east-1a", IsPublic: true},
+				{ID: "subnet-private-1a", AvailabilityZone: "us-east-1a"},
+				{ID: "subnet-missing"},
+			},
+			ec2: []*ec2.Subnet{
+				ec2Subnet("subnet-public-1a", "us-east-1a", 250),
+				ec2Subnet("subnet-private-1a", "us-east-1a", 250),
+			},
+			wantErr: true,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			mockCtrl := gomock.NewController(t)
+			defer mockCtrl.Finish()
+			ec2Mock := mock_ec2iface.NewMockEC2API(mockCtrl)
+
+			scope, err := scope.NewClusterScope(scope.ClusterScopeParams{
+				Cluster: &clusterv1.Cluster{
+					ObjectMeta: metav1.ObjectMeta{Name: "test-cluster"},
+				},
+				AWSClients: scope.AWSClients{
+					EC2: ec2Mock,
+				},
+				AWSCluster: &infrav1.AWSCluster{
+					Spec: infrav1.AWSClusterSpec{
+						NetworkSpec: infrav1.NetworkSpec{
+							VPC:     infrav1.VPCSpec{ID: subnetsVPCID},
+							Subnets: tc.subnets,
+						},
+					},
+				},
+			})
+			if err != nil {
+				t.Fatalf("Failed to create test context: %v", err)
+			}
+
+			ec2Mock.EXPECT().DescribeSubnets(gomock.AssignableToTypeOf(&ec2.DescribeSubnetsInput{})).
+				Return(&ec2.DescribeSubnetsOutput{Subnets: tc.ec2}, nil)
+			ec2Mock.EXPECT().DescribeRouteTables(gomock.AssignableToTypeOf(&ec2.DescribeRouteTablesInput{})).
+				Return(&ec2.DescribeRouteTablesOutput{
+					RouteTables: []*ec2.RouteTable{publicRouteTable, privateRouteTable},
+				}, nil)
+
+			s := NewService(scope)
+			err = s.validateUnmanagedNetwork()
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("validateUnmanagedNetwork() error = %v, wantErr %v", err, tc.wantErr)
+			}
+
+			status := scope.AWSCluster.Status
+			if tc.wantErr {
+				if status.FailureReason == nil || *status.FailureReason != capierrors.InvalidConfigurationClusterError || status.FailureMessage == nil {
+					t.Fatalf("expected the validation failure on the AWSCluster status, got %+v", status)
+				}
+			} else if status.FailureReason != nil || status.FailureMessage != nil {
+				t.Fatalf("expected no failure on the AWSCluster status, got %+v", status)
+			}
+		})
+	}
+}
@@ -0,0 +1,378 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ec2
+
+import (
+	"fmt"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/pkg/errors"
+	"k8s.io/apimachinery/pkg/util/sets"
+	infrav1 "sigs.k8s.io/cluster-api-provider-aws/api/v1alpha3"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/awserrors"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/converters"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/filter"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/services/wait"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/tags"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/record"
+)
+
+const (
+	// defaultNetworkACLRuleNumber is the number of the deny-all rule that
+	// every network ACL ends with. It cannot be modified.
+	defaultNetworkACLRuleNumber = 32767
+)
+
+// networkACLEntryKey identifies an entry of a network ACL.
+type networkACLEntryKey struct {
+	ruleNumber int64
+	egress     bool
+}
+
+func (s *Service) reconcileNetworkACL() error {
+	if s.scope.VPC().IsUnmanaged(s.scope.Name()) {
+		s.scope.V(4).Info("Skipping network ACL reconcile in unmanaged mode")
+		return nil
+	}
+
+	spec := s.scope.NetworkACL()
+	if spec == nil {
+		// Remove the network ACL if it is no longer wanted.
+		return s.deleteNetworkACL()
+	}
+
+	s.scope.V(2).Info("Reconciling network ACL")
+
+	acl, err := s.describeClusterNetworkACL()
+	if err != nil {
+		return err
+	}
+
+	if acl == nil {
+		acl, err = s.createNetworkACL()
+		if err != nil {
+			return err
+		}
+	} else {
+		// Make sure tags are up to date.
+		if err := wait.WaitForWithRetryable(wait.NewBackoff(), func() (bool, error) {
+			if err := tags.Ensure(converters.TagsToMap(acl.Tags), &tags.ApplyParams{
+				EC2Client:   s.scope.EC2,
+				BuildParams: s.getNetworkACLTagParams(*acl.NetworkAclId),
+				Removed:     s.scope.RemovedAdditionalTags(),
+			}); err != nil {
+				return false, err
+			}
+			return true, nil
+		}, awserrors.NetworkACLNotFound); err != nil {
+			record.Warnf(s.scope.AWSCluster, "FailedTagNetworkACL", "Failed to tag managed NetworkACL %q: %v", *acl.NetworkAclId, err)
+			return errors.Wrapf(err, "failed to ensure tags on network ACL %q", *acl.NetworkAclId)
+		}
+	}
+
+	if err := s.reconcileNetworkACLEntries(acl, spec.Rules); err != nil {
+		return err
+	}
+
+	return s.reconcileNetworkACLAssociations(acl)
+}
+
+func (s *Service) reconcileNetworkACLEntries(acl *ec2.NetworkAcl, rules []infrav1.NetworkACLRule) error {
+	current := make(map[networkACLEntryKey]*ec2.NetworkAclEntry)
+	for _, entry := range acl.Entries {
+		if aws.Int64Value(entry.RuleNumber) == defaultNetworkACLRuleNumber {
+			continue
+		}
+		current[networkACLEntryKey{ruleNumber: aws.Int64Value(entry.RuleNumber), egress: aws.BoolValue(entry.Egress)}] = entry
+	}
+
+	for i := range rules {
+		rule := &rules[i]
+		key := networkACLEntryKey{ruleNumber: rule.RuleNumber, egress: rule.Egress}
+		entry, ok := current[key]
+		delete(current, key)
+
+		portRange, icmpTypeCode := networkACLRuleRanges(rule)
+
+		switch {
+		case !ok:
+			if _, err := s.scope.EC2.CreateNetworkAclEntry(&ec2.CreateNetworkAclEntryInput{
+				NetworkAclId: acl.NetworkAclId,
+				RuleNumber:   aws.Int64(rule.RuleNumber),
+				Egress:       aws.Bool(rule.Egress),
+				Protocol:     aws.String(networkACLProtocol(rule.Protocol)),
+				RuleAction:   aws.String(string(rule.Action)),
+				CidrBlock:    aws.String(rule.CidrBlock),
+				PortRange:    portRange,
+				IcmpTypeCode: icmpTypeCode,
+			}); err != nil {
+				record.Warnf(s.scope.AWSCluster, "FailedCreateNetworkACLEntry", "Failed to create rule %d for NetworkACL %q: %v", rule.RuleNumber, *acl.NetworkAclId, err)
+				return errors.Wrapf(err, "failed to create rule %d in network ACL %q", rule.RuleNumber, *acl.NetworkAclId)
+			}
+			record.Eventf(s.scope.AWSCluster, "SuccessfulCreateNetworkACLEntry", "Created rule %d for NetworkACL %q", rule.RuleNumber, *acl.NetworkAclId)
+
+		case !networkACLEntryMatches(entry, rule):
+			if _, err := s.scope.EC2.ReplaceNetworkAclEntry(&ec2.ReplaceNetworkAclEntryInput{
+				NetworkAclId: acl.NetworkAclId,
+				RuleNumber:   aws.Int64(rule.RuleNumber),
+				Egress:       aws.Bool(rule.Egress),
+				Protocol:     aws.String(networkACLProtocol(rule.Protocol)),
+				RuleAction:   aws.String(string(rule.Action)),
+				CidrBlock:    aws.String(rule.CidrBlock),
+				PortRange:    portRange,
+				IcmpTypeCode: icmpTypeCode,
+			}); err != nil {
+				record.Warnf(s.scope.AWSCluster, "FailedReplaceNetworkACLEntry", "Failed to replace rule %d for NetworkACL %q: %v", rule.RuleNumber, *acl.NetworkAclId, err)
+				return errors.Wrapf(err, "failed to replace rule %d in network ACL %q", rule.RuleNumber, *acl.NetworkAclId)
+			}
+			record.Eventf(s.scope.AWSCluster, "SuccessfulReplaceNetworkACLEntry", "Replaced rule %d for NetworkACL %q", rule.RuleNumber, *acl.NetworkAclId)
+		}
+	}
+
+	for key := range current {
+		if _, err := s.scope.EC2.DeleteNetworkAclEntry(&ec2.DeleteNetworkAclEntryInput{
+			NetworkAclId: acl.NetworkAclId,
+			RuleNumber:   aws.Int64(key.ruleNumber),
+			Egress:       aws.Bool(key.egress),
+		}); err != nil {
+			record.Warnf(s.scope.AWSCluster, "FailedDeleteNetworkACLEntry", "Failed to delete rule %d for NetworkACL %q: %v", key.ruleNumber, *acl.NetworkAclId, err)
+			return errors.Wrapf(err, "failed to delete rule %d in network ACL %q", key.ruleNumber, *acl.NetworkAclId)
+		}
+		record.Eventf(s.scope.AWSCluster, "SuccessfulDeleteNetworkACLEntry", "Deleted rule %d for NetworkACL %q", key.ruleNumber, *acl.NetworkAclId)
+	}
+
+	return nil
+}
+
+func (s *Service) reconcileNetworkACLAssociations(acl *ec2.NetworkAcl) error {
+	subnetIDs := sets.NewString()
+	for _, sn := range s.scope.Subnets() {
+		subnetIDs.Insert(sn.ID)
+	}
+	if subnetIDs.Len() == 0 {
+		return nil
+	}
+
+	// Every subnet is associated with exactly one network ACL, the default one of the VPC
+	// unless it was replaced.
+	out, err := s.scope.EC2.DescribeNetworkAcls(&ec2.DescribeNetworkAclsInput{
+		Filters: []*ec2.Filter{
+			filter.EC2.VPC(s.scope.VPC().ID),
+			{
+				Name:   aws.String("association.subnet-id"),
+				Values: aws.StringSlice(subnetIDs.List()),
+			},
+		},
+	})
+	if err != nil {
+		return errors.Wrapf(err, "failed to describe network ACLs of subnets in vpc %q", s.scope.VPC().ID)
+	}
+
+	for _, current := range out.NetworkAcls {
+		if aws.StringValue(current.NetworkAclId) == aws.StringValue(acl.NetworkAclId) {
+			continue
+		}
+
+		for _, as := range current.Associations {
+			if !subnetIDs.Has(aws.StringValue(as.SubnetId)) {
+				continue
+			}
+
+			if err := s.replaceNetworkACLAssociation(as, *acl.NetworkAclId); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+func (s *Service) deleteNetworkACL() error {
+	if s.scope.VPC().IsUnmanaged(s.scope.Name()) {
+		s.scope.V(4).Info("Skipping network ACL deletion in unmanaged mode")
+		return nil
+	}
+
+	acl, err := s.describeClusterNetworkACL()
+	if err != nil {
+		return err
+	}
+
+	if acl == nil {
+		return nil
+	}
+
+	// A network ACL cannot be deleted while subnets are associated with it,
+	// hand them back to the default network ACL of the VPC.
+	if len(acl.Associations) > 0 {
+		defaultACL, err := s.describeDefaultNetworkACL()
+		if err != nil {
+			return err
+		}
+
+		for _, as := range acl.Associations {
+			if err := s.replaceNetworkACLAssociation(as, *defaultACL.NetworkAclId); err != nil {
+				return err
+			}
+		}
+	}
+
+	if _, err := s.scope.EC2.DeleteNetworkAcl(&ec2.DeleteNetworkAclInput{NetworkAclId: acl.NetworkAclId}); err != nil {
+		record.Warnf(s.scope.AWSCluster, "FailedDeleteNetworkACL", "Failed to delete managed NetworkACL %q: %v", *acl.NetworkAclId, err)
+		return errors.Wrapf(err, "failed to delete network ACL %q", *acl.NetworkAclId)
+	}
+
+	record.Eventf(s.scope.AWSCluster, "SuccessfulDeleteNetworkACL", "Deleted managed NetworkACL %q", *acl.NetworkAclId)
+	s.scope.Info("Deleted network ACL", "network-acl-id", *acl.NetworkAclId)
+	return nil
+}
+
+func (s *Service) describeClusterNetworkACL() (*ec2.NetworkAcl, error) {
+	out, err := s.scope.EC2.DescribeNetworkAcls(&ec2.DescribeNetworkAclsInput{
+		Filters: []*ec2.Filter{
+			filter.EC2.VPC(s.scope.VPC().ID),
+			filter.EC2.ClusterOwned(s.scope.Name()),
+		},
+	})
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to describe network ACLs in vpc %q", s.scope.VPC().ID)
+	}
+
+	if len(out.NetworkAcls) == 0 {
+		return nil, nil
+	}
+
+	return out.NetworkAcls[0], nil
+}
+
+func (s *Service) describeDefaultNetworkACL() (*ec2.NetworkAcl, error) {
+	out, err := s.scope.EC2.DescribeNetworkAcls(&ec2.DescribeNetworkAclsInput{
+		Filters: []*ec2.Filter{
+			filter.EC2.VPC(s.scope.VPC().ID),
+			{
+				Name:   aws.String("default"),
+				Values: aws.StringSlice([]string{"true"}),
+			},
+		},
+	})
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to describe default network ACL in vpc %q", s.scope.VPC().ID)
+	}
+
+	if len(out.NetworkAcls) == 0 {
+		return nil, errors.Errorf("no default network ACL found in vpc %q", s.scope.VPC().ID)
+	}
+
+	return out.NetworkAcls[0], nil
+}
+
+func (s *Service) createNetworkACL() (*ec2.NetworkAcl, error) {
+	out, err := s.scope.EC2.CreateNetworkAcl(&ec2.CreateNetworkAclInput{
+		VpcId: aws.String(s.scope.VPC().ID),
+	})
+	if err != nil {
+		record.Warnf(s.scope.AWSCluster, "FailedCreateNetworkACL", "Failed to create managed NetworkACL: %v", err)
+		return nil, errors.Wrapf(err, "failed to create network ACL in vpc %q", s.scope.VPC().ID)
+	}
+	record.Eventf(s.scope.AWSCluster, "SuccessfulCreateNetworkACL", "Created managed NetworkACL %q", *out.NetworkAcl.NetworkAclId)
+
+	if err := wait.WaitForWithRetryable(wait.NewBackoff(), func() (bool, error) {
+		if err := tags.Apply(&tags.ApplyParams{
+			EC2Client:   s.scope.EC2,
+			BuildParams: s.getNetworkACLTagParams(*out.NetworkAcl.NetworkAclId),
+		}); err != nil {
+			return false, err
+		}
+		return true, nil
+	}, awserrors.NetworkACLNotFound); err != nil {
+		record.Warnf(s.scope.AWSCluster, "FailedTagNetworkACL", "Failed to tag managed NetworkACL %q: %v", *out.NetworkAcl.NetworkAclId, err)
+		return nil, errors.Wrapf(err, "failed to tag network ACL %q", *out.NetworkAcl.NetworkAclId)
+	}
+	record.Eventf(s.scope.AWSCluster, "SuccessfulTagNetworkACL", "Tagged managed NetworkACL %q", *out.NetworkAcl.NetworkAclId)
+
+	return out.NetworkAcl, nil
+}
+
+func (s *Service) replaceNetworkACLAssociation(as *ec2.NetworkAclAssociation, aclID string) error {
+	if _, err := s.scope.EC2.ReplaceNetworkAclAssociation(&ec2.ReplaceNetworkAclAssociationInput{
+		AssociationId: as.NetworkAclAssociationId,
+		NetworkAclId:  aws.String(aclID),
+	}); err != nil {
+		record.Warnf(s.scope.AWSCluster, "FailedAssociateNetworkACL", "Failed to associate NetworkACL %q with Subnet %q: %v", aclID, aws.StringValue(as.SubnetId), err)
+		return errors.Wrapf(err, "failed to associate network ACL %q with subnet %q", aclID, aws.StringValue(as.SubnetId))
+	}
+
+	record.Eventf(s.scope.AWSCluster, "SuccessfulAssociateNetworkACL", "Associated NetworkACL %q with subnet %q", aclID, aws.StringValue(as.SubnetId))
+	return nil
+}
+
+func (s *Service) getNetworkACLTagParams(id string) infrav1.BuildParams {
+	name := fmt.Sprintf("%s-nacl", s.scope.Name())
+
+	return infrav1.BuildParams{
+		ClusterName: s.scope.Name(),
+		ResourceID:  id,
+		Lifecycle:   infrav1.ResourceLifecycleOwned,
+		Name:        aws.String(name),
+		Role:        aws.String(infrav1.CommonRoleTagValue),
+		Additional:  s.scope.AdditionalTags(),
+	}
+}
+
+// networkACLProtocol returns the protocol number EC2 uses for the protocol of
+// a network ACL rule.
+func networkACLProtocol(protocol infrav1.SecurityGroupProtocol) string {
+	switch protocol {
+	case infrav1.SecurityGroupProtocolTCP:
+		return "6"
+	case infrav1.SecurityGroupProtocolUDP:
+		return "17"
+	case infrav1.SecurityGroupProtocolICMP:
+		return "1"
+	}
+	return string(protocol)
+}
+
+// networkACLRuleRanges returns the port range of a TCP or UDP rule, or the
+// ICMP type and code of an ICMP rule, which matches all of them.
+func networkACLRuleRanges(rule *infrav1.NetworkACLRule) (*ec2.PortRange, *ec2.IcmpTypeCode) {
+	switch rule.Protocol {
+	case infrav1.SecurityGroupProtocolTCP, infrav1.SecurityGroupProtocolUDP:
+		return &ec2.PortRange{From: aws.Int64(rule.FromPort), To: aws.Int64(rule.ToPort)}, nil
+	case infrav1.SecurityGroupProtocolICMP:
+		return nil, &ec2.IcmpTypeCode{Type: aws.Int64(-1), Code: aws.Int64(-1)}
+	}
+	return nil, nil
+}
+
+func networkACLEntryMatches(entry *ec2.NetworkAclEntry, rule *infrav1.NetworkACLRule) bool {
+	if aws.StringValue(entry.Protocol) != networkACLProtocol(rule.Protocol) ||
+		aws.StringValue(entry.RuleAction) != string(rule.Action) ||
+		aws.StringValue(entry.CidrBlock) != rule.CidrBlock {
+		return false
+	}
+
+	portRange, _ := networkACLRuleRanges(rule)
+	if portRange == nil {
+		return true
+	}
+
+	return entry.PortRange != nil &&
+		aws.Int64Value(entry.PortRange.From) == rule.FromPort &&
+		aws.Int64Value(entry.PortRange.To) == rule.ToPort
+}
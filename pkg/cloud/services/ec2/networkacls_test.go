@@ -0,0 +1,193 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ec2
+
+import (
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/golang/mock/gomock"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	infrav1 "sigs.k8s.io/cluster-api-provider-aws/api/v1alpha3"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/scope"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/services/ec2/mock_ec2iface"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1alpha3"
+)
+
+func TestReconcileNetworkACL(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	testCases := []struct {
+		name   string
+		spec   *infrav1.NetworkACLSpec
+		expect func(m *mock_ec2iface.MockEC2APIMockRecorder)
+	}{
+		{
+			name: "rules are created, replaced and deleted to match the spec",
+			spec: &infrav1.NetworkACLSpec{
+				Rules: []infrav1.NetworkACLRule{
+					{RuleNumber: 100, Protocol: infrav1.SecurityGroupProtocolTCP, FromPort: 443, ToPort: 443, CidrBlock: "0.0.0.0/0", Action: infrav1.NetworkACLRuleActionAllow},
+					{RuleNumber: 110, Protocol: infrav1.SecurityGroupProtocolTCP, FromPort: 22, ToPort: 22, CidrBlock: "10.0.0.0/8", Action: infrav1.NetworkACLRuleActionAllow},
+					{RuleNumber: 200, Protocol: infrav1.SecurityGroupProtocolAll, CidrBlock: "0.0.0.0/0", Action: infrav1.NetworkACLRuleActionDeny},
+				},
+			},
+			expect: func(m *mock_ec2iface.MockEC2APIMockRecorder) {
+				m.DescribeNetworkAcls(gomock.AssignableToTypeOf(&ec2.DescribeNetworkAclsInput{})).
+					Return(&ec2.DescribeNetworkAclsOutput{
+						NetworkAcls: []*ec2.NetworkAcl{
+							{
+								NetworkAclId: aws.String("acl-cluster"),
+								Entries: []*ec2.NetworkAclEntry{
+									{RuleNumber: aws.Int64(100), Egress: aws.Bool(false), Protocol: aws.String("6"), PortRange: &ec2.PortRange{From: aws.Int64(443), To: aws.Int64(443)}, CidrBlock: aws.String("0.0.0.0/0"), RuleAction: aws.String("allow")},
+									{RuleNumber: aws.Int64(110), Egress: aws.Bool(false), Protocol: aws.String("6"), PortRange: &ec2.PortRange{From: aws.Int64(22), To: aws.Int64(22)}, CidrBlock: aws.String("0.0.0.0/0"), RuleAction: aws.String("allow")},
+									{RuleNumber: aws.Int64(120), Egress: aws.Bool(true), Protocol: aws.String("-1"), CidrBlock: aws.String("0.0.0.0/0"), RuleAction: aws.String("allow")},
+									{RuleNumber: aws.Int64(32767), Egress: aws.Bool(false), Protocol: aws.String("-1"), CidrBlock: aws.String("0.0.0.0/0"), RuleAction: aws.String("deny")},
+								},
+								Associations: []*ec2.NetworkAclAssociation{
+									{NetworkAclAssociationId: aws.String("aclassoc-1"), SubnetId: aws.String("subnet-1")},
+								},
+							},
+						},
+					}, nil)
+				m.CreateTags(gomock.AssignableToTypeOf(&ec2.CreateTagsInput{})).
+					Return(&ec2.CreateTagsOutput{}, nil)
+				m.ReplaceNetworkAclEntry(gomock.Eq(&ec2.ReplaceNetworkAclEntryInput{
+					NetworkAclId: aws.String("acl-cluster"),
+					RuleNumber:   aws.Int64(110),
+					Egress:       aws.Bool(false),
+					Protocol:     aws.String("6"),
+					RuleAction:   aws.String("allow"),
+					CidrBlock:    aws.String("10.0.0.0/8"),
+					PortRange:    &ec2.PortRange{From: aws.Int64(22), To: aws.Int64(22)},
+				})).
+					Return(&ec2.ReplaceNetworkAclEntryOutput{}, nil)
+				m.CreateNetworkAclEntry(gomock.Eq(&ec2.CreateNetworkAclEntryInput{
+					NetworkAclId: aws.String("acl-cluster"),
+					RuleNumber:   aws.Int64(200),
+					Egress:       aws.Bool(false),
+					Protocol:     aws.String("-1"),
+					RuleAction:   aws.String("deny"),
+					CidrBlock:    aws.String("0.0.0.0/0"),
+				})).
+					Return(&ec2.CreateNetworkAclEntryOutput{}, nil)
+				m.DeleteNetworkAclEntry(gomock.Eq(&ec2.DeleteNetworkAclEntryInput{
+					NetworkAclId: aws.String("acl-cluster"),
+					RuleNumber:   aws.Int64(120),
+					Egress:       aws.Bool(true),
+				})).
+					Return(&ec2.DeleteNetworkAclEntryOutput{}, nil)
+				m.DescribeNetworkAcls(gomock.AssignableToTypeOf(&ec2.DescribeNetworkAclsInput{})).
+					Return(&ec2.DescribeNetworkAclsOutput{
+						NetworkAcls: []*ec2.NetworkAcl{
+							{
+								NetworkAclId: aws.String("acl-cluster"),
+								Associations: []*ec2.NetworkAclAssociation{
+									{NetworkAclAssociationId: aws.String("aclassoc-1"), SubnetId: aws.String("subnet-1")},
+								},
+							},
+							{
+								NetworkAclId: aws.String("acl-default"),
+								Associations: []*ec2.NetworkAclAssociation{
+									{NetworkAclAssociationId: aws.String("aclassoc-2"), SubnetId: aws.String("subnet-2")},
+								},
+							},
+						},
+					}, nil)
+				m.ReplaceNetworkAclAssociation(gomock.Eq(&ec2.ReplaceNetworkAclAssociationInput{
+					AssociationId: aws.String("aclassoc-2"),
+					NetworkAclId:  aws.String("acl-cluster"),
+				})).
+					Return(&ec2.ReplaceNetworkAclAssociationOutput{}, nil)
+			},
+		},
+		{
+			name: "network ACL is deleted when no longer specified",
+			expect: func(m *mock_ec2iface.MockEC2APIMockRecorder) {
+				m.DescribeNetworkAcls(gomock.AssignableToTypeOf(&ec2.DescribeNetworkAclsInput{})).
+					Return(&ec2.DescribeNetworkAclsOutput{
+						NetworkAcls: []*ec2.NetworkAcl{
+							{
+								NetworkAclId: aws.String("acl-cluster"),
+								Associations: []*ec2.NetworkAclAssociation{
+									{NetworkAclAssociationId: aws.String("aclassoc-1"), SubnetId: aws.String("subnet-1")},
+								},
+							},
+						},
+					}, nil)
+				m.DescribeNetworkAcls(gomock.AssignableToTypeOf(&ec2.DescribeNetworkAclsInput{})).
+					Return(&ec2.DescribeNetworkAclsOutput{
+						NetworkAcls: []*ec2.NetworkAcl{
+							{NetworkAclId: aws.String("acl-default")},
+						},
+					}, nil)
+				m.ReplaceNetworkAclAssociation(gomock.Eq(&ec2.ReplaceNetworkAclAssociationInput{
+					AssociationId: aws.String("aclassoc-1"),
+					NetworkAclId:  aws.String("acl-default"),
+				})).
+					Return(&ec2.ReplaceNetworkAclAssociationOutput{}, nil)
+				m.DeleteNetworkAcl(gomock.Eq(&ec2.DeleteNetworkAclInput{
+					NetworkAclId: aws.String("acl-cluster"),
+				})).
+					Return(&ec2.DeleteNetworkAclOutput{}, nil)
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ec2Mock := mock_ec2iface.NewMockEC2API(mockCtrl)
+
+			scope, err := scope.NewClusterScope(scope.ClusterScopeParams{
+				Cluster: &clusterv1.Cluster{
+					ObjectMeta: metav1.ObjectMeta{Name: "test-cluster"},
+				},
+				AWSClients: scope.AWSClients{
+					EC2: ec2Mock,
+				},
+				AWSCluster: &infrav1.AWSCluster{
+					Spec: infrav1.AWSClusterSpec{
+						NetworkSpec: infrav1.NetworkSpec{
+							VPC: infrav1.VPCSpec{
+								ID: "vpc-nacl",
+								Tags: infrav1.Tags{
+									infrav1.ClusterTagKey("test-cluster"): "owned",
+								},
+							},
+							Subnets: infrav1.Subnets{
+								{ID: "subnet-1"},
+								{ID: "subnet-2"},
+							},
+							NetworkACL: tc.spec,
+						},
+					},
+				},
+			})
+			if err != nil {
+				t.Fatalf("Failed to create test context: %v", err)
+			}
+
+			tc.expect(ec2Mock.EXPECT())
+
+			s := NewService(scope)
+			if err := s.reconcileNetworkACL(); err != nil {
+				t.Fatalf("got an unexpected error: %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,206 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ec2
+
+import (
+	"fmt"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/pkg/errors"
+	"k8s.io/apimachinery/pkg/util/sets"
+	infrav1 "sigs.k8s.io/cluster-api-provider-aws/api/v1alpha3"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/awserrors"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/filter"
+)
+
+// getNetworkInterfaceSpecs resolves the subnet and the security groups of
+// each network interface to their IDs. Interfaces without a subnet are
+// placed in the given default subnet.
+func (s *Service) getNetworkInterfaceSpecs(specs []infrav1.NetworkInterfaceSpec, defaultSubnetID string) ([]infrav1.NetworkInterfaceSpec, error) {
+	resolved := make([]infrav1.NetworkInterfaceSpec, 0, len(specs))
+
+	for _, spec := range specs {
+		subnetID := defaultSubnetID
+		if spec.Subnet != nil {
+			var err error
+			subnetID, err = s.getSubnetID(spec.Subnet)
+			if err != nil {
+				return nil, errors.Wrapf(err, "failed to resolve the subnet of network interface %d", spec.DeviceIndex)
+			}
+		}
+
+		groupIDs, err := s.getSecurityGroupIDs(spec.SecurityGroups)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to resolve the security groups of network interface %d", spec.DeviceIndex)
+		}
+
+		iface := infrav1.NetworkInterfaceSpec{
+			DeviceIndex:                    spec.DeviceIndex,
+			Subnet:                         &infrav1.AWSResourceReference{ID: aws.String(subnetID)},
+			PublicIP:                       spec.PublicIP,
+			SecondaryPrivateIPAddressCount: spec.SecondaryPrivateIPAddressCount,
+		}
+		for _, id := range groupIDs {
+			iface.SecurityGroups = append(iface.SecurityGroups, infrav1.AWSResourceReference{ID: aws.String(id)})
+		}
+		resolved = append(resolved, iface)
+	}
+
+	return resolved, nil
+}
+
+// applyPublicIP makes the primary network interface of the instance request a
+// public IP or not, overriding the MapPublicIpOnLaunch default of its subnet.
+// Instances are launched with an explicit primary interface for that. EC2
+// never assigns a public IP to an instance with several interfaces, so false
+// needs nothing then.
+func (s *Service) applyPublicIP(i *infrav1.Instance, publicIP bool) error {
+	if len(i.NetworkInterfaces) > 0 {
+		return errors.New("the public IP of existing network interfaces can't be set at launch")
+	}
+
+	if publicIP {
+		// Only known subnets can be checked, the cluster subnets are all
+		// described when reconciling the network.
+		if sn := s.scope.Subnets().FindByID(i.SubnetID); sn != nil && !sn.IsPublic {
+			return errors.Errorf("subnet %q has no route to an internet gateway, a public IP would not be reachable", i.SubnetID)
+		}
+	}
+
+	ensurePrimaryNetworkInterface(i)
+
+	if len(i.NetworkInterfaceSpecs) > 1 {
+		if publicIP {
+			return errors.New("a public IP can only be assigned to an instance with a single network interface")
+		}
+		return nil
+	}
+
+	i.NetworkInterfaceSpecs[0].PublicIP = aws.Bool(publicIP)
+	return nil
+}
+
+// applyNetworkInterfaceType sets the type of the network interfaces created
+// with the instance. Instances are launched with an explicit primary
+// interface for that.
+func applyNetworkInterfaceType(i *infrav1.Instance, ifaceType infrav1.NetworkInterfaceType) error {
+	if len(i.NetworkInterfaces) > 0 {
+		return errors.New("the type of existing network interfaces can't be set at launch")
+	}
+
+	ensurePrimaryNetworkInterface(i)
+	i.NetworkInterfaceType = ifaceType
+	return nil
+}
+
+// ensurePrimaryNetworkInterface gives an instance without network interface
+// specs a single primary interface in its subnet.
+func ensurePrimaryNetworkInterface(i *infrav1.Instance) {
+	if len(i.NetworkInterfaceSpecs) > 0 {
+		return
+	}
+
+	i.NetworkInterfaceSpecs = []infrav1.NetworkInterfaceSpec{
+		{
+			DeviceIndex: 0,
+			Subnet:      &infrav1.AWSResourceReference{ID: aws.String(i.SubnetID)},
+		},
+	}
+}
+
+// getSubnetID resolves a reference to a subnet of the cluster VPC to its ID.
+// Filters must match exactly one subnet.
+func (s *Service) getSubnetID(ref *infrav1.AWSResourceReference) (string, error) {
+	if ref.ID != nil {
+		return *ref.ID, nil
+	}
+
+	if len(ref.Filters) == 0 {
+		return "", errors.New("subnet has neither an id nor filters")
+	}
+
+	input := &ec2.DescribeSubnetsInput{
+		Filters: []*ec2.Filter{filter.EC2.VPC(s.scope.VPC().ID)},
+	}
+	for _, f := range ref.Filters {
+		input.Filters = append(input.Filters, &ec2.Filter{
+			Name:   aws.String(f.Name),
+			Values: aws.StringSlice(f.Values),
+		})
+	}
+
+	var out *ec2.DescribeSubnetsOutput
+	err := awserrors.RetryOnThrottling(func() (err error) {
+		out, err = s.scope.EC2.DescribeSubnets(input)
+		return err
+	})
+	if err != nil {
+		return "", errors.Wrapf(err, "failed to describe subnets matching filters %v", ref.Filters)
+	}
+
+	switch len(out.Subnets) {
+	case 0:
+		return "", errors.Errorf("no subnet matching filters %v found in vpc %q", ref.Filters, s.scope.VPC().ID)
+	case 1:
+		return aws.StringValue(out.Subnets[0].SubnetId), nil
+	default:
+		return "", errors.Errorf("%d subnets match filters %v in vpc %q, expected one", len(out.Subnets), ref.Filters, s.scope.VPC().ID)
+	}
+}
+
+// getNetworkInterfaceSpecifications returns the network interfaces to create
+// with the instance. Every interface gets the security groups of the instance
+// and its own.
+func getNetworkInterfaceSpecifications(i *infrav1.Instance) []*ec2.InstanceNetworkInterfaceSpecification {
+	specifications := make([]*ec2.InstanceNetworkInterfaceSpecification, 0, len(i.NetworkInterfaceSpecs))
+
+	for _, iface := range i.NetworkInterfaceSpecs {
+		groups := sets.NewString()
+		groupIDs := []string{}
+		for _, id := range i.SecurityGroupIDs {
+			if !groups.Has(id) {
+				groups.Insert(id)
+				groupIDs = append(groupIDs, id)
+			}
+		}
+		for _, ref := range iface.SecurityGroups {
+			if id := aws.StringValue(ref.ID); !groups.Has(id) {
+				groups.Insert(id)
+				groupIDs = append(groupIDs, id)
+			}
+		}
+
+		specification := &ec2.InstanceNetworkInterfaceSpecification{
+			DeviceIndex:              aws.Int64(iface.DeviceIndex),
+			SubnetId:                 iface.Subnet.ID,
+			Groups:                   aws.StringSlice(groupIDs),
+			AssociatePublicIpAddress: iface.PublicIP,
+			DeleteOnTermination:      aws.Bool(true),
+			Description:              aws.String(fmt.Sprintf("network interface %d", iface.DeviceIndex)),
+		}
+		if iface.SecondaryPrivateIPAddressCount > 0 {
+			specification.SecondaryPrivateIpAddressCount = aws.Int64(iface.SecondaryPrivateIPAddressCount)
+		}
+		if i.NetworkInterfaceType != "" {
+			specification.InterfaceType = aws.String(string(i.NetworkInterfaceType))
+		}
+		specifications = append(specifications, specification)
+	}
+
+	return specifications
+}
@@ -0,0 +1,292 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ec2
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/golang/mock/gomock"
+	infrav1 "sigs.k8s.io/cluster-api-provider-aws/api/v1alpha3"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/scope"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/services/ec2/mock_ec2iface"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1alpha3"
+)
+
+func TestGetNetworkInterfaceSpecs(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	testCases := []struct {
+		name    string
+		specs   []infrav1.NetworkInterfaceSpec
+		expect  func(m *mock_ec2iface.MockEC2APIMockRecorder)
+		want    []infrav1.NetworkInterfaceSpec
+		wantErr bool
+	}{
+		{
+			name: "primary interface defaults to the machine subnet",
+			specs: []infrav1.NetworkInterfaceSpec{
+				{DeviceIndex: 0},
+				{DeviceIndex: 1, Subnet: &infrav1.AWSResourceReference{ID: aws.String("subnet-storage")}},
+			},
+			expect: func(m *mock_ec2iface.MockEC2APIMockRecorder) {},
+			want: []infrav1.NetworkInterfaceSpec{
+				{DeviceIndex: 0, Subnet: &infrav1.AWSResourceReference{ID: aws.String("subnet-default")}},
+				{DeviceIndex: 1, Subnet: &infrav1.AWSResourceReference{ID: aws.String("subnet-storage")}},
+			},
+		},
+		{
+			name: "secondary private ip addresses are kept",
+			specs: []infrav1.NetworkInterfaceSpec{
+				{DeviceIndex: 0, SecondaryPrivateIPAddressCount: 14},
+			},
+			expect: func(m *mock_ec2iface.MockEC2APIMockRecorder) {},
+			want: []infrav1.NetworkInterfaceSpec{
+				{DeviceIndex: 0, Subnet: &infrav1.AWSResourceReference{ID: aws.String("subnet-default")}, SecondaryPrivateIPAddressCount: 14},
+			},
+		},
+		{
+			name: "subnet and security groups are resolved by filters",
+			specs: []infrav1.NetworkInterfaceSpec{
+				{
+					DeviceIndex:    0,
+					Subnet:         &infrav1.AWSResourceReference{Filters: []infrav1.Filter{{Name: "tag:role", Values: []string{"storage"}}}},
+					SecurityGroups: []infrav1.AWSResourceReference{{Filters: []infrav1.Filter{{Name: "tag:role", Values: []string{"storage"}}}}},
+				},
+			},
+			expect: func(m *mock_ec2iface.MockEC2APIMockRecorder) {
+				m.DescribeSubnets(gomock.Eq(&ec2.DescribeSubnetsInput{
+					Filters: []*ec2.Filter{
+						{Name: aws.String("vpc-id"), Values: aws.StringSlice([]string{"vpc-eni"})},
+						{Name: aws.String("tag:role"), Values: aws.StringSlice([]string{"storage"})},
+					},
+				})).
+					Return(&ec2.DescribeSubnetsOutput{Subnets: []*ec2.Subnet{{SubnetId: aws.String("subnet-storage")}}}, nil)
+				m.DescribeSecurityGroups(gomock.AssignableToTypeOf(&ec2.DescribeSecurityGroupsInput{})).
+					Return(&ec2.DescribeSecurityGroupsOutput{SecurityGroups: []*ec2.SecurityGroup{{GroupId: aws.String("sg-storage")}}}, nil)
+			},
+			want: []infrav1.NetworkInterfaceSpec{
+				{
+					DeviceIndex:    0,
+					Subnet:         &infrav1.AWSResourceReference{ID: aws.String("subnet-storage")},
+					SecurityGroups: []infrav1.AWSResourceReference{{ID: aws.String("sg-storage")}},
+				},
+			},
+		},
+		{
+			name: "subnet filters matching several subnets",
+			specs: []infrav1.NetworkInterfaceSpec{
+				{
+					DeviceIndex: 0,
+					Subnet:      &infrav1.AWSResourceReference{Filters: []infrav1.Filter{{Name: "tag:role", Values: []string{"storage"}}}},
+				},
+			},
+			expect: func(m *mock_ec2iface.MockEC2APIMockRecorder) {
+				m.DescribeSubnets(gomock.AssignableToTypeOf(&ec2.DescribeSubnetsInput{})).
+					Return(&ec2.DescribeSubnetsOutput{Subnets: []*ec2.Subnet{{SubnetId: aws.String("subnet-a")}, {SubnetId: aws.String("subnet-b")}}}, nil)
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ec2Mock := mock_ec2iface.NewMockEC2API(mockCtrl)
+
+			scope, err := scope.NewClusterScope(scope.ClusterScopeParams{
+				Cluster: &clusterv1.Cluster{},
+				AWSClients: scope.AWSClients{
+					EC2: ec2Mock,
+				},
+				AWSCluster: &infrav1.AWSCluster{
+					Spec: infrav1.AWSClusterSpec{
+						NetworkSpec: infrav1.NetworkSpec{
+							VPC: infrav1.VPCSpec{ID: "vpc-eni"},
+						},
+					},
+				},
+			})
+			if err != nil {
+				t.Fatalf("Failed to create test context: %v", err)
+			}
+
+			tc.expect(ec2Mock.EXPECT())
+
+			s := NewService(scope)
+			got, err := s.getNetworkInterfaceSpecs(tc.specs, "subnet-default")
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("getNetworkInterfaceSpecs() error = %v, wantErr %v", err, tc.wantErr)
+			}
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Fatalf("getNetworkInterfaceSpecs() = %+v, want %+v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestGetNetworkInterfaceSpecifications(t *testing.T) {
+	i := &infrav1.Instance{
+		SecurityGroupIDs: []string{"sg-node", "sg-lb"},
+		NetworkInterfaceSpecs: []infrav1.NetworkInterfaceSpec{
+			{
+				DeviceIndex: 0,
+				Subnet:      &infrav1.AWSResourceReference{ID: aws.String("subnet-default")},
+			},
+			{
+				DeviceIndex:                    1,
+				Subnet:                         &infrav1.AWSResourceReference{ID: aws.String("subnet-storage")},
+				SecurityGroups:                 []infrav1.AWSResourceReference{{ID: aws.String("sg-storage")}, {ID: aws.String("sg-node")}},
+				SecondaryPrivateIPAddressCount: 14,
+			},
+		},
+	}
+
+	got := getNetworkInterfaceSpecifications(i)
+	if len(got) != 2 {
+		t.Fatalf("expected 2 network interfaces, got %d", len(got))
+	}
+
+	if groups := aws.StringValueSlice(got[0].Groups); !reflect.DeepEqual(groups, []string{"sg-node", "sg-lb"}) {
+		t.Fatalf("unexpected security groups on the primary interface: %v", groups)
+	}
+	if groups := aws.StringValueSlice(got[1].Groups); !reflect.DeepEqual(groups, []string{"sg-node", "sg-lb", "sg-storage"}) {
+		t.Fatalf("unexpected security groups on the secondary interface: %v", groups)
+	}
+	if aws.Int64Value(got[1].DeviceIndex) != 1 || aws.StringValue(got[1].SubnetId) != "subnet-storage" {
+		t.Fatalf("unexpected secondary interface: %v", got[1])
+	}
+	if !aws.BoolValue(got[1].DeleteOnTermination) {
+		t.Fatal("expected network interfaces to be deleted with the instance")
+	}
+	if got[0].SecondaryPrivateIpAddressCount != nil || aws.Int64Value(got[1].SecondaryPrivateIpAddressCount) != 14 {
+		t.Fatalf("unexpected secondary private ip address counts: %v, %v", got[0].SecondaryPrivateIpAddressCount, got[1].SecondaryPrivateIpAddressCount)
+	}
+	if got[0].InterfaceType != nil {
+		t.Fatalf("expected no interface type by default, got %q", aws.StringValue(got[0].InterfaceType))
+	}
+}
+
+func TestApplyNetworkInterfaceType(t *testing.T) {
+	t.Run("efa on an instance without network interface specs", func(t *testing.T) {
+		i := &infrav1.Instance{SubnetID: "subnet-default"}
+		if err := applyNetworkInterfaceType(i, infrav1.NetworkInterfaceTypeEFA); err != nil {
+			t.Fatalf("applyNetworkInterfaceType() error = %v", err)
+		}
+
+		got := getNetworkInterfaceSpecifications(i)
+		if len(got) != 1 {
+			t.Fatalf("expected a primary network interface, got %d interfaces", len(got))
+		}
+		if aws.StringValue(got[0].SubnetId) != "subnet-default" || aws.StringValue(got[0].InterfaceType) != "efa" {
+			t.Fatalf("unexpected primary interface: %v", got[0])
+		}
+	})
+
+	t.Run("efa with existing network interfaces", func(t *testing.T) {
+		i := &infrav1.Instance{NetworkInterfaces: []string{"eni-1"}}
+		if err := applyNetworkInterfaceType(i, infrav1.NetworkInterfaceTypeEFA); err == nil {
+			t.Fatal("expected an error")
+		}
+	})
+}
+
+func TestApplyPublicIP(t *testing.T) {
+	testCases := []struct {
+		name     string
+		instance *infrav1.Instance
+		publicIP bool
+		want     []infrav1.NetworkInterfaceSpec
+		wantErr  bool
+	}{
+		{
+			name:     "no public ip overrides the subnet default",
+			instance: &infrav1.Instance{SubnetID: "subnet-public"},
+			publicIP: false,
+			want: []infrav1.NetworkInterfaceSpec{
+				{DeviceIndex: 0, Subnet: &infrav1.AWSResourceReference{ID: aws.String("subnet-public")}, PublicIP: aws.Bool(false)},
+			},
+		},
+		{
+			name:     "public ip in a public subnet",
+			instance: &infrav1.Instance{SubnetID: "subnet-public"},
+			publicIP: true,
+			want: []infrav1.NetworkInterfaceSpec{
+				{DeviceIndex: 0, Subnet: &infrav1.AWSResourceReference{ID: aws.String("subnet-public")}, PublicIP: aws.Bool(true)},
+			},
+		},
+		{
+			name:     "public ip in a private subnet",
+			instance: &infrav1.Instance{SubnetID: "subnet-private"},
+			publicIP: true,
+			wantErr:  true,
+		},
+		{
+			name: "no public ip with several network interfaces",
+			instance: &infrav1.Instance{
+				SubnetID: "subnet-public",
+				NetworkInterfaceSpecs: []infrav1.NetworkInterfaceSpec{
+					{DeviceIndex: 0, Subnet: &infrav1.AWSResourceReference{ID: aws.String("subnet-public")}},
+					{DeviceIndex: 1, Subnet: &infrav1.AWSResourceReference{ID: aws.String("subnet-private")}},
+				},
+			},
+			publicIP: false,
+			want: []infrav1.NetworkInterfaceSpec{
+				{DeviceIndex: 0, Subnet: &infrav1.AWSResourceReference{ID: aws.String("subnet-public")}},
+				{DeviceIndex: 1, Subnet: &infrav1.AWSResourceReference{ID: aws.String("subnet-private")}},
+			},
+		},
+		{
+			name:     "public ip with existing network interfaces",
+			instance: &infrav1.Instance{SubnetID: "subnet-public", NetworkInterfaces: []string{"eni-1"}},
+			publicIP: true,
+			wantErr:  true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			scope, err := scope.NewClusterScope(scope.ClusterScopeParams{
+				Cluster: &clusterv1.Cluster{},
+				AWSCluster: &infrav1.AWSCluster{
+					Spec: infrav1.AWSClusterSpec{
+						NetworkSpec: infrav1.NetworkSpec{
+							Subnets: infrav1.Subnets{
+								{ID: "subnet-public", IsPublic: true},
+								{ID: "subnet-private", IsPublic: false},
+							},
+						},
+					},
+				},
+			})
+			if err != nil {
+				t.Fatalf("Failed to create test context: %v", err)
+			}
+
+			s := NewService(scope)
+			err = s.applyPublicIP(tc.instance, tc.publicIP)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("applyPublicIP() error = %v, wantErr %v", err, tc.wantErr)
+			}
+			if err == nil && !reflect.DeepEqual(tc.instance.NetworkInterfaceSpecs, tc.want) {
+				t.Fatalf("applyPublicIP() = %+v, want %+v", tc.instance.NetworkInterfaceSpecs, tc.want)
+			}
+		})
+	}
+}
@@ -34,6 +34,7 @@ import (
 
 const (
 	anyIPv4CidrBlock       = "0.0.0.0/0"
+	anyIPv6CidrBlock       = "::/0"
 	mainRouteTableInVPCKey = "main"
 )
 
@@ -58,12 +59,33 @@ func (s *Service) reconcileRouteTables() error {
 				return errors.Errorf("failed to create routing tables: internet gateway for %q is nil", s.scope.VPC().ID)
 			}
 			routes = append(routes, s.getGatewayPublicRoute())
+			if s.scope.VPC().IsDualStack() {
+				routes = append(routes, s.getGatewayPublicIPv6Route())
+			}
 		} else {
-			natGatewayID, err := s.getNatGatewayForSubnet(sn)
-			if err != nil {
-				return err
+			if s.scope.NatGatewayMode() != infrav1.NatGatewayModeNone {
+				natGatewayID, err := s.getNatGatewayForSubnet(sn)
+				if err != nil {
+					return err
+				}
+				routes = append(routes, s.getNatGatewayPrivateRoute(natGatewayID))
+			}
+			if s.scope.VPC().IsDualStack() {
+				if s.scope.VPC().EgressOnlyInternetGatewayID == nil {
+					return errors.Errorf("failed to create routing tables: egress-only internet gateway for %q is nil", s.scope.VPC().ID)
+				}
+				routes = append(routes, s.getEgressOnlyGatewayPrivateRoute())
+			}
+			if tgw := s.scope.TransitGateway(); tgw != nil {
+				for _, cidr := range tgw.Routes {
+					routes = append(routes, s.getTransitGatewayPrivateRoute(tgw.ID, cidr))
+				}
+			}
+		}
+		if peering := s.scope.VPCPeering(); peering != nil && peering.ConnectionID != nil {
+			for _, cidr := range peering.PeerCidrBlocks {
+				routes = append(routes, s.getVPCPeeringRoute(*peering.ConnectionID, cidr))
 			}
-			routes = append(routes, s.getNatGatewayPrivateRoute(natGatewayID))
 		}
 
 		if rt, ok := subnetRouteMap[sn.ID]; ok {
@@ -77,16 +99,29 @@ func (s *Service) reconcileRouteTables() error {
 				for _, specRoute := range routes {
 					// Routes destination cidr blocks must be unique within a routing table.
 					// If there is a mistmatch, we replace the routing association.
-					if *currentRoute.DestinationCidrBlock == *specRoute.DestinationCidrBlock &&
-						((currentRoute.GatewayId != nil && *currentRoute.GatewayId != *specRoute.GatewayId) ||
-							(currentRoute.NatGatewayId != nil && *currentRoute.NatGatewayId != *specRoute.NatGatewayId)) {
+					if routeDestination(currentRoute) == routeDestination(specRoute) &&
+						((currentRoute.GatewayId != nil && *currentRoute.GatewayId != aws.StringValue(specRoute.GatewayId)) ||
+							(currentRoute.NatGatewayId != nil && *currentRoute.NatGatewayId != aws.StringValue(specRoute.NatGatewayId)) ||
+							(currentRoute.EgressOnlyInternetGatewayId != nil && *currentRoute.EgressOnlyInternetGatewayId != aws.StringValue(specRoute.EgressOnlyInternetGatewayId)) ||
+							(currentRoute.TransitGatewayId != nil && *currentRoute.TransitGatewayId != aws.StringValue(specRoute.TransitGatewayId)) ||
+							(currentRoute.VpcPeeringConnectionId != nil && *currentRoute.VpcPeeringConnectionId != aws.StringValue(specRoute.VpcPeeringConnectionId))) {
+
+						if specRoute.NatGatewayId != nil {
+							if err := s.waitForNatGatewayAvailable(*specRoute.NatGatewayId); err != nil {
+								return err
+							}
+						}
 
 						if err := wait.WaitForWithRetryable(wait.NewBackoff(), func() (bool, error) {
 							if _, err := s.scope.EC2.ReplaceRoute(&ec2.ReplaceRouteInput{
-								RouteTableId:         rt.RouteTableId,
-								DestinationCidrBlock: specRoute.DestinationCidrBlock,
-								GatewayId:            specRoute.GatewayId,
-								NatGatewayId:         specRoute.NatGatewayId,
+								RouteTableId:                rt.RouteTableId,
+								DestinationCidrBlock:        specRoute.DestinationCidrBlock,
+								DestinationIpv6CidrBlock:    specRoute.DestinationIpv6CidrBlock,
+								EgressOnlyInternetGatewayId: specRoute.EgressOnlyInternetGatewayId,
+								GatewayId:                   specRoute.GatewayId,
+								NatGatewayId:                specRoute.NatGatewayId,
+								TransitGatewayId:            specRoute.TransitGatewayId,
+								VpcPeeringConnectionId:      specRoute.VpcPeeringConnectionId,
 							}); err != nil {
 								return false, err
 							}
@@ -99,11 +134,49 @@ func (s *Service) reconcileRouteTables() error {
 				}
 			}
 
+			// Routes through NAT gateways that are no longer used, e.g. after
+			// disabling NAT gateways, would be left as blackholes. Routes
+			// through the transit gateway or the peering connection are
+			// removed along with their destination.
+		LoopStaleRoutes:
+			for _, currentRoute := range rt.Routes {
+				if currentRoute.NatGatewayId == nil && !s.isTransitGatewayRoute(currentRoute) && !s.isVPCPeeringRoute(currentRoute) {
+					continue
+				}
+				for _, specRoute := range routes {
+					if routeDestination(currentRoute) == routeDestination(specRoute) {
+						continue LoopStaleRoutes
+					}
+				}
+				if err := s.deleteRoute(*rt.RouteTableId, currentRoute); err != nil {
+					return err
+				}
+			}
+
+			// Route tables created before the VPC became dual-stack lack the
+			// IPv6 default route, and the transit gateway and peering routes
+			// can be changed at any time.
+		LoopMissingRoutes:
+			for _, specRoute := range routes {
+				if specRoute.DestinationIpv6CidrBlock == nil && specRoute.TransitGatewayId == nil && specRoute.VpcPeeringConnectionId == nil {
+					continue
+				}
+				for _, currentRoute := range rt.Routes {
+					if routeDestination(currentRoute) == routeDestination(specRoute) {
+						continue LoopMissingRoutes
+					}
+				}
+				if err := s.createRoute(*rt.RouteTableId, specRoute); err != nil {
+					return err
+				}
+			}
+
 			// Make sure tags are up to date.
 			if err := wait.WaitForWithRetryable(wait.NewBackoff(), func() (bool, error) {
 				if err := tags.Ensure(converters.TagsToMap(rt.Tags), &tags.ApplyParams{
 					EC2Client:   s.scope.EC2,
 					BuildParams: s.getRouteTableTagParams(*rt.RouteTableId, sn.IsPublic),
+					Removed:     s.scope.RemovedAdditionalTags(),
 				}); err != nil {
 					return false, err
 				}
@@ -119,6 +192,14 @@ func (s *Service) reconcileRouteTables() error {
 
 		// For each subnet that doesn't have a routing table associated with it,
 		// create a new table with the appropriate default routes and associate it to the subnet.
+		for _, route := range routes {
+			if route.NatGatewayId != nil {
+				if err := s.waitForNatGatewayAvailable(*route.NatGatewayId); err != nil {
+					return err
+				}
+			}
+		}
+
 		rt, err := s.createRouteTableWithRoutes(routes, sn.IsPublic)
 		if err != nil {
 			return err
@@ -247,27 +328,10 @@ func (s *Service) createRouteTableWithRoutes(routes []*ec2.Route, isPublic bool)
 	record.Eventf(s.scope.AWSCluster, "SuccessfulTagRouteTable", "Tagged managed RouteTable %q", *out.RouteTable.RouteTableId)
 
 	for _, route := range routes {
-		if err := wait.WaitForWithRetryable(wait.NewBackoff(), func() (bool, error) {
-			if _, err := s.scope.EC2.CreateRoute(&ec2.CreateRouteInput{
-				RouteTableId:                out.RouteTable.RouteTableId,
-				DestinationCidrBlock:        route.DestinationCidrBlock,
-				DestinationIpv6CidrBlock:    route.DestinationIpv6CidrBlock,
-				EgressOnlyInternetGatewayId: route.EgressOnlyInternetGatewayId,
-				GatewayId:                   route.GatewayId,
-				InstanceId:                  route.InstanceId,
-				NatGatewayId:                route.NatGatewayId,
-				NetworkInterfaceId:          route.NetworkInterfaceId,
-				VpcPeeringConnectionId:      route.VpcPeeringConnectionId,
-			}); err != nil {
-				return false, err
-			}
-			return true, nil
-		}, awserrors.RouteTableNotFound, awserrors.NATGatewayNotFound, awserrors.GatewayNotFound); err != nil {
+		if err := s.createRoute(*out.RouteTable.RouteTableId, route); err != nil {
 			// TODO(vincepri): cleanup the route table if this fails.
-			record.Warnf(s.scope.AWSCluster, "FailedCreateRoute", "Failed to create route %s for RouteTable %q: %v", route.GoString(), *out.RouteTable.RouteTableId, err)
-			return nil, errors.Wrapf(err, "failed to create route in route table %q: %s", *out.RouteTable.RouteTableId, route.GoString())
+			return nil, err
 		}
-		record.Eventf(s.scope.AWSCluster, "SuccessfulCreateRoute", "Created route %s for RouteTable %q", route.GoString(), *out.RouteTable.RouteTableId)
 	}
 
 	return &infrav1.RouteTable{
@@ -275,6 +339,44 @@ func (s *Service) createRouteTableWithRoutes(routes []*ec2.Route, isPublic bool)
 	}, nil
 }
 
+func (s *Service) createRoute(routeTableID string, route *ec2.Route) error {
+	if err := wait.WaitForWithRetryable(wait.NewBackoff(), func() (bool, error) {
+		if _, err := s.scope.EC2.CreateRoute(&ec2.CreateRouteInput{
+			RouteTableId:                aws.String(routeTableID),
+			DestinationCidrBlock:        route.DestinationCidrBlock,
+			DestinationIpv6CidrBlock:    route.DestinationIpv6CidrBlock,
+			EgressOnlyInternetGatewayId: route.EgressOnlyInternetGatewayId,
+			GatewayId:                   route.GatewayId,
+			InstanceId:                  route.InstanceId,
+			NatGatewayId:                route.NatGatewayId,
+			NetworkInterfaceId:          route.NetworkInterfaceId,
+			TransitGatewayId:            route.TransitGatewayId,
+			VpcPeeringConnectionId:      route.VpcPeeringConnectionId,
+		}); err != nil {
+			return false, err
+		}
+		return true, nil
+	}, awserrors.RouteTableNotFound, awserrors.NATGatewayNotFound, awserrors.GatewayNotFound); err != nil {
+		record.Warnf(s.scope.AWSCluster, "FailedCreateRoute", "Failed to create route %s for RouteTable %q: %v", route.GoString(), routeTableID, err)
+		return errors.Wrapf(err, "failed to create route in route table %q: %s", routeTableID, route.GoString())
+	}
+	record.Eventf(s.scope.AWSCluster, "SuccessfulCreateRoute", "Created route %s for RouteTable %q", route.GoString(), routeTableID)
+	return nil
+}
+
+func (s *Service) deleteRoute(routeTableID string, route *ec2.Route) error {
+	if _, err := s.scope.EC2.DeleteRoute(&ec2.DeleteRouteInput{
+		RouteTableId:             aws.String(routeTableID),
+		DestinationCidrBlock:     route.DestinationCidrBlock,
+		DestinationIpv6CidrBlock: route.DestinationIpv6CidrBlock,
+	}); err != nil && !awserrors.IsNotFound(err) {
+		record.Warnf(s.scope.AWSCluster, "FailedDeleteRoute", "Failed to delete route %s from RouteTable %q: %v", routeDestination(route), routeTableID, err)
+		return errors.Wrapf(err, "failed to delete route %s from route table %q", routeDestination(route), routeTableID)
+	}
+	record.Eventf(s.scope.AWSCluster, "SuccessfulDeleteRoute", "Deleted route %s from RouteTable %q", routeDestination(route), routeTableID)
+	return nil
+}
+
 func (s *Service) associateRouteTable(rt *infrav1.RouteTable, subnetID string) error {
 	_, err := s.scope.EC2.AssociateRouteTable(&ec2.AssociateRouteTableInput{
 		RouteTableId: aws.String(rt.ID),
@@ -304,6 +406,50 @@ func (s *Service) getGatewayPublicRoute() *ec2.Route {
 	}
 }
 
+func (s *Service) getGatewayPublicIPv6Route() *ec2.Route {
+	return &ec2.Route{
+		DestinationIpv6CidrBlock: aws.String(anyIPv6CidrBlock),
+		GatewayId:                aws.String(*s.scope.VPC().InternetGatewayID),
+	}
+}
+
+func (s *Service) getEgressOnlyGatewayPrivateRoute() *ec2.Route {
+	return &ec2.Route{
+		DestinationIpv6CidrBlock:    aws.String(anyIPv6CidrBlock),
+		EgressOnlyInternetGatewayId: aws.String(*s.scope.VPC().EgressOnlyInternetGatewayID),
+	}
+}
+
+func (s *Service) getTransitGatewayPrivateRoute(transitGatewayID, cidr string) *ec2.Route {
+	return &ec2.Route{
+		DestinationCidrBlock: aws.String(cidr),
+		TransitGatewayId:     aws.String(transitGatewayID),
+	}
+}
+
+func (s *Service) getVPCPeeringRoute(connectionID, cidr string) *ec2.Route {
+	return &ec2.Route{
+		DestinationCidrBlock:   aws.String(cidr),
+		VpcPeeringConnectionId: aws.String(connectionID),
+	}
+}
+
+// isTransitGatewayRoute returns whether the route goes through the configured
+// transit gateway. Routes through other transit gateways are left untouched,
+// they may have been added out of band.
+func (s *Service) isTransitGatewayRoute(route *ec2.Route) bool {
+	tgw := s.scope.TransitGateway()
+	return tgw != nil && aws.StringValue(route.TransitGatewayId) == tgw.ID
+}
+
+// routeDestination returns the IPv4 or IPv6 destination of a route.
+func routeDestination(route *ec2.Route) string {
+	if route.DestinationIpv6CidrBlock != nil {
+		return *route.DestinationIpv6CidrBlock
+	}
+	return aws.StringValue(route.DestinationCidrBlock)
+}
+
 func (s *Service) getRouteTableTagParams(id string, public bool) infrav1.BuildParams {
 	var name strings.Builder
 
@@ -20,6 +20,7 @@ import (
 	"fmt"
 
 	errlist "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/apimachinery/pkg/util/sets"
 	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/converters"
 	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/filter"
 	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/services/wait"
@@ -47,6 +48,22 @@ const (
 	IPProtocolICMPv6 = "58"
 )
 
+// managedIngressRuleDescriptions are the descriptions of the ingress rules
+// authorized by the controller. Only the rules carrying one of them are
+// revoked, rules authorized by others, e.g. the AWS load balancer controller,
+// are left untouched.
+var managedIngressRuleDescriptions = sets.NewString(
+	"SSH",
+	"Kubernetes API",
+	"etcd",
+	"etcd peer",
+	"bgp (calico)",
+	"IP-in-IP (calico)",
+	"Node Port Services",
+	"Kubelet API",
+	"HTTPS (VPC endpoints)",
+)
+
 func (s *Service) reconcileSecurityGroups() error {
 	s.scope.V(2).Info("Reconciling security groups")
 
@@ -66,6 +83,9 @@ func (s *Service) reconcileSecurityGroups() error {
 		infrav1.SecurityGroupControlPlane,
 		infrav1.SecurityGroupNode,
 	}
+	if s.hasInterfaceVPCEndpoints() {
+		roles = append(roles, infrav1.SecurityGroupVPCEndpoint)
+	}
 
 	// First iteration makes sure that the security group are valid and fully created.
 	for _, role := range roles {
@@ -93,6 +113,7 @@ func (s *Service) reconcileSecurityGroups() error {
 			if err := tags.Ensure(existing.Tags, &tags.ApplyParams{
 				EC2Client:   s.scope.EC2,
 				BuildParams: s.getSecurityGroupTagParams(existing.Name, existing.ID, role),
+				Removed:     s.scope.RemovedAdditionalTags(),
 			}); err != nil {
 				return false, err
 			}
@@ -109,14 +130,21 @@ func (s *Service) reconcileSecurityGroups() error {
 			// skip rule reconciliation, as we expect the in-cluster cloud integration to manage them
 			continue
 		}
-		current := sg.IngressRules
-
 		want, err := s.getSecurityGroupIngressRules(role)
 		if err != nil {
 			return err
 		}
 
-		toRevoke := current.Difference(want)
+		// EC2 merges the sources of all rules with the same protocol and
+		// ports, so rules are compared source by source.
+		want = expandIngressRules(want)
+
+		current, err := s.reconcileIngressRuleDescriptions(sg.ID, sg.IngressRules, want)
+		if err != nil {
+			return err
+		}
+
+		toRevoke := managedIngressRules(current.Difference(want))
 		if len(toRevoke) > 0 {
 			if err := wait.WaitForWithRetryable(wait.NewBackoff(), func() (bool, error) {
 				if err := s.revokeSecurityGroupIngressRules(sg.ID, toRevoke); err != nil {
@@ -241,7 +269,7 @@ func (s *Service) describeSecurityGroupsByName() (map[string]infrav1.SecurityGro
 		sg := makeInfraSecurityGroup(ec2sg)
 
 		for _, ec2rule := range ec2sg.IpPermissions {
-			sg.IngressRules = append(sg.IngressRules, ingressRuleFromSDKType(ec2rule))
+			sg.IngressRules = append(sg.IngressRules, ingressRulesFromSDKType(ec2rule)...)
 		}
 
 		res[sg.Name] = sg
@@ -308,6 +336,53 @@ func (s *Service) authorizeSecurityGroupIngressRules(id string, rules infrav1.In
 	return nil
 }
 
+// reconcileIngressRuleDescriptions updates the description of the current
+// rules which only differ from a wanted rule by their description, so that
+// fixing a missing or outdated description does not briefly revoke access.
+// It returns the current rules with the descriptions updated.
+func (s *Service) reconcileIngressRuleDescriptions(id string, current infrav1.IngressRules, want infrav1.IngressRules) (infrav1.IngressRules, error) {
+	res := make(infrav1.IngressRules, 0, len(current))
+	var toUpdate infrav1.IngressRules
+	for _, rule := range current {
+		for _, w := range want {
+			if rule.Description == w.Description {
+				continue
+			}
+			described := *rule
+			described.Description = w.Description
+			if described.Equals(w) {
+				toUpdate = append(toUpdate, w)
+				rule = w
+				break
+			}
+		}
+		res = append(res, rule)
+	}
+
+	if len(toUpdate) == 0 {
+		return res, nil
+	}
+
+	input := &ec2.UpdateSecurityGroupRuleDescriptionsIngressInput{GroupId: aws.String(id)}
+	for _, rule := range toUpdate {
+		input.IpPermissions = append(input.IpPermissions, ingressRuleToSDKType(rule))
+	}
+
+	if err := wait.WaitForWithRetryable(wait.NewBackoff(), func() (bool, error) {
+		if _, err := s.scope.EC2.UpdateSecurityGroupRuleDescriptionsIngress(input); err != nil {
+			return false, err
+		}
+		return true, nil
+	}, awserrors.GroupNotFound); err != nil {
+		record.Warnf(s.scope.AWSCluster, "FailedUpdateSecurityGroupRuleDescriptions", "Failed to update security group ingress rule descriptions %v for SecurityGroup %q: %v", toUpdate, id, err)
+		return nil, errors.Wrapf(err, "failed to update security group %q ingress rule descriptions: %v", id, toUpdate)
+	}
+
+	record.Eventf(s.scope.AWSCluster, "SuccessfulUpdateSecurityGroupRuleDescriptions", "Updated security group ingress rule descriptions %v for SecurityGroup %q", toUpdate, id)
+	s.scope.V(2).Info("Updated ingress rule descriptions in security group", "updated-ingress-rules", toUpdate, "security-group-id", id)
+	return res, nil
+}
+
 func (s *Service) revokeSecurityGroupIngressRules(id string, rules infrav1.IngressRules) error {
 	input := &ec2.RevokeSecurityGroupIngressInput{GroupId: aws.String(id)}
 	for _, rule := range rules {
@@ -358,28 +433,168 @@ func (s *Service) defaultSSHIngressRule(sourceSecurityGroupID string) *infrav1.I
 	}
 }
 
+// bastionSSHIngressRules returns the rules allowing SSH from the bastion host,
+// if the bastion host is enabled.
+func (s *Service) bastionSSHIngressRules() infrav1.IngressRules {
+	if !s.scope.BastionEnabled() {
+		return nil
+	}
+	return infrav1.IngressRules{
+		s.defaultSSHIngressRule(s.scope.SecurityGroups()[infrav1.SecurityGroupBastion].ID),
+	}
+}
+
+// bastionAllowedCIDRBlocks returns the CIDR blocks allowed to reach the bastion
+// host over SSH. A bastion host without a public IP is only reachable from
+// within the VPC, or through private connectivity from the configured blocks.
+func (s *Service) bastionAllowedCIDRBlocks() []string {
+	bastion := s.scope.AWSCluster.Spec.Bastion
+	if len(bastion.AllowedCIDRBlocks) > 0 {
+		return append([]string{}, bastion.AllowedCIDRBlocks...)
+	}
+	if !bastion.IsPublic() {
+		return []string{s.scope.VPC().CidrBlock}
+	}
+	return []string{anyIPv4CidrBlock}
+}
+
+// apiServerIngressRule returns the rule allowing access to the Kubernetes API server.
+// Unless ingress sources are configured on the control plane load balancer, any IPv4
+// address is allowed. Otherwise, the configured sources are allowed in addition to the
+// cluster's own security groups and NAT gateways. AWS merges rules for the same
+// protocol and port, so all sources are returned as a single rule.
+func (s *Service) apiServerIngressRule() (*infrav1.IngressRule, error) {
+	rule := &infrav1.IngressRule{
+		Description: "Kubernetes API",
+		Protocol:    infrav1.SecurityGroupProtocolTCP,
+		FromPort:    6443,
+		ToPort:      6443,
+	}
+
+	lb := s.scope.AWSCluster.Spec.ControlPlaneLoadBalancer
+	if lb == nil || len(lb.IngressSources) == 0 {
+		rule.CidrBlocks = []string{anyIPv4CidrBlock}
+		return rule, nil
+	}
+
+	cidrBlocks := sets.NewString()
+	prefixListIDs := sets.NewString()
+	securityGroupIDs := sets.NewString(
+		s.scope.SecurityGroups()[infrav1.SecurityGroupControlPlane].ID,
+		s.scope.SecurityGroups()[infrav1.SecurityGroupNode].ID,
+	)
+	for _, source := range lb.IngressSources {
+		switch {
+		case source.CidrBlock != "":
+			cidrBlocks.Insert(source.CidrBlock)
+		case source.PrefixListID != "":
+			prefixListIDs.Insert(source.PrefixListID)
+		case source.SecurityGroupID != "":
+			securityGroupIDs.Insert(source.SecurityGroupID)
+		}
+	}
+
+	// Network load balancers preserve the client IP, and health check the
+	// control plane instances from their addresses in the VPC.
+	if s.scope.ControlPlaneLoadBalancerType() == infrav1.LoadBalancerTypeNLB {
+		cidrBlocks.Insert(s.scope.VPC().CidrBlock)
+	}
+
+	// Traffic from nodes to an internet-facing load balancer leaves through the NAT gateways.
+	if lb.Scheme == nil || *lb.Scheme != infrav1.ClassicELBSchemeInternal {
+		gateways, err := s.describeNatGatewaysBySubnet()
+		if err != nil {
+			return nil, err
+		}
+		for _, gateway := range gateways {
+			for _, address := range gateway.NatGatewayAddresses {
+				if address.PublicIp != nil {
+					cidrBlocks.Insert(*address.PublicIp + "/32")
+				}
+			}
+		}
+	}
+
+	rule.CidrBlocks = cidrBlocks.List()
+	rule.PrefixListIDs = prefixListIDs.List()
+	rule.SourceSecurityGroupIDs = securityGroupIDs.List()
+	return rule, nil
+}
+
+// nodePortIngressRule returns the ingress rule for NodePort services on the node security group.
+// Unless configured otherwise, ports 30000-32767 are open to any IPv4 address.
+func (s *Service) nodePortIngressRule() *infrav1.IngressRule {
+	rule := &infrav1.IngressRule{
+		Description: "Node Port Services",
+		Protocol:    infrav1.SecurityGroupProtocolTCP,
+		FromPort:    30000,
+		ToPort:      32767,
+		CidrBlocks:  []string{anyIPv4CidrBlock},
+	}
+
+	if ingress := s.scope.NodeIngress(); ingress != nil && ingress.NodePort != nil {
+		applyNodeIngressRule(rule, ingress.NodePort)
+	}
+	return rule
+}
+
+// kubeletIngressRule returns the ingress rule for the kubelet API on the node security group.
+// Unless configured otherwise, port 10250 is open to the node security group, which is
+// needed to support metrics-server deployments. The control plane security group is
+// always allowed, as the API server needs to reach the kubelet.
+func (s *Service) kubeletIngressRule() *infrav1.IngressRule {
+	rule := &infrav1.IngressRule{
+		Description:            "Kubelet API",
+		Protocol:               infrav1.SecurityGroupProtocolTCP,
+		FromPort:               10250,
+		ToPort:                 10250,
+		SourceSecurityGroupIDs: []string{s.scope.SecurityGroups()[infrav1.SecurityGroupNode].ID},
+	}
+
+	if ingress := s.scope.NodeIngress(); ingress != nil && ingress.Kubelet != nil {
+		applyNodeIngressRule(rule, ingress.Kubelet)
+	}
+
+	controlPlaneID := s.scope.SecurityGroups()[infrav1.SecurityGroupControlPlane].ID
+	rule.SourceSecurityGroupIDs = sets.NewString(rule.SourceSecurityGroupIDs...).Insert(controlPlaneID).List()
+	return rule
+}
+
+// applyNodeIngressRule replaces the ports and sources of the rule with the configured ones, if set.
+func applyNodeIngressRule(rule *infrav1.IngressRule, config *infrav1.NodeIngressRule) {
+	if config.FromPort != 0 || config.ToPort != 0 {
+		rule.FromPort = config.FromPort
+		rule.ToPort = config.ToPort
+	}
+
+	if len(config.CidrBlocks) > 0 || len(config.SourceSecurityGroupIDs) > 0 {
+		rule.CidrBlocks = append([]string{}, config.CidrBlocks...)
+		rule.SourceSecurityGroupIDs = append([]string{}, config.SourceSecurityGroupIDs...)
+	}
+}
+
 func (s *Service) getSecurityGroupIngressRules(role infrav1.SecurityGroupRole) (infrav1.IngressRules, error) {
 	switch role {
 	case infrav1.SecurityGroupBastion:
+		if !s.scope.BastionEnabled() {
+			return infrav1.IngressRules{}, nil
+		}
 		return infrav1.IngressRules{
 			{
 				Description: "SSH",
 				Protocol:    infrav1.SecurityGroupProtocolTCP,
 				FromPort:    22,
 				ToPort:      22,
-				CidrBlocks:  []string{anyIPv4CidrBlock},
+				CidrBlocks:  s.bastionAllowedCIDRBlocks(),
 			},
 		}, nil
 	case infrav1.SecurityGroupControlPlane:
-		return infrav1.IngressRules{
-			s.defaultSSHIngressRule(s.scope.SecurityGroups()[infrav1.SecurityGroupBastion].ID),
-			{
-				Description: "Kubernetes API",
-				Protocol:    infrav1.SecurityGroupProtocolTCP,
-				FromPort:    6443,
-				ToPort:      6443,
-				CidrBlocks:  []string{anyIPv4CidrBlock},
-			},
+		apiServerRule, err := s.apiServerIngressRule()
+		if err != nil {
+			return nil, err
+		}
+		rules := infrav1.IngressRules{
+			apiServerRule,
 			{
 				Description:            "etcd",
 				Protocol:               infrav1.SecurityGroupProtocolTCP,
@@ -414,29 +629,13 @@ func (s *Service) getSecurityGroupIngressRules(role infrav1.SecurityGroupRole) (
 					s.scope.SecurityGroups()[infrav1.SecurityGroupNode].ID,
 				},
 			},
-		}, nil
+		}
+		return append(rules, s.bastionSSHIngressRules()...), nil
 
 	case infrav1.SecurityGroupNode:
-		return infrav1.IngressRules{
-			s.defaultSSHIngressRule(s.scope.SecurityGroups()[infrav1.SecurityGroupBastion].ID),
-			{
-				Description: "Node Port Services",
-				Protocol:    infrav1.SecurityGroupProtocolTCP,
-				FromPort:    30000,
-				ToPort:      32767,
-				CidrBlocks:  []string{anyIPv4CidrBlock},
-			},
-			{
-				Description: "Kubelet API",
-				Protocol:    infrav1.SecurityGroupProtocolTCP,
-				FromPort:    10250,
-				ToPort:      10250,
-				SourceSecurityGroupIDs: []string{
-					s.scope.SecurityGroups()[infrav1.SecurityGroupControlPlane].ID,
-					// This is needed to support metrics-server deployments
-					s.scope.SecurityGroups()[infrav1.SecurityGroupNode].ID,
-				},
-			},
+		rules := infrav1.IngressRules{
+			s.nodePortIngressRule(),
+			s.kubeletIngressRule(),
 			{
 				Description: "bgp (calico)",
 				Protocol:    infrav1.SecurityGroupProtocolTCP,
@@ -457,10 +656,24 @@ func (s *Service) getSecurityGroupIngressRules(role infrav1.SecurityGroupRole) (
 					s.scope.SecurityGroups()[infrav1.SecurityGroupControlPlane].ID,
 				},
 			},
-		}, nil
+		}
+		return append(rules, s.bastionSSHIngressRules()...), nil
 	case infrav1.SecurityGroupLB:
 		// We hand this group off to the in-cluster cloud provider, so these rules aren't used
 		return infrav1.IngressRules{}, nil
+	case infrav1.SecurityGroupVPCEndpoint:
+		return infrav1.IngressRules{
+			{
+				Description: "HTTPS (VPC endpoints)",
+				Protocol:    infrav1.SecurityGroupProtocolTCP,
+				FromPort:    443,
+				ToPort:      443,
+				SourceSecurityGroupIDs: []string{
+					s.scope.SecurityGroups()[infrav1.SecurityGroupControlPlane].ID,
+					s.scope.SecurityGroups()[infrav1.SecurityGroupNode].ID,
+				},
+			},
+		}, nil
 	}
 
 	return nil, errors.Errorf("Cannot determine ingress rules for unknown security group role %q", role)
@@ -539,6 +752,76 @@ func ingressRuleToSDKType(i *infrav1.IngressRule) (res *ec2.IpPermission) {
 		res.UserIdGroupPairs = append(res.UserIdGroupPairs, userIDGroupPair)
 	}
 
+	for _, prefixListID := range i.PrefixListIDs {
+		prefixList := &ec2.PrefixListId{
+			PrefixListId: aws.String(prefixListID),
+		}
+
+		if i.Description != "" {
+			prefixList.Description = aws.String(i.Description)
+		}
+
+		res.PrefixListIds = append(res.PrefixListIds, prefixList)
+	}
+
+	return res
+}
+
+// ingressRulesFromSDKType returns one rule per source of the permission, each
+// with the description of its own source.
+func ingressRulesFromSDKType(v *ec2.IpPermission) (res infrav1.IngressRules) {
+	for _, ec2range := range v.IpRanges {
+		res = append(res, ingressRuleFromSDKType(&ec2.IpPermission{
+			IpProtocol: v.IpProtocol,
+			FromPort:   v.FromPort,
+			ToPort:     v.ToPort,
+			IpRanges:   []*ec2.IpRange{ec2range},
+		}))
+	}
+
+	for _, pair := range v.UserIdGroupPairs {
+		if pair.GroupId == nil {
+			continue
+		}
+		res = append(res, ingressRuleFromSDKType(&ec2.IpPermission{
+			IpProtocol:       v.IpProtocol,
+			FromPort:         v.FromPort,
+			ToPort:           v.ToPort,
+			UserIdGroupPairs: []*ec2.UserIdGroupPair{pair},
+		}))
+	}
+
+	for _, prefixList := range v.PrefixListIds {
+		if prefixList.PrefixListId == nil {
+			continue
+		}
+		res = append(res, ingressRuleFromSDKType(&ec2.IpPermission{
+			IpProtocol:    v.IpProtocol,
+			FromPort:      v.FromPort,
+			ToPort:        v.ToPort,
+			PrefixListIds: []*ec2.PrefixListId{prefixList},
+		}))
+	}
+
+	return res
+}
+
+// expandIngressRules splits the rules into one rule per source, the way they
+// are read back from EC2.
+func expandIngressRules(rules infrav1.IngressRules) (res infrav1.IngressRules) {
+	for _, rule := range rules {
+		res = append(res, ingressRulesFromSDKType(ingressRuleToSDKType(rule))...)
+	}
+	return res
+}
+
+// managedIngressRules returns the rules authorized by the controller.
+func managedIngressRules(rules infrav1.IngressRules) (res infrav1.IngressRules) {
+	for _, rule := range rules {
+		if managedIngressRuleDescriptions.Has(rule.Description) {
+			res = append(res, rule)
+		}
+	}
 	return res
 }
 
@@ -563,10 +846,12 @@ func ingressRuleFromSDKType(v *ec2.IpPermission) (res *infrav1.IngressRule) {
 		}
 	}
 
+	// A rule is only described when all of its sources carry the same
+	// description, so that sources missing it are described again.
+	descriptions := sets.NewString()
+
 	for _, ec2range := range v.IpRanges {
-		if ec2range.Description != nil && *ec2range.Description != "" {
-			res.Description = *ec2range.Description
-		}
+		descriptions.Insert(aws.StringValue(ec2range.Description))
 
 		res.CidrBlocks = append(res.CidrBlocks, *ec2range.CidrIp)
 	}
@@ -576,12 +861,24 @@ func ingressRuleFromSDKType(v *ec2.IpPermission) (res *infrav1.IngressRule) {
 			continue
 		}
 
-		if pair.Description != nil && *pair.Description != "" {
-			res.Description = *pair.Description
-		}
+		descriptions.Insert(aws.StringValue(pair.Description))
 
 		res.SourceSecurityGroupIDs = append(res.SourceSecurityGroupIDs, *pair.GroupId)
 	}
 
+	for _, prefixList := range v.PrefixListIds {
+		if prefixList.PrefixListId == nil {
+			continue
+		}
+
+		descriptions.Insert(aws.StringValue(prefixList.Description))
+
+		res.PrefixListIDs = append(res.PrefixListIDs, *prefixList.PrefixListId)
+	}
+
+	if descriptions.Len() == 1 {
+		res.Description = descriptions.List()[0]
+	}
+
 	return res
 }
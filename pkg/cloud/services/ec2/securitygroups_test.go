@@ -205,6 +205,84 @@ func TestReconcileSecurityGroups(t *testing.T) {
 
 			},
 		},
+		{
+			name: "removed rule is revoked",
+			input: &infrav1.NetworkSpec{
+				VPC: infrav1.VPCSpec{
+					ID: "vpc-securitygroups",
+				},
+			},
+			expect: func(m *mock_ec2iface.MockEC2APIMockRecorder) {
+				m.DescribeSecurityGroups(gomock.AssignableToTypeOf(&ec2.DescribeSecurityGroupsInput{})).
+					Return(&ec2.DescribeSecurityGroupsOutput{
+						SecurityGroups: []*ec2.SecurityGroup{
+							{GroupId: aws.String("sg-bastion"), GroupName: aws.String("test-cluster-bastion")},
+							{GroupId: aws.String("sg-lb"), GroupName: aws.String("test-cluster-lb")},
+							{
+								GroupId:   aws.String("sg-control"),
+								GroupName: aws.String("test-cluster-controlplane"),
+								IpPermissions: []*ec2.IpPermission{
+									{
+										IpProtocol: aws.String("tcp"),
+										FromPort:   aws.Int64(6443),
+										ToPort:     aws.Int64(6443),
+										// The second source was removed from the spec.
+										IpRanges: []*ec2.IpRange{
+											{CidrIp: aws.String("203.0.113.0/24"), Description: aws.String("Kubernetes API")},
+											{CidrIp: aws.String("0.0.0.0/0"), Description: aws.String("Kubernetes API")},
+										},
+									},
+								},
+							},
+							{
+								GroupId:   aws.String("sg-node"),
+								GroupName: aws.String("test-cluster-node"),
+								IpPermissions: []*ec2.IpPermission{
+									{
+										IpProtocol: aws.String("tcp"),
+										FromPort:   aws.Int64(30000),
+										ToPort:     aws.Int64(32767),
+										IpRanges: []*ec2.IpRange{
+											{CidrIp: aws.String("0.0.0.0/0"), Description: aws.String("Node Port Services")},
+										},
+										// Authorized by the AWS load balancer controller.
+										UserIdGroupPairs: []*ec2.UserIdGroupPair{
+											{GroupId: aws.String("sg-elbv2"), Description: aws.String("elbv2.k8s.aws/targetGroupBinding=shared")},
+										},
+									},
+									{
+										IpProtocol: aws.String("tcp"),
+										FromPort:   aws.Int64(8080),
+										ToPort:     aws.Int64(8080),
+										IpRanges:   []*ec2.IpRange{{CidrIp: aws.String("10.0.0.0/8")}},
+									},
+								},
+							},
+						},
+					}, nil)
+
+				m.CreateTags(gomock.AssignableToTypeOf(&ec2.CreateTagsInput{})).
+					Return(nil, nil).AnyTimes()
+
+				m.AuthorizeSecurityGroupIngress(gomock.AssignableToTypeOf(&ec2.AuthorizeSecurityGroupIngressInput{})).
+					Return(&ec2.AuthorizeSecurityGroupIngressOutput{}, nil).AnyTimes()
+
+				// Only the removed source is revoked, the rules of others are untouched.
+				m.RevokeSecurityGroupIngress(gomock.Eq(&ec2.RevokeSecurityGroupIngressInput{
+					GroupId: aws.String("sg-control"),
+					IpPermissions: []*ec2.IpPermission{
+						{
+							IpProtocol: aws.String("tcp"),
+							FromPort:   aws.Int64(6443),
+							ToPort:     aws.Int64(6443),
+							IpRanges: []*ec2.IpRange{
+								{CidrIp: aws.String("203.0.113.0/24"), Description: aws.String("Kubernetes API")},
+							},
+						},
+					},
+				})).Return(&ec2.RevokeSecurityGroupIngressOutput{}, nil)
+			},
+		},
 	}
 
 	for _, tc := range testCases {
@@ -245,6 +323,342 @@ func TestReconcileSecurityGroups(t *testing.T) {
 	}
 }
 
+func TestAPIServerIngressRule(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	internal := infrav1.ClassicELBSchemeInternal
+
+	testCases := []struct {
+		name         string
+		lb           *infrav1.AWSLoadBalancerSpec
+		expect       func(m *mock_ec2iface.MockEC2APIMockRecorder)
+		expectedRule *infrav1.IngressRule
+	}{
+		{
+			name: "no ingress sources allows any IPv4 address",
+			lb:   &infrav1.AWSLoadBalancerSpec{},
+			expectedRule: &infrav1.IngressRule{
+				Description: "Kubernetes API",
+				Protocol:    infrav1.SecurityGroupProtocolTCP,
+				FromPort:    6443,
+				ToPort:      6443,
+				CidrBlocks:  []string{anyIPv4CidrBlock},
+			},
+		},
+		{
+			name: "internet-facing load balancer allows the sources and NAT gateways",
+			lb: &infrav1.AWSLoadBalancerSpec{
+				IngressSources: []infrav1.IngressSource{
+					{CidrBlock: "192.168.0.0/16"},
+					{PrefixListID: "pl-corp"},
+					{SecurityGroupID: "sg-peered"},
+				},
+			},
+			expect: func(m *mock_ec2iface.MockEC2APIMockRecorder) {
+				m.DescribeNatGatewaysPages(gomock.AssignableToTypeOf(&ec2.DescribeNatGatewaysInput{}), gomock.Any()).
+					Do(func(_ *ec2.DescribeNatGatewaysInput, fn func(*ec2.DescribeNatGatewaysOutput, bool) bool) {
+						fn(&ec2.DescribeNatGatewaysOutput{
+							NatGateways: []*ec2.NatGateway{
+								{
+									SubnetId: aws.String("subnet-1"),
+									NatGatewayAddresses: []*ec2.NatGatewayAddress{
+										{PublicIp: aws.String("203.0.113.10")},
+									},
+								},
+							},
+						}, true)
+					}).
+					Return(nil)
+			},
+			expectedRule: &infrav1.IngressRule{
+				Description:            "Kubernetes API",
+				Protocol:               infrav1.SecurityGroupProtocolTCP,
+				FromPort:               6443,
+				ToPort:                 6443,
+				CidrBlocks:             []string{"192.168.0.0/16", "203.0.113.10/32"},
+				PrefixListIDs:          []string{"pl-corp"},
+				SourceSecurityGroupIDs: []string{"sg-control", "sg-node", "sg-peered"},
+			},
+		},
+		{
+			name: "internal load balancer does not look up NAT gateways",
+			lb: &infrav1.AWSLoadBalancerSpec{
+				Scheme: &internal,
+				IngressSources: []infrav1.IngressSource{
+					{CidrBlock: "10.0.0.0/8"},
+				},
+			},
+			expectedRule: &infrav1.IngressRule{
+				Description:            "Kubernetes API",
+				Protocol:               infrav1.SecurityGroupProtocolTCP,
+				FromPort:               6443,
+				ToPort:                 6443,
+				CidrBlocks:             []string{"10.0.0.0/8"},
+				SourceSecurityGroupIDs: []string{"sg-control", "sg-node"},
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ec2Mock := mock_ec2iface.NewMockEC2API(mockCtrl)
+
+			scope, err := scope.NewClusterScope(scope.ClusterScopeParams{
+				Cluster: &clusterv1.Cluster{
+					ObjectMeta: metav1.ObjectMeta{Name: "test-cluster"},
+				},
+				AWSClients: scope.AWSClients{
+					EC2: ec2Mock,
+				},
+				AWSCluster: &infrav1.AWSCluster{
+					Spec: infrav1.AWSClusterSpec{
+						ControlPlaneLoadBalancer: tc.lb,
+					},
+					Status: infrav1.AWSClusterStatus{
+						Network: infrav1.Network{
+							SecurityGroups: map[infrav1.SecurityGroupRole]infrav1.SecurityGroup{
+								infrav1.SecurityGroupControlPlane: {ID: "sg-control"},
+								infrav1.SecurityGroupNode:         {ID: "sg-node"},
+							},
+						},
+					},
+				},
+			})
+			if err != nil {
+				t.Fatalf("Failed to create test context: %v", err)
+			}
+
+			if tc.expect != nil {
+				tc.expect(ec2Mock.EXPECT())
+			}
+
+			s := NewService(scope)
+			rule, err := s.apiServerIngressRule()
+			if err != nil {
+				t.Fatalf("got an unexpected error: %v", err)
+			}
+			if !rule.Equals(tc.expectedRule) {
+				t.Fatalf("expected rule %+v, got %+v", tc.expectedRule, rule)
+			}
+		})
+	}
+}
+
+func TestNodeIngressRules(t *testing.T) {
+	testCases := []struct {
+		name             string
+		ingress          *infrav1.NodeIngressSpec
+		expectedNodePort *infrav1.IngressRule
+		expectedKubelet  *infrav1.IngressRule
+	}{
+		{
+			name: "defaults are kept when unset",
+			expectedNodePort: &infrav1.IngressRule{
+				Description: "Node Port Services",
+				Protocol:    infrav1.SecurityGroupProtocolTCP,
+				FromPort:    30000,
+				ToPort:      32767,
+				CidrBlocks:  []string{anyIPv4CidrBlock},
+			},
+			expectedKubelet: &infrav1.IngressRule{
+				Description:            "Kubelet API",
+				Protocol:               infrav1.SecurityGroupProtocolTCP,
+				FromPort:               10250,
+				ToPort:                 10250,
+				SourceSecurityGroupIDs: []string{"sg-control", "sg-node"},
+			},
+		},
+		{
+			name: "configured ports and sources replace the defaults",
+			ingress: &infrav1.NodeIngressSpec{
+				NodePort: &infrav1.NodeIngressRule{
+					FromPort:   31000,
+					ToPort:     31999,
+					CidrBlocks: []string{"10.0.0.0/8"},
+				},
+				Kubelet: &infrav1.NodeIngressRule{
+					SourceSecurityGroupIDs: []string{"sg-monitoring"},
+				},
+			},
+			expectedNodePort: &infrav1.IngressRule{
+				Description: "Node Port Services",
+				Protocol:    infrav1.SecurityGroupProtocolTCP,
+				FromPort:    31000,
+				ToPort:      31999,
+				CidrBlocks:  []string{"10.0.0.0/8"},
+			},
+			expectedKubelet: &infrav1.IngressRule{
+				Description:            "Kubelet API",
+				Protocol:               infrav1.SecurityGroupProtocolTCP,
+				FromPort:               10250,
+				ToPort:                 10250,
+				SourceSecurityGroupIDs: []string{"sg-control", "sg-monitoring"},
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			scope, err := scope.NewClusterScope(scope.ClusterScopeParams{
+				Cluster: &clusterv1.Cluster{
+					ObjectMeta: metav1.ObjectMeta{Name: "test-cluster"},
+				},
+				AWSCluster: &infrav1.AWSCluster{
+					Spec: infrav1.AWSClusterSpec{
+						NetworkSpec: infrav1.NetworkSpec{
+							NodeIngress: tc.ingress,
+						},
+					},
+					Status: infrav1.AWSClusterStatus{
+						Network: infrav1.Network{
+							SecurityGroups: map[infrav1.SecurityGroupRole]infrav1.SecurityGroup{
+								infrav1.SecurityGroupControlPlane: {ID: "sg-control"},
+								infrav1.SecurityGroupNode:         {ID: "sg-node"},
+							},
+						},
+					},
+				},
+			})
+			if err != nil {
+				t.Fatalf("Failed to create test context: %v", err)
+			}
+
+			s := NewService(scope)
+			if rule := s.nodePortIngressRule(); !rule.Equals(tc.expectedNodePort) {
+				t.Fatalf("expected NodePort rule %+v, got %+v", tc.expectedNodePort, rule)
+			}
+			if rule := s.kubeletIngressRule(); !rule.Equals(tc.expectedKubelet) {
+				t.Fatalf("expected kubelet rule %+v, got %+v", tc.expectedKubelet, rule)
+			}
+		})
+	}
+}
+
+func TestSecurityGroupIngressRuleDescriptions(t *testing.T) {
+	scope, err := scope.NewClusterScope(scope.ClusterScopeParams{
+		Cluster: &clusterv1.Cluster{
+			ObjectMeta: metav1.ObjectMeta{Name: "test-cluster"},
+		},
+		AWSCluster: &infrav1.AWSCluster{
+			Status: infrav1.AWSClusterStatus{
+				Network: infrav1.Network{
+					SecurityGroups: map[infrav1.SecurityGroupRole]infrav1.SecurityGroup{
+						infrav1.SecurityGroupBastion:      {ID: "sg-bastion"},
+						infrav1.SecurityGroupControlPlane: {ID: "sg-control"},
+						infrav1.SecurityGroupNode:         {ID: "sg-node"},
+					},
+				},
+			},
+		},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create test context: %v", err)
+	}
+
+	s := NewService(scope)
+	for _, role := range []infrav1.SecurityGroupRole{
+		infrav1.SecurityGroupBastion,
+		infrav1.SecurityGroupControlPlane,
+		infrav1.SecurityGroupNode,
+	} {
+		rules, err := s.getSecurityGroupIngressRules(role)
+		if err != nil {
+			t.Fatalf("got an unexpected error: %v", err)
+		}
+		for _, rule := range rules {
+			if rule.Description == "" {
+				t.Errorf("expected a description on %s rule %v", role, rule)
+			}
+			for _, ipRange := range ingressRuleToSDKType(rule).IpRanges {
+				if aws.StringValue(ipRange.Description) == "" {
+					t.Errorf("expected a description on %s rule %v cidr block %s", role, rule, aws.StringValue(ipRange.CidrIp))
+				}
+			}
+			for _, pair := range ingressRuleToSDKType(rule).UserIdGroupPairs {
+				if aws.StringValue(pair.Description) == "" {
+					t.Errorf("expected a description on %s rule %v source %s", role, rule, aws.StringValue(pair.GroupId))
+				}
+			}
+		}
+	}
+}
+
+func TestReconcileIngressRuleDescriptions(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	ec2Mock := mock_ec2iface.NewMockEC2API(mockCtrl)
+
+	scope, err := scope.NewClusterScope(scope.ClusterScopeParams{
+		Cluster: &clusterv1.Cluster{
+			ObjectMeta: metav1.ObjectMeta{Name: "test-cluster"},
+		},
+		AWSClients: scope.AWSClients{
+			EC2: ec2Mock,
+		},
+		AWSCluster: &infrav1.AWSCluster{},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create test context: %v", err)
+	}
+
+	want := infrav1.IngressRules{
+		{
+			Description: "Node Port Services",
+			Protocol:    infrav1.SecurityGroupProtocolTCP,
+			FromPort:    30000,
+			ToPort:      32767,
+			CidrBlocks:  []string{anyIPv4CidrBlock},
+		},
+		{
+			Description:            "Kubelet API",
+			Protocol:               infrav1.SecurityGroupProtocolTCP,
+			FromPort:               10250,
+			ToPort:                 10250,
+			SourceSecurityGroupIDs: []string{"sg-node"},
+		},
+	}
+
+	// The kubelet rule was authorized without a description.
+	current := infrav1.IngressRules{
+		ingressRuleFromSDKType(ingressRuleToSDKType(want[0])),
+		ingressRuleFromSDKType(&ec2.IpPermission{
+			IpProtocol:       aws.String("tcp"),
+			FromPort:         aws.Int64(10250),
+			ToPort:           aws.Int64(10250),
+			UserIdGroupPairs: []*ec2.UserIdGroupPair{{GroupId: aws.String("sg-node")}},
+		}),
+	}
+
+	ec2Mock.EXPECT().UpdateSecurityGroupRuleDescriptionsIngress(gomock.Eq(&ec2.UpdateSecurityGroupRuleDescriptionsIngressInput{
+		GroupId: aws.String("sg-node"),
+		IpPermissions: []*ec2.IpPermission{
+			{
+				IpProtocol: aws.String("tcp"),
+				FromPort:   aws.Int64(10250),
+				ToPort:     aws.Int64(10250),
+				UserIdGroupPairs: []*ec2.UserIdGroupPair{
+					{GroupId: aws.String("sg-node"), Description: aws.String("Kubelet API")},
+				},
+			},
+		},
+	})).Return(&ec2.UpdateSecurityGroupRuleDescriptionsIngressOutput{}, nil)
+
+	s := NewService(scope)
+	updated, err := s.reconcileIngressRuleDescriptions("sg-node", current, want)
+	if err != nil {
+		t.Fatalf("got an unexpected error: %v", err)
+	}
+
+	// Nothing is left to revoke or authorize once the description is updated.
+	if diff := updated.Difference(want); len(diff) > 0 {
+		t.Fatalf("expected no rules to revoke, got %v", diff)
+	}
+	if diff := want.Difference(updated); len(diff) > 0 {
+		t.Fatalf("expected no rules to authorize, got %v", diff)
+	}
+}
+
 func matchesTags(input *ec2.CreateTagsInput) gomock.Matcher {
 	return tagMatcher{input}
 }
@@ -286,3 +700,65 @@ func (t tagMatcher) Matches(x interface{}) bool {
 func (t tagMatcher) String() string {
 	return fmt.Sprintf("matches %v", t.CreateTagsInput)
 }
+
+func TestBastionIngressRule(t *testing.T) {
+	testCases := []struct {
+		name               string
+		bastion            infrav1.Bastion
+		expectedCidrBlocks []string
+	}{
+		{
+			name:               "public bastion is reachable from anywhere",
+			bastion:            infrav1.Bastion{},
+			expectedCidrBlocks: []string{anyIPv4CidrBlock},
+		},
+		{
+			name:               "private bastion is only reachable from the vpc",
+			bastion:            infrav1.Bastion{PublicIP: aws.Bool(false)},
+			expectedCidrBlocks: []string{"10.0.0.0/16"},
+		},
+		{
+			name:               "public bastion with allowed cidr blocks",
+			bastion:            infrav1.Bastion{AllowedCIDRBlocks: []string{"203.0.113.0/24"}},
+			expectedCidrBlocks: []string{"203.0.113.0/24"},
+		},
+		{
+			name:               "private bastion with allowed cidr blocks",
+			bastion:            infrav1.Bastion{PublicIP: aws.Bool(false), AllowedCIDRBlocks: []string{"10.0.0.0/16", "172.16.0.0/12"}},
+			expectedCidrBlocks: []string{"10.0.0.0/16", "172.16.0.0/12"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			scope, err := scope.NewClusterScope(scope.ClusterScopeParams{
+				Cluster: &clusterv1.Cluster{
+					ObjectMeta: metav1.ObjectMeta{Name: "test-cluster"},
+				},
+				AWSCluster: &infrav1.AWSCluster{
+					Spec: infrav1.AWSClusterSpec{
+						NetworkSpec: infrav1.NetworkSpec{
+							VPC: infrav1.VPCSpec{CidrBlock: "10.0.0.0/16"},
+						},
+						Bastion: tc.bastion,
+					},
+				},
+			})
+			if err != nil {
+				t.Fatalf("Failed to create test context: %v", err)
+			}
+
+			s := NewService(scope)
+			rules, err := s.getSecurityGroupIngressRules(infrav1.SecurityGroupBastion)
+			if err != nil {
+				t.Fatalf("got an unexpected error: %v", err)
+			}
+			if len(rules) != 1 || rules[0].FromPort != 22 {
+				t.Fatalf("expected a single SSH ingress rule, got %v", rules)
+			}
+			if !reflect.DeepEqual(rules[0].CidrBlocks, tc.expectedCidrBlocks) {
+				t.Fatalf("expected cidr blocks %v, got %v", tc.expectedCidrBlocks, rules[0].CidrBlocks)
+			}
+		})
+	}
+}
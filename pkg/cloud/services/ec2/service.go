@@ -17,19 +17,42 @@ limitations under the License.
 package ec2
 
 import (
+	"time"
+
 	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/scope"
 )
 
+const (
+	// DefaultInstanceReadyTimeout is how long CreateInstance waits by default
+	// for a new instance to be running.
+	DefaultInstanceReadyTimeout = 1 * time.Minute
+
+	// DefaultInstanceReadyPollInterval is how often the state of a new
+	// instance is checked by default while waiting for it to be running.
+	DefaultInstanceReadyPollInterval = 15 * time.Second
+)
+
 // Service holds a collection of interfaces.
 // The interfaces are broken down like this to group functions together.
 // One alternative is to have a large list of functions from the ec2 client.
 type Service struct {
 	scope *scope.ClusterScope
+
+	// InstanceReadyTimeout is how long CreateInstance waits for a new
+	// instance to be running before returning it in the pending state, to be
+	// checked again by a later reconcile.
+	InstanceReadyTimeout time.Duration
+
+	// InstanceReadyPollInterval is how often the state of a new instance is
+	// checked while waiting for it to be running.
+	InstanceReadyPollInterval time.Duration
 }
 
 // NewService returns a new service given the ec2 api client.
 func NewService(scope *scope.ClusterScope) *Service {
 	return &Service{
-		scope: scope,
+		scope:                     scope,
+		InstanceReadyTimeout:      DefaultInstanceReadyTimeout,
+		InstanceReadyPollInterval: DefaultInstanceReadyPollInterval,
 	}
 }
@@ -0,0 +1,87 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ec2
+
+import (
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/pkg/errors"
+	infrav1 "sigs.k8s.io/cluster-api-provider-aws/api/v1alpha3"
+)
+
+// reconcileSharedNetwork looks up the subnets shared with the cluster's
+// account through AWS Resource Access Manager, and records them and their VPC
+// in the cluster spec. The VPC is owned by another account, nothing is
+// created, modified or tagged in it.
+func (s *Service) reconcileSharedNetwork() error {
+	s.scope.V(2).Info("Reconciling shared network")
+
+	refs := s.scope.AWSCluster.Spec.NetworkSpec.SharedSubnets
+	ids := make([]*string, 0, len(refs))
+	for _, ref := range refs {
+		ids = append(ids, aws.String(ref.SubnetID()))
+	}
+
+	out, err := s.scope.EC2.DescribeSubnets(&ec2.DescribeSubnetsInput{SubnetIds: ids})
+	if err != nil {
+		return errors.Wrap(err, "failed to describe shared subnets")
+	}
+
+	found := make(map[string]*ec2.Subnet, len(out.Subnets))
+	for _, ec2sn := range out.Subnets {
+		found[aws.StringValue(ec2sn.SubnetId)] = ec2sn
+	}
+
+	vpcID := ""
+	subnets := make(infrav1.Subnets, 0, len(refs))
+	for _, ref := range refs {
+		ec2sn, ok := found[ref.SubnetID()]
+		if !ok {
+			return errors.Errorf("shared subnet %q was not found, make sure it is shared with this account", ref.ID)
+		}
+
+		switch {
+		case vpcID == "":
+			vpcID = aws.StringValue(ec2sn.VpcId)
+		case vpcID != aws.StringValue(ec2sn.VpcId):
+			return errors.Errorf("shared subnets must all be in the same VPC, found %q and %q", vpcID, aws.StringValue(ec2sn.VpcId))
+		}
+
+		subnets = append(subnets, &infrav1.SubnetSpec{
+			ID:               aws.StringValue(ec2sn.SubnetId),
+			CidrBlock:        aws.StringValue(ec2sn.CidrBlock),
+			AvailabilityZone: aws.StringValue(ec2sn.AvailabilityZone),
+			IsPublic:         ref.IsPublic,
+		})
+	}
+
+	if id := s.scope.VPC().ID; id != "" && id != vpcID {
+		return errors.Errorf("shared subnets are in VPC %q, but the cluster uses VPC %q", vpcID, id)
+	}
+	s.scope.VPC().ID = vpcID
+
+	vpc, err := s.describeVPC()
+	if err != nil {
+		return errors.Wrapf(err, "failed to describe shared vpc %q", vpcID)
+	}
+	s.scope.VPC().CidrBlock = vpc.CidrBlock
+	s.scope.VPC().Tags = vpc.Tags
+	s.scope.AWSCluster.Spec.NetworkSpec.Subnets = subnets
+
+	s.scope.V(2).Info("Working on shared VPC", "vpc-id", vpcID)
+	return nil
+}
@@ -0,0 +1,177 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ec2
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/golang/mock/gomock"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	infrav1 "sigs.k8s.io/cluster-api-provider-aws/api/v1alpha3"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/scope"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/services/ec2/mock_ec2iface"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1alpha3"
+)
+
+func newSharedNetworkScope(t *testing.T, ec2Mock *mock_ec2iface.MockEC2API, refs []infrav1.SharedSubnetReference) *scope.ClusterScope {
+	scope, err := scope.NewClusterScope(scope.ClusterScopeParams{
+		Cluster: &clusterv1.Cluster{
+			ObjectMeta: metav1.ObjectMeta{Name: "test-cluster"},
+		},
+		AWSClients: scope.AWSClients{
+			EC2: ec2Mock,
+		},
+		AWSCluster: &infrav1.AWSCluster{
+			Spec: infrav1.AWSClusterSpec{
+				NetworkSpec: infrav1.NetworkSpec{
+					SharedSubnets: refs,
+				},
+			},
+			Status: infrav1.AWSClusterStatus{
+				Network: infrav1.Network{
+					SecurityGroups: map[infrav1.SecurityGroupRole]infrav1.SecurityGroup{
+						infrav1.SecurityGroupNode: {ID: "sg-node"},
+					},
+				},
+			},
+		},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create test context: %v", err)
+	}
+	return scope
+}
+
+func TestReconcileSharedNetwork(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	refs := []infrav1.SharedSubnetReference{
+		{ID: "arn:aws:ec2:us-east-1:123456789012:subnet/subnet-public", IsPublic: true},
+		{ID: "subnet-private"},
+	}
+
+	testCases := []struct {
+		name    string
+		expect  func(m *mock_ec2iface.MockEC2APIMockRecorder)
+		want    infrav1.Subnets
+		wantErr bool
+	}{
+		{
+			name: "shared subnets and their vpc are recorded",
+			expect: func(m *mock_ec2iface.MockEC2APIMockRecorder) {
+				m.DescribeSubnets(gomock.Eq(&ec2.DescribeSubnetsInput{
+					SubnetIds: aws.StringSlice([]string{"subnet-public", "subnet-private"}),
+				})).
+					Return(&ec2.DescribeSubnetsOutput{
+						Subnets: []*ec2.Subnet{
+							{SubnetId: aws.String("subnet-private"), VpcId: aws.String("vpc-shared"), CidrBlock: aws.String("10.0.0.0/24"), AvailabilityZone: aws.String("us-east-1a")},
+							{SubnetId: aws.String("subnet-public"), VpcId: aws.String("vpc-shared"), CidrBlock: aws.String("10.0.1.0/24"), AvailabilityZone: aws.String("us-east-1a")},
+						},
+					}, nil)
+				m.DescribeVpcs(gomock.AssignableToTypeOf(&ec2.DescribeVpcsInput{})).
+					Return(&ec2.DescribeVpcsOutput{
+						Vpcs: []*ec2.Vpc{
+							{VpcId: aws.String("vpc-shared"), CidrBlock: aws.String("10.0.0.0/16"), State: aws.String(ec2.VpcStateAvailable)},
+						},
+					}, nil)
+			},
+			want: infrav1.Subnets{
+				{ID: "subnet-public", CidrBlock: "10.0.1.0/24", AvailabilityZone: "us-east-1a", IsPublic: true},
+				{ID: "subnet-private", CidrBlock: "10.0.0.0/24", AvailabilityZone: "us-east-1a"},
+			},
+		},
+		{
+			name: "subnet that is not shared with the account",
+			expect: func(m *mock_ec2iface.MockEC2APIMockRecorder) {
+				m.DescribeSubnets(gomock.AssignableToTypeOf(&ec2.DescribeSubnetsInput{})).
+					Return(&ec2.DescribeSubnetsOutput{
+						Subnets: []*ec2.Subnet{
+							{SubnetId: aws.String("subnet-private"), VpcId: aws.String("vpc-shared")},
+						},
+					}, nil)
+			},
+			wantErr: true,
+		},
+		{
+			name: "subnets in different vpcs",
+			expect: func(m *mock_ec2iface.MockEC2APIMockRecorder) {
+				m.DescribeSubnets(gomock.AssignableToTypeOf(&ec2.DescribeSubnetsInput{})).
+					Return(&ec2.DescribeSubnetsOutput{
+						Subnets: []*ec2.Subnet{
+							{SubnetId: aws.String("subnet-public"), VpcId: aws.String("vpc-shared")},
+							{SubnetId: aws.String("subnet-private"), VpcId: aws.String("vpc-other")},
+						},
+					}, nil)
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ec2Mock := mock_ec2iface.NewMockEC2API(mockCtrl)
+			scope := newSharedNetworkScope(t, ec2Mock, refs)
+
+			tc.expect(ec2Mock.EXPECT())
+
+			s := NewService(scope)
+			err := s.reconcileSharedNetwork()
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("reconcileSharedNetwork() error = %v, wantErr %v", err, tc.wantErr)
+			}
+			if tc.wantErr {
+				return
+			}
+
+			if scope.VPC().ID != "vpc-shared" || scope.VPC().CidrBlock != "10.0.0.0/16" {
+				t.Errorf("expected the shared vpc to be recorded, got %+v", scope.VPC())
+			}
+			if got := scope.Subnets(); !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("expected subnets %v, got %v", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestDeleteSharedNetwork(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	ec2Mock := mock_ec2iface.NewMockEC2API(mockCtrl)
+	scope := newSharedNetworkScope(t, ec2Mock, []infrav1.SharedSubnetReference{{ID: "subnet-private"}})
+	scope.VPC().ID = "vpc-shared"
+	scope.AWSCluster.Spec.NetworkSpec.Subnets = infrav1.Subnets{{ID: "subnet-private"}}
+
+	// Only the security groups of the cluster are deleted, any other call
+	// to the mock fails the test.
+	m := ec2Mock.EXPECT()
+	m.DescribeSecurityGroups(gomock.Eq(&ec2.DescribeSecurityGroupsInput{GroupIds: aws.StringSlice([]string{"sg-node"})})).
+		Return(&ec2.DescribeSecurityGroupsOutput{}, nil)
+	m.DeleteSecurityGroup(gomock.Eq(&ec2.DeleteSecurityGroupInput{GroupId: aws.String("sg-node")})).
+		Return(&ec2.DeleteSecurityGroupOutput{}, nil)
+	m.DescribeSecurityGroupsPages(gomock.AssignableToTypeOf(&ec2.DescribeSecurityGroupsInput{}), gomock.Any()).
+		Return(nil)
+
+	s := NewService(scope)
+	if err := s.DeleteNetwork(); err != nil {
+		t.Fatalf("got an unexpected error: %v", err)
+	}
+}
@@ -17,6 +17,7 @@ limitations under the License.
 package ec2
 
 import (
+	"net"
 	"strings"
 
 	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/awserrors"
@@ -49,24 +50,33 @@ func (s *Service) reconcileSubnets() error {
 	}()
 
 	// Describe subnets in the vpc.
-	existing, err := s.describeVpcSubnets()
+	out, err := s.describeSubnets()
 	if err != nil {
 		return err
 	}
 
+	existing, err := s.subnetsFromSDKTypes(out.Subnets)
+	if err != nil {
+		return err
+	}
+
+	mapPublicIPOnLaunch := make(map[string]*bool, len(out.Subnets))
+	for _, ec2sn := range out.Subnets {
+		mapPublicIPOnLaunch[*ec2sn.SubnetId] = ec2sn.MapPublicIpOnLaunch
+	}
+
 	// If the subnets are empty, populate the slice with the default configuration.
 	// Adds a single private and public subnet in the first available zone.
-	if len(existing) < 2 && len(subnets) < 2 {
+	// Subnets reserved for pods don't count, they can't hold instances.
+	// Unmanaged networks are never extended, missing subnets are reported by
+	// validateUnmanagedNetwork instead.
+	if !s.scope.VPC().IsUnmanaged(s.scope.Name()) && len(existing)-len(existing.FilterPods()) < 2 && len(subnets)-len(subnets.FilterPods()) < 2 {
 		zones, err := s.getAvailableZones()
 		if err != nil {
 			return err
 		}
 
 		if len(subnets.FilterPrivate()) == 0 {
-			if s.scope.VPC().IsUnmanaged(s.scope.Name()) {
-				return errors.New("expected at least one private subnet available for use, got 0")
-			}
-
 			subnets = append(subnets, &infrav1.SubnetSpec{
 				CidrBlock:        defaultPrivateSubnetCidr,
 				AvailabilityZone: zones[0],
@@ -75,10 +85,6 @@ func (s *Service) reconcileSubnets() error {
 		}
 
 		if len(subnets.FilterPublic()) == 0 {
-			if s.scope.VPC().IsUnmanaged(s.scope.Name()) {
-				return errors.New("expected at least one public subnet available for use, got 0")
-			}
-
 			subnets = append(subnets, &infrav1.SubnetSpec{
 				CidrBlock:        defaultPublicSubnetCidr,
 				AvailabilityZone: zones[0],
@@ -95,7 +101,7 @@ LoopExisting:
 			// or if they are in the same vpc and the cidr block is the same.
 			if (sn.ID != "" && exsn.ID == sn.ID) || (sn.CidrBlock == exsn.CidrBlock) {
 				if s.scope.VPC().IsUnmanaged(s.scope.Name()) {
-					// TODO(vincepri): Validate provided subnet passes some basic checks.
+					// Provided subnets are checked by validateUnmanagedNetwork.
 					exsn.DeepCopyInto(sn)
 					continue LoopExisting
 				}
@@ -104,7 +110,8 @@ LoopExisting:
 				if err := wait.WaitForWithRetryable(wait.NewBackoff(), func() (bool, error) {
 					if err := tags.Ensure(exsn.Tags, &tags.ApplyParams{
 						EC2Client:   s.scope.EC2,
-						BuildParams: s.getSubnetTagParams(exsn.ID, exsn.IsPublic, sn.Tags),
+						BuildParams: s.getSubnetTagParams(exsn.ID, exsn.IsPublic, sn.ForPods, sn.Tags),
+						Removed:     s.scope.RemovedAdditionalTags(),
 					}); err != nil {
 						return false, err
 					}
@@ -114,8 +121,32 @@ LoopExisting:
 					return errors.Wrapf(err, "failed to ensure tags on subnet %q", exsn.ID)
 				}
 
+				// Make sure instances launched in the subnet get a public IP only when expected.
+				wantMapPublicIP := desiredMapPublicIPOnLaunch(sn.MapPublicIPOnLaunch, exsn.IsPublic)
+				if current := mapPublicIPOnLaunch[exsn.ID]; current != nil && *current != wantMapPublicIP {
+					if err := s.modifySubnetMapPublicIPOnLaunch(exsn.ID, wantMapPublicIP); err != nil {
+						return err
+					}
+				}
+
+				// Subnets created before the VPC became dual-stack get an IPv6 CIDR block too.
+				if s.scope.VPC().IsDualStack() && exsn.IPv6CidrBlock == "" {
+					cidr, err := ipv6SubnetCidrBlock(s.scope.VPC().IPv6CidrBlock, usedIPv6CidrBlocks(existing, subnets))
+					if err != nil {
+						return err
+					}
+					if err := s.associateSubnetIPv6CidrBlock(exsn.ID, cidr); err != nil {
+						return err
+					}
+					exsn.IPv6CidrBlock = cidr
+				}
+
 				// TODO(vincepri): check if subnet needs to be updated.
+				override, allocationID, forPods := sn.MapPublicIPOnLaunch, sn.NatGatewayAllocationID, sn.ForPods
 				exsn.DeepCopyInto(sn)
+				sn.MapPublicIPOnLaunch = override
+				sn.NatGatewayAllocationID = allocationID
+				sn.ForPods = forPods
 				continue LoopExisting
 			}
 		}
@@ -131,6 +162,14 @@ LoopExisting:
 				continue
 			}
 
+			if s.scope.VPC().IsDualStack() && subnet.IPv6CidrBlock == "" {
+				cidr, err := ipv6SubnetCidrBlock(s.scope.VPC().IPv6CidrBlock, usedIPv6CidrBlocks(subnets))
+				if err != nil {
+					return err
+				}
+				subnet.IPv6CidrBlock = cidr
+			}
+
 			nsn, err := s.createSubnet(subnet)
 			if err != nil {
 				return err
@@ -166,6 +205,15 @@ func (s *Service) deleteSubnets() error {
 }
 
 func (s *Service) describeVpcSubnets() (infrav1.Subnets, error) {
+	out, err := s.describeSubnets()
+	if err != nil {
+		return nil, err
+	}
+
+	return s.subnetsFromSDKTypes(out.Subnets)
+}
+
+func (s *Service) describeSubnets() (*ec2.DescribeSubnetsOutput, error) {
 	input := &ec2.DescribeSubnetsInput{
 		Filters: []*ec2.Filter{
 			filter.EC2.SubnetStates(ec2.SubnetStatePending, ec2.SubnetStateAvailable),
@@ -183,6 +231,10 @@ func (s *Service) describeVpcSubnets() (infrav1.Subnets, error) {
 		return nil, errors.Wrapf(err, "failed to describe subnets in vpc %q", s.scope.VPC().ID)
 	}
 
+	return out, nil
+}
+
+func (s *Service) subnetsFromSDKTypes(ec2Subnets []*ec2.Subnet) (infrav1.Subnets, error) {
 	routeTables, err := s.describeVpcRouteTablesBySubnet()
 	if err != nil {
 		return nil, err
@@ -193,10 +245,10 @@ func (s *Service) describeVpcSubnets() (infrav1.Subnets, error) {
 		return nil, err
 	}
 
-	subnets := make([]*infrav1.SubnetSpec, 0, len(out.Subnets))
+	subnets := make([]*infrav1.SubnetSpec, 0, len(ec2Subnets))
 	// Besides what the AWS API tells us directly about the subnets, we also want to discover whether the subnet is "public" (i.e. directly connected to the internet) and if there are any associated NAT gateways.
 	// We also look for a tag indicating that a particular subnet should be public, to try and determine whether a managed VPC's subnet should have such a route, but does not.
-	for _, ec2sn := range out.Subnets {
+	for _, ec2sn := range ec2Subnets {
 		spec := &infrav1.SubnetSpec{
 			ID:               *ec2sn.SubnetId,
 			CidrBlock:        *ec2sn.CidrBlock,
@@ -204,11 +256,22 @@ func (s *Service) describeVpcSubnets() (infrav1.Subnets, error) {
 			Tags:             converters.TagsToMap(ec2sn.Tags),
 		}
 
+		for _, association := range ec2sn.Ipv6CidrBlockAssociationSet {
+			if association.Ipv6CidrBlockState != nil && aws.StringValue(association.Ipv6CidrBlockState.State) == ec2.SubnetCidrBlockStateCodeAssociated {
+				spec.IPv6CidrBlock = aws.StringValue(association.Ipv6CidrBlock)
+			}
+		}
+
 		// A subnet is public if it's tagged as such...
 		if spec.Tags.GetRole() == infrav1.PublicRoleTagValue {
 			spec.IsPublic = true
 		}
 
+		// Subnets reserved for pods are only known by their tag.
+		if spec.Tags.GetRole() == infrav1.PodRoleTagValue {
+			spec.ForPods = true
+		}
+
 		// ... or if it has an internet route
 		rt := routeTables[*ec2sn.SubnetId]
 		if rt == nil {
@@ -235,12 +298,16 @@ func (s *Service) describeVpcSubnets() (infrav1.Subnets, error) {
 }
 
 func (s *Service) createSubnet(sn *infrav1.SubnetSpec) (*infrav1.SubnetSpec, error) {
-	out, err := s.scope.EC2.CreateSubnet(&ec2.CreateSubnetInput{
+	input := &ec2.CreateSubnetInput{
 		VpcId:            aws.String(s.scope.VPC().ID),
 		CidrBlock:        aws.String(sn.CidrBlock),
 		AvailabilityZone: aws.String(sn.AvailabilityZone),
-	})
+	}
+	if sn.IPv6CidrBlock != "" {
+		input.Ipv6CidrBlock = aws.String(sn.IPv6CidrBlock)
+	}
 
+	out, err := s.scope.EC2.CreateSubnet(input)
 	if err != nil {
 		record.Warnf(s.scope.AWSCluster, "FailedCreateSubnet", "Failed creating new managed Subnet %v", err)
 		return nil, errors.Wrap(err, "failed to create subnet")
@@ -256,7 +323,7 @@ func (s *Service) createSubnet(sn *infrav1.SubnetSpec) (*infrav1.SubnetSpec, err
 	if err := wait.WaitForWithRetryable(wait.NewBackoff(), func() (bool, error) {
 		if err := tags.Apply(&tags.ApplyParams{
 			EC2Client:   s.scope.EC2,
-			BuildParams: s.getSubnetTagParams(*out.Subnet.SubnetId, sn.IsPublic, sn.Tags),
+			BuildParams: s.getSubnetTagParams(*out.Subnet.SubnetId, sn.IsPublic, sn.ForPods, sn.Tags),
 		}); err != nil {
 			return false, err
 		}
@@ -268,24 +335,17 @@ func (s *Service) createSubnet(sn *infrav1.SubnetSpec) (*infrav1.SubnetSpec, err
 
 	record.Eventf(s.scope.AWSCluster, "SuccessfulTagSubnet", "Tagged managed Subnet %q", *out.Subnet.SubnetId)
 
-	if sn.IsPublic {
-		attReq := &ec2.ModifySubnetAttributeInput{
-			MapPublicIpOnLaunch: &ec2.AttributeBooleanValue{
-				Value: aws.Bool(true),
-			},
-			SubnetId: out.Subnet.SubnetId,
+	// New subnets don't map public IPs on launch, only change the attribute when needed.
+	if desiredMapPublicIPOnLaunch(sn.MapPublicIPOnLaunch, sn.IsPublic) {
+		if err := s.modifySubnetMapPublicIPOnLaunch(*out.Subnet.SubnetId, true); err != nil {
+			return nil, err
 		}
+	}
 
-		if err := wait.WaitForWithRetryable(wait.NewBackoff(), func() (bool, error) {
-			if _, err := s.scope.EC2.ModifySubnetAttribute(attReq); err != nil {
-				return false, err
-			}
-			return true, nil
-		}, awserrors.SubnetNotFound); err != nil {
-			record.Warnf(s.scope.AWSCluster, "FailedModifySubnetAttributes", "Failed modifying managed Subnet %q attributes: %v", *out.Subnet.SubnetId, err)
-			return nil, errors.Wrapf(err, "failed to set subnet %q attributes", *out.Subnet.SubnetId)
+	if sn.IPv6CidrBlock != "" {
+		if err := s.modifySubnetAssignIPv6AddressOnCreation(*out.Subnet.SubnetId); err != nil {
+			return nil, err
 		}
-		record.Eventf(s.scope.AWSCluster, "SuccessfulModifySubnetAttributes", "Modified managed Subnet %q attributes", *out.Subnet.SubnetId)
 	}
 
 	s.scope.V(2).Info("Created new subnet in VPC with cidr and availability zone ",
@@ -295,13 +355,133 @@ func (s *Service) createSubnet(sn *infrav1.SubnetSpec) (*infrav1.SubnetSpec, err
 		"availability-zone", *out.Subnet.AvailabilityZone)
 
 	return &infrav1.SubnetSpec{
-		ID:               *out.Subnet.SubnetId,
-		AvailabilityZone: *out.Subnet.AvailabilityZone,
-		CidrBlock:        *out.Subnet.CidrBlock,
-		IsPublic:         sn.IsPublic,
+		ID:                     *out.Subnet.SubnetId,
+		AvailabilityZone:       *out.Subnet.AvailabilityZone,
+		CidrBlock:              *out.Subnet.CidrBlock,
+		IPv6CidrBlock:          sn.IPv6CidrBlock,
+		IsPublic:               sn.IsPublic,
+		ForPods:                sn.ForPods,
+		MapPublicIPOnLaunch:    sn.MapPublicIPOnLaunch,
+		NatGatewayAllocationID: sn.NatGatewayAllocationID,
 	}, nil
 }
 
+func (s *Service) modifySubnetMapPublicIPOnLaunch(id string, value bool) error {
+	attReq := &ec2.ModifySubnetAttributeInput{
+		MapPublicIpOnLaunch: &ec2.AttributeBooleanValue{
+			Value: aws.Bool(value),
+		},
+		SubnetId: aws.String(id),
+	}
+
+	if err := wait.WaitForWithRetryable(wait.NewBackoff(), func() (bool, error) {
+		if _, err := s.scope.EC2.ModifySubnetAttribute(attReq); err != nil {
+			return false, err
+		}
+		return true, nil
+	}, awserrors.SubnetNotFound); err != nil {
+		record.Warnf(s.scope.AWSCluster, "FailedModifySubnetAttributes", "Failed modifying managed Subnet %q attributes: %v", id, err)
+		return errors.Wrapf(err, "failed to set subnet %q attributes", id)
+	}
+
+	s.scope.V(2).Info("Set subnet attribute", "subnet-id", id, "map-public-ip-on-launch", value)
+	record.Eventf(s.scope.AWSCluster, "SuccessfulModifySubnetAttributes", "Modified managed Subnet %q attributes", id)
+	return nil
+}
+
+// associateSubnetIPv6CidrBlock adds an IPv6 CIDR block to an existing subnet and
+// makes instances launched in it get an IPv6 address.
+func (s *Service) associateSubnetIPv6CidrBlock(id, cidr string) error {
+	if _, err := s.scope.EC2.AssociateSubnetCidrBlock(&ec2.AssociateSubnetCidrBlockInput{
+		SubnetId:      aws.String(id),
+		Ipv6CidrBlock: aws.String(cidr),
+	}); err != nil {
+		record.Warnf(s.scope.AWSCluster, "FailedAssociateSubnetIPv6CidrBlock", "Failed to associate IPv6 CIDR block %q with managed Subnet %q: %v", cidr, id, err)
+		return errors.Wrapf(err, "failed to associate ipv6 cidr block %q with subnet %q", cidr, id)
+	}
+
+	s.scope.V(2).Info("Associated IPv6 cidr block with subnet", "subnet-id", id, "ipv6-cidr-block", cidr)
+	record.Eventf(s.scope.AWSCluster, "SuccessfulAssociateSubnetIPv6CidrBlock", "Associated IPv6 CIDR block %q with managed Subnet %q", cidr, id)
+	return s.modifySubnetAssignIPv6AddressOnCreation(id)
+}
+
+func (s *Service) modifySubnetAssignIPv6AddressOnCreation(id string) error {
+	attReq := &ec2.ModifySubnetAttributeInput{
+		AssignIpv6AddressOnCreation: &ec2.AttributeBooleanValue{
+			Value: aws.Bool(true),
+		},
+		SubnetId: aws.String(id),
+	}
+
+	if err := wait.WaitForWithRetryable(wait.NewBackoff(), func() (bool, error) {
+		if _, err := s.scope.EC2.ModifySubnetAttribute(attReq); err != nil {
+			return false, err
+		}
+		return true, nil
+	}, awserrors.SubnetNotFound); err != nil {
+		record.Warnf(s.scope.AWSCluster, "FailedModifySubnetAttributes", "Failed modifying managed Subnet %q attributes: %v", id, err)
+		return errors.Wrapf(err, "failed to set subnet %q attributes", id)
+	}
+
+	s.scope.V(2).Info("Set subnet attribute", "subnet-id", id, "assign-ipv6-address-on-creation", true)
+	return nil
+}
+
+// usedIPv6CidrBlocks returns the IPv6 CIDR blocks already taken by the given subnets.
+func usedIPv6CidrBlocks(subnetLists ...infrav1.Subnets) []string {
+	var used []string
+	for _, subnets := range subnetLists {
+		for _, sn := range subnets {
+			if sn.IPv6CidrBlock != "" {
+				used = append(used, sn.IPv6CidrBlock)
+			}
+		}
+	}
+	return used
+}
+
+// ipv6SubnetCidrBlock returns the first /64 of the VPC /56 IPv6 CIDR block
+// that is not in use, AWS only allows /64 IPv6 subnets.
+func ipv6SubnetCidrBlock(vpcCidr string, used []string) (string, error) {
+	_, vpcNet, err := net.ParseCIDR(vpcCidr)
+	if err != nil {
+		return "", errors.Wrapf(err, "failed to parse vpc ipv6 cidr block %q", vpcCidr)
+	}
+	if ones, bits := vpcNet.Mask.Size(); ones != 56 || bits != 128 {
+		return "", errors.Errorf("expected a /56 vpc ipv6 cidr block, got %q", vpcCidr)
+	}
+
+	taken := make(map[string]bool, len(used))
+	for _, cidr := range used {
+		_, ipNet, err := net.ParseCIDR(cidr)
+		if err != nil {
+			return "", errors.Wrapf(err, "failed to parse subnet ipv6 cidr block %q", cidr)
+		}
+		taken[ipNet.String()] = true
+	}
+
+	for i := 0; i < 256; i++ {
+		ip := make(net.IP, net.IPv6len)
+		copy(ip, vpcNet.IP.To16())
+		ip[7] = byte(i)
+		candidate := &net.IPNet{IP: ip, Mask: net.CIDRMask(64, 128)}
+		if !taken[candidate.String()] {
+			return candidate.String(), nil
+		}
+	}
+
+	return "", errors.Errorf("no free /64 ipv6 cidr block left in %q", vpcCidr)
+}
+
+// desiredMapPublicIPOnLaunch returns whether a subnet should assign public IPs
+// on launch, public subnets do unless overridden.
+func desiredMapPublicIPOnLaunch(override *bool, public bool) bool {
+	if override != nil {
+		return *override
+	}
+	return public
+}
+
 func (s *Service) deleteSubnet(id string) error {
 	_, err := s.scope.EC2.DeleteSubnet(&ec2.DeleteSubnetInput{
 		SubnetId: aws.String(id),
@@ -317,20 +497,27 @@ func (s *Service) deleteSubnet(id string) error {
 	return nil
 }
 
-func (s *Service) getSubnetTagParams(id string, public bool, manualTags infrav1.Tags) infrav1.BuildParams {
+func (s *Service) getSubnetTagParams(id string, public, forPods bool, manualTags infrav1.Tags) infrav1.BuildParams {
 	var role string
 	additionalTags := s.scope.AdditionalTags()
 
-	if public {
+	switch {
+	case public:
 		role = infrav1.PublicRoleTagValue
 		additionalTags[externalLoadBalancerTag] = "1"
-	} else {
+	case forPods:
+		role = infrav1.PodRoleTagValue
+	default:
 		role = infrav1.PrivateRoleTagValue
 		additionalTags[internalLoadBalancerTag] = "1"
 	}
 
-	// Add tag needed for Service type=LoadBalancer
-	additionalTags[infrav1.NameKubernetesAWSCloudProviderPrefix+s.scope.Name()] = string(infrav1.ResourceLifecycleShared)
+	// Add tag needed for Service type=LoadBalancer, subnets reserved for pods
+	// are left out so that the cloud provider never places load balancers in
+	// them.
+	if !forPods {
+		additionalTags[infrav1.NameKubernetesAWSCloudProviderPrefix+s.scope.Name()] = string(infrav1.ResourceLifecycleShared)
+	}
 
 	for k, v := range manualTags {
 		additionalTags[k] = v
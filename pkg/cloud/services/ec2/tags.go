@@ -0,0 +1,57 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ec2
+
+import (
+	"fmt"
+
+	infrav1 "sigs.k8s.io/cluster-api-provider-aws/api/v1alpha3"
+)
+
+// ResourceTags returns the tags the service applies to each type of EC2
+// resource it manages for the cluster, keyed by resource type. Subnets also
+// get the tags of their own spec, and instances get a Name tag with the name
+// of their AWSMachine and the AWSMachine's additional tags.
+func (s *Service) ResourceTags() map[string]infrav1.Tags {
+	res := map[string]infrav1.Tags{
+		"vpc":                    infrav1.Build(s.getVPCTagParams("")),
+		"subnet/public":          infrav1.Build(s.getSubnetTagParams("", true, false, nil)),
+		"subnet/private":         infrav1.Build(s.getSubnetTagParams("", false, false, nil)),
+		"subnet/pod":             infrav1.Build(s.getSubnetTagParams("", false, true, nil)),
+		"internet-gateway":       infrav1.Build(s.getGatewayTagParams("")),
+		"nat-gateway":            infrav1.Build(s.getNatGatewayTagParams("")),
+		"elastic-ip":             infrav1.Build(s.getEIPTagParams("", infrav1.APIServerRoleTagValue)),
+		"route-table/public":     infrav1.Build(s.getRouteTableTagParams("", true)),
+		"route-table/private":    infrav1.Build(s.getRouteTableTagParams("", false)),
+		"network-acl":            infrav1.Build(s.getNetworkACLTagParams("")),
+		"bastion":                infrav1.Build(s.getBastionTagParams(fmt.Sprintf("%s-bastion", s.scope.Name()))),
+		"instance/control-plane": infrav1.Build(s.getInstanceTagParams(nil, "control-plane", s.scope.AdditionalTags())),
+		"instance/node":          infrav1.Build(s.getInstanceTagParams(nil, "node", s.scope.AdditionalTags())),
+	}
+
+	for _, role := range []infrav1.SecurityGroupRole{
+		infrav1.SecurityGroupBastion,
+		infrav1.SecurityGroupLB,
+		infrav1.SecurityGroupControlPlane,
+		infrav1.SecurityGroupNode,
+	} {
+		name := s.getSecurityGroupName(s.scope.Name(), role)
+		res[fmt.Sprintf("security-group/%s", role)] = infrav1.Build(s.getSecurityGroupTagParams(name, "", role))
+	}
+
+	return res
+}
@@ -0,0 +1,309 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ec2
+
+import (
+	"fmt"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/pkg/errors"
+	"k8s.io/apimachinery/pkg/util/sets"
+	infrav1 "sigs.k8s.io/cluster-api-provider-aws/api/v1alpha3"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/awserrors"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/converters"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/filter"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/services/wait"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/tags"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/record"
+)
+
+// transitGatewayAttachmentActiveStates are the states of the transit gateway
+// attachments which are not being deleted.
+var transitGatewayAttachmentActiveStates = []string{"pendingAcceptance", "pending", "available", "modifying", "rollingBack", "rejecting", "rejected", "failing", "failed"}
+
+// reconcileTransitGatewayAttachment attaches the VPC to the configured transit
+// gateway through a private subnet per availability zone, replacing the
+// attachments to other transit gateways. It waits for the attachment to be
+// available, as routes through the transit gateway can't be created before.
+func (s *Service) reconcileTransitGatewayAttachment() error {
+	if s.scope.VPC().IsUnmanaged(s.scope.Name()) {
+		s.scope.V(4).Info("Skipping transit gateway attachment reconcile in unmanaged mode")
+		return nil
+	}
+
+	spec := s.scope.TransitGateway()
+	if spec == nil {
+		return nil
+	}
+
+	s.scope.V(2).Info("Reconciling transit gateway attachment")
+
+	existing, err := s.describeTransitGatewayAttachments(transitGatewayAttachmentActiveStates...)
+	if err != nil {
+		return err
+	}
+
+	var attachment *ec2.TransitGatewayVpcAttachment
+	var unwanted []*ec2.TransitGatewayVpcAttachment
+	for _, a := range existing {
+		if attachment == nil && aws.StringValue(a.TransitGatewayId) == spec.ID {
+			attachment = a
+			continue
+		}
+		unwanted = append(unwanted, a)
+	}
+
+	if len(unwanted) > 0 {
+		if err := s.removeTransitGatewayAttachments(unwanted); err != nil {
+			return err
+		}
+	}
+
+	if attachment == nil {
+		attachment, err = s.createTransitGatewayAttachment(spec.ID)
+		if err != nil {
+			return err
+		}
+	} else {
+		// Make sure tags are up to date.
+		if err := wait.WaitForWithRetryable(wait.NewBackoff(), func() (bool, error) {
+			if err := tags.Ensure(converters.TagsToMap(attachment.Tags), &tags.ApplyParams{
+				EC2Client:   s.scope.EC2,
+				BuildParams: s.getTransitGatewayAttachmentTagParams(*attachment.TransitGatewayAttachmentId),
+				Removed:     s.scope.RemovedAdditionalTags(),
+			}); err != nil {
+				return false, err
+			}
+			return true, nil
+		}, awserrors.TGWAttachmentNotFound); err != nil {
+			record.Warnf(s.scope.AWSCluster, "FailedTagTransitGatewayAttachment", "Failed to tag managed TransitGatewayAttachment %q: %v", *attachment.TransitGatewayAttachmentId, err)
+			return errors.Wrapf(err, "failed to ensure tags on transit gateway attachment %q", *attachment.TransitGatewayAttachmentId)
+		}
+
+		if err := s.reconcileTransitGatewayAttachmentSubnets(attachment); err != nil {
+			return err
+		}
+	}
+
+	spec.AttachmentID = attachment.TransitGatewayAttachmentId
+	return s.waitForTransitGatewayAttachmentAvailable(*attachment.TransitGatewayAttachmentId)
+}
+
+// reconcileTransitGatewayAttachmentSubnets makes sure the attachment has a
+// network interface in every availability zone with private subnets. Subnets
+// can't be modified while the attachment is pending.
+func (s *Service) reconcileTransitGatewayAttachmentSubnets(attachment *ec2.TransitGatewayVpcAttachment) error {
+	if aws.StringValue(attachment.State) != ec2.TransitGatewayAttachmentStateAvailable {
+		return nil
+	}
+
+	want := sets.NewString(s.getPrivateSubnetIDPerZone()...)
+	current := sets.NewString(aws.StringValueSlice(attachment.SubnetIds)...)
+	add, remove := want.Difference(current).List(), current.Difference(want).List()
+	if len(add) == 0 && len(remove) == 0 {
+		return nil
+	}
+
+	if _, err := s.scope.EC2.ModifyTransitGatewayVpcAttachment(&ec2.ModifyTransitGatewayVpcAttachmentInput{
+		TransitGatewayAttachmentId: attachment.TransitGatewayAttachmentId,
+		AddSubnetIds:               aws.StringSlice(add),
+		RemoveSubnetIds:            aws.StringSlice(remove),
+	}); err != nil {
+		record.Warnf(s.scope.AWSCluster, "FailedModifyTransitGatewayAttachment", "Failed to modify managed TransitGatewayAttachment %q: %v", *attachment.TransitGatewayAttachmentId, err)
+		return errors.Wrapf(err, "failed to modify transit gateway attachment %q", *attachment.TransitGatewayAttachmentId)
+	}
+
+	record.Eventf(s.scope.AWSCluster, "SuccessfulModifyTransitGatewayAttachment", "Modified managed TransitGatewayAttachment %q", *attachment.TransitGatewayAttachmentId)
+	return nil
+}
+
+func (s *Service) deleteTransitGatewayAttachments() error {
+	if s.scope.VPC().IsUnmanaged(s.scope.Name()) {
+		s.scope.V(4).Info("Skipping transit gateway attachment deletion in unmanaged mode")
+		return nil
+	}
+
+	// Attachments are only made while a transit gateway is configured, which
+	// the webhook keeps once set. Other clusters don't need
+	// ec2:DescribeTransitGatewayVpcAttachments in the controllers policy.
+	if s.scope.VPC().ID == "" || s.scope.TransitGateway() == nil {
+		return nil
+	}
+
+	attachments, err := s.describeTransitGatewayAttachments(transitGatewayAttachmentActiveStates...)
+	if err != nil {
+		return err
+	}
+
+	if len(attachments) == 0 {
+		return nil
+	}
+
+	if err := s.removeTransitGatewayAttachments(attachments); err != nil {
+		return err
+	}
+
+	s.scope.TransitGateway().AttachmentID = nil
+	return nil
+}
+
+func (s *Service) describeTransitGatewayAttachments(states ...string) ([]*ec2.TransitGatewayVpcAttachment, error) {
+	input := &ec2.DescribeTransitGatewayVpcAttachmentsInput{
+		Filters: []*ec2.Filter{
+			filter.EC2.VPC(s.scope.VPC().ID),
+			filter.EC2.ClusterOwned(s.scope.Name()),
+			filter.EC2.TransitGatewayAttachmentStates(states...),
+		},
+	}
+
+	var attachments []*ec2.TransitGatewayVpcAttachment
+	for {
+		out, err := s.scope.EC2.DescribeTransitGatewayVpcAttachments(input)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to describe transit gateway attachments in vpc %q", s.scope.VPC().ID)
+		}
+
+		attachments = append(attachments, out.TransitGatewayVpcAttachments...)
+
+		if aws.StringValue(out.NextToken) == "" {
+			return attachments, nil
+		}
+		input.NextToken = out.NextToken
+	}
+}
+
+func (s *Service) createTransitGatewayAttachment(transitGatewayID string) (*ec2.TransitGatewayVpcAttachment, error) {
+	out, err := s.scope.EC2.CreateTransitGatewayVpcAttachment(&ec2.CreateTransitGatewayVpcAttachmentInput{
+		TransitGatewayId: aws.String(transitGatewayID),
+		VpcId:            aws.String(s.scope.VPC().ID),
+		SubnetIds:        aws.StringSlice(s.getPrivateSubnetIDPerZone()),
+	})
+	if err != nil {
+		record.Warnf(s.scope.AWSCluster, "FailedCreateTransitGatewayAttachment", "Failed to attach VPC %q to Transit Gateway %q: %v", s.scope.VPC().ID, transitGatewayID, err)
+		return nil, errors.Wrapf(err, "failed to attach vpc %q to transit gateway %q", s.scope.VPC().ID, transitGatewayID)
+	}
+	id := *out.TransitGatewayVpcAttachment.TransitGatewayAttachmentId
+	record.Eventf(s.scope.AWSCluster, "SuccessfulCreateTransitGatewayAttachment", "Created managed TransitGatewayAttachment %q to Transit Gateway %q", id, transitGatewayID)
+
+	if err := wait.WaitForWithRetryable(wait.NewBackoff(), func() (bool, error) {
+		if err := tags.Apply(&tags.ApplyParams{
+			EC2Client:   s.scope.EC2,
+			BuildParams: s.getTransitGatewayAttachmentTagParams(id),
+		}); err != nil {
+			return false, err
+		}
+		return true, nil
+	}, awserrors.TGWAttachmentNotFound); err != nil {
+		record.Warnf(s.scope.AWSCluster, "FailedTagTransitGatewayAttachment", "Failed to tag managed TransitGatewayAttachment %q: %v", id, err)
+		return nil, errors.Wrapf(err, "failed to tag transit gateway attachment %q", id)
+	}
+	record.Eventf(s.scope.AWSCluster, "SuccessfulTagTransitGatewayAttachment", "Tagged managed TransitGatewayAttachment %q", id)
+
+	s.scope.Info("Attached VPC to transit gateway", "transit-gateway-attachment-id", id, "transit-gateway-id", transitGatewayID, "vpc-id", s.scope.VPC().ID)
+	return out.TransitGatewayVpcAttachment, nil
+}
+
+// waitForTransitGatewayAttachmentAvailable waits for an attachment to become
+// available. An attachment to a transit gateway of another account waits for
+// its owner to accept it, an error is returned so the reconcile is requeued.
+func (s *Service) waitForTransitGatewayAttachmentAvailable(id string) error {
+	describeInput := &ec2.DescribeTransitGatewayVpcAttachmentsInput{
+		TransitGatewayAttachmentIds: aws.StringSlice([]string{id}),
+	}
+
+	if err := wait.WaitForWithRetryable(wait.NewBackoff(), func() (bool, error) {
+		out, err := s.scope.EC2.DescribeTransitGatewayVpcAttachments(describeInput)
+		if err != nil {
+			return false, err
+		}
+
+		if len(out.TransitGatewayVpcAttachments) == 0 {
+			return false, errors.Errorf("no transit gateway attachment returned for id %q", id)
+		}
+
+		switch state := aws.StringValue(out.TransitGatewayVpcAttachments[0].State); state {
+		case ec2.TransitGatewayAttachmentStateAvailable:
+			return true, nil
+		case ec2.TransitGatewayAttachmentStatePending, ec2.TransitGatewayAttachmentStateModifying:
+			s.scope.V(2).Info("Waiting for transit gateway attachment to become available", "transit-gateway-attachment-id", id)
+			return false, nil
+		case ec2.TransitGatewayAttachmentStatePendingAcceptance:
+			return false, errors.Errorf("waiting for the owner of the transit gateway to accept the attachment")
+		case ec2.TransitGatewayAttachmentStateRejected, ec2.TransitGatewayAttachmentStateFailed:
+			record.Warnf(s.scope.AWSCluster, "FailedTransitGatewayAttachment", "Managed TransitGatewayAttachment %q is in %s state", id, state)
+			return false, errors.Errorf("in %s state", state)
+		default:
+			return false, errors.Errorf("in unexpected state %q", state)
+		}
+	}, awserrors.TGWAttachmentNotFound); err != nil {
+		return errors.Wrapf(err, "transit gateway attachment %q is not available yet", id)
+	}
+
+	return nil
+}
+
+// removeTransitGatewayAttachments deletes the given attachments and waits for
+// them to be gone, as they hold network interfaces in the private subnets
+// until then.
+func (s *Service) removeTransitGatewayAttachments(attachments []*ec2.TransitGatewayVpcAttachment) error {
+	ids := make([]string, 0, len(attachments))
+	for _, attachment := range attachments {
+		id := aws.StringValue(attachment.TransitGatewayAttachmentId)
+		if _, err := s.scope.EC2.DeleteTransitGatewayVpcAttachment(&ec2.DeleteTransitGatewayVpcAttachmentInput{
+			TransitGatewayAttachmentId: aws.String(id),
+		}); err != nil && !awserrors.IsNotFound(err) {
+			record.Warnf(s.scope.AWSCluster, "FailedDeleteTransitGatewayAttachment", "Failed to delete managed TransitGatewayAttachment %q: %v", id, err)
+			return errors.Wrapf(err, "failed to delete transit gateway attachment %q", id)
+		}
+		record.Eventf(s.scope.AWSCluster, "SuccessfulDeleteTransitGatewayAttachment", "Deleted managed TransitGatewayAttachment %q", id)
+		ids = append(ids, id)
+	}
+
+	deleting := sets.NewString(ids...)
+	if err := wait.WaitForWithRetryable(wait.NewBackoff(), func() (bool, error) {
+		remaining, err := s.describeTransitGatewayAttachments(append(transitGatewayAttachmentActiveStates, "deleting")...)
+		if err != nil {
+			return false, err
+		}
+
+		for _, attachment := range remaining {
+			if deleting.Has(aws.StringValue(attachment.TransitGatewayAttachmentId)) {
+				return false, nil
+			}
+		}
+		return true, nil
+	}); err != nil {
+		return errors.Wrapf(err, "failed to wait for transit gateway attachments deletion %v", ids)
+	}
+
+	s.scope.Info("Deleted transit gateway attachments", "transit-gateway-attachment-ids", ids)
+	return nil
+}
+
+func (s *Service) getTransitGatewayAttachmentTagParams(id string) infrav1.BuildParams {
+	name := fmt.Sprintf("%s-tgw-attach", s.scope.Name())
+
+	return infrav1.BuildParams{
+		ClusterName: s.scope.Name(),
+		ResourceID:  id,
+		Lifecycle:   infrav1.ResourceLifecycleOwned,
+		Name:        aws.String(name),
+		Role:        aws.String(infrav1.CommonRoleTagValue),
+		Additional:  s.scope.AdditionalTags(),
+	}
+}
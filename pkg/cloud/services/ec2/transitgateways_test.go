@@ -0,0 +1,189 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ec2
+
+import (
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/golang/mock/gomock"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	infrav1 "sigs.k8s.io/cluster-api-provider-aws/api/v1alpha3"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/scope"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/services/ec2/mock_ec2iface"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1alpha3"
+)
+
+func TestReconcileTransitGatewayAttachment(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	describeByID := &ec2.DescribeTransitGatewayVpcAttachmentsInput{
+		TransitGatewayAttachmentIds: aws.StringSlice([]string{"tgw-attach-new"}),
+	}
+
+	testCases := []struct {
+		name             string
+		expect           func(m *mock_ec2iface.MockEC2APIMockRecorder)
+		expectAttachment string
+		expectErr        bool
+	}{
+		{
+			name: "VPC is attached through a private subnet per availability zone",
+			expect: func(m *mock_ec2iface.MockEC2APIMockRecorder) {
+				m.DescribeTransitGatewayVpcAttachments(gomock.AssignableToTypeOf(&ec2.DescribeTransitGatewayVpcAttachmentsInput{})).
+					Return(&ec2.DescribeTransitGatewayVpcAttachmentsOutput{}, nil)
+				m.CreateTransitGatewayVpcAttachment(gomock.Eq(&ec2.CreateTransitGatewayVpcAttachmentInput{
+					TransitGatewayId: aws.String("tgw-hub"),
+					VpcId:            aws.String("vpc-tgw"),
+					SubnetIds:        aws.StringSlice([]string{"subnet-private-a1", "subnet-private-b"}),
+				})).
+					Return(&ec2.CreateTransitGatewayVpcAttachmentOutput{
+						TransitGatewayVpcAttachment: &ec2.TransitGatewayVpcAttachment{
+							TransitGatewayAttachmentId: aws.String("tgw-attach-new"),
+							State:                      aws.String(ec2.TransitGatewayAttachmentStatePending),
+						},
+					}, nil)
+				m.CreateTags(gomock.AssignableToTypeOf(&ec2.CreateTagsInput{})).
+					Return(&ec2.CreateTagsOutput{}, nil)
+				m.DescribeTransitGatewayVpcAttachments(gomock.Eq(describeByID)).
+					Return(&ec2.DescribeTransitGatewayVpcAttachmentsOutput{
+						TransitGatewayVpcAttachments: []*ec2.TransitGatewayVpcAttachment{
+							{TransitGatewayAttachmentId: aws.String("tgw-attach-new"), State: aws.String(ec2.TransitGatewayAttachmentStateAvailable)},
+						},
+					}, nil)
+			},
+			expectAttachment: "tgw-attach-new",
+		},
+		{
+			name: "attachment to another transit gateway is replaced",
+			expect: func(m *mock_ec2iface.MockEC2APIMockRecorder) {
+				m.DescribeTransitGatewayVpcAttachments(gomock.AssignableToTypeOf(&ec2.DescribeTransitGatewayVpcAttachmentsInput{})).
+					Return(&ec2.DescribeTransitGatewayVpcAttachmentsOutput{
+						TransitGatewayVpcAttachments: []*ec2.TransitGatewayVpcAttachment{
+							{
+								TransitGatewayAttachmentId: aws.String("tgw-attach-old"),
+								TransitGatewayId:           aws.String("tgw-old"),
+								State:                      aws.String(ec2.TransitGatewayAttachmentStateAvailable),
+							},
+						},
+					}, nil)
+				m.DeleteTransitGatewayVpcAttachment(gomock.Eq(&ec2.DeleteTransitGatewayVpcAttachmentInput{
+					TransitGatewayAttachmentId: aws.String("tgw-attach-old"),
+				})).
+					Return(&ec2.DeleteTransitGatewayVpcAttachmentOutput{}, nil)
+				m.DescribeTransitGatewayVpcAttachments(gomock.AssignableToTypeOf(&ec2.DescribeTransitGatewayVpcAttachmentsInput{})).
+					Return(&ec2.DescribeTransitGatewayVpcAttachmentsOutput{}, nil)
+				m.CreateTransitGatewayVpcAttachment(gomock.AssignableToTypeOf(&ec2.CreateTransitGatewayVpcAttachmentInput{})).
+					Return(&ec2.CreateTransitGatewayVpcAttachmentOutput{
+						TransitGatewayVpcAttachment: &ec2.TransitGatewayVpcAttachment{
+							TransitGatewayAttachmentId: aws.String("tgw-attach-new"),
+							State:                      aws.String(ec2.TransitGatewayAttachmentStatePending),
+						},
+					}, nil)
+				m.CreateTags(gomock.AssignableToTypeOf(&ec2.CreateTagsInput{})).
+					Return(&ec2.CreateTagsOutput{}, nil)
+				m.DescribeTransitGatewayVpcAttachments(gomock.Eq(describeByID)).
+					Return(&ec2.DescribeTransitGatewayVpcAttachmentsOutput{
+						TransitGatewayVpcAttachments: []*ec2.TransitGatewayVpcAttachment{
+							{TransitGatewayAttachmentId: aws.String("tgw-attach-new"), State: aws.String(ec2.TransitGatewayAttachmentStateAvailable)},
+						},
+					}, nil)
+			},
+			expectAttachment: "tgw-attach-new",
+		},
+		{
+			name: "attachment waiting for acceptance by the owner of the transit gateway",
+			expect: func(m *mock_ec2iface.MockEC2APIMockRecorder) {
+				m.DescribeTransitGatewayVpcAttachments(gomock.AssignableToTypeOf(&ec2.DescribeTransitGatewayVpcAttachmentsInput{})).
+					Return(&ec2.DescribeTransitGatewayVpcAttachmentsOutput{
+						TransitGatewayVpcAttachments: []*ec2.TransitGatewayVpcAttachment{
+							{
+								TransitGatewayAttachmentId: aws.String("tgw-attach-new"),
+								TransitGatewayId:           aws.String("tgw-hub"),
+								State:                      aws.String(ec2.TransitGatewayAttachmentStatePendingAcceptance),
+								Tags: []*ec2.Tag{
+									{Key: aws.String("Name"), Value: aws.String("test-cluster-tgw-attach")},
+									{Key: aws.String("sigs.k8s.io/cluster-api-provider-aws/cluster/test-cluster"), Value: aws.String("owned")},
+									{Key: aws.String("sigs.k8s.io/cluster-api-provider-aws/role"), Value: aws.String("common")},
+								},
+							},
+						},
+					}, nil)
+				m.DescribeTransitGatewayVpcAttachments(gomock.Eq(describeByID)).
+					Return(&ec2.DescribeTransitGatewayVpcAttachmentsOutput{
+						TransitGatewayVpcAttachments: []*ec2.TransitGatewayVpcAttachment{
+							{TransitGatewayAttachmentId: aws.String("tgw-attach-new"), State: aws.String(ec2.TransitGatewayAttachmentStatePendingAcceptance)},
+						},
+					}, nil)
+			},
+			expectAttachment: "tgw-attach-new",
+			expectErr:        true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ec2Mock := mock_ec2iface.NewMockEC2API(mockCtrl)
+
+			scope, err := scope.NewClusterScope(scope.ClusterScopeParams{
+				Cluster: &clusterv1.Cluster{
+					ObjectMeta: metav1.ObjectMeta{Name: "test-cluster"},
+				},
+				AWSClients: scope.AWSClients{
+					EC2: ec2Mock,
+				},
+				AWSCluster: &infrav1.AWSCluster{
+					Spec: infrav1.AWSClusterSpec{
+						Region: "us-east-1",
+						NetworkSpec: infrav1.NetworkSpec{
+							VPC: infrav1.VPCSpec{
+								ID: "vpc-tgw",
+								Tags: infrav1.Tags{
+									infrav1.ClusterTagKey("test-cluster"): "owned",
+								},
+							},
+							Subnets: infrav1.Subnets{
+								{ID: "subnet-public-a", AvailabilityZone: "us-east-1a", IsPublic: true},
+								{ID: "subnet-private-a1", AvailabilityZone: "us-east-1a"},
+								{ID: "subnet-private-a2", AvailabilityZone: "us-east-1a"},
+								{ID: "subnet-private-b", AvailabilityZone: "us-east-1b"},
+							},
+							TransitGateway: &infrav1.TransitGatewaySpec{ID: "tgw-hub", Routes: []string{"10.100.0.0/16"}},
+						},
+					},
+				},
+			})
+			if err != nil {
+				t.Fatalf("Failed to create test context: %v", err)
+			}
+
+			tc.expect(ec2Mock.EXPECT())
+
+			s := NewService(scope)
+			err = s.reconcileTransitGatewayAttachment()
+			if tc.expectErr != (err != nil) {
+				t.Fatalf("expected error %v, got %v", tc.expectErr, err)
+			}
+
+			if id := aws.StringValue(scope.TransitGateway().AttachmentID); id != tc.expectAttachment {
+				t.Fatalf("expected attachment %q, got %q", tc.expectAttachment, id)
+			}
+		})
+	}
+}
@@ -0,0 +1,137 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ec2
+
+import (
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/pkg/errors"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/awserrors"
+)
+
+// volumeModificationInterval is how long EBS requires between two
+// modifications of the same volume.
+// See https://docs.aws.amazon.com/AWSEC2/latest/UserGuide/modify-volume-requirements.html
+const volumeModificationInterval = 6 * time.Hour
+
+// now is replaced in tests.
+var now = time.Now
+
+// GrowRootVolume grows the root EBS volume of the instance to the given size in
+// gigabytes, the filesystem is expected to be grown by the node itself. Volumes
+// are never shrunk. When the volume was last modified too recently to be
+// modified again, it is left untouched and the time to wait before retrying is
+// returned.
+func (s *Service) GrowRootVolume(instanceID string, size int64) (time.Duration, error) {
+	var out *ec2.DescribeInstancesOutput
+	err := awserrors.RetryOnThrottling(func() (err error) {
+		out, err = s.scope.EC2.DescribeInstances(&ec2.DescribeInstancesInput{
+			InstanceIds: []*string{aws.String(instanceID)},
+		})
+		return err
+	})
+	if err != nil {
+		return 0, errors.Wrapf(err, "failed to describe instance %q", instanceID)
+	}
+	if len(out.Reservations) == 0 || len(out.Reservations[0].Instances) == 0 {
+		return 0, errors.Errorf("no instance found with id %q", instanceID)
+	}
+	instance := out.Reservations[0].Instances[0]
+
+	volumeID := getInstanceRootVolumeID(instance)
+	if volumeID == "" {
+		return 0, errors.Errorf("no root volume found for EC2 instance %q", instanceID)
+	}
+
+	var volumes *ec2.DescribeVolumesOutput
+	err = awserrors.RetryOnThrottling(func() (err error) {
+		volumes, err = s.scope.EC2.DescribeVolumes(&ec2.DescribeVolumesInput{
+			VolumeIds: []*string{aws.String(volumeID)},
+		})
+		return err
+	})
+	if err != nil {
+		return 0, errors.Wrapf(err, "failed to describe volume %q", volumeID)
+	}
+	if len(volumes.Volumes) == 0 {
+		return 0, errors.Errorf("no volumes found for id %q", volumeID)
+	}
+
+	currentSize := aws.Int64Value(volumes.Volumes[0].Size)
+	if size <= currentSize {
+		return 0, nil
+	}
+
+	wait, err := s.volumeModificationWait(volumeID)
+	if err != nil {
+		return 0, err
+	}
+	if wait > 0 {
+		s.scope.V(2).Info("Root volume was modified recently, postponing resize", "volume-id", volumeID, "wait", wait.String())
+		return wait, nil
+	}
+
+	if _, err := s.scope.EC2.ModifyVolume(&ec2.ModifyVolumeInput{
+		VolumeId: aws.String(volumeID),
+		Size:     aws.Int64(size),
+	}); err != nil {
+		return 0, errors.Wrapf(err, "failed to resize volume %q from %dGB to %dGB", volumeID, currentSize, size)
+	}
+
+	s.scope.V(2).Info("Resized root volume", "volume-id", volumeID, "from", currentSize, "to", size)
+	return 0, nil
+}
+
+// volumeModificationWait returns how long to wait before the volume can be
+// modified again.
+func (s *Service) volumeModificationWait(volumeID string) (time.Duration, error) {
+	var out *ec2.DescribeVolumesModificationsOutput
+	err := awserrors.RetryOnThrottling(func() (err error) {
+		out, err = s.scope.EC2.DescribeVolumesModifications(&ec2.DescribeVolumesModificationsInput{
+			VolumeIds: []*string{aws.String(volumeID)},
+		})
+		return err
+	})
+	if code, _ := awserrors.Code(err); code == awserrors.VolumeModificationNotFound {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, errors.Wrapf(err, "failed to describe modifications of volume %q", volumeID)
+	}
+
+	var wait time.Duration
+	for _, mod := range out.VolumesModifications {
+		if mod.StartTime == nil {
+			continue
+		}
+		if d := mod.StartTime.Add(volumeModificationInterval).Sub(now()); d > wait {
+			wait = d
+		}
+	}
+	return wait, nil
+}
+
+func getInstanceRootVolumeID(instance *ec2.Instance) string {
+	for _, bdm := range instance.BlockDeviceMappings {
+		if aws.StringValue(bdm.DeviceName) == aws.StringValue(instance.RootDeviceName) && bdm.Ebs != nil {
+			return aws.StringValue(bdm.Ebs.VolumeId)
+		}
+	}
+	return ""
+}
@@ -0,0 +1,167 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ec2
+
+import (
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/golang/mock/gomock"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	infrav1 "sigs.k8s.io/cluster-api-provider-aws/api/v1alpha3"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/awserrors"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/scope"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/services/ec2/mock_ec2iface"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1alpha3"
+)
+
+func TestGrowRootVolume(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	fakeNow := time.Date(2020, 1, 1, 12, 0, 0, 0, time.UTC)
+	defer func(orig func() time.Time) { now = orig }(now)
+	now = func() time.Time { return fakeNow }
+
+	describeInstance := func(m *mock_ec2iface.MockEC2APIMockRecorder) {
+		m.DescribeInstances(gomock.Eq(&ec2.DescribeInstancesInput{
+			InstanceIds: []*string{aws.String("i-1")},
+		})).
+			Return(&ec2.DescribeInstancesOutput{
+				Reservations: []*ec2.Reservation{
+					{
+						Instances: []*ec2.Instance{
+							{
+								InstanceId:     aws.String("i-1"),
+								RootDeviceName: aws.String("/dev/xvda"),
+								BlockDeviceMappings: []*ec2.InstanceBlockDeviceMapping{
+									{
+										DeviceName: aws.String("/dev/xvdb"),
+										Ebs:        &ec2.EbsInstanceBlockDevice{VolumeId: aws.String("vol-data")},
+									},
+									{
+										DeviceName: aws.String("/dev/xvda"),
+										Ebs:        &ec2.EbsInstanceBlockDevice{VolumeId: aws.String("vol-root")},
+									},
+								},
+							},
+						},
+					},
+				},
+			}, nil)
+		m.DescribeVolumes(gomock.Eq(&ec2.DescribeVolumesInput{
+			VolumeIds: []*string{aws.String("vol-root")},
+		})).
+			Return(&ec2.DescribeVolumesOutput{
+				Volumes: []*ec2.Volume{{VolumeId: aws.String("vol-root"), Size: aws.Int64(20)}},
+			}, nil)
+	}
+
+	testCases := []struct {
+		name         string
+		size         int64
+		expect       func(m *mock_ec2iface.MockEC2APIMockRecorder)
+		expectedWait time.Duration
+	}{
+		{
+			name: "volume that was never modified is grown",
+			size: 50,
+			expect: func(m *mock_ec2iface.MockEC2APIMockRecorder) {
+				describeInstance(m)
+				m.DescribeVolumesModifications(gomock.AssignableToTypeOf(&ec2.DescribeVolumesModificationsInput{})).
+					Return(nil, awserr.New(awserrors.VolumeModificationNotFound, "not found", nil))
+				m.ModifyVolume(gomock.Eq(&ec2.ModifyVolumeInput{
+					VolumeId: aws.String("vol-root"),
+					Size:     aws.Int64(50),
+				})).
+					Return(&ec2.ModifyVolumeOutput{}, nil)
+			},
+		},
+		{
+			name: "volume modified more than six hours ago is grown",
+			size: 50,
+			expect: func(m *mock_ec2iface.MockEC2APIMockRecorder) {
+				describeInstance(m)
+				m.DescribeVolumesModifications(gomock.AssignableToTypeOf(&ec2.DescribeVolumesModificationsInput{})).
+					Return(&ec2.DescribeVolumesModificationsOutput{
+						VolumesModifications: []*ec2.VolumeModification{
+							{VolumeId: aws.String("vol-root"), StartTime: aws.Time(fakeNow.Add(-7 * time.Hour))},
+						},
+					}, nil)
+				m.ModifyVolume(gomock.Eq(&ec2.ModifyVolumeInput{
+					VolumeId: aws.String("vol-root"),
+					Size:     aws.Int64(50),
+				})).
+					Return(&ec2.ModifyVolumeOutput{}, nil)
+			},
+		},
+		{
+			name: "volume modified less than six hours ago is left alone",
+			size: 50,
+			expect: func(m *mock_ec2iface.MockEC2APIMockRecorder) {
+				describeInstance(m)
+				m.DescribeVolumesModifications(gomock.AssignableToTypeOf(&ec2.DescribeVolumesModificationsInput{})).
+					Return(&ec2.DescribeVolumesModificationsOutput{
+						VolumesModifications: []*ec2.VolumeModification{
+							{VolumeId: aws.String("vol-root"), StartTime: aws.Time(fakeNow.Add(-2 * time.Hour))},
+						},
+					}, nil)
+			},
+			expectedWait: 4 * time.Hour,
+		},
+		{
+			name: "volume is never shrunk",
+			size: 10,
+			expect: func(m *mock_ec2iface.MockEC2APIMockRecorder) {
+				describeInstance(m)
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ec2Mock := mock_ec2iface.NewMockEC2API(mockCtrl)
+
+			scope, err := scope.NewClusterScope(scope.ClusterScopeParams{
+				Cluster: &clusterv1.Cluster{
+					ObjectMeta: metav1.ObjectMeta{Name: "test-cluster"},
+				},
+				AWSClients: scope.AWSClients{
+					EC2: ec2Mock,
+				},
+				AWSCluster: &infrav1.AWSCluster{},
+			})
+			if err != nil {
+				t.Fatalf("Failed to create test context: %v", err)
+			}
+
+			tc.expect(ec2Mock.EXPECT())
+
+			s := NewService(scope)
+			wait, err := s.GrowRootVolume("i-1", tc.size)
+			if err != nil {
+				t.Fatalf("got an unexpected error: %v", err)
+			}
+			if wait != tc.expectedWait {
+				t.Fatalf("expected to wait %s, got %s", tc.expectedWait, wait)
+			}
+		})
+	}
+}
@@ -42,7 +42,10 @@ func (s *Service) reconcileVPC() error {
 	s.scope.V(2).Info("Reconciling VPC")
 
 	vpc, err := s.describeVPC()
-	if awserrors.IsNotFound(err) {
+	if awserrors.IsNotFound(err) && s.scope.VPC().ExternallyManaged {
+		record.Warnf(s.scope.AWSCluster, "FailedDescribeVPC", "Externally managed VPC %q not found", s.scope.VPC().ID)
+		return errors.Wrapf(err, "externally managed vpc %q not found", s.scope.VPC().ID)
+	} else if awserrors.IsNotFound(err) {
 		// Create a new managed vpc.
 		vpc, err = s.createVPC()
 		if err != nil {
@@ -52,6 +55,9 @@ func (s *Service) reconcileVPC() error {
 	} else if err != nil {
 		return errors.Wrap(err, "failed to describe VPCs")
 	}
+	vpc.EnableIPv6 = s.scope.VPC().EnableIPv6
+	vpc.SecondaryCidrBlocks = s.scope.VPC().SecondaryCidrBlocks
+	vpc.ExternallyManaged = s.scope.VPC().ExternallyManaged
 
 	if vpc.IsUnmanaged(s.scope.Name()) {
 		vpc.DeepCopyInto(s.scope.VPC())
@@ -64,6 +70,7 @@ func (s *Service) reconcileVPC() error {
 		if err := tags.Ensure(vpc.Tags, &tags.ApplyParams{
 			EC2Client:   s.scope.EC2,
 			BuildParams: s.getVPCTagParams(vpc.ID),
+			Removed:     s.scope.RemovedAdditionalTags(),
 		}); err != nil {
 			return false, err
 		}
@@ -83,6 +90,16 @@ func (s *Service) reconcileVPC() error {
 		return errors.Wrapf(err, "failed to to set vpc attributes for %q", vpc.ID)
 	}
 
+	if vpc.EnableIPv6 && !vpc.IsDualStack() {
+		if err := s.ensureVPCIPv6CidrBlock(vpc); err != nil {
+			return err
+		}
+	}
+
+	if err := s.ensureVPCSecondaryCidrBlocks(vpc); err != nil {
+		return err
+	}
+
 	vpc.DeepCopyInto(s.scope.VPC())
 	s.scope.V(2).Info("Working on managed VPC", "vpc-id", vpc.ID)
 	return nil
@@ -157,6 +174,9 @@ func (s *Service) createVPC() (*infrav1.VPCSpec, error) {
 	input := &ec2.CreateVpcInput{
 		CidrBlock: aws.String(s.scope.VPC().CidrBlock),
 	}
+	if s.scope.VPC().EnableIPv6 {
+		input.AmazonProvidedIpv6CidrBlock = aws.Bool(true)
+	}
 
 	out, err := s.scope.EC2.CreateVpc(input)
 	if err != nil {
@@ -199,6 +219,180 @@ func (s *Service) createVPC() (*infrav1.VPCSpec, error) {
 	}, nil
 }
 
+// ensureVPCIPv6CidrBlock requests an Amazon-provided IPv6 CIDR block for the
+// VPC, unless one is already being associated, and waits for it to be
+// associated since subnets can only get an IPv6 CIDR block afterwards.
+func (s *Service) ensureVPCIPv6CidrBlock(vpc *infrav1.VPCSpec) error {
+	out, err := s.scope.EC2.DescribeVpcs(&ec2.DescribeVpcsInput{VpcIds: []*string{aws.String(vpc.ID)}})
+	if err != nil {
+		return errors.Wrapf(err, "failed to describe vpc %q", vpc.ID)
+	}
+
+	associating := false
+	for _, v := range out.Vpcs {
+		for _, association := range v.Ipv6CidrBlockAssociationSet {
+			if association.Ipv6CidrBlockState != nil && aws.StringValue(association.Ipv6CidrBlockState.State) == ec2.VpcCidrBlockStateCodeAssociating {
+				associating = true
+			}
+		}
+	}
+
+	if !associating {
+		if _, err := s.scope.EC2.AssociateVpcCidrBlock(&ec2.AssociateVpcCidrBlockInput{
+			VpcId:                       aws.String(vpc.ID),
+			AmazonProvidedIpv6CidrBlock: aws.Bool(true),
+		}); err != nil {
+			record.Warnf(s.scope.AWSCluster, "FailedAssociateVPCIPv6CidrBlock", "Failed to associate an IPv6 CIDR block with managed VPC %q: %v", vpc.ID, err)
+			return errors.Wrapf(err, "failed to associate an IPv6 cidr block with vpc %q", vpc.ID)
+		}
+		record.Eventf(s.scope.AWSCluster, "SuccessfulAssociateVPCIPv6CidrBlock", "Requested an IPv6 CIDR block for managed VPC %q", vpc.ID)
+	}
+
+	if err := wait.WaitForWithRetryable(wait.NewBackoff(), func() (bool, error) {
+		out, err := s.scope.EC2.DescribeVpcs(&ec2.DescribeVpcsInput{VpcIds: []*string{aws.String(vpc.ID)}})
+		if err != nil {
+			return false, err
+		}
+		for _, v := range out.Vpcs {
+			vpc.IPv6CidrBlock = vpcIPv6CidrBlock(v)
+		}
+		return vpc.IsDualStack(), nil
+	}, awserrors.VPCNotFound); err != nil {
+		return errors.Wrapf(err, "failed to wait for the IPv6 cidr block of vpc %q", vpc.ID)
+	}
+
+	s.scope.V(2).Info("Associated IPv6 cidr block with VPC", "vpc-id", vpc.ID, "ipv6-cidr-block", vpc.IPv6CidrBlock)
+	return nil
+}
+
+// ensureVPCSecondaryCidrBlocks associates the secondary CIDR blocks of the
+// spec which are not associated with the VPC yet, and waits for them to be
+// associated since subnets can only use them afterwards.
+func (s *Service) ensureVPCSecondaryCidrBlocks(vpc *infrav1.VPCSpec) error {
+	if len(vpc.SecondaryCidrBlocks) == 0 {
+		return nil
+	}
+
+	out, err := s.scope.EC2.DescribeVpcs(&ec2.DescribeVpcsInput{VpcIds: []*string{aws.String(vpc.ID)}})
+	if err != nil {
+		return errors.Wrapf(err, "failed to describe vpc %q", vpc.ID)
+	}
+	if len(out.Vpcs) == 0 {
+		return awserrors.NewNotFound(errors.Errorf("could not find vpc %q", vpc.ID))
+	}
+
+	states := vpcCidrBlockStates(out.Vpcs[0])
+	pending := []string{}
+	for _, cidr := range vpc.SecondaryCidrBlocks {
+		switch states[cidr] {
+		case ec2.VpcCidrBlockStateCodeAssociated:
+			continue
+		case ec2.VpcCidrBlockStateCodeAssociating:
+		default:
+			if _, err := s.scope.EC2.AssociateVpcCidrBlock(&ec2.AssociateVpcCidrBlockInput{
+				VpcId:     aws.String(vpc.ID),
+				CidrBlock: aws.String(cidr),
+			}); err != nil {
+				record.Warnf(s.scope.AWSCluster, "FailedAssociateVPCCidrBlock", "Failed to associate CIDR block %q with managed VPC %q: %v", cidr, vpc.ID, err)
+				return errors.Wrapf(err, "failed to associate cidr block %q with vpc %q", cidr, vpc.ID)
+			}
+			record.Eventf(s.scope.AWSCluster, "SuccessfulAssociateVPCCidrBlock", "Requested CIDR block %q for managed VPC %q", cidr, vpc.ID)
+		}
+		pending = append(pending, cidr)
+	}
+
+	if len(pending) == 0 {
+		return nil
+	}
+
+	if err := wait.WaitForWithRetryable(wait.NewBackoff(), func() (bool, error) {
+		out, err := s.scope.EC2.DescribeVpcs(&ec2.DescribeVpcsInput{VpcIds: []*string{aws.String(vpc.ID)}})
+		if err != nil {
+			return false, err
+		}
+		if len(out.Vpcs) == 0 {
+			return false, nil
+		}
+		states := vpcCidrBlockStates(out.Vpcs[0])
+		for _, cidr := range pending {
+			switch states[cidr] {
+			case ec2.VpcCidrBlockStateCodeAssociated:
+			case ec2.VpcCidrBlockStateCodeFailing, ec2.VpcCidrBlockStateCodeFailed:
+				return false, errors.Errorf("association of cidr block %q failed", cidr)
+			default:
+				return false, nil
+			}
+		}
+		return true, nil
+	}, awserrors.VPCNotFound); err != nil {
+		record.Warnf(s.scope.AWSCluster, "FailedAssociateVPCCidrBlock", "Failed to associate CIDR blocks %v with managed VPC %q: %v", pending, vpc.ID, err)
+		return errors.Wrapf(err, "failed to wait for the cidr blocks of vpc %q", vpc.ID)
+	}
+
+	s.scope.V(2).Info("Associated secondary cidr blocks with VPC", "vpc-id", vpc.ID, "cidr-blocks", pending)
+	return nil
+}
+
+// vpcCidrBlockStates returns the state of each IPv4 CIDR block association
+// of the VPC, keyed by CIDR block. Disassociated blocks are left out.
+func vpcCidrBlockStates(v *ec2.Vpc) map[string]string {
+	states := map[string]string{}
+	for _, association := range v.CidrBlockAssociationSet {
+		if association.CidrBlockState == nil {
+			continue
+		}
+		state := aws.StringValue(association.CidrBlockState.State)
+		if state == ec2.VpcCidrBlockStateCodeDisassociated {
+			continue
+		}
+		states[aws.StringValue(association.CidrBlock)] = state
+	}
+	return states
+}
+
+// deleteVPCSecondaryCidrBlocks disassociates the secondary CIDR blocks of a
+// managed VPC, newest first. The subnets using them must be deleted before.
+func (s *Service) deleteVPCSecondaryCidrBlocks() error {
+	vpc := s.scope.VPC()
+
+	if vpc.IsUnmanaged(s.scope.Name()) || vpc.ID == "" {
+		return nil
+	}
+
+	out, err := s.scope.EC2.DescribeVpcs(&ec2.DescribeVpcsInput{VpcIds: []*string{aws.String(vpc.ID)}})
+	if err != nil {
+		if code, ok := awserrors.Code(err); ok && code == awserrors.VPCNotFound {
+			return nil
+		}
+		return errors.Wrapf(err, "failed to describe vpc %q", vpc.ID)
+	}
+
+	for _, v := range out.Vpcs {
+		associations := v.CidrBlockAssociationSet
+		for i := len(associations) - 1; i >= 0; i-- {
+			association := associations[i]
+			cidr := aws.StringValue(association.CidrBlock)
+			if cidr == aws.StringValue(v.CidrBlock) {
+				// The primary CIDR block goes away with the VPC.
+				continue
+			}
+			if association.CidrBlockState == nil || aws.StringValue(association.CidrBlockState.State) != ec2.VpcCidrBlockStateCodeAssociated {
+				continue
+			}
+			if _, err := s.scope.EC2.DisassociateVpcCidrBlock(&ec2.DisassociateVpcCidrBlockInput{
+				AssociationId: association.AssociationId,
+			}); err != nil {
+				record.Warnf(s.scope.AWSCluster, "FailedDisassociateVPCCidrBlock", "Failed to disassociate CIDR block %q from managed VPC %q: %v", cidr, vpc.ID, err)
+				return errors.Wrapf(err, "failed to disassociate cidr block %q from vpc %q", cidr, vpc.ID)
+			}
+			s.scope.V(2).Info("Disassociated cidr block from VPC", "vpc-id", vpc.ID, "cidr-block", cidr)
+			record.Eventf(s.scope.AWSCluster, "SuccessfulDisassociateVPCCidrBlock", "Disassociated CIDR block %q from managed VPC %q", cidr, vpc.ID)
+		}
+	}
+
+	return nil
+}
+
 func (s *Service) deleteVPC() error {
 	vpc := s.scope.VPC()
 
@@ -271,12 +465,23 @@ func (s *Service) describeVPC() (*infrav1.VPCSpec, error) {
 	}
 
 	return &infrav1.VPCSpec{
-		ID:        *out.Vpcs[0].VpcId,
-		CidrBlock: *out.Vpcs[0].CidrBlock,
-		Tags:      converters.TagsToMap(out.Vpcs[0].Tags),
+		ID:            *out.Vpcs[0].VpcId,
+		CidrBlock:     *out.Vpcs[0].CidrBlock,
+		IPv6CidrBlock: vpcIPv6CidrBlock(out.Vpcs[0]),
+		Tags:          converters.TagsToMap(out.Vpcs[0].Tags),
 	}, nil
 }
 
+// vpcIPv6CidrBlock returns the IPv6 CIDR block associated with the VPC, if any.
+func vpcIPv6CidrBlock(v *ec2.Vpc) string {
+	for _, association := range v.Ipv6CidrBlockAssociationSet {
+		if association.Ipv6CidrBlockState != nil && aws.StringValue(association.Ipv6CidrBlockState.State) == ec2.VpcCidrBlockStateCodeAssociated {
+			return aws.StringValue(association.Ipv6CidrBlock)
+		}
+	}
+	return ""
+}
+
 func (s *Service) getVPCTagParams(id string) infrav1.BuildParams {
 	name := fmt.Sprintf("%s-vpc", s.scope.Name())
 
@@ -17,6 +17,7 @@ limitations under the License.
 package ec2
 
 import (
+	"fmt"
 	"reflect"
 	"testing"
 
@@ -111,6 +112,29 @@ func TestReconcileVPC(t *testing.T) {
 					DoAndReturn(describeVpcAttributeTrue).AnyTimes()
 			},
 		},
+		{
+			name:   "externally managed vpc tagged as owned is not modified",
+			input:  &infrav1.VPCSpec{ID: "vpc-external", ExternallyManaged: true},
+			output: &infrav1.VPCSpec{ID: "vpc-external", ExternallyManaged: true, CidrBlock: "10.0.0.0/8"},
+			expect: func(m *mock_ec2iface.MockEC2APIMockRecorder) {
+				m.DescribeVpcs(gomock.AssignableToTypeOf(&ec2.DescribeVpcsInput{})).
+					Return(&ec2.DescribeVpcsOutput{
+						Vpcs: []*ec2.Vpc{
+							{
+								State:     aws.String("available"),
+								VpcId:     aws.String("vpc-external"),
+								CidrBlock: aws.String("10.0.0.0/8"),
+								Tags: []*ec2.Tag{
+									{
+										Key:   aws.String("sigs.k8s.io/cluster-api-provider-aws/cluster/test-cluster"),
+										Value: aws.String("owned"),
+									},
+								},
+							},
+						},
+					}, nil)
+			},
+		},
 		{
 			name:   "managed vpc does not exist",
 			input:  &infrav1.VPCSpec{},
@@ -192,3 +216,123 @@ func TestReconcileVPC(t *testing.T) {
 		})
 	}
 }
+
+func TestEnsureVPCSecondaryCidrBlocks(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	ec2Mock := mock_ec2iface.NewMockEC2API(mockCtrl)
+
+	scope, err := scope.NewClusterScope(scope.ClusterScopeParams{
+		Cluster: &clusterv1.Cluster{
+			ObjectMeta: metav1.ObjectMeta{Name: "test-cluster"},
+		},
+		AWSClients: scope.AWSClients{
+			EC2: ec2Mock,
+		},
+		AWSCluster: &infrav1.AWSCluster{},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create test context: %v", err)
+	}
+
+	vpcWithStates := func(states ...string) *ec2.DescribeVpcsOutput {
+		vpc := &ec2.Vpc{
+			VpcId:     aws.String("vpc-1"),
+			CidrBlock: aws.String("10.0.0.0/16"),
+			CidrBlockAssociationSet: []*ec2.VpcCidrBlockAssociation{
+				{
+					AssociationId:  aws.String("vpc-cidr-assoc-0"),
+					CidrBlock:      aws.String("10.0.0.0/16"),
+					CidrBlockState: &ec2.VpcCidrBlockState{State: aws.String(ec2.VpcCidrBlockStateCodeAssociated)},
+				},
+			},
+		}
+		cidrs := []string{"10.1.0.0/16", "10.2.0.0/16"}
+		for i, state := range states {
+			vpc.CidrBlockAssociationSet = append(vpc.CidrBlockAssociationSet, &ec2.VpcCidrBlockAssociation{
+				AssociationId:  aws.String(fmt.Sprintf("vpc-cidr-assoc-%d", i+1)),
+				CidrBlock:      aws.String(cidrs[i]),
+				CidrBlockState: &ec2.VpcCidrBlockState{State: aws.String(state)},
+			})
+		}
+		return &ec2.DescribeVpcsOutput{Vpcs: []*ec2.Vpc{vpc}}
+	}
+
+	gomock.InOrder(
+		ec2Mock.EXPECT().DescribeVpcs(gomock.AssignableToTypeOf(&ec2.DescribeVpcsInput{})).
+			Return(vpcWithStates(ec2.VpcCidrBlockStateCodeAssociated), nil),
+		ec2Mock.EXPECT().AssociateVpcCidrBlock(&ec2.AssociateVpcCidrBlockInput{
+			VpcId:     aws.String("vpc-1"),
+			CidrBlock: aws.String("10.2.0.0/16"),
+		}).Return(&ec2.AssociateVpcCidrBlockOutput{}, nil),
+		ec2Mock.EXPECT().DescribeVpcs(gomock.AssignableToTypeOf(&ec2.DescribeVpcsInput{})).
+			Return(vpcWithStates(ec2.VpcCidrBlockStateCodeAssociated, ec2.VpcCidrBlockStateCodeAssociated), nil),
+	)
+
+	s := NewService(scope)
+	vpc := &infrav1.VPCSpec{
+		ID:                  "vpc-1",
+		CidrBlock:           "10.0.0.0/16",
+		SecondaryCidrBlocks: []string{"10.1.0.0/16", "10.2.0.0/16"},
+	}
+	if err := s.ensureVPCSecondaryCidrBlocks(vpc); err != nil {
+		t.Fatalf("got an unexpected error: %v", err)
+	}
+}
+
+func TestDeleteVPCSecondaryCidrBlocks(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	ec2Mock := mock_ec2iface.NewMockEC2API(mockCtrl)
+
+	scope, err := scope.NewClusterScope(scope.ClusterScopeParams{
+		Cluster: &clusterv1.Cluster{
+			ObjectMeta: metav1.ObjectMeta{Name: "test-cluster"},
+		},
+		AWSClients: scope.AWSClients{
+			EC2: ec2Mock,
+		},
+		AWSCluster: &infrav1.AWSCluster{
+			Spec: infrav1.AWSClusterSpec{
+				NetworkSpec: infrav1.NetworkSpec{
+					VPC: infrav1.VPCSpec{
+						ID:   "vpc-1",
+						Tags: infrav1.Tags{infrav1.ClusterTagKey("test-cluster"): string(infrav1.ResourceLifecycleOwned)},
+					},
+				},
+			},
+		},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create test context: %v", err)
+	}
+
+	associated := &ec2.VpcCidrBlockState{State: aws.String(ec2.VpcCidrBlockStateCodeAssociated)}
+	ec2Mock.EXPECT().DescribeVpcs(gomock.AssignableToTypeOf(&ec2.DescribeVpcsInput{})).
+		Return(&ec2.DescribeVpcsOutput{
+			Vpcs: []*ec2.Vpc{
+				{
+					VpcId:     aws.String("vpc-1"),
+					CidrBlock: aws.String("10.0.0.0/16"),
+					CidrBlockAssociationSet: []*ec2.VpcCidrBlockAssociation{
+						{AssociationId: aws.String("vpc-cidr-assoc-0"), CidrBlock: aws.String("10.0.0.0/16"), CidrBlockState: associated},
+						{AssociationId: aws.String("vpc-cidr-assoc-1"), CidrBlock: aws.String("10.1.0.0/16"), CidrBlockState: associated},
+						{AssociationId: aws.String("vpc-cidr-assoc-2"), CidrBlock: aws.String("10.2.0.0/16"), CidrBlockState: associated},
+					},
+				},
+			},
+		}, nil)
+	gomock.InOrder(
+		ec2Mock.EXPECT().DisassociateVpcCidrBlock(&ec2.DisassociateVpcCidrBlockInput{AssociationId: aws.String("vpc-cidr-assoc-2")}).
+			Return(&ec2.DisassociateVpcCidrBlockOutput{}, nil),
+		ec2Mock.EXPECT().DisassociateVpcCidrBlock(&ec2.DisassociateVpcCidrBlockInput{AssociationId: aws.String("vpc-cidr-assoc-1")}).
+			Return(&ec2.DisassociateVpcCidrBlockOutput{}, nil),
+	)
+
+	s := NewService(scope)
+	if err := s.deleteVPCSecondaryCidrBlocks(); err != nil {
+		t.Fatalf("got an unexpected error: %v", err)
+	}
+}
@@ -0,0 +1,365 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ec2
+
+import (
+	"fmt"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/pkg/errors"
+	"k8s.io/apimachinery/pkg/util/sets"
+	infrav1 "sigs.k8s.io/cluster-api-provider-aws/api/v1alpha3"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/awserrors"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/converters"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/filter"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/services/wait"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/tags"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/record"
+)
+
+// vpcEndpointActiveStates are the states of the VPC endpoints which are not
+// being deleted.
+var vpcEndpointActiveStates = []string{"pendingAcceptance", "pending", "available", "rejected", "failed"}
+
+func (s *Service) reconcileVPCEndpoints() error {
+	if s.scope.VPC().IsUnmanaged(s.scope.Name()) {
+		s.scope.V(4).Info("Skipping VPC endpoints reconcile in unmanaged mode")
+		return nil
+	}
+
+	s.scope.V(2).Info("Reconciling VPC endpoints")
+
+	existing, err := s.describeVPCEndpoints(vpcEndpointActiveStates...)
+	if err != nil {
+		return err
+	}
+
+	current := make(map[string]*ec2.VpcEndpoint, len(existing))
+	for _, endpoint := range existing {
+		current[aws.StringValue(endpoint.ServiceName)] = endpoint
+	}
+
+	endpoints := s.scope.VPCEndpoints()
+	for i := range endpoints {
+		spec := &endpoints[i]
+		serviceName := s.getVPCEndpointServiceName(spec.Service)
+
+		endpoint, ok := current[serviceName]
+		delete(current, serviceName)
+
+		// The type of an endpoint can't be changed, replace it.
+		if ok && aws.StringValue(endpoint.VpcEndpointType) != string(spec.Type) {
+			if err := s.removeVPCEndpoints([]*ec2.VpcEndpoint{endpoint}); err != nil {
+				return err
+			}
+			ok = false
+		}
+
+		if !ok {
+			if _, err := s.createVPCEndpoint(spec); err != nil {
+				return err
+			}
+			continue
+		}
+
+		// Make sure tags are up to date.
+		if err := wait.WaitForWithRetryable(wait.NewBackoff(), func() (bool, error) {
+			if err := tags.Ensure(converters.TagsToMap(endpoint.Tags), &tags.ApplyParams{
+				EC2Client:   s.scope.EC2,
+				BuildParams: s.getVPCEndpointTagParams(*endpoint.VpcEndpointId, spec.Service),
+				Removed:     s.scope.RemovedAdditionalTags(),
+			}); err != nil {
+				return false, err
+			}
+			return true, nil
+		}, awserrors.VPCEndpointNotFound); err != nil {
+			record.Warnf(s.scope.AWSCluster, "FailedTagVPCEndpoint", "Failed to tag managed VPCEndpoint %q: %v", *endpoint.VpcEndpointId, err)
+			return errors.Wrapf(err, "failed to ensure tags on VPC endpoint %q", *endpoint.VpcEndpointId)
+		}
+
+		if err := s.reconcileVPCEndpointAssociations(endpoint, spec); err != nil {
+			return err
+		}
+	}
+
+	// Remove the endpoints which are no longer wanted.
+	if len(current) > 0 {
+		unwanted := make([]*ec2.VpcEndpoint, 0, len(current))
+		for _, endpoint := range current {
+			unwanted = append(unwanted, endpoint)
+		}
+		if err := s.removeVPCEndpoints(unwanted); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// reconcileVPCEndpointAssociations makes sure a gateway endpoint is associated
+// with the private route tables, and an interface endpoint with the private
+// subnets and its security groups.
+func (s *Service) reconcileVPCEndpointAssociations(endpoint *ec2.VpcEndpoint, spec *infrav1.VPCEndpointSpec) error {
+	input := &ec2.ModifyVpcEndpointInput{VpcEndpointId: endpoint.VpcEndpointId}
+
+	switch spec.Type {
+	case infrav1.VPCEndpointTypeGateway:
+		want := sets.NewString(s.getVPCEndpointRouteTableIDs()...)
+		current := sets.NewString(aws.StringValueSlice(endpoint.RouteTableIds)...)
+		input.AddRouteTableIds = aws.StringSlice(want.Difference(current).List())
+		input.RemoveRouteTableIds = aws.StringSlice(current.Difference(want).List())
+
+	case infrav1.VPCEndpointTypeInterface:
+		want := sets.NewString(s.getPrivateSubnetIDPerZone()...)
+		current := sets.NewString(aws.StringValueSlice(endpoint.SubnetIds)...)
+		input.AddSubnetIds = aws.StringSlice(want.Difference(current).List())
+		input.RemoveSubnetIds = aws.StringSlice(current.Difference(want).List())
+
+		groupIDs, err := s.getVPCEndpointSecurityGroupIDs(spec)
+		if err != nil {
+			return err
+		}
+		wantGroups := sets.NewString(groupIDs...)
+		currentGroups := sets.NewString()
+		for _, group := range endpoint.Groups {
+			currentGroups.Insert(aws.StringValue(group.GroupId))
+		}
+		input.AddSecurityGroupIds = aws.StringSlice(wantGroups.Difference(currentGroups).List())
+		input.RemoveSecurityGroupIds = aws.StringSlice(currentGroups.Difference(wantGroups).List())
+	}
+
+	if len(input.AddRouteTableIds) == 0 && len(input.RemoveRouteTableIds) == 0 &&
+		len(input.AddSubnetIds) == 0 && len(input.RemoveSubnetIds) == 0 &&
+		len(input.AddSecurityGroupIds) == 0 && len(input.RemoveSecurityGroupIds) == 0 {
+		return nil
+	}
+
+	if _, err := s.scope.EC2.ModifyVpcEndpoint(input); err != nil {
+		record.Warnf(s.scope.AWSCluster, "FailedModifyVPCEndpoint", "Failed to modify managed VPCEndpoint %q: %v", *endpoint.VpcEndpointId, err)
+		return errors.Wrapf(err, "failed to modify VPC endpoint %q", *endpoint.VpcEndpointId)
+	}
+
+	record.Eventf(s.scope.AWSCluster, "SuccessfulModifyVPCEndpoint", "Modified managed VPCEndpoint %q", *endpoint.VpcEndpointId)
+	return nil
+}
+
+func (s *Service) deleteVPCEndpoints() error {
+	if s.scope.VPC().IsUnmanaged(s.scope.Name()) {
+		s.scope.V(4).Info("Skipping VPC endpoints deletion in unmanaged mode")
+		return nil
+	}
+
+	endpoints, err := s.describeVPCEndpoints(vpcEndpointActiveStates...)
+	if err != nil {
+		return err
+	}
+
+	if len(endpoints) == 0 {
+		return nil
+	}
+
+	return s.removeVPCEndpoints(endpoints)
+}
+
+func (s *Service) describeVPCEndpoints(states ...string) ([]*ec2.VpcEndpoint, error) {
+	input := &ec2.DescribeVpcEndpointsInput{
+		Filters: []*ec2.Filter{
+			filter.EC2.VPC(s.scope.VPC().ID),
+			filter.EC2.ClusterOwned(s.scope.Name()),
+			filter.EC2.VPCEndpointStates(states...),
+		},
+	}
+
+	var endpoints []*ec2.VpcEndpoint
+	for {
+		out, err := s.scope.EC2.DescribeVpcEndpoints(input)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to describe VPC endpoints in vpc %q", s.scope.VPC().ID)
+		}
+
+		endpoints = append(endpoints, out.VpcEndpoints...)
+
+		if aws.StringValue(out.NextToken) == "" {
+			return endpoints, nil
+		}
+		input.NextToken = out.NextToken
+	}
+}
+
+func (s *Service) createVPCEndpoint(spec *infrav1.VPCEndpointSpec) (*ec2.VpcEndpoint, error) {
+	input := &ec2.CreateVpcEndpointInput{
+		VpcId:           aws.String(s.scope.VPC().ID),
+		ServiceName:     aws.String(s.getVPCEndpointServiceName(spec.Service)),
+		VpcEndpointType: aws.String(string(spec.Type)),
+	}
+
+	switch spec.Type {
+	case infrav1.VPCEndpointTypeGateway:
+		input.RouteTableIds = aws.StringSlice(s.getVPCEndpointRouteTableIDs())
+	case infrav1.VPCEndpointTypeInterface:
+		groupIDs, err := s.getVPCEndpointSecurityGroupIDs(spec)
+		if err != nil {
+			return nil, err
+		}
+		input.SubnetIds = aws.StringSlice(s.getPrivateSubnetIDPerZone())
+		input.SecurityGroupIds = aws.StringSlice(groupIDs)
+		// Clients keep using the default DNS name of the service.
+		input.PrivateDnsEnabled = aws.Bool(true)
+	}
+
+	out, err := s.scope.EC2.CreateVpcEndpoint(input)
+	if err != nil {
+		record.Warnf(s.scope.AWSCluster, "FailedCreateVPCEndpoint", "Failed to create managed VPCEndpoint for service %q: %v", spec.Service, err)
+		return nil, errors.Wrapf(err, "failed to create VPC endpoint for service %q in vpc %q", spec.Service, s.scope.VPC().ID)
+	}
+	record.Eventf(s.scope.AWSCluster, "SuccessfulCreateVPCEndpoint", "Created managed VPCEndpoint %q for service %q", *out.VpcEndpoint.VpcEndpointId, spec.Service)
+
+	if err := wait.WaitForWithRetryable(wait.NewBackoff(), func() (bool, error) {
+		if err := tags.Apply(&tags.ApplyParams{
+			EC2Client:   s.scope.EC2,
+			BuildParams: s.getVPCEndpointTagParams(*out.VpcEndpoint.VpcEndpointId, spec.Service),
+		}); err != nil {
+			return false, err
+		}
+		return true, nil
+	}, awserrors.VPCEndpointNotFound); err != nil {
+		record.Warnf(s.scope.AWSCluster, "FailedTagVPCEndpoint", "Failed to tag managed VPCEndpoint %q: %v", *out.VpcEndpoint.VpcEndpointId, err)
+		return nil, errors.Wrapf(err, "failed to tag VPC endpoint %q", *out.VpcEndpoint.VpcEndpointId)
+	}
+	record.Eventf(s.scope.AWSCluster, "SuccessfulTagVPCEndpoint", "Tagged managed VPCEndpoint %q", *out.VpcEndpoint.VpcEndpointId)
+
+	s.scope.V(2).Info("Created VPC endpoint", "vpc-endpoint-id", *out.VpcEndpoint.VpcEndpointId, "service", spec.Service)
+	return out.VpcEndpoint, nil
+}
+
+// removeVPCEndpoints deletes the given VPC endpoints and waits for them to be
+// gone, as interface endpoints hold network interfaces in the subnets and the
+// VPC endpoint security group until then.
+func (s *Service) removeVPCEndpoints(endpoints []*ec2.VpcEndpoint) error {
+	ids := make([]string, 0, len(endpoints))
+	for _, endpoint := range endpoints {
+		ids = append(ids, aws.StringValue(endpoint.VpcEndpointId))
+	}
+
+	out, err := s.scope.EC2.DeleteVpcEndpoints(&ec2.DeleteVpcEndpointsInput{
+		VpcEndpointIds: aws.StringSlice(ids),
+	})
+	if err == nil && len(out.Unsuccessful) > 0 {
+		item := out.Unsuccessful[0]
+		err = errors.Errorf("%s: %s", aws.StringValue(item.Error.Code), aws.StringValue(item.Error.Message))
+	}
+	if err != nil {
+		record.Warnf(s.scope.AWSCluster, "FailedDeleteVPCEndpoint", "Failed to delete managed VPCEndpoints %v: %v", ids, err)
+		return errors.Wrapf(err, "failed to delete VPC endpoints %v", ids)
+	}
+	record.Eventf(s.scope.AWSCluster, "SuccessfulDeleteVPCEndpoint", "Deleted managed VPCEndpoints %v", ids)
+
+	deleting := sets.NewString(ids...)
+	if err := wait.WaitForWithRetryable(wait.NewBackoff(), func() (bool, error) {
+		remaining, err := s.describeVPCEndpoints(append(vpcEndpointActiveStates, "deleting")...)
+		if err != nil {
+			return false, err
+		}
+
+		for _, endpoint := range remaining {
+			if deleting.Has(aws.StringValue(endpoint.VpcEndpointId)) {
+				return false, nil
+			}
+		}
+		return true, nil
+	}); err != nil {
+		return errors.Wrapf(err, "failed to wait for VPC endpoints deletion %v", ids)
+	}
+
+	s.scope.Info("Deleted VPC endpoints", "vpc-endpoint-ids", ids)
+	return nil
+}
+
+// hasInterfaceVPCEndpoints returns whether interface VPC endpoints without
+// security groups of their own are configured, they need the VPC endpoint
+// security group.
+func (s *Service) hasInterfaceVPCEndpoints() bool {
+	for _, spec := range s.scope.VPCEndpoints() {
+		if spec.Type == infrav1.VPCEndpointTypeInterface && len(spec.SecurityGroups) == 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// getVPCEndpointSecurityGroupIDs returns the security groups of an interface
+// endpoint, the configured ones, which must be in the cluster VPC, or the VPC
+// endpoint security group.
+func (s *Service) getVPCEndpointSecurityGroupIDs(spec *infrav1.VPCEndpointSpec) ([]string, error) {
+	if len(spec.SecurityGroups) == 0 {
+		return []string{s.scope.SecurityGroups()[infrav1.SecurityGroupVPCEndpoint].ID}, nil
+	}
+
+	ids, err := s.getSecurityGroupIDs(spec.SecurityGroups)
+	if err != nil {
+		record.Warnf(s.scope.AWSCluster, "InvalidVPCEndpointSecurityGroups", "Cannot use the security groups of the VPC endpoint for service %q: %v", spec.Service, err)
+		return nil, errors.Wrapf(err, "failed to resolve security groups of VPC endpoint for service %q", spec.Service)
+	}
+	return ids, nil
+}
+
+// getVPCEndpointServiceName returns the name of the endpoint service of an
+// AWS service in the cluster region.
+func (s *Service) getVPCEndpointServiceName(service string) string {
+	return fmt.Sprintf("com.amazonaws.%s.%s", s.scope.Region(), service)
+}
+
+// getVPCEndpointRouteTableIDs returns the route tables of the private subnets.
+func (s *Service) getVPCEndpointRouteTableIDs() []string {
+	ids := sets.NewString()
+	for _, sn := range s.scope.Subnets().FilterPrivate() {
+		if sn.RouteTableID != nil {
+			ids.Insert(*sn.RouteTableID)
+		}
+	}
+	return ids.List()
+}
+
+// getPrivateSubnetIDPerZone returns a private subnet per availability zone,
+// interface endpoints and transit gateway attachments can only have one
+// network interface per zone.
+func (s *Service) getPrivateSubnetIDPerZone() []string {
+	zones := sets.NewString()
+	ids := []string{}
+	for _, sn := range s.scope.Subnets().FilterPrivate() {
+		if sn.ID == "" || zones.Has(sn.AvailabilityZone) {
+			continue
+		}
+		zones.Insert(sn.AvailabilityZone)
+		ids = append(ids, sn.ID)
+	}
+	return ids
+}
+
+func (s *Service) getVPCEndpointTagParams(id string, service string) infrav1.BuildParams {
+	name := fmt.Sprintf("%s-vpce-%s", s.scope.Name(), service)
+
+	return infrav1.BuildParams{
+		ClusterName: s.scope.Name(),
+		ResourceID:  id,
+		Lifecycle:   infrav1.ResourceLifecycleOwned,
+		Name:        aws.String(name),
+		Role:        aws.String(infrav1.CommonRoleTagValue),
+		Additional:  s.scope.AdditionalTags(),
+	}
+}
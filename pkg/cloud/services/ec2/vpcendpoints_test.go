@@ -0,0 +1,323 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ec2
+
+import (
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/golang/mock/gomock"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	infrav1 "sigs.k8s.io/cluster-api-provider-aws/api/v1alpha3"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/scope"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/services/ec2/mock_ec2iface"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1alpha3"
+)
+
+func TestReconcileVPCEndpoints(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	testCases := []struct {
+		name        string
+		spec        []infrav1.VPCEndpointSpec
+		expect      func(m *mock_ec2iface.MockEC2APIMockRecorder)
+		expectError bool
+	}{
+		{
+			name: "gateway and interface endpoints are created in the private route tables and subnets",
+			spec: []infrav1.VPCEndpointSpec{
+				{Service: "s3", Type: infrav1.VPCEndpointTypeGateway},
+				{Service: "ecr.api", Type: infrav1.VPCEndpointTypeInterface},
+			},
+			expect: func(m *mock_ec2iface.MockEC2APIMockRecorder) {
+				m.DescribeVpcEndpoints(gomock.AssignableToTypeOf(&ec2.DescribeVpcEndpointsInput{})).
+					Return(&ec2.DescribeVpcEndpointsOutput{}, nil)
+				m.CreateVpcEndpoint(gomock.Eq(&ec2.CreateVpcEndpointInput{
+					VpcId:           aws.String("vpc-endpoints"),
+					ServiceName:     aws.String("com.amazonaws.us-east-1.s3"),
+					VpcEndpointType: aws.String("Gateway"),
+					RouteTableIds:   aws.StringSlice([]string{"rtb-private-a", "rtb-private-b"}),
+				})).
+					Return(&ec2.CreateVpcEndpointOutput{
+						VpcEndpoint: &ec2.VpcEndpoint{VpcEndpointId: aws.String("vpce-s3")},
+					}, nil)
+				m.CreateVpcEndpoint(gomock.Eq(&ec2.CreateVpcEndpointInput{
+					VpcId:             aws.String("vpc-endpoints"),
+					ServiceName:       aws.String("com.amazonaws.us-east-1.ecr.api"),
+					VpcEndpointType:   aws.String("Interface"),
+					SubnetIds:         aws.StringSlice([]string{"subnet-private-a1", "subnet-private-b"}),
+					SecurityGroupIds:  aws.StringSlice([]string{"sg-vpce"}),
+					PrivateDnsEnabled: aws.Bool(true),
+				})).
+					Return(&ec2.CreateVpcEndpointOutput{
+						VpcEndpoint: &ec2.VpcEndpoint{VpcEndpointId: aws.String("vpce-ecr-api")},
+					}, nil)
+				m.CreateTags(gomock.AssignableToTypeOf(&ec2.CreateTagsInput{})).
+					Return(&ec2.CreateTagsOutput{}, nil).
+					Times(2)
+			},
+		},
+		{
+			name: "existing endpoints are updated and unwanted ones deleted",
+			spec: []infrav1.VPCEndpointSpec{
+				{Service: "s3", Type: infrav1.VPCEndpointTypeGateway},
+			},
+			expect: func(m *mock_ec2iface.MockEC2APIMockRecorder) {
+				m.DescribeVpcEndpoints(gomock.AssignableToTypeOf(&ec2.DescribeVpcEndpointsInput{})).
+					Return(&ec2.DescribeVpcEndpointsOutput{
+						VpcEndpoints: []*ec2.VpcEndpoint{
+							{
+								VpcEndpointId:   aws.String("vpce-s3"),
+								ServiceName:     aws.String("com.amazonaws.us-east-1.s3"),
+								VpcEndpointType: aws.String("Gateway"),
+								RouteTableIds:   aws.StringSlice([]string{"rtb-private-a", "rtb-old"}),
+							},
+							{
+								VpcEndpointId:   aws.String("vpce-ecr-dkr"),
+								ServiceName:     aws.String("com.amazonaws.us-east-1.ecr.dkr"),
+								VpcEndpointType: aws.String("Interface"),
+							},
+						},
+					}, nil)
+				m.CreateTags(gomock.AssignableToTypeOf(&ec2.CreateTagsInput{})).
+					Return(&ec2.CreateTagsOutput{}, nil)
+				m.ModifyVpcEndpoint(gomock.Eq(&ec2.ModifyVpcEndpointInput{
+					VpcEndpointId:       aws.String("vpce-s3"),
+					AddRouteTableIds:    aws.StringSlice([]string{"rtb-private-b"}),
+					RemoveRouteTableIds: aws.StringSlice([]string{"rtb-old"}),
+				})).
+					Return(&ec2.ModifyVpcEndpointOutput{}, nil)
+				m.DeleteVpcEndpoints(gomock.Eq(&ec2.DeleteVpcEndpointsInput{
+					VpcEndpointIds: aws.StringSlice([]string{"vpce-ecr-dkr"}),
+				})).
+					Return(&ec2.DeleteVpcEndpointsOutput{}, nil)
+				m.DescribeVpcEndpoints(gomock.AssignableToTypeOf(&ec2.DescribeVpcEndpointsInput{})).
+					Return(&ec2.DescribeVpcEndpointsOutput{}, nil)
+			},
+		},
+		{
+			name: "interface endpoint is created with its own security groups",
+			spec: []infrav1.VPCEndpointSpec{
+				{
+					Service:        "sts",
+					Type:           infrav1.VPCEndpointTypeInterface,
+					SecurityGroups: []infrav1.AWSResourceReference{{ID: aws.String("sg-custom")}},
+				},
+			},
+			expect: func(m *mock_ec2iface.MockEC2APIMockRecorder) {
+				m.DescribeVpcEndpoints(gomock.AssignableToTypeOf(&ec2.DescribeVpcEndpointsInput{})).
+					Return(&ec2.DescribeVpcEndpointsOutput{}, nil)
+				m.DescribeSecurityGroups(gomock.Eq(&ec2.DescribeSecurityGroupsInput{
+					GroupIds: aws.StringSlice([]string{"sg-custom"}),
+					Filters: []*ec2.Filter{
+						{Name: aws.String("vpc-id"), Values: aws.StringSlice([]string{"vpc-endpoints"})},
+					},
+				})).
+					Return(&ec2.DescribeSecurityGroupsOutput{
+						SecurityGroups: []*ec2.SecurityGroup{{GroupId: aws.String("sg-custom")}},
+					}, nil)
+				m.CreateVpcEndpoint(gomock.Eq(&ec2.CreateVpcEndpointInput{
+					VpcId:             aws.String("vpc-endpoints"),
+					ServiceName:       aws.String("com.amazonaws.us-east-1.sts"),
+					VpcEndpointType:   aws.String("Interface"),
+					SubnetIds:         aws.StringSlice([]string{"subnet-private-a1", "subnet-private-b"}),
+					SecurityGroupIds:  aws.StringSlice([]string{"sg-custom"}),
+					PrivateDnsEnabled: aws.Bool(true),
+				})).
+					Return(&ec2.CreateVpcEndpointOutput{
+						VpcEndpoint: &ec2.VpcEndpoint{VpcEndpointId: aws.String("vpce-sts")},
+					}, nil)
+				m.CreateTags(gomock.AssignableToTypeOf(&ec2.CreateTagsInput{})).
+					Return(&ec2.CreateTagsOutput{}, nil)
+			},
+		},
+		{
+			name: "existing interface endpoint is moved to its own security groups",
+			spec: []infrav1.VPCEndpointSpec{
+				{
+					Service:        "sts",
+					Type:           infrav1.VPCEndpointTypeInterface,
+					SecurityGroups: []infrav1.AWSResourceReference{{ID: aws.String("sg-custom")}},
+				},
+			},
+			expect: func(m *mock_ec2iface.MockEC2APIMockRecorder) {
+				m.DescribeVpcEndpoints(gomock.AssignableToTypeOf(&ec2.DescribeVpcEndpointsInput{})).
+					Return(&ec2.DescribeVpcEndpointsOutput{
+						VpcEndpoints: []*ec2.VpcEndpoint{
+							{
+								VpcEndpointId:   aws.String("vpce-sts"),
+								ServiceName:     aws.String("com.amazonaws.us-east-1.sts"),
+								VpcEndpointType: aws.String("Interface"),
+								SubnetIds:       aws.StringSlice([]string{"subnet-private-a1", "subnet-private-b"}),
+								Groups:          []*ec2.SecurityGroupIdentifier{{GroupId: aws.String("sg-vpce")}},
+							},
+						},
+					}, nil)
+				m.CreateTags(gomock.AssignableToTypeOf(&ec2.CreateTagsInput{})).
+					Return(&ec2.CreateTagsOutput{}, nil)
+				m.DescribeSecurityGroups(gomock.AssignableToTypeOf(&ec2.DescribeSecurityGroupsInput{})).
+					Return(&ec2.DescribeSecurityGroupsOutput{
+						SecurityGroups: []*ec2.SecurityGroup{{GroupId: aws.String("sg-custom")}},
+					}, nil)
+				m.ModifyVpcEndpoint(gomock.Eq(&ec2.ModifyVpcEndpointInput{
+					VpcEndpointId:          aws.String("vpce-sts"),
+					AddSubnetIds:           []*string{},
+					RemoveSubnetIds:        []*string{},
+					AddSecurityGroupIds:    aws.StringSlice([]string{"sg-custom"}),
+					RemoveSecurityGroupIds: aws.StringSlice([]string{"sg-vpce"}),
+				})).
+					Return(&ec2.ModifyVpcEndpointOutput{}, nil)
+			},
+		},
+		{
+			name: "security group outside of the cluster vpc",
+			spec: []infrav1.VPCEndpointSpec{
+				{
+					Service:        "sts",
+					Type:           infrav1.VPCEndpointTypeInterface,
+					SecurityGroups: []infrav1.AWSResourceReference{{ID: aws.String("sg-other-vpc")}},
+				},
+			},
+			expect: func(m *mock_ec2iface.MockEC2APIMockRecorder) {
+				m.DescribeVpcEndpoints(gomock.AssignableToTypeOf(&ec2.DescribeVpcEndpointsInput{})).
+					Return(&ec2.DescribeVpcEndpointsOutput{}, nil)
+				m.DescribeSecurityGroups(gomock.AssignableToTypeOf(&ec2.DescribeSecurityGroupsInput{})).
+					Return(&ec2.DescribeSecurityGroupsOutput{}, nil)
+				m.CreateVpcEndpoint(gomock.Any()).Times(0)
+			},
+			expectError: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ec2Mock := mock_ec2iface.NewMockEC2API(mockCtrl)
+
+			scope, err := scope.NewClusterScope(scope.ClusterScopeParams{
+				Cluster: &clusterv1.Cluster{
+					ObjectMeta: metav1.ObjectMeta{Name: "test-cluster"},
+				},
+				AWSClients: scope.AWSClients{
+					EC2: ec2Mock,
+				},
+				AWSCluster: &infrav1.AWSCluster{
+					Spec: infrav1.AWSClusterSpec{
+						Region: "us-east-1",
+						NetworkSpec: infrav1.NetworkSpec{
+							VPC: infrav1.VPCSpec{
+								ID: "vpc-endpoints",
+								Tags: infrav1.Tags{
+									infrav1.ClusterTagKey("test-cluster"): "owned",
+								},
+							},
+							Subnets: infrav1.Subnets{
+								{ID: "subnet-public-a", AvailabilityZone: "us-east-1a", IsPublic: true, RouteTableID: aws.String("rtb-public")},
+								{ID: "subnet-private-a1", AvailabilityZone: "us-east-1a", RouteTableID: aws.String("rtb-private-a")},
+								{ID: "subnet-private-a2", AvailabilityZone: "us-east-1a", RouteTableID: aws.String("rtb-private-a")},
+								{ID: "subnet-private-b", AvailabilityZone: "us-east-1b", RouteTableID: aws.String("rtb-private-b")},
+							},
+							VPCEndpoints: tc.spec,
+						},
+					},
+					Status: infrav1.AWSClusterStatus{
+						Network: infrav1.Network{
+							SecurityGroups: map[infrav1.SecurityGroupRole]infrav1.SecurityGroup{
+								infrav1.SecurityGroupVPCEndpoint: {ID: "sg-vpce"},
+							},
+						},
+					},
+				},
+			})
+			if err != nil {
+				t.Fatalf("Failed to create test context: %v", err)
+			}
+
+			tc.expect(ec2Mock.EXPECT())
+
+			s := NewService(scope)
+			err = s.reconcileVPCEndpoints()
+			if tc.expectError && err == nil {
+				t.Fatal("expected an error")
+			}
+			if !tc.expectError && err != nil {
+				t.Fatalf("got an unexpected error: %v", err)
+			}
+		})
+	}
+}
+
+func TestHasInterfaceVPCEndpoints(t *testing.T) {
+	testCases := []struct {
+		name     string
+		spec     []infrav1.VPCEndpointSpec
+		expected bool
+	}{
+		{
+			name: "gateway endpoints only",
+			spec: []infrav1.VPCEndpointSpec{
+				{Service: "s3", Type: infrav1.VPCEndpointTypeGateway},
+			},
+			expected: false,
+		},
+		{
+			name: "interface endpoint using the managed security group",
+			spec: []infrav1.VPCEndpointSpec{
+				{Service: "s3", Type: infrav1.VPCEndpointTypeGateway},
+				{Service: "ecr.api", Type: infrav1.VPCEndpointTypeInterface},
+			},
+			expected: true,
+		},
+		{
+			name: "interface endpoints with their own security groups",
+			spec: []infrav1.VPCEndpointSpec{
+				{
+					Service:        "ecr.api",
+					Type:           infrav1.VPCEndpointTypeInterface,
+					SecurityGroups: []infrav1.AWSResourceReference{{ID: aws.String("sg-custom")}},
+				},
+			},
+			expected: false,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			scope, err := scope.NewClusterScope(scope.ClusterScopeParams{
+				Cluster: &clusterv1.Cluster{
+					ObjectMeta: metav1.ObjectMeta{Name: "test-cluster"},
+				},
+				AWSCluster: &infrav1.AWSCluster{
+					Spec: infrav1.AWSClusterSpec{
+						NetworkSpec: infrav1.NetworkSpec{
+							VPCEndpoints: tc.spec,
+						},
+					},
+				},
+			})
+			if err != nil {
+				t.Fatalf("Failed to create test context: %v", err)
+			}
+
+			s := NewService(scope)
+			if got := s.hasInterfaceVPCEndpoints(); got != tc.expected {
+				t.Fatalf("expected %v, got %v", tc.expected, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,403 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ec2
+
+import (
+	"fmt"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/pkg/errors"
+	infrav1 "sigs.k8s.io/cluster-api-provider-aws/api/v1alpha3"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/awserrors"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/converters"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/filter"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/services/wait"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/tags"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/record"
+)
+
+// vpcPeeringActiveStates are the states of the peering connections which are
+// neither being deleted nor given up, rejected or expired connections are
+// replaced.
+var vpcPeeringActiveStates = []string{
+	ec2.VpcPeeringConnectionStateReasonCodeInitiatingRequest,
+	ec2.VpcPeeringConnectionStateReasonCodePendingAcceptance,
+	ec2.VpcPeeringConnectionStateReasonCodeProvisioning,
+	ec2.VpcPeeringConnectionStateReasonCodeActive,
+}
+
+// reconcileVPCPeering peers the VPC with the configured VPC, replacing the
+// peering connections with other VPCs. The connection is accepted by the
+// controller when the peer VPC is in the same account and region, and the
+// configured route tables of the peer VPC get routes to the cluster VPC once
+// the connection is active.
+func (s *Service) reconcileVPCPeering() error {
+	if s.scope.VPC().IsUnmanaged(s.scope.Name()) {
+		s.scope.V(4).Info("Skipping VPC peering reconcile in unmanaged mode")
+		return nil
+	}
+
+	spec := s.scope.VPCPeering()
+	if spec == nil {
+		return nil
+	}
+
+	s.scope.V(2).Info("Reconciling VPC peering")
+
+	existing, err := s.describeVPCPeeringConnections(vpcPeeringActiveStates...)
+	if err != nil {
+		return err
+	}
+
+	var connection *ec2.VpcPeeringConnection
+	var unwanted []*ec2.VpcPeeringConnection
+	for _, c := range existing {
+		if connection == nil && s.isPeeredWith(c, spec) {
+			connection = c
+			continue
+		}
+		unwanted = append(unwanted, c)
+	}
+
+	for _, c := range unwanted {
+		if err := s.deleteVPCPeeringConnection(*c.VpcPeeringConnectionId); err != nil {
+			return err
+		}
+	}
+
+	if connection == nil {
+		connection, err = s.createVPCPeeringConnection(spec)
+		if err != nil {
+			return err
+		}
+	} else {
+		// Make sure tags are up to date.
+		if err := wait.WaitForWithRetryable(wait.NewBackoff(), func() (bool, error) {
+			if err := tags.Ensure(converters.TagsToMap(connection.Tags), &tags.ApplyParams{
+				EC2Client:   s.scope.EC2,
+				BuildParams: s.getVPCPeeringTagParams(*connection.VpcPeeringConnectionId),
+				Removed:     s.scope.RemovedAdditionalTags(),
+			}); err != nil {
+				return false, err
+			}
+			return true, nil
+		}, awserrors.VPCPeeringNotFound); err != nil {
+			record.Warnf(s.scope.AWSCluster, "FailedTagVPCPeeringConnection", "Failed to tag managed VPCPeeringConnection %q: %v", *connection.VpcPeeringConnectionId, err)
+			return errors.Wrapf(err, "failed to ensure tags on VPC peering connection %q", *connection.VpcPeeringConnectionId)
+		}
+	}
+
+	id := *connection.VpcPeeringConnectionId
+	spec.ConnectionID = aws.String(id)
+	if err := s.waitForVPCPeeringActive(id, s.canAcceptVPCPeering(spec)); err != nil {
+		return err
+	}
+
+	return s.reconcileVPCPeeringPeerRoutes(spec, id)
+}
+
+// reconcileVPCPeeringPeerRoutes routes the CIDR blocks of the cluster VPC from
+// the configured route tables of the peer VPC through the peering connection.
+func (s *Service) reconcileVPCPeeringPeerRoutes(spec *infrav1.VPCPeeringSpec, connectionID string) error {
+	if len(spec.PeerRouteTableIDs) == 0 {
+		return nil
+	}
+
+	out, err := s.scope.EC2.DescribeRouteTables(&ec2.DescribeRouteTablesInput{
+		RouteTableIds: aws.StringSlice(spec.PeerRouteTableIDs),
+	})
+	if err != nil {
+		return errors.Wrapf(err, "failed to describe route tables %v of peer vpc %q", spec.PeerRouteTableIDs, spec.PeerVPCID)
+	}
+
+	for _, rt := range out.RouteTables {
+		current := make(map[string]*ec2.Route, len(rt.Routes))
+		for _, route := range rt.Routes {
+			current[routeDestination(route)] = route
+		}
+
+		for _, cidr := range s.getVPCCidrBlocks() {
+			route := &ec2.Route{
+				DestinationCidrBlock:   aws.String(cidr),
+				VpcPeeringConnectionId: aws.String(connectionID),
+			}
+
+			currentRoute, ok := current[cidr]
+			if !ok {
+				if err := s.createRoute(*rt.RouteTableId, route); err != nil {
+					return err
+				}
+				continue
+			}
+
+			if aws.StringValue(currentRoute.VpcPeeringConnectionId) == connectionID {
+				continue
+			}
+
+			if _, err := s.scope.EC2.ReplaceRoute(&ec2.ReplaceRouteInput{
+				RouteTableId:           rt.RouteTableId,
+				DestinationCidrBlock:   route.DestinationCidrBlock,
+				VpcPeeringConnectionId: route.VpcPeeringConnectionId,
+			}); err != nil {
+				record.Warnf(s.scope.AWSCluster, "FailedReplaceRoute", "Failed to replace outdated route on peer RouteTable %q: %v", *rt.RouteTableId, err)
+				return errors.Wrapf(err, "failed to replace outdated route on route table %q", *rt.RouteTableId)
+			}
+		}
+	}
+
+	return nil
+}
+
+func (s *Service) deleteVPCPeering() error {
+	if s.scope.VPC().IsUnmanaged(s.scope.Name()) {
+		s.scope.V(4).Info("Skipping VPC peering deletion in unmanaged mode")
+		return nil
+	}
+
+	// Without vpcPeering there is no connection nor peer route to remove, so
+	// unpeered clusters don't need ec2:DescribeVpcPeeringConnections, which
+	// controllers policies created before VPC peering support lack.
+	spec := s.scope.VPCPeering()
+	if s.scope.VPC().ID == "" || spec == nil {
+		return nil
+	}
+
+	connections, err := s.describeVPCPeeringConnections(vpcPeeringActiveStates...)
+	if err != nil {
+		return err
+	}
+
+	for _, c := range connections {
+		if err := s.deleteVPCPeeringPeerRoutes(spec, *c.VpcPeeringConnectionId); err != nil {
+			return err
+		}
+		if err := s.deleteVPCPeeringConnection(*c.VpcPeeringConnectionId); err != nil {
+			return err
+		}
+	}
+
+	spec.ConnectionID = nil
+	return nil
+}
+
+// deleteVPCPeeringPeerRoutes deletes the routes of the peer VPC through the
+// peering connection, which would be left as blackholes.
+func (s *Service) deleteVPCPeeringPeerRoutes(spec *infrav1.VPCPeeringSpec, connectionID string) error {
+	if len(spec.PeerRouteTableIDs) == 0 {
+		return nil
+	}
+
+	out, err := s.scope.EC2.DescribeRouteTables(&ec2.DescribeRouteTablesInput{
+		RouteTableIds: aws.StringSlice(spec.PeerRouteTableIDs),
+	})
+	if err != nil {
+		return errors.Wrapf(err, "failed to describe route tables %v of peer vpc %q", spec.PeerRouteTableIDs, spec.PeerVPCID)
+	}
+
+	for _, rt := range out.RouteTables {
+		for _, route := range rt.Routes {
+			if aws.StringValue(route.VpcPeeringConnectionId) != connectionID {
+				continue
+			}
+			if err := s.deleteRoute(*rt.RouteTableId, route); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+func (s *Service) describeVPCPeeringConnections(states ...string) ([]*ec2.VpcPeeringConnection, error) {
+	input := &ec2.DescribeVpcPeeringConnectionsInput{
+		Filters: []*ec2.Filter{
+			filter.EC2.RequesterVPC(s.scope.VPC().ID),
+			filter.EC2.ClusterOwned(s.scope.Name()),
+			filter.EC2.VPCPeeringStates(states...),
+		},
+	}
+
+	var connections []*ec2.VpcPeeringConnection
+	for {
+		out, err := s.scope.EC2.DescribeVpcPeeringConnections(input)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to describe VPC peering connections of vpc %q", s.scope.VPC().ID)
+		}
+
+		connections = append(connections, out.VpcPeeringConnections...)
+
+		if aws.StringValue(out.NextToken) == "" {
+			return connections, nil
+		}
+		input.NextToken = out.NextToken
+	}
+}
+
+func (s *Service) createVPCPeeringConnection(spec *infrav1.VPCPeeringSpec) (*ec2.VpcPeeringConnection, error) {
+	input := &ec2.CreateVpcPeeringConnectionInput{
+		VpcId:     aws.String(s.scope.VPC().ID),
+		PeerVpcId: aws.String(spec.PeerVPCID),
+	}
+	if spec.PeerOwnerID != "" {
+		input.PeerOwnerId = aws.String(spec.PeerOwnerID)
+	}
+	if spec.PeerRegion != "" {
+		input.PeerRegion = aws.String(spec.PeerRegion)
+	}
+
+	out, err := s.scope.EC2.CreateVpcPeeringConnection(input)
+	if err != nil {
+		record.Warnf(s.scope.AWSCluster, "FailedCreateVPCPeeringConnection", "Failed to peer VPC %q with VPC %q: %v", s.scope.VPC().ID, spec.PeerVPCID, err)
+		return nil, errors.Wrapf(err, "failed to peer vpc %q with vpc %q", s.scope.VPC().ID, spec.PeerVPCID)
+	}
+	id := *out.VpcPeeringConnection.VpcPeeringConnectionId
+	record.Eventf(s.scope.AWSCluster, "SuccessfulCreateVPCPeeringConnection", "Created managed VPCPeeringConnection %q with VPC %q", id, spec.PeerVPCID)
+
+	if err := wait.WaitForWithRetryable(wait.NewBackoff(), func() (bool, error) {
+		if err := tags.Apply(&tags.ApplyParams{
+			EC2Client:   s.scope.EC2,
+			BuildParams: s.getVPCPeeringTagParams(id),
+		}); err != nil {
+			return false, err
+		}
+		return true, nil
+	}, awserrors.VPCPeeringNotFound); err != nil {
+		record.Warnf(s.scope.AWSCluster, "FailedTagVPCPeeringConnection", "Failed to tag managed VPCPeeringConnection %q: %v", id, err)
+		return nil, errors.Wrapf(err, "failed to tag VPC peering connection %q", id)
+	}
+	record.Eventf(s.scope.AWSCluster, "SuccessfulTagVPCPeeringConnection", "Tagged managed VPCPeeringConnection %q", id)
+
+	s.scope.Info("Peered VPC", "vpc-peering-connection-id", id, "vpc-id", s.scope.VPC().ID, "peer-vpc-id", spec.PeerVPCID)
+	return out.VpcPeeringConnection, nil
+}
+
+// waitForVPCPeeringActive waits for a peering connection to become active,
+// accepting it when allowed. A connection with a VPC of another account or
+// region waits for its owner to accept it, an error is returned so the
+// reconcile is requeued.
+func (s *Service) waitForVPCPeeringActive(id string, accept bool) error {
+	describeInput := &ec2.DescribeVpcPeeringConnectionsInput{
+		VpcPeeringConnectionIds: aws.StringSlice([]string{id}),
+	}
+
+	if err := wait.WaitForWithRetryable(wait.NewBackoff(), func() (bool, error) {
+		out, err := s.scope.EC2.DescribeVpcPeeringConnections(describeInput)
+		if err != nil {
+			return false, err
+		}
+
+		if len(out.VpcPeeringConnections) == 0 {
+			return false, errors.Errorf("no VPC peering connection returned for id %q", id)
+		}
+
+		var code, message string
+		if status := out.VpcPeeringConnections[0].Status; status != nil {
+			code, message = aws.StringValue(status.Code), aws.StringValue(status.Message)
+		}
+
+		switch code {
+		case ec2.VpcPeeringConnectionStateReasonCodeActive:
+			return true, nil
+		case ec2.VpcPeeringConnectionStateReasonCodeInitiatingRequest, ec2.VpcPeeringConnectionStateReasonCodeProvisioning:
+			s.scope.V(2).Info("Waiting for VPC peering connection to become active", "vpc-peering-connection-id", id)
+			return false, nil
+		case ec2.VpcPeeringConnectionStateReasonCodePendingAcceptance:
+			if !accept {
+				return false, errors.Errorf("waiting for the owner of the peer VPC to accept the peering connection")
+			}
+			if _, err := s.scope.EC2.AcceptVpcPeeringConnection(&ec2.AcceptVpcPeeringConnectionInput{
+				VpcPeeringConnectionId: aws.String(id),
+			}); err != nil {
+				record.Warnf(s.scope.AWSCluster, "FailedAcceptVPCPeeringConnection", "Failed to accept managed VPCPeeringConnection %q: %v", id, err)
+				return false, errors.Wrapf(err, "failed to accept VPC peering connection %q", id)
+			}
+			record.Eventf(s.scope.AWSCluster, "SuccessfulAcceptVPCPeeringConnection", "Accepted managed VPCPeeringConnection %q", id)
+			return false, nil
+		default:
+			record.Warnf(s.scope.AWSCluster, "FailedVPCPeeringConnection", "Managed VPCPeeringConnection %q is in %q state: %s", id, code, message)
+			return false, errors.Errorf("in %q state: %s", code, message)
+		}
+	}, awserrors.VPCPeeringNotFound); err != nil {
+		return errors.Wrapf(err, "VPC peering connection %q is not active yet", id)
+	}
+
+	return nil
+}
+
+func (s *Service) deleteVPCPeeringConnection(id string) error {
+	if _, err := s.scope.EC2.DeleteVpcPeeringConnection(&ec2.DeleteVpcPeeringConnectionInput{
+		VpcPeeringConnectionId: aws.String(id),
+	}); err != nil && !awserrors.IsNotFound(err) {
+		record.Warnf(s.scope.AWSCluster, "FailedDeleteVPCPeeringConnection", "Failed to delete managed VPCPeeringConnection %q: %v", id, err)
+		return errors.Wrapf(err, "failed to delete VPC peering connection %q", id)
+	}
+
+	record.Eventf(s.scope.AWSCluster, "SuccessfulDeleteVPCPeeringConnection", "Deleted managed VPCPeeringConnection %q", id)
+	s.scope.Info("Deleted VPC peering connection", "vpc-peering-connection-id", id)
+	return nil
+}
+
+// isPeeredWith returns whether the peering connection is with the configured
+// peer VPC.
+func (s *Service) isPeeredWith(connection *ec2.VpcPeeringConnection, spec *infrav1.VPCPeeringSpec) bool {
+	accepter := connection.AccepterVpcInfo
+	if accepter == nil || aws.StringValue(accepter.VpcId) != spec.PeerVPCID {
+		return false
+	}
+	if spec.PeerOwnerID != "" && aws.StringValue(accepter.OwnerId) != spec.PeerOwnerID {
+		return false
+	}
+	peerRegion := spec.PeerRegion
+	if peerRegion == "" {
+		peerRegion = s.scope.Region()
+	}
+	return accepter.Region == nil || *accepter.Region == peerRegion
+}
+
+// canAcceptVPCPeering returns whether the controller can accept the peering
+// connection, i.e. the peer VPC is in the account and region of the cluster.
+func (s *Service) canAcceptVPCPeering(spec *infrav1.VPCPeeringSpec) bool {
+	return spec.PeerOwnerID == "" && (spec.PeerRegion == "" || spec.PeerRegion == s.scope.Region())
+}
+
+// isVPCPeeringRoute returns whether the route goes through the peering
+// connection of the cluster.
+func (s *Service) isVPCPeeringRoute(route *ec2.Route) bool {
+	peering := s.scope.VPCPeering()
+	return peering != nil && peering.ConnectionID != nil && aws.StringValue(route.VpcPeeringConnectionId) == *peering.ConnectionID
+}
+
+// getVPCCidrBlocks returns the primary and secondary IPv4 CIDR blocks of the
+// VPC.
+func (s *Service) getVPCCidrBlocks() []string {
+	cidrs := []string{s.scope.VPC().CidrBlock}
+	return append(cidrs, s.scope.VPC().SecondaryCidrBlocks...)
+}
+
+func (s *Service) getVPCPeeringTagParams(id string) infrav1.BuildParams {
+	name := fmt.Sprintf("%s-pcx", s.scope.Name())
+
+	return infrav1.BuildParams{
+		ClusterName: s.scope.Name(),
+		ResourceID:  id,
+		Lifecycle:   infrav1.ResourceLifecycleOwned,
+		Name:        aws.String(name),
+		Role:        aws.String(infrav1.CommonRoleTagValue),
+		Additional:  s.scope.AdditionalTags(),
+	}
+}
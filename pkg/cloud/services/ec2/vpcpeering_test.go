@@ -0,0 +1,217 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ec2
+
+import (
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/golang/mock/gomock"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	infrav1 "sigs.k8s.io/cluster-api-provider-aws/api/v1alpha3"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/scope"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/services/ec2/mock_ec2iface"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1alpha3"
+)
+
+func TestReconcileVPCPeering(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	describeByID := &ec2.DescribeVpcPeeringConnectionsInput{
+		VpcPeeringConnectionIds: aws.StringSlice([]string{"pcx-new"}),
+	}
+	connectionWithStatus := func(code string) *ec2.DescribeVpcPeeringConnectionsOutput {
+		return &ec2.DescribeVpcPeeringConnectionsOutput{
+			VpcPeeringConnections: []*ec2.VpcPeeringConnection{
+				{
+					VpcPeeringConnectionId: aws.String("pcx-new"),
+					Status:                 &ec2.VpcPeeringConnectionStateReason{Code: aws.String(code)},
+				},
+			},
+		}
+	}
+
+	testCases := []struct {
+		name             string
+		peering          *infrav1.VPCPeeringSpec
+		expect           func(m *mock_ec2iface.MockEC2APIMockRecorder)
+		expectConnection string
+		expectErr        bool
+	}{
+		{
+			name: "VPC in the same account is peered, accepted and routed from the peer route tables",
+			peering: &infrav1.VPCPeeringSpec{
+				PeerVPCID:         "vpc-mgmt",
+				PeerCidrBlocks:    []string{"10.1.0.0/16"},
+				PeerRouteTableIDs: []string{"rtb-mgmt"},
+			},
+			expect: func(m *mock_ec2iface.MockEC2APIMockRecorder) {
+				m.DescribeVpcPeeringConnections(gomock.AssignableToTypeOf(&ec2.DescribeVpcPeeringConnectionsInput{})).
+					Return(&ec2.DescribeVpcPeeringConnectionsOutput{}, nil)
+				m.CreateVpcPeeringConnection(gomock.Eq(&ec2.CreateVpcPeeringConnectionInput{
+					VpcId:     aws.String("vpc-pcx"),
+					PeerVpcId: aws.String("vpc-mgmt"),
+				})).
+					Return(&ec2.CreateVpcPeeringConnectionOutput{
+						VpcPeeringConnection: &ec2.VpcPeeringConnection{VpcPeeringConnectionId: aws.String("pcx-new")},
+					}, nil)
+				m.CreateTags(gomock.AssignableToTypeOf(&ec2.CreateTagsInput{})).
+					Return(&ec2.CreateTagsOutput{}, nil)
+				m.DescribeVpcPeeringConnections(gomock.Eq(describeByID)).
+					Return(connectionWithStatus(ec2.VpcPeeringConnectionStateReasonCodePendingAcceptance), nil)
+				m.AcceptVpcPeeringConnection(gomock.Eq(&ec2.AcceptVpcPeeringConnectionInput{
+					VpcPeeringConnectionId: aws.String("pcx-new"),
+				})).
+					Return(&ec2.AcceptVpcPeeringConnectionOutput{}, nil)
+				m.DescribeVpcPeeringConnections(gomock.Eq(describeByID)).
+					Return(connectionWithStatus(ec2.VpcPeeringConnectionStateReasonCodeActive), nil)
+				m.DescribeRouteTables(gomock.Eq(&ec2.DescribeRouteTablesInput{
+					RouteTableIds: aws.StringSlice([]string{"rtb-mgmt"}),
+				})).
+					Return(&ec2.DescribeRouteTablesOutput{
+						RouteTables: []*ec2.RouteTable{
+							{
+								RouteTableId: aws.String("rtb-mgmt"),
+								Routes: []*ec2.Route{
+									{DestinationCidrBlock: aws.String("10.1.0.0/16"), GatewayId: aws.String("local")},
+								},
+							},
+						},
+					}, nil)
+				m.CreateRoute(gomock.Eq(&ec2.CreateRouteInput{
+					RouteTableId:           aws.String("rtb-mgmt"),
+					DestinationCidrBlock:   aws.String("10.0.0.0/16"),
+					VpcPeeringConnectionId: aws.String("pcx-new"),
+				})).
+					Return(&ec2.CreateRouteOutput{}, nil)
+			},
+			expectConnection: "pcx-new",
+		},
+		{
+			name: "connection with another VPC is replaced",
+			peering: &infrav1.VPCPeeringSpec{
+				PeerVPCID:      "vpc-mgmt",
+				PeerCidrBlocks: []string{"10.1.0.0/16"},
+			},
+			expect: func(m *mock_ec2iface.MockEC2APIMockRecorder) {
+				m.DescribeVpcPeeringConnections(gomock.AssignableToTypeOf(&ec2.DescribeVpcPeeringConnectionsInput{})).
+					Return(&ec2.DescribeVpcPeeringConnectionsOutput{
+						VpcPeeringConnections: []*ec2.VpcPeeringConnection{
+							{
+								VpcPeeringConnectionId: aws.String("pcx-old"),
+								AccepterVpcInfo:        &ec2.VpcPeeringConnectionVpcInfo{VpcId: aws.String("vpc-old")},
+								Status:                 &ec2.VpcPeeringConnectionStateReason{Code: aws.String(ec2.VpcPeeringConnectionStateReasonCodeActive)},
+							},
+						},
+					}, nil)
+				m.DeleteVpcPeeringConnection(gomock.Eq(&ec2.DeleteVpcPeeringConnectionInput{
+					VpcPeeringConnectionId: aws.String("pcx-old"),
+				})).
+					Return(&ec2.DeleteVpcPeeringConnectionOutput{}, nil)
+				m.CreateVpcPeeringConnection(gomock.AssignableToTypeOf(&ec2.CreateVpcPeeringConnectionInput{})).
+					Return(&ec2.CreateVpcPeeringConnectionOutput{
+						VpcPeeringConnection: &ec2.VpcPeeringConnection{VpcPeeringConnectionId: aws.String("pcx-new")},
+					}, nil)
+				m.CreateTags(gomock.AssignableToTypeOf(&ec2.CreateTagsInput{})).
+					Return(&ec2.CreateTagsOutput{}, nil)
+				m.DescribeVpcPeeringConnections(gomock.Eq(describeByID)).
+					Return(connectionWithStatus(ec2.VpcPeeringConnectionStateReasonCodeActive), nil)
+			},
+			expectConnection: "pcx-new",
+		},
+		{
+			name: "connection with another account waiting for acceptance by the owner of the peer VPC",
+			peering: &infrav1.VPCPeeringSpec{
+				PeerVPCID:      "vpc-mgmt",
+				PeerOwnerID:    "123456789012",
+				PeerCidrBlocks: []string{"10.1.0.0/16"},
+			},
+			expect: func(m *mock_ec2iface.MockEC2APIMockRecorder) {
+				m.DescribeVpcPeeringConnections(gomock.AssignableToTypeOf(&ec2.DescribeVpcPeeringConnectionsInput{})).
+					Return(&ec2.DescribeVpcPeeringConnectionsOutput{
+						VpcPeeringConnections: []*ec2.VpcPeeringConnection{
+							{
+								VpcPeeringConnectionId: aws.String("pcx-new"),
+								AccepterVpcInfo: &ec2.VpcPeeringConnectionVpcInfo{
+									VpcId:   aws.String("vpc-mgmt"),
+									OwnerId: aws.String("123456789012"),
+									Region:  aws.String("us-east-1"),
+								},
+								Status: &ec2.VpcPeeringConnectionStateReason{Code: aws.String(ec2.VpcPeeringConnectionStateReasonCodePendingAcceptance)},
+								Tags: []*ec2.Tag{
+									{Key: aws.String("Name"), Value: aws.String("test-cluster-pcx")},
+									{Key: aws.String("sigs.k8s.io/cluster-api-provider-aws/cluster/test-cluster"), Value: aws.String("owned")},
+									{Key: aws.String("sigs.k8s.io/cluster-api-provider-aws/role"), Value: aws.String("common")},
+								},
+							},
+						},
+					}, nil)
+				m.DescribeVpcPeeringConnections(gomock.Eq(describeByID)).
+					Return(connectionWithStatus(ec2.VpcPeeringConnectionStateReasonCodePendingAcceptance), nil)
+			},
+			expectConnection: "pcx-new",
+			expectErr:        true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ec2Mock := mock_ec2iface.NewMockEC2API(mockCtrl)
+
+			scope, err := scope.NewClusterScope(scope.ClusterScopeParams{
+				Cluster: &clusterv1.Cluster{
+					ObjectMeta: metav1.ObjectMeta{Name: "test-cluster"},
+				},
+				AWSClients: scope.AWSClients{
+					EC2: ec2Mock,
+				},
+				AWSCluster: &infrav1.AWSCluster{
+					Spec: infrav1.AWSClusterSpec{
+						Region: "us-east-1",
+						NetworkSpec: infrav1.NetworkSpec{
+							VPC: infrav1.VPCSpec{
+								ID:        "vpc-pcx",
+								CidrBlock: "10.0.0.0/16",
+								Tags: infrav1.Tags{
+									infrav1.ClusterTagKey("test-cluster"): "owned",
+								},
+							},
+							VPCPeering: tc.peering,
+						},
+					},
+				},
+			})
+			if err != nil {
+				t.Fatalf("Failed to create test context: %v", err)
+			}
+
+			tc.expect(ec2Mock.EXPECT())
+
+			s := NewService(scope)
+			err = s.reconcileVPCPeering()
+			if tc.expectErr != (err != nil) {
+				t.Fatalf("expected error %v, got %v", tc.expectErr, err)
+			}
+
+			if id := aws.StringValue(scope.VPCPeering().ConnectionID); id != tc.expectConnection {
+				t.Fatalf("expected connection %q, got %q", tc.expectConnection, id)
+			}
+		})
+	}
+}
@@ -0,0 +1,312 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package elb
+
+import (
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/route53"
+	"github.com/pkg/errors"
+	infrav1 "sigs.k8s.io/cluster-api-provider-aws/api/v1alpha3"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/awserrors"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/record"
+)
+
+// dnsRecordCNAMETTL is the TTL in seconds of the CNAME records pointing at
+// the API server load balancer. Alias records use the TTL of the load balancer.
+const dnsRecordCNAMETTL = 300
+
+// reconcileAPIServerDNSRecord makes the Route53 record of the control plane
+// load balancer point at it, and removes a record that is no longer wanted.
+// The record is not required for the cluster to work, failures are recorded in
+// the cluster status and events instead of failing the reconcile.
+func (s *Service) reconcileAPIServerDNSRecord() {
+	spec := s.scope.ControlPlaneDNSRecord()
+	current := s.scope.Network().APIServerDNSRecord
+
+	// The record was removed from the spec, or moved to another name or zone.
+	owned := current != nil && spec != nil && current.HostedZoneID == spec.HostedZoneID && dnsNamesEqual(current.Name, spec.Name)
+	if current != nil && !owned {
+		if err := s.deleteDNSRecord(current.HostedZoneID, current.Name, true); err != nil {
+			s.setDNSRecordFailure(current, err)
+			return
+		}
+		s.scope.Network().APIServerDNSRecord = nil
+	}
+
+	if spec == nil {
+		return
+	}
+
+	s.scope.V(2).Info("Reconciling API server DNS record", "hosted-zone-id", spec.HostedZoneID, "name", spec.Name)
+
+	status := &infrav1.DNSRecord{
+		HostedZoneID: spec.HostedZoneID,
+		Name:         spec.Name,
+	}
+	s.scope.Network().APIServerDNSRecord = status
+
+	if err := s.upsertDNSRecord(spec, owned); err != nil {
+		s.setDNSRecordFailure(status, err)
+		return
+	}
+
+	status.Ready = true
+}
+
+// deleteAPIServerDNSRecord removes the Route53 record of the control plane
+// load balancer. Failures are recorded in events, a leftover record doesn't
+// block the deletion of the cluster.
+func (s *Service) deleteAPIServerDNSRecord() {
+	current := s.scope.Network().APIServerDNSRecord
+	owned := current != nil
+
+	// The record may have been created before its status was recorded, it is
+	// only removed then if it points at the load balancer.
+	if current == nil {
+		spec := s.scope.ControlPlaneDNSRecord()
+		if spec == nil {
+			return
+		}
+		current = &infrav1.DNSRecord{HostedZoneID: spec.HostedZoneID, Name: spec.Name}
+	}
+
+	if err := s.deleteDNSRecord(current.HostedZoneID, current.Name, owned); err != nil {
+		s.scope.Error(err, "failed to delete API server DNS record", "hosted-zone-id", current.HostedZoneID, "name", current.Name)
+		return
+	}
+	s.scope.Network().APIServerDNSRecord = nil
+}
+
+func (s *Service) setDNSRecordFailure(status *infrav1.DNSRecord, err error) {
+	s.scope.Info("Failed to reconcile API server DNS record", "hosted-zone-id", status.HostedZoneID, "name", status.Name, "error", err.Error())
+	record.Warnf(s.scope.AWSCluster, "FailedReconcileDNSRecord", "Failed to reconcile DNS record %q in hosted zone %q: %v", status.Name, status.HostedZoneID, err)
+	status.Ready = false
+	status.FailureMessage = aws.String(err.Error())
+}
+
+// upsertDNSRecord creates or updates the record to point at the load
+// balancer. A record that isn't owned is only updated if it already points at
+// the load balancer, so records made for something else are never replaced.
+func (s *Service) upsertDNSRecord(spec *infrav1.DNSRecordSpec, owned bool) error {
+	desired, err := s.getAPIServerDNSRecordSet(spec)
+	if err != nil {
+		return err
+	}
+
+	existing, err := s.describeDNSRecord(spec.HostedZoneID, spec.Name)
+	if err != nil {
+		return err
+	}
+
+	var changes []*route53.Change
+	if existing != nil {
+		if dnsRecordSetsEqual(existing, desired) {
+			return nil
+		}
+
+		if !owned && !s.pointsAtAPIServerELB(existing) {
+			return errors.Errorf("a %s record %q already exists and does not point at the load balancer", aws.StringValue(existing.Type), spec.Name)
+		}
+
+		// The type of a record can't be changed, replace it in the same batch.
+		if aws.StringValue(existing.Type) != aws.StringValue(desired.Type) {
+			changes = append(changes, &route53.Change{
+				Action:            aws.String(route53.ChangeActionDelete),
+				ResourceRecordSet: existing,
+			})
+		}
+	}
+
+	changes = append(changes, &route53.Change{
+		Action:            aws.String(route53.ChangeActionUpsert),
+		ResourceRecordSet: desired,
+	})
+
+	if err := s.changeDNSRecords(spec.HostedZoneID, changes); err != nil {
+		return err
+	}
+
+	record.Eventf(s.scope.AWSCluster, "SuccessfulUpsertDNSRecord", "Pointed DNS record %q in hosted zone %q at load balancer %q", spec.Name, spec.HostedZoneID, s.scope.Network().APIServerELB.Name)
+	return nil
+}
+
+// deleteDNSRecord deletes the record of the given name. A record that isn't
+// owned is only deleted if it points at the load balancer.
+func (s *Service) deleteDNSRecord(hostedZoneID, name string, owned bool) error {
+	existing, err := s.describeDNSRecord(hostedZoneID, name)
+	if code, _ := awserrors.Code(errors.Cause(err)); code == route53.ErrCodeNoSuchHostedZone {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	if existing == nil || (!owned && !s.pointsAtAPIServerELB(existing)) {
+		return nil
+	}
+
+	if err := s.changeDNSRecords(hostedZoneID, []*route53.Change{
+		{
+			Action:            aws.String(route53.ChangeActionDelete),
+			ResourceRecordSet: existing,
+		},
+	}); err != nil {
+		record.Warnf(s.scope.AWSCluster, "FailedDeleteDNSRecord", "Failed to delete DNS record %q in hosted zone %q: %v", name, hostedZoneID, err)
+		return err
+	}
+
+	record.Eventf(s.scope.AWSCluster, "SuccessfulDeleteDNSRecord", "Deleted DNS record %q in hosted zone %q", name, hostedZoneID)
+	return nil
+}
+
+func (s *Service) changeDNSRecords(hostedZoneID string, changes []*route53.Change) error {
+	err := awserrors.RetryOnThrottling(func() error {
+		_, err := s.scope.Route53.ChangeResourceRecordSets(&route53.ChangeResourceRecordSetsInput{
+			HostedZoneId: aws.String(hostedZoneID),
+			ChangeBatch: &route53.ChangeBatch{
+				Comment: aws.String("Managed by the Kubernetes Cluster API Provider AWS"),
+				Changes: changes,
+			},
+		})
+		return err
+	})
+	if err != nil {
+		return errors.Wrapf(err, "failed to change records of hosted zone %q", hostedZoneID)
+	}
+	return nil
+}
+
+// describeDNSRecord returns the A or CNAME record of the given name, or nil
+// if there is none.
+func (s *Service) describeDNSRecord(hostedZoneID, name string) (*route53.ResourceRecordSet, error) {
+	var out *route53.ListResourceRecordSetsOutput
+	err := awserrors.RetryOnThrottling(func() (err error) {
+		// Records are listed in order of name, starting at the given one.
+		out, err = s.scope.Route53.ListResourceRecordSets(&route53.ListResourceRecordSetsInput{
+			HostedZoneId:    aws.String(hostedZoneID),
+			StartRecordName: aws.String(name),
+			MaxItems:        aws.String("10"),
+		})
+		return err
+	})
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to list records %q of hosted zone %q", name, hostedZoneID)
+	}
+
+	for _, set := range out.ResourceRecordSets {
+		if !dnsNamesEqual(aws.StringValue(set.Name), name) {
+			continue
+		}
+		switch aws.StringValue(set.Type) {
+		case route53.RRTypeA, route53.RRTypeCname:
+			return set, nil
+		}
+	}
+	return nil, nil
+}
+
+// getAPIServerDNSRecordSet returns the record pointing at the API server load
+// balancer.
+func (s *Service) getAPIServerDNSRecordSet(spec *infrav1.DNSRecordSpec) (*route53.ResourceRecordSet, error) {
+	apiELB := &s.scope.Network().APIServerELB
+	if apiELB.DNSName == "" {
+		return nil, errors.New("the load balancer has no DNS name yet")
+	}
+
+	if spec.Type == infrav1.DNSRecordTypeCNAME {
+		return &route53.ResourceRecordSet{
+			Name: aws.String(spec.Name),
+			Type: aws.String(route53.RRTypeCname),
+			TTL:  aws.Int64(dnsRecordCNAMETTL),
+			ResourceRecords: []*route53.ResourceRecord{
+				{Value: aws.String(apiELB.DNSName)},
+			},
+		}, nil
+	}
+
+	// Creating a classic load balancer doesn't return the hosted zone of its
+	// DNS name.
+	if apiELB.CanonicalHostedZoneID == "" {
+		lb, err := s.describeClassicELB(apiELB.Name)
+		if err != nil {
+			return nil, err
+		}
+		apiELB.CanonicalHostedZoneID = lb.CanonicalHostedZoneID
+	}
+
+	return &route53.ResourceRecordSet{
+		Name: aws.String(spec.Name),
+		Type: aws.String(route53.RRTypeA),
+		AliasTarget: &route53.AliasTarget{
+			DNSName:              aws.String(apiELB.DNSName),
+			HostedZoneId:         aws.String(apiELB.CanonicalHostedZoneID),
+			EvaluateTargetHealth: aws.Bool(false),
+		},
+	}, nil
+}
+
+// pointsAtAPIServerELB returns true if the record points at the API server
+// load balancer.
+func (s *Service) pointsAtAPIServerELB(set *route53.ResourceRecordSet) bool {
+	dnsName := s.scope.Network().APIServerELB.DNSName
+	if dnsName == "" {
+		return false
+	}
+
+	if set.AliasTarget != nil {
+		return dnsNamesEqual(strings.TrimPrefix(normalizeDNSName(aws.StringValue(set.AliasTarget.DNSName)), "dualstack."), dnsName)
+	}
+	for _, r := range set.ResourceRecords {
+		if dnsNamesEqual(aws.StringValue(r.Value), dnsName) {
+			return true
+		}
+	}
+	return false
+}
+
+func dnsRecordSetsEqual(a, b *route53.ResourceRecordSet) bool {
+	if aws.StringValue(a.Type) != aws.StringValue(b.Type) {
+		return false
+	}
+
+	if a.AliasTarget != nil || b.AliasTarget != nil {
+		return a.AliasTarget != nil && b.AliasTarget != nil &&
+			dnsNamesEqual(aws.StringValue(a.AliasTarget.DNSName), aws.StringValue(b.AliasTarget.DNSName)) &&
+			aws.StringValue(a.AliasTarget.HostedZoneId) == aws.StringValue(b.AliasTarget.HostedZoneId)
+	}
+
+	if aws.Int64Value(a.TTL) != aws.Int64Value(b.TTL) || len(a.ResourceRecords) != len(b.ResourceRecords) {
+		return false
+	}
+	for i := range a.ResourceRecords {
+		if !dnsNamesEqual(aws.StringValue(a.ResourceRecords[i].Value), aws.StringValue(b.ResourceRecords[i].Value)) {
+			return false
+		}
+	}
+	return true
+}
+
+// normalizeDNSName lower cases the name and removes its trailing dot, to
+// compare it with the fully qualified names returned by Route53.
+func normalizeDNSName(name string) string {
+	return strings.TrimSuffix(strings.ToLower(name), ".")
+}
+
+func dnsNamesEqual(a, b string) bool {
+	return normalizeDNSName(a) == normalizeDNSName(b)
+}
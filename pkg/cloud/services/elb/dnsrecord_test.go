@@ -0,0 +1,179 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package elb
+
+import (
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/route53"
+	"github.com/aws/aws-sdk-go/service/route53/route53iface"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	infrav1 "sigs.k8s.io/cluster-api-provider-aws/api/v1alpha3"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/scope"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1alpha3"
+)
+
+// fakeRoute53 returns canned records and records the changes made to them.
+type fakeRoute53 struct {
+	route53iface.Route53API
+	records []*route53.ResourceRecordSet
+	changes []*route53.Change
+}
+
+func (f *fakeRoute53) ListResourceRecordSets(input *route53.ListResourceRecordSetsInput) (*route53.ListResourceRecordSetsOutput, error) {
+	return &route53.ListResourceRecordSetsOutput{ResourceRecordSets: f.records}, nil
+}
+
+func (f *fakeRoute53) ChangeResourceRecordSets(input *route53.ChangeResourceRecordSetsInput) (*route53.ChangeResourceRecordSetsOutput, error) {
+	f.changes = append(f.changes, input.ChangeBatch.Changes...)
+	return &route53.ChangeResourceRecordSetsOutput{}, nil
+}
+
+func TestReconcileAPIServerDNSRecord(t *testing.T) {
+	spec := &infrav1.DNSRecordSpec{
+		HostedZoneID: "Z0123456789ABCDEFGHIJ",
+		Name:         "api.test-cluster.corp.example.com",
+	}
+	owned := &infrav1.DNSRecord{
+		HostedZoneID: "Z0123456789ABCDEFGHIJ",
+		Name:         "api.test-cluster.corp.example.com",
+		Ready:        true,
+	}
+	alias := func(dnsName string) *route53.ResourceRecordSet {
+		return &route53.ResourceRecordSet{
+			Name: aws.String("api.test-cluster.corp.example.com."),
+			Type: aws.String(route53.RRTypeA),
+			AliasTarget: &route53.AliasTarget{
+				DNSName:              aws.String(dnsName),
+				HostedZoneId:         aws.String("Z35SXDOTRQ7X7K"),
+				EvaluateTargetHealth: aws.Bool(false),
+			},
+		}
+	}
+
+	testCases := []struct {
+		name        string
+		spec        *infrav1.DNSRecordSpec
+		status      *infrav1.DNSRecord
+		records     []*route53.ResourceRecordSet
+		wantActions []string
+		wantStatus  bool
+		wantReady   bool
+	}{
+		{
+			name:        "record is created",
+			spec:        spec,
+			wantActions: []string{route53.ChangeActionUpsert},
+			wantStatus:  true,
+			wantReady:   true,
+		},
+		{
+			name:       "record already points at the load balancer",
+			spec:       spec,
+			status:     owned,
+			records:    []*route53.ResourceRecordSet{alias("test-cluster-apiserver-1.us-east-1.elb.amazonaws.com.")},
+			wantStatus: true,
+			wantReady:  true,
+		},
+		{
+			name:        "record follows a recreated load balancer",
+			spec:        spec,
+			status:      owned,
+			records:     []*route53.ResourceRecordSet{alias("test-cluster-apiserver-0.us-east-1.elb.amazonaws.com.")},
+			wantActions: []string{route53.ChangeActionUpsert},
+			wantStatus:  true,
+			wantReady:   true,
+		},
+		{
+			name:       "record of something else is left alone",
+			spec:       spec,
+			records:    []*route53.ResourceRecordSet{alias("other-apiserver.us-east-1.elb.amazonaws.com.")},
+			wantStatus: true,
+			wantReady:  false,
+		},
+		{
+			name:        "record is deleted when no longer specified",
+			status:      owned,
+			records:     []*route53.ResourceRecordSet{alias("test-cluster-apiserver-1.us-east-1.elb.amazonaws.com.")},
+			wantActions: []string{route53.ChangeActionDelete},
+			wantStatus:  false,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			route53Fake := &fakeRoute53{records: tc.records}
+
+			scope, err := scope.NewClusterScope(scope.ClusterScopeParams{
+				Cluster: &clusterv1.Cluster{
+					ObjectMeta: metav1.ObjectMeta{Name: "test-cluster"},
+				},
+				AWSClients: scope.AWSClients{
+					Route53: route53Fake,
+				},
+				AWSCluster: &infrav1.AWSCluster{
+					Spec: infrav1.AWSClusterSpec{
+						ControlPlaneLoadBalancer: &infrav1.AWSLoadBalancerSpec{
+							DNSRecord: tc.spec,
+						},
+					},
+					Status: infrav1.AWSClusterStatus{
+						Network: infrav1.Network{
+							APIServerELB: infrav1.ClassicELB{
+								Name:                  "test-cluster-apiserver",
+								DNSName:               "test-cluster-apiserver-1.us-east-1.elb.amazonaws.com",
+								CanonicalHostedZoneID: "Z35SXDOTRQ7X7K",
+							},
+							APIServerDNSRecord: tc.status.DeepCopy(),
+						},
+					},
+				},
+			})
+			if err != nil {
+				t.Fatalf("Failed to create test context: %v", err)
+			}
+
+			s := NewService(scope)
+			s.reconcileAPIServerDNSRecord()
+
+			var actions []string
+			for _, c := range route53Fake.changes {
+				actions = append(actions, aws.StringValue(c.Action))
+			}
+			if len(actions) != len(tc.wantActions) {
+				t.Fatalf("expected changes %v, got %v", tc.wantActions, actions)
+			}
+			for i := range actions {
+				if actions[i] != tc.wantActions[i] {
+					t.Fatalf("expected changes %v, got %v", tc.wantActions, actions)
+				}
+			}
+
+			status := scope.Network().APIServerDNSRecord
+			if (status != nil) != tc.wantStatus {
+				t.Fatalf("expected a record in status %t, got %+v", tc.wantStatus, status)
+			}
+			if status != nil && status.Ready != tc.wantReady {
+				t.Fatalf("expected the record to be ready %t, got %+v", tc.wantReady, status)
+			}
+			if status != nil && !status.Ready && status.FailureMessage == nil {
+				t.Fatal("expected a failure message for a record that is not ready")
+			}
+		})
+	}
+}
@@ -21,6 +21,7 @@ import (
 
 	"github.com/aws/aws-sdk-go/aws/awserr"
 	"github.com/aws/aws-sdk-go/service/elb"
+	"github.com/aws/aws-sdk-go/service/elbv2"
 	"github.com/pkg/errors"
 	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/awserrors"
 )
@@ -62,7 +63,8 @@ func IsNotFound(err error) bool {
 	}
 	if code, ok := awserrors.Code(errors.Cause(err)); ok {
 		switch code {
-		case elb.ErrCodeAccessPointNotFoundException:
+		case elb.ErrCodeAccessPointNotFoundException,
+			elbv2.ErrCodeTargetGroupNotFoundException:
 			return true
 		}
 	}
@@ -32,16 +32,50 @@ import (
 	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/converters"
 	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/services/wait"
 	"sigs.k8s.io/cluster-api-provider-aws/pkg/internal/hash"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/record"
 )
 
 // ResourceGroups are filtered by ARN identifier: https://docs.aws.amazon.com/general/latest/gr/aws-arns-and-namespaces.html#arns-syntax
-// this is the identifier for classic ELBs: https://docs.aws.amazon.com/IAM/latest/UserGuide/list_elasticloadbalancing.html#elasticloadbalancing-resources-for-iam-policies
+// this is the identifier for classic ELBs, and network and application load balancers:
+// https://docs.aws.amazon.com/IAM/latest/UserGuide/list_elasticloadbalancing.html#elasticloadbalancing-resources-for-iam-policies
 const elbResourceType = "elasticloadbalancing:loadbalancer"
 
+// proxyProtocolPolicyName is the name of the policy enabling the PROXY
+// protocol on the API server port of the classic load balancer.
+const proxyProtocolPolicyName = "capa-proxy-protocol"
+
 // ReconcileLoadbalancers reconciles the load balancers for the given cluster.
 func (s *Service) ReconcileLoadbalancers() error {
 	s.scope.V(2).Info("Reconciling load balancers")
 
+	if arn := s.scope.ControlPlaneLoadBalancerARN(); arn != "" {
+		if err := s.reconcileUnmanagedLoadBalancer(arn); err != nil {
+			return err
+		}
+
+		s.reconcileAPIServerDNSRecord()
+
+		s.scope.V(2).Info("Reconcile load balancers completed successfully")
+		return nil
+	}
+
+	if s.scope.ControlPlaneLoadBalancerType() == infrav1.LoadBalancerTypeNLB {
+		if err := s.reconcileNetworkLoadBalancer(); err != nil {
+			return err
+		}
+
+		s.reconcileAPIServerDNSRecord()
+
+		// The record points at the network load balancer now, the classic ELB
+		// it replaces can go.
+		if err := s.reconcileClassicELBMigration(); err != nil {
+			return err
+		}
+
+		s.scope.V(2).Info("Reconcile load balancers completed successfully")
+		return nil
+	}
+
 	// Get default api server spec.
 	spec, err := s.getAPIServerClassicELBSpec()
 
@@ -58,15 +92,35 @@ func (s *Service) ReconcileLoadbalancers() error {
 		}
 
 		s.scope.V(2).Info("Created new classic load balancer for apiserver", "api-server-elb-name", apiELB.Name)
+
+		// Classic load balancers are created with the default attributes.
+		if err := s.configureAttributes(apiELB.Name, spec); err != nil {
+			return err
+		}
 	} else if err != nil {
 		return err
+	} else if !reflect.DeepEqual(spec.Attributes, apiELB.Attributes) || spec.CrossZoneLoadBalancing != apiELB.CrossZoneLoadBalancing {
+		if err := s.configureAttributes(apiELB.Name, spec); err != nil {
+			return err
+		}
+		apiELB.Attributes = spec.Attributes
+		apiELB.CrossZoneLoadBalancing = spec.CrossZoneLoadBalancing
 	}
 
-	if !reflect.DeepEqual(spec.Attributes, apiELB.Attributes) {
-		err := s.configureAttributes(apiELB.Name, spec.Attributes)
-		if err != nil {
+	// Revert changes made to the health check outside of the controller.
+	if !reflect.DeepEqual(spec.HealthCheck, apiELB.HealthCheck) {
+		if err := s.configureHealthCheck(apiELB.Name, spec.HealthCheck); err != nil {
+			return err
+		}
+		record.Eventf(s.scope.AWSCluster, "SuccessfulConfigureHealthCheck", "Configured health check %q of classic load balancer %q", spec.HealthCheck.Target, apiELB.Name)
+		apiELB.HealthCheck = spec.HealthCheck
+	}
+
+	if spec.ProxyProtocol != apiELB.ProxyProtocol {
+		if err := s.configureProxyProtocol(apiELB.Name, spec.ProxyProtocol); err != nil {
 			return err
 		}
+		apiELB.ProxyProtocol = spec.ProxyProtocol
 	}
 
 	if err := s.reconcileELBTags(apiELB.Name, spec.Tags); err != nil {
@@ -89,19 +143,49 @@ func (s *Service) ReconcileLoadbalancers() error {
 	}
 
 	// TODO(vincepri): check if anything has changed and reconcile as necessary.
-	apiELB.DeepCopyInto(&s.scope.Network().APIServerELB)
-	s.scope.V(4).Info("Control plane load balancer", "api-server-elb", apiELB)
+	s.setAPIServerELB(apiELB)
+
+	s.reconcileAPIServerDNSRecord()
 
 	s.scope.V(2).Info("Reconcile load balancers completed successfully")
 	return nil
 }
 
+// setAPIServerELB records the API server load balancer in the cluster status.
+func (s *Service) setAPIServerELB(apiELB *infrav1.ClassicELB) {
+	// A recreated load balancer gets a new DNS name, which breaks kubeconfigs pointing at the old one.
+	if previous := s.scope.Network().APIServerELB.DNSName; previous != "" && previous != apiELB.DNSName {
+		s.scope.Info("API server load balancer DNS name changed", "api-server-elb-name", apiELB.Name, "previous", previous, "current", apiELB.DNSName)
+		record.Warnf(s.scope.AWSCluster, "APIServerELBDNSNameChanged",
+			"API server load balancer %q DNS name changed from %q to %q, kubeconfigs using the previous endpoint must be regenerated",
+			apiELB.Name, previous, apiELB.DNSName)
+	}
+
+	apiELB.DeepCopyInto(&s.scope.Network().APIServerELB)
+	s.scope.V(4).Info("Control plane load balancer", "api-server-elb", apiELB)
+}
+
 // GetAPIServerDNSName returns the DNS name endpoint for the API server
 func (s *Service) GetAPIServerDNSName() (string, error) {
+	if arn := s.scope.ControlPlaneLoadBalancerARN(); arn != "" {
+		lb, err := s.describeUnmanagedLoadBalancer(arn)
+		if err != nil {
+			return "", err
+		}
+		return lb.DNSName, nil
+	}
+
 	elbName, err := GenerateELBName(s.scope.Name())
 	if err != nil {
 		return "", err
 	}
+	if s.scope.ControlPlaneLoadBalancerType() == infrav1.LoadBalancerTypeNLB {
+		lb, err := s.describeNetworkLoadBalancer(elbName)
+		if err != nil {
+			return "", err
+		}
+		return aws.StringValue(lb.DNSName), nil
+	}
 	apiELB, err := s.describeClassicELB(elbName)
 	if err != nil {
 		return "", err
@@ -109,18 +193,33 @@ func (s *Service) GetAPIServerDNSName() (string, error) {
 	return apiELB.DNSName, nil
 }
 
+// ResourceTags returns the tags the service applies to each type of load
+// balancer it manages for the cluster, keyed by resource type.
+func (s *Service) ResourceTags() map[string]infrav1.Tags {
+	return map[string]infrav1.Tags{
+		"load-balancer/apiserver": infrav1.Build(s.getAPIServerClassicELBTagParams()),
+	}
+}
+
 // DeleteLoadbalancers deletes the load balancers for the given cluster.
 func (s *Service) DeleteLoadbalancers() error {
 	s.scope.V(2).Info("Deleting load balancers")
 
+	// Remove the record while the load balancer it points at still exists.
+	s.deleteAPIServerDNSRecord()
+
 	elbs, err := s.listOwnedELBs()
 	if err != nil {
 		return err
 	}
 
-	for _, elb := range elbs {
-		s.scope.V(3).Info("deleting load balancer", "arn", elb)
-		s.deleteClassicELB(elb)
+	for _, arn := range elbs {
+		s.scope.V(3).Info("deleting load balancer", "arn", arn)
+		if isELBV2ARN(arn) {
+			s.deleteELBV2(arn)
+			continue
+		}
+		s.deleteClassicELB(classicELBNameFromARN(arn))
 	}
 
 	if err := wait.WaitForWithRetryable(wait.NewBackoff(), func() (done bool, err error) {
@@ -134,6 +233,13 @@ func (s *Service) DeleteLoadbalancers() error {
 		return errors.Wrapf(err, "failed to wait for %q ELB deletions", s.scope.Name())
 	}
 
+	// Target groups can only be deleted once no load balancer uses them.
+	if s.scope.ControlPlaneLoadBalancerType() == infrav1.LoadBalancerTypeNLB {
+		if err := s.deleteOrphanedTargetGroups(""); err != nil {
+			return err
+		}
+	}
+
 	s.scope.V(2).Info("Deleting load balancers completed successfully")
 	return nil
 }
@@ -155,13 +261,29 @@ func (s *Service) RegisterInstanceWithClassicELB(instanceID string, loadBalancer
 
 // RegisterInstanceWithAPIServerELB registers an instance with a classic ELB
 func (s *Service) RegisterInstanceWithAPIServerELB(i *infrav1.Instance) error {
+	// The subnets of an existing load balancer are not managed by the cluster,
+	// so there is nothing to check them against.
+	if arn := s.scope.ControlPlaneLoadBalancerARN(); arn != "" {
+		return s.registerInstanceWithUnmanagedLoadBalancer(arn, i.ID)
+	}
+
 	name, err := GenerateELBName(s.scope.Name())
 	if err != nil {
 		return err
 	}
-	out, err := s.describeClassicELB(name)
-	if err != nil {
-		return err
+	nlb := s.scope.ControlPlaneLoadBalancerType() == infrav1.LoadBalancerTypeNLB
+	var out *infrav1.ClassicELB
+	if nlb {
+		lb, err := s.describeNetworkLoadBalancer(name)
+		if err != nil {
+			return err
+		}
+		out = fromSDKTypeToNetworkLoadBalancer(lb)
+	} else {
+		out, err = s.describeClassicELB(name)
+		if err != nil {
+			return err
+		}
 	}
 
 	// Validate that the subnets associated with the load balancer has the instance AZ.
@@ -181,6 +303,10 @@ func (s *Service) RegisterInstanceWithAPIServerELB(i *infrav1.Instance) error {
 		return errors.Errorf("failed to register instance with APIServer ELB %q: instance is in availability zone %q, no public subnets attached to the ELB in the same zone", name, instanceAZ)
 	}
 
+	if nlb {
+		return s.registerInstanceWithAPIServerTargetGroup(name, i.ID)
+	}
+
 	input := &elb.RegisterInstancesWithLoadBalancerInput{
 		Instances:        []*elb.Instance{{InstanceId: aws.String(i.ID)}},
 		LoadBalancerName: aws.String(name),
@@ -194,6 +320,17 @@ func (s *Service) RegisterInstanceWithAPIServerELB(i *infrav1.Instance) error {
 	return nil
 }
 
+// DeregisterInstanceFromAPIServerELB deregisters an instance from the existing
+// API server load balancer the cluster references, if any. Load balancers the
+// cluster owns are deleted with it, while existing ones outlive the instances.
+func (s *Service) DeregisterInstanceFromAPIServerELB(i *infrav1.Instance) error {
+	arn := s.scope.ControlPlaneLoadBalancerARN()
+	if arn == "" {
+		return nil
+	}
+	return s.deregisterInstanceFromUnmanagedLoadBalancer(arn, i.ID)
+}
+
 // GenerateELBName generates a formatted ELB name via either
 // concatenating the cluster name to the "-apiserver" suffix
 // or computing a hash for clusters with names above 32 characters.
@@ -246,25 +383,22 @@ func (s *Service) getAPIServerClassicELBSpec() (*infrav1.ClassicELB, error) {
 				InstancePort:     6443,
 			},
 		},
-		HealthCheck: &infrav1.ClassicELBHealthCheck{
-			Target:             fmt.Sprintf("%v:%d", infrav1.ClassicELBProtocolSSL, 6443),
-			Interval:           10 * time.Second,
-			Timeout:            5 * time.Second,
-			HealthyThreshold:   5,
-			UnhealthyThreshold: 3,
-		},
+		HealthCheck:      s.getAPIServerClassicELBHealthCheck(),
 		SecurityGroupIDs: []string{s.scope.SecurityGroups()[infrav1.SecurityGroupControlPlane].ID},
 		Attributes: infrav1.ClassicELBAttributes{
 			IdleTimeout: 10 * time.Minute,
 		},
 	}
 
-	res.Tags = infrav1.Build(infrav1.BuildParams{
-		ClusterName: s.scope.Name(),
-		Lifecycle:   infrav1.ResourceLifecycleOwned,
-		Role:        aws.String(infrav1.APIServerRoleTagValue),
-		Additional:  s.scope.AdditionalTags(),
-	})
+	if lb := s.scope.ControlPlaneLoadBalancer(); lb != nil {
+		res.ProxyProtocol = lb.ProxyProtocol
+		res.CrossZoneLoadBalancing = lb.CrossZoneLoadBalancing
+		if lb.IdleTimeoutSeconds != 0 {
+			res.Attributes.IdleTimeout = time.Duration(lb.IdleTimeoutSeconds) * time.Second
+		}
+	}
+
+	res.Tags = infrav1.Build(s.getAPIServerClassicELBTagParams())
 
 	// The load balancer APIs require us to only attach one subnet for each AZ.
 	subnets := s.scope.Subnets().FilterPrivate()
@@ -288,6 +422,47 @@ func (s *Service) getAPIServerClassicELBSpec() (*infrav1.ClassicELB, error) {
 	return res, nil
 }
 
+// getAPIServerClassicELBHealthCheck returns the health check of the API
+// server port, with the defaults overridden by the load balancer spec.
+func (s *Service) getAPIServerClassicELBHealthCheck() *infrav1.ClassicELBHealthCheck {
+	custom := &infrav1.ClassicELBHealthCheckSpec{}
+	if lb := s.scope.ControlPlaneLoadBalancer(); lb != nil && lb.HealthCheck != nil {
+		custom = lb.HealthCheck
+	}
+
+	orDefault := func(value, def int64) int64 {
+		if value == 0 {
+			return def
+		}
+		return value
+	}
+
+	target := fmt.Sprintf("%v:%d", infrav1.ClassicELBProtocolSSL, 6443)
+	switch custom.Protocol {
+	case infrav1.ClassicELBProtocolTCP:
+		target = fmt.Sprintf("%v:%d", infrav1.ClassicELBProtocolTCP, 6443)
+	case infrav1.ClassicELBProtocolHTTPS:
+		target = fmt.Sprintf("%v:%d/healthz", infrav1.ClassicELBProtocolHTTPS, 6443)
+	}
+
+	return &infrav1.ClassicELBHealthCheck{
+		Target:             target,
+		Interval:           time.Duration(orDefault(custom.IntervalSeconds, infrav1.DefaultClassicELBHealthCheckInterval)) * time.Second,
+		Timeout:            time.Duration(orDefault(custom.TimeoutSeconds, infrav1.DefaultClassicELBHealthCheckTimeout)) * time.Second,
+		HealthyThreshold:   orDefault(custom.HealthyThreshold, infrav1.DefaultClassicELBHealthCheckHealthyThreshold),
+		UnhealthyThreshold: orDefault(custom.UnhealthyThreshold, infrav1.DefaultClassicELBHealthCheckUnhealthyThreshold),
+	}
+}
+
+func (s *Service) getAPIServerClassicELBTagParams() infrav1.BuildParams {
+	return infrav1.BuildParams{
+		ClusterName: s.scope.Name(),
+		Lifecycle:   infrav1.ResourceLifecycleOwned,
+		Role:        aws.String(infrav1.APIServerRoleTagValue),
+		Additional:  s.scope.AdditionalTags(),
+	}
+}
+
 func (s *Service) createClassicELB(spec *infrav1.ClassicELB) (*infrav1.ClassicELB, error) {
 	input := &elb.CreateLoadBalancerInput{
 		LoadBalancerName: aws.String(spec.Name),
@@ -306,47 +481,121 @@ func (s *Service) createClassicELB(spec *infrav1.ClassicELB) (*infrav1.ClassicEL
 		})
 	}
 
-	out, err := s.scope.ELB.CreateLoadBalancer(input)
+	var out *elb.CreateLoadBalancerOutput
+	err := awserrors.RetryOnThrottling(func() (err error) {
+		out, err = s.scope.ELB.CreateLoadBalancer(input)
+		return err
+	})
 	if err != nil {
 		return nil, errors.Wrapf(err, "failed to create classic load balancer: %v", spec)
 	}
 
 	if spec.HealthCheck != nil {
+		if err := s.configureHealthCheck(spec.Name, spec.HealthCheck); err != nil {
+			return nil, err
+		}
+	}
+
+	if spec.ProxyProtocol {
+		if err := s.configureProxyProtocol(spec.Name, true); err != nil {
+			return nil, err
+		}
+	}
+
+	s.scope.V(2).Info("Created classic load balancer", "dns-name", *out.DNSName)
+
+	res := spec.DeepCopy()
+	res.DNSName = *out.DNSName
+	return res, nil
+}
+
+func (s *Service) configureHealthCheck(name string, healthCheck *infrav1.ClassicELBHealthCheck) error {
+	if err := wait.WaitForWithRetryable(wait.NewBackoff(), func() (bool, error) {
+		if _, err := s.scope.ELB.ConfigureHealthCheck(&elb.ConfigureHealthCheckInput{
+			LoadBalancerName: aws.String(name),
+			HealthCheck: &elb.HealthCheck{
+				Target:             aws.String(healthCheck.Target),
+				Interval:           aws.Int64(int64(healthCheck.Interval.Seconds())),
+				Timeout:            aws.Int64(int64(healthCheck.Timeout.Seconds())),
+				HealthyThreshold:   aws.Int64(healthCheck.HealthyThreshold),
+				UnhealthyThreshold: aws.Int64(healthCheck.UnhealthyThreshold),
+			},
+		}); err != nil {
+			return false, err
+		}
+		return true, nil
+	}, awserrors.LoadBalancerNotFound); err != nil {
+		return errors.Wrapf(err, "failed to configure health check for classic load balancer: %v", name)
+	}
+
+	return nil
+}
+
+// configureProxyProtocol enables or disables the PROXY protocol on the API
+// server port of the instances. The policy is only created while enabled, and
+// deleted once disabled.
+func (s *Service) configureProxyProtocol(name string, enabled bool) error {
+	if enabled {
 		if err := wait.WaitForWithRetryable(wait.NewBackoff(), func() (bool, error) {
-			if _, err := s.scope.ELB.ConfigureHealthCheck(&elb.ConfigureHealthCheckInput{
-				LoadBalancerName: aws.String(spec.Name),
-				HealthCheck: &elb.HealthCheck{
-					Target:             aws.String(spec.HealthCheck.Target),
-					Interval:           aws.Int64(int64(spec.HealthCheck.Interval.Seconds())),
-					Timeout:            aws.Int64(int64(spec.HealthCheck.Timeout.Seconds())),
-					HealthyThreshold:   aws.Int64(spec.HealthCheck.HealthyThreshold),
-					UnhealthyThreshold: aws.Int64(spec.HealthCheck.UnhealthyThreshold),
+			_, err := s.scope.ELB.CreateLoadBalancerPolicy(&elb.CreateLoadBalancerPolicyInput{
+				LoadBalancerName: aws.String(name),
+				PolicyName:       aws.String(proxyProtocolPolicyName),
+				PolicyTypeName:   aws.String("ProxyProtocolPolicyType"),
+				PolicyAttributes: []*elb.PolicyAttribute{
+					{AttributeName: aws.String("ProxyProtocol"), AttributeValue: aws.String("true")},
 				},
-			}); err != nil {
+			})
+			if code, _ := awserrors.Code(err); err != nil && code != elb.ErrCodeDuplicatePolicyNameException {
 				return false, err
 			}
 			return true, nil
 		}, awserrors.LoadBalancerNotFound); err != nil {
-			return nil, errors.Wrapf(err, "failed to configure health check for classic load balancer: %v", spec)
+			return errors.Wrapf(err, "failed to create proxy protocol policy for classic load balancer %q", name)
 		}
 	}
 
-	s.scope.V(2).Info("Created classic load balancer", "dns-name", *out.DNSName)
+	policyNames := []*string{}
+	if enabled {
+		policyNames = aws.StringSlice([]string{proxyProtocolPolicyName})
+	}
+	if _, err := s.scope.ELB.SetLoadBalancerPoliciesForBackendServer(&elb.SetLoadBalancerPoliciesForBackendServerInput{
+		LoadBalancerName: aws.String(name),
+		InstancePort:     aws.Int64(6443),
+		PolicyNames:      policyNames,
+	}); err != nil {
+		record.Warnf(s.scope.AWSCluster, "FailedConfigureProxyProtocol", "Failed to configure proxy protocol of classic load balancer %q: %v", name, err)
+		return errors.Wrapf(err, "failed to set backend server policies for classic load balancer %q", name)
+	}
 
-	res := spec.DeepCopy()
-	res.DNSName = *out.DNSName
-	return res, nil
+	if !enabled {
+		_, err := s.scope.ELB.DeleteLoadBalancerPolicy(&elb.DeleteLoadBalancerPolicyInput{
+			LoadBalancerName: aws.String(name),
+			PolicyName:       aws.String(proxyProtocolPolicyName),
+		})
+		if code, _ := awserrors.Code(err); err != nil && code != elb.ErrCodePolicyNotFoundException {
+			return errors.Wrapf(err, "failed to delete proxy protocol policy of classic load balancer %q", name)
+		}
+	}
+
+	record.Eventf(s.scope.AWSCluster, "SuccessfulConfigureProxyProtocol", "Set proxy protocol of classic load balancer %q to %t", name, enabled)
+	return nil
 }
 
-func (s *Service) configureAttributes(name string, attributes infrav1.ClassicELBAttributes) error {
+// configureAttributes applies the attributes of the spec to the classic load
+// balancer. Only classic load balancers support an idle timeout.
+func (s *Service) configureAttributes(name string, spec *infrav1.ClassicELB) error {
 	attrs := &elb.ModifyLoadBalancerAttributesInput{
-		LoadBalancerName:       aws.String(name),
-		LoadBalancerAttributes: &elb.LoadBalancerAttributes{},
+		LoadBalancerName: aws.String(name),
+		LoadBalancerAttributes: &elb.LoadBalancerAttributes{
+			CrossZoneLoadBalancing: &elb.CrossZoneLoadBalancing{
+				Enabled: aws.Bool(spec.CrossZoneLoadBalancing),
+			},
+		},
 	}
 
-	if attributes.IdleTimeout > 0 {
+	if spec.Attributes.IdleTimeout > 0 {
 		attrs.LoadBalancerAttributes.ConnectionSettings = &elb.ConnectionSettings{
-			IdleTimeout: aws.Int64(int64(attributes.IdleTimeout.Seconds())),
+			IdleTimeout: aws.Int64(int64(spec.Attributes.IdleTimeout.Seconds())),
 		}
 	}
 
@@ -384,19 +633,16 @@ func (s *Service) listByTag(tag string) ([]string, error) {
 		},
 	}
 
-	names := []string{}
+	arns := []string{}
 
 	err := s.scope.ResourceTagging.GetResourcesPages(&input, func(r *rgapi.GetResourcesOutput, last bool) bool {
 		for _, tagmapping := range r.ResourceTagMappingList {
 			if tagmapping.ResourceARN != nil {
-				// We can't use arn.Parse because the "Resource" is loadbalancer/<name>
-				parts := strings.Split(*tagmapping.ResourceARN, "/")
-				name := parts[len(parts)-1]
-				if name == "" {
+				if classicELBNameFromARN(*tagmapping.ResourceARN) == "" {
 					s.scope.Info("failed to parse ARN", "arn", *tagmapping.ResourceARN, "tag", tag)
 					continue
 				}
-				names = append(names, name)
+				arns = append(arns, *tagmapping.ResourceARN)
 			}
 		}
 		return true
@@ -406,7 +652,14 @@ func (s *Service) listByTag(tag string) ([]string, error) {
 		return nil, errors.Wrapf(err, "failed to list %s ELBs by tag group", s.scope.Name())
 	}
 
-	return names, nil
+	return arns, nil
+}
+
+// classicELBNameFromARN returns the name of a classic ELB from its ARN.
+func classicELBNameFromARN(arn string) string {
+	// We can't use arn.Parse because the "Resource" is loadbalancer/<name>
+	parts := strings.Split(arn, "/")
+	return parts[len(parts)-1]
 }
 
 func (s *Service) listOwnedELBs() ([]string, error) {
@@ -424,7 +677,14 @@ func (s *Service) listOwnedELBs() ([]string, error) {
 		return nil, err
 	}
 
-	return append(arns, clusterArns...), nil
+	// An existing load balancer is never deleted, even if tagged for the cluster.
+	owned := []string{}
+	for _, arn := range append(arns, clusterArns...) {
+		if arn != s.scope.ControlPlaneLoadBalancerARN() {
+			owned = append(owned, arn)
+		}
+	}
+	return owned, nil
 }
 
 func (s *Service) describeClassicELB(name string) (*infrav1.ClassicELB, error) {
@@ -432,7 +692,11 @@ func (s *Service) describeClassicELB(name string) (*infrav1.ClassicELB, error) {
 		LoadBalancerNames: aws.StringSlice([]string{name}),
 	}
 
-	out, err := s.scope.ELB.DescribeLoadBalancers(input)
+	var out *elb.DescribeLoadBalancersOutput
+	err := awserrors.RetryOnThrottling(func() (err error) {
+		out, err = s.scope.ELB.DescribeLoadBalancers(input)
+		return err
+	})
 	if err != nil {
 		if aerr, ok := err.(awserr.Error); ok {
 			switch aerr.Code() {
@@ -458,8 +722,12 @@ func (s *Service) describeClassicELB(name string) (*infrav1.ClassicELB, error) {
 			name, *out.LoadBalancerDescriptions[0].VPCId)
 	}
 
-	outAtt, err := s.scope.ELB.DescribeLoadBalancerAttributes(&elb.DescribeLoadBalancerAttributesInput{
-		LoadBalancerName: aws.String(name),
+	var outAtt *elb.DescribeLoadBalancerAttributesOutput
+	err = awserrors.RetryOnThrottling(func() (err error) {
+		outAtt, err = s.scope.ELB.DescribeLoadBalancerAttributes(&elb.DescribeLoadBalancerAttributesInput{
+			LoadBalancerName: aws.String(name),
+		})
+		return err
 	})
 
 	if err != nil {
@@ -470,8 +738,12 @@ func (s *Service) describeClassicELB(name string) (*infrav1.ClassicELB, error) {
 }
 
 func (s *Service) reconcileELBTags(name string, desiredTags map[string]string) error {
-	tags, err := s.scope.ELB.DescribeTags(&elb.DescribeTagsInput{
-		LoadBalancerNames: []*string{aws.String(name)},
+	var tags *elb.DescribeTagsOutput
+	err := awserrors.RetryOnThrottling(func() (err error) {
+		tags, err = s.scope.ELB.DescribeTags(&elb.DescribeTagsInput{
+			LoadBalancerNames: []*string{aws.String(name)},
+		})
+		return err
 	})
 	if err != nil {
 		return err
@@ -498,8 +770,13 @@ func (s *Service) reconcileELBTags(name string, desiredTags map[string]string) e
 		}
 	}
 
-	for k := range currentTags {
-		if _, ok := desiredTags[k]; !ok {
+	// Only remove the tags that were applied from AdditionalTags, tags set by
+	// others are left untouched.
+	for k, v := range s.scope.RemovedAdditionalTags() {
+		if _, ok := desiredTags[k]; ok {
+			continue
+		}
+		if val, ok := currentTags[k]; ok && val == v {
 			s.scope.V(4).Info("removing tag from load balancer", "elb-name", name, "key", k)
 			removeTagsInput.Tags = append(removeTagsInput.Tags, &elb.TagKeyOnly{Key: aws.String(k)})
 		}
@@ -522,16 +799,41 @@ func (s *Service) reconcileELBTags(name string, desiredTags map[string]string) e
 
 func fromSDKTypeToClassicELB(v *elb.LoadBalancerDescription, attrs *elb.LoadBalancerAttributes) *infrav1.ClassicELB {
 	res := &infrav1.ClassicELB{
-		Name:             aws.StringValue(v.LoadBalancerName),
-		Scheme:           infrav1.ClassicELBScheme(*v.Scheme),
-		SubnetIDs:        aws.StringValueSlice(v.Subnets),
-		SecurityGroupIDs: aws.StringValueSlice(v.SecurityGroups),
-		DNSName:          aws.StringValue(v.DNSName),
+		Name:                  aws.StringValue(v.LoadBalancerName),
+		Scheme:                infrav1.ClassicELBScheme(*v.Scheme),
+		SubnetIDs:             aws.StringValueSlice(v.Subnets),
+		SecurityGroupIDs:      aws.StringValueSlice(v.SecurityGroups),
+		DNSName:               aws.StringValue(v.DNSName),
+		CanonicalHostedZoneID: aws.StringValue(v.CanonicalHostedZoneNameID),
 	}
 
 	if attrs.ConnectionSettings != nil && attrs.ConnectionSettings.IdleTimeout != nil {
 		res.Attributes.IdleTimeout = time.Duration(*attrs.ConnectionSettings.IdleTimeout) * time.Second
 	}
+	if attrs.CrossZoneLoadBalancing != nil {
+		res.CrossZoneLoadBalancing = aws.BoolValue(attrs.CrossZoneLoadBalancing.Enabled)
+	}
+
+	for _, backend := range v.BackendServerDescriptions {
+		if aws.Int64Value(backend.InstancePort) != 6443 {
+			continue
+		}
+		for _, policy := range backend.PolicyNames {
+			if aws.StringValue(policy) == proxyProtocolPolicyName {
+				res.ProxyProtocol = true
+			}
+		}
+	}
+
+	if v.HealthCheck != nil {
+		res.HealthCheck = &infrav1.ClassicELBHealthCheck{
+			Target:             aws.StringValue(v.HealthCheck.Target),
+			Interval:           time.Duration(aws.Int64Value(v.HealthCheck.Interval)) * time.Second,
+			Timeout:            time.Duration(aws.Int64Value(v.HealthCheck.Timeout)) * time.Second,
+			HealthyThreshold:   aws.Int64Value(v.HealthCheck.HealthyThreshold),
+			UnhealthyThreshold: aws.Int64Value(v.HealthCheck.UnhealthyThreshold),
+		}
+	}
 
 	return res
 }
@@ -17,7 +17,17 @@ limitations under the License.
 package elb
 
 import (
+	"strings"
 	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/elb"
+	"github.com/golang/mock/gomock"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	infrav1 "sigs.k8s.io/cluster-api-provider-aws/api/v1alpha3"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/scope"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/services/elb/mock_elbiface"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1alpha3"
 )
 
 func TestGenerateELBName(t *testing.T) {
@@ -66,3 +76,496 @@ func TestGenerateELBName(t *testing.T) {
 		})
 	}
 }
+
+func TestReconcileLoadbalancersDNSNameChange(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	drainEvents()
+
+	elbMock := mock_elbiface.NewMockELBAPI(mockCtrl)
+
+	scope, err := scope.NewClusterScope(scope.ClusterScopeParams{
+		Cluster: &clusterv1.Cluster{
+			ObjectMeta: metav1.ObjectMeta{Name: "test-cluster"},
+		},
+		AWSClients: scope.AWSClients{
+			ELB: elbMock,
+		},
+		AWSCluster: &infrav1.AWSCluster{
+			Spec: infrav1.AWSClusterSpec{
+				NetworkSpec: infrav1.NetworkSpec{
+					VPC: infrav1.VPCSpec{
+						ID: "vpc-elb",
+					},
+				},
+			},
+			Status: infrav1.AWSClusterStatus{
+				Network: infrav1.Network{
+					APIServerELB: infrav1.ClassicELB{
+						Name:    "test-cluster-apiserver",
+						DNSName: "test-cluster-apiserver-1.us-east-1.elb.amazonaws.com",
+					},
+				},
+			},
+		},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create test context: %v", err)
+	}
+
+	elbMock.EXPECT().DescribeLoadBalancers(gomock.Eq(&elb.DescribeLoadBalancersInput{
+		LoadBalancerNames: aws.StringSlice([]string{"test-cluster-apiserver"}),
+	})).Return(&elb.DescribeLoadBalancersOutput{
+		LoadBalancerDescriptions: []*elb.LoadBalancerDescription{
+			{
+				LoadBalancerName: aws.String("test-cluster-apiserver"),
+				Scheme:           aws.String(string(infrav1.ClassicELBSchemeInternetFacing)),
+				VPCId:            aws.String("vpc-elb"),
+				DNSName:          aws.String("test-cluster-apiserver-2.us-east-1.elb.amazonaws.com"),
+				HealthCheck: &elb.HealthCheck{
+					Target:             aws.String("SSL:6443"),
+					Interval:           aws.Int64(10),
+					Timeout:            aws.Int64(5),
+					HealthyThreshold:   aws.Int64(5),
+					UnhealthyThreshold: aws.Int64(3),
+				},
+			},
+		},
+	}, nil)
+	elbMock.EXPECT().DescribeLoadBalancerAttributes(gomock.Eq(&elb.DescribeLoadBalancerAttributesInput{
+		LoadBalancerName: aws.String("test-cluster-apiserver"),
+	})).Return(&elb.DescribeLoadBalancerAttributesOutput{
+		LoadBalancerAttributes: &elb.LoadBalancerAttributes{
+			ConnectionSettings: &elb.ConnectionSettings{IdleTimeout: aws.Int64(600)},
+		},
+	}, nil)
+	elbMock.EXPECT().DescribeTags(gomock.AssignableToTypeOf(&elb.DescribeTagsInput{})).
+		Return(&elb.DescribeTagsOutput{
+			TagDescriptions: []*elb.TagDescription{{LoadBalancerName: aws.String("test-cluster-apiserver")}},
+		}, nil)
+	elbMock.EXPECT().AddTags(gomock.AssignableToTypeOf(&elb.AddTagsInput{})).
+		Return(&elb.AddTagsOutput{}, nil)
+
+	s := NewService(scope)
+	if err := s.ReconcileLoadbalancers(); err != nil {
+		t.Fatalf("got an unexpected error: %v", err)
+	}
+
+	if got := scope.Network().APIServerELB.DNSName; got != "test-cluster-apiserver-2.us-east-1.elb.amazonaws.com" {
+		t.Fatalf("expected the new DNS name to be recorded in status, got %q", got)
+	}
+
+	select {
+	case event := <-recorder.Events:
+		if !strings.Contains(event, "APIServerELBDNSNameChanged") {
+			t.Fatalf("unexpected event %q", event)
+		}
+	default:
+		t.Fatal("expected a warning event for the DNS name change")
+	}
+}
+
+func TestReconcileLoadbalancersHealthCheck(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	elbMock := mock_elbiface.NewMockELBAPI(mockCtrl)
+
+	scope, err := scope.NewClusterScope(scope.ClusterScopeParams{
+		Cluster: &clusterv1.Cluster{
+			ObjectMeta: metav1.ObjectMeta{Name: "test-cluster"},
+		},
+		AWSClients: scope.AWSClients{
+			ELB: elbMock,
+		},
+		AWSCluster: &infrav1.AWSCluster{
+			Spec: infrav1.AWSClusterSpec{
+				NetworkSpec: infrav1.NetworkSpec{
+					VPC: infrav1.VPCSpec{
+						ID: "vpc-elb",
+					},
+				},
+				ControlPlaneLoadBalancer: &infrav1.AWSLoadBalancerSpec{
+					HealthCheck: &infrav1.ClassicELBHealthCheckSpec{
+						Protocol:           infrav1.ClassicELBProtocolHTTPS,
+						IntervalSeconds:    30,
+						UnhealthyThreshold: 5,
+					},
+				},
+			},
+		},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create test context: %v", err)
+	}
+
+	// The load balancer still has the default health check, e.g. after it was
+	// changed out of band, and is reconfigured with the custom one.
+	elbMock.EXPECT().DescribeLoadBalancers(gomock.AssignableToTypeOf(&elb.DescribeLoadBalancersInput{})).
+		Return(&elb.DescribeLoadBalancersOutput{
+			LoadBalancerDescriptions: []*elb.LoadBalancerDescription{
+				{
+					LoadBalancerName: aws.String("test-cluster-apiserver"),
+					Scheme:           aws.String(string(infrav1.ClassicELBSchemeInternetFacing)),
+					VPCId:            aws.String("vpc-elb"),
+					DNSName:          aws.String("test-cluster-apiserver.us-east-1.elb.amazonaws.com"),
+					HealthCheck: &elb.HealthCheck{
+						Target:             aws.String("SSL:6443"),
+						Interval:           aws.Int64(10),
+						Timeout:            aws.Int64(5),
+						HealthyThreshold:   aws.Int64(5),
+						UnhealthyThreshold: aws.Int64(3),
+					},
+				},
+			},
+		}, nil)
+	elbMock.EXPECT().DescribeLoadBalancerAttributes(gomock.AssignableToTypeOf(&elb.DescribeLoadBalancerAttributesInput{})).
+		Return(&elb.DescribeLoadBalancerAttributesOutput{
+			LoadBalancerAttributes: &elb.LoadBalancerAttributes{
+				ConnectionSettings: &elb.ConnectionSettings{IdleTimeout: aws.Int64(600)},
+			},
+		}, nil)
+	elbMock.EXPECT().ConfigureHealthCheck(gomock.Eq(&elb.ConfigureHealthCheckInput{
+		LoadBalancerName: aws.String("test-cluster-apiserver"),
+		HealthCheck: &elb.HealthCheck{
+			Target:             aws.String("HTTPS:6443/healthz"),
+			Interval:           aws.Int64(30),
+			Timeout:            aws.Int64(5),
+			HealthyThreshold:   aws.Int64(5),
+			UnhealthyThreshold: aws.Int64(5),
+		},
+	})).Return(&elb.ConfigureHealthCheckOutput{}, nil)
+	elbMock.EXPECT().DescribeTags(gomock.AssignableToTypeOf(&elb.DescribeTagsInput{})).
+		Return(&elb.DescribeTagsOutput{
+			TagDescriptions: []*elb.TagDescription{{LoadBalancerName: aws.String("test-cluster-apiserver")}},
+		}, nil)
+	elbMock.EXPECT().AddTags(gomock.AssignableToTypeOf(&elb.AddTagsInput{})).
+		Return(&elb.AddTagsOutput{}, nil)
+
+	s := NewService(scope)
+	if err := s.ReconcileLoadbalancers(); err != nil {
+		t.Fatalf("got an unexpected error: %v", err)
+	}
+
+	if got := scope.Network().APIServerELB.HealthCheck; got == nil || got.Target != "HTTPS:6443/healthz" {
+		t.Fatalf("expected the custom health check to be recorded in status, got %+v", got)
+	}
+}
+
+func TestReconcileLoadbalancersProxyProtocol(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	testCases := []struct {
+		name           string
+		proxyProtocol  bool
+		backendServers []*elb.BackendServerDescription
+		expect         func(m *mock_elbiface.MockELBAPIMockRecorder)
+	}{
+		{
+			name:          "proxy protocol is enabled",
+			proxyProtocol: true,
+			expect: func(m *mock_elbiface.MockELBAPIMockRecorder) {
+				m.CreateLoadBalancerPolicy(gomock.Eq(&elb.CreateLoadBalancerPolicyInput{
+					LoadBalancerName: aws.String("test-cluster-apiserver"),
+					PolicyName:       aws.String("capa-proxy-protocol"),
+					PolicyTypeName:   aws.String("ProxyProtocolPolicyType"),
+					PolicyAttributes: []*elb.PolicyAttribute{
+						{AttributeName: aws.String("ProxyProtocol"), AttributeValue: aws.String("true")},
+					},
+				})).Return(&elb.CreateLoadBalancerPolicyOutput{}, nil)
+				m.SetLoadBalancerPoliciesForBackendServer(gomock.Eq(&elb.SetLoadBalancerPoliciesForBackendServerInput{
+					LoadBalancerName: aws.String("test-cluster-apiserver"),
+					InstancePort:     aws.Int64(6443),
+					PolicyNames:      aws.StringSlice([]string{"capa-proxy-protocol"}),
+				})).Return(&elb.SetLoadBalancerPoliciesForBackendServerOutput{}, nil)
+			},
+		},
+		{
+			name:          "proxy protocol is already enabled",
+			proxyProtocol: true,
+			backendServers: []*elb.BackendServerDescription{
+				{InstancePort: aws.Int64(6443), PolicyNames: aws.StringSlice([]string{"capa-proxy-protocol"})},
+			},
+			expect: func(m *mock_elbiface.MockELBAPIMockRecorder) {},
+		},
+		{
+			name: "proxy protocol is disabled",
+			backendServers: []*elb.BackendServerDescription{
+				{InstancePort: aws.Int64(6443), PolicyNames: aws.StringSlice([]string{"capa-proxy-protocol"})},
+			},
+			expect: func(m *mock_elbiface.MockELBAPIMockRecorder) {
+				m.SetLoadBalancerPoliciesForBackendServer(gomock.Eq(&elb.SetLoadBalancerPoliciesForBackendServerInput{
+					LoadBalancerName: aws.String("test-cluster-apiserver"),
+					InstancePort:     aws.Int64(6443),
+					PolicyNames:      []*string{},
+				})).Return(&elb.SetLoadBalancerPoliciesForBackendServerOutput{}, nil)
+				m.DeleteLoadBalancerPolicy(gomock.Eq(&elb.DeleteLoadBalancerPolicyInput{
+					LoadBalancerName: aws.String("test-cluster-apiserver"),
+					PolicyName:       aws.String("capa-proxy-protocol"),
+				})).Return(&elb.DeleteLoadBalancerPolicyOutput{}, nil)
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			elbMock := mock_elbiface.NewMockELBAPI(mockCtrl)
+
+			scope, err := scope.NewClusterScope(scope.ClusterScopeParams{
+				Cluster: &clusterv1.Cluster{
+					ObjectMeta: metav1.ObjectMeta{Name: "test-cluster"},
+				},
+				AWSClients: scope.AWSClients{
+					ELB: elbMock,
+				},
+				AWSCluster: &infrav1.AWSCluster{
+					Spec: infrav1.AWSClusterSpec{
+						NetworkSpec: infrav1.NetworkSpec{
+							VPC: infrav1.VPCSpec{
+								ID: "vpc-elb",
+							},
+						},
+						ControlPlaneLoadBalancer: &infrav1.AWSLoadBalancerSpec{
+							ProxyProtocol: tc.proxyProtocol,
+						},
+					},
+				},
+			})
+			if err != nil {
+				t.Fatalf("Failed to create test context: %v", err)
+			}
+
+			m := elbMock.EXPECT()
+			m.DescribeLoadBalancers(gomock.AssignableToTypeOf(&elb.DescribeLoadBalancersInput{})).
+				Return(&elb.DescribeLoadBalancersOutput{
+					LoadBalancerDescriptions: []*elb.LoadBalancerDescription{
+						{
+							LoadBalancerName:          aws.String("test-cluster-apiserver"),
+							Scheme:                    aws.String(string(infrav1.ClassicELBSchemeInternetFacing)),
+							VPCId:                     aws.String("vpc-elb"),
+							DNSName:                   aws.String("test-cluster-apiserver.us-east-1.elb.amazonaws.com"),
+							BackendServerDescriptions: tc.backendServers,
+							HealthCheck: &elb.HealthCheck{
+								Target:             aws.String("SSL:6443"),
+								Interval:           aws.Int64(10),
+								Timeout:            aws.Int64(5),
+								HealthyThreshold:   aws.Int64(5),
+								UnhealthyThreshold: aws.Int64(3),
+							},
+						},
+					},
+				}, nil)
+			m.DescribeLoadBalancerAttributes(gomock.AssignableToTypeOf(&elb.DescribeLoadBalancerAttributesInput{})).
+				Return(&elb.DescribeLoadBalancerAttributesOutput{
+					LoadBalancerAttributes: &elb.LoadBalancerAttributes{
+						ConnectionSettings: &elb.ConnectionSettings{IdleTimeout: aws.Int64(600)},
+					},
+				}, nil)
+			tc.expect(m)
+			m.DescribeTags(gomock.AssignableToTypeOf(&elb.DescribeTagsInput{})).
+				Return(&elb.DescribeTagsOutput{
+					TagDescriptions: []*elb.TagDescription{{LoadBalancerName: aws.String("test-cluster-apiserver")}},
+				}, nil)
+			m.AddTags(gomock.AssignableToTypeOf(&elb.AddTagsInput{})).
+				Return(&elb.AddTagsOutput{}, nil)
+
+			s := NewService(scope)
+			if err := s.ReconcileLoadbalancers(); err != nil {
+				t.Fatalf("got an unexpected error: %v", err)
+			}
+
+			if got := scope.Network().APIServerELB.ProxyProtocol; got != tc.proxyProtocol {
+				t.Fatalf("expected proxy protocol %t to be recorded in status, got %t", tc.proxyProtocol, got)
+			}
+		})
+	}
+}
+
+func TestReconcileLoadbalancersAttributes(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	testCases := []struct {
+		name       string
+		spec       *infrav1.AWSLoadBalancerSpec
+		attributes *elb.LoadBalancerAttributes
+		expect     func(m *mock_elbiface.MockELBAPIMockRecorder)
+	}{
+		{
+			name: "attributes are up to date",
+			spec: &infrav1.AWSLoadBalancerSpec{CrossZoneLoadBalancing: true},
+			attributes: &elb.LoadBalancerAttributes{
+				ConnectionSettings:     &elb.ConnectionSettings{IdleTimeout: aws.Int64(600)},
+				CrossZoneLoadBalancing: &elb.CrossZoneLoadBalancing{Enabled: aws.Bool(true)},
+			},
+			expect: func(m *mock_elbiface.MockELBAPIMockRecorder) {},
+		},
+		{
+			name: "cross-zone load balancing and the idle timeout are configured",
+			spec: &infrav1.AWSLoadBalancerSpec{
+				IdleTimeoutSeconds:     120,
+				CrossZoneLoadBalancing: true,
+			},
+			attributes: &elb.LoadBalancerAttributes{
+				ConnectionSettings:     &elb.ConnectionSettings{IdleTimeout: aws.Int64(600)},
+				CrossZoneLoadBalancing: &elb.CrossZoneLoadBalancing{Enabled: aws.Bool(false)},
+			},
+			expect: func(m *mock_elbiface.MockELBAPIMockRecorder) {
+				m.ModifyLoadBalancerAttributes(gomock.Eq(&elb.ModifyLoadBalancerAttributesInput{
+					LoadBalancerName: aws.String("test-cluster-apiserver"),
+					LoadBalancerAttributes: &elb.LoadBalancerAttributes{
+						ConnectionSettings:     &elb.ConnectionSettings{IdleTimeout: aws.Int64(120)},
+						CrossZoneLoadBalancing: &elb.CrossZoneLoadBalancing{Enabled: aws.Bool(true)},
+					},
+				})).Return(&elb.ModifyLoadBalancerAttributesOutput{}, nil)
+			},
+		},
+		{
+			name: "cross-zone load balancing enabled out of band is turned off",
+			spec: &infrav1.AWSLoadBalancerSpec{},
+			attributes: &elb.LoadBalancerAttributes{
+				ConnectionSettings:     &elb.ConnectionSettings{IdleTimeout: aws.Int64(600)},
+				CrossZoneLoadBalancing: &elb.CrossZoneLoadBalancing{Enabled: aws.Bool(true)},
+			},
+			expect: func(m *mock_elbiface.MockELBAPIMockRecorder) {
+				m.ModifyLoadBalancerAttributes(gomock.Eq(&elb.ModifyLoadBalancerAttributesInput{
+					LoadBalancerName: aws.String("test-cluster-apiserver"),
+					LoadBalancerAttributes: &elb.LoadBalancerAttributes{
+						ConnectionSettings:     &elb.ConnectionSettings{IdleTimeout: aws.Int64(600)},
+						CrossZoneLoadBalancing: &elb.CrossZoneLoadBalancing{Enabled: aws.Bool(false)},
+					},
+				})).Return(&elb.ModifyLoadBalancerAttributesOutput{}, nil)
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			elbMock := mock_elbiface.NewMockELBAPI(mockCtrl)
+
+			scope, err := scope.NewClusterScope(scope.ClusterScopeParams{
+				Cluster: &clusterv1.Cluster{
+					ObjectMeta: metav1.ObjectMeta{Name: "test-cluster"},
+				},
+				AWSClients: scope.AWSClients{
+					ELB: elbMock,
+				},
+				AWSCluster: &infrav1.AWSCluster{
+					Spec: infrav1.AWSClusterSpec{
+						NetworkSpec: infrav1.NetworkSpec{
+							VPC: infrav1.VPCSpec{
+								ID: "vpc-elb",
+							},
+						},
+						ControlPlaneLoadBalancer: tc.spec,
+					},
+				},
+			})
+			if err != nil {
+				t.Fatalf("Failed to create test context: %v", err)
+			}
+
+			m := elbMock.EXPECT()
+			m.DescribeLoadBalancers(gomock.AssignableToTypeOf(&elb.DescribeLoadBalancersInput{})).
+				Return(&elb.DescribeLoadBalancersOutput{
+					LoadBalancerDescriptions: []*elb.LoadBalancerDescription{
+						{
+							LoadBalancerName: aws.String("test-cluster-apiserver"),
+							Scheme:           aws.String(string(infrav1.ClassicELBSchemeInternetFacing)),
+							VPCId:            aws.String("vpc-elb"),
+							DNSName:          aws.String("test-cluster-apiserver.us-east-1.elb.amazonaws.com"),
+							HealthCheck: &elb.HealthCheck{
+								Target:             aws.String("SSL:6443"),
+								Interval:           aws.Int64(10),
+								Timeout:            aws.Int64(5),
+								HealthyThreshold:   aws.Int64(5),
+								UnhealthyThreshold: aws.Int64(3),
+							},
+						},
+					},
+				}, nil)
+			m.DescribeLoadBalancerAttributes(gomock.AssignableToTypeOf(&elb.DescribeLoadBalancerAttributesInput{})).
+				Return(&elb.DescribeLoadBalancerAttributesOutput{LoadBalancerAttributes: tc.attributes}, nil)
+			tc.expect(m)
+			m.DescribeTags(gomock.AssignableToTypeOf(&elb.DescribeTagsInput{})).
+				Return(&elb.DescribeTagsOutput{
+					TagDescriptions: []*elb.TagDescription{{LoadBalancerName: aws.String("test-cluster-apiserver")}},
+				}, nil)
+			m.AddTags(gomock.AssignableToTypeOf(&elb.AddTagsInput{})).
+				Return(&elb.AddTagsOutput{}, nil)
+
+			s := NewService(scope)
+			if err := s.ReconcileLoadbalancers(); err != nil {
+				t.Fatalf("got an unexpected error: %v", err)
+			}
+
+			if got := scope.Network().APIServerELB.CrossZoneLoadBalancing; got != tc.spec.CrossZoneLoadBalancing {
+				t.Fatalf("expected cross-zone load balancing %t to be recorded in status, got %t", tc.spec.CrossZoneLoadBalancing, got)
+			}
+		})
+	}
+}
+
+func TestReconcileELBTags(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	elbMock := mock_elbiface.NewMockELBAPI(mockCtrl)
+
+	scope, err := scope.NewClusterScope(scope.ClusterScopeParams{
+		Cluster: &clusterv1.Cluster{
+			ObjectMeta: metav1.ObjectMeta{Name: "test-cluster"},
+		},
+		AWSClients: scope.AWSClients{
+			ELB: elbMock,
+		},
+		AWSCluster: &infrav1.AWSCluster{
+			ObjectMeta: metav1.ObjectMeta{
+				Annotations: map[string]string{
+					scope.LastAppliedTagsAnnotation: `{"env":"prod","team":"a"}`,
+				},
+			},
+			Spec: infrav1.AWSClusterSpec{
+				AdditionalTags: infrav1.Tags{"env": "staging"},
+			},
+		},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create test context: %v", err)
+	}
+
+	elbMock.EXPECT().DescribeTags(gomock.Eq(&elb.DescribeTagsInput{
+		LoadBalancerNames: aws.StringSlice([]string{"test-cluster-apiserver"}),
+	})).Return(&elb.DescribeTagsOutput{
+		TagDescriptions: []*elb.TagDescription{
+			{
+				LoadBalancerName: aws.String("test-cluster-apiserver"),
+				Tags: []*elb.Tag{
+					{Key: aws.String("sigs.k8s.io/cluster-api-provider-aws/cluster/test-cluster"), Value: aws.String("owned")},
+					{Key: aws.String("env"), Value: aws.String("prod")},
+					{Key: aws.String("team"), Value: aws.String("a")},
+					{Key: aws.String("owner"), Value: aws.String("someone-else")},
+				},
+			},
+		},
+	}, nil)
+	elbMock.EXPECT().AddTags(gomock.Eq(&elb.AddTagsInput{
+		LoadBalancerNames: aws.StringSlice([]string{"test-cluster-apiserver"}),
+		Tags:              []*elb.Tag{{Key: aws.String("env"), Value: aws.String("staging")}},
+	})).Return(&elb.AddTagsOutput{}, nil)
+	elbMock.EXPECT().RemoveTags(gomock.Eq(&elb.RemoveTagsInput{
+		LoadBalancerNames: aws.StringSlice([]string{"test-cluster-apiserver"}),
+		Tags:              []*elb.TagKeyOnly{{Key: aws.String("team")}},
+	})).Return(&elb.RemoveTagsOutput{}, nil)
+
+	s := NewService(scope)
+	desired := map[string]string{
+		"sigs.k8s.io/cluster-api-provider-aws/cluster/test-cluster": "owned",
+		"env": "staging",
+	}
+	if err := s.reconcileELBTags("test-cluster-apiserver", desired); err != nil {
+		t.Fatalf("got an unexpected error: %v", err)
+	}
+}
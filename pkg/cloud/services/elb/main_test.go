@@ -0,0 +1,41 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package elb
+
+import (
+	"os"
+	"testing"
+
+	"k8s.io/client-go/tools/record"
+	capirecord "sigs.k8s.io/cluster-api-provider-aws/pkg/record"
+)
+
+// recorder receives the events of every test in this package. The global
+// recorder can only be initialized once, so the tests must not replace it.
+var recorder = record.NewFakeRecorder(100)
+
+func TestMain(m *testing.M) {
+	capirecord.InitFromRecorder(recorder)
+	os.Exit(m.Run())
+}
+
+// drainEvents discards the events recorded by earlier tests.
+func drainEvents() {
+	for len(recorder.Events) > 0 {
+		<-recorder.Events
+	}
+}
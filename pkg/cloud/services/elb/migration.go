@@ -0,0 +1,155 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package elb
+
+import (
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/elb"
+	"github.com/aws/aws-sdk-go/service/elbv2"
+	"github.com/pkg/errors"
+	infrav1 "sigs.k8s.io/cluster-api-provider-aws/api/v1alpha3"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/awserrors"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/record"
+)
+
+// reconcileClassicELBMigration moves the control plane instances registered
+// with the classic ELB of the cluster to the network load balancer replacing
+// it, and deletes the classic ELB once they are healthy behind the network
+// load balancer. It only runs while the cluster has the
+// MigrateLoadBalancerAnnotation, which is removed once the migration is done.
+func (s *Service) reconcileClassicELBMigration() error {
+	if _, ok := s.scope.AWSCluster.GetAnnotations()[infrav1.MigrateLoadBalancerAnnotation]; !ok {
+		return nil
+	}
+
+	name, err := GenerateELBName(s.scope.Name())
+	if err != nil {
+		return err
+	}
+
+	instanceIDs, err := s.describeClassicELBInstances(name)
+	if IsNotFound(err) {
+		s.removeMigrateLoadBalancerAnnotation()
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	s.scope.Info("Migrating control plane load balancer to a network load balancer", "api-server-elb-name", name, "instances", instanceIDs)
+
+	if len(instanceIDs) > 0 {
+		targetGroupARN, err := s.getAPIServerTargetGroupARN(name)
+		if err != nil {
+			return err
+		}
+
+		targets := make([]*elbv2.TargetDescription, 0, len(instanceIDs))
+		for _, id := range instanceIDs {
+			targets = append(targets, &elbv2.TargetDescription{Id: aws.String(id)})
+		}
+
+		// Registering a registered target again has no effect.
+		if err := awserrors.RetryOnThrottling(func() error {
+			_, err := s.scope.ELBV2.RegisterTargets(&elbv2.RegisterTargetsInput{
+				TargetGroupArn: aws.String(targetGroupARN),
+				Targets:        targets,
+			})
+			return err
+		}); err != nil {
+			record.Warnf(s.scope.AWSCluster, "FailedMigrateLoadBalancer", "Failed to register control plane instances with target group %q: %v", targetGroupARN, err)
+			return errors.Wrapf(err, "failed to register control plane instances with target group %q", targetGroupARN)
+		}
+
+		// Deleting the classic ELB before the network load balancer serves
+		// the API would make the control plane unreachable in the meantime.
+		unhealthy, err := s.describeUnhealthyTargets(targetGroupARN, targets)
+		if err != nil {
+			return err
+		}
+		if len(unhealthy) > 0 {
+			return errors.Errorf("waiting for control plane instances %v to become healthy in target group %q before deleting classic load balancer %q", unhealthy, targetGroupARN, name)
+		}
+	}
+
+	if err := s.deleteClassicELB(name); err != nil {
+		record.Warnf(s.scope.AWSCluster, "FailedMigrateLoadBalancer", "Failed to delete classic load balancer %q: %v", name, err)
+		return errors.Wrapf(err, "failed to delete classic load balancer %q", name)
+	}
+
+	record.Eventf(s.scope.AWSCluster, "SuccessfulMigrateLoadBalancer", "Migrated control plane load balancer %q from a classic to a network load balancer", name)
+	s.removeMigrateLoadBalancerAnnotation()
+	return nil
+}
+
+// describeClassicELBInstances returns the IDs of the instances registered
+// with the classic ELB of the given name in the cluster VPC.
+func (s *Service) describeClassicELBInstances(name string) ([]string, error) {
+	var out *elb.DescribeLoadBalancersOutput
+	err := awserrors.RetryOnThrottling(func() (err error) {
+		out, err = s.scope.ELB.DescribeLoadBalancers(&elb.DescribeLoadBalancersInput{
+			LoadBalancerNames: aws.StringSlice([]string{name}),
+		})
+		return err
+	})
+	if IsNotFound(err) {
+		return nil, NewNotFound(errors.Errorf("no classic load balancer found with name %q", name))
+	} else if err != nil {
+		return nil, errors.Wrapf(err, "failed to describe classic load balancer %q", name)
+	}
+
+	// Load balancer names are unique per region, a classic ELB of the same
+	// name in another VPC belongs to another cluster.
+	if len(out.LoadBalancerDescriptions) == 0 || aws.StringValue(out.LoadBalancerDescriptions[0].VPCId) != s.scope.VPC().ID {
+		return nil, NewNotFound(errors.Errorf("no classic load balancer found with name %q", name))
+	}
+
+	ids := make([]string, 0, len(out.LoadBalancerDescriptions[0].Instances))
+	for _, instance := range out.LoadBalancerDescriptions[0].Instances {
+		ids = append(ids, aws.StringValue(instance.InstanceId))
+	}
+	return ids, nil
+}
+
+// describeUnhealthyTargets returns the IDs of the given targets which aren't
+// healthy in the target group.
+func (s *Service) describeUnhealthyTargets(targetGroupARN string, targets []*elbv2.TargetDescription) ([]string, error) {
+	var out *elbv2.DescribeTargetHealthOutput
+	err := awserrors.RetryOnThrottling(func() (err error) {
+		out, err = s.scope.ELBV2.DescribeTargetHealth(&elbv2.DescribeTargetHealthInput{
+			TargetGroupArn: aws.String(targetGroupARN),
+			Targets:        targets,
+		})
+		return err
+	})
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to describe target health of target group %q", targetGroupARN)
+	}
+
+	var unhealthy []string
+	for _, desc := range out.TargetHealthDescriptions {
+		if desc.TargetHealth == nil || aws.StringValue(desc.TargetHealth.State) != elbv2.TargetHealthStateEnumHealthy {
+			unhealthy = append(unhealthy, aws.StringValue(desc.Target.Id))
+		}
+	}
+	return unhealthy, nil
+}
+
+func (s *Service) removeMigrateLoadBalancerAnnotation() {
+	annotations := s.scope.AWSCluster.GetAnnotations()
+	delete(annotations, infrav1.MigrateLoadBalancerAnnotation)
+	s.scope.AWSCluster.SetAnnotations(annotations)
+}
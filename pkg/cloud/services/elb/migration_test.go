@@ -0,0 +1,214 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package elb
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/elb"
+	"github.com/aws/aws-sdk-go/service/elbv2"
+	"github.com/golang/mock/gomock"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	infrav1 "sigs.k8s.io/cluster-api-provider-aws/api/v1alpha3"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/scope"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/services/elb/mock_elbiface"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/services/elb/mock_elbv2iface"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1alpha3"
+)
+
+func TestReconcileClassicELBMigration(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	const tgARN = "arn:aws:elasticloadbalancing:us-east-1:123456789012:targetgroup/test-cluster-apiserver/73e2d6bc24d8a067"
+
+	describeClassicELB := func(m *mock_elbiface.MockELBAPIMockRecorder, vpcID string, instanceIDs ...string) {
+		var instances []*elb.Instance
+		for _, id := range instanceIDs {
+			instances = append(instances, &elb.Instance{InstanceId: aws.String(id)})
+		}
+		m.DescribeLoadBalancers(gomock.Eq(&elb.DescribeLoadBalancersInput{
+			LoadBalancerNames: aws.StringSlice([]string{"test-cluster-apiserver"}),
+		})).Return(&elb.DescribeLoadBalancersOutput{
+			LoadBalancerDescriptions: []*elb.LoadBalancerDescription{
+				{
+					LoadBalancerName: aws.String("test-cluster-apiserver"),
+					VPCId:            aws.String(vpcID),
+					Instances:        instances,
+				},
+			},
+		}, nil)
+	}
+	registerTargets := func(m *mock_elbv2iface.MockELBV2APIMockRecorder, states map[string]string) {
+		var targets []*elbv2.TargetDescription
+		var health []*elbv2.TargetHealthDescription
+		for _, id := range []string{"i-controlplane-1", "i-controlplane-2"} {
+			targets = append(targets, &elbv2.TargetDescription{Id: aws.String(id)})
+			health = append(health, &elbv2.TargetHealthDescription{
+				Target:       &elbv2.TargetDescription{Id: aws.String(id)},
+				TargetHealth: &elbv2.TargetHealth{State: aws.String(states[id])},
+			})
+		}
+		m.DescribeTargetGroups(gomock.Eq(&elbv2.DescribeTargetGroupsInput{
+			Names: aws.StringSlice([]string{"test-cluster-apiserver"}),
+		})).Return(&elbv2.DescribeTargetGroupsOutput{
+			TargetGroups: []*elbv2.TargetGroup{{TargetGroupArn: aws.String(tgARN)}},
+		}, nil)
+		m.RegisterTargets(gomock.Eq(&elbv2.RegisterTargetsInput{
+			TargetGroupArn: aws.String(tgARN),
+			Targets:        targets,
+		})).Return(&elbv2.RegisterTargetsOutput{}, nil)
+		m.DescribeTargetHealth(gomock.Eq(&elbv2.DescribeTargetHealthInput{
+			TargetGroupArn: aws.String(tgARN),
+			Targets:        targets,
+		})).Return(&elbv2.DescribeTargetHealthOutput{TargetHealthDescriptions: health}, nil)
+	}
+
+	testCases := []struct {
+		name             string
+		annotated        bool
+		expectELB        func(m *mock_elbiface.MockELBAPIMockRecorder)
+		expectELBV2      func(m *mock_elbv2iface.MockELBV2APIMockRecorder)
+		expectError      bool
+		expectAnnotation bool
+		expectEvent      string
+	}{
+		{
+			name:        "nothing is migrated without the annotation",
+			expectELB:   func(m *mock_elbiface.MockELBAPIMockRecorder) {},
+			expectELBV2: func(m *mock_elbv2iface.MockELBV2APIMockRecorder) {},
+		},
+		{
+			name:      "instances are moved and the classic load balancer is deleted",
+			annotated: true,
+			expectELB: func(m *mock_elbiface.MockELBAPIMockRecorder) {
+				describeClassicELB(m, "vpc-elb", "i-controlplane-1", "i-controlplane-2")
+				m.DeleteLoadBalancer(gomock.Eq(&elb.DeleteLoadBalancerInput{
+					LoadBalancerName: aws.String("test-cluster-apiserver"),
+				})).Return(&elb.DeleteLoadBalancerOutput{}, nil)
+			},
+			expectELBV2: func(m *mock_elbv2iface.MockELBV2APIMockRecorder) {
+				registerTargets(m, map[string]string{
+					"i-controlplane-1": elbv2.TargetHealthStateEnumHealthy,
+					"i-controlplane-2": elbv2.TargetHealthStateEnumHealthy,
+				})
+			},
+			expectEvent: "SuccessfulMigrateLoadBalancer",
+		},
+		{
+			name:      "the classic load balancer is kept until the instances are healthy",
+			annotated: true,
+			expectELB: func(m *mock_elbiface.MockELBAPIMockRecorder) {
+				describeClassicELB(m, "vpc-elb", "i-controlplane-1", "i-controlplane-2")
+			},
+			expectELBV2: func(m *mock_elbv2iface.MockELBV2APIMockRecorder) {
+				registerTargets(m, map[string]string{
+					"i-controlplane-1": elbv2.TargetHealthStateEnumHealthy,
+					"i-controlplane-2": elbv2.TargetHealthStateEnumInitial,
+				})
+			},
+			expectError:      true,
+			expectAnnotation: true,
+		},
+		{
+			name:      "the annotation is removed once the classic load balancer is gone",
+			annotated: true,
+			expectELB: func(m *mock_elbiface.MockELBAPIMockRecorder) {
+				m.DescribeLoadBalancers(gomock.AssignableToTypeOf(&elb.DescribeLoadBalancersInput{})).
+					Return(nil, awserr.New(elb.ErrCodeAccessPointNotFoundException, "not found", nil))
+			},
+			expectELBV2: func(m *mock_elbv2iface.MockELBV2APIMockRecorder) {},
+		},
+		{
+			name:      "a classic load balancer of the same name in another VPC is left alone",
+			annotated: true,
+			expectELB: func(m *mock_elbiface.MockELBAPIMockRecorder) {
+				describeClassicELB(m, "vpc-other", "i-other")
+			},
+			expectELBV2: func(m *mock_elbv2iface.MockELBV2APIMockRecorder) {},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			drainEvents()
+
+			elbMock := mock_elbiface.NewMockELBAPI(mockCtrl)
+			elbv2Mock := mock_elbv2iface.NewMockELBV2API(mockCtrl)
+
+			awsCluster := &infrav1.AWSCluster{
+				Spec: infrav1.AWSClusterSpec{
+					NetworkSpec: infrav1.NetworkSpec{
+						VPC: infrav1.VPCSpec{
+							ID: "vpc-elb",
+						},
+					},
+					ControlPlaneLoadBalancer: &infrav1.AWSLoadBalancerSpec{
+						LoadBalancerType: infrav1.LoadBalancerTypeNLB,
+					},
+				},
+			}
+			if tc.annotated {
+				awsCluster.Annotations = map[string]string{infrav1.MigrateLoadBalancerAnnotation: ""}
+			}
+
+			scope, err := scope.NewClusterScope(scope.ClusterScopeParams{
+				Cluster: &clusterv1.Cluster{
+					ObjectMeta: metav1.ObjectMeta{Name: "test-cluster"},
+				},
+				AWSClients: scope.AWSClients{
+					ELB:   elbMock,
+					ELBV2: elbv2Mock,
+				},
+				AWSCluster: awsCluster,
+			})
+			if err != nil {
+				t.Fatalf("Failed to create test context: %v", err)
+			}
+
+			tc.expectELB(elbMock.EXPECT())
+			tc.expectELBV2(elbv2Mock.EXPECT())
+
+			s := NewService(scope)
+			err = s.reconcileClassicELBMigration()
+			if tc.expectError && err == nil {
+				t.Fatal("expected an error")
+			} else if !tc.expectError && err != nil {
+				t.Fatalf("got an unexpected error: %v", err)
+			}
+
+			if _, got := awsCluster.Annotations[infrav1.MigrateLoadBalancerAnnotation]; got != tc.expectAnnotation {
+				t.Fatalf("expected the migration annotation to be kept: %t, got %t", tc.expectAnnotation, got)
+			}
+
+			if tc.expectEvent == "" {
+				return
+			}
+			select {
+			case event := <-recorder.Events:
+				if !strings.Contains(event, tc.expectEvent) {
+					t.Fatalf("unexpected event %q", event)
+				}
+			default:
+				t.Fatalf("expected a %s event", tc.expectEvent)
+			}
+		})
+	}
+}
@@ -0,0 +1,647 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package elb
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/aws/aws-sdk-go/service/elbv2"
+	"github.com/pkg/errors"
+	"k8s.io/apimachinery/pkg/util/sets"
+	infrav1 "sigs.k8s.io/cluster-api-provider-aws/api/v1alpha3"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/awserrors"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/converters"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/filter"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/services/wait"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/tags"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/record"
+)
+
+const (
+	// crossZoneLoadBalancingAttribute is the network load balancer attribute
+	// enabling cross-zone load balancing.
+	crossZoneLoadBalancingAttribute = "load_balancing.cross_zone.enabled"
+
+	// deletionProtectionAttribute is the network load balancer attribute
+	// enabling deletion protection.
+	deletionProtectionAttribute = "deletion_protection.enabled"
+)
+
+// reconcileNetworkLoadBalancer reconciles the API server network load balancer,
+// its target group and its listener.
+func (s *Service) reconcileNetworkLoadBalancer() error {
+	spec, err := s.getAPIServerNetworkLoadBalancerSpec()
+	if err != nil {
+		return err
+	}
+
+	// Describe or create.
+	lb, err := s.describeNetworkLoadBalancer(spec.Name)
+	if IsNotFound(err) {
+		lb, err = s.createNetworkLoadBalancer(spec)
+		if err != nil {
+			return err
+		}
+
+		s.scope.V(2).Info("Created new network load balancer for apiserver", "api-server-elb-name", spec.Name)
+	} else if err != nil {
+		return err
+	}
+
+	lbARN := aws.StringValue(lb.LoadBalancerArn)
+	if err := s.reconcileELBV2Tags(lbARN, spec.Tags); err != nil {
+		return errors.Wrapf(err, "failed to reconcile tags for apiserver load balancer %q", spec.Name)
+	}
+
+	if err := s.reconcileNetworkLoadBalancerAttributes(lbARN); err != nil {
+		return err
+	}
+
+	targetGroupARN, err := s.reconcileAPIServerTargetGroup(spec.Name)
+	if err != nil {
+		return err
+	}
+	if err := s.reconcileELBV2Tags(targetGroupARN, spec.Tags); err != nil {
+		return errors.Wrapf(err, "failed to reconcile tags for apiserver target group %q", spec.Name)
+	}
+
+	if err := s.reconcileAPIServerListener(lbARN, targetGroupARN); err != nil {
+		return err
+	}
+
+	if err := s.deleteOrphanedTargetGroups(targetGroupARN); err != nil {
+		return err
+	}
+
+	// TODO: network load balancers cannot change their subnets, reconcile them
+	// once the API allows it.
+	apiELB := fromSDKTypeToNetworkLoadBalancer(lb)
+	apiELB.CrossZoneLoadBalancing = spec.CrossZoneLoadBalancing
+	s.setAPIServerELB(apiELB)
+	return nil
+}
+
+// reconcileNetworkLoadBalancerAttributes applies the attributes network load
+// balancers support, reverting changes made outside of the controller.
+func (s *Service) reconcileNetworkLoadBalancerAttributes(lbARN string) error {
+	var crossZone, deletionProtection bool
+	if lb := s.scope.ControlPlaneLoadBalancer(); lb != nil {
+		crossZone = lb.CrossZoneLoadBalancing
+		deletionProtection = lb.DeletionProtection
+	}
+	desired := map[string]string{
+		crossZoneLoadBalancingAttribute: strconv.FormatBool(crossZone),
+		deletionProtectionAttribute:     strconv.FormatBool(deletionProtection),
+	}
+
+	var out *elbv2.DescribeLoadBalancerAttributesOutput
+	err := awserrors.RetryOnThrottling(func() (err error) {
+		out, err = s.scope.ELBV2.DescribeLoadBalancerAttributes(&elbv2.DescribeLoadBalancerAttributesInput{
+			LoadBalancerArn: aws.String(lbARN),
+		})
+		return err
+	})
+	if err != nil {
+		return errors.Wrapf(err, "failed to describe attributes of load balancer %q", lbARN)
+	}
+
+	current := map[string]string{}
+	for _, attr := range out.Attributes {
+		current[aws.StringValue(attr.Key)] = aws.StringValue(attr.Value)
+	}
+
+	var changed []*elbv2.LoadBalancerAttribute
+	for _, key := range sets.StringKeySet(desired).List() {
+		if current[key] != desired[key] {
+			changed = append(changed, &elbv2.LoadBalancerAttribute{Key: aws.String(key), Value: aws.String(desired[key])})
+		}
+	}
+	if len(changed) == 0 {
+		return nil
+	}
+
+	if err := awserrors.RetryOnThrottling(func() error {
+		_, err := s.scope.ELBV2.ModifyLoadBalancerAttributes(&elbv2.ModifyLoadBalancerAttributesInput{
+			LoadBalancerArn: aws.String(lbARN),
+			Attributes:      changed,
+		})
+		return err
+	}); err != nil {
+		return errors.Wrapf(err, "failed to configure attributes of load balancer %q", lbARN)
+	}
+
+	s.scope.V(2).Info("Configured network load balancer attributes", "arn", lbARN, "attributes", changed)
+	return nil
+}
+
+func (s *Service) getAPIServerNetworkLoadBalancerSpec() (*infrav1.ClassicELB, error) {
+	name, err := GenerateELBName(s.scope.Name())
+	if err != nil {
+		return nil, err
+	}
+
+	res := &infrav1.ClassicELB{
+		Name:   name,
+		Scheme: s.scope.ControlPlaneLoadBalancerScheme(),
+		Tags:   infrav1.Build(s.getAPIServerClassicELBTagParams()),
+	}
+	if lb := s.scope.ControlPlaneLoadBalancer(); lb != nil {
+		res.CrossZoneLoadBalancing = lb.CrossZoneLoadBalancing
+	}
+
+	subnets := s.scope.Subnets().FilterPrivate()
+	if res.Scheme == infrav1.ClassicELBSchemeInternetFacing {
+		subnets = s.scope.Subnets().FilterPublic()
+	}
+
+	// The load balancer APIs require us to only attach one subnet for each AZ.
+	zones := sets.NewString()
+	for _, sn := range subnets {
+		if zones.Has(sn.AvailabilityZone) {
+			continue
+		}
+		zones.Insert(sn.AvailabilityZone)
+		res.AvailabilityZones = append(res.AvailabilityZones, sn.AvailabilityZone)
+		res.SubnetIDs = append(res.SubnetIDs, sn.ID)
+	}
+
+	return res, nil
+}
+
+func (s *Service) createNetworkLoadBalancer(spec *infrav1.ClassicELB) (*elbv2.LoadBalancer, error) {
+	input := &elbv2.CreateLoadBalancerInput{
+		Name:   aws.String(spec.Name),
+		Type:   aws.String(elbv2.LoadBalancerTypeEnumNetwork),
+		Scheme: aws.String(networkLoadBalancerScheme(spec.Scheme)),
+		Tags:   converters.MapToELBV2Tags(spec.Tags),
+	}
+
+	// The addresses of the load balancer can only be picked on creation.
+	if pool := s.scope.AWSCluster.Spec.NetworkSpec.PublicIPv4Pool; pool != "" && spec.Scheme == infrav1.ClassicELBSchemeInternetFacing {
+		mappings, err := s.getSubnetMappings(spec.SubnetIDs, pool)
+		if err != nil {
+			return nil, err
+		}
+		input.SubnetMappings = mappings
+	} else {
+		input.Subnets = aws.StringSlice(spec.SubnetIDs)
+	}
+
+	var out *elbv2.CreateLoadBalancerOutput
+	err := awserrors.RetryOnThrottling(func() (err error) {
+		out, err = s.scope.ELBV2.CreateLoadBalancer(input)
+		return err
+	})
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to create network load balancer %q", spec.Name)
+	}
+	if len(out.LoadBalancers) == 0 {
+		return nil, errors.Errorf("no network load balancer returned on creation of %q", spec.Name)
+	}
+
+	s.scope.V(2).Info("Created network load balancer", "dns-name", aws.StringValue(out.LoadBalancers[0].DNSName))
+	return out.LoadBalancers[0], nil
+}
+
+// getSubnetMappings gives each subnet of the network load balancer an Elastic
+// IP from the given public IPv4 pool. Unassociated Elastic IPs left behind by
+// a failed creation are used first. The Elastic IPs are released with the
+// other Elastic IPs of the cluster when its network is deleted.
+func (s *Service) getSubnetMappings(subnetIDs []string, pool string) ([]*elbv2.SubnetMapping, error) {
+	var out *ec2.DescribeAddressesOutput
+	err := awserrors.RetryOnThrottling(func() (err error) {
+		out, err = s.scope.EC2.DescribeAddresses(&ec2.DescribeAddressesInput{
+			Filters: []*ec2.Filter{
+				filter.EC2.Cluster(s.scope.Name()),
+				filter.EC2.ProviderRole(infrav1.LoadBalancerRoleTagValue),
+			},
+		})
+		return err
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to describe the elastic IPs of the apiserver load balancer")
+	}
+
+	free := []string{}
+	for _, address := range out.Addresses {
+		if address.AssociationId == nil {
+			free = append(free, aws.StringValue(address.AllocationId))
+		}
+	}
+
+	mappings := make([]*elbv2.SubnetMapping, 0, len(subnetIDs))
+	for _, subnetID := range subnetIDs {
+		var allocationID string
+		if len(free) > 0 {
+			allocationID, free = free[0], free[1:]
+		} else {
+			allocationID, err = s.allocateLoadBalancerAddress(pool)
+			if err != nil {
+				record.Warnf(s.scope.AWSCluster, "FailedAllocateEIP", "Failed to allocate Elastic IP for the apiserver load balancer from pool %q: %v", pool, err)
+				return nil, err
+			}
+		}
+		mappings = append(mappings, &elbv2.SubnetMapping{
+			SubnetId:     aws.String(subnetID),
+			AllocationId: aws.String(allocationID),
+		})
+	}
+
+	return mappings, nil
+}
+
+// allocateLoadBalancerAddress allocates an Elastic IP for the network load
+// balancer from the given public IPv4 pool.
+func (s *Service) allocateLoadBalancerAddress(pool string) (string, error) {
+	var out *ec2.AllocateAddressOutput
+	err := awserrors.RetryOnThrottling(func() (err error) {
+		out, err = s.scope.EC2.AllocateAddress(&ec2.AllocateAddressInput{
+			Domain:         aws.String("vpc"),
+			PublicIpv4Pool: aws.String(pool),
+		})
+		return err
+	})
+	if err != nil {
+		return "", errors.Wrap(err, "failed to create Elastic IP address")
+	}
+
+	id := aws.StringValue(out.AllocationId)
+	if err := wait.WaitForWithRetryable(wait.NewBackoff(), func() (bool, error) {
+		if err := tags.Apply(&tags.ApplyParams{
+			EC2Client: s.scope.EC2,
+			BuildParams: infrav1.BuildParams{
+				ClusterName: s.scope.Name(),
+				ResourceID:  id,
+				Lifecycle:   infrav1.ResourceLifecycleOwned,
+				Name:        aws.String(fmt.Sprintf("%s-eip-%s", s.scope.Name(), infrav1.LoadBalancerRoleTagValue)),
+				Role:        aws.String(infrav1.LoadBalancerRoleTagValue),
+				Additional:  s.scope.AdditionalTags(),
+			},
+		}); err != nil {
+			return false, err
+		}
+		return true, nil
+	}, awserrors.EIPNotFound); err != nil {
+		return "", errors.Wrapf(err, "failed to tag elastic IP %q", id)
+	}
+
+	return id, nil
+}
+
+func (s *Service) describeNetworkLoadBalancer(name string) (*elbv2.LoadBalancer, error) {
+	var out *elbv2.DescribeLoadBalancersOutput
+	err := awserrors.RetryOnThrottling(func() (err error) {
+		out, err = s.scope.ELBV2.DescribeLoadBalancers(&elbv2.DescribeLoadBalancersInput{
+			Names: aws.StringSlice([]string{name}),
+		})
+		return err
+	})
+	if err != nil {
+		if IsNotFound(err) {
+			return nil, NewNotFound(errors.Errorf("no network load balancer found with name %q", name))
+		}
+		return nil, errors.Wrapf(err, "failed to describe network load balancer %q", name)
+	}
+
+	if len(out.LoadBalancers) == 0 {
+		return nil, NewNotFound(errors.Errorf("no network load balancer found with name %q", name))
+	}
+
+	lb := out.LoadBalancers[0]
+	if aws.StringValue(lb.Type) != elbv2.LoadBalancerTypeEnumNetwork {
+		return nil, NewConflict(errors.Errorf("load balancer %q is not a network load balancer", name))
+	}
+	return lb, nil
+}
+
+// reconcileAPIServerTargetGroup returns the ARN of the API server target group,
+// creating it if needed. Control plane instances are registered by instance ID
+// and health checked over TCP on the API server port.
+func (s *Service) reconcileAPIServerTargetGroup(name string) (string, error) {
+	var out *elbv2.DescribeTargetGroupsOutput
+	err := awserrors.RetryOnThrottling(func() (err error) {
+		out, err = s.scope.ELBV2.DescribeTargetGroups(&elbv2.DescribeTargetGroupsInput{
+			Names: aws.StringSlice([]string{name}),
+		})
+		return err
+	})
+	if err != nil && !IsNotFound(err) {
+		return "", errors.Wrapf(err, "failed to describe apiserver target group %q", name)
+	}
+	if err == nil && len(out.TargetGroups) > 0 {
+		return aws.StringValue(out.TargetGroups[0].TargetGroupArn), nil
+	}
+
+	var created *elbv2.CreateTargetGroupOutput
+	err = awserrors.RetryOnThrottling(func() (err error) {
+		created, err = s.scope.ELBV2.CreateTargetGroup(&elbv2.CreateTargetGroupInput{
+			Name:                       aws.String(name),
+			Protocol:                   aws.String(elbv2.ProtocolEnumTcp),
+			Port:                       aws.Int64(6443),
+			VpcId:                      aws.String(s.scope.VPC().ID),
+			TargetType:                 aws.String(elbv2.TargetTypeEnumInstance),
+			HealthCheckProtocol:        aws.String(elbv2.ProtocolEnumTcp),
+			HealthCheckPort:            aws.String("6443"),
+			HealthCheckIntervalSeconds: aws.Int64(10),
+			HealthyThresholdCount:      aws.Int64(3),
+			UnhealthyThresholdCount:    aws.Int64(3),
+		})
+		return err
+	})
+	if err != nil {
+		return "", errors.Wrapf(err, "failed to create apiserver target group %q", name)
+	}
+	if len(created.TargetGroups) == 0 {
+		return "", errors.Errorf("no target group returned on creation of %q", name)
+	}
+
+	s.scope.V(2).Info("Created apiserver target group", "target-group-name", name)
+	return aws.StringValue(created.TargetGroups[0].TargetGroupArn), nil
+}
+
+func (s *Service) reconcileAPIServerListener(lbARN string, targetGroupARN string) error {
+	port := int64(s.scope.APIServerPort())
+
+	var out *elbv2.DescribeListenersOutput
+	err := awserrors.RetryOnThrottling(func() (err error) {
+		out, err = s.scope.ELBV2.DescribeListeners(&elbv2.DescribeListenersInput{
+			LoadBalancerArn: aws.String(lbARN),
+		})
+		return err
+	})
+	if err != nil {
+		return errors.Wrapf(err, "failed to describe listeners of load balancer %q", lbARN)
+	}
+	for _, listener := range out.Listeners {
+		if aws.Int64Value(listener.Port) == port {
+			return nil
+		}
+	}
+
+	if err := awserrors.RetryOnThrottling(func() error {
+		_, err := s.scope.ELBV2.CreateListener(&elbv2.CreateListenerInput{
+			LoadBalancerArn: aws.String(lbARN),
+			Protocol:        aws.String(elbv2.ProtocolEnumTcp),
+			Port:            aws.Int64(port),
+			DefaultActions: []*elbv2.Action{
+				{
+					Type:           aws.String(elbv2.ActionTypeEnumForward),
+					TargetGroupArn: aws.String(targetGroupARN),
+				},
+			},
+		})
+		return err
+	}); err != nil {
+		return errors.Wrapf(err, "failed to create listener on load balancer %q", lbARN)
+	}
+
+	return nil
+}
+
+func (s *Service) registerInstanceWithAPIServerTargetGroup(name string, instanceID string) error {
+	targetGroupARN, err := s.getAPIServerTargetGroupARN(name)
+	if err != nil {
+		return err
+	}
+
+	_, err = s.scope.ELBV2.RegisterTargets(&elbv2.RegisterTargetsInput{
+		TargetGroupArn: aws.String(targetGroupARN),
+		Targets:        []*elbv2.TargetDescription{{Id: aws.String(instanceID)}},
+	})
+	return err
+}
+
+func (s *Service) getAPIServerTargetGroupARN(name string) (string, error) {
+	var out *elbv2.DescribeTargetGroupsOutput
+	err := awserrors.RetryOnThrottling(func() (err error) {
+		out, err = s.scope.ELBV2.DescribeTargetGroups(&elbv2.DescribeTargetGroupsInput{
+			Names: aws.StringSlice([]string{name}),
+		})
+		return err
+	})
+	if err != nil {
+		return "", errors.Wrapf(err, "failed to describe apiserver target group %q", name)
+	}
+	if len(out.TargetGroups) == 0 {
+		return "", NewNotFound(errors.Errorf("no target group found with name %q", name))
+	}
+	return aws.StringValue(out.TargetGroups[0].TargetGroupArn), nil
+}
+
+// deleteOrphanedTargetGroups deletes the target groups of the cluster that no
+// load balancer uses anymore, except keepARN. They are left behind when the
+// load balancer is recreated, and count against the per-region limit.
+func (s *Service) deleteOrphanedTargetGroups(keepARN string) error {
+	orphaned, err := s.listOrphanedTargetGroups()
+	if err != nil {
+		return err
+	}
+
+	for _, arn := range orphaned {
+		if arn == keepARN {
+			continue
+		}
+
+		if _, err := s.scope.ELBV2.DeleteTargetGroup(&elbv2.DeleteTargetGroupInput{
+			TargetGroupArn: aws.String(arn),
+		}); err != nil && !IsNotFound(err) {
+			record.Warnf(s.scope.AWSCluster, "FailedDeleteTargetGroup", "Failed to delete orphaned target group %q: %v", arn, err)
+			return errors.Wrapf(err, "failed to delete orphaned target group %q", arn)
+		}
+
+		s.scope.V(2).Info("Deleted orphaned target group", "target-group-arn", arn)
+		record.Eventf(s.scope.AWSCluster, "SuccessfulDeleteTargetGroup", "Deleted orphaned target group %q", arn)
+	}
+
+	return nil
+}
+
+// listOrphanedTargetGroups returns the ARNs of the target groups in the cluster
+// VPC without a load balancer, that are owned by the cluster or named after
+// its API server load balancer.
+func (s *Service) listOrphanedTargetGroups() ([]string, error) {
+	name, err := GenerateELBName(s.scope.Name())
+	if err != nil {
+		return nil, err
+	}
+
+	var orphaned, unnamed []string
+	if err := s.scope.ELBV2.DescribeTargetGroupsPages(&elbv2.DescribeTargetGroupsInput{}, func(out *elbv2.DescribeTargetGroupsOutput, lastPage bool) bool {
+		for _, tg := range out.TargetGroups {
+			if aws.StringValue(tg.VpcId) != s.scope.VPC().ID || len(tg.LoadBalancerArns) > 0 {
+				continue
+			}
+			if aws.StringValue(tg.TargetGroupName) == name {
+				orphaned = append(orphaned, aws.StringValue(tg.TargetGroupArn))
+				continue
+			}
+			unnamed = append(unnamed, aws.StringValue(tg.TargetGroupArn))
+		}
+		return true
+	}); err != nil {
+		return nil, errors.Wrap(err, "failed to describe target groups")
+	}
+
+	// DescribeTags accepts up to 20 resources at once.
+	ownedTag := infrav1.ClusterTagKey(s.scope.Name())
+	for start := 0; start < len(unnamed); start += 20 {
+		end := start + 20
+		if end > len(unnamed) {
+			end = len(unnamed)
+		}
+
+		var out *elbv2.DescribeTagsOutput
+		err := awserrors.RetryOnThrottling(func() (err error) {
+			out, err = s.scope.ELBV2.DescribeTags(&elbv2.DescribeTagsInput{
+				ResourceArns: aws.StringSlice(unnamed[start:end]),
+			})
+			return err
+		})
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to describe target group tags")
+		}
+
+		for _, desc := range out.TagDescriptions {
+			if converters.ELBV2TagsToMap(desc.Tags)[ownedTag] == string(infrav1.ResourceLifecycleOwned) {
+				orphaned = append(orphaned, aws.StringValue(desc.ResourceArn))
+			}
+		}
+	}
+
+	return orphaned, nil
+}
+
+func (s *Service) deleteELBV2(arn string) error {
+	input := &elbv2.DeleteLoadBalancerInput{
+		LoadBalancerArn: aws.String(arn),
+	}
+
+	_, err := s.scope.ELBV2.DeleteLoadBalancer(input)
+
+	// Deletion protection only guards against deletions outside of the
+	// controller, turn it off to delete the load balancer with the cluster.
+	if code, _ := awserrors.Code(err); code == elbv2.ErrCodeOperationNotPermittedException {
+		if _, err := s.scope.ELBV2.ModifyLoadBalancerAttributes(&elbv2.ModifyLoadBalancerAttributesInput{
+			LoadBalancerArn: aws.String(arn),
+			Attributes: []*elbv2.LoadBalancerAttribute{
+				{Key: aws.String(deletionProtectionAttribute), Value: aws.String("false")},
+			},
+		}); err != nil {
+			return errors.Wrapf(err, "failed to turn off deletion protection of load balancer %q", arn)
+		}
+		_, err = s.scope.ELBV2.DeleteLoadBalancer(input)
+	}
+
+	return err
+}
+
+func (s *Service) reconcileELBV2Tags(arn string, desiredTags map[string]string) error {
+	var tags *elbv2.DescribeTagsOutput
+	err := awserrors.RetryOnThrottling(func() (err error) {
+		tags, err = s.scope.ELBV2.DescribeTags(&elbv2.DescribeTagsInput{
+			ResourceArns: []*string{aws.String(arn)},
+		})
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	if len(tags.TagDescriptions) == 0 {
+		return errors.Errorf("no tag information returned for resource %q", arn)
+	}
+
+	currentTags := converters.ELBV2TagsToMap(tags.TagDescriptions[0].Tags)
+
+	addTagsInput := &elbv2.AddTagsInput{
+		ResourceArns: []*string{aws.String(arn)},
+	}
+
+	removeTagsInput := &elbv2.RemoveTagsInput{
+		ResourceArns: []*string{aws.String(arn)},
+	}
+
+	for k, v := range desiredTags {
+		if val, ok := currentTags[k]; !ok || val != v {
+			s.scope.V(4).Info("adding tag to load balancer resource", "arn", arn, "key", k, "value", v)
+			addTagsInput.Tags = append(addTagsInput.Tags, &elbv2.Tag{Key: aws.String(k), Value: aws.String(v)})
+		}
+	}
+
+	// Only remove the tags that were applied from AdditionalTags, tags set by
+	// others are left untouched.
+	for k, v := range s.scope.RemovedAdditionalTags() {
+		if _, ok := desiredTags[k]; ok {
+			continue
+		}
+		if val, ok := currentTags[k]; ok && val == v {
+			s.scope.V(4).Info("removing tag from load balancer resource", "arn", arn, "key", k)
+			removeTagsInput.TagKeys = append(removeTagsInput.TagKeys, aws.String(k))
+		}
+	}
+
+	if len(addTagsInput.Tags) > 0 {
+		if _, err := s.scope.ELBV2.AddTags(addTagsInput); err != nil {
+			return err
+		}
+	}
+
+	if len(removeTagsInput.TagKeys) > 0 {
+		if _, err := s.scope.ELBV2.RemoveTags(removeTagsInput); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// isELBV2ARN returns true if the load balancer ARN is the ARN of a network or
+// application load balancer, as opposed to a classic ELB.
+func isELBV2ARN(arn string) bool {
+	return strings.Contains(arn, ":loadbalancer/net/") || strings.Contains(arn, ":loadbalancer/app/")
+}
+
+func networkLoadBalancerScheme(scheme infrav1.ClassicELBScheme) string {
+	if scheme == infrav1.ClassicELBSchemeInternal {
+		return elbv2.LoadBalancerSchemeEnumInternal
+	}
+	return elbv2.LoadBalancerSchemeEnumInternetFacing
+}
+
+func fromSDKTypeToNetworkLoadBalancer(v *elbv2.LoadBalancer) *infrav1.ClassicELB {
+	res := &infrav1.ClassicELB{
+		Name:                  aws.StringValue(v.LoadBalancerName),
+		Scheme:                infrav1.ClassicELBSchemeInternetFacing,
+		DNSName:               aws.StringValue(v.DNSName),
+		CanonicalHostedZoneID: aws.StringValue(v.CanonicalHostedZoneId),
+	}
+
+	if aws.StringValue(v.Scheme) == elbv2.LoadBalancerSchemeEnumInternal {
+		res.Scheme = infrav1.ClassicELBSchemeInternal
+	}
+
+	for _, az := range v.AvailabilityZones {
+		res.AvailabilityZones = append(res.AvailabilityZones, aws.StringValue(az.ZoneName))
+		res.SubnetIDs = append(res.SubnetIDs, aws.StringValue(az.SubnetId))
+	}
+
+	return res
+}
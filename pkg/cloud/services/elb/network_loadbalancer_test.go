@@ -0,0 +1,478 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package elb
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/aws/aws-sdk-go/service/elbv2"
+	"github.com/golang/mock/gomock"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	infrav1 "sigs.k8s.io/cluster-api-provider-aws/api/v1alpha3"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/scope"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/services/ec2/mock_ec2iface"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/services/elb/mock_elbv2iface"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1alpha3"
+)
+
+func TestReconcileNetworkLoadBalancerCreate(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	elbv2Mock := mock_elbv2iface.NewMockELBV2API(mockCtrl)
+
+	scope, err := scope.NewClusterScope(scope.ClusterScopeParams{
+		Cluster: &clusterv1.Cluster{
+			ObjectMeta: metav1.ObjectMeta{Name: "test-cluster"},
+		},
+		AWSClients: scope.AWSClients{
+			ELBV2: elbv2Mock,
+		},
+		AWSCluster: &infrav1.AWSCluster{
+			Spec: infrav1.AWSClusterSpec{
+				NetworkSpec: infrav1.NetworkSpec{
+					VPC: infrav1.VPCSpec{
+						ID: "vpc-nlb",
+					},
+					Subnets: infrav1.Subnets{
+						{ID: "subnet-public-1a", AvailabilityZone: "us-east-1a", IsPublic: true},
+						{ID: "subnet-public-1a-2", AvailabilityZone: "us-east-1a", IsPublic: true},
+						{ID: "subnet-private-1a", AvailabilityZone: "us-east-1a"},
+					},
+				},
+				ControlPlaneLoadBalancer: &infrav1.AWSLoadBalancerSpec{
+					LoadBalancerType: infrav1.LoadBalancerTypeNLB,
+				},
+			},
+		},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create test context: %v", err)
+	}
+
+	const (
+		lbARN = "arn:aws:elasticloadbalancing:us-east-1:123456789012:loadbalancer/net/test-cluster-apiserver/50dc6c495c0c9188"
+		tgARN = "arn:aws:elasticloadbalancing:us-east-1:123456789012:targetgroup/test-cluster-apiserver/73e2d6bc24d8a067"
+	)
+
+	elbv2Mock.EXPECT().DescribeLoadBalancers(gomock.Eq(&elbv2.DescribeLoadBalancersInput{
+		Names: aws.StringSlice([]string{"test-cluster-apiserver"}),
+	})).Return(nil, awserr.New(elbv2.ErrCodeLoadBalancerNotFoundException, "not found", nil))
+	elbv2Mock.EXPECT().CreateLoadBalancer(gomock.AssignableToTypeOf(&elbv2.CreateLoadBalancerInput{})).
+		DoAndReturn(func(input *elbv2.CreateLoadBalancerInput) (*elbv2.CreateLoadBalancerOutput, error) {
+			if got := aws.StringValue(input.Type); got != elbv2.LoadBalancerTypeEnumNetwork {
+				t.Fatalf("expected a network load balancer, got type %q", got)
+			}
+			if got := aws.StringValueSlice(input.Subnets); len(got) != 1 || got[0] != "subnet-public-1a" {
+				t.Fatalf("expected a single public subnet per availability zone, got %v", got)
+			}
+			return &elbv2.CreateLoadBalancerOutput{
+				LoadBalancers: []*elbv2.LoadBalancer{
+					{
+						LoadBalancerArn:  aws.String(lbARN),
+						LoadBalancerName: aws.String("test-cluster-apiserver"),
+						Type:             aws.String(elbv2.LoadBalancerTypeEnumNetwork),
+						Scheme:           aws.String(elbv2.LoadBalancerSchemeEnumInternetFacing),
+						DNSName:          aws.String("test-cluster-apiserver-1.elb.us-east-1.amazonaws.com"),
+						AvailabilityZones: []*elbv2.AvailabilityZone{
+							{ZoneName: aws.String("us-east-1a"), SubnetId: aws.String("subnet-public-1a")},
+						},
+					},
+				},
+			}, nil
+		})
+	elbv2Mock.EXPECT().DescribeTags(gomock.Eq(&elbv2.DescribeTagsInput{
+		ResourceArns: aws.StringSlice([]string{lbARN}),
+	})).Return(&elbv2.DescribeTagsOutput{
+		TagDescriptions: []*elbv2.TagDescription{{ResourceArn: aws.String(lbARN)}},
+	}, nil)
+	elbv2Mock.EXPECT().DescribeLoadBalancerAttributes(gomock.Eq(&elbv2.DescribeLoadBalancerAttributesInput{
+		LoadBalancerArn: aws.String(lbARN),
+	})).Return(&elbv2.DescribeLoadBalancerAttributesOutput{
+		Attributes: []*elbv2.LoadBalancerAttribute{
+			{Key: aws.String("deletion_protection.enabled"), Value: aws.String("false")},
+			{Key: aws.String("load_balancing.cross_zone.enabled"), Value: aws.String("false")},
+		},
+	}, nil)
+	elbv2Mock.EXPECT().DescribeTargetGroups(gomock.Eq(&elbv2.DescribeTargetGroupsInput{
+		Names: aws.StringSlice([]string{"test-cluster-apiserver"}),
+	})).Return(nil, awserr.New(elbv2.ErrCodeTargetGroupNotFoundException, "not found", nil))
+	elbv2Mock.EXPECT().CreateTargetGroup(gomock.AssignableToTypeOf(&elbv2.CreateTargetGroupInput{})).
+		Return(&elbv2.CreateTargetGroupOutput{
+			TargetGroups: []*elbv2.TargetGroup{{TargetGroupArn: aws.String(tgARN)}},
+		}, nil)
+	elbv2Mock.EXPECT().DescribeTags(gomock.Eq(&elbv2.DescribeTagsInput{
+		ResourceArns: aws.StringSlice([]string{tgARN}),
+	})).Return(&elbv2.DescribeTagsOutput{
+		TagDescriptions: []*elbv2.TagDescription{{ResourceArn: aws.String(tgARN)}},
+	}, nil)
+	elbv2Mock.EXPECT().AddTags(gomock.AssignableToTypeOf(&elbv2.AddTagsInput{})).
+		Return(&elbv2.AddTagsOutput{}, nil).Times(2)
+	elbv2Mock.EXPECT().DescribeListeners(gomock.Eq(&elbv2.DescribeListenersInput{
+		LoadBalancerArn: aws.String(lbARN),
+	})).Return(&elbv2.DescribeListenersOutput{}, nil)
+	elbv2Mock.EXPECT().CreateListener(gomock.Eq(&elbv2.CreateListenerInput{
+		LoadBalancerArn: aws.String(lbARN),
+		Protocol:        aws.String(elbv2.ProtocolEnumTcp),
+		Port:            aws.Int64(6443),
+		DefaultActions: []*elbv2.Action{
+			{
+				Type:           aws.String(elbv2.ActionTypeEnumForward),
+				TargetGroupArn: aws.String(tgARN),
+			},
+		},
+	})).Return(&elbv2.CreateListenerOutput{}, nil)
+	elbv2Mock.EXPECT().DescribeTargetGroupsPages(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(input *elbv2.DescribeTargetGroupsInput, fn func(*elbv2.DescribeTargetGroupsOutput, bool) bool) error {
+			fn(&elbv2.DescribeTargetGroupsOutput{
+				TargetGroups: []*elbv2.TargetGroup{
+					{
+						TargetGroupArn:   aws.String(tgARN),
+						TargetGroupName:  aws.String("test-cluster-apiserver"),
+						VpcId:            aws.String("vpc-nlb"),
+						LoadBalancerArns: aws.StringSlice([]string{lbARN}),
+					},
+				},
+			}, true)
+			return nil
+		})
+
+	s := NewService(scope)
+	if err := s.ReconcileLoadbalancers(); err != nil {
+		t.Fatalf("got an unexpected error: %v", err)
+	}
+
+	if got := scope.Network().APIServerELB.DNSName; got != "test-cluster-apiserver-1.elb.us-east-1.amazonaws.com" {
+		t.Fatalf("expected the network load balancer DNS name to be recorded in status, got %q", got)
+	}
+}
+
+func TestReconcileNetworkLoadBalancerAttributes(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	const lbARN = "arn:aws:elasticloadbalancing:us-east-1:123456789012:loadbalancer/net/test-cluster-apiserver/50dc6c495c0c9188"
+
+	testCases := []struct {
+		name    string
+		spec    *infrav1.AWSLoadBalancerSpec
+		current map[string]string
+		changed map[string]string
+	}{
+		{
+			name: "defaults are left alone",
+			spec: &infrav1.AWSLoadBalancerSpec{LoadBalancerType: infrav1.LoadBalancerTypeNLB},
+			current: map[string]string{
+				"deletion_protection.enabled":       "false",
+				"load_balancing.cross_zone.enabled": "false",
+			},
+		},
+		{
+			name: "enables cross-zone load balancing and deletion protection",
+			spec: &infrav1.AWSLoadBalancerSpec{
+				LoadBalancerType:       infrav1.LoadBalancerTypeNLB,
+				CrossZoneLoadBalancing: true,
+				DeletionProtection:     true,
+			},
+			current: map[string]string{
+				"deletion_protection.enabled":       "false",
+				"load_balancing.cross_zone.enabled": "false",
+			},
+			changed: map[string]string{
+				"deletion_protection.enabled":       "true",
+				"load_balancing.cross_zone.enabled": "true",
+			},
+		},
+		{
+			name: "reverts an attribute changed out of band",
+			spec: &infrav1.AWSLoadBalancerSpec{
+				LoadBalancerType:       infrav1.LoadBalancerTypeNLB,
+				CrossZoneLoadBalancing: true,
+			},
+			current: map[string]string{
+				"deletion_protection.enabled":       "true",
+				"load_balancing.cross_zone.enabled": "true",
+			},
+			changed: map[string]string{
+				"deletion_protection.enabled": "false",
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			elbv2Mock := mock_elbv2iface.NewMockELBV2API(mockCtrl)
+
+			scope, err := scope.NewClusterScope(scope.ClusterScopeParams{
+				Cluster: &clusterv1.Cluster{
+					ObjectMeta: metav1.ObjectMeta{Name: "test-cluster"},
+				},
+				AWSClients: scope.AWSClients{
+					ELBV2: elbv2Mock,
+				},
+				AWSCluster: &infrav1.AWSCluster{
+					Spec: infrav1.AWSClusterSpec{ControlPlaneLoadBalancer: tc.spec},
+				},
+			})
+			if err != nil {
+				t.Fatalf("Failed to create test context: %v", err)
+			}
+
+			var attrs []*elbv2.LoadBalancerAttribute
+			for k, v := range tc.current {
+				attrs = append(attrs, &elbv2.LoadBalancerAttribute{Key: aws.String(k), Value: aws.String(v)})
+			}
+			elbv2Mock.EXPECT().DescribeLoadBalancerAttributes(gomock.Eq(&elbv2.DescribeLoadBalancerAttributesInput{
+				LoadBalancerArn: aws.String(lbARN),
+			})).Return(&elbv2.DescribeLoadBalancerAttributesOutput{Attributes: attrs}, nil)
+			if len(tc.changed) > 0 {
+				elbv2Mock.EXPECT().ModifyLoadBalancerAttributes(gomock.AssignableToTypeOf(&elbv2.ModifyLoadBalancerAttributesInput{})).
+					DoAndReturn(func(input *elbv2.ModifyLoadBalancerAttributesInput) (*elbv2.ModifyLoadBalancerAttributesOutput, error) {
+						got := map[string]string{}
+						for _, attr := range input.Attributes {
+							got[aws.StringValue(attr.Key)] = aws.StringValue(attr.Value)
+						}
+						if !reflect.DeepEqual(got, tc.changed) {
+							t.Fatalf("expected attributes %v to be modified, got %v", tc.changed, got)
+						}
+						return &elbv2.ModifyLoadBalancerAttributesOutput{}, nil
+					})
+			}
+
+			s := NewService(scope)
+			if err := s.reconcileNetworkLoadBalancerAttributes(lbARN); err != nil {
+				t.Fatalf("got an unexpected error: %v", err)
+			}
+		})
+	}
+}
+
+func TestDeleteELBV2WithDeletionProtection(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	elbv2Mock := mock_elbv2iface.NewMockELBV2API(mockCtrl)
+
+	scope, err := scope.NewClusterScope(scope.ClusterScopeParams{
+		Cluster: &clusterv1.Cluster{
+			ObjectMeta: metav1.ObjectMeta{Name: "test-cluster"},
+		},
+		AWSClients: scope.AWSClients{
+			ELBV2: elbv2Mock,
+		},
+		AWSCluster: &infrav1.AWSCluster{},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create test context: %v", err)
+	}
+
+	const lbARN = "arn:aws:elasticloadbalancing:us-east-1:123456789012:loadbalancer/net/test-cluster-apiserver/50dc6c495c0c9188"
+
+	gomock.InOrder(
+		elbv2Mock.EXPECT().DeleteLoadBalancer(gomock.Eq(&elbv2.DeleteLoadBalancerInput{
+			LoadBalancerArn: aws.String(lbARN),
+		})).Return(nil, awserr.New(elbv2.ErrCodeOperationNotPermittedException, "deletion protection is enabled", nil)),
+		elbv2Mock.EXPECT().ModifyLoadBalancerAttributes(gomock.Eq(&elbv2.ModifyLoadBalancerAttributesInput{
+			LoadBalancerArn: aws.String(lbARN),
+			Attributes: []*elbv2.LoadBalancerAttribute{
+				{Key: aws.String("deletion_protection.enabled"), Value: aws.String("false")},
+			},
+		})).Return(&elbv2.ModifyLoadBalancerAttributesOutput{}, nil),
+		elbv2Mock.EXPECT().DeleteLoadBalancer(gomock.Eq(&elbv2.DeleteLoadBalancerInput{
+			LoadBalancerArn: aws.String(lbARN),
+		})).Return(&elbv2.DeleteLoadBalancerOutput{}, nil),
+	)
+
+	s := NewService(scope)
+	if err := s.deleteELBV2(lbARN); err != nil {
+		t.Fatalf("got an unexpected error: %v", err)
+	}
+}
+
+func TestGetSubnetMappings(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	ec2Mock := mock_ec2iface.NewMockEC2API(mockCtrl)
+
+	scope, err := scope.NewClusterScope(scope.ClusterScopeParams{
+		Cluster: &clusterv1.Cluster{
+			ObjectMeta: metav1.ObjectMeta{Name: "test-cluster"},
+		},
+		AWSClients: scope.AWSClients{
+			EC2: ec2Mock,
+		},
+		AWSCluster: &infrav1.AWSCluster{},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create test context: %v", err)
+	}
+
+	ec2Mock.EXPECT().DescribeAddresses(gomock.Eq(&ec2.DescribeAddressesInput{
+		Filters: []*ec2.Filter{
+			{Name: aws.String("tag-key"), Values: aws.StringSlice([]string{"sigs.k8s.io/cluster-api-provider-aws/cluster/test-cluster"})},
+			{Name: aws.String("tag:sigs.k8s.io/cluster-api-provider-aws/role"), Values: aws.StringSlice([]string{"lb"})},
+		},
+	})).Return(&ec2.DescribeAddressesOutput{
+		Addresses: []*ec2.Address{
+			{AllocationId: aws.String("eipalloc-associated"), AssociationId: aws.String("eipassoc-1")},
+			{AllocationId: aws.String("eipalloc-left-behind")},
+		},
+	}, nil)
+	ec2Mock.EXPECT().AllocateAddress(gomock.Eq(&ec2.AllocateAddressInput{
+		Domain:         aws.String("vpc"),
+		PublicIpv4Pool: aws.String("ipv4pool-ec2-0123456789abcdef0"),
+	})).Return(&ec2.AllocateAddressOutput{AllocationId: aws.String("eipalloc-new")}, nil)
+	ec2Mock.EXPECT().CreateTags(gomock.AssignableToTypeOf(&ec2.CreateTagsInput{})).
+		Return(&ec2.CreateTagsOutput{}, nil)
+
+	s := NewService(scope)
+	got, err := s.getSubnetMappings([]string{"subnet-public-1a", "subnet-public-1b"}, "ipv4pool-ec2-0123456789abcdef0")
+	if err != nil {
+		t.Fatalf("got an unexpected error: %v", err)
+	}
+
+	want := []*elbv2.SubnetMapping{
+		{SubnetId: aws.String("subnet-public-1a"), AllocationId: aws.String("eipalloc-left-behind")},
+		{SubnetId: aws.String("subnet-public-1b"), AllocationId: aws.String("eipalloc-new")},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("expected subnet mappings %v, got %v", want, got)
+	}
+}
+
+func TestDeleteOrphanedTargetGroups(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	elbv2Mock := mock_elbv2iface.NewMockELBV2API(mockCtrl)
+
+	scope, err := scope.NewClusterScope(scope.ClusterScopeParams{
+		Cluster: &clusterv1.Cluster{
+			ObjectMeta: metav1.ObjectMeta{Name: "test-cluster"},
+		},
+		AWSClients: scope.AWSClients{
+			ELBV2: elbv2Mock,
+		},
+		AWSCluster: &infrav1.AWSCluster{
+			Spec: infrav1.AWSClusterSpec{
+				NetworkSpec: infrav1.NetworkSpec{
+					VPC: infrav1.VPCSpec{
+						ID: "vpc-nlb",
+					},
+				},
+			},
+		},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create test context: %v", err)
+	}
+
+	const (
+		arnPrefix   = "arn:aws:elasticloadbalancing:us-east-1:123456789012:targetgroup/"
+		currentARN  = arnPrefix + "test-cluster-apiserver/73e2d6bc24d8a067"
+		leakedARN   = arnPrefix + "test-cluster-apiserver/0f1e2d3c4b5a6978"
+		ownedARN    = arnPrefix + "test-cluster-old/1a2b3c4d5e6f7a8b"
+		foreignARN  = arnPrefix + "other-cluster/9a8b7c6d5e4f3a2b"
+		inUseARN    = arnPrefix + "test-cluster-in-use/5e4f3a2b1c0d9e8f"
+		otherVPCARN = arnPrefix + "test-cluster-apiserver/2b3c4d5e6f7a8b9c"
+	)
+
+	elbv2Mock.EXPECT().DescribeTargetGroupsPages(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(input *elbv2.DescribeTargetGroupsInput, fn func(*elbv2.DescribeTargetGroupsOutput, bool) bool) error {
+			fn(&elbv2.DescribeTargetGroupsOutput{
+				TargetGroups: []*elbv2.TargetGroup{
+					{TargetGroupArn: aws.String(currentARN), TargetGroupName: aws.String("test-cluster-apiserver"), VpcId: aws.String("vpc-nlb")},
+					{TargetGroupArn: aws.String(leakedARN), TargetGroupName: aws.String("test-cluster-apiserver"), VpcId: aws.String("vpc-nlb")},
+					{TargetGroupArn: aws.String(ownedARN), TargetGroupName: aws.String("test-cluster-old"), VpcId: aws.String("vpc-nlb")},
+					{TargetGroupArn: aws.String(foreignARN), TargetGroupName: aws.String("other-cluster"), VpcId: aws.String("vpc-nlb")},
+				},
+			}, false)
+			fn(&elbv2.DescribeTargetGroupsOutput{
+				TargetGroups: []*elbv2.TargetGroup{
+					{
+						TargetGroupArn:   aws.String(inUseARN),
+						TargetGroupName:  aws.String("test-cluster-in-use"),
+						VpcId:            aws.String("vpc-nlb"),
+						LoadBalancerArns: aws.StringSlice([]string{"arn:aws:elasticloadbalancing:us-east-1:123456789012:loadbalancer/net/in-use/50dc6c495c0c9188"}),
+					},
+					{TargetGroupArn: aws.String(otherVPCARN), TargetGroupName: aws.String("test-cluster-apiserver"), VpcId: aws.String("vpc-other")},
+				},
+			}, true)
+			return nil
+		})
+	elbv2Mock.EXPECT().DescribeTags(gomock.Eq(&elbv2.DescribeTagsInput{
+		ResourceArns: aws.StringSlice([]string{ownedARN, foreignARN}),
+	})).Return(&elbv2.DescribeTagsOutput{
+		TagDescriptions: []*elbv2.TagDescription{
+			{
+				ResourceArn: aws.String(ownedARN),
+				Tags: []*elbv2.Tag{
+					{Key: aws.String(infrav1.ClusterTagKey("test-cluster")), Value: aws.String(string(infrav1.ResourceLifecycleOwned))},
+				},
+			},
+			{
+				ResourceArn: aws.String(foreignARN),
+				Tags: []*elbv2.Tag{
+					{Key: aws.String(infrav1.ClusterTagKey("other-cluster")), Value: aws.String(string(infrav1.ResourceLifecycleOwned))},
+				},
+			},
+		},
+	}, nil)
+	elbv2Mock.EXPECT().DeleteTargetGroup(gomock.Eq(&elbv2.DeleteTargetGroupInput{
+		TargetGroupArn: aws.String(leakedARN),
+	})).Return(&elbv2.DeleteTargetGroupOutput{}, nil)
+	elbv2Mock.EXPECT().DeleteTargetGroup(gomock.Eq(&elbv2.DeleteTargetGroupInput{
+		TargetGroupArn: aws.String(ownedARN),
+	})).Return(&elbv2.DeleteTargetGroupOutput{}, nil)
+
+	s := NewService(scope)
+	if err := s.deleteOrphanedTargetGroups(currentARN); err != nil {
+		t.Fatalf("got an unexpected error: %v", err)
+	}
+}
+
+func TestIsELBV2ARN(t *testing.T) {
+	tests := []struct {
+		arn      string
+		expected bool
+	}{
+		{
+			arn:      "arn:aws:elasticloadbalancing:us-east-1:123456789012:loadbalancer/net/test-cluster-apiserver/50dc6c495c0c9188",
+			expected: true,
+		},
+		{
+			arn:      "arn:aws:elasticloadbalancing:us-east-1:123456789012:loadbalancer/app/test-cluster-apiserver/50dc6c495c0c9188",
+			expected: true,
+		},
+		{
+			arn:      "arn:aws:elasticloadbalancing:us-east-1:123456789012:loadbalancer/test-cluster-apiserver",
+			expected: false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.arn, func(t *testing.T) {
+			if got := isELBV2ARN(tt.arn); got != tt.expected {
+				t.Errorf("isELBV2ARN() = %v, expected %v", got, tt.expected)
+			}
+		})
+	}
+}
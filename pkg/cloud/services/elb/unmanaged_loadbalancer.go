@@ -0,0 +1,155 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package elb
+
+import (
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/elb"
+	"github.com/aws/aws-sdk-go/service/elbv2"
+	"github.com/pkg/errors"
+	infrav1 "sigs.k8s.io/cluster-api-provider-aws/api/v1alpha3"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/awserrors"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/record"
+)
+
+// reconcileUnmanagedLoadBalancer records the existing load balancer the
+// cluster references as the API server load balancer. The cluster doesn't own
+// it, so it is only described.
+func (s *Service) reconcileUnmanagedLoadBalancer(arn string) error {
+	lb, err := s.describeUnmanagedLoadBalancer(arn)
+	if err != nil {
+		record.Warnf(s.scope.AWSCluster, "FailedDescribeLoadBalancer", "Failed to describe control plane load balancer %q: %v", arn, err)
+		return err
+	}
+
+	s.setAPIServerELB(lb)
+	return nil
+}
+
+// describeUnmanagedLoadBalancer describes a classic or network load balancer
+// by its ARN. It must be in the cluster VPC to reach the instances.
+func (s *Service) describeUnmanagedLoadBalancer(arn string) (*infrav1.ClassicELB, error) {
+	if !isELBV2ARN(arn) {
+		return s.describeClassicELB(classicELBNameFromARN(arn))
+	}
+
+	var out *elbv2.DescribeLoadBalancersOutput
+	err := awserrors.RetryOnThrottling(func() (err error) {
+		out, err = s.scope.ELBV2.DescribeLoadBalancers(&elbv2.DescribeLoadBalancersInput{
+			LoadBalancerArns: aws.StringSlice([]string{arn}),
+		})
+		return err
+	})
+	if err != nil {
+		if IsNotFound(err) {
+			return nil, NewNotFound(errors.Errorf("no load balancer found with ARN %q", arn))
+		}
+		return nil, errors.Wrapf(err, "failed to describe load balancer %q", arn)
+	}
+	if len(out.LoadBalancers) == 0 {
+		return nil, NewNotFound(errors.Errorf("no load balancer found with ARN %q", arn))
+	}
+
+	lb := out.LoadBalancers[0]
+	if vpcID := s.scope.VPC().ID; vpcID != "" && vpcID != aws.StringValue(lb.VpcId) {
+		return nil, errors.Errorf("load balancer %q is in VPC %q, not in the cluster VPC %q", arn, aws.StringValue(lb.VpcId), vpcID)
+	}
+	return fromSDKTypeToNetworkLoadBalancer(lb), nil
+}
+
+// registerInstanceWithUnmanagedLoadBalancer registers an instance with an
+// existing classic load balancer, or with the target group of the API server
+// listener of an existing network load balancer.
+func (s *Service) registerInstanceWithUnmanagedLoadBalancer(arn string, instanceID string) error {
+	if !isELBV2ARN(arn) {
+		return s.RegisterInstanceWithClassicELB(instanceID, classicELBNameFromARN(arn))
+	}
+
+	targetGroupARN, err := s.getAPIServerListenerTargetGroup(arn)
+	if err != nil {
+		return err
+	}
+
+	if _, err := s.scope.ELBV2.RegisterTargets(&elbv2.RegisterTargetsInput{
+		TargetGroupArn: aws.String(targetGroupARN),
+		Targets:        []*elbv2.TargetDescription{{Id: aws.String(instanceID)}},
+	}); err != nil {
+		return errors.Wrapf(err, "failed to register instance %q with target group %q", instanceID, targetGroupARN)
+	}
+	return nil
+}
+
+// deregisterInstanceFromUnmanagedLoadBalancer is the reverse of
+// registerInstanceWithUnmanagedLoadBalancer. Instances which are not
+// registered are ignored.
+func (s *Service) deregisterInstanceFromUnmanagedLoadBalancer(arn string, instanceID string) error {
+	if !isELBV2ARN(arn) {
+		name := classicELBNameFromARN(arn)
+		_, err := s.scope.ELB.DeregisterInstancesFromLoadBalancer(&elb.DeregisterInstancesFromLoadBalancerInput{
+			LoadBalancerName: aws.String(name),
+			Instances:        []*elb.Instance{{InstanceId: aws.String(instanceID)}},
+		})
+		if code, _ := awserrors.Code(err); err != nil && code != elb.ErrCodeInvalidEndPointException {
+			return errors.Wrapf(err, "failed to deregister instance %q from classic load balancer %q", instanceID, name)
+		}
+		return nil
+	}
+
+	targetGroupARN, err := s.getAPIServerListenerTargetGroup(arn)
+	if err != nil {
+		return err
+	}
+
+	_, err = s.scope.ELBV2.DeregisterTargets(&elbv2.DeregisterTargetsInput{
+		TargetGroupArn: aws.String(targetGroupARN),
+		Targets:        []*elbv2.TargetDescription{{Id: aws.String(instanceID)}},
+	})
+	if code, _ := awserrors.Code(err); err != nil && code != elbv2.ErrCodeInvalidTargetException {
+		return errors.Wrapf(err, "failed to deregister instance %q from target group %q", instanceID, targetGroupARN)
+	}
+	return nil
+}
+
+// getAPIServerListenerTargetGroup returns the ARN of the target group the
+// listener of a network load balancer on the API server port forwards to.
+func (s *Service) getAPIServerListenerTargetGroup(lbARN string) (string, error) {
+	port := int64(s.scope.APIServerPort())
+
+	var out *elbv2.DescribeListenersOutput
+	err := awserrors.RetryOnThrottling(func() (err error) {
+		out, err = s.scope.ELBV2.DescribeListeners(&elbv2.DescribeListenersInput{
+			LoadBalancerArn: aws.String(lbARN),
+		})
+		return err
+	})
+	if err != nil {
+		return "", errors.Wrapf(err, "failed to describe listeners of load balancer %q", lbARN)
+	}
+
+	for _, listener := range out.Listeners {
+		if aws.Int64Value(listener.Port) != port {
+			continue
+		}
+		for _, action := range listener.DefaultActions {
+			if aws.StringValue(action.Type) == elbv2.ActionTypeEnumForward && action.TargetGroupArn != nil {
+				return aws.StringValue(action.TargetGroupArn), nil
+			}
+		}
+	}
+
+	return "", NewNotFound(errors.Errorf("no listener of load balancer %q on port %d forwards to a target group", lbARN, port))
+}
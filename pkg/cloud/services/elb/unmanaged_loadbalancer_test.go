@@ -0,0 +1,126 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package elb
+
+import (
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/elbv2"
+	"github.com/golang/mock/gomock"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	infrav1 "sigs.k8s.io/cluster-api-provider-aws/api/v1alpha3"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/scope"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/services/elb/mock_elbv2iface"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1alpha3"
+)
+
+func TestUnmanagedNetworkLoadBalancer(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	const (
+		lbARN = "arn:aws:elasticloadbalancing:us-east-1:123456789012:loadbalancer/net/central-apiserver/50dc6c495c0c9188"
+		tgARN = "arn:aws:elasticloadbalancing:us-east-1:123456789012:targetgroup/central-apiserver/73e2d6bc24d8a067"
+	)
+
+	elbv2Mock := mock_elbv2iface.NewMockELBV2API(mockCtrl)
+
+	scope, err := scope.NewClusterScope(scope.ClusterScopeParams{
+		Cluster: &clusterv1.Cluster{
+			ObjectMeta: metav1.ObjectMeta{Name: "test-cluster"},
+		},
+		AWSClients: scope.AWSClients{
+			ELBV2: elbv2Mock,
+		},
+		AWSCluster: &infrav1.AWSCluster{
+			Spec: infrav1.AWSClusterSpec{
+				NetworkSpec: infrav1.NetworkSpec{
+					VPC: infrav1.VPCSpec{
+						ID: "vpc-nlb",
+					},
+				},
+				ControlPlaneLoadBalancer: &infrav1.AWSLoadBalancerSpec{
+					LoadBalancerType: infrav1.LoadBalancerTypeNLB,
+					ARN:              aws.String(lbARN),
+				},
+			},
+		},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create test context: %v", err)
+	}
+
+	// The load balancer is only described, never created nor tagged.
+	elbv2Mock.EXPECT().DescribeLoadBalancers(gomock.Eq(&elbv2.DescribeLoadBalancersInput{
+		LoadBalancerArns: aws.StringSlice([]string{lbARN}),
+	})).Return(&elbv2.DescribeLoadBalancersOutput{
+		LoadBalancers: []*elbv2.LoadBalancer{
+			{
+				LoadBalancerArn:  aws.String(lbARN),
+				LoadBalancerName: aws.String("central-apiserver"),
+				Type:             aws.String(elbv2.LoadBalancerTypeEnumNetwork),
+				Scheme:           aws.String(elbv2.LoadBalancerSchemeEnumInternal),
+				VpcId:            aws.String("vpc-nlb"),
+				DNSName:          aws.String("central-apiserver-1.elb.us-east-1.amazonaws.com"),
+			},
+		},
+	}, nil)
+	elbv2Mock.EXPECT().DescribeListeners(gomock.Eq(&elbv2.DescribeListenersInput{
+		LoadBalancerArn: aws.String(lbARN),
+	})).Return(&elbv2.DescribeListenersOutput{
+		Listeners: []*elbv2.Listener{
+			{
+				Port: aws.Int64(443),
+				DefaultActions: []*elbv2.Action{
+					{Type: aws.String(elbv2.ActionTypeEnumForward), TargetGroupArn: aws.String("arn:other")},
+				},
+			},
+			{
+				Port: aws.Int64(6443),
+				DefaultActions: []*elbv2.Action{
+					{Type: aws.String(elbv2.ActionTypeEnumForward), TargetGroupArn: aws.String(tgARN)},
+				},
+			},
+		},
+	}, nil).Times(2)
+	elbv2Mock.EXPECT().RegisterTargets(gomock.Eq(&elbv2.RegisterTargetsInput{
+		TargetGroupArn: aws.String(tgARN),
+		Targets:        []*elbv2.TargetDescription{{Id: aws.String("i-controlplane")}},
+	})).Return(&elbv2.RegisterTargetsOutput{}, nil)
+	elbv2Mock.EXPECT().DeregisterTargets(gomock.Eq(&elbv2.DeregisterTargetsInput{
+		TargetGroupArn: aws.String(tgARN),
+		Targets:        []*elbv2.TargetDescription{{Id: aws.String("i-controlplane")}},
+	})).Return(nil, awserr.New(elbv2.ErrCodeInvalidTargetException, "not registered", nil))
+
+	s := NewService(scope)
+	if err := s.ReconcileLoadbalancers(); err != nil {
+		t.Fatalf("got an unexpected error: %v", err)
+	}
+	if got := scope.Network().APIServerELB; got.DNSName != "central-apiserver-1.elb.us-east-1.amazonaws.com" || got.Scheme != infrav1.ClassicELBSchemeInternal {
+		t.Fatalf("expected the existing load balancer in status, got %+v", got)
+	}
+
+	instance := &infrav1.Instance{ID: "i-controlplane"}
+	if err := s.RegisterInstanceWithAPIServerELB(instance); err != nil {
+		t.Fatalf("got an unexpected error registering the instance: %v", err)
+	}
+	if err := s.DeregisterInstanceFromAPIServerELB(instance); err != nil {
+		t.Fatalf("expected an instance which is not registered to be ignored, got %v", err)
+	}
+}
@@ -0,0 +1,341 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package gc
+
+import (
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	elbsdk "github.com/aws/aws-sdk-go/service/elb"
+	"github.com/aws/aws-sdk-go/service/elbv2"
+	"github.com/pkg/errors"
+	infrav1 "sigs.k8s.io/cluster-api-provider-aws/api/v1alpha3"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/awserrors"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/converters"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/filter"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/services/elb"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/services/wait"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/record"
+)
+
+const (
+	// lbControllerClusterTagKey is set to the cluster name by the AWS Load
+	// Balancer Controller on the resources it creates.
+	lbControllerClusterTagKey = "elbv2.k8s.aws/cluster"
+
+	// maxDescribeTags is the number of resources the DescribeTags calls of
+	// the load balancing APIs accept at once.
+	maxDescribeTags = 20
+)
+
+// ReconcileDelete deletes the load balancers, target groups and security
+// groups the workload cluster created in the cluster VPC, when enabled with
+// ExternalResourceGC. It runs before the network is deleted, which these
+// resources would otherwise block with DependencyViolation errors.
+func (s *Service) ReconcileDelete() error {
+	if !s.scope.AWSCluster.Spec.ExternalResourceGC || s.scope.VPC().ID == "" {
+		return nil
+	}
+
+	s.scope.V(2).Info("Deleting resources created by the workload cluster")
+
+	if err := s.deleteLoadBalancers(); err != nil {
+		return err
+	}
+
+	// Target groups can only be deleted once no load balancer uses them.
+	if err := s.deleteTargetGroups(); err != nil {
+		return err
+	}
+
+	// Security groups can only be deleted once the network interfaces of the
+	// load balancers are gone.
+	if err := s.deleteSecurityGroups(); err != nil {
+		return err
+	}
+
+	s.scope.V(2).Info("Deleting resources created by the workload cluster completed successfully")
+	return nil
+}
+
+func (s *Service) deleteLoadBalancers() error {
+	classicELBs, err := s.listClassicELBs()
+	if err != nil {
+		return err
+	}
+
+	for _, name := range classicELBs {
+		if _, err := s.scope.ELB.DeleteLoadBalancer(&elbsdk.DeleteLoadBalancerInput{
+			LoadBalancerName: aws.String(name),
+		}); err != nil && !elb.IsNotFound(err) {
+			record.Warnf(s.scope.AWSCluster, "FailedDeleteLoadBalancer", "Failed to delete load balancer %q created by the workload cluster: %v", name, err)
+			return errors.Wrapf(err, "failed to delete load balancer %q", name)
+		}
+
+		s.scope.V(2).Info("Deleted load balancer created by the workload cluster", "name", name)
+		record.Eventf(s.scope.AWSCluster, "SuccessfulDeleteLoadBalancer", "Deleted load balancer %q created by the workload cluster", name)
+	}
+
+	elbv2s, err := s.listELBV2s()
+	if err != nil {
+		return err
+	}
+
+	for _, arn := range elbv2s {
+		if _, err := s.scope.ELBV2.DeleteLoadBalancer(&elbv2.DeleteLoadBalancerInput{
+			LoadBalancerArn: aws.String(arn),
+		}); err != nil && !elb.IsNotFound(err) {
+			record.Warnf(s.scope.AWSCluster, "FailedDeleteLoadBalancer", "Failed to delete load balancer %q created by the workload cluster: %v", arn, err)
+			return errors.Wrapf(err, "failed to delete load balancer %q", arn)
+		}
+
+		s.scope.V(2).Info("Deleted load balancer created by the workload cluster", "arn", arn)
+		record.Eventf(s.scope.AWSCluster, "SuccessfulDeleteLoadBalancer", "Deleted load balancer %q created by the workload cluster", arn)
+	}
+
+	return nil
+}
+
+func (s *Service) deleteTargetGroups() error {
+	targetGroups, err := s.listTargetGroups()
+	if err != nil {
+		return err
+	}
+
+	for _, arn := range targetGroups {
+		if err := wait.WaitForWithRetryable(wait.NewBackoff(), func() (bool, error) {
+			if _, err := s.scope.ELBV2.DeleteTargetGroup(&elbv2.DeleteTargetGroupInput{
+				TargetGroupArn: aws.String(arn),
+			}); err != nil && !elb.IsNotFound(err) {
+				return false, err
+			}
+			return true, nil
+		}, elbv2.ErrCodeResourceInUseException); err != nil {
+			record.Warnf(s.scope.AWSCluster, "FailedDeleteTargetGroup", "Failed to delete target group %q created by the workload cluster: %v", arn, err)
+			return errors.Wrapf(err, "failed to delete target group %q", arn)
+		}
+
+		s.scope.V(2).Info("Deleted target group created by the workload cluster", "target-group-arn", arn)
+		record.Eventf(s.scope.AWSCluster, "SuccessfulDeleteTargetGroup", "Deleted target group %q created by the workload cluster", arn)
+	}
+
+	return nil
+}
+
+func (s *Service) deleteSecurityGroups() error {
+	var groups []*ec2.SecurityGroup
+	if err := s.scope.EC2.DescribeSecurityGroupsPages(&ec2.DescribeSecurityGroupsInput{
+		Filters: []*ec2.Filter{filter.EC2.VPC(s.scope.VPC().ID)},
+	}, func(out *ec2.DescribeSecurityGroupsOutput, last bool) bool {
+		groups = append(groups, out.SecurityGroups...)
+		return true
+	}); err != nil {
+		return errors.Wrapf(err, "failed to describe security groups in vpc %q", s.scope.VPC().ID)
+	}
+
+	orphaned := map[string]bool{}
+	for _, sg := range groups {
+		if aws.StringValue(sg.GroupName) != "default" && s.isWorkloadOwned(converters.TagsToMap(sg.Tags)) {
+			orphaned[aws.StringValue(sg.GroupId)] = true
+		}
+	}
+	if len(orphaned) == 0 {
+		return nil
+	}
+
+	// A security group can't be deleted while the rules of another group
+	// reference it, e.g. the rules the cloud provider adds to the node
+	// security group for the traffic from the load balancers.
+	for _, sg := range groups {
+		var revoke []*ec2.IpPermission
+		for _, perm := range sg.IpPermissions {
+			var pairs []*ec2.UserIdGroupPair
+			for _, pair := range perm.UserIdGroupPairs {
+				if orphaned[aws.StringValue(pair.GroupId)] {
+					pairs = append(pairs, pair)
+				}
+			}
+			if len(pairs) > 0 {
+				revoke = append(revoke, &ec2.IpPermission{
+					IpProtocol:       perm.IpProtocol,
+					FromPort:         perm.FromPort,
+					ToPort:           perm.ToPort,
+					UserIdGroupPairs: pairs,
+				})
+			}
+		}
+		if len(revoke) == 0 {
+			continue
+		}
+
+		if _, err := s.scope.EC2.RevokeSecurityGroupIngress(&ec2.RevokeSecurityGroupIngressInput{
+			GroupId:       sg.GroupId,
+			IpPermissions: revoke,
+		}); awserrors.IsIgnorableSecurityGroupError(err) != nil {
+			record.Warnf(s.scope.AWSCluster, "FailedRevokeSecurityGroupIngressRules", "Failed to revoke the ingress rules from security groups created by the workload cluster in SecurityGroup %q: %v", aws.StringValue(sg.GroupId), err)
+			return errors.Wrapf(err, "failed to revoke ingress rules from security group %q", aws.StringValue(sg.GroupId))
+		}
+	}
+
+	for _, sg := range groups {
+		id := aws.StringValue(sg.GroupId)
+		if !orphaned[id] {
+			continue
+		}
+
+		// The network interfaces of deleted load balancers take a few
+		// minutes to be released.
+		if err := wait.WaitForWithRetryable(wait.NewBackoff(), func() (bool, error) {
+			if _, err := s.scope.EC2.DeleteSecurityGroup(&ec2.DeleteSecurityGroupInput{
+				GroupId: aws.String(id),
+			}); awserrors.IsIgnorableSecurityGroupError(err) != nil {
+				return false, err
+			}
+			return true, nil
+		}, awserrors.DependencyViolation); err != nil {
+			record.Warnf(s.scope.AWSCluster, "FailedDeleteSecurityGroup", "Failed to delete SecurityGroup %q created by the workload cluster: %v", id, err)
+			return errors.Wrapf(err, "failed to delete security group %q", id)
+		}
+
+		s.scope.V(2).Info("Deleted security group created by the workload cluster", "security-group-id", id)
+		record.Eventf(s.scope.AWSCluster, "SuccessfulDeleteSecurityGroup", "Deleted SecurityGroup %q created by the workload cluster", id)
+	}
+
+	return nil
+}
+
+// listClassicELBs returns the names of the classic load balancers in the
+// cluster VPC created by the workload cluster.
+func (s *Service) listClassicELBs() ([]string, error) {
+	var names []string
+	if err := s.scope.ELB.DescribeLoadBalancersPages(&elbsdk.DescribeLoadBalancersInput{}, func(out *elbsdk.DescribeLoadBalancersOutput, last bool) bool {
+		for _, lb := range out.LoadBalancerDescriptions {
+			if aws.StringValue(lb.VPCId) == s.scope.VPC().ID {
+				names = append(names, aws.StringValue(lb.LoadBalancerName))
+			}
+		}
+		return true
+	}); err != nil {
+		return nil, errors.Wrap(err, "failed to describe classic load balancers")
+	}
+
+	var owned []string
+	for start := 0; start < len(names); start += maxDescribeTags {
+		end := start + maxDescribeTags
+		if end > len(names) {
+			end = len(names)
+		}
+
+		var out *elbsdk.DescribeTagsOutput
+		err := awserrors.RetryOnThrottling(func() (err error) {
+			out, err = s.scope.ELB.DescribeTags(&elbsdk.DescribeTagsInput{
+				LoadBalancerNames: aws.StringSlice(names[start:end]),
+			})
+			return err
+		})
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to describe classic load balancer tags")
+		}
+
+		for _, desc := range out.TagDescriptions {
+			if s.isWorkloadOwned(converters.ELBTagsToMap(desc.Tags)) {
+				owned = append(owned, aws.StringValue(desc.LoadBalancerName))
+			}
+		}
+	}
+
+	return owned, nil
+}
+
+// listELBV2s returns the ARNs of the network and application load balancers
+// in the cluster VPC created by the workload cluster.
+func (s *Service) listELBV2s() ([]string, error) {
+	var arns []string
+	if err := s.scope.ELBV2.DescribeLoadBalancersPages(&elbv2.DescribeLoadBalancersInput{}, func(out *elbv2.DescribeLoadBalancersOutput, last bool) bool {
+		for _, lb := range out.LoadBalancers {
+			if aws.StringValue(lb.VpcId) == s.scope.VPC().ID {
+				arns = append(arns, aws.StringValue(lb.LoadBalancerArn))
+			}
+		}
+		return true
+	}); err != nil {
+		return nil, errors.Wrap(err, "failed to describe load balancers")
+	}
+
+	return s.filterOwnedELBV2Resources(arns)
+}
+
+// listTargetGroups returns the ARNs of the target groups in the cluster VPC
+// created by the workload cluster.
+func (s *Service) listTargetGroups() ([]string, error) {
+	var arns []string
+	if err := s.scope.ELBV2.DescribeTargetGroupsPages(&elbv2.DescribeTargetGroupsInput{}, func(out *elbv2.DescribeTargetGroupsOutput, last bool) bool {
+		for _, tg := range out.TargetGroups {
+			if aws.StringValue(tg.VpcId) == s.scope.VPC().ID {
+				arns = append(arns, aws.StringValue(tg.TargetGroupArn))
+			}
+		}
+		return true
+	}); err != nil {
+		return nil, errors.Wrap(err, "failed to describe target groups")
+	}
+
+	return s.filterOwnedELBV2Resources(arns)
+}
+
+// filterOwnedELBV2Resources returns the load balancer and target group ARNs
+// created by the workload cluster.
+func (s *Service) filterOwnedELBV2Resources(arns []string) ([]string, error) {
+	var owned []string
+	for start := 0; start < len(arns); start += maxDescribeTags {
+		end := start + maxDescribeTags
+		if end > len(arns) {
+			end = len(arns)
+		}
+
+		var out *elbv2.DescribeTagsOutput
+		err := awserrors.RetryOnThrottling(func() (err error) {
+			out, err = s.scope.ELBV2.DescribeTags(&elbv2.DescribeTagsInput{
+				ResourceArns: aws.StringSlice(arns[start:end]),
+			})
+			return err
+		})
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to describe load balancing resource tags")
+		}
+
+		for _, desc := range out.TagDescriptions {
+			if s.isWorkloadOwned(converters.ELBV2TagsToMap(desc.Tags)) {
+				owned = append(owned, aws.StringValue(desc.ResourceArn))
+			}
+		}
+	}
+
+	return owned, nil
+}
+
+// isWorkloadOwned returns whether the tags are those of a resource created by
+// the in-tree cloud provider or the AWS Load Balancer Controller. Resources
+// tagged for the cluster by the controllers are left to their own deletion,
+// e.g. the security group of the API server load balancer also carries the
+// cloud provider tag.
+func (s *Service) isWorkloadOwned(tags infrav1.Tags) bool {
+	if _, ok := tags[infrav1.ClusterTagKey(s.scope.Name())]; ok {
+		return false
+	}
+	return tags[infrav1.ClusterAWSCloudProviderTagKey(s.scope.Name())] == string(infrav1.ResourceLifecycleOwned) ||
+		tags[lbControllerClusterTagKey] == s.scope.Name()
+}
@@ -0,0 +1,227 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package gc
+
+import (
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	elbsdk "github.com/aws/aws-sdk-go/service/elb"
+	"github.com/aws/aws-sdk-go/service/elbv2"
+	"github.com/golang/mock/gomock"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+	infrav1 "sigs.k8s.io/cluster-api-provider-aws/api/v1alpha3"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/scope"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/services/ec2/mock_ec2iface"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/services/elb/mock_elbiface"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/services/elb/mock_elbv2iface"
+	capirecord "sigs.k8s.io/cluster-api-provider-aws/pkg/record"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1alpha3"
+)
+
+func TestReconcileDelete(t *testing.T) {
+	const (
+		lbARNPrefix = "arn:aws:elasticloadbalancing:us-east-1:123456789012:loadbalancer/net/"
+		tgARNPrefix = "arn:aws:elasticloadbalancing:us-east-1:123456789012:targetgroup/"
+		serviceNLB  = lbARNPrefix + "a1b2c3d4e5f6/50dc6c495c0c9188"
+		ingressALB  = lbARNPrefix + "k8s-default-ingress/73e2d6bc24d8a067"
+		apiNLB      = lbARNPrefix + "test-cluster-apiserver/0f1e2d3c4b5a6978"
+		serviceTG   = tgARNPrefix + "k8s-default-svc/1a2b3c4d5e6f7a8b"
+		apiTG       = tgARNPrefix + "test-cluster-apiserver/9a8b7c6d5e4f3a2b"
+	)
+
+	cloudProviderTag := &ec2.Tag{Key: aws.String(infrav1.ClusterAWSCloudProviderTagKey("test-cluster")), Value: aws.String("owned")}
+	clusterTag := &ec2.Tag{Key: aws.String(infrav1.ClusterTagKey("test-cluster")), Value: aws.String("owned")}
+
+	testCases := []struct {
+		name   string
+		gc     bool
+		expect func(m *mock_ec2iface.MockEC2APIMockRecorder, e *mock_elbiface.MockELBAPIMockRecorder, e2 *mock_elbv2iface.MockELBV2APIMockRecorder)
+	}{
+		{
+			name: "disabled",
+			expect: func(*mock_ec2iface.MockEC2APIMockRecorder, *mock_elbiface.MockELBAPIMockRecorder, *mock_elbv2iface.MockELBV2APIMockRecorder) {
+			},
+		},
+		{
+			name: "deletes the resources created by the workload cluster",
+			gc:   true,
+			expect: func(m *mock_ec2iface.MockEC2APIMockRecorder, e *mock_elbiface.MockELBAPIMockRecorder, e2 *mock_elbv2iface.MockELBV2APIMockRecorder) {
+				e.DescribeLoadBalancersPages(gomock.Any(), gomock.Any()).
+					DoAndReturn(func(input *elbsdk.DescribeLoadBalancersInput, fn func(*elbsdk.DescribeLoadBalancersOutput, bool) bool) error {
+						fn(&elbsdk.DescribeLoadBalancersOutput{
+							LoadBalancerDescriptions: []*elbsdk.LoadBalancerDescription{
+								{LoadBalancerName: aws.String("a0123456789abcdef"), VPCId: aws.String("vpc-gc")},
+								{LoadBalancerName: aws.String("test-cluster-apiserver"), VPCId: aws.String("vpc-gc")},
+								{LoadBalancerName: aws.String("other-vpc"), VPCId: aws.String("vpc-other")},
+							},
+						}, true)
+						return nil
+					})
+				e.DescribeTags(gomock.Eq(&elbsdk.DescribeTagsInput{
+					LoadBalancerNames: aws.StringSlice([]string{"a0123456789abcdef", "test-cluster-apiserver"}),
+				})).
+					Return(&elbsdk.DescribeTagsOutput{
+						TagDescriptions: []*elbsdk.TagDescription{
+							{
+								LoadBalancerName: aws.String("a0123456789abcdef"),
+								Tags:             []*elbsdk.Tag{{Key: cloudProviderTag.Key, Value: cloudProviderTag.Value}},
+							},
+							{
+								LoadBalancerName: aws.String("test-cluster-apiserver"),
+								Tags:             []*elbsdk.Tag{{Key: clusterTag.Key, Value: clusterTag.Value}},
+							},
+						},
+					}, nil)
+				e.DeleteLoadBalancer(gomock.Eq(&elbsdk.DeleteLoadBalancerInput{
+					LoadBalancerName: aws.String("a0123456789abcdef"),
+				})).
+					Return(&elbsdk.DeleteLoadBalancerOutput{}, nil)
+
+				e2.DescribeLoadBalancersPages(gomock.Any(), gomock.Any()).
+					DoAndReturn(func(input *elbv2.DescribeLoadBalancersInput, fn func(*elbv2.DescribeLoadBalancersOutput, bool) bool) error {
+						fn(&elbv2.DescribeLoadBalancersOutput{
+							LoadBalancers: []*elbv2.LoadBalancer{
+								{LoadBalancerArn: aws.String(serviceNLB), VpcId: aws.String("vpc-gc")},
+								{LoadBalancerArn: aws.String(ingressALB), VpcId: aws.String("vpc-gc")},
+								{LoadBalancerArn: aws.String(apiNLB), VpcId: aws.String("vpc-gc")},
+							},
+						}, true)
+						return nil
+					})
+				e2.DescribeTags(gomock.Eq(&elbv2.DescribeTagsInput{
+					ResourceArns: aws.StringSlice([]string{serviceNLB, ingressALB, apiNLB}),
+				})).
+					Return(&elbv2.DescribeTagsOutput{
+						TagDescriptions: []*elbv2.TagDescription{
+							{ResourceArn: aws.String(serviceNLB), Tags: []*elbv2.Tag{{Key: cloudProviderTag.Key, Value: cloudProviderTag.Value}}},
+							{ResourceArn: aws.String(ingressALB), Tags: []*elbv2.Tag{{Key: aws.String("elbv2.k8s.aws/cluster"), Value: aws.String("test-cluster")}}},
+							{ResourceArn: aws.String(apiNLB), Tags: []*elbv2.Tag{{Key: clusterTag.Key, Value: clusterTag.Value}}},
+						},
+					}, nil)
+				e2.DeleteLoadBalancer(gomock.Eq(&elbv2.DeleteLoadBalancerInput{LoadBalancerArn: aws.String(serviceNLB)})).
+					Return(&elbv2.DeleteLoadBalancerOutput{}, nil)
+				e2.DeleteLoadBalancer(gomock.Eq(&elbv2.DeleteLoadBalancerInput{LoadBalancerArn: aws.String(ingressALB)})).
+					Return(&elbv2.DeleteLoadBalancerOutput{}, nil)
+
+				e2.DescribeTargetGroupsPages(gomock.Any(), gomock.Any()).
+					DoAndReturn(func(input *elbv2.DescribeTargetGroupsInput, fn func(*elbv2.DescribeTargetGroupsOutput, bool) bool) error {
+						fn(&elbv2.DescribeTargetGroupsOutput{
+							TargetGroups: []*elbv2.TargetGroup{
+								{TargetGroupArn: aws.String(serviceTG), VpcId: aws.String("vpc-gc")},
+								{TargetGroupArn: aws.String(apiTG), VpcId: aws.String("vpc-gc")},
+							},
+						}, true)
+						return nil
+					})
+				e2.DescribeTags(gomock.Eq(&elbv2.DescribeTagsInput{
+					ResourceArns: aws.StringSlice([]string{serviceTG, apiTG}),
+				})).
+					Return(&elbv2.DescribeTagsOutput{
+						TagDescriptions: []*elbv2.TagDescription{
+							{ResourceArn: aws.String(serviceTG), Tags: []*elbv2.Tag{{Key: aws.String("elbv2.k8s.aws/cluster"), Value: aws.String("test-cluster")}}},
+							{ResourceArn: aws.String(apiTG), Tags: []*elbv2.Tag{{Key: clusterTag.Key, Value: clusterTag.Value}}},
+						},
+					}, nil)
+				e2.DeleteTargetGroup(gomock.Eq(&elbv2.DeleteTargetGroupInput{TargetGroupArn: aws.String(serviceTG)})).
+					Return(&elbv2.DeleteTargetGroupOutput{}, nil)
+
+				m.DescribeSecurityGroupsPages(gomock.Any(), gomock.Any()).
+					DoAndReturn(func(input *ec2.DescribeSecurityGroupsInput, fn func(*ec2.DescribeSecurityGroupsOutput, bool) bool) error {
+						fn(&ec2.DescribeSecurityGroupsOutput{
+							SecurityGroups: []*ec2.SecurityGroup{
+								{GroupId: aws.String("sg-default"), GroupName: aws.String("default")},
+								{GroupId: aws.String("sg-elb"), GroupName: aws.String("k8s-elb-a0123456789abcdef"), Tags: []*ec2.Tag{cloudProviderTag}},
+								{GroupId: aws.String("sg-lb"), GroupName: aws.String("test-cluster-lb"), Tags: []*ec2.Tag{clusterTag, cloudProviderTag}},
+								{
+									GroupId:   aws.String("sg-node"),
+									GroupName: aws.String("test-cluster-node"),
+									Tags:      []*ec2.Tag{clusterTag},
+									IpPermissions: []*ec2.IpPermission{
+										{
+											IpProtocol: aws.String("-1"),
+											UserIdGroupPairs: []*ec2.UserIdGroupPair{
+												{GroupId: aws.String("sg-elb")},
+												{GroupId: aws.String("sg-lb")},
+											},
+										},
+									},
+								},
+							},
+						}, true)
+						return nil
+					})
+				m.RevokeSecurityGroupIngress(gomock.Eq(&ec2.RevokeSecurityGroupIngressInput{
+					GroupId: aws.String("sg-node"),
+					IpPermissions: []*ec2.IpPermission{
+						{
+							IpProtocol:       aws.String("-1"),
+							UserIdGroupPairs: []*ec2.UserIdGroupPair{{GroupId: aws.String("sg-elb")}},
+						},
+					},
+				})).
+					Return(&ec2.RevokeSecurityGroupIngressOutput{}, nil)
+				m.DeleteSecurityGroup(gomock.Eq(&ec2.DeleteSecurityGroupInput{GroupId: aws.String("sg-elb")})).
+					Return(&ec2.DeleteSecurityGroupOutput{}, nil)
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			mockCtrl := gomock.NewController(t)
+			defer mockCtrl.Finish()
+
+			capirecord.InitFromRecorder(record.NewFakeRecorder(20))
+
+			ec2Mock := mock_ec2iface.NewMockEC2API(mockCtrl)
+			elbMock := mock_elbiface.NewMockELBAPI(mockCtrl)
+			elbv2Mock := mock_elbv2iface.NewMockELBV2API(mockCtrl)
+
+			scope, err := scope.NewClusterScope(scope.ClusterScopeParams{
+				Cluster: &clusterv1.Cluster{
+					ObjectMeta: metav1.ObjectMeta{Name: "test-cluster"},
+				},
+				AWSClients: scope.AWSClients{
+					EC2:   ec2Mock,
+					ELB:   elbMock,
+					ELBV2: elbv2Mock,
+				},
+				AWSCluster: &infrav1.AWSCluster{
+					Spec: infrav1.AWSClusterSpec{
+						NetworkSpec: infrav1.NetworkSpec{
+							VPC: infrav1.VPCSpec{ID: "vpc-gc"},
+						},
+						ExternalResourceGC: tc.gc,
+					},
+				},
+			})
+			if err != nil {
+				t.Fatalf("Failed to create test context: %v", err)
+			}
+
+			tc.expect(ec2Mock.EXPECT(), elbMock.EXPECT(), elbv2Mock.EXPECT())
+
+			s := NewService(scope)
+			if err := s.ReconcileDelete(); err != nil {
+				t.Fatalf("got an unexpected error: %v", err)
+			}
+		})
+	}
+}
@@ -19,6 +19,10 @@ package iam
 import (
 	"encoding/json"
 	"fmt"
+	"strings"
+	"unicode"
+
+	"github.com/pkg/errors"
 )
 
 const (
@@ -46,6 +50,10 @@ const (
 
 	// PrincipalService is the principal covering AWS services.
 	PrincipalService = "Service"
+
+	// MaxManagedPolicySize is the maximum number of non-whitespace characters
+	// of a managed policy document.
+	MaxManagedPolicySize = 6144
 )
 
 // PolicyDocument represents an AWS IAM policy document
@@ -93,6 +101,109 @@ func (p *PolicyDocument) JSON() (string, error) {
 	return string(b), nil
 }
 
+// Validate checks that the policy document fits in a managed policy and that
+// the conditions of its statements are well formed.
+func (p *PolicyDocument) Validate() error {
+	for i, statement := range p.Statement {
+		if statement.Effect != EffectAllow && statement.Effect != EffectDeny {
+			return errors.Errorf("statement %d has invalid effect %q", i, statement.Effect)
+		}
+		if len(statement.Action) == 0 {
+			return errors.Errorf("statement %d has no actions", i)
+		}
+		if err := statement.Condition.Validate(); err != nil {
+			return errors.Wrapf(err, "statement %d has invalid conditions", i)
+		}
+	}
+
+	b, err := json.Marshal(p)
+	if err != nil {
+		return errors.Wrap(err, "failed to marshal policy document")
+	}
+	size := len(strings.Map(func(r rune) rune {
+		if unicode.IsSpace(r) {
+			return -1
+		}
+		return r
+	}, string(b)))
+	if size > MaxManagedPolicySize {
+		return errors.Errorf("policy document is %d characters long, the maximum is %d", size, MaxManagedPolicySize)
+	}
+	return nil
+}
+
+// ParseConditions parses a JSON condition block, e.g.
+// {"StringEquals": {"aws:ResourceTag/owner": "team"}}.
+func ParseConditions(s string) (Conditions, error) {
+	conditions := Conditions{}
+	if err := json.Unmarshal([]byte(s), &conditions); err != nil {
+		return nil, errors.Wrap(err, "failed to parse conditions")
+	}
+	if err := conditions.Validate(); err != nil {
+		return nil, err
+	}
+	return conditions, nil
+}
+
+// Validate checks that each condition operator maps condition keys to a
+// value or a list of values.
+func (c Conditions) Validate() error {
+	for operator, block := range c {
+		var keys map[string]interface{}
+		switch v := block.(type) {
+		case map[string]interface{}:
+			keys = v
+		case map[string]string:
+			continue
+		default:
+			return errors.Errorf("condition operator %q must map condition keys to values", operator)
+		}
+		if len(keys) == 0 {
+			return errors.Errorf("condition operator %q has no condition keys", operator)
+		}
+		for key, value := range keys {
+			switch v := value.(type) {
+			case string, bool, float64:
+			case []interface{}:
+				for _, item := range v {
+					if _, ok := item.(string); !ok {
+						return errors.Errorf("condition key %q of operator %q must have string values", key, operator)
+					}
+				}
+			default:
+				return errors.Errorf("condition key %q of operator %q has an invalid value", key, operator)
+			}
+		}
+	}
+	return nil
+}
+
+// Merge returns the conditions of c and other. Condition keys of other take
+// precedence when both use the same operator and key.
+func (c Conditions) Merge(other Conditions) Conditions {
+	merged := Conditions{}
+	for _, conditions := range []Conditions{c, other} {
+		for operator, block := range conditions {
+			keys, ok := merged[operator].(map[string]interface{})
+			if !ok {
+				keys = map[string]interface{}{}
+				merged[operator] = keys
+			}
+			switch v := block.(type) {
+			case map[string]interface{}:
+				for key, value := range v {
+					keys[key] = value
+				}
+			case map[string]string:
+				for key, value := range v {
+					keys[key] = value
+				}
+			}
+		}
+	}
+	return merged
+}
+
 // NewManagedName creates an IAM acceptable name prefixed with this Cluster API
 // implementation's prefix.
 func NewManagedName(prefix string) string {
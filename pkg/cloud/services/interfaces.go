@@ -17,6 +17,8 @@ limitations under the License.
 package services
 
 import (
+	"time"
+
 	infrav1 "sigs.k8s.io/cluster-api-provider-aws/api/v1alpha3"
 	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/scope"
 )
@@ -30,10 +32,20 @@ type EC2MachineInterface interface {
 	GetRunningInstanceByTags(scope *scope.MachineScope) (*infrav1.Instance, error)
 
 	GetCoreSecurityGroups(machine *scope.MachineScope) ([]string, error)
+	GetAdditionalSecurityGroupsIDs(machine *scope.MachineScope) ([]string, error)
+	GetInstanceProfileAssociationState(instanceID string) (infrav1.InstanceProfileAssociationState, error)
 	GetInstanceSecurityGroups(instanceID string) (map[string][]string, error)
 	UpdateInstanceSecurityGroups(id string, securityGroups []string) error
 	UpdateResourceTags(resourceID *string, create map[string]string, remove map[string]string) error
+	GrowRootVolume(instanceID string, size int64) (time.Duration, error)
+	ModifyInstanceMetadataOptions(instanceID string, options *infrav1.InstanceMetadataOptions) error
+	GetInstanceTerminationProtection(instanceID string) (bool, error)
+	SetInstanceTerminationProtection(instanceID string, enabled bool) error
+	ReconcileElasticIP(scope *scope.MachineScope, instance *infrav1.Instance) error
+	ReleaseElasticIP(scope *scope.MachineScope) error
 
 	TerminateInstanceAndWait(instanceID string) error
+	CancelSpotInstanceRequests(instanceID string) error
+	DeleteBootstrapData(scope *scope.MachineScope) error
 	DetachSecurityGroupsFromNetworkInterface(groups []string, interfaceID string) error
 }
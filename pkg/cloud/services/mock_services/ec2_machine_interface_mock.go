@@ -25,6 +25,7 @@ import (
 	reflect "reflect"
 	v1alpha3 "sigs.k8s.io/cluster-api-provider-aws/api/v1alpha3"
 	scope "sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/scope"
+	time "time"
 )
 
 // MockEC2MachineInterface is a mock of EC2MachineInterface interface
@@ -50,6 +51,20 @@ func (m *MockEC2MachineInterface) EXPECT() *MockEC2MachineInterfaceMockRecorder
 	return m.recorder
 }
 
+// CancelSpotInstanceRequests mocks base method
+func (m *MockEC2MachineInterface) CancelSpotInstanceRequests(arg0 string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CancelSpotInstanceRequests", arg0)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CancelSpotInstanceRequests indicates an expected call of CancelSpotInstanceRequests
+func (mr *MockEC2MachineInterfaceMockRecorder) CancelSpotInstanceRequests(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CancelSpotInstanceRequests", reflect.TypeOf((*MockEC2MachineInterface)(nil).CancelSpotInstanceRequests), arg0)
+}
+
 // CreateInstance mocks base method
 func (m *MockEC2MachineInterface) CreateInstance(arg0 *scope.MachineScope) (*v1alpha3.Instance, error) {
 	m.ctrl.T.Helper()
@@ -65,6 +80,20 @@ func (mr *MockEC2MachineInterfaceMockRecorder) CreateInstance(arg0 interface{})
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateInstance", reflect.TypeOf((*MockEC2MachineInterface)(nil).CreateInstance), arg0)
 }
 
+// DeleteBootstrapData mocks base method
+func (m *MockEC2MachineInterface) DeleteBootstrapData(arg0 *scope.MachineScope) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteBootstrapData", arg0)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteBootstrapData indicates an expected call of DeleteBootstrapData
+func (mr *MockEC2MachineInterfaceMockRecorder) DeleteBootstrapData(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteBootstrapData", reflect.TypeOf((*MockEC2MachineInterface)(nil).DeleteBootstrapData), arg0)
+}
+
 // DetachSecurityGroupsFromNetworkInterface mocks base method
 func (m *MockEC2MachineInterface) DetachSecurityGroupsFromNetworkInterface(arg0 []string, arg1 string) error {
 	m.ctrl.T.Helper()
@@ -79,6 +108,21 @@ func (mr *MockEC2MachineInterfaceMockRecorder) DetachSecurityGroupsFromNetworkIn
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DetachSecurityGroupsFromNetworkInterface", reflect.TypeOf((*MockEC2MachineInterface)(nil).DetachSecurityGroupsFromNetworkInterface), arg0, arg1)
 }
 
+// GetAdditionalSecurityGroupsIDs mocks base method
+func (m *MockEC2MachineInterface) GetAdditionalSecurityGroupsIDs(arg0 *scope.MachineScope) ([]string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetAdditionalSecurityGroupsIDs", arg0)
+	ret0, _ := ret[0].([]string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetAdditionalSecurityGroupsIDs indicates an expected call of GetAdditionalSecurityGroupsIDs
+func (mr *MockEC2MachineInterfaceMockRecorder) GetAdditionalSecurityGroupsIDs(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetAdditionalSecurityGroupsIDs", reflect.TypeOf((*MockEC2MachineInterface)(nil).GetAdditionalSecurityGroupsIDs), arg0)
+}
+
 // GetCoreSecurityGroups mocks base method
 func (m *MockEC2MachineInterface) GetCoreSecurityGroups(arg0 *scope.MachineScope) ([]string, error) {
 	m.ctrl.T.Helper()
@@ -94,6 +138,21 @@ func (mr *MockEC2MachineInterfaceMockRecorder) GetCoreSecurityGroups(arg0 interf
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetCoreSecurityGroups", reflect.TypeOf((*MockEC2MachineInterface)(nil).GetCoreSecurityGroups), arg0)
 }
 
+// GetInstanceProfileAssociationState mocks base method
+func (m *MockEC2MachineInterface) GetInstanceProfileAssociationState(arg0 string) (v1alpha3.InstanceProfileAssociationState, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetInstanceProfileAssociationState", arg0)
+	ret0, _ := ret[0].(v1alpha3.InstanceProfileAssociationState)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetInstanceProfileAssociationState indicates an expected call of GetInstanceProfileAssociationState
+func (mr *MockEC2MachineInterfaceMockRecorder) GetInstanceProfileAssociationState(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetInstanceProfileAssociationState", reflect.TypeOf((*MockEC2MachineInterface)(nil).GetInstanceProfileAssociationState), arg0)
+}
+
 // GetInstanceSecurityGroups mocks base method
 func (m *MockEC2MachineInterface) GetInstanceSecurityGroups(arg0 string) (map[string][]string, error) {
 	m.ctrl.T.Helper()
@@ -109,6 +168,21 @@ func (mr *MockEC2MachineInterfaceMockRecorder) GetInstanceSecurityGroups(arg0 in
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetInstanceSecurityGroups", reflect.TypeOf((*MockEC2MachineInterface)(nil).GetInstanceSecurityGroups), arg0)
 }
 
+// GetInstanceTerminationProtection mocks base method
+func (m *MockEC2MachineInterface) GetInstanceTerminationProtection(arg0 string) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetInstanceTerminationProtection", arg0)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetInstanceTerminationProtection indicates an expected call of GetInstanceTerminationProtection
+func (mr *MockEC2MachineInterfaceMockRecorder) GetInstanceTerminationProtection(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetInstanceTerminationProtection", reflect.TypeOf((*MockEC2MachineInterface)(nil).GetInstanceTerminationProtection), arg0)
+}
+
 // GetRunningInstanceByTags mocks base method
 func (m *MockEC2MachineInterface) GetRunningInstanceByTags(arg0 *scope.MachineScope) (*v1alpha3.Instance, error) {
 	m.ctrl.T.Helper()
@@ -124,6 +198,21 @@ func (mr *MockEC2MachineInterfaceMockRecorder) GetRunningInstanceByTags(arg0 int
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetRunningInstanceByTags", reflect.TypeOf((*MockEC2MachineInterface)(nil).GetRunningInstanceByTags), arg0)
 }
 
+// GrowRootVolume mocks base method
+func (m *MockEC2MachineInterface) GrowRootVolume(arg0 string, arg1 int64) (time.Duration, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GrowRootVolume", arg0, arg1)
+	ret0, _ := ret[0].(time.Duration)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GrowRootVolume indicates an expected call of GrowRootVolume
+func (mr *MockEC2MachineInterfaceMockRecorder) GrowRootVolume(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GrowRootVolume", reflect.TypeOf((*MockEC2MachineInterface)(nil).GrowRootVolume), arg0, arg1)
+}
+
 // InstanceIfExists mocks base method
 func (m *MockEC2MachineInterface) InstanceIfExists(arg0 *string) (*v1alpha3.Instance, error) {
 	m.ctrl.T.Helper()
@@ -139,6 +228,62 @@ func (mr *MockEC2MachineInterfaceMockRecorder) InstanceIfExists(arg0 interface{}
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "InstanceIfExists", reflect.TypeOf((*MockEC2MachineInterface)(nil).InstanceIfExists), arg0)
 }
 
+// ModifyInstanceMetadataOptions mocks base method
+func (m *MockEC2MachineInterface) ModifyInstanceMetadataOptions(arg0 string, arg1 *v1alpha3.InstanceMetadataOptions) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ModifyInstanceMetadataOptions", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ModifyInstanceMetadataOptions indicates an expected call of ModifyInstanceMetadataOptions
+func (mr *MockEC2MachineInterfaceMockRecorder) ModifyInstanceMetadataOptions(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ModifyInstanceMetadataOptions", reflect.TypeOf((*MockEC2MachineInterface)(nil).ModifyInstanceMetadataOptions), arg0, arg1)
+}
+
+// ReconcileElasticIP mocks base method
+func (m *MockEC2MachineInterface) ReconcileElasticIP(arg0 *scope.MachineScope, arg1 *v1alpha3.Instance) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ReconcileElasticIP", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ReconcileElasticIP indicates an expected call of ReconcileElasticIP
+func (mr *MockEC2MachineInterfaceMockRecorder) ReconcileElasticIP(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReconcileElasticIP", reflect.TypeOf((*MockEC2MachineInterface)(nil).ReconcileElasticIP), arg0, arg1)
+}
+
+// ReleaseElasticIP mocks base method
+func (m *MockEC2MachineInterface) ReleaseElasticIP(arg0 *scope.MachineScope) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ReleaseElasticIP", arg0)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ReleaseElasticIP indicates an expected call of ReleaseElasticIP
+func (mr *MockEC2MachineInterfaceMockRecorder) ReleaseElasticIP(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReleaseElasticIP", reflect.TypeOf((*MockEC2MachineInterface)(nil).ReleaseElasticIP), arg0)
+}
+
+// SetInstanceTerminationProtection mocks base method
+func (m *MockEC2MachineInterface) SetInstanceTerminationProtection(arg0 string, arg1 bool) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetInstanceTerminationProtection", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SetInstanceTerminationProtection indicates an expected call of SetInstanceTerminationProtection
+func (mr *MockEC2MachineInterfaceMockRecorder) SetInstanceTerminationProtection(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetInstanceTerminationProtection", reflect.TypeOf((*MockEC2MachineInterface)(nil).SetInstanceTerminationProtection), arg0, arg1)
+}
+
 // TerminateInstance mocks base method
 func (m *MockEC2MachineInterface) TerminateInstance(arg0 string) error {
 	m.ctrl.T.Helper()
@@ -0,0 +1,124 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package secretsmanager
+
+import (
+	"fmt"
+	"path"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/secretsmanager"
+	"github.com/pkg/errors"
+	infrav1 "sigs.k8s.io/cluster-api-provider-aws/api/v1alpha3"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/awserrors"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/converters"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/scope"
+)
+
+// maxSecretSize is the size limit of the value of a secret.
+const maxSecretSize = 64 * 1024
+
+// SecretPrefix returns the prefix of the names of the secrets holding the
+// bootstrap data of the machine. The role in the prefix lets the instance
+// profiles only read the secrets of their kind of machines.
+func SecretPrefix(m *scope.MachineScope) string {
+	return path.Join(infrav1.BootstrapDataSecretPrefix, m.Role(), m.Namespace(), m.Name())
+}
+
+// FetchCommand returns the shell command printing the secret whose index is
+// its first argument.
+func FetchCommand(region, prefix string) string {
+	return fmt.Sprintf(`aws secretsmanager get-secret-value --region "%s" --secret-id "%s/$1" --query SecretString --output text`, region, prefix)
+}
+
+// Create stores the bootstrap data of the machine in as many secrets as
+// needed, named after their index, and returns their number. Existing
+// secrets, from a previous attempt to launch the instance, are overwritten.
+func (s *Service) Create(m *scope.MachineScope, data string) (int, error) {
+	prefix := SecretPrefix(m)
+	chunks := splitString(data, maxSecretSize)
+	tags := infrav1.Build(infrav1.BuildParams{
+		ClusterName: s.scope.Name(),
+		Lifecycle:   infrav1.ResourceLifecycleOwned,
+		Name:        aws.String(m.Name()),
+		Role:        aws.String(m.Role()),
+		Additional:  m.AdditionalTags(),
+	})
+
+	for i, chunk := range chunks {
+		name := fmt.Sprintf("%s/%d", prefix, i)
+		err := awserrors.RetryOnThrottling(func() error {
+			_, err := s.scope.SecretsManager.CreateSecret(&secretsmanager.CreateSecretInput{
+				Name:         aws.String(name),
+				Description:  aws.String(fmt.Sprintf("Bootstrap data of machine %s/%s, part %d of %d", m.Namespace(), m.Name(), i+1, len(chunks))),
+				SecretString: aws.String(chunk),
+				Tags:         converters.MapToSecretsManagerTags(tags),
+			})
+			return err
+		})
+		if code, _ := awserrors.Code(err); code == secretsmanager.ErrCodeResourceExistsException {
+			err = awserrors.RetryOnThrottling(func() error {
+				_, err := s.scope.SecretsManager.PutSecretValue(&secretsmanager.PutSecretValueInput{
+					SecretId:     aws.String(name),
+					SecretString: aws.String(chunk),
+				})
+				return err
+			})
+		}
+		if err != nil {
+			return 0, errors.Wrapf(err, "failed to store bootstrap data in secret %q", name)
+		}
+	}
+
+	s.scope.V(2).Info("Stored bootstrap data in Secrets Manager", "prefix", prefix, "secrets", len(chunks))
+	return len(chunks), nil
+}
+
+// Delete deletes the secrets holding the bootstrap data of the machine, up to
+// the first one which is not found. Their indexes are contiguous, even when
+// the secrets of a previous attempt to launch the instance are left.
+func (s *Service) Delete(m *scope.MachineScope) error {
+	prefix := SecretPrefix(m)
+
+	for i := 0; ; i++ {
+		name := fmt.Sprintf("%s/%d", prefix, i)
+		err := awserrors.RetryOnThrottling(func() error {
+			_, err := s.scope.SecretsManager.DeleteSecret(&secretsmanager.DeleteSecretInput{
+				SecretId:                   aws.String(name),
+				ForceDeleteWithoutRecovery: aws.Bool(true),
+			})
+			return err
+		})
+		if code, _ := awserrors.Code(err); code == secretsmanager.ErrCodeResourceNotFoundException {
+			return nil
+		}
+		if err != nil {
+			return errors.Wrapf(err, "failed to delete secret %q", name)
+		}
+		s.scope.V(2).Info("Deleted bootstrap data from Secrets Manager", "secret", name)
+	}
+}
+
+// splitString splits s in chunks of at most size bytes.
+func splitString(s string, size int) []string {
+	chunks := make([]string, 0, len(s)/size+1)
+	for len(s) > size {
+		chunks = append(chunks, s[:size])
+		s = s[size:]
+	}
+	return append(chunks, s)
+}
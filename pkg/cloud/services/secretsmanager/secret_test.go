@@ -0,0 +1,146 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package secretsmanager
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/secretsmanager"
+	"github.com/aws/aws-sdk-go/service/secretsmanager/secretsmanageriface"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	infrav1 "sigs.k8s.io/cluster-api-provider-aws/api/v1alpha3"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/scope"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1alpha3"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+// fakeSecretsManager keeps the secrets in memory.
+type fakeSecretsManager struct {
+	secretsmanageriface.SecretsManagerAPI
+	secrets map[string]string
+	tags    map[string][]*secretsmanager.Tag
+}
+
+func (f *fakeSecretsManager) CreateSecret(input *secretsmanager.CreateSecretInput) (*secretsmanager.CreateSecretOutput, error) {
+	name := aws.StringValue(input.Name)
+	if _, ok := f.secrets[name]; ok {
+		return nil, awserr.New(secretsmanager.ErrCodeResourceExistsException, "exists", nil)
+	}
+	if len(aws.StringValue(input.SecretString)) > maxSecretSize {
+		return nil, fmt.Errorf("secret %q is too large", name)
+	}
+	f.secrets[name] = aws.StringValue(input.SecretString)
+	f.tags[name] = input.Tags
+	return &secretsmanager.CreateSecretOutput{Name: input.Name}, nil
+}
+
+func (f *fakeSecretsManager) PutSecretValue(input *secretsmanager.PutSecretValueInput) (*secretsmanager.PutSecretValueOutput, error) {
+	name := aws.StringValue(input.SecretId)
+	if _, ok := f.secrets[name]; !ok {
+		return nil, awserr.New(secretsmanager.ErrCodeResourceNotFoundException, "not found", nil)
+	}
+	f.secrets[name] = aws.StringValue(input.SecretString)
+	return &secretsmanager.PutSecretValueOutput{Name: input.SecretId}, nil
+}
+
+func (f *fakeSecretsManager) DeleteSecret(input *secretsmanager.DeleteSecretInput) (*secretsmanager.DeleteSecretOutput, error) {
+	name := aws.StringValue(input.SecretId)
+	if _, ok := f.secrets[name]; !ok {
+		return nil, awserr.New(secretsmanager.ErrCodeResourceNotFoundException, "not found", nil)
+	}
+	if !aws.BoolValue(input.ForceDeleteWithoutRecovery) {
+		return nil, fmt.Errorf("secret %q would be recoverable", name)
+	}
+	delete(f.secrets, name)
+	return &secretsmanager.DeleteSecretOutput{Name: input.SecretId}, nil
+}
+
+func TestBootstrapDataSecrets(t *testing.T) {
+	sm := &fakeSecretsManager{
+		secrets: map[string]string{
+			"aws.cluster.x-k8s.io/control-plane/default/worker/0": "other",
+		},
+		tags: map[string][]*secretsmanager.Tag{},
+	}
+
+	cluster := &clusterv1.Cluster{ObjectMeta: metav1.ObjectMeta{Name: "test-cluster"}}
+	awsCluster := &infrav1.AWSCluster{}
+	clusterScope, err := scope.NewClusterScope(scope.ClusterScopeParams{
+		Cluster:    cluster,
+		AWSCluster: awsCluster,
+		AWSClients: scope.AWSClients{SecretsManager: sm},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create test context: %v", err)
+	}
+	machineScope, err := scope.NewMachineScope(scope.MachineScopeParams{
+		Client:     fake.NewFakeClient(),
+		Cluster:    cluster,
+		Machine:    &clusterv1.Machine{},
+		AWSCluster: awsCluster,
+		AWSMachine: &infrav1.AWSMachine{
+			ObjectMeta: metav1.ObjectMeta{Name: "worker", Namespace: "default"},
+		},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create test context: %v", err)
+	}
+
+	s := NewService(clusterScope)
+
+	// A first attempt to launch the instance left larger bootstrap data.
+	if _, err := s.Create(machineScope, strings.Repeat("a", 3*maxSecretSize)); err != nil {
+		t.Fatalf("got an unexpected error: %v", err)
+	}
+
+	data := strings.Repeat("b", maxSecretSize+1)
+	chunks, err := s.Create(machineScope, data)
+	if err != nil {
+		t.Fatalf("got an unexpected error: %v", err)
+	}
+	if chunks != 2 {
+		t.Fatalf("expected the bootstrap data to be split in 2 secrets, got %d", chunks)
+	}
+	if got := sm.secrets["aws.cluster.x-k8s.io/node/default/worker/0"] + sm.secrets["aws.cluster.x-k8s.io/node/default/worker/1"]; got != data {
+		t.Fatalf("expected the secrets to hold the bootstrap data, got %d bytes", len(got))
+	}
+	if len(sm.tags["aws.cluster.x-k8s.io/node/default/worker/0"]) == 0 {
+		t.Fatalf("expected the secrets to be tagged")
+	}
+
+	if err := s.Delete(machineScope); err != nil {
+		t.Fatalf("got an unexpected error: %v", err)
+	}
+	if len(sm.secrets) != 1 || sm.secrets["aws.cluster.x-k8s.io/control-plane/default/worker/0"] == "" {
+		t.Fatalf("expected only the secrets of the machine to be deleted, got %v", sm.secrets)
+	}
+	if err := s.Delete(machineScope); err != nil {
+		t.Fatalf("expected deleting the bootstrap data twice to succeed, got %v", err)
+	}
+}
+
+func TestFetchCommand(t *testing.T) {
+	got := FetchCommand("eu-west-1", "aws.cluster.x-k8s.io/node/default/worker")
+	expected := `aws secretsmanager get-secret-value --region "eu-west-1" --secret-id "aws.cluster.x-k8s.io/node/default/worker/$1" --query SecretString --output text`
+	if got != expected {
+		t.Fatalf("expected %q, got %q", expected, got)
+	}
+}
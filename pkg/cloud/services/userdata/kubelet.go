@@ -0,0 +1,127 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package userdata
+
+import (
+	"bytes"
+	"fmt"
+	"mime/multipart"
+	"net/textproto"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+)
+
+const (
+	// kubeletBoothook runs before any other user data part, so the kubelet
+	// picks up the extra arguments the first time it is started by the bootstrap script.
+	kubeletBoothook = `#cloud-boothook
+#!/bin/sh
+for dir in /etc/default /etc/sysconfig; do
+  if [ -d "$dir" ]; then
+    echo "KUBELET_EXTRA_ARGS='{{.KubeletExtraArgs}}'" > "$dir/kubelet"
+  fi
+done
+`
+)
+
+// KubeletRegistrationInput defines the labels and taints a node registers with.
+type KubeletRegistrationInput struct {
+	NodeLabels map[string]string
+	Taints     []corev1.Taint
+
+	KubeletExtraArgs string
+}
+
+// WithKubeletRegistration merges the user data with a cloud-init boothook that passes
+// the node labels and taints to the kubelet. The result is a MIME multipart archive.
+// The user data is returned unchanged if there are no labels or taints to pass.
+func WithKubeletRegistration(userData []byte, input *KubeletRegistrationInput) ([]byte, error) {
+	var args []string
+	if len(input.NodeLabels) > 0 {
+		labels := make([]string, 0, len(input.NodeLabels))
+		for k, v := range input.NodeLabels {
+			labels = append(labels, k+"="+v)
+		}
+		sort.Strings(labels)
+		args = append(args, "--node-labels="+strings.Join(labels, ","))
+	}
+	if len(input.Taints) > 0 {
+		taints := make([]string, 0, len(input.Taints))
+		for _, taint := range input.Taints {
+			taints = append(taints, taint.ToString())
+		}
+		args = append(args, "--register-with-taints="+strings.Join(taints, ","))
+	}
+	if len(args) == 0 {
+		return userData, nil
+	}
+	input.KubeletExtraArgs = strings.Join(args, " ")
+
+	contentType, err := userDataContentType(userData)
+	if err != nil {
+		return nil, err
+	}
+
+	boothook, err := generate("kubelet", kubeletBoothook, input)
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	mw := multipart.NewWriter(&buf)
+	fmt.Fprintf(&buf, "Content-Type: multipart/mixed; boundary=%q\r\nMIME-Version: 1.0\r\n\r\n", mw.Boundary())
+
+	parts := []struct {
+		contentType string
+		data        []byte
+	}{
+		{contentType: "text/cloud-boothook", data: []byte(boothook)},
+		{contentType: contentType, data: userData},
+	}
+	for _, part := range parts {
+		w, err := mw.CreatePart(textproto.MIMEHeader{"Content-Type": {part.contentType + `; charset="us-ascii"`}})
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to create user data part")
+		}
+		if _, err := w.Write(part.data); err != nil {
+			return nil, errors.Wrap(err, "failed to write user data part")
+		}
+	}
+	if err := mw.Close(); err != nil {
+		return nil, errors.Wrap(err, "failed to close user data archive")
+	}
+
+	return buf.Bytes(), nil
+}
+
+// userDataContentType returns the MIME type of the user data.
+// Unknown formats are sent as text/plain, which cloud-init inspects to find their type.
+func userDataContentType(userData []byte) (string, error) {
+	switch {
+	case bytes.HasPrefix(userData, []byte("#cloud-config")):
+		return "text/cloud-config", nil
+	case bytes.HasPrefix(userData, []byte("#!")):
+		return "text/x-shellscript", nil
+	case bytes.HasPrefix(userData, []byte("Content-Type:")), bytes.HasPrefix(userData, []byte("MIME-Version:")):
+		return "", errors.New("user data is already a MIME multipart archive")
+	default:
+		return "text/plain", nil
+	}
+}
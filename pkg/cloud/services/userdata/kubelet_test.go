@@ -0,0 +1,99 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package userdata
+
+import (
+	"bytes"
+	"io/ioutil"
+	"mime"
+	"mime/multipart"
+	"net/mail"
+	"strings"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+func TestWithKubeletRegistration(t *testing.T) {
+	userData := []byte("#cloud-config\nruncmd:\n- kubeadm join\n")
+
+	t.Run("user data is unchanged without labels or taints", func(t *testing.T) {
+		out, err := WithKubeletRegistration(userData, &KubeletRegistrationInput{})
+		if err != nil {
+			t.Fatalf("got an unexpected error: %v", err)
+		}
+		if !bytes.Equal(out, userData) {
+			t.Fatalf("expected user data to be unchanged, got %q", out)
+		}
+	})
+
+	t.Run("labels and taints are passed to the kubelet", func(t *testing.T) {
+		out, err := WithKubeletRegistration(userData, &KubeletRegistrationInput{
+			NodeLabels: map[string]string{"zone": "a", "role": "gpu"},
+			Taints: []corev1.Taint{
+				{Key: "dedicated", Value: "gpu", Effect: corev1.TaintEffectNoSchedule},
+			},
+		})
+		if err != nil {
+			t.Fatalf("got an unexpected error: %v", err)
+		}
+
+		msg, err := mail.ReadMessage(bytes.NewReader(out))
+		if err != nil {
+			t.Fatalf("failed to parse user data: %v", err)
+		}
+		mediaType, params, err := mime.ParseMediaType(msg.Header.Get("Content-Type"))
+		if err != nil || mediaType != "multipart/mixed" {
+			t.Fatalf("expected a multipart/mixed archive, got %q: %v", mediaType, err)
+		}
+
+		var contentTypes, bodies []string
+		mr := multipart.NewReader(msg.Body, params["boundary"])
+		for {
+			part, err := mr.NextPart()
+			if err != nil {
+				break
+			}
+			body, _ := ioutil.ReadAll(part)
+			contentTypes = append(contentTypes, strings.SplitN(part.Header.Get("Content-Type"), ";", 2)[0])
+			bodies = append(bodies, string(body))
+		}
+
+		if len(bodies) != 2 {
+			t.Fatalf("expected 2 parts, got %d", len(bodies))
+		}
+		if contentTypes[0] != "text/cloud-boothook" || contentTypes[1] != "text/cloud-config" {
+			t.Fatalf("unexpected part content types %v", contentTypes)
+		}
+		expectedArgs := "KUBELET_EXTRA_ARGS='--node-labels=role=gpu,zone=a --register-with-taints=dedicated=gpu:NoSchedule'"
+		if !strings.Contains(bodies[0], expectedArgs) {
+			t.Fatalf("expected boothook to contain %q, got %q", expectedArgs, bodies[0])
+		}
+		if bodies[1] != string(userData) {
+			t.Fatalf("expected original user data to be preserved, got %q", bodies[1])
+		}
+	})
+
+	t.Run("MIME user data is rejected", func(t *testing.T) {
+		_, err := WithKubeletRegistration([]byte("Content-Type: multipart/mixed\n"), &KubeletRegistrationInput{
+			NodeLabels: map[string]string{"role": "gpu"},
+		})
+		if err == nil {
+			t.Fatal("expected an error")
+		}
+	})
+}
@@ -0,0 +1,87 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package userdata
+
+const (
+	// secretFetchMIME runs the fetch script as a boothook, before cloud-init
+	// includes the file it writes.
+	secretFetchMIME = `MIME-Version: 1.0
+Content-Type: multipart/mixed; boundary="MIMEBOUNDARY"
+
+--MIMEBOUNDARY
+Content-Transfer-Encoding: 7bit
+Content-Type: text/cloud-boothook
+Mime-Version: 1.0
+
+{{.Header}}
+# Boothooks run on every boot, the bootstrap data is only fetched once.
+FILE="{{.File}}"
+if [ -f "${FILE}" ]; then
+  exit 0
+fi
+
+umask 077
+
+get_chunk() {
+  for attempt in $(seq 1 30); do
+    if {{.FetchCommand}}; then
+      return 0
+    fi
+    sleep 10
+  done
+  echo "failed to fetch chunk $1 of the bootstrap data" >&2
+  return 1
+}
+
+rm -f "${FILE}.b64"
+for i in $(seq 0 $(({{.Chunks}} - 1))); do
+  get_chunk "${i}" | tr -d '\n' >> "${FILE}.b64"
+done
+base64 -d "${FILE}.b64" | gunzip > "${FILE}.tmp"
+rm -f "${FILE}.b64"
+mv "${FILE}.tmp" "${FILE}"
+
+--MIMEBOUNDARY
+Content-Transfer-Encoding: 7bit
+Content-Type: text/x-include-url
+Mime-Version: 1.0
+
+file://{{.File}}
+--MIMEBOUNDARY--
+`
+)
+
+// SecretFetchInput defines the context to generate the user data of an
+// instance whose bootstrap data is stored in a secrets backend.
+type SecretFetchInput struct {
+	baseUserData
+
+	// FetchCommand prints the chunk of the gzipped, base64 encoded bootstrap
+	// data whose index is its first argument, $1.
+	FetchCommand string
+	// Chunks is the number of chunks.
+	Chunks int
+	// File is where the bootstrap data is written on the instance.
+	File string
+}
+
+// NewSecretFetch returns the user data fetching the bootstrap data of an
+// instance from a secrets backend, for cloud-init to include it.
+func NewSecretFetch(input *SecretFetchInput) (string, error) {
+	input.Header = defaultHeader
+	return generate("secretfetch", secretFetchMIME, input)
+}
@@ -19,7 +19,6 @@ package wait
 import (
 	"time"
 
-	"github.com/pkg/errors"
 	"k8s.io/apimachinery/pkg/util/wait"
 	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/awserrors"
 )
@@ -48,6 +47,8 @@ func NewBackoff() wait.Backoff {
 }
 
 // WaitForWithRetryable repeats a condition check with exponential backoff.
+// Errors with one of the retryable codes, and errors classified as retryable
+// or throttling by awserrors.Classify, are retried.
 func WaitForWithRetryable(backoff wait.Backoff, condition wait.ConditionFunc, retryableErrors ...string) error { //nolint
 	var errToReturn error
 	waitErr := wait.ExponentialBackoff(backoff, func() (bool, error) {
@@ -66,17 +67,11 @@ func WaitForWithRetryable(backoff wait.Backoff, condition wait.ConditionFunc, re
 
 		// If the returned error isn't empty, check if the error is a retryable one,
 		// or return immediately.
-		code, ok := awserrors.Code(errors.Cause(err))
-		if !ok {
-			return false, err
-		}
-
-		for _, r := range retryableErrors {
-			if code == r {
-				// We should retry.
-				errToReturn = err
-				return false, nil
-			}
+		switch awserrors.Classify(err, retryableErrors...) {
+		case awserrors.ClassificationRetryable, awserrors.ClassificationThrottling:
+			// We should retry.
+			errToReturn = err
+			return false, nil
 		}
 
 		// Got an error that we can't retry, so return it.
@@ -22,12 +22,17 @@ import (
 	"github.com/aws/aws-sdk-go/service/ec2/ec2iface"
 	"github.com/pkg/errors"
 	infrav1 "sigs.k8s.io/cluster-api-provider-aws/api/v1alpha3"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/converters"
 )
 
 // ApplyParams are function parameters used to apply tags on an aws resource.
 type ApplyParams struct {
 	infrav1.BuildParams
 	EC2Client ec2iface.EC2API
+
+	// Removed are tags that were previously applied by the controllers and
+	// should be removed from the resource, if they still have the same value.
+	Removed infrav1.Tags
 }
 
 // Apply tags a resource with tags including the cluster tag.
@@ -52,11 +57,37 @@ func Apply(params *ApplyParams) error {
 	return errors.Wrapf(err, "failed to tag resource %q in cluster %q", params.ResourceID, params.ClusterName)
 }
 
-// Ensure applies the tags if the current tags differ from the params.
+// Ensure applies the tags that are missing or differ from the current tags,
+// and removes the params' removed tags. Any other tag is left untouched.
 func Ensure(current infrav1.Tags, params *ApplyParams) error {
 	want := infrav1.Build(params.BuildParams)
-	if !current.Equals(want) {
-		return Apply(params)
+
+	if create := want.Difference(current); len(create) > 0 {
+		if _, err := params.EC2Client.CreateTags(&ec2.CreateTagsInput{
+			Resources: aws.StringSlice([]string{params.ResourceID}),
+			Tags:      converters.MapToTags(create),
+		}); err != nil {
+			return errors.Wrapf(err, "failed to tag resource %q in cluster %q", params.ResourceID, params.ClusterName)
+		}
+	}
+
+	remove := infrav1.Tags{}
+	for k, v := range params.Removed {
+		if _, ok := want[k]; ok {
+			continue
+		}
+		if value, ok := current[k]; ok && value == v {
+			remove[k] = v
+		}
 	}
+	if len(remove) > 0 {
+		if _, err := params.EC2Client.DeleteTags(&ec2.DeleteTagsInput{
+			Resources: aws.StringSlice([]string{params.ResourceID}),
+			Tags:      converters.MapToTags(remove),
+		}); err != nil {
+			return errors.Wrapf(err, "failed to remove tags from resource %q in cluster %q", params.ResourceID, params.ClusterName)
+		}
+	}
+
 	return nil
 }
@@ -0,0 +1,129 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tags
+
+import (
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/golang/mock/gomock"
+	infrav1 "sigs.k8s.io/cluster-api-provider-aws/api/v1alpha3"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/services/ec2/mock_ec2iface"
+)
+
+func TestEnsure(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	owned := infrav1.Tags{
+		"sigs.k8s.io/cluster-api-provider-aws/cluster/test-cluster": "owned",
+		"sigs.k8s.io/cluster-api-provider-aws/role":                 "common",
+		"Name": "test-cluster-vpc",
+	}
+
+	withTags := func(tags infrav1.Tags) infrav1.Tags {
+		res := owned.DeepCopy()
+		res.Merge(tags)
+		return res
+	}
+
+	testCases := []struct {
+		name       string
+		current    infrav1.Tags
+		additional infrav1.Tags
+		removed    infrav1.Tags
+		expect     func(m *mock_ec2iface.MockEC2APIMockRecorder)
+	}{
+		{
+			name:       "up to date, with tags not owned by the cluster",
+			current:    withTags(infrav1.Tags{"env": "prod", "owner": "someone-else"}),
+			additional: infrav1.Tags{"env": "prod"},
+		},
+		{
+			name:       "tag added",
+			current:    withTags(infrav1.Tags{"owner": "someone-else"}),
+			additional: infrav1.Tags{"env": "prod"},
+			expect: func(m *mock_ec2iface.MockEC2APIMockRecorder) {
+				m.CreateTags(gomock.Eq(&ec2.CreateTagsInput{
+					Resources: aws.StringSlice([]string{"vpc-1"}),
+					Tags:      []*ec2.Tag{{Key: aws.String("env"), Value: aws.String("prod")}},
+				})).
+					Return(&ec2.CreateTagsOutput{}, nil)
+			},
+		},
+		{
+			name:       "tag mutated",
+			current:    withTags(infrav1.Tags{"env": "dev", "owner": "someone-else"}),
+			additional: infrav1.Tags{"env": "prod"},
+			expect: func(m *mock_ec2iface.MockEC2APIMockRecorder) {
+				m.CreateTags(gomock.Eq(&ec2.CreateTagsInput{
+					Resources: aws.StringSlice([]string{"vpc-1"}),
+					Tags:      []*ec2.Tag{{Key: aws.String("env"), Value: aws.String("prod")}},
+				})).
+					Return(&ec2.CreateTagsOutput{}, nil)
+			},
+		},
+		{
+			name:    "tag removed",
+			current: withTags(infrav1.Tags{"env": "prod", "owner": "someone-else"}),
+			removed: infrav1.Tags{"env": "prod"},
+			expect: func(m *mock_ec2iface.MockEC2APIMockRecorder) {
+				m.DeleteTags(gomock.Eq(&ec2.DeleteTagsInput{
+					Resources: aws.StringSlice([]string{"vpc-1"}),
+					Tags:      []*ec2.Tag{{Key: aws.String("env"), Value: aws.String("prod")}},
+				})).
+					Return(&ec2.DeleteTagsOutput{}, nil)
+			},
+		},
+		{
+			name:    "removed tag changed by someone else",
+			current: withTags(infrav1.Tags{"env": "staging"}),
+			removed: infrav1.Tags{"env": "prod"},
+		},
+		{
+			name:    "removed tag already gone",
+			current: owned,
+			removed: infrav1.Tags{"env": "prod"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ec2Mock := mock_ec2iface.NewMockEC2API(mockCtrl)
+			if tc.expect != nil {
+				tc.expect(ec2Mock.EXPECT())
+			}
+
+			err := Ensure(tc.current, &ApplyParams{
+				EC2Client: ec2Mock,
+				BuildParams: infrav1.BuildParams{
+					ClusterName: "test-cluster",
+					ResourceID:  "vpc-1",
+					Lifecycle:   infrav1.ResourceLifecycleOwned,
+					Name:        aws.String("test-cluster-vpc"),
+					Role:        aws.String(infrav1.CommonRoleTagValue),
+					Additional:  tc.additional,
+				},
+				Removed: tc.removed,
+			})
+			if err != nil {
+				t.Fatalf("got an unexpected error: %v", err)
+			}
+		})
+	}
+}
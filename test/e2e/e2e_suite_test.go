@@ -53,6 +53,7 @@ import (
 	"k8s.io/client-go/kubernetes"
 	infrav1 "sigs.k8s.io/cluster-api-provider-aws/api/v1alpha3"
 	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/awserrors"
+	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/partition"
 	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/services/cloudformation"
 	"sigs.k8s.io/cluster-api-provider-aws/pkg/cloud/services/sts"
 	bootstrapv1 "sigs.k8s.io/cluster-api/bootstrap/kubeadm/api/v1alpha3"
@@ -258,7 +259,7 @@ func getAccountID(prov client.ConfigProvider) string {
 func createIAMRoles(prov client.ConfigProvider, accountID string) {
 	cfnSvc := cloudformation.NewService(cfn.New(prov))
 	Expect(
-		cfnSvc.ReconcileBootstrapStack(stackName, accountID, "aws", []string{}, []string{}),
+		cfnSvc.ReconcileBootstrapStack(stackName, accountID, partition.ForRegion(region), cloudformation.BootstrapConfig{}, nil, false),
 	).To(Succeed())
 }
 